@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
@@ -13,15 +14,75 @@ import (
 
 	"mem0-go/internal/observability"
 
+	"mem0-go/internal/auth"
 	"mem0-go/internal/config"
+	"mem0-go/internal/db"
 	"mem0-go/internal/docs"
+	"mem0-go/internal/embedding"
+	"mem0-go/internal/events"
+	"mem0-go/internal/extract"
 	"mem0-go/internal/graphql"
+	"mem0-go/internal/hnsw"
 	"mem0-go/internal/inmem"
+	"mem0-go/internal/llm"
 	"mem0-go/internal/memory"
+	"mem0-go/internal/rerank"
 	"mem0-go/internal/rest"
+	"mem0-go/internal/tenant"
+	"mem0-go/internal/vector"
 )
 
+// vectorBackend is the vector store contract memory.Service depends on.
+type vectorBackend interface {
+	Upsert(ctx context.Context, collection string, pts []vector.Point) error
+	Query(ctx context.Context, collection string, vec []float32, limit int, filter *vector.Filter) ([]vector.QueryResult, error)
+	Delete(ctx context.Context, collection string, ids []string) error
+	Scroll(ctx context.Context, collection, offset string, limit int) ([]vector.Point, string, error)
+}
+
+// setupApp builds the app with configuration from the environment.
 func setupApp(logger *slog.Logger) *fiber.App {
+	app, err := newApp(logger, config.Load())
+	if err != nil {
+		panic(err)
+	}
+	return app
+}
+
+// newVectorBackend returns the vector store selected by cfg, registering
+// any cleanup it needs on app shutdown. dim is the embedding dimension the
+// memories collection is created with.
+func newVectorBackend(app *fiber.App, cfg config.Config, dim int) (vectorBackend, error) {
+	switch cfg.VectorBackend {
+	case "memory":
+		return inmem.NewVectorWithDistance(vector.LoadConfig().Distance), nil
+	case "qdrant":
+		vcfg := vector.LoadConfig()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		client, err := vector.Connect(ctx, vcfg)
+		if err != nil {
+			return nil, err
+		}
+		if err := client.EnsureCollection(ctx, memory.Collection, vcfg.CollectionConfig(dim)); err != nil {
+			return nil, fmt.Errorf("ensure collection %q: %w", memory.Collection, err)
+		}
+		return client, nil
+	case "hnsw":
+		hcfg := hnsw.LoadConfig()
+		hcfg.Distance = vector.LoadConfig().Distance
+		store, err := hnsw.Open(hcfg)
+		if err != nil {
+			return nil, err
+		}
+		app.Hooks().OnShutdown(store.Close)
+		return store, nil
+	default:
+		return nil, fmt.Errorf("unknown vector backend %q", cfg.VectorBackend)
+	}
+}
+
+func newApp(logger *slog.Logger, cfg config.Config) (*fiber.App, error) {
 	app := fiber.New(fiber.Config{
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
 			logger.Error("unhandled error", "err", err)
@@ -51,23 +112,77 @@ func setupApp(logger *slog.Logger) *fiber.App {
 	})
 
 	repo := inmem.NewRepo()
-	vec := inmem.NewVector()
+	authn, err := auth.New(auth.LoadConfig(), repo, auth.WithRoles(repo))
+	if err != nil {
+		return nil, err
+	}
+	app.Use("/api", auth.Middleware(authn))
+	app.Use("/graphql", auth.Middleware(authn, "/graphql"))
+
+	emb := embedding.New(embedding.LoadConfig())
+	vec, err := newVectorBackend(app, cfg, emb.Dimension())
+	if err != nil {
+		return nil, err
+	}
 	g := inmem.NewGraph()
-	svc := memory.NewService(repo, vec, g)
-	graphql.Register(app, svc)
+	opts := []memory.Option{
+		memory.WithLLM(llm.New(llm.LoadConfig())),
+		memory.WithEmbedder(emb),
+		memory.WithEvents(events.NewBus()),
+		memory.WithExtractor(extract.New(extract.LoadConfig())),
+	}
+	if rcfg := rerank.LoadConfig(); rcfg.URL != "" {
+		opts = append(opts, memory.WithCrossEncoder(rerank.NewCrossEncoder(rcfg)))
+	}
+	svc := memory.NewService(repo, vec, g, opts...)
+	graphql.Register(app, svc, authn)
 	rest.Register(app, svc)
+	rest.RegisterGraph(app, svc)
+	tenants := tenant.NewService(repo)
+	rest.RegisterKeys(app, authn, tenants)
+	rest.RegisterTenants(app, tenants)
+	rest.RegisterRoles(app, authn, tenants)
 	docs.Register(app)
 
-	return app
+	return app, nil
+}
+
+// migrate applies pending Postgres migrations.
+func migrate(ctx context.Context, logger *slog.Logger) error {
+	connCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	pool, err := db.Connect(connCtx, db.LoadConfig())
+	cancel()
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+	m, err := db.NewMigrator(pool)
+	if err != nil {
+		return err
+	}
+	applied, err := m.Up(ctx)
+	logger.Info("migrations applied", "versions", applied)
+	return err
 }
 
 func main() {
 	cfg := config.Load()
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 
+	if cfg.AutoMigrate {
+		if err := migrate(context.Background(), logger); err != nil {
+			logger.Error("migration failed", "err", err)
+			os.Exit(1)
+		}
+	}
+
 	shutdown := observability.Start(context.Background(), "api")
 
-	app := setupApp(logger)
+	app, err := newApp(logger, cfg)
+	if err != nil {
+		logger.Error("setup failed", "err", err)
+		os.Exit(1)
+	}
 	addr := ":" + cfg.HTTPPort
 
 	srvErr := make(chan error, 1)
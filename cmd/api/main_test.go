@@ -1,9 +1,11 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"testing"
@@ -153,3 +155,1194 @@ func TestOpenAPIDocs(t *testing.T) {
 		t.Fatalf("status %d", resp.StatusCode)
 	}
 }
+
+func TestRESTTextAndIngest(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	app := setupApp(logger)
+
+	for _, content := range []string{"Alice works at Acme", "Bob likes sailing"} {
+		body := `{"userID":1,"content":"` + content + `"}`
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/memories", strings.NewReader(body))
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("post: %v", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("status %d", resp.StatusCode)
+		}
+	}
+
+	body := `{"query":"where does Alice work","limit":5}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/memories/search", strings.NewReader(body))
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+
+	body = `{"userID":1,"messages":[{"role":"user","content":"Alice works at Acme. Carol lives in Rome."}]}`
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/memories/ingest", strings.NewReader(body))
+	resp, err = app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("ingest: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+	var out struct {
+		Results []struct {
+			Event string `json:"event"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(out.Results) != 2 {
+		t.Fatalf("unexpected results %+v", out.Results)
+	}
+}
+
+func TestHNSWBackendPersistsOnShutdown(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("MEM0_VECTOR_BACKEND", "hnsw")
+	t.Setenv("MEM0_HNSW_PATH", dir)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	app := setupApp(logger)
+
+	body := `{"userID":1,"content":"hello"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/memories", strings.NewReader(body))
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+	if err := app.ShutdownWithContext(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "memories.hnsw")); err != nil {
+		t.Fatalf("index not saved: %v", err)
+	}
+}
+
+func TestRESTSearchIsScopedByUser(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	app := setupApp(logger)
+
+	for _, body := range []string{
+		`{"userID":1,"content":"likes tea","tags":["drink"]}`,
+		`{"userID":2,"content":"likes tea","metadata":{"source":"import"}}`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/memories", strings.NewReader(body))
+		if resp, err := app.Test(req, -1); err != nil || resp.StatusCode != http.StatusOK {
+			t.Fatalf("post: %v", err)
+		}
+	}
+
+	search := func(body string) []int64 {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/memories/search", strings.NewReader(body))
+		resp, err := app.Test(req, -1)
+		if err != nil || resp.StatusCode != http.StatusOK {
+			t.Fatalf("search: %v", err)
+		}
+		var out struct {
+			Results []struct {
+				ID int64 `json:"id"`
+			} `json:"results"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		var ids []int64
+		for _, r := range out.Results {
+			ids = append(ids, r.ID)
+		}
+		return ids
+	}
+
+	if ids := search(`{"query":"tea","limit":10,"userID":2}`); len(ids) != 1 || ids[0] != 2 {
+		t.Fatalf("unexpected ids %v", ids)
+	}
+	if ids := search(`{"query":"tea","limit":10,"filter":{"must_not":[{"key":"metadata.source","match":{"value":"import"}}]}}`); len(ids) != 1 || ids[0] != 1 {
+		t.Fatalf("unexpected ids %v", ids)
+	}
+	if ids := search(`{"query":"tea","limit":10,"tags":["drink"]}`); len(ids) != 1 || ids[0] != 1 {
+		t.Fatalf("unexpected ids %v", ids)
+	}
+}
+
+func TestRESTUpdateDeleteHistory(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	app := setupApp(logger)
+
+	do := func(method, path, body string) *http.Response {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-Actor", "tester")
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		return resp
+	}
+
+	if resp := do(http.MethodPost, "/api/v1/memories", `{"userID":1,"content":"likes tea","tags":["drink"]}`); resp.StatusCode != http.StatusOK {
+		t.Fatalf("create status %d", resp.StatusCode)
+	}
+	if resp := do(http.MethodPatch, "/api/v1/memories/1", `{"tags":["drink","hot"]}`); resp.StatusCode != http.StatusOK {
+		t.Fatalf("patch status %d", resp.StatusCode)
+	}
+	resp := do(http.MethodPut, "/api/v1/memories/1", `{"content":"likes coffee"}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("put status %d", resp.StatusCode)
+	}
+	var m struct {
+		Content string   `json:"content"`
+		Tags    []string `json:"tags"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&m); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if m.Content != "likes coffee" || len(m.Tags) != 0 {
+		t.Fatalf("put did not replace memory: %+v", m)
+	}
+
+	if resp := do(http.MethodDelete, "/api/v1/memories/1", ""); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("delete status %d", resp.StatusCode)
+	}
+	if resp := do(http.MethodGet, "/api/v1/memories/1", ""); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("get after delete status %d", resp.StatusCode)
+	}
+	if resp := do(http.MethodPut, "/api/v1/memories/1", `{"content":"x"}`); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("put after delete status %d", resp.StatusCode)
+	}
+
+	resp = do(http.MethodGet, "/api/v1/memories/1/history", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("history status %d", resp.StatusCode)
+	}
+	var out struct {
+		History []struct {
+			Event  string   `json:"event"`
+			Actor  string   `json:"actor"`
+			Fields []string `json:"fields"`
+		} `json:"history"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	var events []string
+	for _, h := range out.History {
+		if h.Actor != "tester" {
+			t.Fatalf("unexpected actor %q", h.Actor)
+		}
+		events = append(events, h.Event)
+	}
+	if strings.Join(events, ",") != "ADD,UPDATE,UPDATE,DELETE" {
+		t.Fatalf("unexpected events %v", events)
+	}
+	if f := out.History[2].Fields; len(f) != 2 || f[0] != "content" || f[1] != "tags" {
+		t.Fatalf("unexpected changed fields %v", f)
+	}
+	if resp := do(http.MethodGet, "/api/v1/memories/99/history", ""); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("history of unknown memory status %d", resp.StatusCode)
+	}
+}
+
+func TestRESTListMemories(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	app := setupApp(logger)
+
+	do := func(method, path, body string) *http.Response {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		return resp
+	}
+	for _, body := range []string{
+		`{"userID":1,"content":"Likes tea","runID":"s1","tags":["drink"]}`,
+		`{"userID":1,"content":"lives in Paris","runID":"s1"}`,
+		`{"userID":2,"content":"drinks green tea","agentID":"bot","tags":["drink"]}`,
+		`{"userID":1,"content":"plays chess","runID":"s2"}`,
+		`{"userID":1,"content":"tea after dinner","runID":"s2","tags":["drink"]}`,
+	} {
+		if resp := do(http.MethodPost, "/api/v1/memories", body); resp.StatusCode != http.StatusOK {
+			t.Fatalf("create status %d", resp.StatusCode)
+		}
+	}
+
+	type page struct {
+		Memories []struct {
+			ID int64 `json:"id"`
+		} `json:"memories"`
+		NextCursor string `json:"nextCursor"`
+		HasMore    bool   `json:"hasMore"`
+	}
+	list := func(query string) page {
+		resp := do(http.MethodGet, "/api/v1/memories?"+query, "")
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("list %q: status %d", query, resp.StatusCode)
+		}
+		var p page
+		if err := json.NewDecoder(resp.Body).Decode(&p); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return p
+	}
+	ids := func(p page) string {
+		var out []string
+		for _, m := range p.Memories {
+			out = append(out, strconv.FormatInt(m.ID, 10))
+		}
+		return strings.Join(out, ",")
+	}
+
+	// walk every memory two at a time, newest first
+	var got []string
+	cursor := ""
+	for i := 0; ; i++ {
+		p := list("limit=2&sort=createdAt&order=desc&cursor=" + cursor)
+		got = append(got, ids(p))
+		if !p.HasMore {
+			break
+		}
+		if i == 3 {
+			t.Fatalf("listing did not end: %v", got)
+		}
+		cursor = p.NextCursor
+	}
+	if strings.Join(got, "|") != "5,4|3,2|1" {
+		t.Fatalf("pages %v", got)
+	}
+
+	for query, want := range map[string]string{
+		"userID=1":                "1,2,4,5",
+		"agentID=bot":             "3",
+		"runID=s2":                "4,5",
+		"tag=drink&userID=1":      "1,5",
+		"contains=TEA":            "1,3,5",
+		"contains=tea&order=desc": "5,3,1",
+		"createdAfter=2000-01-01T00:00:00Z&createdBefore=2001-01-01T00:00:00Z": "",
+	} {
+		if p := list(query); ids(p) != want || p.HasMore {
+			t.Fatalf("%s: got %s (more %v), want %s", query, ids(p), p.HasMore, want)
+		}
+	}
+
+	p := list("limit=2")
+	if resp := do(http.MethodGet, "/api/v1/memories?sort=createdAt&cursor="+p.NextCursor, ""); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("cursor of another order: status %d", resp.StatusCode)
+	}
+	for _, query := range []string{"cursor=bogus", "sort=name", "order=up", "createdAfter=yesterday", "limit=x"} {
+		if resp := do(http.MethodGet, "/api/v1/memories?"+query, ""); resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("%s: status %d", query, resp.StatusCode)
+		}
+	}
+
+	// the GraphQL connection pages the same way
+	gql := func(after string) map[string]interface{} {
+		body, _ := json.Marshal(map[string]interface{}{
+			"query":     `query($after: String) { memoryConnection(first: 3, after: $after, contains: "e", sort: CREATED_AT) { edges { cursor node { id } } pageInfo { hasNextPage endCursor } } }`,
+			"variables": map[string]interface{}{"after": after},
+		})
+		req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("graphql: %v", err)
+		}
+		var out struct {
+			Data struct {
+				MemoryConnection map[string]interface{} `json:"memoryConnection"`
+			} `json:"data"`
+			Errors []interface{} `json:"errors"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil || len(out.Errors) > 0 {
+			t.Fatalf("graphql: %v %v", err, out.Errors)
+		}
+		return out.Data.MemoryConnection
+	}
+	conn := gql("")
+	info := conn["pageInfo"].(map[string]interface{})
+	edges := conn["edges"].([]interface{})
+	if len(edges) != 3 || info["hasNextPage"] != true || info["endCursor"] != edges[2].(map[string]interface{})["cursor"] {
+		t.Fatalf("first page: %v", conn)
+	}
+	conn = gql(info["endCursor"].(string))
+	edges = conn["edges"].([]interface{})
+	if len(edges) != 2 || conn["pageInfo"].(map[string]interface{})["hasNextPage"] != false {
+		t.Fatalf("second page: %v", conn)
+	}
+	if id := edges[0].(map[string]interface{})["node"].(map[string]interface{})["id"]; id != float64(4) {
+		t.Fatalf("second page starts at %v", id)
+	}
+}
+
+func TestHybridSearch(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	app := setupApp(logger)
+
+	post := func(path, body string) *http.Response {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("post %s: %v", path, err)
+		}
+		return resp
+	}
+	for _, content := range []string{"likes green tea", "order ZX-4471 shipped", "lives in Paris"} {
+		if resp := post("/api/v1/memories", `{"userID":1,"content":"`+content+`"}`); resp.StatusCode != http.StatusOK {
+			t.Fatalf("create status %d", resp.StatusCode)
+		}
+	}
+
+	resp := post("/api/v1/memories/search", `{"query":"zx-4471","mode":"hybrid","fusion":"weighted","vectorWeight":0.2,"limit":2}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("search status %d", resp.StatusCode)
+	}
+	var out struct {
+		Results []struct {
+			ID          int64 `json:"id"`
+			Explanation struct {
+				Fusion   string `json:"fusion"`
+				TextRank int    `json:"textRank"`
+			} `json:"explanation"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(out.Results) != 2 || out.Results[0].ID != 2 || out.Results[0].Explanation.Fusion != "weighted" || out.Results[0].Explanation.TextRank != 1 {
+		t.Fatalf("unexpected results: %+v", out.Results)
+	}
+	if resp := post("/api/v1/memories/search", `{"query":"tea","mode":"fuzzy"}`); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("unknown mode: status %d", resp.StatusCode)
+	}
+
+	resp = post("/graphql", `{"query":"{ search(query: \"paris\", mode: HYBRID) { id explanation { fusion textRank vectorRank } } }"}`)
+	var gql struct {
+		Data struct {
+			Search []struct {
+				ID          int64 `json:"id"`
+				Explanation struct {
+					Fusion     string `json:"fusion"`
+					TextRank   int    `json:"textRank"`
+					VectorRank int    `json:"vectorRank"`
+				} `json:"explanation"`
+			} `json:"search"`
+		} `json:"data"`
+		Errors []interface{} `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&gql); err != nil || len(gql.Errors) > 0 {
+		t.Fatalf("graphql: %v %v", err, gql.Errors)
+	}
+	if res := gql.Data.Search; len(res) != 3 || res[0].ID != 3 || res[0].Explanation.Fusion != "RRF" || res[0].Explanation.TextRank != 1 || res[0].Explanation.VectorRank == 0 {
+		t.Fatalf("unexpected graphql results: %+v", res)
+	}
+
+	// reranking
+	resp = post("/api/v1/memories/search", `{"query":"tea","limit":1,"rerank":[{"type":"mmr"},{"type":"recency","halfLife":"24h"}]}`)
+	var reranked struct {
+		Results []struct {
+			Explanation struct {
+				RetrievalRank int `json:"retrievalRank"`
+			} `json:"explanation"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&reranked); err != nil || len(reranked.Results) != 1 || reranked.Results[0].Explanation.RetrievalRank == 0 {
+		t.Fatalf("reranked search: %v %+v", err, reranked)
+	}
+	for _, body := range []string{`{"query":"tea","rerank":[{"type":"llm"}]}`, `{"query":"tea","rerank":[{"type":"cross-encoder"}]}`} {
+		if resp := post("/api/v1/memories/search", body); resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("%s: status %d", body, resp.StatusCode)
+		}
+	}
+	resp = post("/graphql", `{"query":"{ search(query: \"tea\", limit: 2, rerank: [{type: MMR, lambda: 0.5}]) { id explanation { retrievalRank retrievalScore } } }"}`)
+	var gqlReranked struct {
+		Data struct {
+			Search []struct {
+				Explanation struct {
+					RetrievalRank *int `json:"retrievalRank"`
+				} `json:"explanation"`
+			} `json:"search"`
+		} `json:"data"`
+		Errors []interface{} `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&gqlReranked); err != nil || len(gqlReranked.Errors) > 0 || len(gqlReranked.Data.Search) != 2 || gqlReranked.Data.Search[0].Explanation.RetrievalRank == nil {
+		t.Fatalf("graphql reranked search: %v %+v", err, gqlReranked)
+	}
+}
+
+func TestGraphSearch(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	app := setupApp(logger)
+
+	post := func(path, body string) *http.Response {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("post %s: %v", path, err)
+		}
+		return resp
+	}
+	graphql := func(query string, out interface{}) {
+		t.Helper()
+		body, _ := json.Marshal(map[string]string{"query": query})
+		resp := post("/graphql", string(body))
+		var res struct {
+			Data   json.RawMessage `json:"data"`
+			Errors []interface{}   `json:"errors"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&res); err != nil || len(res.Errors) > 0 {
+			t.Fatalf("%s: %v %v", query, err, res.Errors)
+		}
+		if err := json.Unmarshal(res.Data, out); err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+	}
+	for _, content := range []string{"had lunch with Alice", "Acme ships on Fridays"} {
+		if resp := post("/api/v1/memories", `{"userID":1,"content":"`+content+`"}`); resp.StatusCode != http.StatusOK {
+			t.Fatalf("create status %d", resp.StatusCode)
+		}
+	}
+	var nodes struct {
+		Entities []struct {
+			ID         string                 `json:"id"`
+			Properties map[string]interface{} `json:"properties"`
+		} `json:"entities"`
+	}
+	graphql(`{ entities(label: "Memory") { id properties } }`, &nodes)
+	memoryNodes := map[float64]string{}
+	for _, n := range nodes.Entities {
+		id, _ := n.Properties["memory_id"].(float64)
+		memoryNodes[id] = n.ID
+	}
+	var entity struct {
+		CreateEntity struct {
+			ID string `json:"id"`
+		} `json:"createEntity"`
+	}
+	graphql(`mutation { createEntity(label: "Person", properties: {name: "Alice"}) { id } }`, &entity)
+	alice := entity.CreateEntity.ID
+	graphql(`mutation { createEntity(label: "Company", properties: {name: "Acme"}) { id } }`, &entity)
+	acme := entity.CreateEntity.ID
+	for _, rel := range [][3]string{{alice, acme, "WORKS_AT"}, {memoryNodes[1], alice, "MENTIONS"}, {memoryNodes[2], acme, "MENTIONS"}} {
+		var out interface{}
+		graphql(`mutation { relateEntities(from: "`+rel[0]+`", to: "`+rel[1]+`", type: "`+rel[2]+`") { id } }`, &out)
+	}
+
+	resp := post("/api/v1/memories/search/graph", `{"query":"lunch","mode":"keyword","limit":1,"hops":1}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("graph search status %d", resp.StatusCode)
+	}
+	var out struct {
+		Seeds []struct {
+			ID int64 `json:"id"`
+		} `json:"seeds"`
+		Triples []struct {
+			Subject   string `json:"subject"`
+			Predicate string `json:"predicate"`
+			Object    string `json:"object"`
+		} `json:"triples"`
+		Related []struct {
+			Memory struct {
+				ID int64 `json:"id"`
+			} `json:"memory"`
+			Hops int `json:"hops"`
+		} `json:"related"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(out.Seeds) != 1 || out.Seeds[0].ID != 1 {
+		t.Fatalf("seeds: %+v", out.Seeds)
+	}
+	if len(out.Triples) != 1 || out.Triples[0].Subject != "Alice" || out.Triples[0].Predicate != "WORKS_AT" || out.Triples[0].Object != "Acme" {
+		t.Fatalf("triples: %+v", out.Triples)
+	}
+	if len(out.Related) != 1 || out.Related[0].Memory.ID != 2 || out.Related[0].Hops != 1 {
+		t.Fatalf("related: %+v", out.Related)
+	}
+	if resp := post("/api/v1/memories/search/graph", `{"query":"lunch","hops":9}`); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("too many hops: status %d", resp.StatusCode)
+	}
+
+	var gql struct {
+		GraphSearch struct {
+			Entities []struct {
+				ID string `json:"id"`
+			} `json:"entities"`
+			Triples []struct {
+				Relationship struct {
+					FromID string `json:"fromID"`
+				} `json:"relationship"`
+			} `json:"triples"`
+			Related []struct {
+				Memory struct {
+					Content string `json:"content"`
+				} `json:"memory"`
+				EntityIDs []string `json:"entityIDs"`
+			} `json:"related"`
+		} `json:"graphSearch"`
+	}
+	graphql(`{ graphSearch(query: "lunch", mode: KEYWORD, limit: 1) { entities { id } triples { relationship { fromID } } related { memory { content } entityIDs } } }`, &gql)
+	res := gql.GraphSearch
+	if len(res.Entities) != 2 || len(res.Triples) != 1 || res.Triples[0].Relationship.FromID != alice {
+		t.Fatalf("graphql graph search: %+v", res)
+	}
+	if len(res.Related) != 1 || res.Related[0].Memory.Content != "Acme ships on Fridays" || res.Related[0].EntityIDs[0] != acme {
+		t.Fatalf("graphql related: %+v", res.Related)
+	}
+}
+
+func TestGraphTraversal(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	app := setupApp(logger)
+
+	post := func(path, body string) *http.Response {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("post %s: %v", path, err)
+		}
+		return resp
+	}
+	graphql := func(query string, out interface{}) {
+		t.Helper()
+		body, _ := json.Marshal(map[string]string{"query": query})
+		var res struct {
+			Data   json.RawMessage `json:"data"`
+			Errors []interface{}   `json:"errors"`
+		}
+		if err := json.NewDecoder(post("/graphql", string(body)).Body).Decode(&res); err != nil || len(res.Errors) > 0 {
+			t.Fatalf("%s: %v %v", query, err, res.Errors)
+		}
+		if err := json.Unmarshal(res.Data, out); err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+	}
+	// alice -KNOWS-> bob -KNOWS-> carol (retired)
+	var ids []string
+	for _, name := range []string{"alice", "bob", "carol"} {
+		var out struct {
+			CreateEntity struct {
+				ID string `json:"id"`
+			} `json:"createEntity"`
+		}
+		graphql(`mutation { createEntity(label: "Person", properties: {name: "`+name+`", retired: `+strconv.FormatBool(name == "carol")+`}) { id } }`, &out)
+		ids = append(ids, out.CreateEntity.ID)
+	}
+	for _, rel := range [][2]string{{ids[0], ids[1]}, {ids[1], ids[2]}} {
+		var out interface{}
+		graphql(`mutation { relateEntities(from: "`+rel[0]+`", to: "`+rel[1]+`", type: "KNOWS") { id } }`, &out)
+	}
+
+	resp := post("/api/v1/graph/traverse", `{"start":"`+ids[0]+`","types":["KNOWS"],"maxDepth":2}`)
+	var visits struct {
+		Visits []struct {
+			Node struct {
+				ID string `json:"id"`
+			} `json:"node"`
+			Depth int `json:"depth"`
+		} `json:"visits"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&visits); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if v := visits.Visits; len(v) != 2 || v[0].Node.ID != ids[1] || v[1].Node.ID != ids[2] || v[1].Depth != 2 {
+		t.Fatalf("unexpected visits: %+v", v)
+	}
+	resp = post("/api/v1/graph/path", `{"from":"`+ids[2]+`","to":"`+ids[0]+`","direction":"both"}`)
+	var path struct {
+		Path struct {
+			Nodes []struct {
+				ID string `json:"id"`
+			} `json:"nodes"`
+			Edges []struct {
+				Type string `json:"type"`
+			} `json:"edges"`
+		} `json:"path"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&path); err != nil || len(path.Path.Nodes) != 3 || len(path.Path.Edges) != 2 {
+		t.Fatalf("path: %v %+v", err, path)
+	}
+	resp = post("/api/v1/graph/subgraph", `{"center":"`+ids[1]+`","direction":"both"}`)
+	var sg struct {
+		Nodes []interface{} `json:"nodes"`
+		Edges []interface{} `json:"edges"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&sg); err != nil || len(sg.Nodes) != 3 || len(sg.Edges) != 2 {
+		t.Fatalf("subgraph: %v %+v", err, sg)
+	}
+	for path, body := range map[string]string{
+		"/api/v1/graph/traverse": `{"start":"` + ids[0] + `","maxDepth":99}`,
+		"/api/v1/graph/path":     `{"from":"` + ids[0] + `","to":"` + ids[1] + `","direction":"up"}`,
+	} {
+		if resp := post(path, body); resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("%s %s: status %d", path, body, resp.StatusCode)
+		}
+	}
+	if resp := post("/api/v1/graph/subgraph", `{"center":"missing"}`); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("missing center: status %d", resp.StatusCode)
+	}
+
+	var gql struct {
+		Traverse []struct {
+			Entity struct {
+				ID string `json:"id"`
+			} `json:"entity"`
+			Via struct {
+				FromID string `json:"fromID"`
+			} `json:"via"`
+		} `json:"traverse"`
+		ShortestPath *struct {
+			Length int `json:"length"`
+		} `json:"shortestPath"`
+		Subgraph struct {
+			Relationships []struct {
+				Type string `json:"type"`
+			} `json:"relationships"`
+		} `json:"subgraph"`
+	}
+	graphql(`{
+		traverse(start: "`+ids[0]+`", maxDepth: 3, strategy: DFS, where: [{key: "retired", op: NE, value: true}]) { entity { id } via { fromID } }
+		shortestPath(from: "`+ids[0]+`", to: "`+ids[2]+`") { length }
+		subgraph(center: "`+ids[0]+`", depth: 2, types: ["KNOWS"]) { relationships { type } }
+	}`, &gql)
+	if len(gql.Traverse) != 1 || gql.Traverse[0].Entity.ID != ids[1] || gql.Traverse[0].Via.FromID != ids[0] {
+		t.Fatalf("graphql traverse: %+v", gql.Traverse)
+	}
+	if gql.ShortestPath == nil || gql.ShortestPath.Length != 2 || len(gql.Subgraph.Relationships) != 2 {
+		t.Fatalf("graphql path %+v, subgraph %+v", gql.ShortestPath, gql.Subgraph)
+	}
+	var none struct {
+		ShortestPath *struct{} `json:"shortestPath"`
+	}
+	graphql(`{ shortestPath(from: "`+ids[2]+`", to: "`+ids[0]+`") { length } }`, &none)
+	if none.ShortestPath != nil {
+		t.Fatal("expected no path against relationship direction")
+	}
+}
+
+func TestEntityRoutes(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	app := setupApp(logger)
+
+	do := func(method, path, body string, out interface{}) int {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		if out != nil {
+			if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+				t.Fatalf("%s %s: decode: %v", method, path, err)
+			}
+		}
+		return resp.StatusCode
+	}
+	type node struct {
+		ID    string                 `json:"id"`
+		Label string                 `json:"label"`
+		Props map[string]interface{} `json:"properties"`
+	}
+	create := func(path, body string) string {
+		t.Helper()
+		var out struct {
+			ID string `json:"id"`
+		}
+		if code := do(http.MethodPost, path, body, &out); code != http.StatusOK || out.ID == "" {
+			t.Fatalf("create %s: status %d", body, code)
+		}
+		return out.ID
+	}
+	alice := create("/api/v1/entities", `{"label":"Person","properties":{"name":"Alice","age":30}}`)
+	bob := create("/api/v1/entities", `{"label":"Person","properties":{"name":"Bob","age":25}}`)
+	rel := create("/api/v1/relationships", `{"from":"`+alice+`","to":"`+bob+`","type":"KNOWS"}`)
+
+	var found []node
+	if code := do(http.MethodGet, "/api/v1/entities?label=Person&name=Alice&age=30", "", &found); code != http.StatusOK || len(found) != 1 || found[0].ID != alice {
+		t.Fatalf("find: %d %+v", code, found)
+	}
+	var n node
+	if code := do(http.MethodGet, "/api/v1/entities/"+bob, "", &n); code != http.StatusOK || n.Props["name"] != "Bob" {
+		t.Fatalf("get: %d %+v", code, n)
+	}
+	var patched node
+	if code := do(http.MethodPatch, "/api/v1/entities/"+bob, `{"properties":{"age":null,"city":"Paris"}}`, &patched); code != http.StatusOK || patched.Props["city"] != "Paris" || patched.Props["age"] != nil {
+		t.Fatalf("patch: %d %+v", code, patched)
+	}
+	var edges []struct {
+		ID    string                 `json:"id"`
+		Props map[string]interface{} `json:"properties"`
+	}
+	if code := do(http.MethodGet, "/api/v1/entities/"+bob+"/relationships", "", &edges); code != http.StatusOK || len(edges) != 1 || edges[0].ID != rel {
+		t.Fatalf("entity relationships: %d %+v", code, edges)
+	}
+	if code := do(http.MethodGet, "/api/v1/relationships?from="+alice+"&type=KNOWS", "", &edges); code != http.StatusOK || len(edges) != 1 {
+		t.Fatalf("find relationships: %d %+v", code, edges)
+	}
+	if code := do(http.MethodPatch, "/api/v1/relationships/"+rel, `{"properties":{"since":2020}}`, &edges[0]); code != http.StatusOK || edges[0].Props["since"] != 2020.0 {
+		t.Fatalf("patch relationship: %d %+v", code, edges[0])
+	}
+	if code := do(http.MethodDelete, "/api/v1/entities/"+bob, "", nil); code != http.StatusConflict {
+		t.Fatalf("delete related entity: status %d", code)
+	}
+	if code := do(http.MethodDelete, "/api/v1/entities/"+bob+"?cascade=true", "", nil); code != http.StatusNoContent {
+		t.Fatalf("cascade delete: status %d", code)
+	}
+	for _, path := range []string{"/api/v1/entities/" + bob, "/api/v1/relationships/" + rel} {
+		if code := do(http.MethodGet, path, "", nil); code != http.StatusNotFound {
+			t.Fatalf("%s after delete: status %d", path, code)
+		}
+	}
+	if code := do(http.MethodDelete, "/api/v1/relationships/"+rel, "", nil); code != http.StatusNotFound {
+		t.Fatalf("delete missing relationship: status %d", code)
+	}
+	if code := do(http.MethodPost, "/api/v1/relationships", `{"from":"`+alice+`","type":"KNOWS"}`, nil); code != http.StatusBadRequest {
+		t.Fatalf("relationship without end: status %d", code)
+	}
+
+	var gql struct {
+		Data struct {
+			UpdateEntity struct {
+				ID string `json:"id"`
+			} `json:"updateEntity"`
+			DeleteEntity string `json:"deleteEntity"`
+		} `json:"data"`
+		Errors []interface{} `json:"errors"`
+	}
+	query, _ := json.Marshal(map[string]string{"query": `mutation {
+		updateEntity(id: "` + alice + `", properties: {nickname: "Al"}) { id }
+		deleteEntity(id: "` + alice + `")
+	}`})
+	if code := do(http.MethodPost, "/graphql", string(query), &gql); code != http.StatusOK || len(gql.Errors) > 0 || gql.Data.DeleteEntity != alice {
+		t.Fatalf("graphql: %d %+v", code, gql)
+	}
+}
+
+func TestRESTRoutingErrors(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	app := setupApp(logger)
+
+	cases := []struct {
+		method, path string
+		status       int
+		allow        string
+	}{
+		{http.MethodPost, "/api/v1/memories/1", http.StatusMethodNotAllowed, "DELETE, GET, HEAD, PATCH, PUT"},
+		{http.MethodDelete, "/api/v1/memories/1/history", http.StatusMethodNotAllowed, "GET, HEAD"},
+		{http.MethodPut, "/graphql", http.StatusMethodNotAllowed, "GET, HEAD, POST"},
+		{http.MethodGet, "/api/v1/memories/abc", http.StatusBadRequest, ""},
+		{http.MethodGet, "/api/v1/nothing", http.StatusNotFound, ""},
+	}
+	for _, tc := range cases {
+		resp, err := app.Test(httptest.NewRequest(tc.method, tc.path, nil), -1)
+		if err != nil {
+			t.Fatalf("%s %s: %v", tc.method, tc.path, err)
+		}
+		if resp.StatusCode != tc.status || resp.Header.Get("Allow") != tc.allow {
+			t.Fatalf("%s %s: got %d Allow=%q, want %d Allow=%q", tc.method, tc.path, resp.StatusCode, resp.Header.Get("Allow"), tc.status, tc.allow)
+		}
+	}
+}
+
+func TestRESTAuthentication(t *testing.T) {
+	t.Setenv("MEM0_AUTH_REQUIRED", "true")
+	t.Setenv("MEM0_ADMIN_API_KEY", "bootstrap")
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	app := setupApp(logger)
+
+	do := func(method, target, key, body string) *http.Response {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, target, err)
+		}
+		return resp
+	}
+	issue := func(userID int) string {
+		resp := do(http.MethodPost, "/api/v1/keys", "bootstrap", `{"name":"test","userID":`+strconv.Itoa(userID)+`}`)
+		var out struct {
+			Key string `json:"key"`
+		}
+		if resp.StatusCode != http.StatusOK || json.NewDecoder(resp.Body).Decode(&out) != nil || out.Key == "" {
+			t.Fatalf("issue key: %d", resp.StatusCode)
+		}
+		return out.Key
+	}
+
+	if resp := do(http.MethodGet, "/api/v1/memories/1", "", ""); resp.StatusCode != http.StatusUnauthorized || resp.Header.Get("WWW-Authenticate") == "" {
+		t.Fatalf("expected 401 with a challenge, got %d", resp.StatusCode)
+	}
+	if resp := do(http.MethodGet, "/api/v1/memories/1", "wrong", ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for an unknown key, got %d", resp.StatusCode)
+	}
+	if resp := do(http.MethodGet, "/healthz", "", ""); resp.StatusCode != http.StatusOK {
+		t.Fatalf("healthz needs no credentials, got %d", resp.StatusCode)
+	}
+	for _, target := range []string{"/api/v1/memories", "/graphql"} {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(`{"content":"x","vector":[1,0,0]}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Upgrade", "websocket")
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("upgrade headers: %v", err)
+		}
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("upgrade headers must not skip authentication of POST %s, got %d", target, resp.StatusCode)
+		}
+	}
+
+	alice, bob := issue(1), issue(2)
+	resp := do(http.MethodPost, "/api/v1/memories", alice, `{"content":"likes tea","vector":[1,0,0]}`)
+	var created struct {
+		ID int64 `json:"id"`
+	}
+	if resp.StatusCode != http.StatusOK || json.NewDecoder(resp.Body).Decode(&created) != nil {
+		t.Fatalf("create: %d", resp.StatusCode)
+	}
+	path := "/api/v1/memories/" + strconv.FormatInt(created.ID, 10)
+
+	if resp := do(http.MethodPost, "/api/v1/memories", bob, `{"userID":1,"content":"x","vector":[1,0,0]}`); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403 writing for another user, got %d", resp.StatusCode)
+	}
+	if resp := do(http.MethodGet, path, bob, ""); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 reading another user's memory, got %d", resp.StatusCode)
+	}
+	if resp := do(http.MethodGet, path, "bootstrap", ""); resp.StatusCode != http.StatusOK {
+		t.Fatalf("admin read: %d", resp.StatusCode)
+	}
+
+	resp = do(http.MethodGet, path, alice, "")
+	var m struct {
+		UserID int64 `json:"userID"`
+	}
+	if resp.StatusCode != http.StatusOK || json.NewDecoder(resp.Body).Decode(&m) != nil || m.UserID != 1 {
+		t.Fatalf("owner read: %d %+v", resp.StatusCode, m)
+	}
+	resp = do(http.MethodPost, "/api/v1/memories/search", bob, `{"vector":[1,0,0],"limit":10}`)
+	var found struct {
+		Results []json.RawMessage `json:"results"`
+	}
+	if resp.StatusCode != http.StatusOK || json.NewDecoder(resp.Body).Decode(&found) != nil || len(found.Results) != 0 {
+		t.Fatalf("search leaked memories: %d %d", resp.StatusCode, len(found.Results))
+	}
+	if resp := do(http.MethodPost, "/api/v1/keys", bob, `{"name":"x","scopes":["admin"]}`); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403 granting admin, got %d", resp.StatusCode)
+	}
+}
+
+func TestProjectIsolation(t *testing.T) {
+	t.Setenv("MEM0_AUTH_REQUIRED", "true")
+	t.Setenv("MEM0_ADMIN_API_KEY", "bootstrap")
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	app := setupApp(logger)
+
+	do := func(method, target, key, body string, out interface{}) int {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", key)
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, target, err)
+		}
+		if out != nil {
+			if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+				t.Fatalf("%s %s: decode: %v", method, target, err)
+			}
+		}
+		return resp.StatusCode
+	}
+	var org, project struct {
+		ID int64 `json:"id"`
+	}
+	if code := do(http.MethodPost, "/api/v1/orgs", "bootstrap", `{"name":"acme"}`, &org); code != http.StatusOK {
+		t.Fatalf("create org: %d", code)
+	}
+	if code := do(http.MethodPost, "/api/v1/orgs/"+strconv.FormatInt(org.ID, 10)+"/projects", "bootstrap", `{"name":"search"}`, &project); code != http.StatusOK {
+		t.Fatalf("create project: %d", code)
+	}
+	issue := func(body string) string {
+		var out struct {
+			Key string `json:"key"`
+		}
+		if code := do(http.MethodPost, "/api/v1/keys", "bootstrap", body, &out); code != http.StatusOK {
+			t.Fatalf("issue key: %d", code)
+		}
+		return out.Key
+	}
+	team := issue(`{"name":"team","userID":1,"projectID":` + strconv.FormatInt(project.ID, 10) + `}`)
+	other := issue(`{"name":"default","userID":1}`)
+
+	var created struct {
+		ID int64 `json:"id"`
+	}
+	if code := do(http.MethodPost, "/api/v1/memories", team, `{"content":"project secret","vector":[1,0,0]}`, &created); code != http.StatusOK {
+		t.Fatalf("create: %d", code)
+	}
+	path := "/api/v1/memories/" + strconv.FormatInt(created.ID, 10)
+	var m struct {
+		OrgID     int64 `json:"orgID"`
+		ProjectID int64 `json:"projectID"`
+	}
+	if code := do(http.MethodGet, path, team, "", &m); code != http.StatusOK || m.OrgID != org.ID || m.ProjectID != project.ID {
+		t.Fatalf("memory not tagged with its project: %d %+v", code, m)
+	}
+	if code := do(http.MethodGet, path, other, "", nil); code != http.StatusNotFound {
+		t.Fatalf("expected 404 from another project, got %d", code)
+	}
+	if code := do(http.MethodGet, path, "bootstrap", "", nil); code != http.StatusNotFound {
+		t.Fatalf("expected 404 for the operator outside the project, got %d", code)
+	}
+
+	var found struct {
+		Results []json.RawMessage `json:"results"`
+	}
+	if code := do(http.MethodPost, "/api/v1/memories/search", other, `{"vector":[1,0,0],"limit":10}`, &found); code != http.StatusOK || len(found.Results) != 0 {
+		t.Fatalf("search crossed projects: %d %d", code, len(found.Results))
+	}
+	if code := do(http.MethodPost, "/api/v1/memories/search", team, `{"vector":[1,0,0],"limit":10}`, &found); code != http.StatusOK || len(found.Results) != 1 {
+		t.Fatalf("project search: %d %d", code, len(found.Results))
+	}
+
+	var gql struct {
+		Data struct {
+			Entities []struct {
+				ID string `json:"id"`
+			} `json:"entities"`
+		} `json:"data"`
+	}
+	do(http.MethodPost, "/graphql", team, `{"query":"mutation { createEntity(label: \"Person\", properties: {name: \"ada\"}) { id } }"}`, nil)
+	if do(http.MethodPost, "/graphql", other, `{"query":"{ entities(label: \"Person\") { id } }"}`, &gql); len(gql.Data.Entities) != 0 {
+		t.Fatalf("entities crossed projects: %+v", gql.Data.Entities)
+	}
+	if do(http.MethodPost, "/graphql", team, `{"query":"{ entities(label: \"Person\") { id } }"}`, &gql); len(gql.Data.Entities) != 1 {
+		t.Fatalf("project entities: %+v", gql.Data.Entities)
+	}
+
+	if code := do(http.MethodPost, "/api/v1/orgs", team, `{"name":"rogue"}`, nil); code != http.StatusForbidden {
+		t.Fatalf("expected 403 creating an organization from a project, got %d", code)
+	}
+	var orgs struct {
+		Organizations []json.RawMessage `json:"organizations"`
+	}
+	if code := do(http.MethodGet, "/api/v1/orgs", team, "", &orgs); code != http.StatusOK || len(orgs.Organizations) != 1 {
+		t.Fatalf("list orgs: %d %d", code, len(orgs.Organizations))
+	}
+}
+
+func TestRoleBasedAccess(t *testing.T) {
+	t.Setenv("MEM0_AUTH_REQUIRED", "true")
+	t.Setenv("MEM0_ADMIN_API_KEY", "bootstrap")
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	app := setupApp(logger)
+
+	do := func(method, target, key, body string, out interface{}) int {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", key)
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, target, err)
+		}
+		if out != nil {
+			if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+				t.Fatalf("%s %s: decode: %v", method, target, err)
+			}
+		}
+		return resp.StatusCode
+	}
+	issue := func(body string) string {
+		var out struct {
+			Key string `json:"key"`
+		}
+		if code := do(http.MethodPost, "/api/v1/keys", "bootstrap", body, &out); code != http.StatusOK {
+			t.Fatalf("issue key: %d", code)
+		}
+		return out.Key
+	}
+	analyst := issue(`{"name":"analyst","userID":1,"scopes":["reader"]}`)
+	agent := issue(`{"name":"agent","userID":1}`)
+
+	var denied struct {
+		Error      string `json:"error"`
+		Permission string `json:"permission"`
+	}
+	if code := do(http.MethodPost, "/api/v1/memories", analyst, `{"content":"x","vector":[1,0,0]}`, &denied); code != http.StatusForbidden || denied.Permission != "memories:write" {
+		t.Fatalf("expected 403 naming memories:write for a reader, got %d %+v", code, denied)
+	}
+	var created struct {
+		ID int64 `json:"id"`
+	}
+	if code := do(http.MethodPost, "/api/v1/memories", agent, `{"content":"likes tea","vector":[1,0,0]}`, &created); code != http.StatusOK {
+		t.Fatalf("writer create: %d", code)
+	}
+	path := "/api/v1/memories/" + strconv.FormatInt(created.ID, 10)
+	if code := do(http.MethodGet, path, analyst, "", nil); code != http.StatusOK {
+		t.Fatalf("reader get: %d", code)
+	}
+	if code := do(http.MethodDelete, path, agent, "", &denied); code != http.StatusForbidden || denied.Permission != "memories:delete" {
+		t.Fatalf("expected 403 naming memories:delete for a writer, got %d %+v", code, denied)
+	}
+	if code := do(http.MethodPost, "/api/v1/roles", agent, `{"name":"curator","permissions":["memories:delete"]}`, &denied); code != http.StatusForbidden || denied.Permission != "roles:manage" {
+		t.Fatalf("expected 403 naming roles:manage, got %d %+v", code, denied)
+	}
+
+	if code := do(http.MethodPost, "/api/v1/roles", "bootstrap", `{"name":"curator","permissions":["memories:read","memories:write","memories:delete"]}`, nil); code != http.StatusOK {
+		t.Fatalf("create role: %d", code)
+	}
+	var binding struct {
+		ID int64 `json:"id"`
+	}
+	if code := do(http.MethodPost, "/api/v1/role-bindings", "bootstrap", `{"userID":1,"role":"curator"}`, &binding); code != http.StatusOK {
+		t.Fatalf("bind: %d", code)
+	}
+	var bindings struct {
+		Bindings []json.RawMessage `json:"bindings"`
+	}
+	if code := do(http.MethodGet, "/api/v1/role-bindings", agent, "", &bindings); code != http.StatusOK || len(bindings.Bindings) != 1 {
+		t.Fatalf("own bindings: %d %d", code, len(bindings.Bindings))
+	}
+	// The reader key stays read-only: its scopes narrow the user's roles.
+	if code := do(http.MethodDelete, path, analyst, "", nil); code != http.StatusForbidden {
+		t.Fatalf("expected the reader key to stay read-only, got %d", code)
+	}
+	if code := do(http.MethodDelete, path, agent, "", nil); code != http.StatusNoContent {
+		t.Fatalf("curator delete: %d", code)
+	}
+
+	var gql struct {
+		Errors []struct {
+			Extensions map[string]interface{} `json:"extensions"`
+		} `json:"errors"`
+	}
+	do(http.MethodPost, "/graphql", analyst, `{"query":"mutation { createEntity(label: \"Person\", properties: {name: \"ada\"}) { id } }"}`, &gql)
+	if len(gql.Errors) != 1 || gql.Errors[0].Extensions["permission"] != "graph:write" {
+		t.Fatalf("expected a GraphQL error naming graph:write, got %+v", gql.Errors)
+	}
+}
+
+func TestMergeRoutes(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	app := setupApp(logger)
+
+	do := func(method, path, body string, out interface{}) int {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		if out != nil {
+			if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+				t.Fatalf("%s %s: decode: %v", method, path, err)
+			}
+		}
+		return resp.StatusCode
+	}
+	create := func(body string) string {
+		t.Helper()
+		var out struct {
+			ID string `json:"id"`
+		}
+		if code := do(http.MethodPost, "/api/v1/entities", body, &out); code != http.StatusOK || out.ID == "" {
+			t.Fatalf("create %s: status %d", body, code)
+		}
+		return out.ID
+	}
+	bob := create(`{"label":"Person","properties":{"name":"Bob"}}`)
+	robert := create(`{"label":"Person","properties":{"name":"Robert Smith"}}`)
+
+	var dups []struct {
+		A       struct{ ID string } `json:"a"`
+		B       struct{ ID string } `json:"b"`
+		Score   float64             `json:"score"`
+		Reasons []string            `json:"reasons"`
+	}
+	if code := do(http.MethodGet, "/api/v1/graph/duplicates?label=Person", "", &dups); code != http.StatusOK || len(dups) != 1 || dups[0].Reasons[0] != "alias" {
+		t.Fatalf("duplicates: %d %+v", code, dups)
+	}
+	if code := do(http.MethodGet, "/api/v1/graph/duplicates?minScore=high", "", nil); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a bad minScore, got %d", code)
+	}
+
+	if code := do(http.MethodPost, "/api/v1/graph/merges", `{"survivor":"`+robert+`","merged":[]}`, nil); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an empty merge, got %d", code)
+	}
+	type record struct {
+		ID       string            `json:"id"`
+		Survivor string            `json:"survivor"`
+		UndoneAt string            `json:"undoneAt"`
+		Restored map[string]string `json:"restored"`
+	}
+	var rec record
+	if code := do(http.MethodPost, "/api/v1/graph/merges", `{"survivor":"`+robert+`","merged":["`+bob+`"]}`, &rec); code != http.StatusOK || rec.ID == "" {
+		t.Fatalf("merge: %d %+v", code, rec)
+	}
+	if code := do(http.MethodGet, "/api/v1/entities/"+bob, "", nil); code != http.StatusNotFound {
+		t.Fatalf("expected merged entity gone, got %d", code)
+	}
+	var recs []record
+	if code := do(http.MethodGet, "/api/v1/graph/merges", "", &recs); code != http.StatusOK || len(recs) != 1 || recs[0].Survivor != robert {
+		t.Fatalf("list: %d %+v", code, recs)
+	}
+	var undone record
+	if code := do(http.MethodPost, "/api/v1/graph/merges/"+rec.ID+"/undo", "", &undone); code != http.StatusOK || undone.UndoneAt == "" || undone.Restored[bob] == "" {
+		t.Fatalf("undo: %d %+v", code, undone)
+	}
+	if code := do(http.MethodGet, "/api/v1/entities/"+undone.Restored[bob], "", nil); code != http.StatusOK {
+		t.Fatalf("expected merged entity restored, got %d", code)
+	}
+	if code := do(http.MethodPost, "/api/v1/graph/merges/"+rec.ID+"/undo", "", nil); code != http.StatusConflict {
+		t.Fatalf("expected 409 undoing twice, got %d", code)
+	}
+	if code := do(http.MethodGet, "/api/v1/graph/merges/"+robert, "", nil); code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an entity ID, got %d", code)
+	}
+
+	var gql struct {
+		Data struct {
+			DuplicateEntities []struct {
+				Reasons []string `json:"reasons"`
+			} `json:"duplicateEntities"`
+			MergeEntities struct {
+				Survivor struct {
+					Properties map[string]interface{} `json:"properties"`
+				} `json:"survivor"`
+				Merged []struct {
+					ID string `json:"id"`
+				} `json:"merged"`
+			} `json:"mergeEntities"`
+		} `json:"data"`
+		Errors []interface{} `json:"errors"`
+	}
+	query, _ := json.Marshal(map[string]string{"query": `{ duplicateEntities(label: "Person") { reasons } }`})
+	if code := do(http.MethodPost, "/graphql", string(query), &gql); code != http.StatusOK || len(gql.Errors) > 0 || len(gql.Data.DuplicateEntities) != 1 {
+		t.Fatalf("graphql duplicates: %d %+v", code, gql)
+	}
+	query, _ = json.Marshal(map[string]string{"query": `mutation {
+		mergeEntities(survivor: "` + robert + `", merged: ["` + undone.Restored[bob] + `"]) {
+			survivor { properties }
+			merged { id }
+		}
+	}`})
+	if code := do(http.MethodPost, "/graphql", string(query), &gql); code != http.StatusOK || len(gql.Errors) > 0 || len(gql.Data.MergeEntities.Merged) != 1 || gql.Data.MergeEntities.Survivor.Properties["aliases"] == nil {
+		t.Fatalf("graphql merge: %d %+v", code, gql)
+	}
+}
@@ -0,0 +1,121 @@
+// Command migrator applies and reverts the Postgres schema migrations
+// embedded in the db package.
+//
+//	migrator up          apply every pending migration
+//	migrator down [n]    revert the last n migrations, 1 by default
+//	migrator goto V      migrate up or down to version V; 0 reverts all
+//	migrator status      list migrations and whether they are applied
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"mem0-go/internal/db"
+)
+
+// migrator is the part of *db.Migrator the commands use.
+type migrator interface {
+	Up(ctx context.Context) ([]int64, error)
+	Down(ctx context.Context, n int) ([]int64, error)
+	Goto(ctx context.Context, version int64) ([]int64, error)
+	Status(ctx context.Context) ([]db.MigrationStatus, error)
+}
+
+var errUsage = errors.New("usage: migrator up | down [n] | goto <version> | status")
+
+// run executes the command in args against m, writing what it did to w.
+func run(ctx context.Context, m migrator, args []string, w io.Writer) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	var done []int64
+	var err error
+	switch args[0] {
+	case "up":
+		done, err = m.Up(ctx)
+	case "down":
+		n := 1
+		if len(args) > 1 {
+			if n, err = strconv.Atoi(args[1]); err != nil || n < 1 {
+				return fmt.Errorf("invalid count %q", args[1])
+			}
+		}
+		done, err = m.Down(ctx, n)
+	case "goto":
+		if len(args) < 2 {
+			return errUsage
+		}
+		v, perr := strconv.ParseInt(args[1], 10, 64)
+		if perr != nil || v < 0 {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		done, err = m.Goto(ctx, v)
+	case "status":
+		return status(ctx, m, w)
+	default:
+		return errUsage
+	}
+	for _, v := range done {
+		fmt.Fprintf(w, "%s %06d\n", args[0], v)
+	}
+	if err == nil && len(done) == 0 {
+		fmt.Fprintln(w, "no change")
+	}
+	return err
+}
+
+// status prints one line per migration.
+func status(ctx context.Context, m migrator, w io.Writer) error {
+	list, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tNAME\tSTATE\tAPPLIED AT")
+	for _, s := range list {
+		state := "pending"
+		switch {
+		case s.Unknown:
+			state = "unknown"
+		case s.ChecksumMismatch:
+			state = "modified"
+		case s.Applied:
+			state = "applied"
+		}
+		fmt.Fprintf(tw, "%06d\t%s\t%s\t%s\n", s.Version, s.Name, state, s.AppliedAt)
+	}
+	return tw.Flush()
+}
+
+func main() {
+	flag.Usage = func() { fmt.Fprintln(flag.CommandLine.Output(), errUsage) }
+	flag.Parse()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	ctx := context.Background()
+	connCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	pool, err := db.Connect(connCtx, db.LoadConfig())
+	cancel()
+	if err != nil {
+		logger.Error("connect failed", "err", err)
+		os.Exit(1)
+	}
+	m, err := db.NewMigrator(pool)
+	if err == nil {
+		err = run(ctx, m, flag.Args(), os.Stdout)
+	}
+	pool.Close()
+	if err != nil {
+		logger.Error("migrate failed", "err", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"mem0-go/internal/db"
+)
+
+type fakeMigrator struct {
+	calls []string
+}
+
+func (f *fakeMigrator) Up(ctx context.Context) ([]int64, error) {
+	f.calls = append(f.calls, "up")
+	return []int64{1, 2}, nil
+}
+
+func (f *fakeMigrator) Down(ctx context.Context, n int) ([]int64, error) {
+	f.calls = append(f.calls, "down "+strings.Repeat("x", n))
+	return []int64{2}, nil
+}
+
+func (f *fakeMigrator) Goto(ctx context.Context, version int64) ([]int64, error) {
+	f.calls = append(f.calls, "goto")
+	return nil, nil
+}
+
+func (f *fakeMigrator) Status(ctx context.Context) ([]db.MigrationStatus, error) {
+	return []db.MigrationStatus{
+		{Version: 1, Name: "create_users", Applied: true, AppliedAt: "2024-01-01"},
+		{Version: 2, Name: "create_memories", Applied: true, ChecksumMismatch: true},
+		{Version: 3, Name: "create_embeddings"},
+	}, nil
+}
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	m := &fakeMigrator{}
+	var out bytes.Buffer
+	if err := run(ctx, m, []string{"up"}, &out); err != nil || out.String() != "up 000001\nup 000002\n" {
+		t.Fatalf("up: %q %v", out.String(), err)
+	}
+	out.Reset()
+	if err := run(ctx, m, []string{"down", "3"}, &out); err != nil || out.String() != "down 000002\n" {
+		t.Fatalf("down: %q %v", out.String(), err)
+	}
+	out.Reset()
+	if err := run(ctx, m, []string{"goto", "0"}, &out); err != nil || out.String() != "no change\n" {
+		t.Fatalf("goto: %q %v", out.String(), err)
+	}
+	if strings.Join(m.calls, ",") != "up,down xxx,goto" {
+		t.Fatalf("calls: %v", m.calls)
+	}
+
+	out.Reset()
+	if err := run(ctx, m, []string{"status"}, &out); err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 || !strings.Contains(lines[2], "modified") || !strings.Contains(lines[3], "pending") {
+		t.Fatalf("status output:\n%s", out.String())
+	}
+
+	for _, args := range [][]string{nil, {"sideways"}, {"goto"}} {
+		if err := run(ctx, m, args, &out); !errors.Is(err, errUsage) {
+			t.Fatalf("%v: expected usage error, got %v", args, err)
+		}
+	}
+	if err := run(ctx, m, []string{"down", "0"}, &out); err == nil {
+		t.Fatalf("expected an error for a zero count")
+	}
+}
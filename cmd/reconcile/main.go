@@ -0,0 +1,105 @@
+// Command reconcile checks Postgres, the vector collection and the graph
+// for drift and optionally repairs it from Postgres. It prints a JSON
+// report and exits with status 2 when drift remains.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"mem0-go/internal/auth"
+	"mem0-go/internal/config"
+	"mem0-go/internal/db"
+	"mem0-go/internal/embedding"
+	"mem0-go/internal/graph"
+	"mem0-go/internal/hnsw"
+	"mem0-go/internal/memory"
+	"mem0-go/internal/vector"
+)
+
+// run reconciles svc, writes the JSON report to w and returns the exit
+// status.
+func run(ctx context.Context, svc *memory.Service, opts memory.ReconcileOptions, w io.Writer) (int, error) {
+	rep, err := svc.Reconcile(ctx, opts)
+	if err != nil {
+		return 1, err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(rep); err != nil {
+		return 1, err
+	}
+	if rep.Drift() && !rep.Repaired {
+		return 2, nil
+	}
+	return 0, nil
+}
+
+// newService connects to the configured stores. The returned function
+// persists local state and must be called once reconciliation is done.
+func newService(ctx context.Context, cfg config.Config, emb embedding.Embedder) (*memory.Service, func() error, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	pool, err := db.Connect(ctx, db.LoadConfig())
+	if err != nil {
+		return nil, nil, err
+	}
+	g, err := graph.Connect(ctx, graph.LoadConfig())
+	if err != nil {
+		return nil, nil, err
+	}
+	repo := db.NewRepository(pool)
+	opts := []memory.Option{memory.WithEmbedder(emb)}
+	switch cfg.VectorBackend {
+	case "qdrant":
+		vec, err := vector.Connect(ctx, vector.LoadConfig())
+		if err != nil {
+			return nil, nil, err
+		}
+		return memory.NewService(repo, vec, g, opts...), func() error { return nil }, nil
+	case "hnsw":
+		hcfg := hnsw.LoadConfig()
+		hcfg.Distance = vector.LoadConfig().Distance
+		store, err := hnsw.Open(hcfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		return memory.NewService(repo, store, g, opts...), store.Close, nil
+	default:
+		return nil, nil, fmt.Errorf("vector backend %q is not persistent; use qdrant or hnsw", cfg.VectorBackend)
+	}
+}
+
+func main() {
+	repair := flag.Bool("repair", false, "repair drift from Postgres instead of only reporting it")
+	dim := flag.Int("dim", 0, "expected vector size; defaults to the embedder's dimension")
+	flag.Parse()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	ctx := auth.Internal(context.Background())
+	emb := embedding.New(embedding.LoadConfig())
+	if *dim == 0 {
+		*dim = emb.Dimension()
+	}
+
+	svc, done, err := newService(ctx, config.Load(), emb)
+	if err != nil {
+		logger.Error("setup failed", "err", err)
+		os.Exit(1)
+	}
+	code, err := run(ctx, svc, memory.ReconcileOptions{Repair: *repair, Dimension: *dim}, os.Stdout)
+	if err != nil {
+		logger.Error("reconcile failed", "err", err)
+	}
+	if err := done(); err != nil {
+		logger.Error("save failed", "err", err)
+		code = 1
+	}
+	os.Exit(code)
+}
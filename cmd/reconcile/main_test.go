@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"mem0-go/internal/auth"
+	"mem0-go/internal/embedding"
+	"mem0-go/internal/inmem"
+	"mem0-go/internal/memory"
+	"mem0-go/internal/vector"
+)
+
+func TestReconcileReportsAndRepairsDrift(t *testing.T) {
+	ctx := auth.Internal(context.Background())
+	repo := inmem.NewRepo()
+	vec := inmem.NewVector()
+	g := inmem.NewGraph()
+	svc := memory.NewService(repo, vec, g, memory.WithEmbedder(embedding.NewHashing(8)))
+
+	for _, text := range []string{"likes tea", "lives in Paris", "plays chess"} {
+		if _, err := svc.StoreMemory(ctx, 1, text, nil); err != nil {
+			t.Fatalf("store: %v", err)
+		}
+	}
+	// drift: a lost point, an orphan point, a wrong-sized point, a point
+	// tagged with another project, a lost node and a dangling edge
+	pts, _, _ := vec.Scroll(ctx, memory.Collection, "", 10)
+	for _, p := range pts {
+		if p.ID == "2" {
+			p.Payload = map[string]interface{}{"user_id": int64(1), "project_id": int64(9)}
+			_ = vec.Upsert(ctx, memory.Collection, []vector.Point{p})
+		}
+	}
+	_ = vec.Delete(ctx, memory.Collection, []string{"1"})
+	_ = vec.Upsert(ctx, memory.Collection, []vector.Point{{ID: "42", Vector: make([]float32, 8)}, {ID: "3", Vector: []float32{1}}})
+	nodes, _ := g.FindNodes(ctx, memory.MemoryLabel, map[string]interface{}{"memory_id": int64(2)})
+	_ = g.DeleteNode(ctx, nodes[0].ID)
+	_, _ = g.CreateEdge(ctx, "n1", "missing", "KNOWS", nil)
+
+	var out bytes.Buffer
+	code, err := run(ctx, svc, memory.ReconcileOptions{Dimension: 8}, &out)
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if code != 2 {
+		t.Fatalf("expected drift exit code, got %d", code)
+	}
+	var rep memory.ReconcileReport
+	if err := json.Unmarshal(out.Bytes(), &rep); err != nil {
+		t.Fatalf("decode report: %v\n%s", err, out.String())
+	}
+	if len(rep.MissingPoints) != 1 || rep.MissingPoints[0] != 1 {
+		t.Fatalf("missing points: %v", rep.MissingPoints)
+	}
+	if len(rep.OrphanPoints) != 1 || rep.OrphanPoints[0] != "42" {
+		t.Fatalf("orphan points: %v", rep.OrphanPoints)
+	}
+	if len(rep.StalePoints) != 1 || rep.StalePoints[0] != 2 {
+		t.Fatalf("stale points: %v", rep.StalePoints)
+	}
+	if len(rep.DimensionMismatches) != 1 || rep.DimensionMismatches[0].MemoryID != 3 || rep.DimensionMismatches[0].Point != 1 {
+		t.Fatalf("dimension mismatches: %+v", rep.DimensionMismatches)
+	}
+	if len(rep.MissingNodes) != 1 || rep.MissingNodes[0] != 2 {
+		t.Fatalf("missing nodes: %v", rep.MissingNodes)
+	}
+	if len(rep.DanglingEdges) != 1 || rep.Repaired {
+		t.Fatalf("dangling edges %v, repaired %v", rep.DanglingEdges, rep.Repaired)
+	}
+	if res, _ := vec.Query(ctx, memory.Collection, make([]float32, 8), 0, nil); len(res) != 2 {
+		t.Fatalf("dry run changed the vector store: %d points", len(res))
+	}
+
+	out.Reset()
+	if code, err := run(ctx, svc, memory.ReconcileOptions{Repair: true, Dimension: 8}, &out); err != nil || code != 0 {
+		t.Fatalf("repair: code %d, err %v", code, err)
+	}
+	out.Reset()
+	if code, err := run(ctx, svc, memory.ReconcileOptions{Dimension: 8}, &out); err != nil || code != 0 {
+		t.Fatalf("drift remains after repair: code %d, err %v\n%s", code, err, out.String())
+	}
+}
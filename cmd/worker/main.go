@@ -1,12 +1,23 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	workers "github.com/jrallison/go-workers"
+
+	"mem0-go/internal/auth"
+	"mem0-go/internal/db"
+	"mem0-go/internal/extract"
+	"mem0-go/internal/graph"
+	"mem0-go/internal/memory"
+	"mem0-go/internal/outbox"
+	"mem0-go/internal/vector"
 )
 
 var logger = slog.New(slog.NewTextHandler(os.Stdout, nil))
@@ -15,8 +26,84 @@ func embeddingJob(msg *workers.Msg) {
 	logger.Info("embedding job", "args", msg.Args())
 }
 
+// linksQueue is the queue link jobs run on.
+const linksQueue = "links"
+
+// linker links memories to the entities they mention; main sets it to the
+// memory service.
+var linker interface {
+	LinkMemory(ctx context.Context, id int64) (memory.LinkResult, error)
+}
+
+// linkJob links the memory whose ID is the job's first argument to its
+// entities. Failures panic so the job is retried.
 func linkJob(msg *workers.Msg) {
-	logger.Info("link job", "args", msg.Args())
+	args := msg.Args()
+	id, ok := int64(0), false
+	if len(args) > 0 {
+		id, ok = memoryID(args[0])
+	}
+	if !ok || linker == nil {
+		logger.Error("link job: invalid arguments", "args", args)
+		return
+	}
+	res, err := linker.LinkMemory(auth.Internal(context.Background()), id)
+	if err != nil {
+		logger.Error("link job failed", "memory_id", id, "err", err)
+		panic(err)
+	}
+	logger.Info("link job", "memory_id", id, "entities", len(res.Entities), "relationships", len(res.Relationships))
+}
+
+// memoryID reads a memory ID job argument as it was decoded from JSON.
+func memoryID(v interface{}) (int64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return v, true
+	case int:
+		return int64(v), true
+	case float64:
+		return int64(v), v == float64(int64(v))
+	case json.Number:
+		id, err := v.Int64()
+		return id, err == nil
+	}
+	return 0, false
+}
+
+// linkQueue enqueues link jobs for stored memories.
+type linkQueue struct{}
+
+func (linkQueue) EnqueueLink(_ context.Context, id int64) error {
+	_, err := workers.Enqueue(linksQueue, "link", []interface{}{id})
+	return err
+}
+
+// newDispatcher connects to Postgres, Qdrant and Neo4j and returns a
+// dispatcher applying the outbox to the vector and graph stores, and the
+// memory service behind it, which queues link jobs for the memories it
+// applies.
+func newDispatcher(ctx context.Context) (*outbox.Dispatcher, *memory.Service, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	pool, err := db.Connect(ctx, db.LoadConfig())
+	if err != nil {
+		return nil, nil, err
+	}
+	vec, err := vector.Connect(ctx, vector.LoadConfig())
+	if err != nil {
+		return nil, nil, err
+	}
+	g, err := graph.Connect(ctx, graph.LoadConfig())
+	if err != nil {
+		return nil, nil, err
+	}
+	repo := db.NewRepository(pool)
+	svc := memory.NewService(repo, vec, g, memory.WithOutbox(),
+		memory.WithExtractor(extract.New(extract.LoadConfig())), memory.WithLinkQueue(linkQueue{}))
+	d := outbox.New(repo, svc.ApplyEvent, outbox.LoadConfig())
+	d.Logger = logger
+	return d, svc, nil
 }
 
 func main() {
@@ -32,12 +119,26 @@ func main() {
 	})
 
 	workers.Process("embeddings", embeddingJob, 1)
-	workers.Process("links", linkJob, 1)
+	workers.Process(linksQueue, linkJob, 1)
+
+	ctx, stop := signal.NotifyContext(auth.Internal(context.Background()), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	d, svc, err := newDispatcher(ctx)
+	if err != nil {
+		logger.Error("outbox setup failed", "err", err)
+		os.Exit(1)
+	}
+	linker = svc
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = d.Run(ctx)
+	}()
 
 	go workers.Run()
 
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
-	<-sig
+	<-ctx.Done()
 	workers.Quit()
+	<-done
 }
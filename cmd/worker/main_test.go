@@ -1,9 +1,16 @@
 package main
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	workers "github.com/jrallison/go-workers"
+
+	"mem0-go/internal/auth"
+	"mem0-go/internal/extract"
+	"mem0-go/internal/inmem"
+	"mem0-go/internal/memory"
 )
 
 func TestEmbeddingJob(t *testing.T) {
@@ -15,3 +22,55 @@ func TestLinkJob(t *testing.T) {
 	msg := workers.NewMsg([]interface{}{"a", "b"})
 	linkJob(msg)
 }
+
+func TestLinkJobLinksMemory(t *testing.T) {
+	ctx := auth.Internal(context.Background())
+	repo, vec, g := inmem.NewRepo(), inmem.NewVector(), inmem.NewGraph()
+	id, err := memory.NewService(repo, vec, g).StoreMemory(ctx, 1, "Alice works at Acme", []float32{1, 0})
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	svc := memory.NewService(repo, vec, g, memory.WithExtractor(extract.NewRules()))
+	linker = svc
+	defer func() { linker = nil }()
+
+	// Job arguments come back from Redis as JSON numbers.
+	linkJob(workers.NewMsg([]interface{}{float64(id)}))
+	nodes, err := svc.FindEntities(ctx, "Organization", map[string]interface{}{"name": "Acme"})
+	if err != nil || len(nodes) != 1 {
+		t.Fatalf("expected Acme to be linked, got %+v, %v", nodes, err)
+	}
+}
+
+func TestStoredMemoriesAreLinkedThroughTheQueue(t *testing.T) {
+	ctx := auth.Internal(context.Background())
+	svc := memory.NewService(inmem.NewRepo(), inmem.NewVector(), inmem.NewGraph(),
+		memory.WithExtractor(extract.NewRules()), memory.WithLinkQueue(linkQueue{}))
+	linker = svc
+	defer func() { linker = nil }()
+	workers.Process(linksQueue, linkJob, 1)
+
+	if _, err := svc.StoreMemory(ctx, 1, "Alice works at Acme", []float32{1, 0}); err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	// The job waits in the queue until the workers run.
+	if nodes, _ := svc.FindEntities(ctx, "Organization", nil); len(nodes) != 0 {
+		t.Fatalf("linked before the workers ran: %+v", nodes)
+	}
+	go workers.Run()
+	defer workers.Quit()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		nodes, err := svc.FindEntities(ctx, "Organization", map[string]interface{}{"name": "Acme"})
+		if err != nil {
+			t.Fatalf("find: %v", err)
+		}
+		if len(nodes) == 1 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the link job never linked the stored memory")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
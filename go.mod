@@ -4,6 +4,7 @@ go 1.24.3
 
 require (
 	github.com/gofiber/fiber/v2 v2.52.0
+	github.com/gofiber/websocket/v2 v2.2.1
 	github.com/jackc/pgx/v5 v5.5.0
 	github.com/jrallison/go-workers v0.0.0
 	mem0-go/internal/observability v0.0.0
@@ -11,6 +12,8 @@ require (
 
 replace github.com/gofiber/fiber/v2 => ./internal/fiber
 
+replace github.com/gofiber/websocket/v2 => ./internal/websocket
+
 replace github.com/jackc/pgx/v5 => ./internal/pgx
 
 replace github.com/jrallison/go-workers => ./internal/workers
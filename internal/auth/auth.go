@@ -0,0 +1,395 @@
+// Package auth authenticates API callers with API keys or JWTs and carries
+// the resulting principal in the request context.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/websocket/v2"
+
+	"mem0-go/internal/db"
+)
+
+// ScopeAdmin grants a principal every permission in its project,
+// regardless of its role bindings.
+const ScopeAdmin = "admin"
+
+var (
+	// ErrUnauthenticated is returned when credentials are missing or
+	// invalid.
+	ErrUnauthenticated = errors.New("auth: unauthenticated")
+	// ErrForbidden is returned when the principal may not do what it
+	// asked.
+	ErrForbidden = errors.New("auth: forbidden")
+	// ErrUserRequired is returned when a key is issued without a user.
+	ErrUserRequired = errors.New("auth: userID required")
+)
+
+// Principal is an authenticated caller. UserID is the user it acts as;
+// it is 0 for the bootstrap admin key and for tokens without a user.
+// OrgID and ProjectID are the tenant its credentials belong to; every
+// call it makes is confined to that project, or to the default project
+// when ProjectID is 0. Permissions are resolved from its roles when it
+// authenticates.
+type Principal struct {
+	UserID    int64    `json:"userID"`
+	Subject   string   `json:"subject"`
+	Scopes    []string `json:"scopes"`
+	OrgID     int64    `json:"orgID,omitempty"`
+	ProjectID int64    `json:"projectID,omitempty"`
+	// KeyID is the API key used, or 0.
+	KeyID       int64    `json:"keyID,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+}
+
+// HasScope reports whether p was granted scope.
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Admin reports whether p may act for every user: it holds
+// users:manage, as the admin role does.
+func (p *Principal) Admin() bool { return p.Can(PermUsersManage) }
+
+// Operator reports whether p administers the whole deployment: an admin
+// outside any project, such as the bootstrap admin key. Only operators
+// manage organizations and projects. A nil principal, which FromContext
+// only returns for internal calls, is an operator.
+func (p *Principal) Operator() bool { return p == nil || (p.Admin() && p.ProjectID == 0) }
+
+type (
+	principalKey struct{}
+	internalKey  struct{}
+)
+
+// WithPrincipal returns ctx carrying p.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// Internal returns ctx marked as an internal call, such as a worker's or a
+// request to a server that does not require credentials. Internal calls
+// without a principal hold every permission in the default project.
+func Internal(ctx context.Context) context.Context {
+	return context.WithValue(ctx, internalKey{}, true)
+}
+
+// FromContext returns the principal in ctx. Without one it returns nil
+// for internal calls and otherwise an anonymous principal holding no
+// permissions, so a call nobody vouched for is denied rather than
+// trusted.
+func FromContext(ctx context.Context) *Principal {
+	if p, _ := ctx.Value(principalKey{}).(*Principal); p != nil {
+		return p
+	}
+	if internal, _ := ctx.Value(internalKey{}).(bool); internal {
+		return nil
+	}
+	return &Principal{Subject: "anonymous", Permissions: []string{}}
+}
+
+// Config selects the accepted credentials.
+type Config struct {
+	// Required rejects requests without credentials. Otherwise they run
+	// unauthenticated and unscoped.
+	Required bool
+	// AdminKey is a bootstrap API key with the admin scope, for issuing
+	// the first user keys.
+	AdminKey string
+	// JWTSecret verifies HS256 tokens.
+	JWTSecret string
+	// JWKSFile is a JSON Web Key Set file with RS256 (RSA) and HS256
+	// (oct) keys, selected by the token's kid.
+	JWKSFile string
+	// Issuer and Audience, when set, must match the iss and aud claims.
+	Issuer   string
+	Audience string
+}
+
+// LoadConfig reads settings from environment variables.
+func LoadConfig() Config {
+	return Config{
+		Required:  os.Getenv("MEM0_AUTH_REQUIRED") == "true",
+		AdminKey:  os.Getenv("MEM0_ADMIN_API_KEY"),
+		JWTSecret: os.Getenv("MEM0_JWT_SECRET"),
+		JWKSFile:  os.Getenv("MEM0_JWT_JWKS_FILE"),
+		Issuer:    os.Getenv("MEM0_JWT_ISSUER"),
+		Audience:  os.Getenv("MEM0_JWT_AUDIENCE"),
+	}
+}
+
+// Authenticator checks credentials and resolves the permissions they
+// carry. A nil Authenticator accepts every request unauthenticated.
+type Authenticator struct {
+	cfg   Config
+	keys  db.APIKeys
+	roles db.Roles
+	jwt   *verifier
+	admin []byte
+}
+
+// Option configures an Authenticator.
+type Option func(*Authenticator)
+
+// WithRoles resolves principals' permissions from the roles and bindings
+// in r. Without it every principal gets the roles its scopes name, or the
+// writer role.
+func WithRoles(r db.Roles) Option {
+	return func(a *Authenticator) { a.roles = r }
+}
+
+// New returns an Authenticator looking API keys up in keys, which may be
+// nil to accept only JWTs and the admin key.
+func New(cfg Config, keys db.APIKeys, opts ...Option) (*Authenticator, error) {
+	a := &Authenticator{cfg: cfg, keys: keys}
+	for _, o := range opts {
+		o(a)
+	}
+	if cfg.AdminKey != "" {
+		h := sha256.Sum256([]byte(cfg.AdminKey))
+		a.admin = h[:]
+	}
+	if cfg.JWTSecret != "" || cfg.JWKSFile != "" {
+		v, err := newVerifier(cfg)
+		if err != nil {
+			return nil, err
+		}
+		a.jwt = v
+	}
+	return a, nil
+}
+
+// Authenticate returns the principal for the credentials in h: a bearer
+// token in Authorization, or an API key in X-API-Key. Without credentials
+// it returns nil, or ErrUnauthenticated when they are required.
+func (a *Authenticator) Authenticate(ctx context.Context, h http.Header) (*Principal, error) {
+	if a == nil {
+		return nil, nil
+	}
+	p, err := a.authenticate(ctx, h)
+	if p == nil || err != nil {
+		return p, err
+	}
+	if err := a.authorize(ctx, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (a *Authenticator) authenticate(ctx context.Context, h http.Header) (*Principal, error) {
+	token := h.Get("X-API-Key")
+	if bearer, ok := cutPrefixFold(h.Get("Authorization"), "Bearer "); ok {
+		token = strings.TrimSpace(bearer)
+	}
+	switch {
+	case token == "":
+		if a.cfg.Required {
+			return nil, fmt.Errorf("%w: missing credentials", ErrUnauthenticated)
+		}
+		return nil, nil
+	case strings.Count(token, ".") == 2:
+		if a.jwt == nil {
+			return nil, fmt.Errorf("%w: tokens are not accepted", ErrUnauthenticated)
+		}
+		return a.jwt.verify(token, time.Now())
+	default:
+		return a.apiKey(ctx, token)
+	}
+}
+
+func (a *Authenticator) apiKey(ctx context.Context, key string) (*Principal, error) {
+	hash := sha256.Sum256([]byte(key))
+	if a.admin != nil && subtle.ConstantTimeCompare(hash[:], a.admin) == 1 {
+		return &Principal{Subject: "admin", Scopes: []string{ScopeAdmin}}, nil
+	}
+	if a.keys == nil {
+		return nil, fmt.Errorf("%w: invalid api key", ErrUnauthenticated)
+	}
+	k, err := a.keys.APIKeyByHash(ctx, hex.EncodeToString(hash[:]))
+	if errors.Is(err, db.ErrNotFound) {
+		return nil, fmt.Errorf("%w: invalid api key", ErrUnauthenticated)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Principal{
+		UserID:    k.UserID,
+		Subject:   fmt.Sprintf("user:%d", k.UserID),
+		Scopes:    k.Scopes,
+		OrgID:     k.OrgID,
+		ProjectID: k.ProjectID,
+		KeyID:     k.ID,
+	}, nil
+}
+
+func cutPrefixFold(s, prefix string) (string, bool) {
+	if len(s) < len(prefix) || !strings.EqualFold(s[:len(prefix)], prefix) {
+		return "", false
+	}
+	return s[len(prefix):], true
+}
+
+// Middleware authenticates requests, storing the principal in the user
+// context; requests without credentials, which only pass when they are
+// not required, are marked internal. Failures get 401. WebSocket upgrades
+// of the paths in upgrades pass through untouched, since browsers cannot
+// set headers on them; those routes authenticate in their own handshake.
+func Middleware(a *Authenticator, upgrades ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Method() == http.MethodGet && websocket.IsWebSocketUpgrade(c) {
+			for _, path := range upgrades {
+				if c.Path() == path {
+					return c.Next()
+				}
+			}
+		}
+		p, err := a.Authenticate(c.UserContext(), c.Request.Header)
+		if errors.Is(err, ErrUnauthenticated) {
+			c.Set("WWW-Authenticate", `Bearer realm="mem0"`)
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err != nil {
+			return err
+		}
+		if p != nil {
+			c.SetUserContext(WithPrincipal(c.UserContext(), p))
+		} else {
+			c.SetUserContext(Internal(c.UserContext()))
+		}
+		return c.Next()
+	}
+}
+
+// keyPrefix starts every generated key, so leaked keys are easy to spot.
+const keyPrefix = "m0_"
+
+// CreateKey issues an API key from k's UserID, Name, Scopes, OrgID and
+// ProjectID and returns it with the plaintext key, which is not stored. A
+// caller that is not an admin may only issue keys for itself, and a
+// UserID of 0 means the caller's own user; only admins may grant the admin
+// scope, and others may only grant roles and permissions they hold.
+// Callers in a project may only issue keys for it; operators may issue
+// them for any project, and must give its organization.
+func (a *Authenticator) CreateKey(ctx context.Context, k db.APIKey) (db.APIKey, string, error) {
+	if a == nil || a.keys == nil {
+		return db.APIKey{}, "", errors.New("auth: api keys are not enabled")
+	}
+	if p := FromContext(ctx); p != nil {
+		if !p.Admin() {
+			if k.UserID == 0 {
+				k.UserID = p.UserID
+			}
+			if k.UserID != p.UserID || p.UserID == 0 {
+				return db.APIKey{}, "", ErrForbidden
+			}
+			for _, s := range k.Scopes {
+				if s == ScopeAdmin {
+					return db.APIKey{}, "", ErrForbidden
+				}
+			}
+		}
+		if !p.Operator() {
+			if k.ProjectID != 0 && k.ProjectID != p.ProjectID {
+				return db.APIKey{}, "", ErrForbidden
+			}
+			k.OrgID, k.ProjectID = p.OrgID, p.ProjectID
+		}
+		if !p.Admin() {
+			roles, err := a.projectRoles(ctx, k.OrgID, k.ProjectID)
+			if err != nil {
+				return db.APIKey{}, "", err
+			}
+			if err := checkGrant(ctx, scopePermissions(k.Scopes, roles)); err != nil {
+				return db.APIKey{}, "", err
+			}
+		}
+	}
+	if k.UserID <= 0 {
+		return db.APIKey{}, "", ErrUserRequired
+	}
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return db.APIKey{}, "", err
+	}
+	key := keyPrefix + base64.RawURLEncoding.EncodeToString(raw)
+	hash := sha256.Sum256([]byte(key))
+	k.ID, k.Prefix, k.Hash, k.CreatedAt, k.RevokedAt = 0, key[:len(keyPrefix)+8], hex.EncodeToString(hash[:]), "", ""
+	if k.Scopes == nil {
+		k.Scopes = []string{}
+	}
+	id, err := a.keys.CreateAPIKey(ctx, k)
+	if err != nil {
+		return db.APIKey{}, "", err
+	}
+	k.ID = id
+	return k, key, nil
+}
+
+// ListKeys returns the keys of userID, or of every user when userID is 0.
+// Callers that are not admins only see their own keys, and callers in a
+// project only that project's.
+func (a *Authenticator) ListKeys(ctx context.Context, userID int64) ([]db.APIKey, error) {
+	if a == nil || a.keys == nil {
+		return []db.APIKey{}, nil
+	}
+	p := FromContext(ctx)
+	if p != nil && !p.Admin() {
+		if p.UserID == 0 || (userID != 0 && userID != p.UserID) {
+			return nil, ErrForbidden
+		}
+		userID = p.UserID
+	}
+	keys, err := a.keys.ListAPIKeys(ctx, userID)
+	if err != nil || p.Operator() {
+		return keys, err
+	}
+	out := []db.APIKey{}
+	for _, k := range keys {
+		if k.ProjectID == p.ProjectID {
+			out = append(out, k)
+		}
+	}
+	return out, nil
+}
+
+// RevokeKey revokes key id. Callers may only revoke keys ListKeys shows
+// them.
+func (a *Authenticator) RevokeKey(ctx context.Context, id int64) error {
+	if a == nil || a.keys == nil {
+		return db.ErrNotFound
+	}
+	if p := FromContext(ctx); !p.Operator() {
+		keys, err := a.ListKeys(ctx, 0)
+		if errors.Is(err, ErrForbidden) {
+			return db.ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		found := false
+		for _, k := range keys {
+			found = found || k.ID == id
+		}
+		if !found {
+			return db.ErrNotFound
+		}
+	}
+	return a.keys.RevokeAPIKey(ctx, id)
+}
@@ -0,0 +1,326 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"mem0-go/internal/db"
+	"mem0-go/internal/inmem"
+)
+
+func bearer(token string) http.Header {
+	return http.Header{"Authorization": {"Bearer " + token}}
+}
+
+func sign(t *testing.T, header, claims map[string]interface{}, signer func([]byte) []byte) string {
+	t.Helper()
+	enc := func(v interface{}) string {
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+	signed := enc(header) + "." + enc(claims)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signer([]byte(signed)))
+}
+
+func hs256(secret string) func([]byte) []byte {
+	return func(b []byte) []byte {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(b)
+		return mac.Sum(nil)
+	}
+}
+
+func TestAPIKeys(t *testing.T) {
+	ctx := context.Background()
+	repo := inmem.NewRepo()
+	a, err := New(Config{Required: true, AdminKey: "bootstrap"}, repo)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+
+	if _, err := a.Authenticate(ctx, http.Header{}); !errors.Is(err, ErrUnauthenticated) {
+		t.Fatalf("expected ErrUnauthenticated without credentials, got %v", err)
+	}
+	admin, err := a.Authenticate(ctx, http.Header{"X-Api-Key": {"bootstrap"}})
+	if err != nil || !admin.Admin() {
+		t.Fatalf("admin key: %+v %v", admin, err)
+	}
+
+	k, key, err := a.CreateKey(WithPrincipal(ctx, admin), db.APIKey{UserID: 7, Name: "laptop", Scopes: []string{"memories"}})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if k.Prefix != key[:11] || k.Hash == key {
+		t.Fatalf("unexpected key record %+v", k)
+	}
+	p, err := a.Authenticate(ctx, bearer(key))
+	if err != nil || p.UserID != 7 || p.KeyID != k.ID || p.Admin() {
+		t.Fatalf("user key: %+v %v", p, err)
+	}
+
+	user := WithPrincipal(ctx, p)
+	if _, _, err := a.CreateKey(user, db.APIKey{UserID: 8, Name: "other"}); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("expected ErrForbidden issuing for another user, got %v", err)
+	}
+	if _, _, err := a.CreateKey(user, db.APIKey{Name: "escalate", Scopes: []string{ScopeAdmin}}); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("expected ErrForbidden granting admin, got %v", err)
+	}
+	own, _, err := a.CreateKey(user, db.APIKey{Name: "phone"})
+	if err != nil || own.UserID != 7 {
+		t.Fatalf("own key: %+v %v", own, err)
+	}
+	if keys, err := a.ListKeys(user, 0); err != nil || len(keys) != 2 {
+		t.Fatalf("list: %v %v", keys, err)
+	}
+
+	other, _, err := a.CreateKey(WithPrincipal(ctx, admin), db.APIKey{UserID: 8, Name: "other"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := a.RevokeKey(user, other.ID); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound revoking another user's key, got %v", err)
+	}
+	if err := a.RevokeKey(user, k.ID); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	if _, err := a.Authenticate(ctx, bearer(key)); !errors.Is(err, ErrUnauthenticated) {
+		t.Fatalf("revoked key accepted: %v", err)
+	}
+}
+
+func TestProjectKeys(t *testing.T) {
+	ctx := context.Background()
+	a, err := New(Config{AdminKey: "bootstrap"}, inmem.NewRepo())
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	operator, _ := a.Authenticate(ctx, http.Header{"X-Api-Key": {"bootstrap"}})
+	if !operator.Operator() {
+		t.Fatalf("bootstrap key is not an operator: %+v", operator)
+	}
+
+	_, key, err := a.CreateKey(WithPrincipal(ctx, operator), db.APIKey{UserID: 7, Name: "team", OrgID: 1, ProjectID: 3, Scopes: []string{ScopeAdmin}})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, _, err := a.CreateKey(WithPrincipal(ctx, operator), db.APIKey{UserID: 8, Name: "default"}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	p, err := a.Authenticate(ctx, bearer(key))
+	if err != nil || p.OrgID != 1 || p.ProjectID != 3 || !p.Admin() || p.Operator() {
+		t.Fatalf("project key: %+v %v", p, err)
+	}
+
+	team := WithPrincipal(ctx, p)
+	if _, _, err := a.CreateKey(team, db.APIKey{UserID: 8, Name: "elsewhere", ProjectID: 4}); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("expected ErrForbidden issuing into another project, got %v", err)
+	}
+	k, _, err := a.CreateKey(team, db.APIKey{UserID: 8, Name: "member"})
+	if err != nil || k.ProjectID != 3 || k.OrgID != 1 {
+		t.Fatalf("key not issued into the caller's project: %+v %v", k, err)
+	}
+	if keys, err := a.ListKeys(team, 0); err != nil || len(keys) != 2 {
+		t.Fatalf("expected the project's two keys, got %v %v", keys, err)
+	}
+	if err := a.RevokeKey(team, 2); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound revoking a key of the default project, got %v", err)
+	}
+
+	hs, _ := New(Config{JWTSecret: "s3cret"}, nil)
+	token := sign(t, map[string]interface{}{"alg": "HS256"}, map[string]interface{}{"sub": "9", "org_id": 2, "project_id": "5"}, hs256("s3cret"))
+	if p, err := hs.Authenticate(ctx, bearer(token)); err != nil || p.OrgID != 2 || p.ProjectID != 5 {
+		t.Fatalf("token tenant: %+v %v", p, err)
+	}
+}
+
+func TestHS256(t *testing.T) {
+	a, err := New(Config{JWTSecret: "s3cret", Issuer: "mem0", Audience: "api"}, nil)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	now := time.Now().Unix()
+	claims := map[string]interface{}{"sub": "42", "iss": "mem0", "aud": []string{"api"}, "exp": now + 60, "scope": "memories admin"}
+	token := sign(t, map[string]interface{}{"alg": "HS256", "typ": "JWT"}, claims, hs256("s3cret"))
+	p, err := a.Authenticate(context.Background(), bearer(token))
+	if err != nil || p.UserID != 42 || !p.Admin() {
+		t.Fatalf("token: %+v %v", p, err)
+	}
+
+	bad := map[string]string{
+		"wrong secret": sign(t, map[string]interface{}{"alg": "HS256"}, claims, hs256("guess")),
+		"expired":      sign(t, map[string]interface{}{"alg": "HS256"}, map[string]interface{}{"sub": "42", "iss": "mem0", "aud": "api", "exp": now - 120}, hs256("s3cret")),
+		"wrong issuer": sign(t, map[string]interface{}{"alg": "HS256"}, map[string]interface{}{"sub": "42", "iss": "evil", "aud": "api"}, hs256("s3cret")),
+		"alg none":     sign(t, map[string]interface{}{"alg": "none"}, claims, func([]byte) []byte { return nil }),
+	}
+	for name, token := range bad {
+		if _, err := a.Authenticate(context.Background(), bearer(token)); !errors.Is(err, ErrUnauthenticated) {
+			t.Fatalf("%s: expected ErrUnauthenticated, got %v", name, err)
+		}
+	}
+}
+
+func TestRS256WithJWKSFile(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	jwks := map[string]interface{}{"keys": []map[string]string{{
+		"kty": "RSA",
+		"kid": "k1",
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	b, _ := json.Marshal(jwks)
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	a, err := New(Config{JWKSFile: path}, nil)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	rs256 := func(b []byte) []byte {
+		sum := sha256.Sum256(b)
+		sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+		if err != nil {
+			t.Fatalf("sign: %v", err)
+		}
+		return sig
+	}
+
+	token := sign(t, map[string]interface{}{"alg": "RS256", "kid": "k1"}, map[string]interface{}{"sub": "alice", "user_id": 5, "scp": []string{"memories"}}, rs256)
+	p, err := a.Authenticate(context.Background(), bearer(token))
+	if err != nil || p.UserID != 5 || p.Subject != "alice" || !p.HasScope("memories") {
+		t.Fatalf("token: %+v %v", p, err)
+	}
+	unknown := sign(t, map[string]interface{}{"alg": "RS256", "kid": "k2"}, map[string]interface{}{"sub": "5"}, rs256)
+	if _, err := a.Authenticate(context.Background(), bearer(unknown)); !errors.Is(err, ErrUnauthenticated) {
+		t.Fatalf("expected ErrUnauthenticated for unknown kid, got %v", err)
+	}
+	// An HS256 token signed with the RSA public key must not verify.
+	confused := sign(t, map[string]interface{}{"alg": "HS256", "kid": "k1"}, map[string]interface{}{"sub": "5"}, hs256(string(key.N.Bytes())))
+	if _, err := a.Authenticate(context.Background(), bearer(confused)); !errors.Is(err, ErrUnauthenticated) {
+		t.Fatalf("expected ErrUnauthenticated for algorithm confusion, got %v", err)
+	}
+}
+
+func TestRoles(t *testing.T) {
+	ctx := context.Background()
+	repo := inmem.NewRepo()
+	a, err := New(Config{AdminKey: "bootstrap"}, repo, WithRoles(repo))
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	operator, _ := a.Authenticate(ctx, http.Header{"X-Api-Key": {"bootstrap"}})
+	op := WithPrincipal(ctx, operator)
+	authenticate := func(k db.APIKey) *Principal {
+		t.Helper()
+		_, key, err := a.CreateKey(op, k)
+		if err != nil {
+			t.Fatalf("create key: %v", err)
+		}
+		p, err := a.Authenticate(ctx, bearer(key))
+		if err != nil {
+			t.Fatalf("authenticate: %v", err)
+		}
+		return p
+	}
+
+	if _, err := a.CreateRole(op, db.Role{OrgID: 1, Name: "auditor", Permissions: []string{PermMemoriesRead, PermReconcile}}); err != nil {
+		t.Fatalf("create org role: %v", err)
+	}
+	if _, err := a.CreateRole(op, db.Role{OrgID: 1, ProjectID: 3, Name: "auditor", Permissions: []string{PermMemoriesRead}}); err != nil {
+		t.Fatalf("create project role: %v", err)
+	}
+	if _, err := a.CreateRole(op, db.Role{Name: RoleWriter}); !errors.Is(err, ErrInvalidRole) {
+		t.Fatalf("expected ErrInvalidRole redefining a built-in role, got %v", err)
+	}
+	if _, err := a.CreateRole(op, db.Role{Name: "x", Permissions: []string{"memories:burn"}}); !errors.Is(err, ErrInvalidRole) {
+		t.Fatalf("expected ErrInvalidRole for an unknown permission, got %v", err)
+	}
+	if _, err := a.Bind(op, db.RoleBinding{UserID: 7, OrgID: 1, Role: "auditor"}); err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+
+	// The organization role applies in project 4, the project role
+	// shadows it in project 3, and neither applies in the default project.
+	if p := authenticate(db.APIKey{UserID: 7, Name: "p4", OrgID: 1, ProjectID: 4}); !p.Can(PermReconcile) || p.Can(PermMemoriesWrite) {
+		t.Fatalf("org role not applied: %v", p.Permissions)
+	}
+	if p := authenticate(db.APIKey{UserID: 7, Name: "p3", OrgID: 1, ProjectID: 3}); p.Can(PermReconcile) || !p.Can(PermMemoriesRead) {
+		t.Fatalf("project role did not shadow the org role: %v", p.Permissions)
+	}
+	if p := authenticate(db.APIKey{UserID: 7, Name: "default"}); !p.Can(PermMemoriesWrite) || p.Can(PermMemoriesDelete) {
+		t.Fatalf("expected the writer role without bindings: %v", p.Permissions)
+	}
+
+	// A project admin manages roles in its project only, and cannot grant
+	// more than it holds.
+	if _, err := a.Bind(op, db.RoleBinding{UserID: 8, OrgID: 1, ProjectID: 4, Role: RoleAdmin}); err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	lead := authenticate(db.APIKey{UserID: 8, Name: "lead", OrgID: 1, ProjectID: 4, Scopes: []string{PermRolesManage, RoleReader}})
+	var perr *PermissionError
+	if _, err := a.Bind(WithPrincipal(ctx, lead), db.RoleBinding{UserID: 9, Role: RoleWriter}); !errors.As(err, &perr) {
+		t.Fatalf("expected a PermissionError binding a role with permissions the caller lacks, got %v", err)
+	}
+	if _, err := a.CreateRole(WithPrincipal(ctx, lead), db.Role{Name: "deleter", Permissions: []string{PermMemoriesDelete}}); !errors.As(err, &perr) || perr.Permission != PermMemoriesDelete {
+		t.Fatalf("expected missing %s, got %v", PermMemoriesDelete, err)
+	}
+	if _, err := a.Bind(WithPrincipal(ctx, lead), db.RoleBinding{UserID: 9, Role: RoleReader, ProjectID: 3}); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("expected ErrForbidden binding in another project, got %v", err)
+	}
+	b, err := a.Bind(WithPrincipal(ctx, lead), db.RoleBinding{UserID: 9, Role: RoleReader})
+	if err != nil || b.OrgID != 1 || b.ProjectID != 4 {
+		t.Fatalf("bind in own project: %+v %v", b, err)
+	}
+	if _, _, err := a.CreateKey(WithPrincipal(ctx, lead), db.APIKey{Name: "writer", Scopes: []string{RoleWriter}}); !errors.As(err, &perr) || perr.Permission != PermMemoriesWrite {
+		t.Fatalf("expected missing %s issuing a writer key, got %v", PermMemoriesWrite, err)
+	}
+
+	// Deleting a role deletes the bindings granting it.
+	roles, err := a.ListRoles(op, 1, 0)
+	if err != nil || len(roles) != 4 || roles[3].Name != "auditor" {
+		t.Fatalf("list org roles: %+v %v", roles, err)
+	}
+	if err := a.DeleteRole(op, roles[3].ID); err != nil {
+		t.Fatalf("delete role: %v", err)
+	}
+	if bindings, err := a.ListBindings(op, 7); err != nil || len(bindings) != 0 {
+		t.Fatalf("binding outlived its role: %+v %v", bindings, err)
+	}
+}
+
+func TestContextWithoutPrincipal(t *testing.T) {
+	anon := FromContext(context.Background())
+	if anon == nil || anon.Can(PermMemoriesRead) || anon.Operator() {
+		t.Fatalf("a call without a principal must hold nothing, got %+v", anon)
+	}
+	if err := Require(context.Background(), PermMemoriesRead); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("expected ErrForbidden, got %v", err)
+	}
+	internal := Internal(context.Background())
+	if p := FromContext(internal); p != nil || !p.Operator() {
+		t.Fatalf("internal calls act as operator, got %+v", p)
+	}
+	if err := Require(internal, PermMemoriesDelete); err != nil {
+		t.Fatalf("internal call: %v", err)
+	}
+}
@@ -0,0 +1,226 @@
+package auth
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// leeway is the clock skew tolerated for exp and nbf.
+const leeway = 30 * time.Second
+
+// verifier checks HS256 and RS256 tokens.
+type verifier struct {
+	secret   []byte
+	hmacKeys map[string][]byte
+	rsaKeys  map[string]*rsa.PublicKey
+	issuer   string
+	audience string
+}
+
+// jwk is a JSON Web Key. Only the fields of RSA and oct keys are read.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	K   string `json:"k"`
+}
+
+func newVerifier(cfg Config) (*verifier, error) {
+	v := &verifier{
+		secret:   []byte(cfg.JWTSecret),
+		hmacKeys: map[string][]byte{},
+		rsaKeys:  map[string]*rsa.PublicKey{},
+		issuer:   cfg.Issuer,
+		audience: cfg.Audience,
+	}
+	if cfg.JWKSFile == "" {
+		return v, nil
+	}
+	b, err := os.ReadFile(cfg.JWKSFile)
+	if err != nil {
+		return nil, fmt.Errorf("auth: read jwks: %w", err)
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(b, &set); err != nil {
+		return nil, fmt.Errorf("auth: parse jwks: %w", err)
+	}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		switch k.Kty {
+		case "RSA":
+			n, err1 := base64.RawURLEncoding.DecodeString(k.N)
+			e, err2 := base64.RawURLEncoding.DecodeString(k.E)
+			if err1 != nil || err2 != nil || len(e) > 4 {
+				return nil, fmt.Errorf("auth: jwks key %q: invalid RSA key", k.Kid)
+			}
+			v.rsaKeys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case "oct":
+			secret, err := base64.RawURLEncoding.DecodeString(k.K)
+			if err != nil {
+				return nil, fmt.Errorf("auth: jwks key %q: invalid oct key", k.Kid)
+			}
+			v.hmacKeys[k.Kid] = secret
+		}
+	}
+	return v, nil
+}
+
+// verify checks the token's signature and claims and returns its
+// principal. The user comes from a numeric sub claim or a user_id claim;
+// scopes from scope (space separated) or scp; the tenant from org_id and
+// project_id.
+func (v *verifier) verify(token string, now time.Time) (*Principal, error) {
+	parts := strings.Split(token, ".")
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, invalidToken("malformed header")
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, invalidToken("malformed signature")
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+	switch header.Alg {
+	case "HS256":
+		secret := v.hmacKeys[header.Kid]
+		if secret == nil {
+			secret = v.secret
+		}
+		if len(secret) == 0 {
+			return nil, invalidToken("unknown key")
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(signed)
+		if !hmac.Equal(mac.Sum(nil), sig) {
+			return nil, invalidToken("bad signature")
+		}
+	case "RS256":
+		key := v.rsaKeys[header.Kid]
+		if key == nil && header.Kid == "" && len(v.rsaKeys) == 1 {
+			for _, k := range v.rsaKeys {
+				key = k
+			}
+		}
+		if key == nil {
+			return nil, invalidToken("unknown key")
+		}
+		sum := sha256.Sum256(signed)
+		if rsa.VerifyPKCS1v15(key, crypto.SHA256, sum[:], sig) != nil {
+			return nil, invalidToken("bad signature")
+		}
+	default:
+		return nil, invalidToken("unsupported algorithm " + strconv.Quote(header.Alg))
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, invalidToken("malformed claims")
+	}
+	if exp, ok := numericClaim(claims, "exp"); ok && now.After(time.Unix(exp, 0).Add(leeway)) {
+		return nil, invalidToken("expired")
+	}
+	if nbf, ok := numericClaim(claims, "nbf"); ok && now.Add(leeway).Before(time.Unix(nbf, 0)) {
+		return nil, invalidToken("not yet valid")
+	}
+	if v.issuer != "" && claims["iss"] != v.issuer {
+		return nil, invalidToken("wrong issuer")
+	}
+	if v.audience != "" && !hasAudience(claims["aud"], v.audience) {
+		return nil, invalidToken("wrong audience")
+	}
+
+	sub, _ := claims["sub"].(string)
+	p := &Principal{Subject: sub, Scopes: scopes(claims)}
+	if id, err := strconv.ParseInt(sub, 10, 64); err == nil {
+		p.UserID = id
+	} else if id, ok := numericClaim(claims, "user_id"); ok {
+		p.UserID = id
+	}
+	p.OrgID, _ = numericClaim(claims, "org_id")
+	p.ProjectID, _ = numericClaim(claims, "project_id")
+	return p, nil
+}
+
+func invalidToken(reason string) error {
+	return fmt.Errorf("%w: invalid token: %s", ErrUnauthenticated, reason)
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// numericClaim reads a claim holding a number or a numeric string.
+func numericClaim(claims map[string]interface{}, name string) (int64, bool) {
+	switch v := claims[name].(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n, true
+		}
+		if f, err := v.Float64(); err == nil {
+			return int64(f), true
+		}
+	case string:
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+func hasAudience(aud interface{}, want string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == want
+	case []interface{}:
+		for _, a := range v {
+			if a == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func scopes(claims map[string]interface{}) []string {
+	if s, ok := claims["scope"].(string); ok {
+		return strings.Fields(s)
+	}
+	var out []string
+	switch v := claims["scp"].(type) {
+	case string:
+		out = strings.Fields(v)
+	case []interface{}:
+		for _, s := range v {
+			if s, ok := s.(string); ok {
+				out = append(out, s)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,427 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"mem0-go/internal/db"
+)
+
+// Permissions the services check.
+const (
+	PermMemoriesRead   = "memories:read"
+	PermMemoriesWrite  = "memories:write"
+	PermMemoriesDelete = "memories:delete"
+	PermGraphRead      = "graph:read"
+	PermGraphWrite     = "graph:write"
+	// PermUsersManage creates users and acts for every user of the
+	// project, which makes a principal an admin.
+	PermUsersManage = "users:manage"
+	PermRolesManage = "roles:manage"
+	PermReconcile   = "reconcile"
+)
+
+// Permissions lists every permission.
+var Permissions = []string{
+	PermMemoriesRead, PermMemoriesWrite, PermMemoriesDelete,
+	PermGraphRead, PermGraphWrite,
+	PermUsersManage, PermRolesManage, PermReconcile,
+}
+
+// Built-in roles, defined in every project. Principals without role
+// bindings in their project get RoleWriter.
+const (
+	RoleReader = "reader"
+	RoleWriter = "writer"
+	RoleAdmin  = ScopeAdmin
+)
+
+var builtinRoles = map[string][]string{
+	RoleReader: {PermMemoriesRead, PermGraphRead},
+	RoleWriter: {PermMemoriesRead, PermMemoriesWrite, PermGraphRead, PermGraphWrite},
+	RoleAdmin:  Permissions,
+}
+
+// ErrInvalidRole is returned for roles and bindings that name unknown
+// roles or permissions.
+var ErrInvalidRole = errors.New("auth: invalid role")
+
+// PermissionError is returned when a principal lacks a permission. It
+// matches ErrForbidden.
+type PermissionError struct {
+	Permission string
+}
+
+func (e *PermissionError) Error() string {
+	return "auth: forbidden: missing permission " + e.Permission
+}
+
+// Is reports whether target is ErrForbidden.
+func (e *PermissionError) Is(target error) bool { return target == ErrForbidden }
+
+// Extensions describes the error to GraphQL clients.
+func (e *PermissionError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": "FORBIDDEN", "permission": e.Permission}
+}
+
+// Can reports whether p holds permission perm. A nil principal, an
+// internal call, holds every permission, as do principals with the admin
+// scope. The anonymous principal FromContext returns for other calls
+// holds none.
+func (p *Principal) Can(perm string) bool {
+	if p == nil || p.HasScope(ScopeAdmin) {
+		return true
+	}
+	return contains(p.granted(), perm)
+}
+
+// granted returns p's permissions. Principals that no Authenticator
+// authorized get those their scopes name, or else the writer role's.
+func (p *Principal) granted() []string {
+	if p.Permissions != nil {
+		return p.Permissions
+	}
+	if perms := scopePermissions(p.Scopes, builtinRoles); perms != nil {
+		return perms
+	}
+	return builtinRoles[RoleWriter]
+}
+
+// Require returns a *PermissionError unless the principal in ctx holds
+// perm.
+func Require(ctx context.Context, perm string) error {
+	if !FromContext(ctx).Can(perm) {
+		return &PermissionError{Permission: perm}
+	}
+	return nil
+}
+
+// authorize resolves p's permissions: the union of the roles bound to its
+// user in its project, or the writer role without any, narrowed to the
+// roles and permissions its scopes name when they name any.
+func (a *Authenticator) authorize(ctx context.Context, p *Principal) error {
+	if p.HasScope(ScopeAdmin) {
+		p.Permissions = append([]string{}, Permissions...)
+		return nil
+	}
+	roles, err := a.projectRoles(ctx, p.OrgID, p.ProjectID)
+	if err != nil {
+		return err
+	}
+	var grants []string
+	bound := false
+	if a.roles != nil && p.UserID != 0 {
+		bindings, err := a.roles.ListRoleBindings(ctx, p.UserID)
+		if err != nil {
+			return err
+		}
+		for _, b := range bindings {
+			if applies(b.OrgID, b.ProjectID, p.OrgID, p.ProjectID) {
+				grants, bound = union(grants, roles[b.Role]), true
+			}
+		}
+	}
+	if !bound {
+		grants = builtinRoles[RoleWriter]
+	}
+	if named := scopePermissions(p.Scopes, roles); named != nil {
+		grants = intersect(grants, named)
+	}
+	p.Permissions = append([]string{}, grants...)
+	return nil
+}
+
+// projectRoles returns the permissions of every role defined in a
+// project by name, or in an organization when projectID is 0. Project
+// roles shadow organization roles of the same name.
+func (a *Authenticator) projectRoles(ctx context.Context, orgID, projectID int64) (map[string][]string, error) {
+	out := make(map[string][]string, len(builtinRoles))
+	for name, perms := range builtinRoles {
+		out[name] = perms
+	}
+	if a == nil || a.roles == nil {
+		return out, nil
+	}
+	defined, err := a.roles.ListRoles(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range defined {
+		if r.ProjectID == 0 {
+			out[r.Name] = r.Permissions
+		}
+	}
+	for _, r := range defined {
+		if r.ProjectID != 0 && r.ProjectID == projectID {
+			out[r.Name] = r.Permissions
+		}
+	}
+	return out, nil
+}
+
+// applies reports whether a role or binding defined for orgID and
+// projectID applies in project inProject of organization inOrg.
+// Organization-wide ones have a ProjectID of 0 and apply in all of its
+// projects.
+func applies(orgID, projectID, inOrg, inProject int64) bool {
+	if projectID != 0 {
+		return projectID == inProject
+	}
+	if orgID != 0 {
+		return orgID == inOrg && inProject != 0
+	}
+	return inOrg == 0 && inProject == 0
+}
+
+// scopePermissions returns the permissions granted by the roles in roles
+// and the permissions that scopes name, or nil when they name none.
+func scopePermissions(scopes []string, roles map[string][]string) []string {
+	var out []string
+	for _, s := range scopes {
+		if perms, ok := roles[s]; ok {
+			out = union(out, perms)
+		} else if contains(Permissions, s) {
+			out = union(out, []string{s})
+		}
+	}
+	return out
+}
+
+// checkGrant fails unless the caller holds every permission in perms,
+// naming the first one it lacks.
+func checkGrant(ctx context.Context, perms []string) error {
+	for _, perm := range perms {
+		if err := Require(ctx, perm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scope returns the organization and project a management call on
+// orgID and projectID acts in. Operators act where they ask; everyone
+// else only in their own project.
+func scope(p *Principal, orgID, projectID int64) (int64, int64, error) {
+	if p.Operator() {
+		return orgID, projectID, nil
+	}
+	if (projectID != 0 && projectID != p.ProjectID) || (orgID != 0 && orgID != p.OrgID) {
+		return 0, 0, ErrForbidden
+	}
+	return p.OrgID, p.ProjectID, nil
+}
+
+// CreateRole defines a role in r's project, or in every project of r's
+// organization when r.ProjectID is 0. Callers need roles:manage and every
+// permission the role grants. Callers in a project may only define roles
+// for it.
+func (a *Authenticator) CreateRole(ctx context.Context, r db.Role) (db.Role, error) {
+	if a == nil || a.roles == nil {
+		return db.Role{}, errors.New("auth: roles are not enabled")
+	}
+	if err := Require(ctx, PermRolesManage); err != nil {
+		return db.Role{}, err
+	}
+	var err error
+	if r.OrgID, r.ProjectID, err = scope(FromContext(ctx), r.OrgID, r.ProjectID); err != nil {
+		return db.Role{}, err
+	}
+	if r.Name = strings.TrimSpace(r.Name); r.Name == "" {
+		return db.Role{}, fmt.Errorf("%w: name required", ErrInvalidRole)
+	}
+	if _, ok := builtinRoles[r.Name]; ok || contains(Permissions, r.Name) {
+		return db.Role{}, fmt.Errorf("%w: %q is reserved", ErrInvalidRole, r.Name)
+	}
+	for _, perm := range r.Permissions {
+		if !contains(Permissions, perm) {
+			return db.Role{}, fmt.Errorf("%w: unknown permission %q", ErrInvalidRole, perm)
+		}
+	}
+	if err := checkGrant(ctx, r.Permissions); err != nil {
+		return db.Role{}, err
+	}
+	r.Permissions = union(nil, r.Permissions)
+	if r.Permissions == nil {
+		r.Permissions = []string{}
+	}
+	id, err := a.roles.CreateRole(ctx, r)
+	if err != nil {
+		return db.Role{}, err
+	}
+	r.ID = id
+	return r, nil
+}
+
+// ListRoles returns the built-in roles, which have ID 0, and the roles
+// defined in projectID of orgID, or the organization-wide roles when
+// projectID is 0. Callers in a project only see their own project's
+// roles.
+func (a *Authenticator) ListRoles(ctx context.Context, orgID, projectID int64) ([]db.Role, error) {
+	orgID, projectID, err := scope(FromContext(ctx), orgID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	out := []db.Role{}
+	for _, name := range []string{RoleReader, RoleWriter, RoleAdmin} {
+		out = append(out, db.Role{Name: name, Permissions: builtinRoles[name]})
+	}
+	if a == nil || a.roles == nil {
+		return out, nil
+	}
+	defined, err := a.roles.ListRoles(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range defined {
+		if applies(r.OrgID, r.ProjectID, orgID, projectID) || (projectID == 0 && r.ProjectID == 0) {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+// DeleteRole deletes role id and the bindings granting it. Callers need
+// roles:manage, and callers in a project may only delete its own roles.
+func (a *Authenticator) DeleteRole(ctx context.Context, id int64) error {
+	if a == nil || a.roles == nil {
+		return db.ErrNotFound
+	}
+	if err := Require(ctx, PermRolesManage); err != nil {
+		return err
+	}
+	if p := FromContext(ctx); !p.Operator() {
+		defined, err := a.roles.ListRoles(ctx, p.OrgID)
+		if err != nil {
+			return err
+		}
+		found := false
+		for _, r := range defined {
+			found = found || (r.ID == id && r.ProjectID == p.ProjectID)
+		}
+		if !found {
+			return db.ErrNotFound
+		}
+	}
+	return a.roles.DeleteRole(ctx, id)
+}
+
+// Bind grants b.Role to b.UserID in b's project, or in every project of
+// b's organization when b.ProjectID is 0. Callers need roles:manage and
+// every permission the role grants; callers in a project may only bind
+// roles in it.
+func (a *Authenticator) Bind(ctx context.Context, b db.RoleBinding) (db.RoleBinding, error) {
+	if a == nil || a.roles == nil {
+		return db.RoleBinding{}, errors.New("auth: roles are not enabled")
+	}
+	if err := Require(ctx, PermRolesManage); err != nil {
+		return db.RoleBinding{}, err
+	}
+	var err error
+	if b.OrgID, b.ProjectID, err = scope(FromContext(ctx), b.OrgID, b.ProjectID); err != nil {
+		return db.RoleBinding{}, err
+	}
+	if b.UserID <= 0 {
+		return db.RoleBinding{}, ErrUserRequired
+	}
+	roles, err := a.projectRoles(ctx, b.OrgID, b.ProjectID)
+	if err != nil {
+		return db.RoleBinding{}, err
+	}
+	perms, ok := roles[b.Role]
+	if !ok {
+		return db.RoleBinding{}, fmt.Errorf("%w: unknown role %q", ErrInvalidRole, b.Role)
+	}
+	if err := checkGrant(ctx, perms); err != nil {
+		return db.RoleBinding{}, err
+	}
+	id, err := a.roles.CreateRoleBinding(ctx, b)
+	if err != nil {
+		return db.RoleBinding{}, err
+	}
+	b.ID = id
+	return b, nil
+}
+
+// ListBindings returns the role bindings of userID, or of every user when
+// userID is 0. Callers without roles:manage only see their own, and
+// callers in a project only that project's.
+func (a *Authenticator) ListBindings(ctx context.Context, userID int64) ([]db.RoleBinding, error) {
+	if a == nil || a.roles == nil {
+		return []db.RoleBinding{}, nil
+	}
+	p := FromContext(ctx)
+	if !p.Can(PermRolesManage) {
+		if p.UserID == 0 || (userID != 0 && userID != p.UserID) {
+			return nil, &PermissionError{Permission: PermRolesManage}
+		}
+		userID = p.UserID
+	}
+	bindings, err := a.roles.ListRoleBindings(ctx, userID)
+	if err != nil || p.Operator() {
+		return bindings, err
+	}
+	out := []db.RoleBinding{}
+	for _, b := range bindings {
+		if b.OrgID == p.OrgID && b.ProjectID == p.ProjectID {
+			out = append(out, b)
+		}
+	}
+	return out, nil
+}
+
+// Unbind deletes role binding id. Callers need roles:manage and may only
+// delete bindings ListBindings shows them.
+func (a *Authenticator) Unbind(ctx context.Context, id int64) error {
+	if a == nil || a.roles == nil {
+		return db.ErrNotFound
+	}
+	if err := Require(ctx, PermRolesManage); err != nil {
+		return err
+	}
+	if p := FromContext(ctx); !p.Operator() {
+		bindings, err := a.ListBindings(ctx, 0)
+		if err != nil {
+			return err
+		}
+		found := false
+		for _, b := range bindings {
+			found = found || b.ID == id
+		}
+		if !found {
+			return db.ErrNotFound
+		}
+	}
+	return a.roles.DeleteRoleBinding(ctx, id)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// union returns a with the elements of b it lacks appended.
+func union(a, b []string) []string {
+	for _, s := range b {
+		if !contains(a, s) {
+			a = append(a, s)
+		}
+	}
+	return a
+}
+
+// intersect returns the elements of a that are also in b.
+func intersect(a, b []string) []string {
+	out := []string{}
+	for _, s := range a {
+		if contains(b, s) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -8,6 +8,10 @@ import (
 type Config struct {
 	// HTTPPort is the port the API server listens on.
 	HTTPPort string
+	// VectorBackend selects the vector store: "memory", "hnsw" or "qdrant".
+	VectorBackend string
+	// AutoMigrate applies pending Postgres migrations on startup.
+	AutoMigrate bool
 }
 
 // Load reads configuration from environment variables or defaults.
@@ -16,5 +20,9 @@ func Load() Config {
 	if port == "" {
 		port = "8080"
 	}
-	return Config{HTTPPort: port}
+	backend := os.Getenv("MEM0_VECTOR_BACKEND")
+	if backend == "" {
+		backend = "memory"
+	}
+	return Config{HTTPPort: port, VectorBackend: backend, AutoMigrate: os.Getenv("MEM0_AUTO_MIGRATE") == "true"}
 }
@@ -0,0 +1,88 @@
+package db
+
+import (
+	"context"
+)
+
+// APIKey is a credential belonging to a user. Only the SHA-256 hash of the
+// key is stored; Prefix is its first characters, kept to tell keys apart.
+// Revoked keys are kept with RevokedAt set. Callers using the key act in
+// its project, or in the default project when ProjectID is 0.
+type APIKey struct {
+	ID        int64    `json:"id"`
+	UserID    int64    `json:"userID"`
+	OrgID     int64    `json:"orgID,omitempty"`
+	ProjectID int64    `json:"projectID,omitempty"`
+	Name      string   `json:"name"`
+	Prefix    string   `json:"prefix"`
+	Hash      string   `json:"-"`
+	Scopes    []string `json:"scopes"`
+	CreatedAt string   `json:"createdAt"`
+	RevokedAt string   `json:"revokedAt,omitempty"`
+}
+
+// APIKeys stores API keys.
+type APIKeys interface {
+	CreateAPIKey(ctx context.Context, k APIKey) (int64, error)
+	// APIKeyByHash returns the unrevoked key with the given hash.
+	APIKeyByHash(ctx context.Context, hash string) (APIKey, error)
+	// ListAPIKeys returns a user's keys, or every key when userID is 0,
+	// in ID order.
+	ListAPIKeys(ctx context.Context, userID int64) ([]APIKey, error)
+	// RevokeAPIKey revokes a key. Revoking a revoked key is a no-op.
+	RevokeAPIKey(ctx context.Context, id int64) error
+}
+
+var _ APIKeys = (*PgxRepository)(nil)
+
+func (r *PgxRepository) CreateAPIKey(ctx context.Context, k APIKey) (int64, error) {
+	row := r.q.QueryRow(ctx, `INSERT INTO api_keys (user_id, name, prefix, hash, scopes, org_id, project_id)
+		VALUES ($1,$2,$3,$4,$5,NULLIF($6,0),NULLIF($7,0)) RETURNING id`,
+		k.UserID, k.Name, k.Prefix, k.Hash, tagsOrEmpty(k.Scopes), k.OrgID, k.ProjectID)
+	var id int64
+	if err := row.Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *PgxRepository) APIKeyByHash(ctx context.Context, hash string) (APIKey, error) {
+	row := r.q.QueryRow(ctx, `SELECT id, user_id, name, prefix, hash, scopes, created_at::text, '',
+		COALESCE(org_id, 0), COALESCE(project_id, 0) FROM api_keys WHERE hash=$1 AND revoked_at IS NULL`, hash)
+	k, err := scanAPIKey(row)
+	if err != nil {
+		return APIKey{}, notFound(err)
+	}
+	return k, nil
+}
+
+func (r *PgxRepository) ListAPIKeys(ctx context.Context, userID int64) ([]APIKey, error) {
+	rows, err := r.q.Query(ctx, `SELECT id, user_id, name, prefix, hash, scopes, created_at::text, COALESCE(revoked_at::text, ''),
+		COALESCE(org_id, 0), COALESCE(project_id, 0) FROM api_keys WHERE $1 = 0 OR user_id = $1 ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []APIKey{}
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, k)
+	}
+	return out, rows.Err()
+}
+
+func (r *PgxRepository) RevokeAPIKey(ctx context.Context, id int64) error {
+	row := r.q.QueryRow(ctx, "UPDATE api_keys SET revoked_at = COALESCE(revoked_at, NOW()) WHERE id=$1 RETURNING id", id)
+	return notFound(row.Scan(&id))
+}
+
+func scanAPIKey(row interface {
+	Scan(dest ...interface{}) error
+}) (APIKey, error) {
+	var k APIKey
+	err := row.Scan(&k.ID, &k.UserID, &k.Name, &k.Prefix, &k.Hash, &k.Scopes, &k.CreatedAt, &k.RevokedAt, &k.OrgID, &k.ProjectID)
+	return k, err
+}
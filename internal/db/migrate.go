@@ -0,0 +1,379 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// migrationFiles holds the schema as versioned NNNNNN_name.up.sql and
+// NNNNNN_name.down.sql pairs.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+var (
+	// ErrChecksumMismatch is returned when an applied migration was
+	// edited after it ran.
+	ErrChecksumMismatch = errors.New("db: migration checksum mismatch")
+	// ErrUnknownMigration is returned for versions that have no migration
+	// files, including applied versions from a newer build.
+	ErrUnknownMigration = errors.New("db: unknown migration")
+)
+
+// Migration is one schema change. Checksum is the SHA-256 of Up, recorded
+// when it is applied so later edits are caught.
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// AppliedMigration is a migration recorded in schema_migrations.
+// AppliedAt is an RFC 3339 timestamp.
+type AppliedMigration struct {
+	Version   int64
+	Name      string
+	Checksum  string
+	AppliedAt string
+}
+
+// MigrationStatus reports whether a migration has been applied.
+// ChecksumMismatch is set when its file changed since; Unknown when it is
+// applied but has no files.
+type MigrationStatus struct {
+	Version          int64  `json:"version"`
+	Name             string `json:"name"`
+	Applied          bool   `json:"applied"`
+	AppliedAt        string `json:"appliedAt,omitempty"`
+	ChecksumMismatch bool   `json:"checksumMismatch,omitempty"`
+	Unknown          bool   `json:"unknown,omitempty"`
+}
+
+var migrationName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migrations returns the embedded migrations in version order.
+func Migrations() ([]Migration, error) {
+	sub, err := fs.Sub(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return LoadMigrations(sub)
+}
+
+// LoadMigrations reads the migrations in the root of fsys in version
+// order. Every version needs exactly one up and one down file.
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int64]*Migration{}
+	for _, e := range entries {
+		if e.IsDir() || path.Ext(e.Name()) != ".sql" {
+			continue
+		}
+		parts := migrationName.FindStringSubmatch(e.Name())
+		if parts == nil {
+			return nil, fmt.Errorf("db: bad migration file name %q", e.Name())
+		}
+		version, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("db: bad migration version in %q", e.Name())
+		}
+		b, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, err
+		}
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: parts[2]}
+			byVersion[version] = m
+		}
+		if m.Name != parts[2] {
+			return nil, fmt.Errorf("db: migration %d is named both %q and %q", version, m.Name, parts[2])
+		}
+		if parts[3] == "up" {
+			sum := sha256.Sum256(b)
+			m.Up, m.Checksum = string(b), hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(b)
+		}
+	}
+	out := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Checksum == "" || m.Down == "" {
+			return nil, fmt.Errorf("db: migration %d_%s needs both an up and a down file", m.Version, m.Name)
+		}
+		out = append(out, *m)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+// migrationStore records applied migrations and runs them.
+type migrationStore interface {
+	// Lock blocks until no other migrator holds the lock, creating the
+	// bookkeeping table if needed.
+	Lock(ctx context.Context) error
+	Unlock(ctx context.Context) error
+	// Applied returns the applied migrations in version order.
+	Applied(ctx context.Context) ([]AppliedMigration, error)
+	// Apply runs m's up or down SQL and records the result in one
+	// transaction.
+	Apply(ctx context.Context, m Migration, up bool) error
+}
+
+// Migrator applies and reverts migrations. Every run holds a Postgres
+// advisory lock, so concurrent migrators, such as several API replicas
+// migrating on startup, apply each migration once.
+type Migrator struct {
+	mu         sync.Mutex
+	store      migrationStore
+	migrations []Migration
+}
+
+// NewMigrator returns a Migrator applying the embedded migrations to
+// pool.
+func NewMigrator(pool *pgxpool.Pool) (*Migrator, error) {
+	ms, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{store: &pgxMigrations{pool: pool}, migrations: ms}, nil
+}
+
+// Up applies every pending migration in version order and returns the
+// versions it applied.
+func (m *Migrator) Up(ctx context.Context) ([]int64, error) {
+	return m.run(ctx, func(applied map[int64]AppliedMigration) []step {
+		return m.pending(applied, -1)
+	})
+}
+
+// Down reverts the n most recently applied migrations, newest first, and
+// returns the versions it reverted.
+func (m *Migrator) Down(ctx context.Context, n int) ([]int64, error) {
+	return m.run(ctx, func(applied map[int64]AppliedMigration) []step {
+		steps := m.applied(applied, 0)
+		if n = max(n, 0); n < len(steps) {
+			steps = steps[:n]
+		}
+		return steps
+	})
+}
+
+// Goto reverts and applies migrations until exactly those up to version
+// are applied, and returns the versions it ran. Version 0 reverts
+// everything.
+func (m *Migrator) Goto(ctx context.Context, version int64) ([]int64, error) {
+	if version != 0 && m.find(version) == nil {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownMigration, version)
+	}
+	return m.run(ctx, func(applied map[int64]AppliedMigration) []step {
+		return append(m.applied(applied, version), m.pending(applied, version)...)
+	})
+}
+
+// Status reports every known migration and any applied migration without
+// files, in version order.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.store.Lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.store.Unlock(ctx)
+	list, err := m.store.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]AppliedMigration, len(list))
+	for _, a := range list {
+		applied[a.Version] = a
+	}
+	out := make([]MigrationStatus, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		s := MigrationStatus{Version: mig.Version, Name: mig.Name}
+		if a, ok := applied[mig.Version]; ok {
+			s.Applied, s.AppliedAt, s.ChecksumMismatch = true, a.AppliedAt, a.Checksum != mig.Checksum
+		}
+		out = append(out, s)
+	}
+	for _, a := range list {
+		if m.find(a.Version) == nil {
+			out = append(out, MigrationStatus{Version: a.Version, Name: a.Name, Applied: true, AppliedAt: a.AppliedAt, Unknown: true})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+// step applies or reverts one migration.
+type step struct {
+	Migration
+	up bool
+}
+
+// run takes the lock, verifies the applied migrations and runs the steps
+// plan picks, returning the versions it ran.
+func (m *Migrator) run(ctx context.Context, plan func(map[int64]AppliedMigration) []step) ([]int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.store.Lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.store.Unlock(ctx)
+	list, err := m.store.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]AppliedMigration, len(list))
+	for _, a := range list {
+		mig := m.find(a.Version)
+		if mig == nil {
+			return nil, fmt.Errorf("%w: %d_%s is applied but has no files", ErrUnknownMigration, a.Version, a.Name)
+		}
+		if a.Checksum != mig.Checksum {
+			return nil, fmt.Errorf("%w: %d_%s changed after it was applied", ErrChecksumMismatch, a.Version, a.Name)
+		}
+		applied[a.Version] = a
+	}
+	done := []int64{}
+	for _, st := range plan(applied) {
+		if err := m.store.Apply(ctx, st.Migration, st.up); err != nil {
+			return done, fmt.Errorf("db: migration %d_%s: %w", st.Version, st.Name, err)
+		}
+		done = append(done, st.Version)
+	}
+	return done, nil
+}
+
+// pending returns steps applying the migrations that are not applied,
+// oldest first, up to version, or all of them when version is negative.
+func (m *Migrator) pending(applied map[int64]AppliedMigration, version int64) []step {
+	var out []step
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.Version]; !ok && (version < 0 || mig.Version <= version) {
+			out = append(out, step{Migration: mig, up: true})
+		}
+	}
+	return out
+}
+
+// applied returns steps reverting the applied migrations above version,
+// newest first.
+func (m *Migrator) applied(applied map[int64]AppliedMigration, version int64) []step {
+	var out []step
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		mig := m.migrations[i]
+		if _, ok := applied[mig.Version]; ok && mig.Version > version {
+			out = append(out, step{Migration: mig})
+		}
+	}
+	return out
+}
+
+func (m *Migrator) find(version int64) *Migration {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return &m.migrations[i]
+		}
+	}
+	return nil
+}
+
+// migrationLockID keys the advisory lock migrators hold.
+const migrationLockID = 0x6d656d30 // "mem0"
+
+// pgxMigrations implements migrationStore on a connection acquired from
+// pool for the duration of the lock, since advisory locks belong to a
+// session.
+type pgxMigrations struct {
+	pool *pgxpool.Pool
+	conn *pgxpool.Conn
+}
+
+func (s *pgxMigrations) Lock(ctx context.Context) error {
+	conn, err := s.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+		conn.Release()
+		return err
+	}
+	s.conn = conn
+	_, err = conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name TEXT NOT NULL,
+		checksum TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`)
+	if err != nil {
+		_ = s.Unlock(ctx)
+	}
+	return err
+}
+
+func (s *pgxMigrations) Unlock(ctx context.Context) error {
+	if s.conn == nil {
+		return nil
+	}
+	_, err := s.conn.Exec(ctx, "SELECT pg_advisory_unlock($1)", migrationLockID)
+	s.conn.Release()
+	s.conn = nil
+	return err
+}
+
+func (s *pgxMigrations) Applied(ctx context.Context) ([]AppliedMigration, error) {
+	rows, err := s.conn.Query(ctx, "SELECT version, name, checksum, applied_at::text FROM schema_migrations ORDER BY version")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []AppliedMigration{}
+	for rows.Next() {
+		var a AppliedMigration
+		if err := rows.Scan(&a.Version, &a.Name, &a.Checksum, &a.AppliedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+func (s *pgxMigrations) Apply(ctx context.Context, m Migration, up bool) error {
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	sql, record := m.Down, "DELETE FROM schema_migrations WHERE version=$1"
+	args := []interface{}{m.Version}
+	if up {
+		sql, record = m.Up, "INSERT INTO schema_migrations (version, name, checksum) VALUES ($1,$2,$3)"
+		args = append(args, m.Name, m.Checksum)
+	}
+	if _, err := tx.Exec(ctx, sql); err != nil {
+		_ = tx.Rollback(ctx)
+		return err
+	}
+	if _, err := tx.Exec(ctx, record, args...); err != nil {
+		_ = tx.Rollback(ctx)
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,178 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+// fakeMigrations implements migrationStore in memory, recording the SQL
+// it ran.
+type fakeMigrations struct {
+	mu      sync.Mutex
+	locked  bool
+	applied map[int64]AppliedMigration
+	ran     []string
+	fail    int64
+}
+
+func (f *fakeMigrations) Lock(ctx context.Context) error {
+	f.mu.Lock()
+	f.locked = true
+	if f.applied == nil {
+		f.applied = map[int64]AppliedMigration{}
+	}
+	return nil
+}
+
+func (f *fakeMigrations) Unlock(ctx context.Context) error {
+	f.locked = false
+	f.mu.Unlock()
+	return nil
+}
+
+func (f *fakeMigrations) Applied(ctx context.Context) ([]AppliedMigration, error) {
+	if !f.locked {
+		return nil, errors.New("not locked")
+	}
+	out := []AppliedMigration{}
+	for _, a := range f.applied {
+		out = append(out, a)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+func (f *fakeMigrations) Apply(ctx context.Context, m Migration, up bool) error {
+	if !f.locked {
+		return errors.New("not locked")
+	}
+	if m.Version == f.fail {
+		return errors.New("syntax error")
+	}
+	if up {
+		f.ran = append(f.ran, m.Up)
+		f.applied[m.Version] = AppliedMigration{Version: m.Version, Name: m.Name, Checksum: m.Checksum, AppliedAt: "now"}
+	} else {
+		f.ran = append(f.ran, m.Down)
+		delete(f.applied, m.Version)
+	}
+	return nil
+}
+
+func testMigrations(t *testing.T, n int) []Migration {
+	t.Helper()
+	fsys := fstest.MapFS{}
+	for v := 1; v <= n; v++ {
+		fsys[fmt.Sprintf("%06d_step_%d.up.sql", v, v)] = &fstest.MapFile{Data: []byte(fmt.Sprintf("up %d", v))}
+		fsys[fmt.Sprintf("%06d_step_%d.down.sql", v, v)] = &fstest.MapFile{Data: []byte(fmt.Sprintf("down %d", v))}
+	}
+	ms, err := LoadMigrations(fsys)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	return ms
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	ms, err := Migrations()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	for i, m := range ms {
+		if m.Version != int64(i+1) || m.Up == "" || m.Down == "" || len(m.Checksum) != 64 {
+			t.Fatalf("migration %d: %+v", i, m)
+		}
+	}
+	if len(ms) < 9 || ms[0].Name != "create_users" {
+		t.Fatalf("unexpected migrations: %d, first %q", len(ms), ms[0].Name)
+	}
+}
+
+func TestLoadMigrationsRejectsBadFiles(t *testing.T) {
+	bad := map[string]fstest.MapFS{
+		"missing down": {"000001_a.up.sql": {Data: []byte("x")}},
+		"bad name":     {"one.up.sql": {Data: []byte("x")}},
+		"renamed": {
+			"000001_a.up.sql":   {Data: []byte("x")},
+			"000001_b.down.sql": {Data: []byte("x")},
+		},
+	}
+	for name, fsys := range bad {
+		if _, err := LoadMigrations(fsys); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+	store := &fakeMigrations{}
+	m := &Migrator{store: store, migrations: testMigrations(t, 3)}
+
+	if done, err := m.Up(ctx); err != nil || fmt.Sprint(done) != "[1 2 3]" {
+		t.Fatalf("up: %v %v", done, err)
+	}
+	if done, err := m.Up(ctx); err != nil || len(done) != 0 {
+		t.Fatalf("second up: %v %v", done, err)
+	}
+	if done, err := m.Down(ctx, 2); err != nil || fmt.Sprint(done) != "[3 2]" {
+		t.Fatalf("down: %v %v", done, err)
+	}
+	if done, err := m.Goto(ctx, 3); err != nil || fmt.Sprint(done) != "[2 3]" {
+		t.Fatalf("goto 3: %v %v", done, err)
+	}
+	if done, err := m.Goto(ctx, 1); err != nil || fmt.Sprint(done) != "[3 2]" {
+		t.Fatalf("goto 1: %v %v", done, err)
+	}
+	if _, err := m.Goto(ctx, 7); !errors.Is(err, ErrUnknownMigration) {
+		t.Fatalf("expected ErrUnknownMigration, got %v", err)
+	}
+	want := "[up 1 up 2 up 3 down 3 down 2 up 2 up 3 down 3 down 2]"
+	if fmt.Sprint(store.ran) != want {
+		t.Fatalf("ran %v, want %v", store.ran, want)
+	}
+
+	status, err := m.Status(ctx)
+	if err != nil || len(status) != 3 || !status[0].Applied || status[1].Applied {
+		t.Fatalf("status: %+v %v", status, err)
+	}
+
+	// A failing migration stops the run after the ones before it.
+	store.fail = 3
+	if done, err := m.Up(ctx); err == nil || fmt.Sprint(done) != "[2]" {
+		t.Fatalf("expected up to stop at 3: %v %v", done, err)
+	}
+}
+
+func TestMigratorVerifiesAppliedMigrations(t *testing.T) {
+	ctx := context.Background()
+	store := &fakeMigrations{}
+	m := &Migrator{store: store, migrations: testMigrations(t, 2)}
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+
+	edited := testMigrations(t, 2)
+	edited[0].Checksum = "edited"
+	m.migrations = edited
+	if _, err := m.Up(ctx); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("expected ErrChecksumMismatch, got %v", err)
+	}
+	if status, err := m.Status(ctx); err != nil || !status[0].ChecksumMismatch {
+		t.Fatalf("status should flag the edit: %+v %v", status, err)
+	}
+
+	// A database migrated by a newer build refuses older binaries.
+	m.migrations = testMigrations(t, 1)
+	if _, err := m.Down(ctx, 1); !errors.Is(err, ErrUnknownMigration) {
+		t.Fatalf("expected ErrUnknownMigration, got %v", err)
+	}
+	if status, err := m.Status(ctx); err != nil || len(status) != 2 || !status[1].Unknown {
+		t.Fatalf("status should list the unknown migration: %+v %v", status, err)
+	}
+}
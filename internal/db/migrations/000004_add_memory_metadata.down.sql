@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS memories_user_agent_idx;
+ALTER TABLE memories
+    DROP COLUMN IF EXISTS metadata,
+    DROP COLUMN IF EXISTS tags,
+    DROP COLUMN IF EXISTS run_id,
+    DROP COLUMN IF EXISTS agent_id;
@@ -0,0 +1,6 @@
+ALTER TABLE memories
+    ADD COLUMN IF NOT EXISTS agent_id TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS run_id TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS metadata JSONB NOT NULL DEFAULT '{}';
+CREATE INDEX IF NOT EXISTS memories_user_agent_idx ON memories (user_id, agent_id);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS memory_history (
+    id BIGSERIAL PRIMARY KEY,
+    memory_id INTEGER NOT NULL,
+    event TEXT NOT NULL,
+    actor TEXT NOT NULL DEFAULT '',
+    fields TEXT[] NOT NULL DEFAULT '{}',
+    old_value JSONB,
+    new_value JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS memory_history_memory_idx ON memory_history (memory_id, id);
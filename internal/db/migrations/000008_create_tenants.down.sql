@@ -0,0 +1,9 @@
+ALTER TABLE api_keys
+    DROP COLUMN IF EXISTS project_id,
+    DROP COLUMN IF EXISTS org_id;
+DROP INDEX IF EXISTS memories_project_user_idx;
+ALTER TABLE memories
+    DROP COLUMN IF EXISTS project_id,
+    DROP COLUMN IF EXISTS org_id;
+DROP TABLE IF EXISTS projects;
+DROP TABLE IF EXISTS organizations;
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS roles (
+    id BIGSERIAL PRIMARY KEY,
+    org_id BIGINT REFERENCES organizations(id) ON DELETE CASCADE,
+    project_id BIGINT REFERENCES projects(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    permissions TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS roles_scope_name_idx ON roles (COALESCE(org_id, 0), COALESCE(project_id, 0), name);
+CREATE TABLE IF NOT EXISTS role_bindings (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role TEXT NOT NULL,
+    org_id BIGINT REFERENCES organizations(id) ON DELETE CASCADE,
+    project_id BIGINT REFERENCES projects(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS role_bindings_scope_idx ON role_bindings (user_id, COALESCE(org_id, 0), COALESCE(project_id, 0), role);
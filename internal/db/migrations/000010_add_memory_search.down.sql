@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS memories_search_idx;
+ALTER TABLE memories DROP COLUMN IF EXISTS search;
@@ -0,0 +1,3 @@
+ALTER TABLE memories
+    ADD COLUMN IF NOT EXISTS search TSVECTOR GENERATED ALWAYS AS (to_tsvector('english', content)) STORED;
+CREATE INDEX IF NOT EXISTS memories_search_idx ON memories USING GIN (search);
@@ -0,0 +1,111 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Outbox event kinds. Events carry only the memory ID; applying one brings
+// the vector and graph stores in line with the memory's current row, so
+// events are idempotent and may be applied more than once.
+const (
+	OutboxUpsert = "memory.upsert"
+	OutboxDelete = "memory.delete"
+)
+
+// OutboxEvent is a pending change to propagate from Postgres to the vector
+// and graph stores.
+type OutboxEvent struct {
+	ID        int64  `json:"id"`
+	Kind      string `json:"kind"`
+	MemoryID  int64  `json:"memoryID"`
+	Attempts  int    `json:"attempts"`
+	LastError string `json:"lastError,omitempty"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// Outbox stores events written in the same transaction as the rows they
+// describe.
+type Outbox interface {
+	AddOutbox(ctx context.Context, e OutboxEvent) (int64, error)
+	// DueOutbox returns up to limit pending events available at now,
+	// oldest first.
+	DueOutbox(ctx context.Context, now time.Time, limit int) ([]OutboxEvent, error)
+	// CompleteOutbox marks an event as applied.
+	CompleteOutbox(ctx context.Context, id int64) error
+	// FailOutbox records a failed attempt. The event is retried from
+	// retryAt, or abandoned when retryAt is zero.
+	FailOutbox(ctx context.Context, id int64, errMsg string, retryAt time.Time) error
+}
+
+// TxRepository is a Repository with an outbox whose writes can be grouped
+// into a transaction.
+type TxRepository interface {
+	Repository
+	Outbox
+	// InTx runs fn with a repository bound to a transaction, committing
+	// when fn returns nil and rolling back otherwise.
+	InTx(ctx context.Context, fn func(TxRepository) error) error
+}
+
+var _ TxRepository = (*PgxRepository)(nil)
+
+func (r *PgxRepository) InTx(ctx context.Context, fn func(TxRepository) error) error {
+	if _, ok := r.q.(*pgxpool.Tx); ok {
+		return fn(r)
+	}
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	if err := fn(&PgxRepository{pool: r.pool, q: tx}); err != nil {
+		_ = tx.Rollback(ctx)
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *PgxRepository) AddOutbox(ctx context.Context, e OutboxEvent) (int64, error) {
+	row := r.q.QueryRow(ctx, "INSERT INTO outbox (kind, memory_id) VALUES ($1,$2) RETURNING id", e.Kind, e.MemoryID)
+	var id int64
+	if err := row.Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *PgxRepository) DueOutbox(ctx context.Context, now time.Time, limit int) ([]OutboxEvent, error) {
+	rows, err := r.q.Query(ctx, `SELECT id, kind, memory_id, attempts, last_error, created_at::text
+		FROM outbox WHERE status='pending' AND available_at <= $1 ORDER BY id LIMIT $2`, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []OutboxEvent
+	for rows.Next() {
+		var e OutboxEvent
+		if err := rows.Scan(&e.ID, &e.Kind, &e.MemoryID, &e.Attempts, &e.LastError, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+func (r *PgxRepository) CompleteOutbox(ctx context.Context, id int64) error {
+	_, err := r.q.Exec(ctx, "UPDATE outbox SET status='done', processed_at=NOW() WHERE id=$1", id)
+	return err
+}
+
+func (r *PgxRepository) FailOutbox(ctx context.Context, id int64, errMsg string, retryAt time.Time) error {
+	if retryAt.IsZero() {
+		_, err := r.q.Exec(ctx, `UPDATE outbox SET status='failed', attempts=attempts+1, last_error=$2,
+			processed_at=NOW() WHERE id=$1`, id, errMsg)
+		return err
+	}
+	_, err := r.q.Exec(ctx, "UPDATE outbox SET attempts=attempts+1, last_error=$2, available_at=$3 WHERE id=$1",
+		id, errMsg, retryAt)
+	return err
+}
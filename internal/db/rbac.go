@@ -0,0 +1,116 @@
+package db
+
+import (
+	"context"
+)
+
+// Role is a named set of permissions defined in a project, or in every
+// project of an organization when ProjectID is 0 and OrgID is not. Both
+// are 0 for roles of the default project.
+type Role struct {
+	ID          int64    `json:"id"`
+	OrgID       int64    `json:"orgID,omitempty"`
+	ProjectID   int64    `json:"projectID,omitempty"`
+	Name        string   `json:"name"`
+	Permissions []string `json:"permissions"`
+	CreatedAt   string   `json:"createdAt,omitempty"`
+}
+
+// RoleBinding grants the role named Role to a user in a project, or in
+// every project of an organization when ProjectID is 0 and OrgID is not.
+type RoleBinding struct {
+	ID        int64  `json:"id"`
+	UserID    int64  `json:"userID"`
+	Role      string `json:"role"`
+	OrgID     int64  `json:"orgID,omitempty"`
+	ProjectID int64  `json:"projectID,omitempty"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// Roles stores roles and role bindings.
+type Roles interface {
+	CreateRole(ctx context.Context, r Role) (int64, error)
+	// ListRoles returns the roles of an organization and its projects, or
+	// of the default project when orgID is 0, in ID order.
+	ListRoles(ctx context.Context, orgID int64) ([]Role, error)
+	// DeleteRole deletes a role together with the bindings granting it.
+	DeleteRole(ctx context.Context, id int64) error
+	CreateRoleBinding(ctx context.Context, b RoleBinding) (int64, error)
+	// ListRoleBindings returns a user's bindings, or every binding when
+	// userID is 0, in ID order.
+	ListRoleBindings(ctx context.Context, userID int64) ([]RoleBinding, error)
+	DeleteRoleBinding(ctx context.Context, id int64) error
+}
+
+var _ Roles = (*PgxRepository)(nil)
+
+func (r *PgxRepository) CreateRole(ctx context.Context, role Role) (int64, error) {
+	row := r.q.QueryRow(ctx, `INSERT INTO roles (org_id, project_id, name, permissions)
+		VALUES (NULLIF($1,0),NULLIF($2,0),$3,$4) RETURNING id`,
+		role.OrgID, role.ProjectID, role.Name, tagsOrEmpty(role.Permissions))
+	var id int64
+	if err := row.Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *PgxRepository) ListRoles(ctx context.Context, orgID int64) ([]Role, error) {
+	rows, err := r.q.Query(ctx, `SELECT id, COALESCE(org_id, 0), COALESCE(project_id, 0), name, permissions, created_at::text
+		FROM roles WHERE COALESCE(org_id, 0) = $1 ORDER BY id`, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []Role{}
+	for rows.Next() {
+		var role Role
+		if err := rows.Scan(&role.ID, &role.OrgID, &role.ProjectID, &role.Name, &role.Permissions, &role.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, role)
+	}
+	return out, rows.Err()
+}
+
+func (r *PgxRepository) DeleteRole(ctx context.Context, id int64) error {
+	row := r.q.QueryRow(ctx, `WITH role AS (DELETE FROM roles WHERE id=$1 RETURNING id, name, org_id, project_id),
+		bindings AS (DELETE FROM role_bindings b USING role
+			WHERE b.role = role.name AND b.org_id IS NOT DISTINCT FROM role.org_id
+			AND (role.project_id IS NULL OR b.project_id = role.project_id))
+		SELECT id FROM role`, id)
+	return notFound(row.Scan(&id))
+}
+
+func (r *PgxRepository) CreateRoleBinding(ctx context.Context, b RoleBinding) (int64, error) {
+	row := r.q.QueryRow(ctx, `INSERT INTO role_bindings (user_id, role, org_id, project_id)
+		VALUES ($1,$2,NULLIF($3,0),NULLIF($4,0)) RETURNING id`, b.UserID, b.Role, b.OrgID, b.ProjectID)
+	var id int64
+	if err := row.Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *PgxRepository) ListRoleBindings(ctx context.Context, userID int64) ([]RoleBinding, error) {
+	rows, err := r.q.Query(ctx, `SELECT id, user_id, role, COALESCE(org_id, 0), COALESCE(project_id, 0), created_at::text
+		FROM role_bindings WHERE $1 = 0 OR user_id = $1 ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []RoleBinding{}
+	for rows.Next() {
+		var b RoleBinding
+		if err := rows.Scan(&b.ID, &b.UserID, &b.Role, &b.OrgID, &b.ProjectID, &b.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, b)
+	}
+	return out, rows.Err()
+}
+
+func (r *PgxRepository) DeleteRoleBinding(ctx context.Context, id int64) error {
+	row := r.q.QueryRow(ctx, "DELETE FROM role_bindings WHERE id=$1 RETURNING id", id)
+	return notFound(row.Scan(&id))
+}
@@ -2,33 +2,134 @@ package db
 
 import (
 	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// ErrNotFound is returned when a record does not exist.
+var ErrNotFound = errors.New("db: not found")
+
 // Repository defines persistence operations used by the app.
 type Repository interface {
 	CreateUser(ctx context.Context, username string) (int64, error)
-	CreateMemory(ctx context.Context, userID int64, content string) (int64, error)
+	GetUser(ctx context.Context, id int64) (User, error)
+	CreateMemory(ctx context.Context, m Memory) (int64, error)
 	AddEmbedding(ctx context.Context, memoryID int64, vector []float32) error
+	GetEmbedding(ctx context.Context, memoryID int64) ([]float32, error)
 	GetMemory(ctx context.Context, id int64) (Memory, error)
+	// ListMemories returns up to q.Limit memories matching q, in q's sort
+	// order and after q's cursor. The zero query scans every memory in ID
+	// order.
+	ListMemories(ctx context.Context, q MemoryQuery) ([]Memory, error)
+	// UpdateMemory replaces the content, tags and metadata of m.ID.
+	UpdateMemory(ctx context.Context, m Memory) error
+	DeleteMemory(ctx context.Context, id int64) error
+	AddHistory(ctx context.Context, h HistoryEntry) error
+	// ListHistory returns the history of a memory, oldest first. Entries
+	// outlive the memory itself.
+	ListHistory(ctx context.Context, memoryID int64) ([]HistoryEntry, error)
+}
+
+// User is an account memories belong to. CreatedAt is an RFC 3339
+// timestamp.
+type User struct {
+	ID        int64  `json:"id"`
+	Username  string `json:"username"`
+	CreatedAt string `json:"createdAt"`
 }
 
-// Memory represents a stored memory record.
+// Memory represents a stored memory record. AgentID and RunID identify the
+// agent and session that produced it; Tags and Metadata are free-form.
+// OrgID and ProjectID are the tenant it belongs to, 0 for the default
+// project. CreatedAt is an RFC 3339 timestamp.
 type Memory struct {
-	ID        int64
-	UserID    int64
-	Content   string
-	CreatedAt string
+	ID        int64                  `json:"id"`
+	UserID    int64                  `json:"userID"`
+	OrgID     int64                  `json:"orgID,omitempty"`
+	ProjectID int64                  `json:"projectID,omitempty"`
+	AgentID   string                 `json:"agentID,omitempty"`
+	RunID     string                 `json:"runID,omitempty"`
+	Content   string                 `json:"content"`
+	Tags      []string               `json:"tags,omitempty"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	CreatedAt string                 `json:"createdAt"`
+}
+
+// Sort orders for ListMemories.
+const (
+	SortByID        = "id"
+	SortByCreatedAt = "createdAt"
+)
+
+// MemoryQuery selects, orders and pages memories. Zero fields leave the
+// listing unrestricted. ProjectID is nil to list every project and points
+// to 0 for the default project. CreatedAfter is inclusive and
+// CreatedBefore exclusive; Contains matches content case-insensitively.
+//
+// Memories are ordered by Sort, SortByID when empty, ties broken by ID,
+// descending when Desc is set. A non-zero AfterID continues the listing
+// after that memory; when sorting by creation time AfterCreatedAt must
+// hold its CreatedAt. A Limit of 0 or less returns every match.
+type MemoryQuery struct {
+	UserID         int64
+	ProjectID      *int64
+	AgentID        string
+	RunID          string
+	Tag            string
+	CreatedAfter   time.Time
+	CreatedBefore  time.Time
+	Contains       string
+	Sort           string
+	Desc           bool
+	AfterID        int64
+	AfterCreatedAt string
+	Limit          int
+}
+
+// History events.
+const (
+	HistoryAdd    = "ADD"
+	HistoryUpdate = "UPDATE"
+	HistoryDelete = "DELETE"
+)
+
+// HistoryEntry records one change to a memory: who made it, when, which
+// fields changed and the versions before and after. Old is nil for ADD and
+// New is nil for DELETE.
+type HistoryEntry struct {
+	ID        int64    `json:"id"`
+	MemoryID  int64    `json:"memoryID"`
+	Event     string   `json:"event"`
+	Actor     string   `json:"actor,omitempty"`
+	Fields    []string `json:"fields,omitempty"`
+	Old       *Memory  `json:"old,omitempty"`
+	New       *Memory  `json:"new,omitempty"`
+	CreatedAt string   `json:"createdAt"`
+}
+
+// querier is the query surface shared by a pool and a transaction.
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgxpool.CommandTag, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgxpool.Row
+	Query(ctx context.Context, sql string, args ...interface{}) (pgxpool.Rows, error)
 }
 
-// PgxRepository implements Repository with a pgx pool.
-type PgxRepository struct{ pool *pgxpool.Pool }
+// PgxRepository implements TxRepository with a pgx pool. Inside InTx it
+// runs its queries on the transaction instead.
+type PgxRepository struct {
+	pool *pgxpool.Pool
+	q    querier
+}
 
-func NewRepository(pool *pgxpool.Pool) *PgxRepository { return &PgxRepository{pool: pool} }
+func NewRepository(pool *pgxpool.Pool) *PgxRepository { return &PgxRepository{pool: pool, q: pool} }
 
 func (r *PgxRepository) CreateUser(ctx context.Context, username string) (int64, error) {
-	row := r.pool.QueryRow(ctx, "INSERT INTO users (username) VALUES ($1) RETURNING id", username)
+	row := r.q.QueryRow(ctx, "INSERT INTO users (username) VALUES ($1) RETURNING id", username)
 	var id int64
 	if err := row.Scan(&id); err != nil {
 		return 0, err
@@ -36,8 +137,19 @@ func (r *PgxRepository) CreateUser(ctx context.Context, username string) (int64,
 	return id, nil
 }
 
-func (r *PgxRepository) CreateMemory(ctx context.Context, userID int64, content string) (int64, error) {
-	row := r.pool.QueryRow(ctx, "INSERT INTO memories (user_id, content) VALUES ($1,$2) RETURNING id", userID, content)
+func (r *PgxRepository) GetUser(ctx context.Context, id int64) (User, error) {
+	row := r.q.QueryRow(ctx, "SELECT id, username, created_at::text FROM users WHERE id=$1", id)
+	var u User
+	if err := row.Scan(&u.ID, &u.Username, &u.CreatedAt); err != nil {
+		return User{}, notFound(err)
+	}
+	return u, nil
+}
+
+func (r *PgxRepository) CreateMemory(ctx context.Context, m Memory) (int64, error) {
+	row := r.q.QueryRow(ctx, `INSERT INTO memories (user_id, agent_id, run_id, content, tags, metadata, created_at, org_id, project_id)
+		VALUES ($1,$2,$3,$4,$5,$6,COALESCE(NULLIF($7,'')::timestamptz, NOW()),NULLIF($8,0),NULLIF($9,0)) RETURNING id`,
+		m.UserID, m.AgentID, m.RunID, m.Content, tagsOrEmpty(m.Tags), metadataOrEmpty(m.Metadata), m.CreatedAt, m.OrgID, m.ProjectID)
 	var id int64
 	if err := row.Scan(&id); err != nil {
 		return 0, err
@@ -46,15 +158,160 @@ func (r *PgxRepository) CreateMemory(ctx context.Context, userID int64, content
 }
 
 func (r *PgxRepository) AddEmbedding(ctx context.Context, memoryID int64, vector []float32) error {
-	_, err := r.pool.Exec(ctx, "INSERT INTO embeddings (memory_id, vector) VALUES ($1,$2)", memoryID, vector)
+	_, err := r.q.Exec(ctx, `INSERT INTO embeddings (memory_id, vector) VALUES ($1,$2)
+		ON CONFLICT (memory_id) DO UPDATE SET vector = EXCLUDED.vector`, memoryID, vector)
 	return err
 }
 
+func (r *PgxRepository) GetEmbedding(ctx context.Context, memoryID int64) ([]float32, error) {
+	row := r.q.QueryRow(ctx, "SELECT vector FROM embeddings WHERE memory_id=$1", memoryID)
+	var vec []float32
+	if err := row.Scan(&vec); err != nil {
+		return nil, notFound(err)
+	}
+	return vec, nil
+}
+
 func (r *PgxRepository) GetMemory(ctx context.Context, id int64) (Memory, error) {
-	row := r.pool.QueryRow(ctx, "SELECT id, user_id, content, created_at FROM memories WHERE id=$1", id)
+	row := r.q.QueryRow(ctx, `SELECT id, user_id, agent_id, run_id, content, tags, metadata, created_at::text,
+		COALESCE(org_id, 0), COALESCE(project_id, 0) FROM memories WHERE id=$1`, id)
 	var m Memory
-	if err := row.Scan(&m.ID, &m.UserID, &m.Content, &m.CreatedAt); err != nil {
-		return Memory{}, err
+	if err := row.Scan(&m.ID, &m.UserID, &m.AgentID, &m.RunID, &m.Content, &m.Tags, &m.Metadata, &m.CreatedAt, &m.OrgID, &m.ProjectID); err != nil {
+		return Memory{}, notFound(err)
 	}
 	return m, nil
 }
+
+func (r *PgxRepository) ListMemories(ctx context.Context, q MemoryQuery) ([]Memory, error) {
+	query, args := memoryQuerySQL(q)
+	rows, err := r.q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []Memory{}
+	for rows.Next() {
+		var m Memory
+		if err := rows.Scan(&m.ID, &m.UserID, &m.AgentID, &m.RunID, &m.Content, &m.Tags, &m.Metadata, &m.CreatedAt, &m.OrgID, &m.ProjectID); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+// memoryQuerySQL builds the SELECT for q and its arguments.
+func memoryQuerySQL(q MemoryQuery) (string, []interface{}) {
+	var (
+		where []string
+		args  []interface{}
+	)
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+	if q.UserID != 0 {
+		where = append(where, "user_id = "+arg(q.UserID))
+	}
+	if q.ProjectID != nil {
+		where = append(where, "COALESCE(project_id, 0) = "+arg(*q.ProjectID))
+	}
+	if q.AgentID != "" {
+		where = append(where, "agent_id = "+arg(q.AgentID))
+	}
+	if q.RunID != "" {
+		where = append(where, "run_id = "+arg(q.RunID))
+	}
+	if q.Tag != "" {
+		where = append(where, arg(q.Tag)+" = ANY(tags)")
+	}
+	if !q.CreatedAfter.IsZero() {
+		where = append(where, "created_at >= "+arg(q.CreatedAfter))
+	}
+	if !q.CreatedBefore.IsZero() {
+		where = append(where, "created_at < "+arg(q.CreatedBefore))
+	}
+	if q.Contains != "" {
+		where = append(where, "strpos(lower(content), lower("+arg(q.Contains)+")) > 0")
+	}
+	cmp, dir := ">", "ASC"
+	if q.Desc {
+		cmp, dir = "<", "DESC"
+	}
+	order := "id " + dir
+	if q.Sort == SortByCreatedAt {
+		order = "created_at " + dir + ", " + order
+		if q.AfterID != 0 {
+			where = append(where, "(created_at, id) "+cmp+" ("+arg(q.AfterCreatedAt)+"::timestamptz, "+arg(q.AfterID)+")")
+		}
+	} else if q.AfterID != 0 {
+		where = append(where, "id "+cmp+" "+arg(q.AfterID))
+	}
+	query := `SELECT id, user_id, agent_id, run_id, content, tags, metadata, created_at::text,
+		COALESCE(org_id, 0), COALESCE(project_id, 0) FROM memories`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY " + order
+	if q.Limit > 0 {
+		query += " LIMIT " + arg(q.Limit)
+	}
+	return query, args
+}
+
+func (r *PgxRepository) UpdateMemory(ctx context.Context, m Memory) error {
+	_, err := r.q.Exec(ctx, "UPDATE memories SET content=$2, tags=$3, metadata=$4 WHERE id=$1",
+		m.ID, m.Content, tagsOrEmpty(m.Tags), metadataOrEmpty(m.Metadata))
+	return err
+}
+
+func (r *PgxRepository) DeleteMemory(ctx context.Context, id int64) error {
+	_, err := r.q.Exec(ctx, "DELETE FROM memories WHERE id=$1", id)
+	return err
+}
+
+func (r *PgxRepository) AddHistory(ctx context.Context, h HistoryEntry) error {
+	_, err := r.q.Exec(ctx, `INSERT INTO memory_history (memory_id, event, actor, fields, old_value, new_value)
+		VALUES ($1,$2,$3,$4,$5,$6)`, h.MemoryID, h.Event, h.Actor, tagsOrEmpty(h.Fields), h.Old, h.New)
+	return err
+}
+
+func (r *PgxRepository) ListHistory(ctx context.Context, memoryID int64) ([]HistoryEntry, error) {
+	rows, err := r.q.Query(ctx, `SELECT id, memory_id, event, actor, fields, old_value, new_value, created_at::text
+		FROM memory_history WHERE memory_id=$1 ORDER BY id`, memoryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []HistoryEntry
+	for rows.Next() {
+		var h HistoryEntry
+		if err := rows.Scan(&h.ID, &h.MemoryID, &h.Event, &h.Actor, &h.Fields, &h.Old, &h.New, &h.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, h)
+	}
+	return out, rows.Err()
+}
+
+// notFound maps pgx.ErrNoRows to ErrNotFound.
+func notFound(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func tagsOrEmpty(tags []string) []string {
+	if tags == nil {
+		return []string{}
+	}
+	return tags
+}
+
+func metadataOrEmpty(md map[string]interface{}) map[string]interface{} {
+	if md == nil {
+		return map[string]interface{}{}
+	}
+	return md
+}
@@ -0,0 +1,68 @@
+package db
+
+import (
+	"context"
+)
+
+// TextQuery is a keyword search over memory content within one project,
+// 0 being the default project. The other fields narrow it like the
+// corresponding vector payload filters; Tags match memories with any of
+// them. A Limit of 0 or less returns every match.
+type TextQuery struct {
+	Query     string
+	UserID    int64
+	ProjectID int64
+	AgentID   string
+	RunID     string
+	Tags      []string
+	Limit     int
+	// Offset skips that many of the best matches.
+	Offset int
+}
+
+// TextMatch is a memory matching a TextQuery with its relevance; higher
+// scores are better.
+type TextMatch struct {
+	MemoryID int64   `json:"memoryID"`
+	Score    float32 `json:"score"`
+}
+
+// TextSearcher ranks memories by keyword relevance.
+type TextSearcher interface {
+	// SearchText returns the memories matching any term of q.Query, best
+	// first.
+	SearchText(ctx context.Context, q TextQuery) ([]TextMatch, error)
+}
+
+var _ TextSearcher = (*PgxRepository)(nil)
+
+// SearchText ranks memories with the full-text index on content. Terms are
+// stemmed and or-ed together, and matches are ranked by ts_rank_cd.
+func (r *PgxRepository) SearchText(ctx context.Context, q TextQuery) ([]TextMatch, error) {
+	limit := interface{}(nil)
+	if q.Limit > 0 {
+		limit = q.Limit
+	}
+	rows, err := r.q.Query(ctx, `WITH query AS (
+			SELECT NULLIF(replace(plainto_tsquery('english', $1)::text, '&', '|'), '')::tsquery AS q
+		)
+		SELECT id, ts_rank_cd(search, query.q) AS score FROM memories, query
+		WHERE search @@ query.q AND COALESCE(project_id, 0) = $2
+			AND ($3 = 0 OR user_id = $3) AND ($4 = '' OR agent_id = $4) AND ($5 = '' OR run_id = $5)
+			AND (cardinality($6::text[]) = 0 OR tags && $6)
+		ORDER BY score DESC, id LIMIT $7 OFFSET $8`,
+		q.Query, q.ProjectID, q.UserID, q.AgentID, q.RunID, tagsOrEmpty(q.Tags), limit, q.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []TextMatch{}
+	for rows.Next() {
+		var m TextMatch
+		if err := rows.Scan(&m.MemoryID, &m.Score); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,107 @@
+package db
+
+import (
+	"context"
+)
+
+// Organization is a tenant owning projects. CreatedAt is an RFC 3339
+// timestamp.
+type Organization struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// Project partitions an organization's memories, entities and
+// relationships from every other project's. CreatedAt is an RFC 3339
+// timestamp.
+type Project struct {
+	ID        int64  `json:"id"`
+	OrgID     int64  `json:"orgID"`
+	Name      string `json:"name"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// Tenants stores organizations and projects.
+type Tenants interface {
+	CreateOrganization(ctx context.Context, name string) (int64, error)
+	GetOrganization(ctx context.Context, id int64) (Organization, error)
+	// ListOrganizations returns every organization in ID order.
+	ListOrganizations(ctx context.Context) ([]Organization, error)
+	CreateProject(ctx context.Context, p Project) (int64, error)
+	GetProject(ctx context.Context, id int64) (Project, error)
+	// ListProjects returns an organization's projects in ID order.
+	ListProjects(ctx context.Context, orgID int64) ([]Project, error)
+}
+
+var _ Tenants = (*PgxRepository)(nil)
+
+func (r *PgxRepository) CreateOrganization(ctx context.Context, name string) (int64, error) {
+	row := r.q.QueryRow(ctx, "INSERT INTO organizations (name) VALUES ($1) RETURNING id", name)
+	var id int64
+	if err := row.Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *PgxRepository) GetOrganization(ctx context.Context, id int64) (Organization, error) {
+	row := r.q.QueryRow(ctx, "SELECT id, name, created_at::text FROM organizations WHERE id=$1", id)
+	var o Organization
+	if err := row.Scan(&o.ID, &o.Name, &o.CreatedAt); err != nil {
+		return Organization{}, notFound(err)
+	}
+	return o, nil
+}
+
+func (r *PgxRepository) ListOrganizations(ctx context.Context) ([]Organization, error) {
+	rows, err := r.q.Query(ctx, "SELECT id, name, created_at::text FROM organizations ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []Organization{}
+	for rows.Next() {
+		var o Organization
+		if err := rows.Scan(&o.ID, &o.Name, &o.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, o)
+	}
+	return out, rows.Err()
+}
+
+func (r *PgxRepository) CreateProject(ctx context.Context, p Project) (int64, error) {
+	row := r.q.QueryRow(ctx, "INSERT INTO projects (org_id, name) VALUES ($1,$2) RETURNING id", p.OrgID, p.Name)
+	var id int64
+	if err := row.Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *PgxRepository) GetProject(ctx context.Context, id int64) (Project, error) {
+	row := r.q.QueryRow(ctx, "SELECT id, org_id, name, created_at::text FROM projects WHERE id=$1", id)
+	var p Project
+	if err := row.Scan(&p.ID, &p.OrgID, &p.Name, &p.CreatedAt); err != nil {
+		return Project{}, notFound(err)
+	}
+	return p, nil
+}
+
+func (r *PgxRepository) ListProjects(ctx context.Context, orgID int64) ([]Project, error) {
+	rows, err := r.q.Query(ctx, "SELECT id, org_id, name, created_at::text FROM projects WHERE org_id=$1 ORDER BY id", orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []Project{}
+	for rows.Next() {
+		var p Project
+		if err := rows.Scan(&p.ID, &p.OrgID, &p.Name, &p.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
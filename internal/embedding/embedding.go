@@ -0,0 +1,58 @@
+package embedding
+
+import (
+	"context"
+	"os"
+	"strconv"
+)
+
+// Embedder turns texts into fixed-size vectors.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+	// Dimension reports the length of the vectors returned by Embed.
+	Dimension() int
+}
+
+// Config holds embedding provider settings.
+type Config struct {
+	// BaseURL is the OpenAI-compatible API root. When both BaseURL and
+	// APIKey are empty the offline Hashing embedder is used.
+	BaseURL   string
+	APIKey    string
+	Model     string
+	Dimension int
+}
+
+// LoadConfig reads settings from environment variables with fallbacks.
+func LoadConfig() Config {
+	cfg := Config{
+		BaseURL: os.Getenv("MEM0_EMBEDDING_URL"),
+		APIKey:  os.Getenv("MEM0_EMBEDDING_KEY"),
+		Model:   os.Getenv("MEM0_EMBEDDING_MODEL"),
+	}
+	if cfg.Model == "" {
+		cfg.Model = "text-embedding-3-small"
+	}
+	if d, err := strconv.Atoi(os.Getenv("MEM0_EMBEDDING_DIM")); err == nil && d > 0 {
+		cfg.Dimension = d
+	}
+	return cfg
+}
+
+// New returns the embedder described by cfg.
+func New(cfg Config) Embedder {
+	if cfg.BaseURL == "" && cfg.APIKey == "" {
+		dim := cfg.Dimension
+		if dim == 0 {
+			dim = DefaultHashingDimension
+		}
+		return NewHashing(dim)
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = "https://api.openai.com/v1"
+	}
+	if cfg.Dimension == 0 {
+		cfg.Dimension = 1536
+	}
+	return NewOpenAI(cfg)
+}
@@ -0,0 +1,75 @@
+package embedding
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func cosine(a, b []float32) float32 {
+	var dot float32
+	for i := range a {
+		dot += a[i] * b[i]
+	}
+	return dot
+}
+
+func TestHashing(t *testing.T) {
+	h := New(Config{Dimension: 64})
+	if h.Dimension() != 64 {
+		t.Fatalf("unexpected dimension %d", h.Dimension())
+	}
+	vecs, err := h.Embed(context.Background(), []string{"Alice likes tea", "alice LIKES tea!", "Bob drives trucks", ""})
+	if err != nil {
+		t.Fatalf("embed: %v", err)
+	}
+	if len(vecs) != 4 || len(vecs[0]) != 64 {
+		t.Fatalf("unexpected shape")
+	}
+	if s := cosine(vecs[0], vecs[1]); s < 0.999 {
+		t.Fatalf("identical texts should match, got %f", s)
+	}
+	if cosine(vecs[0], vecs[2]) >= cosine(vecs[0], vecs[1]) {
+		t.Fatalf("unrelated text scored too high")
+	}
+	if cosine(vecs[3], vecs[3]) != 0 {
+		t.Fatalf("empty text should embed to zero vector")
+	}
+}
+
+func TestOpenAI(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" || r.Header.Get("Authorization") != "Bearer k" {
+			t.Fatalf("unexpected request %s", r.URL.Path)
+		}
+		var req struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if req.Model != "m" || len(req.Input) != 2 {
+			t.Fatalf("bad request %+v", req)
+		}
+		// answer out of order to check index handling
+		_, _ = w.Write([]byte(`{"data":[{"index":1,"embedding":[0,1]},{"index":0,"embedding":[1,0]}]}`))
+	}))
+	defer srv.Close()
+
+	e := New(Config{BaseURL: srv.URL + "/v1", APIKey: "k", Model: "m", Dimension: 2})
+	vecs, err := e.Embed(context.Background(), []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("embed: %v", err)
+	}
+	if vecs[0][0] != 1 || vecs[1][1] != 1 {
+		t.Fatalf("unexpected vectors %v", vecs)
+	}
+
+	e = New(Config{BaseURL: srv.URL + "/v1", APIKey: "k", Model: "m", Dimension: 3})
+	if _, err := e.Embed(context.Background(), []string{"a", "b"}); err == nil {
+		t.Fatalf("expected dimension error")
+	}
+}
@@ -0,0 +1,63 @@
+package embedding
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// DefaultHashingDimension is the vector size used when none is configured.
+const DefaultHashingDimension = 256
+
+// Hashing is an offline bag-of-words embedder. Each lowercased word is
+// hashed into one of dim buckets with a hash-derived sign and the result is
+// L2 normalised, so texts sharing words get a high cosine similarity. It has
+// no notion of meaning but is deterministic and needs no network.
+type Hashing struct{ dim int }
+
+// NewHashing returns a Hashing embedder producing dim-sized vectors.
+func NewHashing(dim int) *Hashing { return &Hashing{dim: dim} }
+
+// Dimension reports the vector length.
+func (h *Hashing) Dimension() int { return h.dim }
+
+// Embed hashes each text into a vector.
+func (h *Hashing) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, len(texts))
+	for i, t := range texts {
+		out[i] = h.embed(t)
+	}
+	return out, nil
+}
+
+func (h *Hashing) embed(text string) []float32 {
+	v := make([]float32, h.dim)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, w := range words {
+		f := fnv.New64a()
+		_, _ = f.Write([]byte(w))
+		sum := f.Sum64()
+		idx := sum % uint64(h.dim)
+		if sum>>63 == 1 {
+			v[idx]--
+		} else {
+			v[idx]++
+		}
+	}
+	var norm float64
+	for _, x := range v {
+		norm += float64(x) * float64(x)
+	}
+	if norm == 0 {
+		return v
+	}
+	scale := float32(1 / math.Sqrt(norm))
+	for i := range v {
+		v[i] *= scale
+	}
+	return v
+}
@@ -0,0 +1,83 @@
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// OpenAI implements Embedder using an OpenAI-compatible embeddings API.
+type OpenAI struct {
+	baseURL    string
+	apiKey     string
+	model      string
+	dim        int
+	httpClient *http.Client
+}
+
+// NewOpenAI constructs an embedder talking to cfg.BaseURL.
+func NewOpenAI(cfg Config) *OpenAI {
+	return &OpenAI{
+		baseURL:    strings.TrimRight(cfg.BaseURL, "/"),
+		apiKey:     cfg.APIKey,
+		model:      cfg.Model,
+		dim:        cfg.Dimension,
+		httpClient: &http.Client{},
+	}
+}
+
+// Dimension reports the configured vector length.
+func (o *OpenAI) Dimension() int { return o.dim }
+
+// Embed requests embeddings for texts in a single batch.
+func (o *OpenAI) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(struct {
+		Model string   `json:"model"`
+		Input []string `json:"input"`
+	}{o.model, texts})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.baseURL+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if o.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.apiKey)
+	}
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("embedding status %d", resp.StatusCode)
+	}
+	var out struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	if len(out.Data) != len(texts) {
+		return nil, fmt.Errorf("embedding: got %d vectors for %d inputs", len(out.Data), len(texts))
+	}
+	vecs := make([][]float32, len(texts))
+	for _, d := range out.Data {
+		if d.Index < 0 || d.Index >= len(texts) {
+			return nil, fmt.Errorf("embedding: index %d out of range", d.Index)
+		}
+		if o.dim > 0 && len(d.Embedding) != o.dim {
+			return nil, fmt.Errorf("embedding: got dimension %d, want %d", len(d.Embedding), o.dim)
+		}
+		vecs[d.Index] = d.Embedding
+	}
+	return vecs, nil
+}
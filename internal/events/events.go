@@ -0,0 +1,103 @@
+package events
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"mem0-go/internal/db"
+	"mem0-go/internal/graph"
+)
+
+// Kind identifies what happened.
+type Kind string
+
+const (
+	MemoryAdded         Kind = "memory.added"
+	MemoryUpdated       Kind = "memory.updated"
+	MemoryDeleted       Kind = "memory.deleted"
+	RelationshipCreated Kind = "relationship.created"
+)
+
+// Event is a committed change. Memory is set for memory events and holds
+// the memory as it was last stored; Relationship is set for relationship
+// events.
+type Event struct {
+	Kind         Kind
+	Memory       db.Memory
+	Relationship graph.Edge
+	Actor        string
+	At           time.Time
+}
+
+// DefaultBuffer is the number of events a subscriber may fall behind by
+// before further events are dropped for it.
+const DefaultBuffer = 64
+
+// Bus fans events out to subscribers. Publishing never blocks: a
+// subscriber whose buffer is full misses the event.
+type Bus struct {
+	mu     sync.RWMutex
+	subs   map[*Subscription]struct{}
+	buffer int
+}
+
+// NewBus returns a bus whose subscribers buffer DefaultBuffer events.
+func NewBus() *Bus {
+	return &Bus{subs: make(map[*Subscription]struct{}), buffer: DefaultBuffer}
+}
+
+// Subscription receives the events accepted by its filter on C until
+// Close is called.
+type Subscription struct {
+	C <-chan Event
+
+	bus     *Bus
+	ch      chan Event
+	filter  func(Event) bool
+	once    sync.Once
+	dropped atomic.Int64
+}
+
+// Subscribe registers a subscriber. A nil filter accepts every event.
+func (b *Bus) Subscribe(filter func(Event) bool) *Subscription {
+	ch := make(chan Event, b.buffer)
+	s := &Subscription{C: ch, bus: b, ch: ch, filter: filter}
+	b.mu.Lock()
+	b.subs[s] = struct{}{}
+	b.mu.Unlock()
+	return s
+}
+
+// Publish delivers e to every matching subscriber. A zero At is set to
+// the current time.
+func (b *Bus) Publish(e Event) {
+	if e.At.IsZero() {
+		e.At = time.Now().UTC()
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for s := range b.subs {
+		if s.filter != nil && !s.filter(e) {
+			continue
+		}
+		select {
+		case s.ch <- e:
+		default:
+			s.dropped.Add(1)
+		}
+	}
+}
+
+// Close unsubscribes and closes C.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.bus.mu.Lock()
+		delete(s.bus.subs, s)
+		s.bus.mu.Unlock()
+		close(s.ch)
+	})
+}
+
+// Dropped reports how many events were dropped because C was full.
+func (s *Subscription) Dropped() int { return int(s.dropped.Load()) }
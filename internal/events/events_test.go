@@ -0,0 +1,37 @@
+package events
+
+import (
+	"testing"
+
+	"mem0-go/internal/db"
+)
+
+func TestBusFiltersAndDropsWhenFull(t *testing.T) {
+	b := NewBus()
+	b.buffer = 1
+	mine := b.Subscribe(func(e Event) bool { return e.Memory.UserID == 1 })
+	all := b.Subscribe(nil)
+
+	b.Publish(Event{Kind: MemoryAdded, Memory: db.Memory{ID: 1, UserID: 1}})
+	b.Publish(Event{Kind: MemoryAdded, Memory: db.Memory{ID: 2, UserID: 2}})
+
+	if e := <-mine.C; e.Memory.ID != 1 || e.At.IsZero() {
+		t.Fatalf("unexpected event %+v", e)
+	}
+	if mine.Dropped() != 0 {
+		t.Fatalf("filtered events must not count as dropped")
+	}
+	if e := <-all.C; e.Memory.ID != 1 || all.Dropped() != 1 {
+		t.Fatalf("expected second event to be dropped, got %+v dropped=%d", e, all.Dropped())
+	}
+
+	mine.Close()
+	mine.Close()
+	if _, ok := <-mine.C; ok {
+		t.Fatal("expected closed channel")
+	}
+	b.Publish(Event{Kind: MemoryDeleted, Memory: db.Memory{ID: 1, UserID: 1}})
+	if e := <-all.C; e.Kind != MemoryDeleted {
+		t.Fatalf("unexpected event %+v", e)
+	}
+}
@@ -0,0 +1,147 @@
+// Package extract finds the entities a memory mentions and the
+// relationships it states between them.
+package extract
+
+import (
+	"context"
+	"os"
+	"strings"
+	"unicode"
+
+	"mem0-go/internal/llm"
+)
+
+// Entity types the extractors assign. Other types are kept as given.
+const (
+	TypeEntity       = "Entity"
+	TypePerson       = "Person"
+	TypeOrganization = "Organization"
+	TypeLocation     = "Location"
+)
+
+// Entity is a named thing mentioned in a text.
+type Entity struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Relation is a typed relationship stated between two entities, named by
+// their Name.
+type Relation struct {
+	Source string `json:"source"`
+	Type   string `json:"type"`
+	Target string `json:"target"`
+}
+
+// Result is what an Extractor found in a text.
+type Result struct {
+	Entities  []Entity   `json:"entities"`
+	Relations []Relation `json:"relations"`
+}
+
+// Extractor finds entities and relations in text.
+type Extractor interface {
+	Extract(ctx context.Context, text string) (Result, error)
+}
+
+// Modes select an extractor.
+const (
+	ModeOff   = "off"
+	ModeRules = "rules"
+	ModeLLM   = "llm"
+)
+
+// Config holds extractor settings.
+type Config struct {
+	// Mode is ModeRules, ModeLLM or ModeOff.
+	Mode string
+	// LLM configures the model ModeLLM sends texts to.
+	LLM llm.Config
+}
+
+// LoadConfig reads settings from environment variables. Extraction is off
+// unless MEM0_EXTRACTOR selects a mode; the model is the one configured
+// for fact extraction.
+func LoadConfig() Config {
+	mode := os.Getenv("MEM0_EXTRACTOR")
+	if mode == "" {
+		mode = ModeOff
+	}
+	return Config{Mode: mode, LLM: llm.LoadConfig()}
+}
+
+// New returns the extractor described by cfg, or nil when extraction is
+// off. ModeLLM falls back to the rules when no model URL is configured.
+func New(cfg Config) Extractor {
+	switch cfg.Mode {
+	case ModeOff:
+		return nil
+	case ModeLLM:
+		if cfg.LLM.BaseURL != "" {
+			return NewLLM(llm.NewOpenAI(cfg.LLM))
+		}
+	}
+	return NewRules()
+}
+
+// Normalize cleans r: names are trimmed, entity types title-cased and
+// relation types upper snake-cased, entities are deduplicated by name and
+// relations dropped unless both ends are among the entities.
+func Normalize(r Result) Result {
+	var out Result
+	seen := map[string]bool{}
+	for _, e := range r.Entities {
+		e.Name = strings.Join(strings.Fields(e.Name), " ")
+		if e.Name == "" || seen[e.Name] {
+			continue
+		}
+		seen[e.Name] = true
+		e.Type = Label(e.Type)
+		out.Entities = append(out.Entities, e)
+	}
+	rels := map[Relation]bool{}
+	for _, rel := range r.Relations {
+		rel.Source = strings.Join(strings.Fields(rel.Source), " ")
+		rel.Target = strings.Join(strings.Fields(rel.Target), " ")
+		rel.Type = RelationType(rel.Type)
+		if rel.Type == "" || rel.Source == rel.Target || !seen[rel.Source] || !seen[rel.Target] || rels[rel] {
+			continue
+		}
+		rels[rel] = true
+		out.Relations = append(out.Relations, rel)
+	}
+	return out
+}
+
+// Label returns t as a graph label: its letters and digits in title case,
+// such as "Organization" for "organization", or TypeEntity when t has
+// none.
+func Label(t string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range t {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if upper {
+				r = unicode.ToUpper(r)
+			} else {
+				r = unicode.ToLower(r)
+			}
+			b.WriteRune(r)
+			upper = false
+		default:
+			upper = true
+		}
+	}
+	if b.Len() == 0 {
+		return TypeEntity
+	}
+	return b.String()
+}
+
+// RelationType returns t as a relationship type: its words upper-cased and
+// joined by underscores, such as "WORKS_AT" for "works at".
+func RelationType(t string) string {
+	words := strings.FieldsFunc(t, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+	return strings.ToUpper(strings.Join(words, "_"))
+}
@@ -0,0 +1,88 @@
+package extract
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestRules(t *testing.T) {
+	r, err := NewRules().Extract(context.Background(),
+		"Alice works at Acme Corp. She also lives in New York; Alice's manager is Bob Smith. I met Carol yesterday!")
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	want := Result{
+		Entities: []Entity{
+			{Name: "Alice", Type: TypePerson},
+			{Name: "Acme Corp", Type: TypeOrganization},
+			{Name: "New York", Type: TypeEntity},
+			{Name: "Bob Smith", Type: TypeEntity},
+			{Name: "Carol", Type: TypeEntity},
+		},
+		Relations: []Relation{{Source: "Alice", Type: "WORKS_AT", Target: "Acme Corp"}},
+	}
+	if !reflect.DeepEqual(r, want) {
+		t.Fatalf("got %+v\nwant %+v", r, want)
+	}
+
+	r, _ = NewRules().Extract(context.Background(), "Bob is married to Carol and Carol visited Paris")
+	rels := []Relation{{Source: "Bob", Type: "MARRIED_TO", Target: "Carol"}, {Source: "Carol", Type: "VISITED", Target: "Paris"}}
+	if !reflect.DeepEqual(r.Relations, rels) || r.Entities[2] != (Entity{Name: "Paris", Type: TypeLocation}) {
+		t.Fatalf("unexpected result %+v", r)
+	}
+	if r, _ := NewRules().Extract(context.Background(), "likes green tea"); len(r.Entities) != 0 {
+		t.Fatalf("expected no entities, got %+v", r)
+	}
+}
+
+type fakeChat struct {
+	reply Result
+	err   error
+}
+
+func (f fakeChat) Chat(_ context.Context, _, _ string, out interface{}) error {
+	*out.(*Result) = f.reply
+	return f.err
+}
+
+func TestLLMNormalizes(t *testing.T) {
+	reply := Result{
+		Entities: []Entity{{Name: " Alice ", Type: "person"}, {Name: "Acme", Type: "company name"}, {Name: "Alice", Type: "Person"}},
+		Relations: []Relation{
+			{Source: "Alice", Type: "works at", Target: "Acme"},
+			{Source: "Alice", Type: "works-at", Target: "Acme"},
+			{Source: "Alice", Type: "knows", Target: "Dave"},
+		},
+	}
+	r, err := NewLLM(fakeChat{reply: reply}).Extract(context.Background(), "Alice works at Acme")
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	want := Result{
+		Entities:  []Entity{{Name: "Alice", Type: "Person"}, {Name: "Acme", Type: "CompanyName"}},
+		Relations: []Relation{{Source: "Alice", Type: "WORKS_AT", Target: "Acme"}},
+	}
+	if !reflect.DeepEqual(r, want) {
+		t.Fatalf("got %+v\nwant %+v", r, want)
+	}
+	boom := errors.New("boom")
+	if _, err := NewLLM(fakeChat{err: boom}).Extract(context.Background(), "x"); !errors.Is(err, boom) {
+		t.Fatalf("expected model error, got %v", err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	if New(Config{Mode: ModeOff}) != nil {
+		t.Fatal("expected no extractor when off")
+	}
+	if _, ok := New(Config{Mode: ModeLLM}).(*Rules); !ok {
+		t.Fatal("expected rules without a model URL")
+	}
+	cfg := Config{Mode: ModeLLM}
+	cfg.LLM.BaseURL = "http://localhost:1"
+	if _, ok := New(cfg).(*LLM); !ok {
+		t.Fatal("expected the llm extractor")
+	}
+}
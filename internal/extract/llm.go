@@ -0,0 +1,35 @@
+package extract
+
+import "context"
+
+const extractPrompt = `You extract a knowledge graph from a short memory about a user.
+Return a JSON object {"entities": [...], "relations": [...]} where each entity is
+{"name": "...", "type": "..."} with type one of Person, Organization, Location or
+another single-word category, and each relation is
+{"source": "...", "type": "...", "target": "..."} naming two of the entities with
+a short verb phrase such as works_at, lives_in or married_to. Name entities as
+written in the text. Do not add the user as an entity unless named. Return
+{"entities": [], "relations": []} when the memory names nothing.`
+
+// chatter sends prompts to a chat model and decodes its JSON reply.
+type chatter interface {
+	Chat(ctx context.Context, system, user string, out interface{}) error
+}
+
+// LLM extracts entities and relations with a chat model, such as an
+// llm.OpenAI talking to an OpenAI-compatible endpoint.
+type LLM struct {
+	model chatter
+}
+
+// NewLLM returns an extractor asking model.
+func NewLLM(model chatter) *LLM { return &LLM{model: model} }
+
+// Extract asks the model for the entities and relations in text.
+func (l *LLM) Extract(ctx context.Context, text string) (Result, error) {
+	var r Result
+	if err := l.model.Chat(ctx, extractPrompt, text, &r); err != nil {
+		return Result{}, err
+	}
+	return Normalize(r), nil
+}
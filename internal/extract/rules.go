@@ -0,0 +1,167 @@
+package extract
+
+import (
+	"context"
+	"strings"
+	"unicode"
+)
+
+// rule types a relationship stated by a phrase between two entities, and
+// the entities by their role in it.
+type rule struct {
+	relation, source, target string
+}
+
+// phrases maps the lowercased words between two entities of a sentence to
+// the relationship they state.
+var phrases = map[string]rule{
+	"works at":          {"WORKS_AT", TypePerson, TypeOrganization},
+	"works for":         {"WORKS_AT", TypePerson, TypeOrganization},
+	"is employed by":    {"WORKS_AT", TypePerson, TypeOrganization},
+	"joined":            {"WORKS_AT", TypePerson, TypeOrganization},
+	"studied at":        {"STUDIED_AT", TypePerson, TypeOrganization},
+	"studies at":        {"STUDIED_AT", TypePerson, TypeOrganization},
+	"graduated from":    {"STUDIED_AT", TypePerson, TypeOrganization},
+	"is a member of":    {"MEMBER_OF", TypePerson, TypeOrganization},
+	"founded":           {"FOUNDED", TypePerson, TypeOrganization},
+	"lives in":          {"LIVES_IN", TypePerson, TypeLocation},
+	"moved to":          {"LIVES_IN", TypePerson, TypeLocation},
+	"is based in":       {"LOCATED_IN", TypeEntity, TypeLocation},
+	"is located in":     {"LOCATED_IN", TypeEntity, TypeLocation},
+	"is from":           {"FROM", TypePerson, TypeLocation},
+	"comes from":        {"FROM", TypePerson, TypeLocation},
+	"was born in":       {"BORN_IN", TypePerson, TypeLocation},
+	"visited":           {"VISITED", TypePerson, TypeLocation},
+	"is married to":     {"MARRIED_TO", TypePerson, TypePerson},
+	"married":           {"MARRIED_TO", TypePerson, TypePerson},
+	"knows":             {"KNOWS", TypePerson, TypePerson},
+	"met":               {"KNOWS", TypePerson, TypePerson},
+	"is friends with":   {"FRIEND_OF", TypePerson, TypePerson},
+	"manages":           {"MANAGES", TypePerson, TypePerson},
+	"reports to":        {"REPORTS_TO", TypePerson, TypePerson},
+	"is the manager of": {"MANAGES", TypePerson, TypePerson},
+}
+
+// fillers are words skipped between two entities when matching phrases.
+var fillers = map[string]bool{"also": true, "now": true, "recently": true, "currently": true, "still": true}
+
+// starters are capitalized words that begin sentences rather than name
+// entities.
+var starters = map[string]bool{
+	"I": true, "A": true, "An": true, "The": true, "My": true, "Our": true, "Your": true,
+	"His": true, "Her": true, "Their": true, "He": true, "She": true, "They": true,
+	"We": true, "You": true, "It": true, "This": true, "That": true, "These": true,
+	"Those": true, "User": true, "Also": true, "And": true, "But": true, "Yes": true,
+	"No": true, "Recently": true, "Today": true, "Yesterday": true, "When": true, "If": true,
+}
+
+// Rules is a deterministic offline Extractor. Entities are runs of
+// capitalized words and relations come from a fixed table of phrases
+// joining two entities in a sentence, such as "Alice works at Acme", which
+// also types Alice as a Person and Acme as an Organization. It misses a lot
+// but needs no model.
+type Rules struct{}
+
+// NewRules returns a rule-based extractor.
+func NewRules() *Rules { return &Rules{} }
+
+// Extract finds the entities and relations in text.
+func (*Rules) Extract(_ context.Context, text string) (Result, error) {
+	var r Result
+	types := map[string]string{}
+	for _, sentence := range sentences(text) {
+		spans := entitySpans(sentence)
+		for i, s := range spans {
+			name := sentence[s[0]:s[1]]
+			if _, ok := types[name]; !ok {
+				types[name] = TypeEntity
+				r.Entities = append(r.Entities, Entity{Name: name})
+			}
+			if i == 0 {
+				continue
+			}
+			prev := sentence[spans[i-1][0]:spans[i-1][1]]
+			rl, ok := phrases[between(sentence[spans[i-1][1]:s[0]])]
+			if !ok {
+				continue
+			}
+			r.Relations = append(r.Relations, Relation{Source: prev, Type: rl.relation, Target: name})
+			for n, t := range map[string]string{prev: rl.source, name: rl.target} {
+				if types[n] == TypeEntity {
+					types[n] = t
+				}
+			}
+		}
+	}
+	for i := range r.Entities {
+		r.Entities[i].Type = types[r.Entities[i].Name]
+	}
+	return Normalize(r), nil
+}
+
+// sentences splits text at sentence ends and line breaks.
+func sentences(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool { return r == '.' || r == '!' || r == '?' || r == ';' || r == '\n' })
+}
+
+// entitySpans returns the byte ranges of the runs of capitalized words in
+// s, skipping sentence starters and a trailing possessive 's.
+func entitySpans(s string) [][2]int {
+	var spans [][2]int
+	start, end := -1, -1
+	flush := func() {
+		if start >= 0 {
+			spans = append(spans, [2]int{start, end})
+		}
+		start = -1
+	}
+	for _, w := range words(s) {
+		word := strings.TrimSuffix(strings.TrimSuffix(s[w[0]:w[1]], "'s"), "’s")
+		if word == "" || !unicode.IsUpper([]rune(word)[0]) || (start < 0 && starters[word]) {
+			flush()
+			continue
+		}
+		if start >= 0 && strings.TrimSpace(s[end:w[0]]) != "" {
+			flush()
+		}
+		if start < 0 {
+			start = w[0]
+		}
+		end = w[0] + len(word)
+		if len(word) < w[1]-w[0] {
+			flush()
+		}
+	}
+	flush()
+	return spans
+}
+
+// words returns the byte ranges of the words of s: runs of letters, digits,
+// apostrophes, hyphens and ampersands.
+func words(s string) [][2]int {
+	var out [][2]int
+	start := -1
+	for i, r := range s + " " {
+		inWord := unicode.IsLetter(r) || unicode.IsDigit(r) || r == '\'' || r == '’' || r == '-' || r == '&'
+		switch {
+		case inWord && start < 0:
+			start = i
+		case !inWord && start >= 0:
+			out = append(out, [2]int{start, i})
+			start = -1
+		}
+	}
+	return out
+}
+
+// between returns the lowercased words of s without fillers, as looked up
+// in phrases.
+func between(s string) string {
+	var out []string
+	for _, w := range strings.Fields(strings.ToLower(s)) {
+		if !fillers[w] {
+			out = append(out, w)
+		}
+	}
+	return strings.Join(out, " ")
+}
@@ -3,84 +3,96 @@ package fiber
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"mime"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
 )
 
 type Map map[string]interface{}
 
 // Handler defines a request handler used by middleware and routes.
-type Handler func(*Ctx) error
+type Handler = func(*Ctx) error
 
 // Config allows customizing app behavior.
 type Config struct {
 	ErrorHandler func(*Ctx, error) error
 }
 
-// App is a minimal HTTP application.
+// App is a minimal HTTP application. Middleware and routes run in the
+// order they were registered.
 type App struct {
-	mux        *http.ServeMux
-	config     Config
-	server     *http.Server
-	middleware []Handler
+	stack  []*route
+	config Config
+	server *http.Server
+	hooks  Hooks
 }
 
-// New creates a new App with the given config.
-func New(cfg Config) *App {
-	return &App{mux: http.NewServeMux(), config: cfg}
+// OnShutdownHandler runs when the app shuts down.
+type OnShutdownHandler func() error
+
+// Hooks holds lifecycle callbacks.
+type Hooks struct {
+	onShutdown []OnShutdownHandler
 }
 
-// Use registers a middleware handler.
-func (a *App) Use(h Handler) {
-	a.middleware = append(a.middleware, h)
+// Hooks returns the app's lifecycle hooks.
+func (a *App) Hooks() *Hooks { return &a.hooks }
+
+// OnShutdown registers handlers run by ShutdownWithContext.
+func (h *Hooks) OnShutdown(handler ...OnShutdownHandler) {
+	h.onShutdown = append(h.onShutdown, handler...)
 }
 
-// Get registers a handler for the given path.
-func (a *App) Get(path string, h Handler) {
-	a.mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
-		chain := append([]Handler{}, a.middleware...)
-		chain = append(chain, h)
-		ctx := &Ctx{Request: r, ResponseWriter: w, chain: chain}
-		if err := ctx.Next(); err != nil && a.config.ErrorHandler != nil {
-			a.config.ErrorHandler(ctx, err)
-		}
-	})
+// New creates a new App with the given config.
+func New(cfg Config) *App {
+	return &App{config: cfg}
 }
 
-// Post registers a POST handler for the given path.
-func (a *App) Post(path string, h Handler) {
-	a.mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodPost {
-			w.WriteHeader(http.StatusMethodNotAllowed)
-			return
-		}
-		chain := append([]Handler{}, a.middleware...)
-		chain = append(chain, h)
-		ctx := &Ctx{Request: r, ResponseWriter: w, chain: chain}
-		if err := ctx.Next(); err != nil && a.config.ErrorHandler != nil {
-			a.config.ErrorHandler(ctx, err)
-		}
-	})
+// ServeHTTP runs the handlers matching the request.
+func (a *App) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := &Ctx{Request: r, ResponseWriter: w, chain: a.chain(r)}
+	if err := ctx.Next(); err != nil && a.config.ErrorHandler != nil {
+		a.config.ErrorHandler(ctx, err)
+	}
 }
 
 // Listen starts the HTTP server.
 func (a *App) Listen(addr string) error {
-	a.server = &http.Server{Addr: addr, Handler: a.mux}
+	a.server = &http.Server{Addr: addr, Handler: a}
 	return a.server.ListenAndServe()
 }
 
-// ShutdownWithContext gracefully stops the server.
+// Listener serves the app on an existing listener.
+func (a *App) Listener(ln net.Listener) error {
+	a.server = &http.Server{Handler: a}
+	return a.server.Serve(ln)
+}
+
+// ShutdownWithContext gracefully stops the server and then runs the
+// OnShutdown hooks, returning the first error encountered.
 func (a *App) ShutdownWithContext(ctx context.Context) error {
-	if a.server == nil {
-		return nil
+	var err error
+	if a.server != nil {
+		err = a.server.Shutdown(ctx)
+	}
+	for _, h := range a.hooks.onShutdown {
+		if herr := h(); herr != nil && err == nil {
+			err = herr
+		}
 	}
-	return a.server.Shutdown(ctx)
+	return err
 }
 
 // Test executes the app for testing purposes.
 func (a *App) Test(req *http.Request, _ int) (*http.Response, error) {
 	rr := httptest.NewRecorder()
-	a.mux.ServeHTTP(rr, req)
+	a.ServeHTTP(rr, req)
 	return rr.Result(), nil
 }
 
@@ -89,14 +101,20 @@ var ErrServerClosed = http.ErrServerClosed
 
 const StatusInternalServerError = http.StatusInternalServerError
 const StatusBadRequest = http.StatusBadRequest
+const StatusUnauthorized = http.StatusUnauthorized
+const StatusForbidden = http.StatusForbidden
+const StatusNotFound = http.StatusNotFound
 const StatusMethodNotAllowed = http.StatusMethodNotAllowed
+const StatusUnprocessableEntity = http.StatusUnprocessableEntity
+const StatusServiceUnavailable = http.StatusServiceUnavailable
 
 // Ctx represents the request context passed to handlers.
 type Ctx struct {
 	Request        *http.Request
 	ResponseWriter http.ResponseWriter
-	chain          []Handler
+	chain          []link
 	index          int
+	params         map[string]string
 	statusCode     int
 }
 
@@ -105,9 +123,10 @@ func (c *Ctx) Next() error {
 	if c.index >= len(c.chain) {
 		return nil
 	}
-	h := c.chain[c.index]
+	l := c.chain[c.index]
 	c.index++
-	return h(c)
+	c.params = l.params
+	return l.handler(c)
 }
 
 // Status sets the HTTP status code.
@@ -136,14 +155,23 @@ func (c *Ctx) Send(b []byte) error {
 	return err
 }
 
-// SendString writes a plain text response.
+// SendString writes a plain text response, unless Type set another
+// content type.
 func (c *Ctx) SendString(s string) error {
-	c.ResponseWriter.Header().Set("Content-Type", "text/plain")
+	if c.ResponseWriter.Header().Get("Content-Type") == "" {
+		c.ResponseWriter.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
 	return c.Send([]byte(s))
 }
 
-// Type sets the Content-Type header.
+// Type sets the Content-Type header from a file extension such as "json"
+// or ".html", or from a full media type.
 func (c *Ctx) Type(t string) *Ctx {
+	if !strings.Contains(t, "/") {
+		if ct := mime.TypeByExtension("." + strings.TrimPrefix(t, ".")); ct != "" {
+			t = ct
+		}
+	}
 	c.ResponseWriter.Header().Set("Content-Type", t)
 	return c
 }
@@ -151,12 +179,161 @@ func (c *Ctx) Type(t string) *Ctx {
 // Context returns the request context.
 func (c *Ctx) Context() context.Context { return c.Request.Context() }
 
+// UserContext returns the context set by SetUserContext, which is the
+// request context.
+func (c *Ctx) UserContext() context.Context { return c.Request.Context() }
+
+// SetUserContext replaces the request context seen by later handlers.
+func (c *Ctx) SetUserContext(ctx context.Context) {
+	c.Request = c.Request.WithContext(ctx)
+}
+
 // Method returns the HTTP method.
 func (c *Ctx) Method() string { return c.Request.Method }
 
+// Get returns the request header key, or defaultValue when it is unset.
+func (c *Ctx) Get(key string, defaultValue ...string) string {
+	if v := c.Request.Header.Get(key); v != "" || len(defaultValue) == 0 {
+		return v
+	}
+	return defaultValue[0]
+}
+
+// Set sets the response header key.
+func (c *Ctx) Set(key, val string) {
+	c.ResponseWriter.Header().Set(key, val)
+}
+
 // Path returns the request path.
 func (c *Ctx) Path() string { return c.Request.URL.Path }
 
+// Params returns the route parameter key, or defaultValue when it is
+// empty. The wildcard is named "*".
+func (c *Ctx) Params(key string, defaultValue ...string) string {
+	if v := c.params[key]; v != "" || len(defaultValue) == 0 {
+		return v
+	}
+	return defaultValue[0]
+}
+
+// Query returns the query string parameter key, or defaultValue when it
+// is empty.
+func (c *Ctx) Query(key string, defaultValue ...string) string {
+	if v := c.Request.URL.Query().Get(key); v != "" || len(defaultValue) == 0 {
+		return v
+	}
+	return defaultValue[0]
+}
+
+// Queries returns the query string parameters, keeping the first value of
+// each.
+func (c *Ctx) Queries() map[string]string {
+	out := make(map[string]string)
+	for k, v := range c.Request.URL.Query() {
+		out[k] = v[0]
+	}
+	return out
+}
+
+// ErrUnprocessableEntity is returned by BodyParser for unsupported content
+// types.
+var ErrUnprocessableEntity = errors.New(http.StatusText(http.StatusUnprocessableEntity))
+
+// BodyParser decodes the request body into out, which must be a pointer.
+// JSON bodies, and bodies without a Content-Type, are decoded with
+// encoding/json; form bodies fill struct fields by their form tag or,
+// failing that, their name.
+func (c *Ctx) BodyParser(out interface{}) error {
+	ctype, _, _ := mime.ParseMediaType(c.Get("Content-Type"))
+	switch {
+	case ctype == "" || ctype == "application/json" || strings.HasSuffix(ctype, "+json"):
+		return json.NewDecoder(c.Request.Body).Decode(out)
+	case ctype == "application/x-www-form-urlencoded" || ctype == "multipart/form-data":
+		if err := c.Request.ParseMultipartForm(32 << 20); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+			return err
+		}
+		return decodeForm(c.Request.Form, out)
+	default:
+		return ErrUnprocessableEntity
+	}
+}
+
+func decodeForm(form url.Values, out interface{}) error {
+	v := reflect.ValueOf(out)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return errors.New("fiber: BodyParser needs a pointer to a struct for form bodies")
+	}
+	v = v.Elem()
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("form"), ",")
+		if name == "-" {
+			continue
+		}
+		var vals []string
+		for k, vs := range form {
+			if (name != "" && k == name) || (name == "" && strings.EqualFold(k, f.Name)) {
+				vals = vs
+			}
+		}
+		if len(vals) == 0 {
+			continue
+		}
+		if err := setField(v.Field(i), vals); err != nil {
+			return errors.New("fiber: field " + f.Name + ": " + err.Error())
+		}
+	}
+	return nil
+}
+
+func setField(f reflect.Value, vals []string) error {
+	if f.Kind() == reflect.Slice {
+		s := reflect.MakeSlice(f.Type(), len(vals), len(vals))
+		for i, v := range vals {
+			if err := setField(s.Index(i), []string{v}); err != nil {
+				return err
+			}
+		}
+		f.Set(s)
+		return nil
+	}
+	v := vals[0]
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(v)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+		f.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(v, 10, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(v, 10, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(v, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetFloat(n)
+	default:
+		return errors.New("unsupported type " + f.Type().String())
+	}
+	return nil
+}
+
 // Response provides minimal access to response status code.
 type Response struct{ status int }
 
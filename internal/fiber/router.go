@@ -0,0 +1,314 @@
+package fiber
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Router is implemented by App and Group.
+type Router interface {
+	Use(args ...interface{}) Router
+	Get(path string, handlers ...Handler) Router
+	Head(path string, handlers ...Handler) Router
+	Post(path string, handlers ...Handler) Router
+	Put(path string, handlers ...Handler) Router
+	Patch(path string, handlers ...Handler) Router
+	Delete(path string, handlers ...Handler) Router
+	Options(path string, handlers ...Handler) Router
+	All(path string, handlers ...Handler) Router
+	Add(method, path string, handlers ...Handler) Router
+	Group(prefix string, handlers ...Handler) Router
+}
+
+// route is a middleware or a method route. Middleware matches every path
+// under its prefix; a method route with an empty method matches any
+// method.
+type route struct {
+	use      bool
+	method   string
+	path     string
+	parts    []string
+	handlers []Handler
+}
+
+// Use registers middleware. An optional leading string restricts it to
+// paths under that prefix.
+func (a *App) Use(args ...interface{}) Router {
+	a.use("", args)
+	return a
+}
+
+func (a *App) use(group string, args []interface{}) {
+	prefix := "/"
+	var handlers []Handler
+	for _, arg := range args {
+		switch v := arg.(type) {
+		case string:
+			prefix = v
+		case Handler:
+			handlers = append(handlers, v)
+		default:
+			panic(fmt.Sprintf("fiber: Use: invalid argument of type %T", arg))
+		}
+	}
+	a.add(&route{use: true, path: joinPath(group, prefix), handlers: handlers})
+}
+
+// Get registers a GET route, which also serves HEAD requests.
+func (a *App) Get(path string, handlers ...Handler) Router {
+	return a.Add(http.MethodHead, path, handlers...).Add(http.MethodGet, path, handlers...)
+}
+
+// Head registers a HEAD route.
+func (a *App) Head(path string, handlers ...Handler) Router {
+	return a.Add(http.MethodHead, path, handlers...)
+}
+
+// Post registers a POST route.
+func (a *App) Post(path string, handlers ...Handler) Router {
+	return a.Add(http.MethodPost, path, handlers...)
+}
+
+// Put registers a PUT route.
+func (a *App) Put(path string, handlers ...Handler) Router {
+	return a.Add(http.MethodPut, path, handlers...)
+}
+
+// Patch registers a PATCH route.
+func (a *App) Patch(path string, handlers ...Handler) Router {
+	return a.Add(http.MethodPatch, path, handlers...)
+}
+
+// Delete registers a DELETE route.
+func (a *App) Delete(path string, handlers ...Handler) Router {
+	return a.Add(http.MethodDelete, path, handlers...)
+}
+
+// Options registers an OPTIONS route.
+func (a *App) Options(path string, handlers ...Handler) Router {
+	return a.Add(http.MethodOptions, path, handlers...)
+}
+
+// All registers a route for every method.
+func (a *App) All(path string, handlers ...Handler) Router {
+	return a.Add("", path, handlers...)
+}
+
+// Add registers a route for method. Path segments starting with ':' are
+// parameters, optional when they end in '?', and a '*' segment matches
+// the rest of the path.
+func (a *App) Add(method, path string, handlers ...Handler) Router {
+	a.add(&route{method: strings.ToUpper(method), path: joinPath("", path), handlers: handlers})
+	return a
+}
+
+func (a *App) add(r *route) {
+	r.parts = splitPath(r.path)
+	a.stack = append(a.stack, r)
+}
+
+// Group returns a router whose routes are prefixed with prefix. Handlers
+// run as middleware for every path under the prefix.
+func (a *App) Group(prefix string, handlers ...Handler) Router {
+	return newGroup(a, joinPath("", prefix), handlers)
+}
+
+// Group is a set of routes sharing a path prefix and middleware.
+type Group struct {
+	app    *App
+	prefix string
+}
+
+func newGroup(a *App, prefix string, handlers []Handler) *Group {
+	if len(handlers) > 0 {
+		args := make([]interface{}, len(handlers))
+		for i, h := range handlers {
+			args[i] = h
+		}
+		a.use(prefix, args)
+	}
+	return &Group{app: a, prefix: prefix}
+}
+
+// Use registers middleware for paths under the group's prefix.
+func (g *Group) Use(args ...interface{}) Router {
+	g.app.use(g.prefix, args)
+	return g
+}
+
+// Get registers a GET route, which also serves HEAD requests.
+func (g *Group) Get(path string, handlers ...Handler) Router {
+	return g.Add(http.MethodHead, path, handlers...).Add(http.MethodGet, path, handlers...)
+}
+
+// Head registers a HEAD route.
+func (g *Group) Head(path string, handlers ...Handler) Router {
+	return g.Add(http.MethodHead, path, handlers...)
+}
+
+// Post registers a POST route.
+func (g *Group) Post(path string, handlers ...Handler) Router {
+	return g.Add(http.MethodPost, path, handlers...)
+}
+
+// Put registers a PUT route.
+func (g *Group) Put(path string, handlers ...Handler) Router {
+	return g.Add(http.MethodPut, path, handlers...)
+}
+
+// Patch registers a PATCH route.
+func (g *Group) Patch(path string, handlers ...Handler) Router {
+	return g.Add(http.MethodPatch, path, handlers...)
+}
+
+// Delete registers a DELETE route.
+func (g *Group) Delete(path string, handlers ...Handler) Router {
+	return g.Add(http.MethodDelete, path, handlers...)
+}
+
+// Options registers an OPTIONS route.
+func (g *Group) Options(path string, handlers ...Handler) Router {
+	return g.Add(http.MethodOptions, path, handlers...)
+}
+
+// All registers a route for every method.
+func (g *Group) All(path string, handlers ...Handler) Router {
+	return g.Add("", path, handlers...)
+}
+
+// Add registers a route for method under the group's prefix.
+func (g *Group) Add(method, path string, handlers ...Handler) Router {
+	g.app.Add(method, joinPath(g.prefix, path), handlers...)
+	return g
+}
+
+// Group returns a nested group.
+func (g *Group) Group(prefix string, handlers ...Handler) Router {
+	return newGroup(g.app, joinPath(g.prefix, prefix), handlers)
+}
+
+// link is a handler in a request's chain with the parameters of the route
+// it came from.
+type link struct {
+	handler Handler
+	params  map[string]string
+}
+
+// chain returns the handlers matching r in registration order. When no
+// method route matches, it ends with a 404, or a 405 listing the allowed
+// methods when the path matches routes for other methods.
+func (a *App) chain(r *http.Request) []link {
+	parts := splitPath(r.URL.Path)
+	var (
+		links   []link
+		matched bool
+		allowed = map[string]bool{}
+	)
+	for _, rt := range a.stack {
+		if rt.use {
+			if hasPrefix(parts, rt.parts) {
+				for _, h := range rt.handlers {
+					links = append(links, link{handler: h})
+				}
+			}
+			continue
+		}
+		params, ok := match(rt.parts, parts)
+		if !ok {
+			continue
+		}
+		if rt.method != "" && rt.method != r.Method {
+			allowed[rt.method] = true
+			continue
+		}
+		matched = true
+		for _, h := range rt.handlers {
+			links = append(links, link{handler: h, params: params})
+		}
+	}
+	if matched {
+		return links
+	}
+	if len(allowed) == 0 {
+		return append(links, link{handler: func(c *Ctx) error {
+			return c.Status(StatusNotFound).SendString("Cannot " + c.Method() + " " + c.Path())
+		}})
+	}
+	allow := make([]string, 0, len(allowed))
+	for m := range allowed {
+		allow = append(allow, m)
+	}
+	sort.Strings(allow)
+	return append(links, link{handler: func(c *Ctx) error {
+		c.Set("Allow", strings.Join(allow, ", "))
+		return c.Status(StatusMethodNotAllowed).SendString("Method Not Allowed")
+	}})
+}
+
+// match reports whether path matches the route pattern and returns its
+// parameters.
+func match(pattern, path []string) (map[string]string, bool) {
+	var params map[string]string
+	set := func(k, v string) {
+		if params == nil {
+			params = map[string]string{}
+		}
+		params[k] = v
+	}
+	for i, p := range pattern {
+		switch {
+		case p == "*":
+			rest := ""
+			if i < len(path) {
+				rest = strings.Join(path[i:], "/")
+			}
+			set("*", rest)
+			return params, true
+		case strings.HasPrefix(p, ":") && strings.HasSuffix(p, "?"):
+			if i >= len(path) {
+				set(p[1:len(p)-1], "")
+				continue
+			}
+			set(p[1:len(p)-1], path[i])
+		case strings.HasPrefix(p, ":"):
+			if i >= len(path) || path[i] == "" {
+				return nil, false
+			}
+			set(p[1:], path[i])
+		default:
+			if i >= len(path) || path[i] != p {
+				return nil, false
+			}
+		}
+	}
+	return params, len(path) <= len(pattern)
+}
+
+// hasPrefix reports whether path is prefix or lies under it.
+func hasPrefix(path, prefix []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i, p := range prefix {
+		if path[i] != p {
+			return false
+		}
+	}
+	return true
+}
+
+// splitPath splits a path into segments, ignoring leading and trailing
+// slashes.
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+func joinPath(prefix, path string) string {
+	return "/" + strings.Trim(strings.TrimRight(prefix, "/")+"/"+strings.TrimLeft(path, "/"), "/")
+}
@@ -0,0 +1,136 @@
+package fiber
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func do(t *testing.T, app *App, method, target string, body io.Reader) (*http.Response, string) {
+	t.Helper()
+	resp, err := app.Test(httptest.NewRequest(method, target, body), -1)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, target, err)
+	}
+	b, _ := io.ReadAll(resp.Body)
+	return resp, string(b)
+}
+
+func TestParamsWildcardAndQuery(t *testing.T) {
+	app := New(Config{})
+	app.Get("/users/:id/posts/:post?", func(c *Ctx) error {
+		return c.SendString(c.Params("id") + "|" + c.Params("post", "none") + "|" + c.Query("sort", "asc"))
+	})
+	app.Get("/files/*", func(c *Ctx) error {
+		return c.SendString(c.Params("*"))
+	})
+	app.Get("/search", func(c *Ctx) error {
+		q := c.Queries()
+		return c.SendString(q["a"] + "|" + q["b"])
+	})
+
+	cases := map[string]string{
+		"/users/7/posts/3?sort=desc": "7|3|desc",
+		"/users/7/posts":             "7|none|asc",
+		"/users/7/posts/":            "7|none|asc",
+		"/files/a/b.txt":             "a/b.txt",
+		"/files":                     "",
+		"/search?a=1&b=x&a=2":        "1|x",
+	}
+	for target, want := range cases {
+		if resp, body := do(t, app, http.MethodGet, target, nil); resp.StatusCode != http.StatusOK || body != want {
+			t.Fatalf("%s: got %d %q, want %q", target, resp.StatusCode, body, want)
+		}
+	}
+	if resp, _ := do(t, app, http.MethodGet, "/users", nil); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for missing parameter, got %d", resp.StatusCode)
+	}
+}
+
+func TestMethodRoutingAndAllow(t *testing.T) {
+	app := New(Config{})
+	ok := func(c *Ctx) error { return c.SendString(c.Method()) }
+	app.Get("/items/:id", ok)
+	app.Put("/items/:id", ok)
+	app.Delete("/items/:id", ok)
+	app.All("/any", ok)
+
+	if _, body := do(t, app, http.MethodPut, "/items/1", nil); body != http.MethodPut {
+		t.Fatalf("got %q", body)
+	}
+	resp, _ := do(t, app, http.MethodPost, "/items/1", nil)
+	if resp.StatusCode != http.StatusMethodNotAllowed || resp.Header.Get("Allow") != "DELETE, GET, HEAD, PUT" {
+		t.Fatalf("got %d Allow=%q", resp.StatusCode, resp.Header.Get("Allow"))
+	}
+	if _, body := do(t, app, http.MethodPatch, "/any", nil); body != http.MethodPatch {
+		t.Fatalf("All did not match PATCH: %q", body)
+	}
+	if resp, _ := do(t, app, http.MethodGet, "/nope", nil); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestGroupMiddlewareIsScoped(t *testing.T) {
+	app := New(Config{})
+	var trail []string
+	app.Use(func(c *Ctx) error {
+		trail = append(trail, "app")
+		return c.Next()
+	})
+	api := app.Group("/api", func(c *Ctx) error {
+		trail = append(trail, "api")
+		return c.Next()
+	})
+	v1 := api.Group("/v1")
+	v1.Use(func(c *Ctx) error {
+		trail = append(trail, "v1")
+		return c.Next()
+	})
+	v1.Get("/ping", func(c *Ctx) error { return c.SendString("pong") })
+	app.Get("/apiary", func(c *Ctx) error { return c.SendString("bees") })
+
+	if _, body := do(t, app, http.MethodGet, "/api/v1/ping", nil); body != "pong" || strings.Join(trail, ",") != "app,api,v1" {
+		t.Fatalf("got %q via %v", body, trail)
+	}
+	trail = nil
+	if _, body := do(t, app, http.MethodGet, "/apiary", nil); body != "bees" || strings.Join(trail, ",") != "app" {
+		t.Fatalf("group middleware leaked: %q via %v", body, trail)
+	}
+	trail = nil
+	if resp, _ := do(t, app, http.MethodGet, "/api/v1/missing", nil); resp.StatusCode != http.StatusNotFound || len(trail) != 3 {
+		t.Fatalf("expected middleware then 404, got %d via %v", resp.StatusCode, trail)
+	}
+}
+
+func TestBodyParser(t *testing.T) {
+	type payload struct {
+		Name string   `json:"name" form:"name"`
+		Age  int      `json:"age" form:"age"`
+		Tags []string `json:"tags" form:"tag"`
+	}
+	parse := func(ctype, body string) (payload, error) {
+		var p payload
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		if ctype != "" {
+			req.Header.Set("Content-Type", ctype)
+		}
+		err := (&Ctx{Request: req, ResponseWriter: httptest.NewRecorder()}).BodyParser(&p)
+		return p, err
+	}
+
+	p, err := parse("application/json; charset=utf-8", `{"name":"ada","age":36,"tags":["a"]}`)
+	if err != nil || p.Name != "ada" || p.Age != 36 || len(p.Tags) != 1 {
+		t.Fatalf("json: %+v %v", p, err)
+	}
+	form := url.Values{"name": {"bob"}, "age": {"7"}, "tag": {"x", "y"}}
+	p, err = parse("application/x-www-form-urlencoded", form.Encode())
+	if err != nil || p.Name != "bob" || p.Age != 7 || strings.Join(p.Tags, ",") != "x,y" {
+		t.Fatalf("form: %+v %v", p, err)
+	}
+	if _, err := parse("text/csv", "a,b"); err != ErrUnprocessableEntity {
+		t.Fatalf("expected ErrUnprocessableEntity, got %v", err)
+	}
+}
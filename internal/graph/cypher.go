@@ -0,0 +1,169 @@
+package graph
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// params are the parameters of a Cypher statement.
+type params map[string]interface{}
+
+// Error is a failure reported by Neo4j for a statement.
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string { return "neo4j: " + e.Code + ": " + e.Message }
+
+// Transient reports whether the statement may succeed when retried, such
+// as after a deadlock or while the database is unavailable.
+func (e *Error) Transient() bool { return strings.HasPrefix(e.Code, "Neo.TransientError.") }
+
+// statusError is returned for non-2xx responses.
+type statusError struct {
+	code int
+	msg  string
+}
+
+func (e *statusError) Error() string {
+	if e.msg == "" {
+		return fmt.Sprintf("neo4j status %d", e.code)
+	}
+	return fmt.Sprintf("neo4j status %d: %s", e.code, e.msg)
+}
+
+// statement is a request body entry of the transactional endpoint.
+type statement struct {
+	Statement  string `json:"statement"`
+	Parameters params `json:"parameters,omitempty"`
+}
+
+// run executes a parameterized Cypher statement in its own transaction
+// and returns its rows, retrying transient failures with exponential
+// backoff.
+func (g *Graph) run(ctx context.Context, cypher string, p params) ([][]interface{}, error) {
+	body, err := json.Marshal(struct {
+		Statements []statement `json:"statements"`
+	}{[]statement{{Statement: cypher, Parameters: p}}})
+	if err != nil {
+		return nil, err
+	}
+	delay := g.backoff
+	for attempt := 0; ; attempt++ {
+		rows, err := g.post(ctx, body)
+		if err == nil || attempt >= g.retries || !retryable(err) {
+			return rows, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// post sends one request to the transactional endpoint.
+func (g *Graph) post(ctx context.Context, body []byte) ([][]interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if g.user != "" {
+		req.SetBasicAuth(g.user, g.password)
+	}
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, &statusError{code: resp.StatusCode, msg: strings.TrimSpace(string(msg))}
+	}
+	var res struct {
+		Results []struct {
+			Data []struct {
+				Row []interface{} `json:"row"`
+			} `json:"data"`
+		} `json:"results"`
+		Errors []Error `json:"errors"`
+	}
+	dec := json.NewDecoder(resp.Body)
+	dec.UseNumber()
+	if err := dec.Decode(&res); err != nil {
+		return nil, fmt.Errorf("neo4j: decode response: %w", err)
+	}
+	if len(res.Errors) > 0 {
+		return nil, &res.Errors[0]
+	}
+	if len(res.Results) == 0 {
+		return nil, nil
+	}
+	rows := make([][]interface{}, 0, len(res.Results[0].Data))
+	for _, d := range res.Results[0].Data {
+		row := make([]interface{}, len(d.Row))
+		for i, v := range d.Row {
+			row[i] = value(v)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// retryable reports whether err shows the statement was not run, so that
+// sending it again cannot apply a CREATE twice: a transient Neo4j error,
+// whose transaction was rolled back, an unavailable server, or a
+// connection that could not be made. Gateway errors and connections lost
+// after the request was sent are not retried since the statement may have
+// committed.
+func retryable(err error) bool {
+	var ne *Error
+	if errors.As(err, &ne) {
+		return ne.Transient()
+	}
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.code == http.StatusServiceUnavailable
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var oe *net.OpError
+	return errors.As(err, &oe) && oe.Op == "dial"
+}
+
+// value converts decoded JSON numbers to int64 when integral and float64
+// otherwise, matching the property types the stores are written with.
+func value(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case []interface{}:
+		for i := range v {
+			v[i] = value(v[i])
+		}
+		return v
+	case map[string]interface{}:
+		for k := range v {
+			v[k] = value(v[k])
+		}
+		return v
+	}
+	return v
+}
@@ -1,11 +1,23 @@
+// Package graph stores entities and their relationships in Neo4j through
+// its HTTP transactional Cypher endpoint.
 package graph
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
+	"net/http"
 	"os"
-	"sync"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Errors returned for missing nodes and relationships.
+var (
+	ErrNodeNotFound = errors.New("graph: node not found")
+	ErrEdgeNotFound = errors.New("graph: relationship not found")
 )
 
 // Config holds Neo4j connection settings.
@@ -13,7 +25,18 @@ type Config struct {
 	User     string
 	Password string
 	Host     string
-	Port     string
+	// Port is the Neo4j HTTP port.
+	Port string
+	// Database is the database statements run in.
+	Database string
+	// MaxConns bounds the connections kept open to Neo4j.
+	MaxConns int
+	// MaxRetries is how many times a statement failing with a transient
+	// error is retried.
+	MaxRetries int
+	// RetryBackoff is the delay before the first retry; it doubles with
+	// each attempt.
+	RetryBackoff time.Duration
 }
 
 // LoadConfig reads settings from environment variables with fallbacks.
@@ -34,84 +57,406 @@ func LoadConfig() Config {
 	if port == "" {
 		port = "7474"
 	}
-	return Config{User: user, Password: pass, Host: host, Port: port}
+	database := os.Getenv("NEO4J_DATABASE")
+	if database == "" {
+		database = "neo4j"
+	}
+	return Config{
+		User:         user,
+		Password:     pass,
+		Host:         host,
+		Port:         port,
+		Database:     database,
+		MaxConns:     getint("NEO4J_MAX_CONNS", 10),
+		MaxRetries:   getint("NEO4J_MAX_RETRIES", 3),
+		RetryBackoff: 100 * time.Millisecond,
+	}
 }
 
-var dialContext = (&net.Dialer{}).DialContext
+func getint(key string, def int) int {
+	if v, err := strconv.Atoi(os.Getenv(key)); err == nil && v >= 0 {
+		return v
+	}
+	return def
+}
 
-// Graph provides helpers for storing nodes and edges.
+// Graph stores nodes and edges in Neo4j. Node and edge IDs are Neo4j
+// element IDs.
 type Graph struct {
-	addr  string
-	mu    sync.RWMutex
-	nodes map[string]Node
-	edges []Edge
-	next  int
+	endpoint   string
+	user       string
+	password   string
+	httpClient *http.Client
+	retries    int
+	backoff    time.Duration
 }
 
 // Node represents a graph node.
 type Node struct {
-	ID    string
-	Label string
-	Props map[string]interface{}
+	ID    string                 `json:"id"`
+	Label string                 `json:"label"`
+	Props map[string]interface{} `json:"properties,omitempty"`
 }
 
 // Edge represents a relationship between two nodes.
 type Edge struct {
-	ID    string
+	ID    string                 `json:"id"`
+	From  string                 `json:"from"`
+	To    string                 `json:"to"`
+	Type  string                 `json:"type"`
+	Props map[string]interface{} `json:"properties,omitempty"`
+}
+
+// EdgeQuery selects relationships: those from node From, to node To and
+// of type Type, when set, with every property in Props.
+type EdgeQuery struct {
 	From  string
 	To    string
 	Type  string
 	Props map[string]interface{}
 }
 
-// Connect verifies a bolt connection can be established and returns a Graph.
+// Connect returns a Graph for the Neo4j server described by cfg after
+// checking that it answers queries with the configured credentials.
 func Connect(ctx context.Context, cfg Config) (*Graph, error) {
-	addr := net.JoinHostPort(cfg.Host, cfg.Port)
-	conn, err := dialContext(ctx, "tcp", addr)
-	if err != nil {
-		return nil, err
+	database := cfg.Database
+	if database == "" {
+		database = "neo4j"
+	}
+	g := &Graph{
+		endpoint: fmt.Sprintf("http://%s/db/%s/tx/commit", net.JoinHostPort(cfg.Host, cfg.Port), database),
+		user:     cfg.User,
+		password: cfg.Password,
+		httpClient: &http.Client{Transport: &http.Transport{
+			Proxy:               http.ProxyFromEnvironment,
+			MaxConnsPerHost:     cfg.MaxConns,
+			MaxIdleConnsPerHost: cfg.MaxConns,
+			IdleConnTimeout:     90 * time.Second,
+		}},
+		retries: cfg.MaxRetries,
+		backoff: cfg.RetryBackoff,
 	}
-	_ = conn.Close()
-	return &Graph{
-		addr:  addr,
-		nodes: make(map[string]Node),
-	}, nil
+	if _, err := g.run(ctx, "RETURN 1", nil); err != nil {
+		g.Close()
+		return nil, fmt.Errorf("graph: connect: %w", err)
+	}
+	return g, nil
+}
+
+// Close releases the idle connections to Neo4j.
+func (g *Graph) Close() {
+	g.httpClient.CloseIdleConnections()
 }
 
+// nodeColumns returns a node's ID, first label and properties.
+const nodeColumns = "elementId(n), head(labels(n)), properties(n)"
+
 // CreateNode inserts a node and returns its generated ID.
-func (g *Graph) CreateNode(_ context.Context, label string, props map[string]interface{}) (string, error) {
-	g.mu.Lock()
-	defer g.mu.Unlock()
-	g.next++
-	id := fmt.Sprintf("n%d", g.next)
-	g.nodes[id] = Node{ID: id, Label: label, Props: props}
-	return id, nil
+func (g *Graph) CreateNode(ctx context.Context, label string, props map[string]interface{}) (string, error) {
+	if label == "" {
+		return "", fmt.Errorf("graph: node label required")
+	}
+	rows, err := g.run(ctx, "CREATE (n:"+quote(label)+" $props) RETURN elementId(n)", params{"props": properties(props)})
+	if err != nil {
+		return "", err
+	}
+	return first(rows), nil
 }
 
 // CreateEdge inserts a relationship and returns its generated ID.
-func (g *Graph) CreateEdge(_ context.Context, from, to, relType string, props map[string]interface{}) (string, error) {
-	g.mu.Lock()
-	defer g.mu.Unlock()
-	g.next++
-	id := fmt.Sprintf("e%d", g.next)
-	g.edges = append(g.edges, Edge{ID: id, From: from, To: to, Type: relType, Props: props})
-	return id, nil
+func (g *Graph) CreateEdge(ctx context.Context, from, to, relType string, props map[string]interface{}) (string, error) {
+	if relType == "" {
+		return "", fmt.Errorf("graph: relationship type required")
+	}
+	rows, err := g.run(ctx, "MATCH (a) WHERE elementId(a) = $from MATCH (b) WHERE elementId(b) = $to "+
+		"CREATE (a)-[r:"+quote(relType)+" $props]->(b) RETURN elementId(r)",
+		params{"from": from, "to": to, "props": properties(props)})
+	if err != nil {
+		return "", err
+	}
+	if len(rows) == 0 {
+		return "", fmt.Errorf("%w: %s or %s", ErrNodeNotFound, from, to)
+	}
+	return first(rows), nil
 }
 
 // Neighbors returns nodes connected by the given relationship type.
-func (g *Graph) Neighbors(_ context.Context, id, relType string) ([]Node, error) {
-	g.mu.RLock()
-	defer g.mu.RUnlock()
-	var out []Node
-	for _, e := range g.edges {
-		if e.Type != relType {
+func (g *Graph) Neighbors(ctx context.Context, id, relType string) ([]Node, error) {
+	rows, err := g.run(ctx, "MATCH (a)-[:"+quote(relType)+"]->(n) WHERE elementId(a) = $id "+
+		"RETURN "+nodeColumns+" ORDER BY elementId(n)", params{"id": id})
+	if err != nil {
+		return nil, err
+	}
+	return nodes(rows), nil
+}
+
+// FindNodes returns nodes with the given label whose properties include
+// every entry of props. An empty label matches any node.
+func (g *Graph) FindNodes(ctx context.Context, label string, props map[string]interface{}) ([]Node, error) {
+	pattern := "(n)"
+	if label != "" {
+		pattern = "(n:" + quote(label) + ")"
+	}
+	rows, err := g.run(ctx, "MATCH "+pattern+" WHERE all(k IN keys($props) WHERE n[k] = $props[k]) "+
+		"RETURN "+nodeColumns+" ORDER BY elementId(n)", params{"props": properties(props)})
+	if err != nil {
+		return nil, err
+	}
+	return nodes(rows), nil
+}
+
+// UpdateNode merges props into the node's properties; nil values remove
+// properties.
+func (g *Graph) UpdateNode(ctx context.Context, id string, props map[string]interface{}) error {
+	rows, err := g.run(ctx, "MATCH (n) WHERE elementId(n) = $id SET n += $props RETURN elementId(n)",
+		params{"id": id, "props": properties(props)})
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return fmt.Errorf("%w: %s", ErrNodeNotFound, id)
+	}
+	return nil
+}
+
+// edgeColumns returns a relationship's ID, ends, type and properties.
+const edgeColumns = "elementId(r), elementId(a), elementId(b), type(r), properties(r)"
+
+// Edges returns every relationship.
+func (g *Graph) Edges(ctx context.Context) ([]Edge, error) {
+	rows, err := g.run(ctx, "MATCH (a)-[r]->(b) RETURN "+edgeColumns+" ORDER BY elementId(r)", nil)
+	if err != nil {
+		return nil, err
+	}
+	return edges(rows), nil
+}
+
+// Edge returns the relationship with the given ID, or ErrEdgeNotFound.
+func (g *Graph) Edge(ctx context.Context, id string) (Edge, error) {
+	rows, err := g.run(ctx, "MATCH (a)-[r]->(b) WHERE elementId(r) = $id RETURN "+edgeColumns, params{"id": id})
+	if err != nil {
+		return Edge{}, err
+	}
+	if len(rows) == 0 {
+		return Edge{}, fmt.Errorf("%w: %s", ErrEdgeNotFound, id)
+	}
+	return edges(rows)[0], nil
+}
+
+// FindEdges returns the relationships q selects.
+func (g *Graph) FindEdges(ctx context.Context, q EdgeQuery) ([]Edge, error) {
+	rows, err := g.run(ctx, "MATCH (a)-[r]->(b) WHERE ($from = '' OR elementId(a) = $from) AND ($to = '' OR elementId(b) = $to) "+
+		"AND ($type = '' OR type(r) = $type) AND all(k IN keys($props) WHERE r[k] = $props[k]) "+
+		"RETURN "+edgeColumns+" ORDER BY elementId(r)",
+		params{"from": q.From, "to": q.To, "type": q.Type, "props": properties(q.Props)})
+	if err != nil {
+		return nil, err
+	}
+	return edges(rows), nil
+}
+
+// UpdateEdge merges props into the relationship's properties; nil values
+// remove properties.
+func (g *Graph) UpdateEdge(ctx context.Context, id string, props map[string]interface{}) error {
+	rows, err := g.run(ctx, "MATCH ()-[r]->() WHERE elementId(r) = $id SET r += $props RETURN elementId(r)",
+		params{"id": id, "props": properties(props)})
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return fmt.Errorf("%w: %s", ErrEdgeNotFound, id)
+	}
+	return nil
+}
+
+// DeleteEdge removes a relationship.
+func (g *Graph) DeleteEdge(ctx context.Context, id string) error {
+	_, err := g.run(ctx, "MATCH ()-[r]->() WHERE elementId(r) = $id DELETE r", params{"id": id})
+	return err
+}
+
+// DeleteNode removes a node together with its relationships.
+func (g *Graph) DeleteNode(ctx context.Context, id string) error {
+	_, err := g.run(ctx, "MATCH (n) WHERE elementId(n) = $id DETACH DELETE n", params{"id": id})
+	return err
+}
+
+// Nodes returns the nodes with the given IDs, skipping missing ones.
+func (g *Graph) Nodes(ctx context.Context, ids []string) ([]Node, error) {
+	rows, err := g.run(ctx, "MATCH (n) WHERE elementId(n) IN $ids RETURN "+nodeColumns+" ORDER BY elementId(n)",
+		params{"ids": ids})
+	if err != nil {
+		return nil, err
+	}
+	return nodes(rows), nil
+}
+
+// Expand returns, for each of ids, its relationships in dir with one of
+// types, any type when empty, and the nodes at their other ends.
+func (g *Graph) Expand(ctx context.Context, ids []string, dir Direction, types []string) (map[string][]Hop, error) {
+	pattern := "(a)-[r]->(n)"
+	switch dir {
+	case In:
+		pattern = "(a)<-[r]-(n)"
+	case Both:
+		pattern = "(a)-[r]-(n)"
+	}
+	if types == nil {
+		types = []string{}
+	}
+	rows, err := g.run(ctx, "MATCH "+pattern+" WHERE elementId(a) IN $ids AND (size($types) = 0 OR type(r) IN $types) "+
+		"RETURN DISTINCT elementId(a), elementId(r), elementId(startNode(r)), elementId(endNode(r)), type(r), properties(r), "+
+		nodeColumns+" ORDER BY elementId(r)", params{"ids": ids, "types": types})
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string][]Hop, len(ids))
+	for _, row := range rows {
+		from := str(row, 0)
+		out[from] = append(out[from], Hop{
+			Edge: Edge{ID: str(row, 1), From: str(row, 2), To: str(row, 3), Type: str(row, 4), Props: props(row, 5)},
+			Node: Node{ID: str(row, 6), Label: str(row, 7), Props: props(row, 8)},
+		})
+	}
+	return out, nil
+}
+
+// Traverse walks the graph from q.Start; see Walk.
+func (g *Graph) Traverse(ctx context.Context, q TraversalQuery) ([]Visit, error) {
+	return Walk(ctx, g, q)
+}
+
+// ShortestPath returns a shortest path between two nodes; see FindPath.
+func (g *Graph) ShortestPath(ctx context.Context, from, to string, e Expansion, maxDepth int) (*Path, error) {
+	return FindPath(ctx, g, from, to, e, maxDepth)
+}
+
+// Subgraph returns the neighbourhood of center; see Extract.
+func (g *Graph) Subgraph(ctx context.Context, center string, e Expansion, depth int) (Subgraph, error) {
+	return Extract(ctx, g, center, e, depth)
+}
+
+// quote escapes a label or relationship type for use in Cypher, which
+// cannot take either as a parameter.
+func quote(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// properties returns props, or an empty map for Cypher when it is nil.
+func properties(props map[string]interface{}) map[string]interface{} {
+	if props == nil {
+		return map[string]interface{}{}
+	}
+	return props
+}
+
+// edges converts rows of edgeColumns.
+func edges(rows [][]interface{}) []Edge {
+	out := make([]Edge, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, Edge{ID: str(row, 0), From: str(row, 1), To: str(row, 2), Type: str(row, 3), Props: props(row, 4)})
+	}
+	return out
+}
+
+// nodes converts rows of nodeColumns.
+func nodes(rows [][]interface{}) []Node {
+	out := make([]Node, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, Node{ID: str(row, 0), Label: str(row, 1), Props: props(row, 2)})
+	}
+	return out
+}
+
+// first returns the first column of the first row, if any.
+func first(rows [][]interface{}) string {
+	if len(rows) == 0 {
+		return ""
+	}
+	return str(rows[0], 0)
+}
+
+func str(row []interface{}, col int) string {
+	if col >= len(row) {
+		return ""
+	}
+	s, _ := row[col].(string)
+	return s
+}
+
+func props(row []interface{}, col int) map[string]interface{} {
+	if col >= len(row) {
+		return nil
+	}
+	m, _ := row[col].(map[string]interface{})
+	if len(m) == 0 {
+		return nil
+	}
+	return m
+}
+
+// NodeMatches reports whether n has label (when non-empty) and every
+// property in props. Numbers compare by value whatever their type.
+func NodeMatches(n Node, label string, props map[string]interface{}) bool {
+	if label != "" && n.Label != label {
+		return false
+	}
+	return propsMatch(n.Props, props)
+}
+
+// EdgeMatches reports whether e is selected by q.
+func EdgeMatches(e Edge, q EdgeQuery) bool {
+	if (q.From != "" && e.From != q.From) || (q.To != "" && e.To != q.To) || (q.Type != "" && e.Type != q.Type) {
+		return false
+	}
+	return propsMatch(e.Props, q.Props)
+}
+
+func propsMatch(have, want map[string]interface{}) bool {
+	for k, v := range want {
+		got, ok := have[k]
+		if !ok || !equal(got, v) {
+			return false
+		}
+	}
+	return true
+}
+
+// MergeProps returns a copy of dst with props applied on top. Properties
+// set to nil are removed, as in Neo4j.
+func MergeProps(dst, props map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(dst)+len(props))
+	for k, v := range dst {
+		out[k] = v
+	}
+	for k, v := range props {
+		if v == nil {
+			delete(out, k)
 			continue
 		}
-		if e.From == id {
-			if n, ok := g.nodes[e.To]; ok {
-				out = append(out, n)
-			}
+		out[k] = v
+	}
+	return out
+}
+
+// DetachEdges returns edges without those touching node id.
+func DetachEdges(edges []Edge, id string) []Edge {
+	out := edges[:0]
+	for _, e := range edges {
+		if e.From != id && e.To != id {
+			out = append(out, e)
 		}
 	}
-	return out, nil
+	return out
+}
+
+// RemoveEdge returns edges without the edge with the given ID.
+func RemoveEdge(edges []Edge, id string) []Edge {
+	out := edges[:0]
+	for _, e := range edges {
+		if e.ID != id {
+			out = append(out, e)
+		}
+	}
+	return out
 }
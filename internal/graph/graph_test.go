@@ -2,37 +2,578 @@ package graph
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
-func TestCreateAndQuery(t *testing.T) {
-	// stub dialer to avoid real network
-        dialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
-                c1, c2 := net.Pipe()
-                go func() {
-                        _ = c1.Close()
-                }()
-                return c2, nil
-        }
-
-	cfg := LoadConfig()
-	g, err := Connect(context.Background(), cfg)
+// fakeNeo4j serves the transactional Cypher endpoint for the statements
+// Graph sends, keeping nodes and relationships in memory.
+type fakeNeo4j struct {
+	mu         sync.Mutex
+	nodes      map[string]fakeNode
+	edges      []Edge
+	next       int
+	requests   int
+	statements []string
+	// failures makes the next requests fail with failCode.
+	failures int
+	failCode string
+}
+
+type fakeNode struct {
+	label string
+	props map[string]interface{}
+}
+
+var (
+	createNodeRE = regexp.MustCompile("^CREATE \\(n:`(.+)` \\$props\\) RETURN elementId\\(n\\)$")
+	createEdgeRE = regexp.MustCompile("CREATE \\(a\\)-\\[r:`(.+)` \\$props\\]->\\(b\\)")
+	neighborsRE  = regexp.MustCompile("^MATCH \\(a\\)-\\[:`(.+)`\\]->\\(n\\)")
+	findNodesRE  = regexp.MustCompile("^MATCH \\(n(?::`(.+)`)?\\) WHERE all\\(")
+	expandRE     = regexp.MustCompile("^MATCH \\(a\\)(<?-)\\[r\\](->?)\\(n\\) WHERE elementId\\(a\\) IN \\$ids")
+)
+
+func (f *fakeNeo4j) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if user, pass, _ := r.BasicAuth(); user != "neo4j" || pass != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if r.URL.Path != "/db/neo4j/tx/commit" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	var req struct {
+		Statements []struct {
+			Statement  string                 `json:"statement"`
+			Parameters map[string]interface{} `json:"parameters"`
+		} `json:"statements"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Statements) != 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests++
+	st := req.Statements[0]
+	f.statements = append(f.statements, st.Statement)
+	var rows [][]interface{}
+	var err error
+	if f.failures > 0 {
+		f.failures--
+		err = &Error{Code: f.failCode, Message: "injected"}
+	} else {
+		rows, err = f.exec(st.Statement, st.Parameters)
+	}
+	res := map[string]interface{}{"results": []interface{}{}, "errors": []interface{}{}}
+	if err != nil {
+		res["errors"] = []interface{}{err}
+	} else {
+		data := []interface{}{}
+		for _, row := range rows {
+			data = append(data, map[string]interface{}{"row": row})
+		}
+		res["results"] = []interface{}{map[string]interface{}{"columns": []string{}, "data": data}}
+	}
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+func (f *fakeNeo4j) exec(cypher string, p map[string]interface{}) ([][]interface{}, error) {
+	props, _ := p["props"].(map[string]interface{})
+	id, _ := p["id"].(string)
+	switch {
+	case cypher == "RETURN 1":
+		return [][]interface{}{{1}}, nil
+	case strings.HasPrefix(cypher, "MATCH (n) WHERE elementId(n) IN $ids"):
+		var ids []string
+		for _, id := range p["ids"].([]interface{}) {
+			if _, ok := f.nodes[id.(string)]; ok {
+				ids = append(ids, id.(string))
+			}
+		}
+		return f.nodeRows(ids), nil
+	case expandRE.MatchString(cypher):
+		m := expandRE.FindStringSubmatch(cypher)
+		dir := Both
+		if m[1] == "<-" {
+			dir = In
+		} else if m[2] == "->" {
+			dir = Out
+		}
+		var ids, types []string
+		for _, id := range p["ids"].([]interface{}) {
+			ids = append(ids, id.(string))
+		}
+		for _, t := range p["types"].([]interface{}) {
+			types = append(types, t.(string))
+		}
+		nodes := map[string]Node{}
+		for id, n := range f.nodes {
+			nodes[id] = Node{ID: id, Label: n.label, Props: n.props}
+		}
+		var rows [][]interface{}
+		for _, id := range ids {
+			for _, h := range ExpandEdges(nodes, f.edges, []string{id}, dir, types)[id] {
+				e, n := h.Edge, h.Node
+				rows = append(rows, []interface{}{id, e.ID, e.From, e.To, e.Type, e.Props, n.ID, n.Label, n.Props})
+			}
+		}
+		return rows, nil
+	case createNodeRE.MatchString(cypher):
+		f.next++
+		id := fmt.Sprintf("4:fake:%d", f.next)
+		f.nodes[id] = fakeNode{label: createNodeRE.FindStringSubmatch(cypher)[1], props: props}
+		return [][]interface{}{{id}}, nil
+	case createEdgeRE.MatchString(cypher):
+		from, _ := p["from"].(string)
+		to, _ := p["to"].(string)
+		if _, ok := f.nodes[from]; !ok {
+			return nil, nil
+		}
+		if _, ok := f.nodes[to]; !ok {
+			return nil, nil
+		}
+		f.next++
+		id := fmt.Sprintf("5:fake:%d", f.next)
+		f.edges = append(f.edges, Edge{ID: id, From: from, To: to, Type: createEdgeRE.FindStringSubmatch(cypher)[1], Props: props})
+		return [][]interface{}{{id}}, nil
+	case neighborsRE.MatchString(cypher):
+		var ids []string
+		for _, e := range f.edges {
+			if e.From == id && e.Type == neighborsRE.FindStringSubmatch(cypher)[1] {
+				ids = append(ids, e.To)
+			}
+		}
+		return f.nodeRows(ids), nil
+	case findNodesRE.MatchString(cypher):
+		label := findNodesRE.FindStringSubmatch(cypher)[1]
+		var ids []string
+		for id, n := range f.nodes {
+			if NodeMatches(Node{Label: n.label, Props: n.props}, label, props) {
+				ids = append(ids, id)
+			}
+		}
+		return f.nodeRows(ids), nil
+	case strings.Contains(cypher, "SET n += $props"):
+		n, ok := f.nodes[id]
+		if !ok {
+			return nil, nil
+		}
+		n.props = MergeProps(n.props, props)
+		f.nodes[id] = n
+		return [][]interface{}{{id}}, nil
+	case strings.HasPrefix(cypher, "MATCH (a)-[r]->(b) RETURN"):
+		return edgeRows(f.edges), nil
+	case strings.HasPrefix(cypher, "MATCH (a)-[r]->(b) WHERE elementId(r) = $id RETURN"):
+		return edgeRows(filterEdges(f.edges, func(e Edge) bool { return e.ID == id })), nil
+	case strings.HasPrefix(cypher, "MATCH (a)-[r]->(b) WHERE ($from = '' OR"):
+		q := EdgeQuery{From: p["from"].(string), To: p["to"].(string), Type: p["type"].(string), Props: props}
+		return edgeRows(filterEdges(f.edges, func(e Edge) bool { return EdgeMatches(e, q) })), nil
+	case strings.Contains(cypher, "SET r += $props"):
+		for i, e := range f.edges {
+			if e.ID == id {
+				f.edges[i].Props = MergeProps(e.Props, props)
+				return [][]interface{}{{id}}, nil
+			}
+		}
+		return nil, nil
+	case strings.HasSuffix(cypher, "DELETE r"):
+		f.edges = RemoveEdge(f.edges, id)
+		return nil, nil
+	case strings.HasSuffix(cypher, "DETACH DELETE n"):
+		delete(f.nodes, id)
+		f.edges = DetachEdges(f.edges, id)
+		return nil, nil
+	}
+	return nil, &Error{Code: "Neo.ClientError.Statement.SyntaxError", Message: cypher}
+}
+
+func (f *fakeNeo4j) nodeRows(ids []string) [][]interface{} {
+	sort.Strings(ids)
+	var rows [][]interface{}
+	for _, id := range ids {
+		n := f.nodes[id]
+		rows = append(rows, []interface{}{id, n.label, n.props})
+	}
+	return rows
+}
+
+func filterEdges(edges []Edge, keep func(Edge) bool) []Edge {
+	var out []Edge
+	for _, e := range edges {
+		if keep(e) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func edgeRows(edges []Edge) [][]interface{} {
+	var rows [][]interface{}
+	for _, e := range edges {
+		rows = append(rows, []interface{}{e.ID, e.From, e.To, e.Type, e.Props})
+	}
+	return rows
+}
+
+// connectFake starts a fake Neo4j server and connects a Graph to it.
+func connectFake(t *testing.T) (*Graph, *fakeNeo4j) {
+	t.Helper()
+	f := &fakeNeo4j{nodes: map[string]fakeNode{}}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	g, err := Connect(context.Background(), testConfig(t, srv.URL))
 	if err != nil {
 		t.Fatalf("connect: %v", err)
 	}
+	t.Cleanup(g.Close)
+	return g, f
+}
 
-	n1, _ := g.CreateNode(context.Background(), "Person", nil)
-	n2, _ := g.CreateNode(context.Background(), "Person", nil)
-	if _, err := g.CreateEdge(context.Background(), n1, n2, "KNOWS", nil); err != nil {
+func testConfig(t *testing.T, rawURL string) Config {
+	t.Helper()
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatalf("parse url: %v", err)
+	}
+	host, port, _ := net.SplitHostPort(u.Host)
+	return Config{User: "neo4j", Password: "secret", Host: host, Port: port, Database: "neo4j", MaxConns: 2, MaxRetries: 2, RetryBackoff: time.Millisecond}
+}
+
+func TestCreateAndQuery(t *testing.T) {
+	ctx := context.Background()
+	g, _ := connectFake(t)
+
+	n1, _ := g.CreateNode(ctx, "Person", map[string]interface{}{"name": "Alice", "memory_id": int64(1)})
+	n2, _ := g.CreateNode(ctx, "Person", nil)
+	if _, err := g.CreateEdge(ctx, n1, n2, "KNOWS", nil); err != nil {
 		t.Fatalf("create edge: %v", err)
 	}
 
-	neigh, err := g.Neighbors(context.Background(), n1, "KNOWS")
+	neigh, err := g.Neighbors(ctx, n1, "KNOWS")
 	if err != nil {
 		t.Fatalf("neighbors: %v", err)
 	}
-	if len(neigh) != 1 || neigh[0].ID != n2 {
+	if len(neigh) != 1 || neigh[0].ID != n2 || neigh[0].Label != "Person" {
 		t.Fatalf("unexpected neighbors: %+v", neigh)
 	}
+
+	found, err := g.FindNodes(ctx, "Person", map[string]interface{}{"memory_id": int64(1)})
+	if err != nil {
+		t.Fatalf("find nodes: %v", err)
+	}
+	if len(found) != 1 || found[0].ID != n1 || !reflect.DeepEqual(found[0].Props, map[string]interface{}{"name": "Alice", "memory_id": int64(1)}) {
+		t.Fatalf("unexpected nodes: %+v", found)
+	}
+	if all, _ := g.FindNodes(ctx, "", nil); len(all) != 2 {
+		t.Fatalf("expected every node, got %+v", all)
+	}
+
+	if err := g.UpdateNode(ctx, n2, map[string]interface{}{"name": "Bob"}); err != nil {
+		t.Fatalf("update node: %v", err)
+	}
+	if found, _ := g.FindNodes(ctx, "", map[string]interface{}{"name": "Bob"}); len(found) != 1 || found[0].ID != n2 {
+		t.Fatalf("node not updated: %+v", found)
+	}
+	if err := g.UpdateNode(ctx, "4:fake:99", map[string]interface{}{"name": "Eve"}); err == nil {
+		t.Fatal("expected error updating a missing node")
+	}
+	if _, err := g.CreateEdge(ctx, n1, "4:fake:99", "KNOWS", nil); err == nil {
+		t.Fatal("expected error relating a missing node")
+	}
+
+	e, _ := g.CreateEdge(ctx, n2, n1, "LIKES", map[string]interface{}{"since": 2020.5})
+	edges, err := g.Edges(ctx)
+	if err != nil {
+		t.Fatalf("edges: %v", err)
+	}
+	if len(edges) != 2 || edges[1].ID != e || edges[1].From != n2 || edges[1].Props["since"] != 2020.5 {
+		t.Fatalf("unexpected edges: %+v", edges)
+	}
+	if err := g.DeleteEdge(ctx, e); err != nil {
+		t.Fatalf("delete edge: %v", err)
+	}
+	if err := g.DeleteNode(ctx, n2); err != nil {
+		t.Fatalf("delete node: %v", err)
+	}
+	if edges, _ := g.Edges(ctx); len(edges) != 0 {
+		t.Fatalf("relationships not detached: %+v", edges)
+	}
+}
+
+func TestEdgeLookups(t *testing.T) {
+	ctx := context.Background()
+	g, _ := connectFake(t)
+
+	alice, _ := g.CreateNode(ctx, "Person", map[string]interface{}{"name": "Alice"})
+	bob, _ := g.CreateNode(ctx, "Person", map[string]interface{}{"name": "Bob"})
+	knows, _ := g.CreateEdge(ctx, alice, bob, "KNOWS", map[string]interface{}{"since": int64(2020)})
+	likes, _ := g.CreateEdge(ctx, bob, alice, "LIKES", nil)
+
+	e, err := g.Edge(ctx, knows)
+	if err != nil || e.From != alice || e.To != bob || e.Type != "KNOWS" || e.Props["since"] != int64(2020) {
+		t.Fatalf("edge: %+v, %v", e, err)
+	}
+	if _, err := g.Edge(ctx, "5:fake:99"); !errors.Is(err, ErrEdgeNotFound) {
+		t.Fatalf("expected ErrEdgeNotFound, got %v", err)
+	}
+	for _, tc := range []struct {
+		q    EdgeQuery
+		want []string
+	}{
+		{EdgeQuery{}, []string{knows, likes}},
+		{EdgeQuery{From: bob}, []string{likes}},
+		{EdgeQuery{To: bob, Type: "KNOWS"}, []string{knows}},
+		{EdgeQuery{Props: map[string]interface{}{"since": 2020.0}}, []string{knows}},
+		{EdgeQuery{Type: "HATES"}, nil},
+	} {
+		edges, err := g.FindEdges(ctx, tc.q)
+		if err != nil {
+			t.Fatalf("find edges %+v: %v", tc.q, err)
+		}
+		var got []string
+		for _, e := range edges {
+			got = append(got, e.ID)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("find edges %+v: got %v, want %v", tc.q, got, tc.want)
+		}
+	}
+
+	if err := g.UpdateEdge(ctx, knows, map[string]interface{}{"since": nil, "weight": 0.5}); err != nil {
+		t.Fatalf("update edge: %v", err)
+	}
+	if e, _ := g.Edge(ctx, knows); !reflect.DeepEqual(e.Props, map[string]interface{}{"weight": 0.5}) {
+		t.Fatalf("edge not updated: %+v", e.Props)
+	}
+	if err := g.UpdateEdge(ctx, "5:fake:99", nil); !errors.Is(err, ErrEdgeNotFound) {
+		t.Fatalf("expected ErrEdgeNotFound, got %v", err)
+	}
+	if err := g.UpdateNode(ctx, "4:fake:99", nil); !errors.Is(err, ErrNodeNotFound) {
+		t.Fatalf("expected ErrNodeNotFound, got %v", err)
+	}
+}
+
+func TestLabelsAreQuoted(t *testing.T) {
+	g, f := connectFake(t)
+	if _, err := g.CreateNode(context.Background(), "Person`) DETACH DELETE (m", nil); err != nil {
+		t.Fatalf("create node: %v", err)
+	}
+	if got := f.statements[len(f.statements)-1]; got != "CREATE (n:`Person``) DETACH DELETE (m` $props) RETURN elementId(n)" {
+		t.Fatalf("label not quoted: %s", got)
+	}
+	if _, err := g.CreateNode(context.Background(), "", nil); err == nil {
+		t.Fatal("expected error for an empty label")
+	}
+}
+
+func TestRetriesTransientErrors(t *testing.T) {
+	ctx := context.Background()
+	g, f := connectFake(t)
+
+	f.failures, f.failCode, f.requests = 2, "Neo.TransientError.Transaction.DeadlockDetected", 0
+	if _, err := g.CreateNode(ctx, "Person", nil); err != nil {
+		t.Fatalf("create node after transient errors: %v", err)
+	}
+	if f.requests != 3 {
+		t.Fatalf("expected 3 attempts, got %d", f.requests)
+	}
+
+	f.failures, f.requests = 3, 0
+	var ne *Error
+	if _, err := g.CreateNode(ctx, "Person", nil); !errors.As(err, &ne) || !ne.Transient() {
+		t.Fatalf("expected transient error once retries run out, got %v", err)
+	}
+	if f.requests != 3 {
+		t.Fatalf("expected 3 attempts, got %d", f.requests)
+	}
+
+	f.failures, f.failCode, f.requests = 1, "Neo.ClientError.Statement.SyntaxError", 0
+	if _, err := g.FindNodes(ctx, "Person", nil); !errors.As(err, &ne) || ne.Transient() {
+		t.Fatalf("expected client error, got %v", err)
+	}
+	if f.requests != 1 {
+		t.Fatalf("client errors must not be retried, got %d attempts", f.requests)
+	}
+}
+
+func TestConnectChecksCredentials(t *testing.T) {
+	srv := httptest.NewServer(&fakeNeo4j{nodes: map[string]fakeNode{}})
+	defer srv.Close()
+	cfg := testConfig(t, srv.URL)
+	cfg.Password = "wrong"
+	if _, err := Connect(context.Background(), cfg); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("expected unauthorized, got %v", err)
+	}
+}
+
+func TestTraversal(t *testing.T) {
+	ctx := context.Background()
+	g, _ := connectFake(t)
+
+	// alice -KNOWS-> bob -KNOWS-> carol -WORKS_AT-> acme <-WORKS_AT- alice
+	alice, _ := g.CreateNode(ctx, "Person", map[string]interface{}{"name": "alice", "age": int64(30)})
+	bob, _ := g.CreateNode(ctx, "Person", map[string]interface{}{"name": "bob", "age": int64(25)})
+	carol, _ := g.CreateNode(ctx, "Person", map[string]interface{}{"name": "carol", "age": int64(40)})
+	acme, _ := g.CreateNode(ctx, "Company", map[string]interface{}{"name": "acme"})
+	for _, e := range [][3]string{{alice, bob, "KNOWS"}, {bob, carol, "KNOWS"}, {carol, acme, "WORKS_AT"}, {alice, acme, "WORKS_AT"}} {
+		if _, err := g.CreateEdge(ctx, e[0], e[1], e[2], nil); err != nil {
+			t.Fatalf("create edge: %v", err)
+		}
+	}
+	names := func(nodes []Node) string {
+		var out []string
+		for _, n := range nodes {
+			out = append(out, n.Props["name"].(string))
+		}
+		return strings.Join(out, " ")
+	}
+	walk := func(q TraversalQuery) string {
+		t.Helper()
+		visits, err := g.Traverse(ctx, q)
+		if err != nil {
+			t.Fatalf("traverse %+v: %v", q, err)
+		}
+		var out []string
+		for _, v := range visits {
+			out = append(out, fmt.Sprintf("%s:%d", v.Node.Props["name"], v.Depth))
+		}
+		return strings.Join(out, " ")
+	}
+
+	if got := walk(TraversalQuery{Start: alice, MaxDepth: 3}); got != "bob:1 acme:1 carol:2" {
+		t.Fatalf("bfs: %s", got)
+	}
+	if got := walk(TraversalQuery{Start: alice, MaxDepth: 3, Strategy: DFS}); got != "bob:1 carol:2 acme:3" {
+		t.Fatalf("dfs: %s", got)
+	}
+	if got := walk(TraversalQuery{Start: alice, MaxDepth: 3, Expansion: Expansion{Types: []string{"KNOWS"}}}); got != "bob:1 carol:2" {
+		t.Fatalf("types: %s", got)
+	}
+	if got := walk(TraversalQuery{Start: acme, Expansion: Expansion{Direction: In}}); got != "carol:1 alice:1" {
+		t.Fatalf("incoming: %s", got)
+	}
+	where := []Predicate{{Key: "age", Op: OpLt, Value: 35.0}}
+	if got := walk(TraversalQuery{Start: acme, MaxDepth: 3, Expansion: Expansion{Direction: Both, Where: where}}); got != "alice:1 bob:2" {
+		t.Fatalf("predicate: %s", got)
+	}
+	if got := walk(TraversalQuery{Start: alice, MaxDepth: 3, Limit: 2}); got != "bob:1 acme:1" {
+		t.Fatalf("limit: %s", got)
+	}
+
+	path, err := g.ShortestPath(ctx, bob, acme, Expansion{}, 1)
+	if err != nil || path != nil {
+		t.Fatalf("expected no path within one hop, got %+v, %v", path, err)
+	}
+	path, err = g.ShortestPath(ctx, bob, alice, Expansion{Direction: Both}, 3)
+	if err != nil || path == nil || names(path.Nodes) != "bob alice" || path.Len() != 1 {
+		t.Fatalf("shortest path: %+v, %v", path, err)
+	}
+	path, _ = g.ShortestPath(ctx, bob, acme, Expansion{}, 3)
+	if path == nil || names(path.Nodes) != "bob carol acme" || path.Edges[1].Type != "WORKS_AT" {
+		t.Fatalf("directed path: %+v", path)
+	}
+
+	sg, err := g.Subgraph(ctx, alice, Expansion{Direction: Both}, 1)
+	if err != nil {
+		t.Fatalf("subgraph: %v", err)
+	}
+	if names(sg.Nodes) != "alice bob acme" || len(sg.Edges) != 2 {
+		t.Fatalf("subgraph: %+v", sg)
+	}
+	if sg, _ := g.Subgraph(ctx, bob, Expansion{Direction: Both}, 2); len(sg.Nodes) != 4 || len(sg.Edges) != 4 {
+		t.Fatalf("subgraph closes over the nodes reached: %+v", sg)
+	}
+
+	for _, q := range []TraversalQuery{
+		{Start: alice, MaxDepth: MaxDepth + 1},
+		{Start: alice, Strategy: "random"},
+		{Start: alice, Expansion: Expansion{Direction: "sideways"}},
+		{Start: alice, Expansion: Expansion{Where: []Predicate{{Key: "age", Op: "like"}}}},
+	} {
+		if _, err := g.Traverse(ctx, q); !errors.Is(err, ErrInvalidQuery) {
+			t.Fatalf("%+v: expected ErrInvalidQuery, got %v", q, err)
+		}
+	}
+	if _, err := g.Traverse(ctx, TraversalQuery{Start: "4:fake:99"}); !errors.Is(err, ErrNodeNotFound) {
+		t.Fatalf("expected ErrNodeNotFound, got %v", err)
+	}
+}
+
+func TestPredicateMatch(t *testing.T) {
+	n := Node{Props: map[string]interface{}{"age": int64(30), "name": "alice", "tags": []interface{}{"a", "b"}}}
+	for _, tc := range []struct {
+		p    Predicate
+		want bool
+	}{
+		{Predicate{Key: "age", Value: 30.0}, true},
+		{Predicate{Key: "age", Op: OpNe, Value: 30}, false},
+		{Predicate{Key: "age", Op: OpGte, Value: 30}, true},
+		{Predicate{Key: "age", Op: OpGt, Value: "30"}, false},
+		{Predicate{Key: "name", Op: OpLt, Value: "bob"}, true},
+		{Predicate{Key: "name", Op: OpContains, Value: "lic"}, true},
+		{Predicate{Key: "tags", Op: OpContains, Value: "b"}, true},
+		{Predicate{Key: "email", Op: OpExists}, false},
+		{Predicate{Key: "email", Op: OpNe, Value: "x"}, true},
+	} {
+		if got := tc.p.Match(n); got != tc.want {
+			t.Fatalf("%+v: got %v", tc.p, got)
+		}
+	}
+}
+
+func TestRetriesOnlyUnsentStatements(t *testing.T) {
+	ctx := context.Background()
+	g, _ := connectFake(t)
+
+	// the connection drops after the statement was sent: it may have run
+	var mu sync.Mutex
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err == nil {
+			_ = conn.Close()
+		}
+	}))
+	defer srv.Close()
+	g.endpoint = srv.URL + "/db/neo4j/tx/commit"
+	if _, err := g.CreateNode(ctx, "Person", nil); err == nil {
+		t.Fatalf("expected error from dropped connection")
+	}
+	mu.Lock()
+	if requests != 1 {
+		t.Fatalf("a statement that reached the server must not be retried, got %d attempts", requests)
+	}
+	mu.Unlock()
+
+	// nothing listens: the statement never left
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := l.Addr().String()
+	_ = l.Close()
+	g.endpoint = "http://" + addr + "/db/neo4j/tx/commit"
+	_, err = g.CreateNode(ctx, "Person", nil)
+	if err == nil || !retryable(err) {
+		t.Fatalf("expected a retryable dial error, got %v", err)
+	}
+	if retryable(&statusError{code: http.StatusGatewayTimeout}) {
+		t.Fatalf("gateway timeouts must not be retried")
+	}
 }
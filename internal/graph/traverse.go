@@ -0,0 +1,538 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// MaxDepth bounds how many relationships a traversal, path or subgraph
+// may span.
+const MaxDepth = 6
+
+// ErrInvalidQuery is returned for traversal queries with an unknown
+// direction, strategy or predicate, or a depth outside [1, MaxDepth].
+var ErrInvalidQuery = errors.New("graph: invalid traversal query")
+
+// Direction selects which relationships of a node are followed.
+type Direction string
+
+// Directions.
+const (
+	// Out follows relationships starting at the node; it is the default.
+	Out Direction = "out"
+	// In follows relationships ending at the node.
+	In Direction = "in"
+	// Both follows relationships regardless of direction.
+	Both Direction = "both"
+)
+
+// Strategy is the order a traversal visits nodes in.
+type Strategy string
+
+// Strategies.
+const (
+	// BFS visits nodes breadth first, nearest first; it is the default.
+	BFS Strategy = "bfs"
+	// DFS visits nodes depth first.
+	DFS Strategy = "dfs"
+)
+
+// Predicate operators.
+const (
+	OpEq       = "eq"
+	OpNe       = "ne"
+	OpGt       = "gt"
+	OpGte      = "gte"
+	OpLt       = "lt"
+	OpLte      = "lte"
+	OpContains = "contains"
+	OpExists   = "exists"
+)
+
+// Predicate compares the property Key of a node with Value. Op is one of
+// the Op constants and defaults to OpEq. Numbers compare by value whatever
+// their type; strings are ordered lexically; OpContains matches substrings
+// of strings and elements of lists; OpExists ignores Value.
+type Predicate struct {
+	Key   string      `json:"key"`
+	Op    string      `json:"op,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// Match reports whether n satisfies p.
+func (p Predicate) Match(n Node) bool {
+	got, ok := n.Props[p.Key]
+	switch p.Op {
+	case OpExists:
+		return ok
+	case OpNe:
+		return !ok || !equal(got, p.Value)
+	}
+	if !ok {
+		return false
+	}
+	switch p.Op {
+	case "", OpEq:
+		return equal(got, p.Value)
+	case OpContains:
+		if s, ok := got.(string); ok {
+			sub, ok := p.Value.(string)
+			return ok && strings.Contains(s, sub)
+		}
+		if list, ok := got.([]interface{}); ok {
+			for _, v := range list {
+				if equal(v, p.Value) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	c, ok := compare(got, p.Value)
+	if !ok {
+		return false
+	}
+	switch p.Op {
+	case OpGt:
+		return c > 0
+	case OpGte:
+		return c >= 0
+	case OpLt:
+		return c < 0
+	case OpLte:
+		return c <= 0
+	}
+	return false
+}
+
+func (p Predicate) validate() error {
+	switch p.Op {
+	case "", OpEq, OpNe, OpGt, OpGte, OpLt, OpLte, OpContains, OpExists:
+	default:
+		return fmt.Errorf("%w: operator %q", ErrInvalidQuery, p.Op)
+	}
+	if p.Key == "" {
+		return fmt.Errorf("%w: predicate key required", ErrInvalidQuery)
+	}
+	return nil
+}
+
+// equal compares property values, numbers by value.
+func equal(a, b interface{}) bool {
+	if x, ok := number(a); ok {
+		y, ok := number(b)
+		return ok && x == y
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// compare orders two numbers or two strings.
+func compare(a, b interface{}) (int, bool) {
+	if x, ok := number(a); ok {
+		y, ok := number(b)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	}
+	x, ok := a.(string)
+	y, ok2 := b.(string)
+	if !ok || !ok2 {
+		return 0, false
+	}
+	return strings.Compare(x, y), true
+}
+
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// Expansion selects the relationships a traversal follows from each node:
+// those in Direction with one of Types, any type when empty, leading to
+// nodes satisfying every predicate in Where. NodeFilter and EdgeFilter,
+// when set, further restrict the nodes entered and relationships followed.
+type Expansion struct {
+	Direction  Direction
+	Types      []string
+	Where      []Predicate
+	NodeFilter func(Node) bool
+	EdgeFilter func(Edge) bool
+}
+
+func (e Expansion) validate() error {
+	switch e.Direction {
+	case "", Out, In, Both:
+	default:
+		return fmt.Errorf("%w: direction %q", ErrInvalidQuery, e.Direction)
+	}
+	for _, p := range e.Where {
+		if err := p.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// follows reports whether the expansion follows h.
+func (e Expansion) follows(h Hop) bool {
+	if e.EdgeFilter != nil && !e.EdgeFilter(h.Edge) {
+		return false
+	}
+	if e.NodeFilter != nil && !e.NodeFilter(h.Node) {
+		return false
+	}
+	for _, p := range e.Where {
+		if !p.Match(h.Node) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e Expansion) direction() Direction {
+	if e.Direction == "" {
+		return Out
+	}
+	return e.Direction
+}
+
+// TraversalQuery describes a walk from Start over up to MaxDepth
+// relationships, 1 by default, returning at most Limit nodes when Limit
+// is positive.
+type TraversalQuery struct {
+	Start string
+	Expansion
+	MaxDepth int
+	Strategy Strategy
+	Limit    int
+}
+
+// Visit is a node reached by a traversal, Depth relationships from the
+// start, and the relationship it was first reached by.
+type Visit struct {
+	Node  Node `json:"node"`
+	Depth int  `json:"depth"`
+	Via   Edge `json:"via"`
+}
+
+// Path is a sequence of nodes and the relationships between them; Edges
+// has one element fewer than Nodes.
+type Path struct {
+	Nodes []Node `json:"nodes"`
+	Edges []Edge `json:"edges"`
+}
+
+// Len returns the number of relationships on p.
+func (p Path) Len() int { return len(p.Edges) }
+
+// Subgraph is a set of nodes and the relationships between them.
+type Subgraph struct {
+	Nodes []Node `json:"nodes"`
+	Edges []Edge `json:"edges"`
+}
+
+// Hop is a relationship seen from the node being expanded, and the node
+// at its other end.
+type Hop struct {
+	Edge Edge
+	Node Node
+}
+
+// Expander is implemented by stores Walk, FindPath and Extract traverse.
+type Expander interface {
+	// Nodes returns the nodes with the given IDs, skipping missing ones.
+	Nodes(ctx context.Context, ids []string) ([]Node, error)
+	// Expand returns, for each of ids, its relationships in dir with one
+	// of types, any type when empty, and the nodes at their other ends.
+	Expand(ctx context.Context, ids []string, dir Direction, types []string) (map[string][]Hop, error)
+}
+
+// depth returns the depth d defaulted and checked.
+func depth(d int) (int, error) {
+	if d == 0 {
+		return 1, nil
+	}
+	if d < 0 || d > MaxDepth {
+		return 0, fmt.Errorf("%w: depth %d outside [1, %d]", ErrInvalidQuery, d, MaxDepth)
+	}
+	return d, nil
+}
+
+// start returns node id, or ErrNodeNotFound when it does not exist or the
+// expansion's NodeFilter rejects it.
+func start(ctx context.Context, x Expander, id string, e Expansion) (Node, error) {
+	nodes, err := x.Nodes(ctx, []string{id})
+	if err != nil {
+		return Node{}, err
+	}
+	if len(nodes) == 0 || (e.NodeFilter != nil && !e.NodeFilter(nodes[0])) {
+		return Node{}, fmt.Errorf("%w: %s", ErrNodeNotFound, id)
+	}
+	return nodes[0], nil
+}
+
+// Walk traverses x as described by q and returns the nodes reached, other
+// than the start, in the order visited.
+func Walk(ctx context.Context, x Expander, q TraversalQuery) ([]Visit, error) {
+	if err := q.validate(); err != nil {
+		return nil, err
+	}
+	maxDepth, err := depth(q.MaxDepth)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := start(ctx, x, q.Start, q.Expansion); err != nil {
+		return nil, err
+	}
+	w := walker{x: x, e: q.Expansion, maxDepth: maxDepth, limit: q.Limit, seen: map[string]bool{q.Start: true}}
+	switch q.Strategy {
+	case "", BFS:
+		err = w.bfs(ctx, q.Start)
+	case DFS:
+		err = w.dfs(ctx, q.Start, 0)
+	default:
+		return nil, fmt.Errorf("%w: strategy %q", ErrInvalidQuery, q.Strategy)
+	}
+	if err != nil && !errors.Is(err, errLimit) {
+		return nil, err
+	}
+	return w.visits, nil
+}
+
+// errLimit stops a walk once it has visited enough nodes.
+var errLimit = errors.New("limit reached")
+
+type walker struct {
+	x        Expander
+	e        Expansion
+	maxDepth int
+	limit    int
+	seen     map[string]bool
+	visits   []Visit
+}
+
+func (w *walker) visit(h Hop, d int) error {
+	w.seen[h.Node.ID] = true
+	w.visits = append(w.visits, Visit{Node: h.Node, Depth: d, Via: h.Edge})
+	if w.limit > 0 && len(w.visits) >= w.limit {
+		return errLimit
+	}
+	return nil
+}
+
+func (w *walker) bfs(ctx context.Context, id string) error {
+	frontier := []string{id}
+	for d := 1; d <= w.maxDepth && len(frontier) > 0; d++ {
+		hops, err := w.x.Expand(ctx, frontier, w.e.direction(), w.e.Types)
+		if err != nil {
+			return err
+		}
+		var next []string
+		for _, from := range frontier {
+			for _, h := range hops[from] {
+				if w.seen[h.Node.ID] || !w.e.follows(h) {
+					continue
+				}
+				if err := w.visit(h, d); err != nil {
+					return err
+				}
+				next = append(next, h.Node.ID)
+			}
+		}
+		frontier = next
+	}
+	return nil
+}
+
+func (w *walker) dfs(ctx context.Context, id string, d int) error {
+	if d == w.maxDepth {
+		return nil
+	}
+	hops, err := w.x.Expand(ctx, []string{id}, w.e.direction(), w.e.Types)
+	if err != nil {
+		return err
+	}
+	for _, h := range hops[id] {
+		if w.seen[h.Node.ID] || !w.e.follows(h) {
+			continue
+		}
+		if err := w.visit(h, d+1); err != nil {
+			return err
+		}
+		if err := w.dfs(ctx, h.Node.ID, d+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FindPath returns a shortest path from node from to node to over at most
+// maxDepth relationships, MaxDepth when 0, followed as e describes, or nil
+// when there is none.
+func FindPath(ctx context.Context, x Expander, from, to string, e Expansion, maxDepth int) (*Path, error) {
+	if err := e.validate(); err != nil {
+		return nil, err
+	}
+	if maxDepth == 0 {
+		maxDepth = MaxDepth
+	}
+	maxDepth, err := depth(maxDepth)
+	if err != nil {
+		return nil, err
+	}
+	first, err := start(ctx, x, from, e)
+	if err != nil {
+		return nil, err
+	}
+	if from == to {
+		return &Path{Nodes: []Node{first}, Edges: []Edge{}}, nil
+	}
+	reached := map[string]Hop{from: {Node: first}}
+	frontier := []string{from}
+	for d := 1; d <= maxDepth && len(frontier) > 0; d++ {
+		hops, err := x.Expand(ctx, frontier, e.direction(), e.Types)
+		if err != nil {
+			return nil, err
+		}
+		var next []string
+		for _, id := range frontier {
+			for _, h := range hops[id] {
+				if _, ok := reached[h.Node.ID]; ok || !e.follows(h) {
+					continue
+				}
+				reached[h.Node.ID] = h
+				if h.Node.ID == to {
+					return path(reached, from, to), nil
+				}
+				next = append(next, h.Node.ID)
+			}
+		}
+		frontier = next
+	}
+	return nil, nil
+}
+
+// path follows the hops in reached back from to.
+func path(reached map[string]Hop, from, to string) *Path {
+	p := &Path{}
+	for id := to; ; {
+		h := reached[id]
+		p.Nodes = append(p.Nodes, h.Node)
+		if id == from {
+			break
+		}
+		p.Edges = append(p.Edges, h.Edge)
+		id = other(h.Edge, id)
+	}
+	for i, j := 0, len(p.Nodes)-1; i < j; i, j = i+1, j-1 {
+		p.Nodes[i], p.Nodes[j] = p.Nodes[j], p.Nodes[i]
+	}
+	for i, j := 0, len(p.Edges)-1; i < j; i, j = i+1, j-1 {
+		p.Edges[i], p.Edges[j] = p.Edges[j], p.Edges[i]
+	}
+	return p
+}
+
+// other returns the end of e that is not id.
+func other(e Edge, id string) string {
+	if e.From == id {
+		return e.To
+	}
+	return e.From
+}
+
+// Extract returns the nodes within depth relationships of center,
+// followed as e describes, and every such relationship between them. The
+// center comes first, then nodes nearest first.
+func Extract(ctx context.Context, x Expander, center string, e Expansion, depth int) (Subgraph, error) {
+	visits, err := Walk(ctx, x, TraversalQuery{Start: center, Expansion: e, MaxDepth: depth})
+	if err != nil {
+		return Subgraph{}, err
+	}
+	first, err := start(ctx, x, center, e)
+	if err != nil {
+		return Subgraph{}, err
+	}
+	sg := Subgraph{Nodes: []Node{first}, Edges: []Edge{}}
+	ids := []string{center}
+	in := map[string]bool{center: true}
+	for _, v := range visits {
+		sg.Nodes = append(sg.Nodes, v.Node)
+		ids = append(ids, v.Node.ID)
+		in[v.Node.ID] = true
+	}
+	hops, err := x.Expand(ctx, ids, e.direction(), e.Types)
+	if err != nil {
+		return Subgraph{}, err
+	}
+	seen := map[string]bool{}
+	for _, id := range ids {
+		for _, h := range hops[id] {
+			if !in[h.Node.ID] || seen[h.Edge.ID] || (e.EdgeFilter != nil && !e.EdgeFilter(h.Edge)) {
+				continue
+			}
+			seen[h.Edge.ID] = true
+			sg.Edges = append(sg.Edges, h.Edge)
+		}
+	}
+	return sg, nil
+}
+
+// ExpandEdges implements Expander.Expand over nodes and edges held in
+// memory, keeping the order of edges.
+func ExpandEdges(nodes map[string]Node, edges []Edge, ids []string, dir Direction, types []string) map[string][]Hop {
+	want := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		want[id] = true
+	}
+	out := make(map[string][]Hop, len(ids))
+	for _, e := range edges {
+		if len(types) > 0 && !slices.Contains(types, e.Type) {
+			continue
+		}
+		if dir != In && want[e.From] {
+			if n, ok := nodes[e.To]; ok {
+				out[e.From] = append(out[e.From], Hop{Edge: e, Node: n})
+			}
+		}
+		if dir != Out && want[e.To] && (dir == In || e.From != e.To) {
+			if n, ok := nodes[e.From]; ok {
+				out[e.To] = append(out[e.To], Hop{Edge: e, Node: n})
+			}
+		}
+	}
+	return out
+}
+
+// NodesByID implements Expander.Nodes over nodes held in memory.
+func NodesByID(nodes map[string]Node, ids []string) []Node {
+	out := make([]Node, 0, len(ids))
+	for _, id := range ids {
+		if n, ok := nodes[id]; ok {
+			out = append(out, n)
+		}
+	}
+	return out
+}
@@ -0,0 +1,248 @@
+package graphql
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Location is a 1-based line and column in a GraphQL source.
+type Location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Document is a parsed GraphQL document. Executable documents hold
+// operations and fragments; schema documents hold type definitions.
+type Document struct {
+	Operations []*Operation
+	Fragments  []*Fragment
+	Types      []*TypeDef
+	Schema     *SchemaDef
+}
+
+// Operation is a query, mutation or subscription.
+type Operation struct {
+	Type       string
+	Name       string
+	Vars       []*VarDef
+	Directives []*Directive
+	Selections []Selection
+	Loc        Location
+}
+
+// VarDef declares an operation variable.
+type VarDef struct {
+	Name    string
+	Type    *TypeRef
+	Default *Value
+	Loc     Location
+}
+
+// TypeRef is a type reference such as [String!]!. Exactly one of Name and
+// Elem is set.
+type TypeRef struct {
+	Name    string
+	Elem    *TypeRef
+	NonNull bool
+	Loc     Location
+}
+
+func (t *TypeRef) String() string {
+	s := t.Name
+	if t.Elem != nil {
+		s = "[" + t.Elem.String() + "]"
+	}
+	if t.NonNull {
+		s += "!"
+	}
+	return s
+}
+
+// Selection is a *Field, *FragmentSpread or *InlineFragment.
+type Selection interface{ location() Location }
+
+// Field selects a field, optionally under an alias.
+type Field struct {
+	Alias      string
+	Name       string
+	Args       []*Argument
+	Directives []*Directive
+	Selections []Selection
+	Loc        Location
+}
+
+// ResponseKey is the alias if present, else the field name.
+func (f *Field) ResponseKey() string {
+	if f.Alias != "" {
+		return f.Alias
+	}
+	return f.Name
+}
+
+// FragmentSpread includes a named fragment.
+type FragmentSpread struct {
+	Name       string
+	Directives []*Directive
+	Loc        Location
+}
+
+// InlineFragment includes selections, optionally for a type condition.
+type InlineFragment struct {
+	TypeCond   string
+	Directives []*Directive
+	Selections []Selection
+	Loc        Location
+}
+
+func (f *Field) location() Location          { return f.Loc }
+func (f *FragmentSpread) location() Location { return f.Loc }
+func (f *InlineFragment) location() Location { return f.Loc }
+
+// Fragment is a named fragment definition.
+type Fragment struct {
+	Name       string
+	TypeCond   string
+	Directives []*Directive
+	Selections []Selection
+	Loc        Location
+}
+
+// Argument is a name and value passed to a field or directive.
+type Argument struct {
+	Name  string
+	Value *Value
+	Loc   Location
+}
+
+// Directive annotates a definition or selection.
+type Directive struct {
+	Name string
+	Args []*Argument
+	Loc  Location
+}
+
+// ValueKind identifies the kind of a literal Value.
+type ValueKind int
+
+const (
+	VariableValue ValueKind = iota
+	IntValue
+	FloatValue
+	StringValue
+	BooleanValue
+	NullValue
+	EnumValue
+	ListValue
+	ObjectValue
+)
+
+// Value is an input literal. Raw holds the variable or enum name, the
+// number text, the decoded string or "true"/"false".
+type Value struct {
+	Kind   ValueKind
+	Raw    string
+	List   []*Value
+	Fields []*ObjectField
+	Loc    Location
+}
+
+// ObjectField is a field of an input object literal.
+type ObjectField struct {
+	Name  string
+	Value *Value
+	Loc   Location
+}
+
+// String prints v as a GraphQL literal.
+func (v *Value) String() string {
+	switch v.Kind {
+	case VariableValue:
+		return "$" + v.Raw
+	case StringValue:
+		return strconv.Quote(v.Raw)
+	case NullValue:
+		return "null"
+	case ListValue:
+		parts := make([]string, len(v.List))
+		for i, e := range v.List {
+			parts[i] = e.String()
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	case ObjectValue:
+		parts := make([]string, len(v.Fields))
+		for i, f := range v.Fields {
+			parts[i] = f.Name + ": " + f.Value.String()
+		}
+		return "{" + strings.Join(parts, ", ") + "}"
+	default:
+		return v.Raw
+	}
+}
+
+// TypeDef is a type definition in a schema document: a scalar, type,
+// input or enum.
+type TypeDef struct {
+	Kind        string
+	Name        string
+	Description string
+	Fields      []*FieldDef
+	InputFields []*InputValueDef
+	Values      []*EnumValueDef
+	Directives  []*Directive
+	Loc         Location
+}
+
+// FieldDef defines a field of an object type.
+type FieldDef struct {
+	Name        string
+	Description string
+	Args        []*InputValueDef
+	Type        *TypeRef
+	Directives  []*Directive
+	Loc         Location
+}
+
+// InputValueDef defines an argument or input object field.
+type InputValueDef struct {
+	Name        string
+	Description string
+	Type        *TypeRef
+	Default     *Value
+	Directives  []*Directive
+	Loc         Location
+}
+
+// EnumValueDef defines an enum value.
+type EnumValueDef struct {
+	Name        string
+	Description string
+	Directives  []*Directive
+	Loc         Location
+}
+
+// SchemaDef names the root operation types.
+type SchemaDef struct {
+	Query        string
+	Mutation     string
+	Subscription string
+}
+
+// directive returns the directive called name, if present.
+func directive(ds []*Directive, name string) *Directive {
+	for _, d := range ds {
+		if d.Name == name {
+			return d
+		}
+	}
+	return nil
+}
+
+// argument returns the argument called name, if present.
+func argument(args []*Argument, name string) *Argument {
+	for _, a := range args {
+		if a.Name == name {
+			return a
+		}
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package graphql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Error is a GraphQL error as it appears in a response's errors array.
+// Path is set for errors raised while resolving a field.
+type Error struct {
+	Message    string                 `json:"message"`
+	Locations  []Location             `json:"locations,omitempty"`
+	Path       []interface{}          `json:"path,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Message)
+	for _, l := range e.Locations {
+		fmt.Fprintf(&b, " (%d:%d)", l.Line, l.Column)
+	}
+	return b.String()
+}
+
+func newError(loc Location, format string, args ...interface{}) *Error {
+	return &Error{Message: fmt.Sprintf(format, args...), Locations: []Location{loc}}
+}
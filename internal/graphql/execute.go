@@ -0,0 +1,495 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Params is a GraphQL request.
+type Params struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// Response is a GraphQL response. Data is absent when the request failed
+// before execution began (syntax, validation or variable errors) and null
+// when a non-null root field failed.
+type Response struct {
+	Data   interface{} `json:"data"`
+	Errors []*Error    `json:"errors,omitempty"`
+
+	executed bool
+}
+
+// Executed reports whether execution began, i.e. Data is meaningful.
+func (r *Response) Executed() bool { return r.executed }
+
+func (r *Response) MarshalJSON() ([]byte, error) {
+	type body struct {
+		Data   interface{} `json:"data"`
+		Errors []*Error    `json:"errors,omitempty"`
+	}
+	if !r.executed {
+		return json.Marshal(struct {
+			Errors []*Error `json:"errors"`
+		}{r.Errors})
+	}
+	return json.Marshal(body{r.Data, r.Errors})
+}
+
+// Do parses, validates and executes a request.
+func (s *Schema) Do(ctx context.Context, p Params) *Response {
+	doc, err := Parse(p.Query)
+	if err != nil {
+		return &Response{Errors: []*Error{toError(err)}}
+	}
+	if errs := Validate(s, doc); len(errs) > 0 {
+		return &Response{Errors: errs}
+	}
+	return s.Execute(ctx, doc, p.OperationName, p.Variables)
+}
+
+// SelectOperation returns the operation of doc that a request named name would
+// run, or an error when it is missing or ambiguous.
+func SelectOperation(doc *Document, name string) (*Operation, error) {
+	if name == "" {
+		if len(doc.Operations) != 1 {
+			return nil, &Error{Message: "Must provide operation name if query contains multiple operations."}
+		}
+		return doc.Operations[0], nil
+	}
+	for _, op := range doc.Operations {
+		if op.Name == name {
+			return op, nil
+		}
+	}
+	return nil, &Error{Message: fmt.Sprintf("Unknown operation named %q.", name)}
+}
+
+// Execute runs a validated document. Query fields are resolved in order;
+// mutation fields run serially, as the spec requires.
+func (s *Schema) Execute(ctx context.Context, doc *Document, operationName string, variables map[string]interface{}) *Response {
+	op, err := SelectOperation(doc, operationName)
+	if err != nil {
+		return &Response{Errors: []*Error{toError(err)}}
+	}
+	vars, errs := coerceVariables(s, op, variables)
+	if len(errs) > 0 {
+		return &Response{Errors: errs}
+	}
+	if op.Type == "subscription" {
+		return &Response{Errors: []*Error{newError(op.Loc, "Subscription operations must be run with Subscribe.")}}
+	}
+	root := s.Query
+	if op.Type == "mutation" {
+		root = s.Mutation
+	}
+	if root == nil {
+		return &Response{Errors: []*Error{newError(op.Loc, "Schema is not configured to execute %s operation.", op.Type)}}
+	}
+	e := &executor{s: s, doc: doc, vars: vars}
+	data, _ := e.selectionSet(ctx, root, nil, op.Selections, nil)
+	resp := &Response{Errors: e.errs, executed: true}
+	if data != nil {
+		resp.Data = data
+	}
+	return resp
+}
+
+// Subscribe runs a validated subscription operation. The returned channel
+// carries one response per event and is closed when ctx is done or the
+// source stream ends. When the subscription cannot be set up, the channel
+// is nil and the response holds the errors.
+func (s *Schema) Subscribe(ctx context.Context, doc *Document, operationName string, variables map[string]interface{}) (<-chan *Response, *Response) {
+	op, err := SelectOperation(doc, operationName)
+	if err != nil {
+		return nil, &Response{Errors: []*Error{toError(err)}}
+	}
+	if op.Type != "subscription" || s.Subscription == nil {
+		return nil, &Response{Errors: []*Error{newError(op.Loc, "Expected a subscription operation.")}}
+	}
+	vars, errs := coerceVariables(s, op, variables)
+	if len(errs) > 0 {
+		return nil, &Response{Errors: errs}
+	}
+	e := &executor{s: s, doc: doc, vars: vars}
+	var groups fieldGroups
+	e.collect(s.Subscription, op.Selections, map[string]bool{}, &groups)
+	if len(groups.keys) != 1 {
+		return nil, &Response{Errors: []*Error{newError(op.Loc, "Subscription must select exactly one top level field.")}}
+	}
+	key := groups.keys[0]
+	fields := groups.fields[key]
+	fd := s.fieldDef(s.Subscription, fields[0].Name)
+	args, err := coerceArgs(fd.Args, fields[0].Args, vars)
+	if err != nil || fd.Resolve == nil {
+		if err == nil {
+			err = fmt.Errorf("Subscription field %q has no source stream.", fd.Name)
+		}
+		e.fieldError(err, fields[0], []interface{}{key})
+		return nil, &Response{Errors: e.errs, executed: true}
+	}
+	src, err := safeResolve(ctx, fd.Resolve, ResolveParams{Args: args, Field: fields[0]})
+	stream, ok := src.(<-chan interface{})
+	if err == nil && !ok {
+		err = fmt.Errorf("Subscription field %q did not return an event stream.", fd.Name)
+	}
+	if err != nil {
+		e.fieldError(err, fields[0], []interface{}{key})
+		return nil, &Response{Errors: e.errs, executed: true}
+	}
+	out := make(chan *Response)
+	go func() {
+		defer close(out)
+		for {
+			var ev interface{}
+			select {
+			case <-ctx.Done():
+				return
+			case ev, ok = <-stream:
+				if !ok {
+					return
+				}
+			}
+			e := &executor{s: s, doc: doc, vars: vars}
+			resp := &Response{executed: true}
+			if v, failed := e.complete(ctx, fd.Type, fields, ev, []interface{}{key}); !failed || fd.Type.Kind != KindNonNull {
+				data := &OrderedMap{}
+				data.Set(key, v)
+				resp.Data = data
+			}
+			resp.Errors = e.errs
+			select {
+			case out <- resp:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// executor holds the state of one execution.
+type executor struct {
+	s    *Schema
+	doc  *Document
+	vars map[string]interface{}
+	errs []*Error
+}
+
+func (e *executor) fieldError(err error, f *Field, path []interface{}) {
+	ge := &Error{Message: err.Error(), Locations: []Location{f.Loc}, Path: append([]interface{}(nil), path...)}
+	var ext interface{ Extensions() map[string]interface{} }
+	if errors.As(err, &ext) {
+		ge.Extensions = ext.Extensions()
+	}
+	e.errs = append(e.errs, ge)
+}
+
+// collect groups the selected fields by response key, applying @skip,
+// @include and fragment type conditions.
+func (e *executor) collect(t *Type, sels []Selection, visited map[string]bool, out *fieldGroups) {
+	for _, sel := range sels {
+		switch sel := sel.(type) {
+		case *Field:
+			if e.include(sel.Directives) {
+				out.add(sel.ResponseKey(), sel)
+			}
+		case *InlineFragment:
+			if !e.include(sel.Directives) || (sel.TypeCond != "" && sel.TypeCond != t.Name) {
+				continue
+			}
+			e.collect(t, sel.Selections, visited, out)
+		case *FragmentSpread:
+			if !e.include(sel.Directives) || visited[sel.Name] {
+				continue
+			}
+			visited[sel.Name] = true
+			f := e.fragment(sel.Name)
+			if f == nil || f.TypeCond != t.Name {
+				continue
+			}
+			e.collect(t, f.Selections, visited, out)
+		}
+	}
+}
+
+func (e *executor) fragment(name string) *Fragment {
+	for _, f := range e.doc.Fragments {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+func (e *executor) include(ds []*Directive) bool {
+	for _, d := range ds {
+		if d.Name != "skip" && d.Name != "include" {
+			continue
+		}
+		args, err := coerceArgs(e.s.directive(d.Name).Args, d.Args, e.vars)
+		if err != nil {
+			continue
+		}
+		cond, _ := args["if"].(bool)
+		if cond == (d.Name == "skip") {
+			return false
+		}
+	}
+	return true
+}
+
+type fieldGroups struct {
+	keys   []string
+	fields map[string][]*Field
+}
+
+func (g *fieldGroups) add(key string, f *Field) {
+	if g.fields == nil {
+		g.fields = map[string][]*Field{}
+	}
+	if _, ok := g.fields[key]; !ok {
+		g.keys = append(g.keys, key)
+	}
+	g.fields[key] = append(g.fields[key], f)
+}
+
+// selectionSet resolves sels against source, an object of type t. The
+// second result is false when a non-null field failed, nulling the object.
+func (e *executor) selectionSet(ctx context.Context, t *Type, source interface{}, sels []Selection, path []interface{}) (*OrderedMap, bool) {
+	var groups fieldGroups
+	e.collect(t, sels, map[string]bool{}, &groups)
+	out := &OrderedMap{}
+	for _, key := range groups.keys {
+		fields := groups.fields[key]
+		f := fields[0]
+		fd := e.s.fieldDef(t, f.Name)
+		if fd == nil {
+			continue
+		}
+		if f.Name == "__typename" {
+			out.Set(key, t.Name)
+			continue
+		}
+		v, failed := e.field(ctx, fd, source, fields, append(path, key))
+		if failed && fd.Type.Kind == KindNonNull {
+			return nil, false
+		}
+		out.Set(key, v)
+	}
+	return out, true
+}
+
+// field resolves one field and completes its value. failed reports that
+// the value is null because of an error that has been recorded.
+func (e *executor) field(ctx context.Context, fd *FieldInfo, source interface{}, fields []*Field, path []interface{}) (v interface{}, failed bool) {
+	f := fields[0]
+	args, err := coerceArgs(fd.Args, f.Args, e.vars)
+	if err != nil {
+		e.fieldError(err, f, path)
+		return nil, true
+	}
+	resolve := fd.Resolve
+	if resolve == nil {
+		resolve = defaultResolve
+	}
+	v, err = safeResolve(ctx, resolve, ResolveParams{Source: source, Args: args, Field: f})
+	if err != nil {
+		e.fieldError(err, f, path)
+		return nil, true
+	}
+	return e.complete(ctx, fd.Type, fields, v, path)
+}
+
+func safeResolve(ctx context.Context, fn ResolveFunc, p ResolveParams) (v interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("internal error: %v", r)
+		}
+	}()
+	return fn(ctx, p)
+}
+
+// complete converts a resolved value to the output shape of t. failed
+// reports that the value is null because of a recorded error, which a
+// non-null position propagates to its parent.
+func (e *executor) complete(ctx context.Context, t *Type, fields []*Field, v interface{}, path []interface{}) (interface{}, bool) {
+	if t.Kind == KindNonNull {
+		out, failed := e.complete(ctx, t.OfType, fields, v, path)
+		if failed {
+			return nil, true
+		}
+		if out == nil {
+			e.fieldError(fmt.Errorf("Cannot return null for non-nullable field %s.", fields[0].Name), fields[0], path)
+			return nil, true
+		}
+		return out, false
+	}
+	if isNil(v) {
+		return nil, false
+	}
+	switch t.Kind {
+	case KindList:
+		rv := reflect.ValueOf(v)
+		for rv.Kind() == reflect.Ptr {
+			rv = rv.Elem()
+		}
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			e.fieldError(fmt.Errorf("Expected Iterable, but did not find one for field %s.", fields[0].Name), fields[0], path)
+			return nil, true
+		}
+		out := make([]interface{}, rv.Len())
+		for i := range out {
+			item, failed := e.complete(ctx, t.OfType, fields, rv.Index(i).Interface(), append(path, i))
+			if failed && t.OfType.Kind == KindNonNull {
+				return nil, true
+			}
+			out[i] = item
+		}
+		return out, false
+	case KindObject:
+		var sels []Selection
+		for _, f := range fields {
+			sels = append(sels, f.Selections...)
+		}
+		obj, ok := e.selectionSet(ctx, t, v, sels, path)
+		if !ok {
+			return nil, true
+		}
+		return obj, false
+	}
+	out, err := serialize(t, v)
+	if err != nil {
+		e.fieldError(err, fields[0], path)
+		return nil, true
+	}
+	return out, false
+}
+
+func isNil(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface, reflect.Func:
+		return rv.IsNil()
+	}
+	return false
+}
+
+// defaultResolve reads the field from a map or struct source.
+func defaultResolve(_ context.Context, p ResolveParams) (interface{}, error) {
+	name := p.Field.Name
+	if m, ok := p.Source.(map[string]interface{}); ok {
+		return m[name], nil
+	}
+	rv := reflect.ValueOf(p.Source)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() == reflect.String {
+			if v := rv.MapIndex(reflect.ValueOf(name).Convert(rv.Type().Key())); v.IsValid() {
+				return v.Interface(), nil
+			}
+		}
+		return nil, nil
+	case reflect.Struct:
+		if f, ok := structField(rv, name); ok {
+			return f.Interface(), nil
+		}
+	}
+	return nil, nil
+}
+
+// structField finds the field named name by json tag, falling back to a
+// case-insensitive match on the Go field name.
+func structField(rv reflect.Value, name string) (reflect.Value, bool) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		if tag, _, _ := strings.Cut(sf.Tag.Get("json"), ","); tag == name {
+			return rv.Field(i), true
+		}
+	}
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if sf.IsExported() && strings.EqualFold(sf.Name, name) {
+			return rv.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+func toError(err error) *Error {
+	var ge *Error
+	if errors.As(err, &ge) {
+		return ge
+	}
+	return &Error{Message: err.Error()}
+}
+
+// OrderedMap is a JSON object that keeps its keys in insertion order, so
+// responses follow the order of the selection set.
+type OrderedMap struct {
+	keys []string
+	vals map[string]interface{}
+}
+
+// Set adds or replaces key.
+func (m *OrderedMap) Set(key string, v interface{}) {
+	if m.vals == nil {
+		m.vals = map[string]interface{}{}
+	}
+	if _, ok := m.vals[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.vals[key] = v
+}
+
+// Get returns the value of key.
+func (m *OrderedMap) Get(key string) (interface{}, bool) {
+	v, ok := m.vals[key]
+	return v, ok
+}
+
+// Keys returns the keys in order.
+func (m *OrderedMap) Keys() []string { return m.keys }
+
+func (m *OrderedMap) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, k := range m.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		kb, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(kb)
+		b.WriteByte(':')
+		vb, err := json.Marshal(m.vals[k])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(vb)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
@@ -0,0 +1,236 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"mem0-go/internal/auth"
+	"mem0-go/internal/inmem"
+	"mem0-go/internal/memory"
+)
+
+func newTestSchema(t *testing.T) *Schema {
+	t.Helper()
+	svc := memory.NewService(inmem.NewRepo(), inmem.NewVector(), inmem.NewGraph())
+	return NewMemorySchema(svc)
+}
+
+// do runs a request and decodes the JSON response.
+func do(t *testing.T, s *Schema, query string, vars map[string]interface{}) map[string]interface{} {
+	t.Helper()
+	resp := s.Do(auth.Internal(context.Background()), Params{Query: query, Variables: vars})
+	b, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var out map[string]interface{}
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	return out
+}
+
+func errorMessages(out map[string]interface{}) []string {
+	list, _ := out["errors"].([]interface{})
+	var msgs []string
+	for _, e := range list {
+		msgs = append(msgs, e.(map[string]interface{})["message"].(string))
+	}
+	return msgs
+}
+
+func TestParseErrorHasLocation(t *testing.T) {
+	_, err := Parse("{\n  memory(id: 1 {\n}")
+	var ge *Error
+	if !errors.As(err, &ge) {
+		t.Fatalf("expected *Error, got %v", err)
+	}
+	if !strings.HasPrefix(ge.Message, "Syntax Error:") || len(ge.Locations) != 1 || ge.Locations[0].Line != 2 {
+		t.Fatalf("unexpected error %+v", ge)
+	}
+}
+
+func TestBlockStringDedent(t *testing.T) {
+	doc, err := Parse("{ search(query: \"\"\"\n    hello\n      world\n  \"\"\") { id } }")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	f := doc.Operations[0].Selections[0].(*Field)
+	if got := f.Args[0].Value.Raw; got != "hello\n  world" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestMutationVariablesFragmentsAndAliases(t *testing.T) {
+	s := newTestSchema(t)
+	out := do(t, s, `mutation Add($user: Int!, $text: String!) {
+		first: upsertMemory(userID: $user, content: $text, vector: [1, 0], tags: ["a"]) { ...M }
+		second: upsertMemory(userID: $user, content: "other", vector: [0, 1]) { id }
+	}
+	fragment M on Memory { id content tags __typename }`, map[string]interface{}{"user": 1, "text": "hello"})
+	if msgs := errorMessages(out); len(msgs) > 0 {
+		t.Fatalf("errors: %v", msgs)
+	}
+	data := out["data"].(map[string]interface{})
+	first := data["first"].(map[string]interface{})
+	if first["content"] != "hello" || first["__typename"] != "Memory" || first["id"].(float64) != 1 {
+		t.Fatalf("unexpected first %v", first)
+	}
+	if data["second"].(map[string]interface{})["id"].(float64) != 2 {
+		t.Fatalf("mutations must run in order: %v", data)
+	}
+
+	out = do(t, s, `query($v: [Float!], $skip: Boolean!) {
+		search(vector: $v, limit: 1) { id score memory { content user { id } } }
+		memories(userID: 1) @skip(if: $skip) { id }
+	}`, map[string]interface{}{"v": []interface{}{1, 0}, "skip": true})
+	if msgs := errorMessages(out); len(msgs) > 0 {
+		t.Fatalf("errors: %v", msgs)
+	}
+	data = out["data"].(map[string]interface{})
+	if _, ok := data["memories"]; ok {
+		t.Fatalf("@skip ignored: %v", data)
+	}
+	res := data["search"].([]interface{})
+	if len(res) != 1 || res[0].(map[string]interface{})["memory"].(map[string]interface{})["content"] != "hello" {
+		t.Fatalf("unexpected search %v", res)
+	}
+}
+
+func TestResponseKeepsSelectionOrder(t *testing.T) {
+	s := newTestSchema(t)
+	do(t, s, `mutation { upsertMemory(userID: 1, content: "x", vector: [1]) { id } }`, nil)
+	resp := s.Do(auth.Internal(context.Background()), Params{Query: `{ memory(id: 1) { content userID id } }`})
+	b, _ := json.Marshal(resp)
+	if want := `{"data":{"memory":{"content":"x","userID":1,"id":1}}}`; string(b) != want {
+		t.Fatalf("got %s, want %s", b, want)
+	}
+}
+
+func TestValidationErrors(t *testing.T) {
+	s := newTestSchema(t)
+	cases := map[string]string{
+		`{ unknown }`:                                `Cannot query field "unknown" on type "Query".`,
+		`{ memory(id: 1) }`:                          `must have a selection of subfields`,
+		`{ memory(id: "x") { id } }`:                 `Int cannot represent "x".`,
+		`{ memory { id } }`:                          `argument "id" of type "Int!" is required`,
+		`query($id: Int) { memory(id: $id) { id } }`: `used in position expecting type "Int!"`,
+		`{ memory(id: 1) { ...F } }`:                 `Unknown fragment "F".`,
+		`query($x: Int) { memories { id } }`:         `Variable "$x" is never used.`,
+		`{ search { id @bogus } }`:                   `Unknown directive "@bogus".`,
+	}
+	for query, want := range cases {
+		out := do(t, s, query, nil)
+		if _, ok := out["data"]; ok {
+			t.Fatalf("%s: expected no data, got %v", query, out)
+		}
+		msgs := strings.Join(errorMessages(out), "\n")
+		if !strings.Contains(msgs, want) {
+			t.Fatalf("%s: got %q, want %q", query, msgs, want)
+		}
+	}
+}
+
+func TestVariableCoercionErrors(t *testing.T) {
+	s := newTestSchema(t)
+	out := do(t, s, `query($id: Int!) { memory(id: $id) { id } }`, map[string]interface{}{"id": "one"})
+	if _, ok := out["data"]; ok || len(errorMessages(out)) != 1 {
+		t.Fatalf("unexpected response %v", out)
+	}
+}
+
+func TestFieldErrorsNullTheNearestNullableParent(t *testing.T) {
+	s := MustSchema(`
+		type Query { outer: Outer }
+		type Outer { ok: String, inner: Inner! }
+		type Inner { boom: String! }`, Resolvers{
+		"Query": {"outer": func(context.Context, ResolveParams) (interface{}, error) {
+			return map[string]interface{}{"ok": "yes", "inner": map[string]interface{}{}}, nil
+		}},
+		"Inner": {"boom": func(context.Context, ResolveParams) (interface{}, error) {
+			return nil, errors.New("kaboom")
+		}},
+	})
+	out := do(t, s, `{ outer { ok inner { boom } } }`, nil)
+	data := out["data"].(map[string]interface{})
+	if data["outer"] != nil {
+		t.Fatalf("expected outer to be null, got %v", data)
+	}
+	errs := out["errors"].([]interface{})
+	if len(errs) != 1 {
+		t.Fatalf("expected one error, got %v", errs)
+	}
+	e := errs[0].(map[string]interface{})
+	path, _ := json.Marshal(e["path"])
+	if e["message"] != "kaboom" || string(path) != `["outer","inner","boom"]` || e["locations"] == nil {
+		t.Fatalf("unexpected error %v", e)
+	}
+}
+
+func TestIntrospection(t *testing.T) {
+	s := newTestSchema(t)
+	out := do(t, s, IntrospectionQuery, nil)
+	if msgs := errorMessages(out); len(msgs) > 0 {
+		t.Fatalf("errors: %v", msgs)
+	}
+	schema := out["data"].(map[string]interface{})["__schema"].(map[string]interface{})
+	if schema["queryType"].(map[string]interface{})["name"] != "Query" {
+		t.Fatalf("unexpected queryType %v", schema["queryType"])
+	}
+	found := false
+	for _, typ := range schema["types"].([]interface{}) {
+		typ := typ.(map[string]interface{})
+		if typ["name"] == "Entity" && typ["kind"] == "OBJECT" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("Entity type missing from introspection")
+	}
+
+	out = do(t, s, `{ __type(name: "Query") { fields { name args { name defaultValue } } } }`, nil)
+	fields := out["data"].(map[string]interface{})["__type"].(map[string]interface{})["fields"].([]interface{})
+	for _, f := range fields {
+		f := f.(map[string]interface{})
+		if f["name"] != "memories" {
+			continue
+		}
+		for _, a := range f["args"].([]interface{}) {
+			a := a.(map[string]interface{})
+			if a["name"] == "limit" && a["defaultValue"] != "20" {
+				t.Fatalf("unexpected default %v", a["defaultValue"])
+			}
+		}
+		return
+	}
+	t.Fatal("memories field missing")
+}
+
+func TestEntitiesAndRelationships(t *testing.T) {
+	s := newTestSchema(t)
+	out := do(t, s, `mutation {
+		a: createEntity(label: "Person", properties: {name: "Ada"}) { id }
+		b: createEntity(label: "Person", properties: {name: "Bob"}) { id }
+	}`, nil)
+	data := out["data"].(map[string]interface{})
+	a := data["a"].(map[string]interface{})["id"]
+	b := data["b"].(map[string]interface{})["id"]
+	out = do(t, s, `mutation($a: ID!, $b: ID!) { relateEntities(from: $a, to: $b, type: "KNOWS") { id to { properties } } }`,
+		map[string]interface{}{"a": a, "b": b})
+	if msgs := errorMessages(out); len(msgs) > 0 {
+		t.Fatalf("errors: %v", msgs)
+	}
+	out = do(t, s, `query($a: ID!) { entity(id: $a) { label neighbors(type: "KNOWS") { properties } relationships { type fromID } } }`,
+		map[string]interface{}{"a": a})
+	e := out["data"].(map[string]interface{})["entity"].(map[string]interface{})
+	neighbors := e["neighbors"].([]interface{})
+	if e["label"] != "Person" || len(neighbors) != 1 || neighbors[0].(map[string]interface{})["properties"].(map[string]interface{})["name"] != "Bob" {
+		t.Fatalf("unexpected entity %v", e)
+	}
+	if rels := e["relationships"].([]interface{}); len(rels) != 1 || rels[0].(map[string]interface{})["fromID"] != a {
+		t.Fatalf("unexpected relationships %v", rels)
+	}
+}
@@ -1,66 +1,99 @@
 package graphql
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/websocket/v2"
 
+	"mem0-go/internal/auth"
 	"mem0-go/internal/memory"
 )
 
-// Request represents a minimal GraphQL request payload.
-type Request struct {
-	Query     string                 `json:"query"`
-	Variables map[string]interface{} `json:"variables"`
+// Request is a GraphQL request payload.
+type Request = Params
+
+type actorKey struct{}
+
+// withActor records who is making the request, for memory history.
+func withActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+func actorFrom(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
 }
 
 // Register sets up GraphQL routes on the given app using the service.
-func Register(app *fiber.App, svc *memory.Service) {
+// WebSocket upgrades on the same path speak graphql-ws and authenticate
+// with authn, which may be nil, in their connection_init.
+func Register(app *fiber.App, svc *memory.Service, authn *auth.Authenticator) {
+	schema := NewMemorySchema(svc)
+	ws := wsHandler(schema, authn)
 	app.Get("/graphql", func(c *fiber.Ctx) error {
-		if c.Method() != http.MethodPost {
-			return c.Type("html").SendString(playgroundHTML)
+		if websocket.IsWebSocketUpgrade(c) {
+			return ws(c)
 		}
+		return c.Next()
+	}, Handler(schema))
+	app.Post("/graphql", Handler(schema))
+}
+
+// Handler serves schema over HTTP. POST takes a JSON body; GET takes the
+// query, operationName and variables parameters and may only run queries.
+// A GET without a query serves the playground. Requests that fail before
+// execution get 400, as the GraphQL over HTTP spec recommends.
+func Handler(schema *Schema) fiber.Handler {
+	return func(c *fiber.Ctx) error {
 		var req Request
-		if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid json"})
-		}
-		q := req.Query
-		switch {
-		case strings.Contains(q, "search") && strings.Contains(q, "query"):
-			vecAny, _ := req.Variables["vector"].([]interface{})
-			vec := make([]float32, len(vecAny))
-			for i, v := range vecAny {
-				if f, ok := v.(float64); ok {
-					vec[i] = float32(f)
-				}
+		switch c.Method() {
+		case http.MethodPost:
+			if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(&Response{Errors: []*Error{{Message: "invalid json: " + err.Error()}}})
 			}
-			limitF, _ := req.Variables["limit"].(float64)
-			res, err := svc.Search(c.Context(), vec, int(limitF))
-			if err != nil {
-				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		case http.MethodGet:
+			req.Query, req.OperationName = c.Query("query"), c.Query("operationName")
+			if req.Query == "" {
+				return c.Type("html").SendString(playgroundHTML)
 			}
-			return c.JSON(fiber.Map{"data": fiber.Map{"search": res}})
-		case strings.Contains(q, "upsertMemory"):
-			userF, _ := req.Variables["userID"].(float64)
-			content, _ := req.Variables["content"].(string)
-			vecAny, _ := req.Variables["vector"].([]interface{})
-			vec := make([]float32, len(vecAny))
-			for i, v := range vecAny {
-				if f, ok := v.(float64); ok {
-					vec[i] = float32(f)
+			if v := c.Query("variables"); v != "" {
+				if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+					return c.Status(fiber.StatusBadRequest).JSON(&Response{Errors: []*Error{{Message: "variables must be a JSON object"}}})
 				}
 			}
-			id, err := svc.StoreMemory(c.Context(), int64(userF), content, vec)
-			if err != nil {
-				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
-			}
-			return c.JSON(fiber.Map{"data": fiber.Map{"upsertMemory": fiber.Map{"id": id}}})
 		default:
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "unknown operation"})
+			return c.Status(fiber.StatusMethodNotAllowed).JSON(&Response{Errors: []*Error{{Message: "GraphQL only supports GET and POST requests."}}})
+		}
+		if strings.TrimSpace(req.Query) == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(&Response{Errors: []*Error{{Message: "Must provide query string."}}})
+		}
+
+		doc, err := Parse(req.Query)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(&Response{Errors: []*Error{toError(err)}})
+		}
+		if errs := Validate(schema, doc); len(errs) > 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(&Response{Errors: errs})
+		}
+		if op, err := SelectOperation(doc, req.OperationName); err == nil {
+			if op.Type == "subscription" {
+				return c.Status(fiber.StatusBadRequest).JSON(&Response{Errors: []*Error{{Message: "Subscriptions are only supported over WebSocket."}}})
+			}
+			if c.Method() == http.MethodGet && op.Type != "query" {
+				return c.Status(fiber.StatusMethodNotAllowed).JSON(&Response{Errors: []*Error{{Message: "Can only perform a " + op.Type + " operation from a POST request."}}})
+			}
+		}
+		ctx := withActor(c.Context(), c.Get("X-Actor"))
+		resp := schema.Execute(ctx, doc, req.OperationName, req.Variables)
+		if !resp.Executed() {
+			c.Status(fiber.StatusBadRequest)
 		}
-	})
+		return c.JSON(resp)
+	}
 }
 
 const playgroundHTML = `<!DOCTYPE html>
@@ -0,0 +1,270 @@
+package graphql
+
+import (
+	"context"
+	"strings"
+)
+
+// introspectionSDL defines the introspection types from the spec.
+const introspectionSDL = `
+type __Schema {
+  description: String
+  types: [__Type!]!
+  queryType: __Type!
+  mutationType: __Type
+  subscriptionType: __Type
+  directives: [__Directive!]!
+}
+
+type __Type {
+  kind: __TypeKind!
+  name: String
+  description: String
+  specifiedByURL: String
+  fields(includeDeprecated: Boolean = false): [__Field!]
+  interfaces: [__Type!]
+  possibleTypes: [__Type!]
+  enumValues(includeDeprecated: Boolean = false): [__EnumValue!]
+  inputFields(includeDeprecated: Boolean = false): [__InputValue!]
+  ofType: __Type
+  isOneOf: Boolean
+}
+
+type __Field {
+  name: String!
+  description: String
+  args(includeDeprecated: Boolean = false): [__InputValue!]!
+  type: __Type!
+  isDeprecated: Boolean!
+  deprecationReason: String
+}
+
+type __InputValue {
+  name: String!
+  description: String
+  type: __Type!
+  defaultValue: String
+  isDeprecated: Boolean!
+  deprecationReason: String
+}
+
+type __EnumValue {
+  name: String!
+  description: String
+  isDeprecated: Boolean!
+  deprecationReason: String
+}
+
+type __Directive {
+  name: String!
+  description: String
+  locations: [__DirectiveLocation!]!
+  args(includeDeprecated: Boolean = false): [__InputValue!]!
+  isRepeatable: Boolean!
+}
+
+enum __TypeKind { SCALAR OBJECT INTERFACE UNION ENUM INPUT_OBJECT LIST NON_NULL }
+
+enum __DirectiveLocation {
+  QUERY MUTATION SUBSCRIPTION FIELD FRAGMENT_DEFINITION FRAGMENT_SPREAD
+  INLINE_FRAGMENT VARIABLE_DEFINITION SCHEMA SCALAR OBJECT FIELD_DEFINITION
+  ARGUMENT_DEFINITION INTERFACE UNION ENUM ENUM_VALUE INPUT_OBJECT
+  INPUT_FIELD_DEFINITION
+}
+`
+
+// introspectionResolvers attaches resolvers to the introspection types.
+func introspectionResolvers(s *Schema) {
+	set := func(typeName string, fields map[string]ResolveFunc) {
+		for name, fn := range fields {
+			s.types[typeName].field(name).Resolve = fn
+		}
+	}
+	str := func(v string) interface{} {
+		if v == "" {
+			return nil
+		}
+		return v
+	}
+	set("__Schema", map[string]ResolveFunc{
+		"types": func(context.Context, ResolveParams) (interface{}, error) {
+			return s.Types(), nil
+		},
+		"queryType": func(context.Context, ResolveParams) (interface{}, error) { return s.Query, nil },
+		"mutationType": func(context.Context, ResolveParams) (interface{}, error) {
+			if s.Mutation == nil {
+				return nil, nil
+			}
+			return s.Mutation, nil
+		},
+		"subscriptionType": func(context.Context, ResolveParams) (interface{}, error) {
+			if s.Subscription == nil {
+				return nil, nil
+			}
+			return s.Subscription, nil
+		},
+		"directives": func(context.Context, ResolveParams) (interface{}, error) { return s.Directives, nil },
+	})
+	set("__Type", map[string]ResolveFunc{
+		"name": func(_ context.Context, p ResolveParams) (interface{}, error) {
+			return str(p.Source.(*Type).Name), nil
+		},
+		"description": func(_ context.Context, p ResolveParams) (interface{}, error) {
+			return str(p.Source.(*Type).Description), nil
+		},
+		"fields": func(_ context.Context, p ResolveParams) (interface{}, error) {
+			t := p.Source.(*Type)
+			if t.Kind != KindObject {
+				return nil, nil
+			}
+			all, _ := p.Args["includeDeprecated"].(bool)
+			out := []*FieldInfo{}
+			for _, f := range t.Fields {
+				if all || f.DeprecationReason == nil {
+					out = append(out, f)
+				}
+			}
+			return out, nil
+		},
+		"interfaces": func(_ context.Context, p ResolveParams) (interface{}, error) {
+			if p.Source.(*Type).Kind != KindObject {
+				return nil, nil
+			}
+			return []*Type{}, nil
+		},
+		"possibleTypes": func(context.Context, ResolveParams) (interface{}, error) { return nil, nil },
+		"enumValues": func(_ context.Context, p ResolveParams) (interface{}, error) {
+			t := p.Source.(*Type)
+			if t.Kind != KindEnum {
+				return nil, nil
+			}
+			all, _ := p.Args["includeDeprecated"].(bool)
+			out := []*EnumValueInfo{}
+			for _, v := range t.EnumValues {
+				if all || v.DeprecationReason == nil {
+					out = append(out, v)
+				}
+			}
+			return out, nil
+		},
+		"inputFields": func(_ context.Context, p ResolveParams) (interface{}, error) {
+			t := p.Source.(*Type)
+			if t.Kind != KindInputObject {
+				return nil, nil
+			}
+			return t.InputFields, nil
+		},
+		"ofType": func(_ context.Context, p ResolveParams) (interface{}, error) {
+			if t := p.Source.(*Type).OfType; t != nil {
+				return t, nil
+			}
+			return nil, nil
+		},
+		"specifiedByURL": func(context.Context, ResolveParams) (interface{}, error) { return nil, nil },
+		"isOneOf": func(_ context.Context, p ResolveParams) (interface{}, error) {
+			if p.Source.(*Type).Kind != KindInputObject {
+				return nil, nil
+			}
+			return false, nil
+		},
+	})
+	deprecated := func(reason *string) (interface{}, interface{}) {
+		if reason == nil {
+			return false, nil
+		}
+		return true, *reason
+	}
+	set("__Field", map[string]ResolveFunc{
+		"description": func(_ context.Context, p ResolveParams) (interface{}, error) {
+			return str(p.Source.(*FieldInfo).Description), nil
+		},
+		"args": func(_ context.Context, p ResolveParams) (interface{}, error) {
+			return inputValues(p.Source.(*FieldInfo).Args), nil
+		},
+		"isDeprecated": func(_ context.Context, p ResolveParams) (interface{}, error) {
+			is, _ := deprecated(p.Source.(*FieldInfo).DeprecationReason)
+			return is, nil
+		},
+		"deprecationReason": func(_ context.Context, p ResolveParams) (interface{}, error) {
+			_, reason := deprecated(p.Source.(*FieldInfo).DeprecationReason)
+			return reason, nil
+		},
+	})
+	set("__InputValue", map[string]ResolveFunc{
+		"description": func(_ context.Context, p ResolveParams) (interface{}, error) {
+			return str(p.Source.(*InputValue).Description), nil
+		},
+		"defaultValue": func(_ context.Context, p ResolveParams) (interface{}, error) {
+			if d := p.Source.(*InputValue).Default; d != nil {
+				return d.String(), nil
+			}
+			return nil, nil
+		},
+		"isDeprecated":      func(context.Context, ResolveParams) (interface{}, error) { return false, nil },
+		"deprecationReason": func(context.Context, ResolveParams) (interface{}, error) { return nil, nil },
+	})
+	set("__EnumValue", map[string]ResolveFunc{
+		"description": func(_ context.Context, p ResolveParams) (interface{}, error) {
+			return str(p.Source.(*EnumValueInfo).Description), nil
+		},
+		"isDeprecated": func(_ context.Context, p ResolveParams) (interface{}, error) {
+			is, _ := deprecated(p.Source.(*EnumValueInfo).DeprecationReason)
+			return is, nil
+		},
+		"deprecationReason": func(_ context.Context, p ResolveParams) (interface{}, error) {
+			_, reason := deprecated(p.Source.(*EnumValueInfo).DeprecationReason)
+			return reason, nil
+		},
+	})
+	set("__Directive", map[string]ResolveFunc{
+		"description": func(_ context.Context, p ResolveParams) (interface{}, error) {
+			return str(p.Source.(*DirectiveInfo).Description), nil
+		},
+		"args": func(_ context.Context, p ResolveParams) (interface{}, error) {
+			return inputValues(p.Source.(*DirectiveInfo).Args), nil
+		},
+		"isRepeatable": func(context.Context, ResolveParams) (interface{}, error) { return false, nil },
+	})
+}
+
+func inputValues(vs []*InputValue) []*InputValue {
+	if vs == nil {
+		return []*InputValue{}
+	}
+	return vs
+}
+
+// IntrospectionQuery is the query GraphiQL and code generators send to
+// discover a schema.
+var IntrospectionQuery = strings.TrimSpace(`
+query IntrospectionQuery {
+  __schema {
+    queryType { name }
+    mutationType { name }
+    subscriptionType { name }
+    types { ...FullType }
+    directives { name description locations args { ...InputValue } }
+  }
+}
+fragment FullType on __Type {
+  kind name description
+  fields(includeDeprecated: true) {
+    name description
+    args { ...InputValue }
+    type { ...TypeRef }
+    isDeprecated deprecationReason
+  }
+  inputFields { ...InputValue }
+  interfaces { ...TypeRef }
+  enumValues(includeDeprecated: true) { name description isDeprecated deprecationReason }
+  possibleTypes { ...TypeRef }
+}
+fragment InputValue on __InputValue {
+  name description
+  type { ...TypeRef }
+  defaultValue
+}
+fragment TypeRef on __Type {
+  kind name
+  ofType { kind name ofType { kind name ofType { kind name ofType { kind name } } } }
+}`)
@@ -0,0 +1,325 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// tokenKind identifies a lexical token.
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokPunct
+	tokName
+	tokInt
+	tokFloat
+	tokString
+	tokBlockString
+)
+
+func (k tokenKind) String() string {
+	switch k {
+	case tokEOF:
+		return "<EOF>"
+	case tokPunct:
+		return "Punctuator"
+	case tokName:
+		return "Name"
+	case tokInt:
+		return "Int"
+	case tokFloat:
+		return "Float"
+	default:
+		return "String"
+	}
+}
+
+// token is a lexical token. Value holds the punctuator, name, number text
+// or decoded string.
+type token struct {
+	kind  tokenKind
+	value string
+	loc   Location
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "<EOF>"
+	case tokPunct:
+		return `"` + t.value + `"`
+	case tokString, tokBlockString:
+		return "String " + strconv.Quote(t.value)
+	default:
+		return t.kind.String() + ` "` + t.value + `"`
+	}
+}
+
+// lexer splits a GraphQL source into tokens, skipping whitespace, commas
+// and comments.
+type lexer struct {
+	src  string
+	pos  int
+	line int
+	col  int // byte offset of the current line start
+}
+
+func newLexer(src string) *lexer {
+	src = strings.TrimPrefix(src, "\ufeff")
+	return &lexer{src: src, line: 1}
+}
+
+func (l *lexer) loc(pos int) Location {
+	return Location{Line: l.line, Column: utf8.RuneCountInString(l.src[l.col:pos]) + 1}
+}
+
+func (l *lexer) errorf(pos int, format string, args ...interface{}) error {
+	return &Error{Message: "Syntax Error: " + fmt.Sprintf(format, args...), Locations: []Location{l.loc(pos)}}
+}
+
+func (l *lexer) newline(pos int) {
+	l.line++
+	l.col = pos
+}
+
+// next returns the next token.
+func (l *lexer) next() (token, error) {
+	if err := l.skipIgnored(); err != nil {
+		return token{}, err
+	}
+	if l.pos >= len(l.src) {
+		return token{kind: tokEOF, loc: l.loc(l.pos)}, nil
+	}
+	start := l.pos
+	c := l.src[l.pos]
+	switch {
+	case strings.IndexByte("!$&():=@[]{}|", c) >= 0:
+		l.pos++
+		return token{kind: tokPunct, value: string(c), loc: l.loc(start)}, nil
+	case c == '.':
+		if strings.HasPrefix(l.src[l.pos:], "...") {
+			l.pos += 3
+			return token{kind: tokPunct, value: "...", loc: l.loc(start)}, nil
+		}
+		return token{}, l.errorf(start, "Unexpected \".\".")
+	case c == '_' || isLetter(c):
+		for l.pos < len(l.src) && isNameChar(l.src[l.pos]) {
+			l.pos++
+		}
+		return token{kind: tokName, value: l.src[start:l.pos], loc: l.loc(start)}, nil
+	case c == '-' || isDigit(c):
+		return l.number()
+	case c == '"':
+		if strings.HasPrefix(l.src[l.pos:], `"""`) {
+			return l.blockString()
+		}
+		return l.string()
+	}
+	r, _ := utf8.DecodeRuneInString(l.src[l.pos:])
+	return token{}, l.errorf(start, "Unexpected character %q.", r)
+}
+
+func (l *lexer) skipIgnored() error {
+	for l.pos < len(l.src) {
+		switch c := l.src[l.pos]; c {
+		case ' ', '\t', ',':
+			l.pos++
+		case '\n':
+			l.pos++
+			l.newline(l.pos)
+		case '\r':
+			l.pos++
+			if l.pos < len(l.src) && l.src[l.pos] == '\n' {
+				l.pos++
+			}
+			l.newline(l.pos)
+		case '#':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' && l.src[l.pos] != '\r' {
+				l.pos++
+			}
+		default:
+			if strings.HasPrefix(l.src[l.pos:], "\ufeff") {
+				l.pos += len("\ufeff")
+				continue
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+func (l *lexer) number() (token, error) {
+	start := l.pos
+	kind := tokInt
+	if l.src[l.pos] == '-' {
+		l.pos++
+	}
+	digits := func() error {
+		if l.pos >= len(l.src) || !isDigit(l.src[l.pos]) {
+			return l.errorf(l.pos, "Invalid number, expected digit but got %s.", l.describe())
+		}
+		for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+			l.pos++
+		}
+		return nil
+	}
+	if l.pos < len(l.src) && l.src[l.pos] == '0' {
+		l.pos++
+		if l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+			return token{}, l.errorf(l.pos, "Invalid number, unexpected digit after 0: %s.", l.describe())
+		}
+	} else if err := digits(); err != nil {
+		return token{}, err
+	}
+	if l.pos < len(l.src) && l.src[l.pos] == '.' {
+		kind = tokFloat
+		l.pos++
+		if err := digits(); err != nil {
+			return token{}, err
+		}
+	}
+	if l.pos < len(l.src) && (l.src[l.pos] == 'e' || l.src[l.pos] == 'E') {
+		kind = tokFloat
+		l.pos++
+		if l.pos < len(l.src) && (l.src[l.pos] == '+' || l.src[l.pos] == '-') {
+			l.pos++
+		}
+		if err := digits(); err != nil {
+			return token{}, err
+		}
+	}
+	if l.pos < len(l.src) && (l.src[l.pos] == '.' || l.src[l.pos] == '_' || isLetter(l.src[l.pos])) {
+		return token{}, l.errorf(l.pos, "Invalid number, expected digit but got %s.", l.describe())
+	}
+	return token{kind: kind, value: l.src[start:l.pos], loc: l.loc(start)}, nil
+}
+
+func (l *lexer) describe() string {
+	if l.pos >= len(l.src) {
+		return "<EOF>"
+	}
+	r, _ := utf8.DecodeRuneInString(l.src[l.pos:])
+	return strconv.QuoteRune(r)
+}
+
+func (l *lexer) string() (token, error) {
+	start := l.pos
+	l.pos++
+	var b strings.Builder
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '"':
+			l.pos++
+			return token{kind: tokString, value: b.String(), loc: l.loc(start)}, nil
+		case c == '\n' || c == '\r':
+			return token{}, l.errorf(l.pos, "Unterminated string.")
+		case c == '\\':
+			if l.pos+1 >= len(l.src) {
+				return token{}, l.errorf(l.pos, "Unterminated string.")
+			}
+			esc := l.src[l.pos+1]
+			switch esc {
+			case '"', '\\', '/':
+				b.WriteByte(esc)
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'u':
+				if l.pos+6 > len(l.src) {
+					return token{}, l.errorf(l.pos, "Invalid Unicode escape sequence.")
+				}
+				n, err := strconv.ParseUint(l.src[l.pos+2:l.pos+6], 16, 32)
+				if err != nil {
+					return token{}, l.errorf(l.pos, "Invalid Unicode escape sequence: %q.", l.src[l.pos:l.pos+6])
+				}
+				b.WriteRune(rune(n))
+				l.pos += 4
+			default:
+				return token{}, l.errorf(l.pos, "Invalid character escape sequence: \"\\%c\".", esc)
+			}
+			l.pos += 2
+		default:
+			b.WriteByte(c)
+			l.pos++
+		}
+	}
+	return token{}, l.errorf(l.pos, "Unterminated string.")
+}
+
+func (l *lexer) blockString() (token, error) {
+	start := l.pos
+	startLine, startCol := l.line, l.col
+	l.pos += 3
+	var raw strings.Builder
+	for l.pos < len(l.src) {
+		switch {
+		case strings.HasPrefix(l.src[l.pos:], `"""`):
+			l.pos += 3
+			tok := token{kind: tokBlockString, value: blockStringValue(raw.String())}
+			tok.loc = Location{Line: startLine, Column: utf8.RuneCountInString(l.src[startCol:start]) + 1}
+			return tok, nil
+		case strings.HasPrefix(l.src[l.pos:], `\"""`):
+			raw.WriteString(`"""`)
+			l.pos += 4
+		case l.src[l.pos] == '\n':
+			raw.WriteByte('\n')
+			l.pos++
+			l.newline(l.pos)
+		case l.src[l.pos] == '\r':
+			raw.WriteByte('\n')
+			l.pos++
+			if l.pos < len(l.src) && l.src[l.pos] == '\n' {
+				l.pos++
+			}
+			l.newline(l.pos)
+		default:
+			raw.WriteByte(l.src[l.pos])
+			l.pos++
+		}
+	}
+	return token{}, l.errorf(l.pos, "Unterminated string.")
+}
+
+// blockStringValue removes the common indentation and the leading and
+// trailing blank lines of a block string, as the spec describes.
+func blockStringValue(raw string) string {
+	lines := strings.Split(raw, "\n")
+	common := -1
+	for _, line := range lines[1:] {
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent < len(line) && (common < 0 || indent < common) {
+			common = indent
+		}
+	}
+	if common > 0 {
+		for i := 1; i < len(lines); i++ {
+			if len(lines[i]) >= common {
+				lines[i] = lines[i][common:]
+			} else {
+				lines[i] = ""
+			}
+		}
+	}
+	for len(lines) > 0 && strings.TrimLeft(lines[0], " \t") == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimLeft(lines[len(lines)-1], " \t") == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+func isDigit(c byte) bool    { return c >= '0' && c <= '9' }
+func isLetter(c byte) bool   { return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+func isNameChar(c byte) bool { return c == '_' || isLetter(c) || isDigit(c) }
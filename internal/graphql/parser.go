@@ -0,0 +1,598 @@
+package graphql
+
+import "fmt"
+
+// Parse parses an executable document: operations and fragments.
+func Parse(src string) (*Document, error) {
+	p, err := newParser(src)
+	if err != nil {
+		return nil, err
+	}
+	doc := &Document{}
+	for p.tok.kind != tokEOF {
+		switch {
+		case p.peek("{"):
+			op, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.Operations = append(doc.Operations, op)
+		case p.tok.kind == tokName && p.tok.value == "fragment":
+			f, err := p.fragment()
+			if err != nil {
+				return nil, err
+			}
+			doc.Fragments = append(doc.Fragments, f)
+		case p.tok.kind == tokName && (p.tok.value == "query" || p.tok.value == "mutation" || p.tok.value == "subscription"):
+			op, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.Operations = append(doc.Operations, op)
+		default:
+			return nil, p.unexpected()
+		}
+	}
+	if len(doc.Operations) == 0 && len(doc.Fragments) == 0 {
+		return nil, p.errorf(p.tok.loc, "Unexpected %s.", p.tok)
+	}
+	return doc, nil
+}
+
+// ParseSchema parses a schema document: scalar, type, input and enum
+// definitions and an optional schema definition.
+func ParseSchema(src string) (*Document, error) {
+	p, err := newParser(src)
+	if err != nil {
+		return nil, err
+	}
+	doc := &Document{}
+	for p.tok.kind != tokEOF {
+		desc, err := p.description()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokName {
+			return nil, p.unexpected()
+		}
+		if p.tok.value == "schema" {
+			if doc.Schema, err = p.schemaDef(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		td, err := p.typeDef(desc)
+		if err != nil {
+			return nil, err
+		}
+		doc.Types = append(doc.Types, td)
+	}
+	return doc, nil
+}
+
+type parser struct {
+	lex *lexer
+	tok token
+}
+
+func newParser(src string) (*parser, error) {
+	p := &parser{lex: newLexer(src)}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *parser) advance() error {
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *parser) errorf(loc Location, format string, args ...interface{}) error {
+	return &Error{Message: "Syntax Error: " + fmt.Sprintf(format, args...), Locations: []Location{loc}}
+}
+
+func (p *parser) unexpected() error {
+	return p.errorf(p.tok.loc, "Unexpected %s.", p.tok)
+}
+
+// peek reports whether the current token is the punctuator s.
+func (p *parser) peek(s string) bool {
+	return p.tok.kind == tokPunct && p.tok.value == s
+}
+
+// skip consumes the punctuator s if it is next.
+func (p *parser) skip(s string) (bool, error) {
+	if !p.peek(s) {
+		return false, nil
+	}
+	return true, p.advance()
+}
+
+func (p *parser) expect(s string) error {
+	if !p.peek(s) {
+		return p.errorf(p.tok.loc, "Expected %q, found %s.", s, p.tok)
+	}
+	return p.advance()
+}
+
+func (p *parser) expectKeyword(kw string) error {
+	if p.tok.kind != tokName || p.tok.value != kw {
+		return p.errorf(p.tok.loc, "Expected %q, found %s.", kw, p.tok)
+	}
+	return p.advance()
+}
+
+func (p *parser) name() (string, error) {
+	if p.tok.kind != tokName {
+		return "", p.errorf(p.tok.loc, "Expected Name, found %s.", p.tok)
+	}
+	n := p.tok.value
+	return n, p.advance()
+}
+
+// many parses open item* close, requiring at least one item.
+func (p *parser) many(open, close string, item func() error) error {
+	if err := p.expect(open); err != nil {
+		return err
+	}
+	for {
+		if err := item(); err != nil {
+			return err
+		}
+		if ok, err := p.skip(close); ok || err != nil {
+			return err
+		}
+	}
+}
+
+func (p *parser) operation() (*Operation, error) {
+	op := &Operation{Type: "query", Loc: p.tok.loc}
+	if p.peek("{") {
+		sel, err := p.selectionSet()
+		op.Selections = sel
+		return op, err
+	}
+	op.Type = p.tok.value
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if p.tok.kind == tokName {
+		op.Name = p.tok.value
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+	if p.peek("(") {
+		err := p.many("(", ")", func() error {
+			v, err := p.varDef()
+			op.Vars = append(op.Vars, v)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	var err error
+	if op.Directives, err = p.directives(false); err != nil {
+		return nil, err
+	}
+	op.Selections, err = p.selectionSet()
+	return op, err
+}
+
+func (p *parser) varDef() (*VarDef, error) {
+	v := &VarDef{Loc: p.tok.loc}
+	if err := p.expect("$"); err != nil {
+		return nil, err
+	}
+	var err error
+	if v.Name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	if v.Type, err = p.typeRef(); err != nil {
+		return nil, err
+	}
+	if ok, err := p.skip("="); err != nil {
+		return nil, err
+	} else if ok {
+		if v.Default, err = p.value(true); err != nil {
+			return nil, err
+		}
+	}
+	// directives on variable definitions are accepted and ignored
+	_, err = p.directives(true)
+	return v, err
+}
+
+func (p *parser) typeRef() (*TypeRef, error) {
+	t := &TypeRef{Loc: p.tok.loc}
+	if ok, err := p.skip("["); err != nil {
+		return nil, err
+	} else if ok {
+		if t.Elem, err = p.typeRef(); err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+	} else if t.Name, err = p.name(); err != nil {
+		return nil, err
+	}
+	ok, err := p.skip("!")
+	t.NonNull = ok
+	return t, err
+}
+
+func (p *parser) selectionSet() ([]Selection, error) {
+	var out []Selection
+	err := p.many("{", "}", func() error {
+		s, err := p.selection()
+		out = append(out, s)
+		return err
+	})
+	return out, err
+}
+
+func (p *parser) selection() (Selection, error) {
+	if p.peek("...") {
+		return p.fragmentSelection()
+	}
+	f := &Field{Loc: p.tok.loc}
+	var err error
+	if f.Name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if ok, err := p.skip(":"); err != nil {
+		return nil, err
+	} else if ok {
+		f.Alias = f.Name
+		if f.Name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if f.Args, err = p.arguments(false); err != nil {
+		return nil, err
+	}
+	if f.Directives, err = p.directives(false); err != nil {
+		return nil, err
+	}
+	if p.peek("{") {
+		f.Selections, err = p.selectionSet()
+	}
+	return f, err
+}
+
+func (p *parser) fragmentSelection() (Selection, error) {
+	loc := p.tok.loc
+	if err := p.expect("..."); err != nil {
+		return nil, err
+	}
+	if p.tok.kind == tokName && p.tok.value != "on" {
+		fs := &FragmentSpread{Name: p.tok.value, Loc: loc}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		var err error
+		fs.Directives, err = p.directives(false)
+		return fs, err
+	}
+	inl := &InlineFragment{Loc: loc}
+	if p.tok.kind == tokName && p.tok.value == "on" {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		var err error
+		if inl.TypeCond, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	var err error
+	if inl.Directives, err = p.directives(false); err != nil {
+		return nil, err
+	}
+	inl.Selections, err = p.selectionSet()
+	return inl, err
+}
+
+func (p *parser) fragment() (*Fragment, error) {
+	f := &Fragment{Loc: p.tok.loc}
+	if err := p.expectKeyword("fragment"); err != nil {
+		return nil, err
+	}
+	var err error
+	if p.tok.kind == tokName && p.tok.value == "on" {
+		return nil, p.unexpected()
+	}
+	if f.Name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if err := p.expectKeyword("on"); err != nil {
+		return nil, err
+	}
+	if f.TypeCond, err = p.name(); err != nil {
+		return nil, err
+	}
+	if f.Directives, err = p.directives(false); err != nil {
+		return nil, err
+	}
+	f.Selections, err = p.selectionSet()
+	return f, err
+}
+
+func (p *parser) arguments(constant bool) ([]*Argument, error) {
+	if !p.peek("(") {
+		return nil, nil
+	}
+	var out []*Argument
+	err := p.many("(", ")", func() error {
+		a := &Argument{Loc: p.tok.loc}
+		var err error
+		if a.Name, err = p.name(); err != nil {
+			return err
+		}
+		if err := p.expect(":"); err != nil {
+			return err
+		}
+		a.Value, err = p.value(constant)
+		out = append(out, a)
+		return err
+	})
+	return out, err
+}
+
+func (p *parser) directives(constant bool) ([]*Directive, error) {
+	var out []*Directive
+	for p.peek("@") {
+		d := &Directive{Loc: p.tok.loc}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		var err error
+		if d.Name, err = p.name(); err != nil {
+			return nil, err
+		}
+		if d.Args, err = p.arguments(constant); err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+// value parses an input literal; variables are rejected when constant.
+func (p *parser) value(constant bool) (*Value, error) {
+	v := &Value{Loc: p.tok.loc, Raw: p.tok.value}
+	switch p.tok.kind {
+	case tokInt:
+		v.Kind = IntValue
+	case tokFloat:
+		v.Kind = FloatValue
+	case tokString, tokBlockString:
+		v.Kind = StringValue
+	case tokName:
+		switch p.tok.value {
+		case "true", "false":
+			v.Kind = BooleanValue
+		case "null":
+			v.Kind = NullValue
+		default:
+			v.Kind = EnumValue
+		}
+	case tokPunct:
+		switch p.tok.value {
+		case "$":
+			if constant {
+				return nil, p.unexpected()
+			}
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			v.Kind = VariableValue
+			var err error
+			v.Raw, err = p.name()
+			return v, err
+		case "[":
+			v.Kind, v.Raw = ListValue, ""
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			for !p.peek("]") {
+				e, err := p.value(constant)
+				if err != nil {
+					return nil, err
+				}
+				v.List = append(v.List, e)
+			}
+			return v, p.advance()
+		case "{":
+			v.Kind, v.Raw = ObjectValue, ""
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			for !p.peek("}") {
+				f := &ObjectField{Loc: p.tok.loc}
+				var err error
+				if f.Name, err = p.name(); err != nil {
+					return nil, err
+				}
+				if err := p.expect(":"); err != nil {
+					return nil, err
+				}
+				if f.Value, err = p.value(constant); err != nil {
+					return nil, err
+				}
+				v.Fields = append(v.Fields, f)
+			}
+			return v, p.advance()
+		default:
+			return nil, p.unexpected()
+		}
+	default:
+		return nil, p.unexpected()
+	}
+	return v, p.advance()
+}
+
+// description parses an optional description string.
+func (p *parser) description() (string, error) {
+	if p.tok.kind != tokString && p.tok.kind != tokBlockString {
+		return "", nil
+	}
+	d := p.tok.value
+	return d, p.advance()
+}
+
+func (p *parser) schemaDef() (*SchemaDef, error) {
+	if err := p.expectKeyword("schema"); err != nil {
+		return nil, err
+	}
+	if _, err := p.directives(true); err != nil {
+		return nil, err
+	}
+	s := &SchemaDef{}
+	err := p.many("{", "}", func() error {
+		loc := p.tok.loc
+		op, err := p.name()
+		if err != nil {
+			return err
+		}
+		if err := p.expect(":"); err != nil {
+			return err
+		}
+		name, err := p.name()
+		if err != nil {
+			return err
+		}
+		switch op {
+		case "query":
+			s.Query = name
+		case "mutation":
+			s.Mutation = name
+		case "subscription":
+			s.Subscription = name
+		default:
+			return p.errorf(loc, "Unexpected operation type %q.", op)
+		}
+		return nil
+	})
+	return s, err
+}
+
+func (p *parser) typeDef(desc string) (*TypeDef, error) {
+	td := &TypeDef{Kind: p.tok.value, Description: desc, Loc: p.tok.loc}
+	switch td.Kind {
+	case "scalar", "type", "input", "enum":
+	default:
+		return nil, p.errorf(p.tok.loc, "Unsupported definition %q.", td.Kind)
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	var err error
+	if td.Name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if td.Directives, err = p.directives(true); err != nil {
+		return nil, err
+	}
+	switch td.Kind {
+	case "type":
+		if p.peek("{") {
+			err = p.many("{", "}", func() error {
+				f, err := p.fieldDef()
+				td.Fields = append(td.Fields, f)
+				return err
+			})
+		}
+	case "input":
+		if p.peek("{") {
+			err = p.many("{", "}", func() error {
+				f, err := p.inputValueDef()
+				td.InputFields = append(td.InputFields, f)
+				return err
+			})
+		}
+	case "enum":
+		if p.peek("{") {
+			err = p.many("{", "}", func() error {
+				ev := &EnumValueDef{Loc: p.tok.loc}
+				var err error
+				if ev.Description, err = p.description(); err != nil {
+					return err
+				}
+				if ev.Name, err = p.name(); err != nil {
+					return err
+				}
+				ev.Directives, err = p.directives(true)
+				td.Values = append(td.Values, ev)
+				return err
+			})
+		}
+	}
+	return td, err
+}
+
+func (p *parser) fieldDef() (*FieldDef, error) {
+	f := &FieldDef{}
+	var err error
+	if f.Description, err = p.description(); err != nil {
+		return nil, err
+	}
+	f.Loc = p.tok.loc
+	if f.Name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if p.peek("(") {
+		err = p.many("(", ")", func() error {
+			a, err := p.inputValueDef()
+			f.Args = append(f.Args, a)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	if f.Type, err = p.typeRef(); err != nil {
+		return nil, err
+	}
+	f.Directives, err = p.directives(true)
+	return f, err
+}
+
+func (p *parser) inputValueDef() (*InputValueDef, error) {
+	v := &InputValueDef{}
+	var err error
+	if v.Description, err = p.description(); err != nil {
+		return nil, err
+	}
+	v.Loc = p.tok.loc
+	if v.Name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	if v.Type, err = p.typeRef(); err != nil {
+		return nil, err
+	}
+	if ok, err := p.skip("="); err != nil {
+		return nil, err
+	} else if ok {
+		if v.Default, err = p.value(true); err != nil {
+			return nil, err
+		}
+	}
+	v.Directives, err = p.directives(true)
+	return v, err
+}
@@ -0,0 +1,703 @@
+package graphql
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"mem0-go/internal/db"
+	"mem0-go/internal/events"
+	"mem0-go/internal/graph"
+	"mem0-go/internal/llm"
+	"mem0-go/internal/memory"
+	"mem0-go/internal/rerank"
+)
+
+//go:embed schema.graphqls
+var schemaSDL string
+
+// NewMemorySchema returns the schema served at /graphql, resolved against
+// svc.
+func NewMemorySchema(svc *memory.Service) *Schema {
+	r := &resolver{svc: svc}
+	return MustSchema(schemaSDL, Resolvers{
+		"Query": {
+			"memory":            r.memory,
+			"memories":          r.memories,
+			"memoryConnection":  r.memoryConnection,
+			"search":            r.search,
+			"graphSearch":       r.graphSearch,
+			"memoryHistory":     r.memoryHistory,
+			"user":              r.user,
+			"entity":            r.entity,
+			"entities":          r.entities,
+			"relationship":      r.relationship,
+			"relationships":     r.relationships,
+			"traverse":          r.traverse,
+			"shortestPath":      r.shortestPath,
+			"subgraph":          r.subgraph,
+			"duplicateEntities": r.duplicateEntities,
+			"merges":            r.merges,
+			"merge":             r.merge,
+		},
+		"Mutation": {
+			"upsertMemory":       r.upsertMemory,
+			"updateMemory":       r.updateMemory,
+			"deleteMemory":       r.deleteMemory,
+			"ingest":             r.ingest,
+			"createUser":         r.createUser,
+			"createEntity":       r.createEntity,
+			"relateEntities":     r.relateEntities,
+			"updateEntity":       r.updateEntity,
+			"deleteEntity":       r.deleteEntity,
+			"updateRelationship": r.updateRelationship,
+			"deleteRelationship": r.deleteRelationship,
+			"mergeEntities":      r.mergeEntities,
+			"undoMerge":          r.undoMerge,
+		},
+		"Subscription": {
+			"memoryAdded":         r.memoryEvents(events.MemoryAdded),
+			"memoryUpdated":       r.memoryEvents(events.MemoryUpdated),
+			"memoryDeleted":       r.memoryEvents(events.MemoryDeleted),
+			"relationshipCreated": r.relationshipCreated,
+		},
+		"Memory": {
+			"tags": func(_ context.Context, p ResolveParams) (interface{}, error) {
+				return orEmpty(p.Source.(db.Memory).Tags), nil
+			},
+			"user": func(ctx context.Context, p ResolveParams) (interface{}, error) {
+				return r.lookupUser(ctx, p.Source.(db.Memory).UserID)
+			},
+			"history": func(ctx context.Context, p ResolveParams) (interface{}, error) {
+				return r.svc.History(ctx, p.Source.(db.Memory).ID)
+			},
+			"node": func(ctx context.Context, p ResolveParams) (interface{}, error) {
+				return nodeOrNil(r.svc.MemoryNode(ctx, p.Source.(db.Memory).ID))
+			},
+		},
+		"SearchResult": {
+			"memory": func(ctx context.Context, p ResolveParams) (interface{}, error) {
+				return r.lookupMemory(ctx, p.Source.(memory.MemoryResult).ID)
+			},
+			"explanation": func(_ context.Context, p ResolveParams) (interface{}, error) {
+				if e := p.Source.(memory.MemoryResult).Explanation; e != nil {
+					return e, nil
+				}
+				return nil, nil
+			},
+		},
+		"ScoreExplanation": {
+			"fusion": func(_ context.Context, p ResolveParams) (interface{}, error) {
+				if f := p.Source.(*memory.ScoreExplanation).Fusion; f != "" {
+					return strings.ToUpper(f), nil
+				}
+				return nil, nil
+			},
+			"retrievalRank": func(_ context.Context, p ResolveParams) (interface{}, error) {
+				if e := p.Source.(*memory.ScoreExplanation); e.RetrievalRank != 0 {
+					return e.RetrievalRank, nil
+				}
+				return nil, nil
+			},
+			"retrievalScore": func(_ context.Context, p ResolveParams) (interface{}, error) {
+				if e := p.Source.(*memory.ScoreExplanation); e.RetrievalRank != 0 {
+					return e.RetrievalScore, nil
+				}
+				return nil, nil
+			},
+		},
+		"HistoryEntry": {
+			"fields": func(_ context.Context, p ResolveParams) (interface{}, error) {
+				return orEmpty(p.Source.(db.HistoryEntry).Fields), nil
+			},
+			"old": func(_ context.Context, p ResolveParams) (interface{}, error) {
+				return derefMemory(p.Source.(db.HistoryEntry).Old), nil
+			},
+			"new": func(_ context.Context, p ResolveParams) (interface{}, error) {
+				return derefMemory(p.Source.(db.HistoryEntry).New), nil
+			},
+		},
+		"User": {
+			"memories": func(ctx context.Context, p ResolveParams) (interface{}, error) {
+				return r.listMemories(ctx, memory.ListRequest{
+					UserID:  p.Source.(db.User).ID,
+					AfterID: int64(intArg(p.Args, "after", 0)),
+					Limit:   intArg(p.Args, "limit", 20),
+				})
+			},
+		},
+		"Entity": {
+			"properties": func(_ context.Context, p ResolveParams) (interface{}, error) {
+				return p.Source.(graph.Node).Props, nil
+			},
+			"neighbors": func(ctx context.Context, p ResolveParams) (interface{}, error) {
+				relType, _ := p.Args["type"].(string)
+				return r.svc.Neighbors(ctx, p.Source.(graph.Node).ID, relType)
+			},
+			"relationships": func(ctx context.Context, p ResolveParams) (interface{}, error) {
+				return r.svc.Relationships(ctx, p.Source.(graph.Node).ID)
+			},
+		},
+		"Relationship": {
+			"fromID": func(_ context.Context, p ResolveParams) (interface{}, error) {
+				return p.Source.(graph.Edge).From, nil
+			},
+			"toID": func(_ context.Context, p ResolveParams) (interface{}, error) {
+				return p.Source.(graph.Edge).To, nil
+			},
+			"from": func(ctx context.Context, p ResolveParams) (interface{}, error) {
+				return nodeOrNil(r.svc.Entity(ctx, p.Source.(graph.Edge).From))
+			},
+			"to": func(ctx context.Context, p ResolveParams) (interface{}, error) {
+				return nodeOrNil(r.svc.Entity(ctx, p.Source.(graph.Edge).To))
+			},
+			"properties": func(_ context.Context, p ResolveParams) (interface{}, error) {
+				return p.Source.(graph.Edge).Props, nil
+			},
+		},
+		"Visit": {
+			"entity": func(_ context.Context, p ResolveParams) (interface{}, error) {
+				return p.Source.(graph.Visit).Node, nil
+			},
+			"via": func(_ context.Context, p ResolveParams) (interface{}, error) {
+				return p.Source.(graph.Visit).Via, nil
+			},
+		},
+		"Path": {
+			"entities": func(_ context.Context, p ResolveParams) (interface{}, error) {
+				return p.Source.(graph.Path).Nodes, nil
+			},
+			"relationships": func(_ context.Context, p ResolveParams) (interface{}, error) {
+				return p.Source.(graph.Path).Edges, nil
+			},
+			"length": func(_ context.Context, p ResolveParams) (interface{}, error) {
+				return p.Source.(graph.Path).Len(), nil
+			},
+		},
+		"Subgraph": {
+			"entities": func(_ context.Context, p ResolveParams) (interface{}, error) {
+				return p.Source.(graph.Subgraph).Nodes, nil
+			},
+			"relationships": func(_ context.Context, p ResolveParams) (interface{}, error) {
+				return p.Source.(graph.Subgraph).Edges, nil
+			},
+		},
+		"Merge": {
+			"survivorID": func(_ context.Context, p ResolveParams) (interface{}, error) {
+				return p.Source.(memory.MergeRecord).Survivor, nil
+			},
+			"survivor": func(ctx context.Context, p ResolveParams) (interface{}, error) {
+				return nodeOrNil(r.svc.Entity(ctx, p.Source.(memory.MergeRecord).Survivor))
+			},
+			"relationships": func(_ context.Context, p ResolveParams) (interface{}, error) {
+				return p.Source.(memory.MergeRecord).Edges, nil
+			},
+			"actor": func(_ context.Context, p ResolveParams) (interface{}, error) {
+				if a := p.Source.(memory.MergeRecord).Actor; a != "" {
+					return a, nil
+				}
+				return nil, nil
+			},
+			"undoneAt": func(_ context.Context, p ResolveParams) (interface{}, error) {
+				if t := p.Source.(memory.MergeRecord).UndoneAt; t != "" {
+					return t, nil
+				}
+				return nil, nil
+			},
+		},
+		"IngestResult": {
+			"memoryID": func(_ context.Context, p ResolveParams) (interface{}, error) {
+				if id := p.Source.(memory.IngestResult).MemoryID; id != 0 {
+					return id, nil
+				}
+				return nil, nil
+			},
+			"memory": func(ctx context.Context, p ResolveParams) (interface{}, error) {
+				res := p.Source.(memory.IngestResult)
+				if res.MemoryID == 0 || res.Event == llm.EventDelete {
+					return nil, nil
+				}
+				return r.lookupMemory(ctx, res.MemoryID)
+			},
+		},
+	})
+}
+
+// resolver holds the root field resolvers.
+type resolver struct {
+	svc *memory.Service
+}
+
+func (r *resolver) memory(ctx context.Context, p ResolveParams) (interface{}, error) {
+	return r.lookupMemory(ctx, int64(intArg(p.Args, "id", 0)))
+}
+
+func (r *resolver) memories(ctx context.Context, p ResolveParams) (interface{}, error) {
+	return r.listMemories(ctx, memory.ListRequest{
+		UserID:  int64(intArg(p.Args, "userID", 0)),
+		AfterID: int64(intArg(p.Args, "after", 0)),
+		Limit:   intArg(p.Args, "limit", 20),
+	})
+}
+
+// listMemories returns the memories of one page of req.
+func (r *resolver) listMemories(ctx context.Context, req memory.ListRequest) ([]db.Memory, error) {
+	page, err := r.svc.ListMemories(ctx, req)
+	return page.Memories, err
+}
+
+// memorySorts maps MemorySort values to service sort orders.
+var memorySorts = map[string]string{"ID": db.SortByID, "CREATED_AT": db.SortByCreatedAt}
+
+func (r *resolver) memoryConnection(ctx context.Context, p ResolveParams) (interface{}, error) {
+	sort, _ := p.Args["sort"].(string)
+	direction, _ := p.Args["direction"].(string)
+	req := memory.ListRequest{
+		UserID: int64(intArg(p.Args, "userID", 0)),
+		Limit:  intArg(p.Args, "first", memory.DefaultListLimit),
+		Sort:   memorySorts[sort],
+		Desc:   direction == "DESC",
+	}
+	req.Cursor, _ = p.Args["after"].(string)
+	req.AgentID, _ = p.Args["agentID"].(string)
+	req.RunID, _ = p.Args["runID"].(string)
+	req.Tag, _ = p.Args["tag"].(string)
+	req.Contains, _ = p.Args["contains"].(string)
+	var err error
+	if req.CreatedAfter, err = timeArg(p.Args, "createdAfter"); err != nil {
+		return nil, err
+	}
+	if req.CreatedBefore, err = timeArg(p.Args, "createdBefore"); err != nil {
+		return nil, err
+	}
+	page, err := r.svc.ListMemories(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	edges := make([]map[string]interface{}, len(page.Memories))
+	var end interface{}
+	for i, m := range page.Memories {
+		c := page.Cursor(m)
+		edges[i] = map[string]interface{}{"cursor": c, "node": m}
+		end = c
+	}
+	return map[string]interface{}{
+		"edges":    edges,
+		"pageInfo": map[string]interface{}{"hasNextPage": page.HasMore, "endCursor": end},
+	}, nil
+}
+
+func (r *resolver) search(ctx context.Context, p ResolveParams) (interface{}, error) {
+	return r.svc.SearchMemories(ctx, searchRequest(p.Args))
+}
+
+func (r *resolver) graphSearch(ctx context.Context, p ResolveParams) (interface{}, error) {
+	return r.svc.GraphSearch(ctx, memory.GraphSearchRequest{
+		SearchRequest: searchRequest(p.Args),
+		Hops:          intArg(p.Args, "hops", 1),
+		RelatedLimit:  intArg(p.Args, "relatedLimit", 20),
+	})
+}
+
+// searchRequest reads the arguments shared by search and graphSearch.
+func searchRequest(args map[string]interface{}) memory.SearchRequest {
+	query, _ := args["query"].(string)
+	agent, _ := args["agentID"].(string)
+	run, _ := args["runID"].(string)
+	mode, _ := args["mode"].(string)
+	fusion, _ := args["fusion"].(string)
+	req := memory.SearchRequest{
+		Query:   query,
+		Vector:  floats(args["vector"]),
+		Limit:   intArg(args, "limit", 10),
+		UserID:  int64(intArg(args, "userID", 0)),
+		AgentID: agent,
+		RunID:   run,
+		Tags:    strs(args["tags"]),
+		Mode:    strings.ToLower(mode),
+		Fusion:  strings.ToLower(fusion),
+	}
+	if w, ok := args["vectorWeight"].(float64); ok {
+		weight := float32(w)
+		req.VectorWeight = &weight
+	}
+	req.Candidates = intArg(args, "candidates", 0)
+	list, _ := args["rerank"].([]interface{})
+	for _, item := range list {
+		in, _ := item.(map[string]interface{})
+		typ, _ := in["type"].(string)
+		spec := rerank.Spec{Type: strings.ReplaceAll(strings.ToLower(typ), "_", "-")}
+		spec.Lambda, _ = in["lambda"].(float64)
+		spec.HalfLife, _ = in["halfLife"].(string)
+		spec.RelevanceWeight, _ = in["relevanceWeight"].(float64)
+		spec.RecencyWeight, _ = in["recencyWeight"].(float64)
+		spec.ImportanceWeight, _ = in["importanceWeight"].(float64)
+		req.Rerank = append(req.Rerank, spec)
+	}
+	return req
+}
+
+func (r *resolver) memoryHistory(ctx context.Context, p ResolveParams) (interface{}, error) {
+	return r.svc.History(ctx, int64(intArg(p.Args, "id", 0)))
+}
+
+func (r *resolver) user(ctx context.Context, p ResolveParams) (interface{}, error) {
+	return r.lookupUser(ctx, int64(intArg(p.Args, "id", 0)))
+}
+
+func (r *resolver) entity(ctx context.Context, p ResolveParams) (interface{}, error) {
+	id, _ := p.Args["id"].(string)
+	return nodeOrNil(r.svc.Entity(ctx, id))
+}
+
+func (r *resolver) entities(ctx context.Context, p ResolveParams) (interface{}, error) {
+	label, _ := p.Args["label"].(string)
+	props, _ := p.Args["properties"].(map[string]interface{})
+	return r.svc.FindEntities(ctx, label, props)
+}
+
+func (r *resolver) relationship(ctx context.Context, p ResolveParams) (interface{}, error) {
+	id, _ := p.Args["id"].(string)
+	e, err := r.svc.Relationship(ctx, id)
+	if err != nil || e == nil {
+		return nil, err
+	}
+	return *e, nil
+}
+
+func (r *resolver) relationships(ctx context.Context, p ResolveParams) (interface{}, error) {
+	id, _ := p.Args["nodeID"].(string)
+	return r.svc.Relationships(ctx, id)
+}
+
+func (r *resolver) traverse(ctx context.Context, p ResolveParams) (interface{}, error) {
+	start, _ := p.Args["start"].(string)
+	strategy, _ := p.Args["strategy"].(string)
+	return r.svc.Traverse(ctx, graph.TraversalQuery{
+		Start:     start,
+		Expansion: expansion(p.Args),
+		MaxDepth:  intArg(p.Args, "maxDepth", 1),
+		Strategy:  graph.Strategy(strings.ToLower(strategy)),
+		Limit:     intArg(p.Args, "limit", 0),
+	})
+}
+
+func (r *resolver) shortestPath(ctx context.Context, p ResolveParams) (interface{}, error) {
+	from, _ := p.Args["from"].(string)
+	to, _ := p.Args["to"].(string)
+	path, err := r.svc.ShortestPath(ctx, from, to, expansion(p.Args), intArg(p.Args, "maxDepth", graph.MaxDepth))
+	if err != nil || path == nil {
+		return nil, err
+	}
+	return *path, nil
+}
+
+func (r *resolver) subgraph(ctx context.Context, p ResolveParams) (interface{}, error) {
+	center, _ := p.Args["center"].(string)
+	return r.svc.Subgraph(ctx, center, expansion(p.Args), intArg(p.Args, "depth", 1))
+}
+
+// expansion reads the arguments shared by the graph traversal fields.
+func expansion(args map[string]interface{}) graph.Expansion {
+	dir, _ := args["direction"].(string)
+	e := graph.Expansion{Direction: graph.Direction(strings.ToLower(dir)), Types: strs(args["types"])}
+	list, _ := args["where"].([]interface{})
+	for _, item := range list {
+		in, _ := item.(map[string]interface{})
+		key, _ := in["key"].(string)
+		op, _ := in["op"].(string)
+		e.Where = append(e.Where, graph.Predicate{Key: key, Op: strings.ToLower(op), Value: in["value"]})
+	}
+	return e
+}
+
+func (r *resolver) upsertMemory(ctx context.Context, p ResolveParams) (interface{}, error) {
+	content, _ := p.Args["content"].(string)
+	agent, _ := p.Args["agentID"].(string)
+	run, _ := p.Args["runID"].(string)
+	metadata, _ := p.Args["metadata"].(map[string]interface{})
+	id, err := r.svc.Store(ctx, memory.StoreRequest{
+		UserID:   int64(intArg(p.Args, "userID", 0)),
+		AgentID:  agent,
+		RunID:    run,
+		Content:  content,
+		Tags:     strs(p.Args["tags"]),
+		Metadata: metadata,
+		Vector:   floats(p.Args["vector"]),
+		Actor:    actorFrom(ctx),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.svc.GetMemory(ctx, id)
+}
+
+func (r *resolver) updateMemory(ctx context.Context, p ResolveParams) (interface{}, error) {
+	req := memory.UpdateRequest{Vector: floats(p.Args["vector"]), Actor: actorFrom(ctx)}
+	if content, ok := p.Args["content"].(string); ok {
+		req.Content = &content
+	}
+	if tags, ok := p.Args["tags"]; ok {
+		req.Tags = orEmpty(strs(tags))
+	}
+	req.Metadata, _ = p.Args["metadata"].(map[string]interface{})
+	return r.svc.Update(ctx, int64(intArg(p.Args, "id", 0)), req)
+}
+
+func (r *resolver) deleteMemory(ctx context.Context, p ResolveParams) (interface{}, error) {
+	id := int64(intArg(p.Args, "id", 0))
+	if err := r.svc.Delete(ctx, id, actorFrom(ctx)); err != nil {
+		return nil, err
+	}
+	return id, nil
+}
+
+func (r *resolver) ingest(ctx context.Context, p ResolveParams) (interface{}, error) {
+	list, _ := p.Args["messages"].([]interface{})
+	msgs := make([]llm.Message, 0, len(list))
+	for _, item := range list {
+		m, _ := item.(map[string]interface{})
+		role, _ := m["role"].(string)
+		content, _ := m["content"].(string)
+		msgs = append(msgs, llm.Message{Role: role, Content: content})
+	}
+	return r.svc.Ingest(ctx, int64(intArg(p.Args, "userID", 0)), msgs)
+}
+
+func (r *resolver) createUser(ctx context.Context, p ResolveParams) (interface{}, error) {
+	name, _ := p.Args["username"].(string)
+	id, err := r.svc.CreateUser(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return r.svc.GetUser(ctx, id)
+}
+
+func (r *resolver) createEntity(ctx context.Context, p ResolveParams) (interface{}, error) {
+	label, _ := p.Args["label"].(string)
+	props, _ := p.Args["properties"].(map[string]interface{})
+	id, err := r.svc.CreateEntity(ctx, label, props)
+	if err != nil {
+		return nil, err
+	}
+	return graph.Node{ID: id, Label: label, Props: props}, nil
+}
+
+func (r *resolver) relateEntities(ctx context.Context, p ResolveParams) (interface{}, error) {
+	from, _ := p.Args["from"].(string)
+	to, _ := p.Args["to"].(string)
+	relType, _ := p.Args["type"].(string)
+	props, _ := p.Args["properties"].(map[string]interface{})
+	id, err := r.svc.RelateEntities(ctx, from, to, relType, props)
+	if err != nil {
+		return nil, err
+	}
+	return graph.Edge{ID: id, From: from, To: to, Type: relType, Props: props}, nil
+}
+
+func (r *resolver) updateEntity(ctx context.Context, p ResolveParams) (interface{}, error) {
+	id, _ := p.Args["id"].(string)
+	props, _ := p.Args["properties"].(map[string]interface{})
+	return r.svc.UpdateEntity(ctx, id, props)
+}
+
+func (r *resolver) deleteEntity(ctx context.Context, p ResolveParams) (interface{}, error) {
+	id, _ := p.Args["id"].(string)
+	cascade, _ := p.Args["cascade"].(bool)
+	if err := r.svc.DeleteEntity(ctx, id, cascade); err != nil {
+		return nil, err
+	}
+	return id, nil
+}
+
+func (r *resolver) updateRelationship(ctx context.Context, p ResolveParams) (interface{}, error) {
+	id, _ := p.Args["id"].(string)
+	props, _ := p.Args["properties"].(map[string]interface{})
+	return r.svc.UpdateRelationship(ctx, id, props)
+}
+
+func (r *resolver) deleteRelationship(ctx context.Context, p ResolveParams) (interface{}, error) {
+	id, _ := p.Args["id"].(string)
+	if err := r.svc.DeleteRelationship(ctx, id); err != nil {
+		return nil, err
+	}
+	return id, nil
+}
+
+func (r *resolver) duplicateEntities(ctx context.Context, p ResolveParams) (interface{}, error) {
+	opts := memory.DuplicateOptions{Limit: intArg(p.Args, "limit", 0)}
+	opts.Label, _ = p.Args["label"].(string)
+	opts.MinScore, _ = p.Args["minScore"].(float64)
+	return r.svc.FindDuplicates(ctx, opts)
+}
+
+func (r *resolver) merges(ctx context.Context, _ ResolveParams) (interface{}, error) {
+	return r.svc.Merges(ctx)
+}
+
+func (r *resolver) merge(ctx context.Context, p ResolveParams) (interface{}, error) {
+	id, _ := p.Args["id"].(string)
+	rec, err := r.svc.Merge(ctx, id)
+	if errors.Is(err, memory.ErrMergeNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+func (r *resolver) mergeEntities(ctx context.Context, p ResolveParams) (interface{}, error) {
+	survivor, _ := p.Args["survivor"].(string)
+	return r.svc.MergeEntities(ctx, survivor, strs(p.Args["merged"]))
+}
+
+func (r *resolver) undoMerge(ctx context.Context, p ResolveParams) (interface{}, error) {
+	id, _ := p.Args["id"].(string)
+	return r.svc.UndoMerge(ctx, id)
+}
+
+// memoryEvents returns a subscription resolver streaming memories from
+// events of kind, filtered by the userID and agentID arguments.
+func (r *resolver) memoryEvents(kind events.Kind) ResolveFunc {
+	return func(ctx context.Context, p ResolveParams) (interface{}, error) {
+		user := int64(intArg(p.Args, "userID", 0))
+		agent, _ := p.Args["agentID"].(string)
+		return r.stream(ctx, func(e events.Event) bool {
+			return e.Kind == kind &&
+				(user == 0 || e.Memory.UserID == user) &&
+				(agent == "" || e.Memory.AgentID == agent)
+		}, func(e events.Event) interface{} { return e.Memory })
+	}
+}
+
+func (r *resolver) relationshipCreated(ctx context.Context, p ResolveParams) (interface{}, error) {
+	relType, _ := p.Args["type"].(string)
+	node, _ := p.Args["nodeID"].(string)
+	return r.stream(ctx, func(e events.Event) bool {
+		rel := e.Relationship
+		return e.Kind == events.RelationshipCreated &&
+			(relType == "" || rel.Type == relType) &&
+			(node == "" || rel.From == node || rel.To == node)
+	}, func(e events.Event) interface{} { return e.Relationship })
+}
+
+// stream subscribes to the service's events and forwards the matching ones
+// the caller may see, converted by value, until ctx is done.
+func (r *resolver) stream(ctx context.Context, filter func(events.Event) bool, value func(events.Event) interface{}) (interface{}, error) {
+	sub := r.svc.SubscribeEvents(ctx, filter)
+	if sub == nil {
+		return nil, errors.New("subscriptions are not enabled")
+	}
+	out := make(chan interface{})
+	go func() {
+		defer close(out)
+		defer sub.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case e := <-sub.C:
+				select {
+				case out <- value(e):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return (<-chan interface{})(out), nil
+}
+
+// lookupMemory returns the memory, or nil when it does not exist.
+func (r *resolver) lookupMemory(ctx context.Context, id int64) (interface{}, error) {
+	m, err := r.svc.GetMemory(ctx, id)
+	if errors.Is(err, db.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// lookupUser returns the user, or nil when it does not exist.
+func (r *resolver) lookupUser(ctx context.Context, id int64) (interface{}, error) {
+	u, err := r.svc.GetUser(ctx, id)
+	if errors.Is(err, db.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func nodeOrNil(n *graph.Node, err error) (interface{}, error) {
+	if err != nil || n == nil {
+		return nil, err
+	}
+	return *n, nil
+}
+
+func derefMemory(m *db.Memory) interface{} {
+	if m == nil {
+		return nil
+	}
+	return *m
+}
+
+func intArg(args map[string]interface{}, name string, def int) int {
+	if v, ok := args[name].(int); ok {
+		return v
+	}
+	return def
+}
+
+// timeArg parses an optional RFC 3339 argument.
+func timeArg(args map[string]interface{}, name string) (time.Time, error) {
+	v, ok := args[name].(string)
+	if !ok {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s: expected an RFC 3339 timestamp", name)
+	}
+	return t, nil
+}
+
+func floats(v interface{}) []float32 {
+	list, _ := v.([]interface{})
+	if list == nil {
+		return nil
+	}
+	out := make([]float32, len(list))
+	for i, f := range list {
+		n, _ := f.(float64)
+		out[i] = float32(n)
+	}
+	return out
+}
+
+func strs(v interface{}) []string {
+	list, _ := v.([]interface{})
+	if list == nil {
+		return nil
+	}
+	out := make([]string, len(list))
+	for i, s := range list {
+		out[i], _ = s.(string)
+	}
+	return out
+}
+
+func orEmpty(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
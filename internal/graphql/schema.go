@@ -0,0 +1,391 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// Type kinds, as reported by introspection.
+const (
+	KindScalar      = "SCALAR"
+	KindObject      = "OBJECT"
+	KindEnum        = "ENUM"
+	KindInputObject = "INPUT_OBJECT"
+	KindList        = "LIST"
+	KindNonNull     = "NON_NULL"
+)
+
+// Type is a named or wrapping (list, non-null) schema type.
+type Type struct {
+	Kind        string
+	Name        string
+	Description string
+	Fields      []*FieldInfo
+	InputFields []*InputValue
+	EnumValues  []*EnumValueInfo
+	OfType      *Type
+
+	fields map[string]*FieldInfo
+}
+
+// String prints the type as it is referenced, e.g. [String!]!.
+func (t *Type) String() string {
+	switch t.Kind {
+	case KindList:
+		return "[" + t.OfType.String() + "]"
+	case KindNonNull:
+		return t.OfType.String() + "!"
+	}
+	return t.Name
+}
+
+// named unwraps lists and non-nulls.
+func (t *Type) named() *Type {
+	for t.OfType != nil {
+		t = t.OfType
+	}
+	return t
+}
+
+func (t *Type) isLeaf() bool {
+	n := t.named()
+	return n.Kind == KindScalar || n.Kind == KindEnum
+}
+
+func (t *Type) isInput() bool {
+	k := t.named().Kind
+	return k == KindScalar || k == KindEnum || k == KindInputObject
+}
+
+func (t *Type) field(name string) *FieldInfo { return t.fields[name] }
+
+func (t *Type) enumValue(name string) *EnumValueInfo {
+	for _, v := range t.EnumValues {
+		if v.Name == name {
+			return v
+		}
+	}
+	return nil
+}
+
+// FieldInfo is a field of an object type.
+type FieldInfo struct {
+	Name              string
+	Description       string
+	Args              []*InputValue
+	Type              *Type
+	DeprecationReason *string
+	Resolve           ResolveFunc
+}
+
+// InputValue is an argument or input object field.
+type InputValue struct {
+	Name        string
+	Description string
+	Type        *Type
+	Default     *Value
+}
+
+// EnumValueInfo is a value of an enum type.
+type EnumValueInfo struct {
+	Name              string
+	Description       string
+	DeprecationReason *string
+}
+
+// DirectiveInfo describes a directive the schema supports.
+type DirectiveInfo struct {
+	Name        string
+	Description string
+	Locations   []string
+	Args        []*InputValue
+}
+
+// ResolveParams is passed to a ResolveFunc: the parent value, the coerced
+// arguments and the field being resolved.
+type ResolveParams struct {
+	Source interface{}
+	Args   map[string]interface{}
+	Field  *Field
+}
+
+// ResolveFunc produces the value of a field. Fields without one read the
+// parent value by name: a map key, or a struct field by json tag or name.
+// Root fields of the subscription type return a <-chan interface{} instead;
+// each value received is completed as the field's value for one event, and
+// the channel must be closed once ctx is done.
+type ResolveFunc func(ctx context.Context, p ResolveParams) (interface{}, error)
+
+// Resolvers maps type names to field names to resolvers.
+type Resolvers map[string]map[string]ResolveFunc
+
+// Schema is an executable schema built from SDL.
+type Schema struct {
+	Query        *Type
+	Mutation     *Type
+	Subscription *Type
+	Directives   []*DirectiveInfo
+
+	types map[string]*Type
+
+	typenameField *FieldInfo
+	schemaField   *FieldInfo
+	typeField     *FieldInfo
+}
+
+// Type returns the named type, or nil.
+func (s *Schema) Type(name string) *Type { return s.types[name] }
+
+// Types returns every named type sorted by name.
+func (s *Schema) Types() []*Type {
+	out := make([]*Type, 0, len(s.types))
+	for _, t := range s.types {
+		out = append(out, t)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+func (s *Schema) directive(name string) *DirectiveInfo {
+	for _, d := range s.Directives {
+		if d.Name == name {
+			return d
+		}
+	}
+	return nil
+}
+
+// fieldDef looks up a field on t, including the __typename meta field and
+// the __schema and __type introspection fields of the query root.
+func (s *Schema) fieldDef(t *Type, name string) *FieldInfo {
+	switch {
+	case name == "__typename":
+		return s.typenameField
+	case name == "__schema" && t == s.Query:
+		return s.schemaField
+	case name == "__type" && t == s.Query:
+		return s.typeField
+	}
+	return t.field(name)
+}
+
+// NewSchema builds a schema from SDL and attaches resolvers. The root
+// types are named by a schema definition or default to Query, Mutation and
+// Subscription. The built-in scalars and the introspection types are always
+// available; custom scalars such as JSON accept and return any value.
+func NewSchema(sdl string, resolvers Resolvers) (*Schema, error) {
+	doc, err := ParseSchema(sdl)
+	if err != nil {
+		return nil, err
+	}
+	intro, err := ParseSchema(introspectionSDL)
+	if err != nil {
+		return nil, err
+	}
+	s := &Schema{types: map[string]*Type{}}
+	for _, name := range []string{"Int", "Float", "String", "Boolean", "ID"} {
+		s.types[name] = &Type{Kind: KindScalar, Name: name, Description: scalarDescriptions[name]}
+	}
+	defs := append(intro.Types, doc.Types...)
+	for _, td := range defs {
+		if _, dup := s.types[td.Name]; dup {
+			return nil, fmt.Errorf("graphql: type %s defined more than once", td.Name)
+		}
+		s.types[td.Name] = &Type{Kind: kindOf(td.Kind), Name: td.Name, Description: td.Description}
+	}
+	for _, td := range defs {
+		if err := s.define(s.types[td.Name], td); err != nil {
+			return nil, err
+		}
+	}
+
+	roots := SchemaDef{Query: "Query", Mutation: "Mutation", Subscription: "Subscription"}
+	if doc.Schema != nil {
+		roots = *doc.Schema
+	}
+	s.Query = s.types[roots.Query]
+	if s.Query == nil || s.Query.Kind != KindObject {
+		return nil, fmt.Errorf("graphql: query root type %q is not defined", roots.Query)
+	}
+	s.Mutation = s.root(roots.Mutation)
+	s.Subscription = s.root(roots.Subscription)
+
+	for typeName, fields := range resolvers {
+		t := s.types[typeName]
+		if t == nil || t.Kind != KindObject {
+			return nil, fmt.Errorf("graphql: resolvers given for unknown type %s", typeName)
+		}
+		for name, fn := range fields {
+			f := t.field(name)
+			if f == nil {
+				return nil, fmt.Errorf("graphql: resolver given for unknown field %s.%s", typeName, name)
+			}
+			f.Resolve = fn
+		}
+	}
+
+	s.Directives = builtinDirectives(s)
+	s.typenameField = &FieldInfo{Name: "__typename", Type: nonNull(s.types["String"])}
+	s.schemaField = &FieldInfo{Name: "__schema", Type: nonNull(s.types["__Schema"]),
+		Resolve: func(context.Context, ResolveParams) (interface{}, error) { return s, nil }}
+	s.typeField = &FieldInfo{
+		Name: "__type",
+		Type: s.types["__Type"],
+		Args: []*InputValue{{Name: "name", Type: nonNull(s.types["String"])}},
+		Resolve: func(_ context.Context, p ResolveParams) (interface{}, error) {
+			name, _ := p.Args["name"].(string)
+			if t := s.types[name]; t != nil {
+				return t, nil
+			}
+			return nil, nil
+		},
+	}
+	introspectionResolvers(s)
+	return s, nil
+}
+
+// MustSchema is like NewSchema but panics on error. It is meant for
+// schemas compiled into the binary.
+func MustSchema(sdl string, resolvers Resolvers) *Schema {
+	s, err := NewSchema(sdl, resolvers)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func (s *Schema) root(name string) *Type {
+	if t := s.types[name]; t != nil && t.Kind == KindObject {
+		return t
+	}
+	return nil
+}
+
+func kindOf(def string) string {
+	switch def {
+	case "scalar":
+		return KindScalar
+	case "enum":
+		return KindEnum
+	case "input":
+		return KindInputObject
+	}
+	return KindObject
+}
+
+func (s *Schema) define(t *Type, td *TypeDef) error {
+	switch t.Kind {
+	case KindObject:
+		t.fields = map[string]*FieldInfo{}
+		for _, fd := range td.Fields {
+			ft, err := s.ref(fd.Type)
+			if err != nil {
+				return err
+			}
+			if ft.isInput() && ft.named().Kind == KindInputObject {
+				return fmt.Errorf("graphql: field %s.%s has input type %s", t.Name, fd.Name, ft)
+			}
+			args, err := s.inputValues(fd.Args)
+			if err != nil {
+				return err
+			}
+			f := &FieldInfo{Name: fd.Name, Description: fd.Description, Type: ft, Args: args,
+				DeprecationReason: deprecation(fd.Directives)}
+			if t.fields[f.Name] != nil {
+				return fmt.Errorf("graphql: field %s.%s defined more than once", t.Name, f.Name)
+			}
+			t.fields[f.Name] = f
+			t.Fields = append(t.Fields, f)
+		}
+		if len(t.Fields) == 0 {
+			return fmt.Errorf("graphql: type %s must define fields", t.Name)
+		}
+	case KindInputObject:
+		fields, err := s.inputValues(td.InputFields)
+		if err != nil {
+			return err
+		}
+		t.InputFields = fields
+	case KindEnum:
+		for _, v := range td.Values {
+			t.EnumValues = append(t.EnumValues, &EnumValueInfo{Name: v.Name, Description: v.Description,
+				DeprecationReason: deprecation(v.Directives)})
+		}
+	}
+	return nil
+}
+
+func (s *Schema) inputValues(defs []*InputValueDef) ([]*InputValue, error) {
+	var out []*InputValue
+	for _, d := range defs {
+		t, err := s.ref(d.Type)
+		if err != nil {
+			return nil, err
+		}
+		if !t.isInput() {
+			return nil, fmt.Errorf("graphql: %s must have an input type, found %s", d.Name, t)
+		}
+		out = append(out, &InputValue{Name: d.Name, Description: d.Description, Type: t, Default: d.Default})
+	}
+	return out, nil
+}
+
+// ref resolves a type reference against the schema's named types.
+func (s *Schema) ref(r *TypeRef) (*Type, error) {
+	var t *Type
+	if r.Elem != nil {
+		elem, err := s.ref(r.Elem)
+		if err != nil {
+			return nil, err
+		}
+		t = &Type{Kind: KindList, OfType: elem}
+	} else if t = s.types[r.Name]; t == nil {
+		return nil, fmt.Errorf("graphql: unknown type %s", r.Name)
+	}
+	if r.NonNull {
+		t = nonNull(t)
+	}
+	return t, nil
+}
+
+func nonNull(t *Type) *Type { return &Type{Kind: KindNonNull, OfType: t} }
+
+// deprecation returns the @deprecated reason, or nil when not deprecated.
+func deprecation(ds []*Directive) *string {
+	d := directive(ds, "deprecated")
+	if d == nil {
+		return nil
+	}
+	reason := "No longer supported"
+	if a := argument(d.Args, "reason"); a != nil && a.Value.Kind == StringValue {
+		reason = a.Value.Raw
+	}
+	return &reason
+}
+
+func builtinDirectives(s *Schema) []*DirectiveInfo {
+	boolean, str := s.types["Boolean"], s.types["String"]
+	cond := func(desc string) []*InputValue {
+		return []*InputValue{{Name: "if", Description: desc, Type: nonNull(boolean)}}
+	}
+	selections := []string{"FIELD", "FRAGMENT_SPREAD", "INLINE_FRAGMENT"}
+	return []*DirectiveInfo{
+		{Name: "include", Description: "Directs the executor to include this field or fragment only when the `if` argument is true.",
+			Locations: selections, Args: cond("Included when true.")},
+		{Name: "skip", Description: "Directs the executor to skip this field or fragment when the `if` argument is true.",
+			Locations: selections, Args: cond("Skipped when true.")},
+		{Name: "deprecated", Description: "Marks an element of a GraphQL schema as no longer supported.",
+			Locations: []string{"FIELD_DEFINITION", "ARGUMENT_DEFINITION", "INPUT_FIELD_DEFINITION", "ENUM_VALUE"},
+			Args: []*InputValue{{Name: "reason", Type: str,
+				Default: &Value{Kind: StringValue, Raw: "No longer supported"}}}},
+	}
+}
+
+var scalarDescriptions = map[string]string{
+	"Int":     "The `Int` scalar type represents non-fractional signed whole numeric values.",
+	"Float":   "The `Float` scalar type represents signed double-precision fractional values.",
+	"String":  "The `String` scalar type represents textual data, represented as UTF-8 character sequences.",
+	"Boolean": "The `Boolean` scalar type represents `true` or `false`.",
+	"ID":      "The `ID` scalar type represents a unique identifier, serialized as a string.",
+}
@@ -0,0 +1,435 @@
+package graphql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Validate checks doc against the schema: operations and fragments are
+// well formed, every selected field and argument exists, leaf and object
+// fields are selected correctly, literals match their types, and variables
+// are defined, used and used at compatible positions.
+func Validate(s *Schema, doc *Document) []*Error {
+	v := &validator{s: s, frags: map[string]*Fragment{}, seen: map[string]bool{}}
+	v.document(doc)
+	return v.errs
+}
+
+type validator struct {
+	s     *Schema
+	frags map[string]*Fragment
+	errs  []*Error
+	seen  map[string]bool
+
+	// per operation
+	vars    map[string]*VarDef
+	usages  []varUsage
+	visited map[string]bool
+	spreads map[string]bool
+}
+
+// varUsage is a variable referenced where a value of typ is expected. typ
+// is nil inside custom scalar literals, which accept any value.
+type varUsage struct {
+	name       string
+	typ        *Type
+	hasDefault bool
+	loc        Location
+}
+
+func (v *validator) errorf(loc Location, format string, args ...interface{}) {
+	e := newError(loc, format, args...)
+	key := e.Error()
+	if v.seen[key] {
+		return
+	}
+	v.seen[key] = true
+	v.errs = append(v.errs, e)
+}
+
+func (v *validator) document(doc *Document) {
+	for _, f := range doc.Fragments {
+		if v.frags[f.Name] != nil {
+			v.errorf(f.Loc, "There can be only one fragment named %q.", f.Name)
+			continue
+		}
+		v.frags[f.Name] = f
+	}
+	names := map[string]bool{}
+	for _, op := range doc.Operations {
+		if op.Name == "" && len(doc.Operations) > 1 {
+			v.errorf(op.Loc, "This anonymous operation must be the only defined operation.")
+		}
+		if op.Name != "" {
+			if names[op.Name] {
+				v.errorf(op.Loc, "There can be only one operation named %q.", op.Name)
+			}
+			names[op.Name] = true
+		}
+	}
+	for _, f := range doc.Fragments {
+		t := v.s.Type(f.TypeCond)
+		if t == nil {
+			v.errorf(f.Loc, "Unknown type %q.", f.TypeCond)
+		} else if t.Kind != KindObject {
+			v.errorf(f.Loc, "Fragment %q cannot condition on non composite type %q.", f.Name, f.TypeCond)
+		}
+		v.cycles(f, nil, map[string]bool{})
+	}
+	used := map[string]bool{}
+	for _, op := range doc.Operations {
+		v.operation(op)
+		for name := range v.spreads {
+			used[name] = true
+		}
+	}
+	for _, f := range doc.Fragments {
+		if !used[f.Name] {
+			v.errorf(f.Loc, "Fragment %q is never used.", f.Name)
+		}
+	}
+}
+
+// cycles reports fragments that spread themselves, directly or not.
+func (v *validator) cycles(f *Fragment, path []string, onPath map[string]bool) {
+	if onPath[f.Name] {
+		if path[0] == f.Name {
+			v.errorf(v.frags[path[0]].Loc, "Cannot spread fragment %q within itself.", f.Name)
+		}
+		return
+	}
+	onPath[f.Name] = true
+	defer delete(onPath, f.Name)
+	walkSpreads(f.Selections, func(fs *FragmentSpread) {
+		if next := v.frags[fs.Name]; next != nil {
+			v.cycles(next, append(path, f.Name), onPath)
+		}
+	})
+}
+
+func walkSpreads(sels []Selection, fn func(*FragmentSpread)) {
+	for _, sel := range sels {
+		switch sel := sel.(type) {
+		case *Field:
+			walkSpreads(sel.Selections, fn)
+		case *InlineFragment:
+			walkSpreads(sel.Selections, fn)
+		case *FragmentSpread:
+			fn(sel)
+		}
+	}
+}
+
+func (v *validator) operation(op *Operation) {
+	v.vars, v.usages = map[string]*VarDef{}, nil
+	v.visited, v.spreads = map[string]bool{}, map[string]bool{}
+	for _, d := range op.Vars {
+		if v.vars[d.Name] != nil {
+			v.errorf(d.Loc, "There can be only one variable named \"$%s\".", d.Name)
+			continue
+		}
+		v.vars[d.Name] = d
+		t, err := v.s.ref(d.Type)
+		if err != nil {
+			v.errorf(d.Type.Loc, "Unknown type %q.", d.Type.Name)
+			continue
+		}
+		if !t.isInput() {
+			v.errorf(d.Loc, "Variable \"$%s\" cannot be non-input type %q.", d.Name, t)
+			continue
+		}
+		if d.Default != nil {
+			v.value(t, d.Default, true)
+		}
+	}
+
+	var root *Type
+	switch op.Type {
+	case "query":
+		root = v.s.Query
+	case "mutation":
+		root = v.s.Mutation
+	case "subscription":
+		root = v.s.Subscription
+	}
+	if root == nil {
+		v.errorf(op.Loc, "Schema is not configured to execute %s operation.", op.Type)
+		return
+	}
+	v.directives(op.Directives, strings.ToUpper(op.Type))
+	if op.Type == "subscription" {
+		fields := 0
+		for _, sel := range op.Selections {
+			if _, ok := sel.(*Field); ok {
+				fields++
+			} else {
+				fields += 2
+			}
+		}
+		if fields != 1 {
+			v.errorf(op.Loc, "Subscription %q must select only one top level field.", op.Name)
+		}
+	}
+	v.selections(root, op.Selections)
+
+	used := map[string]bool{}
+	for _, u := range v.usages {
+		used[u.name] = true
+		d := v.vars[u.name]
+		if d == nil {
+			if op.Name != "" {
+				v.errorf(u.loc, "Variable \"$%s\" is not defined by operation %q.", u.name, op.Name)
+			} else {
+				v.errorf(u.loc, "Variable \"$%s\" is not defined.", u.name)
+			}
+			continue
+		}
+		vt, err := v.s.ref(d.Type)
+		if err != nil || !vt.isInput() || u.typ == nil {
+			continue
+		}
+		if !allowedVariable(vt, d.Default != nil && d.Default.Kind != NullValue, u) {
+			v.errorf(u.loc, "Variable \"$%s\" of type %q used in position expecting type %q.", u.name, vt, u.typ)
+		}
+	}
+	for _, d := range op.Vars {
+		if !used[d.Name] {
+			if op.Name != "" {
+				v.errorf(d.Loc, "Variable \"$%s\" is never used in operation %q.", d.Name, op.Name)
+			} else {
+				v.errorf(d.Loc, "Variable \"$%s\" is never used.", d.Name)
+			}
+		}
+	}
+}
+
+func allowedVariable(vt *Type, varDefault bool, u varUsage) bool {
+	loc := u.typ
+	if loc.Kind == KindNonNull && vt.Kind != KindNonNull {
+		if !varDefault && !u.hasDefault {
+			return false
+		}
+		loc = loc.OfType
+	}
+	return subtype(vt, loc)
+}
+
+// subtype reports whether a value of type a may be used where b is expected.
+func subtype(a, b *Type) bool {
+	switch {
+	case b.Kind == KindNonNull:
+		return a.Kind == KindNonNull && subtype(a.OfType, b.OfType)
+	case a.Kind == KindNonNull:
+		return subtype(a.OfType, b)
+	case b.Kind == KindList:
+		return a.Kind == KindList && subtype(a.OfType, b.OfType)
+	case a.Kind == KindList:
+		return false
+	}
+	return a == b
+}
+
+func (v *validator) selections(t *Type, sels []Selection) {
+	for _, sel := range sels {
+		switch sel := sel.(type) {
+		case *Field:
+			v.field(t, sel)
+		case *InlineFragment:
+			v.directives(sel.Directives, "INLINE_FRAGMENT")
+			inner := t
+			if sel.TypeCond != "" {
+				inner = v.s.Type(sel.TypeCond)
+				if inner == nil {
+					v.errorf(sel.Loc, "Unknown type %q.", sel.TypeCond)
+					continue
+				}
+				if inner.Kind != KindObject {
+					v.errorf(sel.Loc, "Fragment cannot condition on non composite type %q.", sel.TypeCond)
+					continue
+				}
+				if inner != t {
+					v.errorf(sel.Loc, "Fragment cannot be spread here as objects of type %q can never be of type %q.", t.Name, inner.Name)
+					continue
+				}
+			}
+			v.selections(inner, sel.Selections)
+		case *FragmentSpread:
+			v.directives(sel.Directives, "FRAGMENT_SPREAD")
+			f := v.frags[sel.Name]
+			if f == nil {
+				v.errorf(sel.Loc, "Unknown fragment %q.", sel.Name)
+				continue
+			}
+			v.spreads[sel.Name] = true
+			ft := v.s.Type(f.TypeCond)
+			if ft == nil || ft.Kind != KindObject {
+				continue
+			}
+			if ft != t {
+				v.errorf(sel.Loc, "Fragment %q cannot be spread here as objects of type %q can never be of type %q.", sel.Name, t.Name, ft.Name)
+				continue
+			}
+			if v.visited[sel.Name] {
+				continue
+			}
+			v.visited[sel.Name] = true
+			v.selections(ft, f.Selections)
+		}
+	}
+}
+
+func (v *validator) field(t *Type, f *Field) {
+	fd := v.s.fieldDef(t, f.Name)
+	if fd == nil {
+		v.errorf(f.Loc, "Cannot query field %q on type %q.", f.Name, t.Name)
+		return
+	}
+	v.directives(f.Directives, "FIELD")
+	v.arguments(fd.Args, f.Args, f.Loc, fmt.Sprintf("Field %q", f.Name))
+	named := fd.Type.named()
+	switch {
+	case fd.Type.isLeaf() && len(f.Selections) > 0:
+		v.errorf(f.Loc, "Field %q must not have a selection since type %q has no subfields.", f.Name, fd.Type)
+	case !fd.Type.isLeaf() && len(f.Selections) == 0:
+		v.errorf(f.Loc, "Field %q of type %q must have a selection of subfields. Did you mean \"%s { ... }\"?", f.Name, fd.Type, f.Name)
+	case !fd.Type.isLeaf():
+		v.selections(named, f.Selections)
+	}
+}
+
+func (v *validator) directives(ds []*Directive, location string) {
+	names := map[string]bool{}
+	for _, d := range ds {
+		info := v.s.directive(d.Name)
+		if info == nil {
+			v.errorf(d.Loc, "Unknown directive \"@%s\".", d.Name)
+			continue
+		}
+		if names[d.Name] {
+			v.errorf(d.Loc, "The directive \"@%s\" can only be used once at this location.", d.Name)
+		}
+		names[d.Name] = true
+		allowed := false
+		for _, l := range info.Locations {
+			allowed = allowed || l == location
+		}
+		if !allowed {
+			v.errorf(d.Loc, "Directive \"@%s\" may not be used on %s.", d.Name, location)
+			continue
+		}
+		v.arguments(info.Args, d.Args, d.Loc, fmt.Sprintf("Directive \"@%s\"", d.Name))
+	}
+}
+
+func (v *validator) arguments(defs []*InputValue, args []*Argument, loc Location, owner string) {
+	names := map[string]bool{}
+	for _, a := range args {
+		if names[a.Name] {
+			v.errorf(a.Loc, "There can be only one argument named %q.", a.Name)
+		}
+		names[a.Name] = true
+		var def *InputValue
+		for _, d := range defs {
+			if d.Name == a.Name {
+				def = d
+			}
+		}
+		if def == nil {
+			v.errorf(a.Loc, "Unknown argument %q on %s.", a.Name, strings.ToLower(owner[:1])+owner[1:])
+			continue
+		}
+		v.value(def.Type, a.Value, def.Default != nil)
+	}
+	for _, d := range defs {
+		if d.Type.Kind == KindNonNull && d.Default == nil && !names[d.Name] {
+			v.errorf(loc, "%s argument %q of type %q is required, but it was not provided.", owner, d.Name, d.Type)
+		}
+	}
+}
+
+// value checks a literal against t, recording variable usages.
+func (v *validator) value(t *Type, val *Value, hasDefault bool) {
+	if val.Kind == VariableValue {
+		v.usages = append(v.usages, varUsage{name: val.Raw, typ: t, hasDefault: hasDefault, loc: val.Loc})
+		return
+	}
+	if t.Kind == KindNonNull {
+		if val.Kind == NullValue {
+			v.errorf(val.Loc, "Expected value of type %q, found null.", t)
+			return
+		}
+		t = t.OfType
+	}
+	if val.Kind == NullValue {
+		return
+	}
+	switch t.Kind {
+	case KindList:
+		if val.Kind != ListValue {
+			v.value(t.OfType, val, false)
+			return
+		}
+		for _, e := range val.List {
+			v.value(t.OfType, e, false)
+		}
+	case KindInputObject:
+		if val.Kind != ObjectValue {
+			v.errorf(val.Loc, "Expected value of type %q, found %s.", t, val)
+			return
+		}
+		given := map[string]bool{}
+		for _, f := range val.Fields {
+			if given[f.Name] {
+				v.errorf(f.Loc, "There can be only one input field named %q.", f.Name)
+			}
+			given[f.Name] = true
+			def := t.inputField(f.Name)
+			if def == nil {
+				v.errorf(f.Loc, "Field %q is not defined by type %q.", f.Name, t)
+				continue
+			}
+			v.value(def.Type, f.Value, def.Default != nil)
+		}
+		for _, f := range t.InputFields {
+			if f.Type.Kind == KindNonNull && f.Default == nil && !given[f.Name] {
+				v.errorf(val.Loc, "Field \"%s.%s\" of required type %q was not provided.", t, f.Name, f.Type)
+			}
+		}
+	case KindEnum:
+		if val.Kind != EnumValue || t.enumValue(val.Raw) == nil {
+			v.errorf(val.Loc, "Value %s does not exist in %q enum.", val, t)
+		}
+	default:
+		if !isBuiltinScalar(t.Name) {
+			v.jsonVariables(val)
+			return
+		}
+		if _, err := scalarLiteral(t, val, nil); err != nil {
+			v.errorf(val.Loc, "%s", err)
+		}
+	}
+}
+
+// jsonVariables records variables nested in a custom scalar literal.
+func (v *validator) jsonVariables(val *Value) {
+	switch val.Kind {
+	case VariableValue:
+		v.usages = append(v.usages, varUsage{name: val.Raw, loc: val.Loc})
+	case ListValue:
+		for _, e := range val.List {
+			v.jsonVariables(e)
+		}
+	case ObjectValue:
+		for _, f := range val.Fields {
+			v.jsonVariables(f.Value)
+		}
+	}
+}
+
+func isBuiltinScalar(name string) bool {
+	switch name {
+	case "Int", "Float", "String", "Boolean", "ID":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,455 @@
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// coerceVariables validates the provided variable values against the
+// operation's definitions, applying defaults.
+func coerceVariables(s *Schema, op *Operation, values map[string]interface{}) (map[string]interface{}, []*Error) {
+	out := map[string]interface{}{}
+	var errs []*Error
+	for _, def := range op.Vars {
+		t, err := s.ref(def.Type)
+		if err != nil || !t.isInput() {
+			errs = append(errs, newError(def.Loc, "Variable \"$%s\" expected value of type %q which cannot be used as an input type.", def.Name, def.Type))
+			continue
+		}
+		v, ok := values[def.Name]
+		switch {
+		case !ok && def.Default != nil:
+			d, err := literal(t, def.Default, nil)
+			if err != nil {
+				errs = append(errs, newError(def.Loc, "Variable \"$%s\" has invalid default value: %s", def.Name, err))
+				continue
+			}
+			out[def.Name] = d
+		case !ok || v == nil:
+			if t.Kind == KindNonNull {
+				what := "was not provided"
+				if ok {
+					what = "must not be null"
+				}
+				errs = append(errs, newError(def.Loc, "Variable \"$%s\" of non-null type %q %s.", def.Name, t, what))
+				continue
+			}
+			if ok {
+				out[def.Name] = nil
+			}
+		default:
+			c, err := coerceInput(t, v)
+			if err != nil {
+				errs = append(errs, newError(def.Loc, "Variable \"$%s\" got invalid value %s; %s", def.Name, describe(v), err))
+				continue
+			}
+			out[def.Name] = c
+		}
+	}
+	return out, errs
+}
+
+// coerceArgs builds the argument map for a field or directive. Arguments
+// that are omitted and have no default are left out of the map.
+func coerceArgs(defs []*InputValue, args []*Argument, vars map[string]interface{}) (map[string]interface{}, error) {
+	out := map[string]interface{}{}
+	for _, def := range defs {
+		a := argument(args, def.Name)
+		if a != nil && a.Value.Kind == VariableValue {
+			if v, ok := vars[a.Value.Raw]; ok {
+				if v == nil && def.Type.Kind == KindNonNull {
+					return nil, fmt.Errorf("Argument %q of non-null type %q must not be null.", def.Name, def.Type)
+				}
+				out[def.Name] = v
+				continue
+			}
+			a = nil
+		}
+		if a == nil {
+			if def.Default != nil {
+				v, err := literal(def.Type, def.Default, nil)
+				if err != nil {
+					return nil, err
+				}
+				out[def.Name] = v
+			} else if def.Type.Kind == KindNonNull {
+				return nil, fmt.Errorf("Argument %q of required type %q was not provided.", def.Name, def.Type)
+			}
+			continue
+		}
+		v, err := literal(def.Type, a.Value, vars)
+		if err != nil {
+			return nil, fmt.Errorf("Argument %q has invalid value %s. %s", def.Name, a.Value, err)
+		}
+		out[def.Name] = v
+	}
+	return out, nil
+}
+
+// literal coerces an AST value to type t, substituting variables.
+func literal(t *Type, v *Value, vars map[string]interface{}) (interface{}, error) {
+	if v.Kind == VariableValue {
+		val, ok := vars[v.Raw]
+		if (!ok || val == nil) && t.Kind == KindNonNull {
+			return nil, fmt.Errorf("Expected non-null value of type %s.", t)
+		}
+		return val, nil
+	}
+	if t.Kind == KindNonNull {
+		if v.Kind == NullValue {
+			return nil, fmt.Errorf("Expected value of type %q, found null.", t)
+		}
+		return literal(t.OfType, v, vars)
+	}
+	if v.Kind == NullValue {
+		return nil, nil
+	}
+	switch t.Kind {
+	case KindList:
+		if v.Kind != ListValue {
+			e, err := literal(t.OfType, v, vars)
+			if err != nil {
+				return nil, err
+			}
+			return []interface{}{e}, nil
+		}
+		out := make([]interface{}, len(v.List))
+		for i, e := range v.List {
+			c, err := literal(t.OfType, e, vars)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = c
+		}
+		return out, nil
+	case KindInputObject:
+		if v.Kind != ObjectValue {
+			return nil, fmt.Errorf("Expected value of type %q, found %s.", t, v)
+		}
+		given := map[string]*Value{}
+		for _, f := range v.Fields {
+			if t.inputField(f.Name) == nil {
+				return nil, fmt.Errorf("Field %q is not defined by type %q.", f.Name, t)
+			}
+			given[f.Name] = f.Value
+		}
+		out := map[string]interface{}{}
+		for _, f := range t.InputFields {
+			fv, ok := given[f.Name]
+			if !ok || (fv.Kind == VariableValue && !hasVar(vars, fv.Raw)) {
+				if f.Default != nil {
+					fv = f.Default
+				} else if f.Type.Kind == KindNonNull {
+					return nil, fmt.Errorf("Field \"%s.%s\" of required type %q was not provided.", t, f.Name, f.Type)
+				} else {
+					continue
+				}
+			}
+			c, err := literal(f.Type, fv, vars)
+			if err != nil {
+				return nil, err
+			}
+			out[f.Name] = c
+		}
+		return out, nil
+	case KindEnum:
+		if v.Kind != EnumValue || t.enumValue(v.Raw) == nil {
+			return nil, fmt.Errorf("Value %s does not exist in %q enum.", v, t)
+		}
+		return v.Raw, nil
+	}
+	return scalarLiteral(t, v, vars)
+}
+
+func hasVar(vars map[string]interface{}, name string) bool {
+	_, ok := vars[name]
+	return ok
+}
+
+func scalarLiteral(t *Type, v *Value, vars map[string]interface{}) (interface{}, error) {
+	mismatch := fmt.Errorf("%s cannot represent %s.", t.Name, v)
+	switch t.Name {
+	case "Int":
+		if v.Kind != IntValue {
+			return nil, mismatch
+		}
+		n, err := strconv.ParseInt(v.Raw, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("Int cannot represent non 32-bit signed integer value: %s", v.Raw)
+		}
+		return int(n), nil
+	case "Float":
+		if v.Kind != IntValue && v.Kind != FloatValue {
+			return nil, mismatch
+		}
+		return strconv.ParseFloat(v.Raw, 64)
+	case "String":
+		if v.Kind != StringValue {
+			return nil, mismatch
+		}
+		return v.Raw, nil
+	case "Boolean":
+		if v.Kind != BooleanValue {
+			return nil, mismatch
+		}
+		return v.Raw == "true", nil
+	case "ID":
+		if v.Kind != StringValue && v.Kind != IntValue {
+			return nil, mismatch
+		}
+		return v.Raw, nil
+	}
+	return jsonLiteral(v, vars), nil
+}
+
+// jsonLiteral converts an AST value to its JSON equivalent for custom
+// scalars. Enum values become strings.
+func jsonLiteral(v *Value, vars map[string]interface{}) interface{} {
+	switch v.Kind {
+	case VariableValue:
+		return vars[v.Raw]
+	case IntValue, FloatValue:
+		f, _ := strconv.ParseFloat(v.Raw, 64)
+		return f
+	case BooleanValue:
+		return v.Raw == "true"
+	case NullValue:
+		return nil
+	case ListValue:
+		out := make([]interface{}, len(v.List))
+		for i, e := range v.List {
+			out[i] = jsonLiteral(e, vars)
+		}
+		return out
+	case ObjectValue:
+		out := make(map[string]interface{}, len(v.Fields))
+		for _, f := range v.Fields {
+			out[f.Name] = jsonLiteral(f.Value, vars)
+		}
+		return out
+	}
+	return v.Raw
+}
+
+func (t *Type) inputField(name string) *InputValue {
+	for _, f := range t.InputFields {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// coerceInput coerces a JSON-decoded variable value to type t.
+func coerceInput(t *Type, v interface{}) (interface{}, error) {
+	if t.Kind == KindNonNull {
+		if v == nil {
+			return nil, fmt.Errorf("Expected non-nullable type %q not to be null.", t)
+		}
+		return coerceInput(t.OfType, v)
+	}
+	if v == nil {
+		return nil, nil
+	}
+	switch t.Kind {
+	case KindList:
+		list, ok := v.([]interface{})
+		if !ok {
+			e, err := coerceInput(t.OfType, v)
+			if err != nil {
+				return nil, err
+			}
+			return []interface{}{e}, nil
+		}
+		out := make([]interface{}, len(list))
+		for i, e := range list {
+			c, err := coerceInput(t.OfType, e)
+			if err != nil {
+				return nil, fmt.Errorf("%s at index %d", err, i)
+			}
+			out[i] = c
+		}
+		return out, nil
+	case KindInputObject:
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("Expected type %q to be an object.", t)
+		}
+		for k := range obj {
+			if t.inputField(k) == nil {
+				return nil, fmt.Errorf("Field %q is not defined by type %q.", k, t)
+			}
+		}
+		out := map[string]interface{}{}
+		for _, f := range t.InputFields {
+			fv, ok := obj[f.Name]
+			if !ok {
+				if f.Default != nil {
+					d, err := literal(f.Type, f.Default, nil)
+					if err != nil {
+						return nil, err
+					}
+					out[f.Name] = d
+				} else if f.Type.Kind == KindNonNull {
+					return nil, fmt.Errorf("Field %q of required type %q was not provided.", f.Name, f.Type)
+				}
+				continue
+			}
+			c, err := coerceInput(f.Type, fv)
+			if err != nil {
+				return nil, err
+			}
+			out[f.Name] = c
+		}
+		return out, nil
+	case KindEnum:
+		s, ok := v.(string)
+		if !ok || t.enumValue(s) == nil {
+			return nil, fmt.Errorf("Value %s does not exist in %q enum.", describe(v), t)
+		}
+		return s, nil
+	}
+	switch t.Name {
+	case "Int":
+		f, ok := number(v)
+		if !ok || f != math.Trunc(f) || f > math.MaxInt32 || f < math.MinInt32 {
+			return nil, fmt.Errorf("Int cannot represent value: %s", describe(v))
+		}
+		return int(f), nil
+	case "Float":
+		f, ok := number(v)
+		if !ok {
+			return nil, fmt.Errorf("Float cannot represent value: %s", describe(v))
+		}
+		return f, nil
+	case "String":
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("String cannot represent a non string value: %s", describe(v))
+		}
+		return s, nil
+	case "Boolean":
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("Boolean cannot represent a non boolean value: %s", describe(v))
+		}
+		return b, nil
+	case "ID":
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+		if f, ok := number(v); ok && f == math.Trunc(f) {
+			return strconv.FormatInt(int64(f), 10), nil
+		}
+		return nil, fmt.Errorf("ID cannot represent value: %s", describe(v))
+	}
+	return v, nil
+}
+
+// number reads a JSON number.
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case int:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+func describe(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// serialize converts a resolved leaf value to its output representation.
+func serialize(t *Type, v interface{}) (interface{}, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+	if t.Kind == KindEnum {
+		if rv.Kind() != reflect.String || t.enumValue(rv.String()) == nil {
+			return nil, fmt.Errorf("Enum %q cannot represent value: %s", t.Name, describe(v))
+		}
+		return rv.String(), nil
+	}
+	switch t.Name {
+	case "Int":
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n := rv.Int()
+			if n > math.MaxInt32 || n < math.MinInt32 {
+				return nil, fmt.Errorf("Int cannot represent non 32-bit signed integer value: %d", n)
+			}
+			return n, nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if rv.Uint() > math.MaxInt32 {
+				return nil, fmt.Errorf("Int cannot represent non 32-bit signed integer value: %d", rv.Uint())
+			}
+			return int64(rv.Uint()), nil
+		case reflect.Float32, reflect.Float64:
+			if f := rv.Float(); f == math.Trunc(f) && f <= math.MaxInt32 && f >= math.MinInt32 {
+				return int64(f), nil
+			}
+		case reflect.Bool:
+			if rv.Bool() {
+				return 1, nil
+			}
+			return 0, nil
+		}
+	case "Float":
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return float64(rv.Int()), nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return float64(rv.Uint()), nil
+		case reflect.Float32, reflect.Float64:
+			if f := rv.Float(); !math.IsNaN(f) && !math.IsInf(f, 0) {
+				return f, nil
+			}
+		case reflect.Bool:
+			if rv.Bool() {
+				return 1.0, nil
+			}
+			return 0.0, nil
+		}
+	case "String", "ID":
+		switch rv.Kind() {
+		case reflect.String:
+			return rv.String(), nil
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return strconv.FormatInt(rv.Int(), 10), nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return strconv.FormatUint(rv.Uint(), 10), nil
+		case reflect.Float32, reflect.Float64:
+			return strconv.FormatFloat(rv.Float(), 'f', -1, 64), nil
+		case reflect.Bool:
+			if t.Name == "String" {
+				return strconv.FormatBool(rv.Bool()), nil
+			}
+		}
+		if s, ok := v.(fmt.Stringer); ok {
+			return s.String(), nil
+		}
+	case "Boolean":
+		if rv.Kind() == reflect.Bool {
+			return rv.Bool(), nil
+		}
+	default:
+		return v, nil
+	}
+	return nil, fmt.Errorf("%s cannot represent value: %s", t.Name, describe(v))
+}
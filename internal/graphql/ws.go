@@ -0,0 +1,301 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/websocket/v2"
+
+	"mem0-go/internal/auth"
+)
+
+// WebSocket subprotocols. graphql-transport-ws is the protocol of the
+// graphql-ws library; graphql-ws is the legacy subscriptions-transport-ws
+// protocol still used by older Apollo clients.
+const (
+	protocolTransportWS = "graphql-transport-ws"
+	protocolLegacyWS    = "graphql-ws"
+)
+
+// Close codes defined by graphql-transport-ws.
+const (
+	closeBadRequest      = 4400
+	closeUnauthorized    = 4401
+	closeForbidden       = 4403
+	closeBadProtocol     = 4406
+	closeInitTimeout     = 4408
+	closeSubscriberInUse = 4409
+	closeTooManyInits    = 4429
+)
+
+// initTimeout is how long a client has to send connection_init.
+var initTimeout = 10 * time.Second
+
+// wsMessage is a protocol message in either direction.
+type wsMessage struct {
+	ID      string      `json:"id,omitempty"`
+	Type    string      `json:"type"`
+	Payload interface{} `json:"payload,omitempty"`
+}
+
+// wsHandler serves schema over WebSocket. Queries and mutations may be
+// sent as well as subscriptions. Connections authenticate with authn when
+// they are initialised.
+func wsHandler(schema *Schema, authn *auth.Authenticator) fiber.Handler {
+	return websocket.New(func(c *websocket.Conn) {
+		s := &wsSession{schema: schema, authn: authn, conn: c, legacy: c.Subprotocol() == protocolLegacyWS, ops: map[string]context.CancelFunc{}}
+		s.run()
+	}, websocket.Config{Subprotocols: []string{protocolTransportWS, protocolLegacyWS}})
+}
+
+// wsSession is one WebSocket connection.
+type wsSession struct {
+	schema *Schema
+	authn  *auth.Authenticator
+	conn   *websocket.Conn
+	legacy bool
+
+	mu        sync.Mutex
+	inited    bool
+	acked     bool
+	actor     string
+	principal *auth.Principal
+	ops       map[string]context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+func (s *wsSession) run() {
+	if s.conn.Subprotocol() == "" {
+		s.conn.WriteClose(closeBadProtocol, "Subprotocol not acceptable")
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		s.wg.Wait()
+	}()
+	s.actor = s.conn.Headers("X-Actor")
+	timer := time.AfterFunc(initTimeout, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if !s.acked {
+			s.conn.WriteClose(closeInitTimeout, "Connection initialisation timeout")
+			s.conn.Close()
+		}
+	})
+	defer timer.Stop()
+
+	for {
+		_, data, err := s.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var msg struct {
+			ID      string          `json:"id"`
+			Type    string          `json:"type"`
+			Payload json.RawMessage `json:"payload"`
+		}
+		if err := json.Unmarshal(data, &msg); err != nil || msg.Type == "" {
+			s.conn.WriteClose(closeBadRequest, "Invalid message received")
+			return
+		}
+		switch msg.Type {
+		case "connection_init":
+			if !s.init(msg.Payload) {
+				return
+			}
+		case "ping":
+			s.send(wsMessage{Type: "pong"})
+		case "pong":
+		case "subscribe", "start":
+			if !s.start(ctx, msg.ID, msg.Payload) {
+				return
+			}
+		case "complete", "stop":
+			s.stop(msg.ID)
+		case "connection_terminate":
+			return
+		default:
+			s.conn.WriteClose(closeBadRequest, "Invalid message received")
+			return
+		}
+	}
+}
+
+// init handles connection_init. Its payload is treated like request
+// headers, since browsers cannot set headers on a WebSocket, and the
+// connection is authenticated with them.
+func (s *wsSession) init(raw json.RawMessage) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.inited {
+		s.conn.WriteClose(closeTooManyInits, "Too many initialisation requests")
+		return false
+	}
+	s.inited = true
+	h := http.Header{}
+	for _, k := range []string{"Authorization", "X-API-Key"} {
+		if v := s.conn.Headers(k); v != "" {
+			h.Set(k, v)
+		}
+	}
+	var payload map[string]interface{}
+	_ = json.Unmarshal(raw, &payload)
+	for k, v := range payload {
+		v, ok := v.(string)
+		switch {
+		case !ok:
+		case strings.EqualFold(k, "X-Actor"):
+			s.actor = v
+		case strings.EqualFold(k, "Authorization"), strings.EqualFold(k, "X-API-Key"):
+			h.Set(k, v)
+		}
+	}
+	p, err := s.authn.Authenticate(context.Background(), h)
+	if err != nil {
+		if errors.Is(err, auth.ErrUnauthenticated) {
+			s.conn.WriteClose(closeForbidden, "Forbidden")
+		} else {
+			s.conn.WriteClose(closeBadRequest, "Authentication failed")
+		}
+		return false
+	}
+	s.principal, s.acked = p, true
+	s.conn.WriteJSON(wsMessage{Type: "connection_ack"})
+	if s.legacy {
+		s.conn.WriteJSON(wsMessage{Type: "ka"})
+	}
+	return true
+}
+
+// start runs operation id. It returns false when the connection must be
+// closed.
+func (s *wsSession) start(ctx context.Context, id string, raw json.RawMessage) bool {
+	var p Params
+	if id == "" || json.Unmarshal(raw, &p) != nil {
+		s.conn.WriteClose(closeBadRequest, "Invalid message received")
+		return false
+	}
+	s.mu.Lock()
+	if !s.acked {
+		s.mu.Unlock()
+		s.conn.WriteClose(closeUnauthorized, "Unauthorized")
+		return false
+	}
+	if _, busy := s.ops[id]; busy {
+		s.mu.Unlock()
+		s.conn.WriteClose(closeSubscriberInUse, "Subscriber for "+id+" already exists")
+		return false
+	}
+	if s.principal != nil {
+		ctx = auth.WithPrincipal(ctx, s.principal)
+	} else {
+		// Only connections to servers that do not require credentials
+		// are acknowledged without a principal.
+		ctx = auth.Internal(ctx)
+	}
+	opCtx, cancel := context.WithCancel(withActor(ctx, s.actor))
+	s.ops[id] = cancel
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.finish(id, s.execute(opCtx, id, p))
+	}()
+	return true
+}
+
+// stop cancels operation id at the client's request.
+func (s *wsSession) stop(id string) {
+	s.mu.Lock()
+	cancel := s.ops[id]
+	delete(s.ops, id)
+	s.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+}
+
+// finish forgets operation id and, when complete is set, tells the client
+// it is complete unless the client stopped it.
+func (s *wsSession) finish(id string, complete bool) {
+	s.mu.Lock()
+	cancel, running := s.ops[id]
+	delete(s.ops, id)
+	s.mu.Unlock()
+	if running {
+		cancel()
+		if complete {
+			s.send(wsMessage{ID: id, Type: "complete"})
+		}
+	}
+}
+
+// execute runs an operation and sends its results. It returns false when
+// it ended the operation with an error message, which needs no complete.
+func (s *wsSession) execute(ctx context.Context, id string, p Params) bool {
+	doc, err := Parse(p.Query)
+	var errs []*Error
+	if err != nil {
+		errs = []*Error{toError(err)}
+	} else {
+		errs = Validate(s.schema, doc)
+	}
+	if len(errs) > 0 {
+		return s.sendErrors(id, errs)
+	}
+	op, err := SelectOperation(doc, p.OperationName)
+	if err != nil {
+		return s.sendErrors(id, []*Error{toError(err)})
+	}
+	if op.Type != "subscription" {
+		resp := s.schema.Execute(ctx, doc, p.OperationName, p.Variables)
+		if !resp.Executed() {
+			return s.sendErrors(id, resp.Errors)
+		}
+		s.next(id, resp)
+		return true
+	}
+	stream, resp := s.schema.Subscribe(ctx, doc, p.OperationName, p.Variables)
+	if stream == nil {
+		if !resp.Executed() {
+			return s.sendErrors(id, resp.Errors)
+		}
+		s.next(id, resp)
+		return true
+	}
+	for resp := range stream {
+		s.next(id, resp)
+	}
+	return true
+}
+
+func (s *wsSession) next(id string, resp *Response) {
+	typ := "next"
+	if s.legacy {
+		typ = "data"
+	}
+	s.send(wsMessage{ID: id, Type: typ, Payload: resp})
+}
+
+// sendErrors reports errors that prevented execution. The legacy protocol
+// sends them as data followed by complete.
+func (s *wsSession) sendErrors(id string, errs []*Error) bool {
+	if s.legacy {
+		s.send(wsMessage{ID: id, Type: "data", Payload: &Response{Errors: errs}})
+		return true
+	}
+	s.send(wsMessage{ID: id, Type: "error", Payload: errs})
+	return false
+}
+
+func (s *wsSession) send(m wsMessage) {
+	_ = s.conn.WriteJSON(m)
+}
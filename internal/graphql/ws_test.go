@@ -0,0 +1,251 @@
+package graphql
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"mem0-go/internal/auth"
+	"mem0-go/internal/events"
+	"mem0-go/internal/inmem"
+	"mem0-go/internal/memory"
+)
+
+// wsClient is a minimal WebSocket client for exercising the protocol.
+type wsClient struct {
+	t    *testing.T
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+func dialWS(t *testing.T, addr, protocol string) *wsClient {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	req := "GET /graphql HTTP/1.1\r\nHost: " + addr + "\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n" +
+		"Sec-WebSocket-Protocol: " + protocol + "\r\n\r\n"
+	if _, err := conn.Write([]byte(req)); err != nil {
+		t.Fatalf("handshake: %v", err)
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatalf("handshake: %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Protocol") != protocol {
+		t.Fatalf("unexpected handshake response %d %v", resp.StatusCode, resp.Header)
+	}
+	return &wsClient{t: t, conn: conn, br: br}
+}
+
+func (c *wsClient) send(v interface{}) {
+	c.t.Helper()
+	b, _ := json.Marshal(v)
+	mask := [4]byte{1, 2, 3, 4}
+	frame := []byte{0x81, 0x80 | 126, byte(len(b) >> 8), byte(len(b))}
+	frame = append(frame, mask[:]...)
+	for i, x := range b {
+		frame = append(frame, x^mask[i%4])
+	}
+	if _, err := c.conn.Write(frame); err != nil {
+		c.t.Fatalf("write: %v", err)
+	}
+}
+
+// read returns the next message, or the close code as {"close": code}.
+func (c *wsClient) read() map[string]interface{} {
+	c.t.Helper()
+	var h [2]byte
+	if _, err := io.ReadFull(c.br, h[:]); err != nil {
+		c.t.Fatalf("read: %v", err)
+	}
+	n := int(h[1] & 0x7f)
+	if n == 126 {
+		var ext [2]byte
+		io.ReadFull(c.br, ext[:])
+		n = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		c.t.Fatalf("read: %v", err)
+	}
+	if h[0]&0x0f == 8 {
+		return map[string]interface{}{"close": float64(binary.BigEndian.Uint16(payload))}
+	}
+	var msg map[string]interface{}
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		c.t.Fatalf("unmarshal %s: %v", payload, err)
+	}
+	return msg
+}
+
+func startWSServer(t *testing.T, authn *auth.Authenticator) (string, *memory.Service) {
+	t.Helper()
+	svc := memory.NewService(inmem.NewRepo(), inmem.NewVector(), inmem.NewGraph(), memory.WithEvents(events.NewBus()))
+	app := fiber.New(fiber.Config{})
+	Register(app, svc, authn)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	go app.Listener(ln)
+	t.Cleanup(func() { app.ShutdownWithContext(context.Background()) })
+	return ln.Addr().String(), svc
+}
+
+func TestSubscriptionReceivesMatchingMemories(t *testing.T) {
+	addr, svc := startWSServer(t, nil)
+	c := dialWS(t, addr, protocolTransportWS)
+	c.send(wsMessage{Type: "connection_init"})
+	if msg := c.read(); msg["type"] != "connection_ack" {
+		t.Fatalf("expected connection_ack, got %v", msg)
+	}
+	c.send(wsMessage{ID: "1", Type: "subscribe", Payload: Params{Query: `subscription { memoryAdded(userID: 2) { content userID } }`}})
+
+	// The subscription is set up asynchronously; store until it sees one.
+	ctx := auth.Internal(context.Background())
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, err := svc.Store(ctx, memory.StoreRequest{UserID: 1, Content: "other user", Vector: []float32{1}}); err != nil {
+			t.Fatalf("store: %v", err)
+		}
+		if _, err := svc.Store(ctx, memory.StoreRequest{UserID: 2, Content: "mine", Vector: []float32{1}}); err != nil {
+			t.Fatalf("store: %v", err)
+		}
+		c.conn.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+		if _, err := c.br.Peek(1); err == nil {
+			break
+		}
+	}
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	msg := c.read()
+	b, _ := json.Marshal(msg["payload"])
+	if msg["type"] != "next" || msg["id"] != "1" || string(b) != `{"data":{"memoryAdded":{"content":"mine","userID":2}}}` {
+		t.Fatalf("unexpected message %v", msg)
+	}
+
+	c.send(wsMessage{ID: "1", Type: "complete"})
+	c.send(wsMessage{ID: "2", Type: "subscribe", Payload: Params{Query: `{ memory(id: 2) { content } }`}})
+	msg = c.read()
+	for msg["id"] == "1" {
+		msg = c.read()
+	}
+	b, _ = json.Marshal(msg["payload"])
+	if msg["type"] != "next" || string(b) != `{"data":{"memory":{"content":"mine"}}}` {
+		t.Fatalf("unexpected query result %v", msg)
+	}
+	if msg = c.read(); msg["type"] != "complete" || msg["id"] != "2" {
+		t.Fatalf("expected complete, got %v", msg)
+	}
+}
+
+func TestSubscriptionProtocolErrors(t *testing.T) {
+	addr, _ := startWSServer(t, nil)
+
+	c := dialWS(t, addr, protocolTransportWS)
+	c.send(wsMessage{ID: "1", Type: "subscribe", Payload: Params{Query: `subscription { memoryAdded { id } }`}})
+	if msg := c.read(); msg["close"] != float64(closeUnauthorized) {
+		t.Fatalf("expected 4401 before init, got %v", msg)
+	}
+
+	c = dialWS(t, addr, protocolTransportWS)
+	c.send(wsMessage{Type: "connection_init"})
+	c.read()
+	c.send(wsMessage{ID: "1", Type: "subscribe", Payload: Params{Query: `subscription { nope { id } }`}})
+	msg := c.read()
+	if msg["type"] != "error" || !strings.Contains(string(mustJSON(msg["payload"])), `Cannot query field \"nope\"`) {
+		t.Fatalf("expected validation error, got %v", msg)
+	}
+
+	c = dialWS(t, addr, protocolLegacyWS)
+	c.send(wsMessage{Type: "connection_init", Payload: map[string]string{"X-Actor": "ada"}})
+	if msg := c.read(); msg["type"] != "connection_ack" {
+		t.Fatalf("expected connection_ack, got %v", msg)
+	}
+	if msg := c.read(); msg["type"] != "ka" {
+		t.Fatalf("expected ka, got %v", msg)
+	}
+	c.send(wsMessage{Type: "connection_init"})
+	if msg := c.read(); msg["close"] != float64(closeTooManyInits) {
+		t.Fatalf("expected 4429 on second init, got %v", msg)
+	}
+}
+
+func TestSubscriptionAuthentication(t *testing.T) {
+	authn, err := auth.New(auth.Config{Required: true, JWTSecret: "s3cret"}, nil)
+	if err != nil {
+		t.Fatalf("auth: %v", err)
+	}
+	addr, svc := startWSServer(t, authn)
+
+	c := dialWS(t, addr, protocolTransportWS)
+	c.send(wsMessage{Type: "connection_init"})
+	if msg := c.read(); msg["close"] != float64(closeForbidden) {
+		t.Fatalf("expected close %d without credentials, got %v", closeForbidden, msg)
+	}
+
+	// Bad tokens are rejected. User 2's token only sees their own
+	// memories, even without a userID argument.
+	c = dialWS(t, addr, protocolTransportWS)
+	c.send(wsMessage{Type: "connection_init", Payload: map[string]string{"Authorization": "Bearer a.b.c"}})
+	if msg := c.read(); msg["close"] != float64(closeForbidden) {
+		t.Fatalf("expected close %d for a bad token, got %v", closeForbidden, msg)
+	}
+	c = dialWS(t, addr, protocolTransportWS)
+	c.send(wsMessage{Type: "connection_init", Payload: map[string]string{"Authorization": "Bearer " + hs256Token("s3cret", `{"sub":"2"}`)}})
+	if msg := c.read(); msg["type"] != "connection_ack" {
+		t.Fatalf("expected connection_ack, got %v", msg)
+	}
+	c.send(wsMessage{ID: "1", Type: "subscribe", Payload: Params{Query: `subscription { memoryAdded { content userID } }`}})
+	ctx := auth.Internal(context.Background())
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, err := svc.Store(ctx, memory.StoreRequest{UserID: 1, Content: "other user", Vector: []float32{1}}); err != nil {
+			t.Fatalf("store: %v", err)
+		}
+		if _, err := svc.Store(ctx, memory.StoreRequest{UserID: 2, Content: "mine", Vector: []float32{1}}); err != nil {
+			t.Fatalf("store: %v", err)
+		}
+		c.conn.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+		if _, err := c.br.Peek(1); err == nil {
+			break
+		}
+	}
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	msg := c.read()
+	b, _ := json.Marshal(msg["payload"])
+	if msg["type"] != "next" || string(b) != `{"data":{"memoryAdded":{"content":"mine","userID":2}}}` {
+		t.Fatalf("unexpected message %v", msg)
+	}
+}
+
+// hs256Token signs claims with secret.
+func hs256Token(secret, claims string) string {
+	enc := base64.RawURLEncoding.EncodeToString
+	signed := enc([]byte(`{"alg":"HS256","typ":"JWT"}`)) + "." + enc([]byte(claims))
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signed))
+	return signed + "." + enc(mac.Sum(nil))
+}
+
+func mustJSON(v interface{}) []byte {
+	b, _ := json.Marshal(v)
+	return b
+}
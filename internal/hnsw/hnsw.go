@@ -0,0 +1,465 @@
+package hnsw
+
+import (
+	"container/heap"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"mem0-go/internal/vector"
+)
+
+// Config holds HNSW graph parameters.
+type Config struct {
+	// M is the number of neighbours kept per node on upper layers; layer 0
+	// keeps 2*M.
+	M int
+	// EfConstruction is the candidate list size used while inserting.
+	EfConstruction int
+	// EfSearch is the minimum candidate list size used while querying.
+	EfSearch int
+	// Distance is the metric used to compare vectors.
+	Distance vector.Distance
+	// Path is the directory collections are persisted in. Empty disables
+	// persistence.
+	Path string
+	// SaveInterval is how often a Store with a Path saves collections
+	// changed since the last save. Zero saves only on Save and Close.
+	SaveInterval time.Duration
+	// CompactRatio is the share of tombstoned nodes above which an index
+	// compacts itself after a delete or replacement. Zero never compacts
+	// automatically.
+	CompactRatio float64
+}
+
+// DefaultConfig returns commonly used parameters.
+func DefaultConfig() Config {
+	return Config{M: 16, EfConstruction: 200, EfSearch: 64, Distance: vector.Cosine, SaveInterval: 10 * time.Second, CompactRatio: 0.25}
+}
+
+// LoadConfig reads settings from environment variables with fallbacks.
+func LoadConfig() Config {
+	cfg := DefaultConfig()
+	cfg.M = getint("MEM0_HNSW_M", cfg.M)
+	cfg.EfConstruction = getint("MEM0_HNSW_EF_CONSTRUCTION", cfg.EfConstruction)
+	cfg.EfSearch = getint("MEM0_HNSW_EF_SEARCH", cfg.EfSearch)
+	cfg.Path = os.Getenv("MEM0_HNSW_PATH")
+	if d, err := time.ParseDuration(os.Getenv("MEM0_HNSW_SAVE_INTERVAL")); err == nil && d >= 0 {
+		cfg.SaveInterval = d
+	}
+	if r, err := strconv.ParseFloat(os.Getenv("MEM0_HNSW_COMPACT_RATIO"), 64); err == nil && r >= 0 && r < 1 {
+		cfg.CompactRatio = r
+	}
+	return cfg
+}
+
+func getint(key string, def int) int {
+	if v, err := strconv.Atoi(os.Getenv(key)); err == nil && v > 0 {
+		return v
+	}
+	return def
+}
+
+type node struct {
+	id      string
+	vec     []float32
+	payload map[string]interface{}
+	// friends holds neighbour indexes per layer, 0..level.
+	friends [][]int
+	deleted bool
+}
+
+// Index is an HNSW approximate nearest-neighbour graph over a single
+// collection. Deleted and replaced points are tombstoned: they stay in the
+// graph for navigation but are never returned. Compact rebuilds the graph
+// without them, as Add and Delete do once tombstones exceed
+// Config.CompactRatio of the nodes.
+type Index struct {
+	mu       sync.RWMutex
+	cfg      Config
+	levelMul float64
+	rnd      *rand.Rand
+	nodes    []*node
+	ids      map[string]int
+	entry    int
+	maxLevel int
+	dim      int
+	live     int
+}
+
+// New returns an empty index.
+func New(cfg Config) *Index {
+	def := DefaultConfig()
+	if cfg.M < 2 {
+		cfg.M = def.M
+	}
+	if cfg.EfConstruction <= 0 {
+		cfg.EfConstruction = def.EfConstruction
+	}
+	if cfg.EfSearch <= 0 {
+		cfg.EfSearch = def.EfSearch
+	}
+	if cfg.Distance == "" {
+		cfg.Distance = def.Distance
+	}
+	return &Index{
+		cfg:      cfg,
+		levelMul: 1 / math.Log(float64(cfg.M)),
+		rnd:      rand.New(rand.NewSource(1)),
+		ids:      make(map[string]int),
+		entry:    -1,
+	}
+}
+
+// Len reports the number of live points.
+func (ix *Index) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return ix.live
+}
+
+// dist returns a value where lower is closer regardless of metric. Cosine
+// vectors are normalised on the way in, so cosine reduces to a dot product.
+func (ix *Index) dist(a, b []float32) float32 {
+	if ix.cfg.Distance == vector.Euclid {
+		return vector.Euclid.Score(a, b)
+	}
+	return -vector.Dot.Score(a, b)
+}
+
+// prepare returns the form of v stored and compared by the index.
+func (ix *Index) prepare(v []float32) []float32 {
+	if ix.cfg.Distance != vector.Cosine {
+		return v
+	}
+	var norm float64
+	for _, x := range v {
+		norm += float64(x) * float64(x)
+	}
+	out := make([]float32, len(v))
+	if norm == 0 {
+		return out
+	}
+	scale := float32(1 / math.Sqrt(norm))
+	for i, x := range v {
+		out[i] = x * scale
+	}
+	return out
+}
+
+// score converts an internal distance back to the metric's score.
+func (ix *Index) score(d float32) float32 {
+	if ix.cfg.Distance == vector.Euclid {
+		return d
+	}
+	return -d
+}
+
+// Add inserts p, replacing any existing point with the same ID.
+func (ix *Index) Add(p vector.Point) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if err := ix.add(p); err != nil {
+		return err
+	}
+	ix.compactIfStale()
+	return nil
+}
+
+func (ix *Index) add(p vector.Point) error {
+	if ix.dim != 0 && len(p.Vector) != ix.dim {
+		return &vector.DimensionError{ID: p.ID, Got: len(p.Vector), Want: ix.dim}
+	}
+	vec := ix.prepare(p.Vector)
+	if old, ok := ix.ids[p.ID]; ok {
+		n := ix.nodes[old]
+		if equal(n.vec, vec) {
+			n.payload = p.Payload
+			return nil
+		}
+		n.deleted = true
+		ix.live--
+	}
+	ix.dim = len(vec)
+	level := int(-math.Log(1-ix.rnd.Float64()) * ix.levelMul)
+	n := &node{id: p.ID, vec: vec, payload: p.Payload, friends: make([][]int, level+1)}
+	idx := len(ix.nodes)
+	ix.nodes = append(ix.nodes, n)
+	ix.ids[p.ID] = idx
+	ix.live++
+	if ix.entry < 0 {
+		ix.entry, ix.maxLevel = idx, level
+		return nil
+	}
+
+	ep := ix.entry
+	epDist := ix.dist(vec, ix.nodes[ep].vec)
+	for l := ix.maxLevel; l > level; l-- {
+		ep, epDist = ix.greedy(vec, ep, epDist, l)
+	}
+	for l := min(level, ix.maxLevel); l >= 0; l-- {
+		cands := ix.searchLayer(vec, []candidate{{ep, epDist}}, ix.cfg.EfConstruction, l)
+		n.friends[l] = ix.selectNeighbors(cands, ix.maxFriends(l))
+		for _, f := range n.friends[l] {
+			ix.link(f, idx, l)
+		}
+		ep, epDist = cands[0].idx, cands[0].dist
+	}
+	if level > ix.maxLevel {
+		ix.entry, ix.maxLevel = idx, level
+	}
+	return nil
+}
+
+// Delete tombstones the point with the given ID and reports whether there
+// was one.
+func (ix *Index) Delete(id string) bool {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	i, ok := ix.ids[id]
+	if !ok {
+		return false
+	}
+	ix.nodes[i].deleted = true
+	delete(ix.ids, id)
+	ix.live--
+	ix.compactIfStale()
+	return true
+}
+
+// Search returns up to k live points nearest to q whose payload matches
+// filter, best first. The graph is searched with a beam widened for
+// tombstones and filtering; if that yields fewer than k matches the
+// matching points are scanned exhaustively, which is cheap precisely when
+// the filter is selective.
+func (ix *Index) Search(q []float32, k int, filter *vector.Filter) []vector.QueryResult {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	if ix.entry < 0 || k <= 0 || len(q) != ix.dim {
+		return nil
+	}
+	q = ix.prepare(q)
+	ep := ix.entry
+	epDist := ix.dist(q, ix.nodes[ep].vec)
+	for l := ix.maxLevel; l > 0; l-- {
+		ep, epDist = ix.greedy(q, ep, epDist, l)
+	}
+	ef := max(ix.cfg.EfSearch, k)
+	if ix.live > 0 && len(ix.nodes) > ix.live {
+		ef = min(len(ix.nodes), ef*len(ix.nodes)/ix.live)
+	}
+	if filter != nil {
+		ef = min(len(ix.nodes), ef*4)
+	}
+	cands := ix.searchLayer(q, []candidate{{ep, epDist}}, ef, 0)
+	out := ix.collect(cands, k, filter)
+	if len(out) < k && filter != nil && len(cands) < len(ix.nodes) {
+		all := make([]candidate, 0, len(ix.nodes))
+		for i, n := range ix.nodes {
+			if !n.deleted && filter.Matches(n.payload) {
+				all = append(all, candidate{i, ix.dist(q, n.vec)})
+			}
+		}
+		sort.Slice(all, func(i, j int) bool { return all[i].dist < all[j].dist })
+		out = ix.collect(all, k, nil)
+	}
+	return out
+}
+
+// collect converts up to k live candidates matching filter to results.
+func (ix *Index) collect(cands []candidate, k int, filter *vector.Filter) []vector.QueryResult {
+	out := make([]vector.QueryResult, 0, k)
+	for _, c := range cands {
+		n := ix.nodes[c.idx]
+		if n.deleted || !filter.Matches(n.payload) {
+			continue
+		}
+		out = append(out, vector.QueryResult{ID: n.id, Score: ix.score(c.dist), Payload: n.payload})
+		if len(out) == k {
+			break
+		}
+	}
+	return out
+}
+
+// Compact rebuilds the graph from live points, dropping tombstones.
+func (ix *Index) Compact() {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.compact()
+}
+
+// compactIfStale compacts the index when tombstones exceed
+// cfg.CompactRatio of its nodes.
+func (ix *Index) compactIfStale() {
+	if r := ix.cfg.CompactRatio; r > 0 && float64(len(ix.nodes)-ix.live) > r*float64(len(ix.nodes)) {
+		ix.compact()
+	}
+}
+
+func (ix *Index) compact() {
+	old := ix.nodes
+	ix.nodes, ix.ids, ix.entry, ix.maxLevel, ix.dim, ix.live = nil, make(map[string]int), -1, 0, 0, 0
+	for _, n := range old {
+		if !n.deleted {
+			_ = ix.add(vector.Point{ID: n.id, Vector: n.vec, Payload: n.payload})
+		}
+	}
+}
+
+func (ix *Index) maxFriends(level int) int {
+	if level == 0 {
+		return 2 * ix.cfg.M
+	}
+	return ix.cfg.M
+}
+
+// greedy walks layer l towards q until no neighbour is closer.
+func (ix *Index) greedy(q []float32, ep int, epDist float32, l int) (int, float32) {
+	for changed := true; changed; {
+		changed = false
+		for _, f := range ix.nodes[ep].friends[l] {
+			if d := ix.dist(q, ix.nodes[f].vec); d < epDist {
+				ep, epDist, changed = f, d, true
+			}
+		}
+	}
+	return ep, epDist
+}
+
+// searchLayer runs a beam search of width ef on layer l and returns the
+// candidates found, nearest first.
+func (ix *Index) searchLayer(q []float32, eps []candidate, ef, l int) []candidate {
+	visited := make([]bool, len(ix.nodes))
+	near := &minHeap{}
+	found := &maxHeap{}
+	for _, c := range eps {
+		visited[c.idx] = true
+		heap.Push(near, c)
+		heap.Push(found, c)
+	}
+	for near.Len() > 0 {
+		c := heap.Pop(near).(candidate)
+		if found.Len() >= ef && c.dist > (*found)[0].dist {
+			break
+		}
+		for _, f := range ix.nodes[c.idx].friends[l] {
+			if visited[f] {
+				continue
+			}
+			visited[f] = true
+			d := ix.dist(q, ix.nodes[f].vec)
+			if found.Len() < ef || d < (*found)[0].dist {
+				heap.Push(near, candidate{f, d})
+				heap.Push(found, candidate{f, d})
+				if found.Len() > ef {
+					heap.Pop(found)
+				}
+			}
+		}
+	}
+	out := []candidate(*found)
+	sort.Slice(out, func(i, j int) bool { return out[i].dist < out[j].dist })
+	return out
+}
+
+// selectNeighbors applies the HNSW neighbour heuristic: a candidate is kept
+// only if it is closer to the base than to any already kept neighbour,
+// which favours links in diverse directions. Remaining slots are filled
+// with the nearest pruned candidates. cands must be sorted nearest first.
+func (ix *Index) selectNeighbors(cands []candidate, m int) []int {
+	out := make([]int, 0, m)
+	var pruned []int
+	for _, c := range cands {
+		if len(out) >= m {
+			break
+		}
+		good := true
+		for _, r := range out {
+			if ix.dist(ix.nodes[c.idx].vec, ix.nodes[r].vec) < c.dist {
+				good = false
+				break
+			}
+		}
+		if good {
+			out = append(out, c.idx)
+		} else {
+			pruned = append(pruned, c.idx)
+		}
+	}
+	for _, p := range pruned {
+		if len(out) >= m {
+			break
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// link adds a back edge from node a to node b on layer l. When a's list
+// overflows the farthest neighbour is dropped; running the full heuristic
+// here costs O(M^2) distance computations per link for little recall gain.
+func (ix *Index) link(a, b, l int) {
+	n := ix.nodes[a]
+	limit := ix.maxFriends(l)
+	if len(n.friends[l]) < limit {
+		n.friends[l] = append(n.friends[l], b)
+		return
+	}
+	worst, worstDist := -1, ix.dist(n.vec, ix.nodes[b].vec)
+	for i, f := range n.friends[l] {
+		if d := ix.dist(n.vec, ix.nodes[f].vec); d > worstDist {
+			worst, worstDist = i, d
+		}
+	}
+	if worst >= 0 {
+		n.friends[l][worst] = b
+	}
+}
+
+func equal(a, b []float32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+type candidate struct {
+	idx  int
+	dist float32
+}
+
+type minHeap []candidate
+
+func (h minHeap) Len() int            { return len(h) }
+func (h minHeap) Less(i, j int) bool  { return h[i].dist < h[j].dist }
+func (h minHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *minHeap) Push(x interface{}) { *h = append(*h, x.(candidate)) }
+func (h *minHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+type maxHeap []candidate
+
+func (h maxHeap) Len() int            { return len(h) }
+func (h maxHeap) Less(i, j int) bool  { return h[i].dist > h[j].dist }
+func (h maxHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *maxHeap) Push(x interface{}) { *h = append(*h, x.(candidate)) }
+func (h *maxHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
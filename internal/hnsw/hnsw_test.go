@@ -0,0 +1,250 @@
+package hnsw
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"mem0-go/internal/inmem"
+	"mem0-go/internal/vector"
+)
+
+func randomPoints(n, dim int, seed int64) []vector.Point {
+	rnd := rand.New(rand.NewSource(seed))
+	pts := make([]vector.Point, n)
+	for i := range pts {
+		v := make([]float32, dim)
+		for j := range v {
+			v[j] = rnd.Float32()*2 - 1
+		}
+		pts[i] = vector.Point{ID: fmt.Sprint(i), Vector: v}
+	}
+	return pts
+}
+
+// recall measures the fraction of brute-force top-k results the index finds.
+func recall(t testing.TB, d vector.Distance, n, dim, k, queries int) float64 {
+	ctx := context.Background()
+	pts := randomPoints(n, dim, 1)
+	exact := inmem.NewVectorWithDistance(d)
+	cfg := DefaultConfig()
+	cfg.Distance = d
+	s := NewStore(cfg)
+	if err := exact.Upsert(ctx, "c", pts); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if err := s.Upsert(ctx, "c", pts); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	hits, total := 0, 0
+	for _, q := range randomPoints(queries, dim, 2) {
+		want, _ := exact.Query(ctx, "c", q.Vector, k, nil)
+		got, _ := s.Query(ctx, "c", q.Vector, k, nil)
+		ids := map[string]bool{}
+		for _, r := range got {
+			ids[r.ID] = true
+		}
+		for _, r := range want {
+			if ids[r.ID] {
+				hits++
+			}
+			total++
+		}
+	}
+	return float64(hits) / float64(total)
+}
+
+func TestRecallVsBruteForce(t *testing.T) {
+	for _, d := range []vector.Distance{vector.Cosine, vector.Dot, vector.Euclid} {
+		if r := recall(t, d, 2000, 32, 10, 50); r < 0.9 {
+			t.Fatalf("%s: recall %.3f below 0.9", d, r)
+		}
+	}
+}
+
+func BenchmarkRecall(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		b.ReportMetric(recall(b, vector.Cosine, 10000, 64, 10, 100), "recall@10")
+	}
+}
+
+func BenchmarkQuery(b *testing.B) {
+	ctx := context.Background()
+	s := NewStore(DefaultConfig())
+	_ = s.Upsert(ctx, "c", randomPoints(10000, 64, 1))
+	qs := randomPoints(100, 64, 2)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = s.Query(ctx, "c", qs[i%len(qs)].Vector, 10, nil)
+	}
+}
+
+func TestUpsertDeleteAndCompact(t *testing.T) {
+	ctx := context.Background()
+	cfg := DefaultConfig()
+	cfg.CompactRatio = 0 // compact by hand below
+	s := NewStore(cfg)
+	_ = s.Upsert(ctx, "c", []vector.Point{
+		{ID: "a", Vector: []float32{1, 0}},
+		{ID: "b", Vector: []float32{0, 1}},
+		{ID: "c", Vector: []float32{-1, 0}},
+	})
+	if err := s.Upsert(ctx, "c", []vector.Point{{ID: "x", Vector: []float32{1, 2, 3}}}); err == nil {
+		t.Fatalf("expected dimension error")
+	}
+
+	// replacing "a" moves it away from the query
+	_ = s.Upsert(ctx, "c", []vector.Point{{ID: "a", Vector: []float32{0, -1}, Payload: map[string]interface{}{"v": 2}}})
+	res, _ := s.Query(ctx, "c", []float32{1, 0.1}, 0, nil)
+	if len(res) != 3 || res[0].ID != "b" || res[2].ID != "c" {
+		t.Fatalf("unexpected results: %+v", res)
+	}
+
+	_ = s.Delete(ctx, "c", []string{"b"})
+	ix := s.index("c")
+	if ix.Len() != 2 || len(ix.nodes) != 4 {
+		t.Fatalf("unexpected sizes live=%d nodes=%d", ix.Len(), len(ix.nodes))
+	}
+	ix.Compact()
+	if ix.Len() != 2 || len(ix.nodes) != 2 {
+		t.Fatalf("compact left %d nodes", len(ix.nodes))
+	}
+	res, _ = s.Query(ctx, "c", []float32{1, 0.1}, 5, nil)
+	if len(res) != 2 || res[1].ID != "c" || res[0].Payload["v"] != 2 {
+		t.Fatalf("unexpected results after compact: %+v", res)
+	}
+}
+
+func TestPersistence(t *testing.T) {
+	ctx := context.Background()
+	cfg := DefaultConfig()
+	cfg.Path = t.TempDir()
+	s, err := Open(cfg)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer func() { _ = s.Close() }()
+	pts := randomPoints(200, 8, 3)
+	pts[0].Payload = map[string]interface{}{"user_id": int64(7), "tags": []interface{}{"x"}}
+	_ = s.Upsert(ctx, "memories", pts)
+	_ = s.Delete(ctx, "memories", []string{"5"})
+	if err := s.Save(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	loaded, err := Open(cfg)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	for _, q := range pts[:20] {
+		want, _ := s.Query(ctx, "memories", q.Vector, 5, nil)
+		got, _ := loaded.Query(ctx, "memories", q.Vector, 5, nil)
+		if fmt.Sprint(want) != fmt.Sprint(got) {
+			t.Fatalf("results differ after reload:\n%v\n%v", want, got)
+		}
+	}
+	if loaded.index("memories").Len() != 199 {
+		t.Fatalf("unexpected live count %d", loaded.index("memories").Len())
+	}
+
+	var buf bytes.Buffer
+	if err := s.index("memories").Save(&buf); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if _, err := Load(bytes.NewReader(buf.Bytes()[:10]), cfg); err == nil {
+		t.Fatalf("expected error loading truncated index")
+	}
+}
+
+func TestFilteredSearch(t *testing.T) {
+	ctx := context.Background()
+	s := NewStore(DefaultConfig())
+	pts := randomPoints(1000, 16, 4)
+	for i := range pts {
+		pts[i].Payload = map[string]interface{}{"user_id": int64(i % 100)}
+	}
+	_ = s.Upsert(ctx, "c", pts)
+	f := &vector.Filter{Must: []vector.Condition{vector.MatchValue("user_id", 7)}}
+	res, _ := s.Query(ctx, "c", pts[7].Vector, 5, f)
+	if len(res) != 5 || res[0].ID != "7" {
+		t.Fatalf("unexpected results: %+v", res)
+	}
+	for _, r := range res {
+		if r.Payload["user_id"] != int64(7) {
+			t.Fatalf("filter not applied: %+v", r)
+		}
+	}
+}
+
+func TestAutoCompactAndBatchValidation(t *testing.T) {
+	ctx := context.Background()
+	s := NewStore(DefaultConfig())
+	_ = s.Upsert(ctx, "c", randomPoints(8, 4, 5))
+	_ = s.Delete(ctx, "c", []string{"0", "1"})
+	ix := s.index("c")
+	if len(ix.nodes) != 8 {
+		t.Fatalf("compacted below the threshold: %d nodes", len(ix.nodes))
+	}
+	_ = s.Delete(ctx, "c", []string{"2"})
+	if ix.Len() != 5 || len(ix.nodes) != 5 {
+		t.Fatalf("not compacted: live=%d nodes=%d", ix.Len(), len(ix.nodes))
+	}
+
+	batch := randomPoints(3, 4, 6)
+	batch[0].ID, batch[1].ID, batch[2].ID = "x", "y", "z"
+	batch[2].Vector = []float32{1, 2}
+	err := s.Upsert(ctx, "c", batch)
+	var de *vector.DimensionError
+	if !errors.As(err, &de) || de.ID != "z" || de.Collection != "c" {
+		t.Fatalf("expected dimension error for z, got %v", err)
+	}
+	if ix.Len() != 5 {
+		t.Fatalf("rejected batch was partly applied: %d live", ix.Len())
+	}
+	if _, err := s.Query(ctx, "c", []float32{1, 2}, 3, nil); !errors.As(err, &de) || de.Got != 2 || de.Want != 4 {
+		t.Fatalf("expected dimension error querying, got %v", err)
+	}
+	if res, err := s.Query(ctx, "empty", []float32{1, 2}, 3, nil); err != nil || len(res) != 0 {
+		t.Fatalf("query of an empty collection: %v, %v", res, err)
+	}
+
+	// only deletes that remove something need saving
+	s.dirty.Store(false)
+	_ = s.Delete(ctx, "c", []string{"missing", "0"})
+	if s.dirty.Load() {
+		t.Fatalf("deleting missing points marked the store changed")
+	}
+	_ = s.Delete(ctx, "c", []string{"3"})
+	if !s.dirty.Load() {
+		t.Fatalf("deleting a point did not mark the store changed")
+	}
+}
+
+func TestAutosave(t *testing.T) {
+	ctx := context.Background()
+	cfg := DefaultConfig()
+	cfg.Path = t.TempDir()
+	cfg.SaveInterval = 10 * time.Millisecond
+	s, err := Open(cfg)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer func() { _ = s.Close() }()
+	_ = s.Upsert(ctx, "memories", randomPoints(10, 4, 7))
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if ix, err := loadFile(filepath.Join(cfg.Path, "memories.hnsw"), cfg); err == nil && ix.Len() == 10 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("store was not saved in the background")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
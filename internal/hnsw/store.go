@@ -0,0 +1,296 @@
+package hnsw
+
+import (
+	"context"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"mem0-go/internal/vector"
+)
+
+func init() {
+	// payload values decoded from JSON
+	gob.Register(map[string]interface{}{})
+	gob.Register([]interface{}{})
+}
+
+// Store implements the vector store contract used by memory.Service with
+// one HNSW index per collection.
+type Store struct {
+	// Logger reports failed background saves; slog.Default when nil.
+	Logger *slog.Logger
+
+	mu          sync.Mutex
+	cfg         Config
+	collections map[string]*Index
+	dirty       atomic.Bool
+	stop        chan struct{}
+	stopped     chan struct{}
+}
+
+// NewStore returns an empty in-memory Store.
+func NewStore(cfg Config) *Store {
+	return &Store{cfg: cfg, collections: make(map[string]*Index)}
+}
+
+// Open returns a Store loaded from cfg.Path, creating the directory if
+// needed. Every *.hnsw file in it is loaded as a collection. Collections
+// changed since the last save are saved every cfg.SaveInterval until
+// Close, so a crash loses at most that much.
+func Open(cfg Config) (*Store, error) {
+	s := NewStore(cfg)
+	if cfg.Path == "" {
+		return s, nil
+	}
+	if err := os.MkdirAll(cfg.Path, 0o755); err != nil {
+		return nil, err
+	}
+	files, err := filepath.Glob(filepath.Join(cfg.Path, "*.hnsw"))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		ix, err := loadFile(f, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("hnsw: load %s: %w", f, err)
+		}
+		s.collections[strings.TrimSuffix(filepath.Base(f), ".hnsw")] = ix
+	}
+	if cfg.SaveInterval > 0 {
+		s.stop, s.stopped = make(chan struct{}), make(chan struct{})
+		go s.autosave(cfg.SaveInterval)
+	}
+	return s, nil
+}
+
+// autosave saves the store every interval when it changed, until Close.
+func (s *Store) autosave(interval time.Duration) {
+	defer close(s.stopped)
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-t.C:
+			if !s.dirty.Load() {
+				continue
+			}
+			if err := s.Save(); err != nil {
+				logger := s.Logger
+				if logger == nil {
+					logger = slog.Default()
+				}
+				logger.Warn("hnsw save failed", "path", s.cfg.Path, "err", err)
+			}
+		}
+	}
+}
+
+// Close stops saving in the background and saves the store a last time.
+func (s *Store) Close() error {
+	if s.stop != nil {
+		close(s.stop)
+		<-s.stopped
+		s.stop = nil
+	}
+	return s.Save()
+}
+
+func (s *Store) index(collection string) *Index {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ix, ok := s.collections[collection]
+	if !ok {
+		ix = New(s.cfg)
+		s.collections[collection] = ix
+	}
+	return ix
+}
+
+// Upsert inserts pts, replacing existing points with the same ID. A batch
+// with a vector of the wrong size is rejected whole.
+func (s *Store) Upsert(_ context.Context, collection string, pts []vector.Point) error {
+	ix := s.index(collection)
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	want := ix.dim
+	for _, p := range pts {
+		if want == 0 {
+			want = len(p.Vector)
+		}
+		if len(p.Vector) != want {
+			return &vector.DimensionError{Collection: collection, ID: p.ID, Got: len(p.Vector), Want: want}
+		}
+	}
+	for _, p := range pts {
+		if err := ix.add(p); err != nil {
+			return err
+		}
+	}
+	if len(pts) > 0 {
+		s.dirty.Store(true)
+		ix.compactIfStale()
+	}
+	return nil
+}
+
+// Query returns up to limit points nearest to vec whose payload matches
+// filter, best first. A limit <= 0 returns every matching point. A vector
+// of the wrong size for the collection is a *vector.DimensionError.
+func (s *Store) Query(_ context.Context, collection string, vec []float32, limit int, filter *vector.Filter) ([]vector.QueryResult, error) {
+	ix := s.index(collection)
+	ix.mu.RLock()
+	dim := ix.dim
+	ix.mu.RUnlock()
+	if dim != 0 && len(vec) != dim {
+		return nil, &vector.DimensionError{Collection: collection, Got: len(vec), Want: dim}
+	}
+	if limit <= 0 {
+		limit = ix.Len()
+	}
+	res := ix.Search(vec, limit, filter)
+	if res == nil {
+		res = []vector.QueryResult{}
+	}
+	return res, nil
+}
+
+// Scroll pages through the live points of a collection in ID order. Cosine
+// vectors are returned normalised.
+func (s *Store) Scroll(_ context.Context, collection, offset string, limit int) ([]vector.Point, string, error) {
+	ix := s.index(collection)
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	ids := make([]string, 0, len(ix.ids))
+	for id := range ix.ids {
+		ids = append(ids, id)
+	}
+	page, next := vector.PageIDs(ids, offset, limit)
+	out := make([]vector.Point, len(page))
+	for i, id := range page {
+		n := ix.nodes[ix.ids[id]]
+		out[i] = vector.Point{ID: n.id, Vector: n.vec, Payload: n.payload}
+	}
+	return out, next, nil
+}
+
+// Delete removes points by ID.
+func (s *Store) Delete(_ context.Context, collection string, ids []string) error {
+	ix := s.index(collection)
+	for _, id := range ids {
+		if ix.Delete(id) {
+			s.dirty.Store(true)
+		}
+	}
+	return nil
+}
+
+// Save writes every collection to cfg.Path. It is a no-op when
+// persistence is disabled.
+func (s *Store) Save() error {
+	if s.cfg.Path == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dirty.Store(false)
+	for name, ix := range s.collections {
+		if err := saveFile(filepath.Join(s.cfg.Path, name+".hnsw"), ix); err != nil {
+			s.dirty.Store(true)
+			return err
+		}
+	}
+	return nil
+}
+
+// snapshot is the gob-encoded form of an Index.
+type snapshot struct {
+	Distance vector.Distance
+	M        int
+	Entry    int
+	MaxLevel int
+	Dim      int
+	Nodes    []snapshotNode
+}
+
+type snapshotNode struct {
+	ID      string
+	Vector  []float32
+	Payload map[string]interface{}
+	Friends [][]int
+	Deleted bool
+}
+
+// Save writes the index to w.
+func (ix *Index) Save(w io.Writer) error {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	snap := snapshot{
+		Distance: ix.cfg.Distance,
+		M:        ix.cfg.M,
+		Entry:    ix.entry,
+		MaxLevel: ix.maxLevel,
+		Dim:      ix.dim,
+		Nodes:    make([]snapshotNode, len(ix.nodes)),
+	}
+	for i, n := range ix.nodes {
+		snap.Nodes[i] = snapshotNode{ID: n.id, Vector: n.vec, Payload: n.payload, Friends: n.friends, Deleted: n.deleted}
+	}
+	return gob.NewEncoder(w).Encode(snap)
+}
+
+// Load reads an index written by Save. The distance and M recorded in the
+// file override cfg since the graph was built with them.
+func Load(r io.Reader, cfg Config) (*Index, error) {
+	var snap snapshot
+	if err := gob.NewDecoder(r).Decode(&snap); err != nil {
+		return nil, err
+	}
+	cfg.Distance, cfg.M = snap.Distance, snap.M
+	ix := New(cfg)
+	ix.entry, ix.maxLevel, ix.dim = snap.Entry, snap.MaxLevel, snap.Dim
+	ix.nodes = make([]*node, len(snap.Nodes))
+	for i, n := range snap.Nodes {
+		ix.nodes[i] = &node{id: n.ID, vec: n.Vector, payload: n.Payload, friends: n.Friends, deleted: n.Deleted}
+		if !n.Deleted {
+			ix.ids[n.ID] = i
+			ix.live++
+		}
+	}
+	return ix, nil
+}
+
+// saveFile writes ix to path atomically via a temporary file.
+func saveFile(path string, ix *Index) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".hnsw-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if err := ix.Save(tmp); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func loadFile(path string, cfg Config) (*Index, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return Load(f, cfg)
+}
@@ -0,0 +1,103 @@
+package inmem
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// BM25 parameters: term frequency saturation and length normalisation.
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// bm25Index is an inverted index over memory content scored with Okapi
+// BM25. Terms are the lower-cased runs of letters and digits.
+type bm25Index struct {
+	postings map[string]map[int64]int // term -> memory -> occurrences
+	lengths  map[int64]int
+	total    int
+}
+
+func newBM25Index() *bm25Index {
+	return &bm25Index{postings: map[string]map[int64]int{}, lengths: map[int64]int{}}
+}
+
+// tokenize splits text into index terms.
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// add indexes text as the content of memory id, replacing what it had.
+func (x *bm25Index) add(id int64, text string) {
+	x.remove(id)
+	terms := tokenize(text)
+	for _, t := range terms {
+		if x.postings[t] == nil {
+			x.postings[t] = map[int64]int{}
+		}
+		x.postings[t][id]++
+	}
+	x.lengths[id] = len(terms)
+	x.total += len(terms)
+}
+
+// remove drops memory id from the index.
+func (x *bm25Index) remove(id int64) {
+	n, ok := x.lengths[id]
+	if !ok {
+		return
+	}
+	for t, docs := range x.postings {
+		delete(docs, id)
+		if len(docs) == 0 {
+			delete(x.postings, t)
+		}
+	}
+	delete(x.lengths, id)
+	x.total -= n
+}
+
+// search scores every memory containing a term of query.
+func (x *bm25Index) search(query string) map[int64]float64 {
+	scores := map[int64]float64{}
+	if len(x.lengths) == 0 {
+		return scores
+	}
+	n := float64(len(x.lengths))
+	avg := float64(x.total) / n
+	seen := map[string]bool{}
+	for _, t := range tokenize(query) {
+		docs := x.postings[t]
+		if seen[t] || len(docs) == 0 {
+			continue
+		}
+		seen[t] = true
+		df := float64(len(docs))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for id, tf := range docs {
+			f := float64(tf)
+			norm := 1 - bm25B + bm25B*float64(x.lengths[id])/avg
+			scores[id] += idf * f * (bm25K1 + 1) / (f + bm25K1*norm)
+		}
+	}
+	return scores
+}
+
+// clone returns a deep copy of x.
+func (x *bm25Index) clone() *bm25Index {
+	c := &bm25Index{postings: make(map[string]map[int64]int, len(x.postings)), lengths: make(map[int64]int, len(x.lengths)), total: x.total}
+	for t, docs := range x.postings {
+		c.postings[t] = make(map[int64]int, len(docs))
+		for id, n := range docs {
+			c.postings[t][id] = n
+		}
+	}
+	for id, n := range x.lengths {
+		c.lengths[id] = n
+	}
+	return c
+}
@@ -3,63 +3,655 @@ package inmem
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"mem0-go/internal/db"
 	"mem0-go/internal/graph"
 	"mem0-go/internal/vector"
 )
 
-// Repo implements db.Repository using memory.
-// Ensure it satisfies the interface.
-var _ db.Repository = (*Repo)(nil)
+// Repo implements db.TxRepository, db.APIKeys, db.Tenants, db.Roles and
+// db.TextSearcher using memory.
+// Ensure it satisfies the interfaces.
+var (
+	_ db.TxRepository = (*Repo)(nil)
+	_ db.APIKeys      = (*Repo)(nil)
+	_ db.Tenants      = (*Repo)(nil)
+	_ db.Roles        = (*Repo)(nil)
+	_ db.TextSearcher = (*Repo)(nil)
+)
 
 type Repo struct {
-	users      []string
-	memories   []string
-	embeddings [][]float32
+	mu   sync.Mutex
+	txMu sync.Mutex
+	repoState
+}
+
+// repoState is the data a transaction can roll back.
+type repoState struct {
+	users      []db.User
+	memories   map[int64]db.Memory
+	embeddings map[int64][]float32
+	text       *bm25Index
+	history    []db.HistoryEntry
+	outbox     []outboxEntry
+	keys       []db.APIKey
+	orgs       []db.Organization
+	projects   []db.Project
+	roles      []db.Role
+	bindings   []db.RoleBinding
+	next       int64
+	nextRole   int64
+	nextBind   int64
+}
+
+// outboxEntry is an outbox event with its delivery state.
+type outboxEntry struct {
+	db.OutboxEvent
+	done        bool
+	failed      bool
+	availableAt time.Time
+}
+
+func NewRepo() *Repo {
+	return &Repo{repoState: repoState{memories: make(map[int64]db.Memory), embeddings: make(map[int64][]float32), text: newBM25Index()}}
 }
 
-func NewRepo() *Repo { return &Repo{} }
+func (s repoState) clone() repoState {
+	c := s
+	c.users = append([]db.User(nil), s.users...)
+	c.memories = make(map[int64]db.Memory, len(s.memories))
+	for k, v := range s.memories {
+		c.memories[k] = v
+	}
+	c.embeddings = make(map[int64][]float32, len(s.embeddings))
+	for k, v := range s.embeddings {
+		c.embeddings[k] = v
+	}
+	c.text = s.text.clone()
+	c.history = append([]db.HistoryEntry(nil), s.history...)
+	c.outbox = append([]outboxEntry(nil), s.outbox...)
+	c.keys = append([]db.APIKey(nil), s.keys...)
+	c.orgs = append([]db.Organization(nil), s.orgs...)
+	c.projects = append([]db.Project(nil), s.projects...)
+	c.roles = append([]db.Role(nil), s.roles...)
+	c.bindings = append([]db.RoleBinding(nil), s.bindings...)
+	return c
+}
+
+// repoTx is the repository handed to an InTx callback.
+type repoTx struct{ *Repo }
+
+// InTx runs fn in the same transaction.
+func (t repoTx) InTx(ctx context.Context, fn func(db.TxRepository) error) error { return fn(t) }
+
+// InTx runs fn against r and restores r's previous state if fn fails.
+// Transactions are serialised with each other, but their writes are visible
+// to concurrent readers before fn returns.
+func (r *Repo) InTx(ctx context.Context, fn func(db.TxRepository) error) error {
+	r.txMu.Lock()
+	defer r.txMu.Unlock()
+	r.mu.Lock()
+	snap := r.repoState.clone()
+	r.mu.Unlock()
+	if err := fn(repoTx{r}); err != nil {
+		r.mu.Lock()
+		r.repoState = snap
+		r.mu.Unlock()
+		return err
+	}
+	return nil
+}
 
 func (r *Repo) CreateUser(ctx context.Context, username string) (int64, error) {
-	r.users = append(r.users, username)
-	return int64(len(r.users)), nil
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	id := int64(len(r.users)) + 1
+	r.users = append(r.users, db.User{ID: id, Username: username, CreatedAt: time.Now().UTC().Format(time.RFC3339)})
+	return id, nil
 }
 
-func (r *Repo) CreateMemory(ctx context.Context, userID int64, content string) (int64, error) {
-	r.memories = append(r.memories, content)
-	return int64(len(r.memories)), nil
+func (r *Repo) GetUser(ctx context.Context, id int64) (db.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if id <= 0 || id > int64(len(r.users)) {
+		return db.User{}, db.ErrNotFound
+	}
+	return r.users[id-1], nil
+}
+
+func (r *Repo) CreateMemory(ctx context.Context, m db.Memory) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.next++
+	m.ID = r.next
+	if m.CreatedAt == "" {
+		m.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	}
+	r.memories[m.ID] = m
+	r.text.add(m.ID, m.Content)
+	return m.ID, nil
 }
 
 func (r *Repo) AddEmbedding(ctx context.Context, memoryID int64, vec []float32) error {
-	r.embeddings = append(r.embeddings, vec)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.embeddings[memoryID] = vec
 	return nil
 }
 
+func (r *Repo) GetEmbedding(ctx context.Context, memoryID int64) ([]float32, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	vec, ok := r.embeddings[memoryID]
+	if !ok {
+		return nil, db.ErrNotFound
+	}
+	return vec, nil
+}
+
 func (r *Repo) GetMemory(ctx context.Context, id int64) (db.Memory, error) {
-	if int(id) <= 0 || int(id) > len(r.memories) {
-		return db.Memory{}, fmt.Errorf("not found")
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m, ok := r.memories[id]
+	if !ok {
+		return db.Memory{}, db.ErrNotFound
+	}
+	return m, nil
+}
+
+func (r *Repo) ListMemories(ctx context.Context, q db.MemoryQuery) ([]db.Memory, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	after := db.Memory{ID: q.AfterID, CreatedAt: q.AfterCreatedAt}
+	out := []db.Memory{}
+	for _, m := range r.memories {
+		if matchMemory(q, m) && (q.AfterID == 0 || memoryLess(q, after, m)) {
+			out = append(out, m)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return memoryLess(q, out[i], out[j]) })
+	if q.Limit > 0 && len(out) > q.Limit {
+		out = out[:q.Limit]
+	}
+	return out, nil
+}
+
+// matchMemory reports whether m passes the filters of q.
+func matchMemory(q db.MemoryQuery, m db.Memory) bool {
+	created := createdAt(m.CreatedAt)
+	switch {
+	case q.UserID != 0 && m.UserID != q.UserID,
+		q.ProjectID != nil && m.ProjectID != *q.ProjectID,
+		q.AgentID != "" && m.AgentID != q.AgentID,
+		q.RunID != "" && m.RunID != q.RunID,
+		q.Tag != "" && !hasTag(m.Tags, q.Tag),
+		!q.CreatedAfter.IsZero() && created.Before(q.CreatedAfter),
+		!q.CreatedBefore.IsZero() && !created.Before(q.CreatedBefore),
+		q.Contains != "" && !strings.Contains(strings.ToLower(m.Content), strings.ToLower(q.Contains)):
+		return false
+	}
+	return true
+}
+
+// memoryLess reports whether a comes before b in the sort order of q.
+func memoryLess(q db.MemoryQuery, a, b db.Memory) bool {
+	if q.Sort == db.SortByCreatedAt {
+		if ta, tb := createdAt(a.CreatedAt), createdAt(b.CreatedAt); !ta.Equal(tb) {
+			return ta.Before(tb) != q.Desc
+		}
+	}
+	if q.Desc {
+		return a.ID > b.ID
+	}
+	return a.ID < b.ID
+}
+
+// createdAt parses an RFC 3339 creation time, the zero time if invalid.
+func createdAt(s string) time.Time {
+	t, _ := time.Parse(time.RFC3339, s)
+	return t
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// SearchText ranks memories with an in-process BM25 index.
+func (r *Repo) SearchText(ctx context.Context, q db.TextQuery) ([]db.TextMatch, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := []db.TextMatch{}
+	for id, score := range r.text.search(q.Query) {
+		m := r.memories[id]
+		switch {
+		case m.ProjectID != q.ProjectID,
+			q.UserID != 0 && m.UserID != q.UserID,
+			q.AgentID != "" && m.AgentID != q.AgentID,
+			q.RunID != "" && m.RunID != q.RunID,
+			len(q.Tags) > 0 && !hasAnyTag(m.Tags, q.Tags):
+			continue
+		}
+		out = append(out, db.TextMatch{MemoryID: id, Score: float32(score)})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Score != out[j].Score {
+			return out[i].Score > out[j].Score
+		}
+		return out[i].MemoryID < out[j].MemoryID
+	})
+	if q.Offset >= len(out) {
+		return []db.TextMatch{}, nil
+	}
+	out = out[q.Offset:]
+	if q.Limit > 0 && len(out) > q.Limit {
+		out = out[:q.Limit]
+	}
+	return out, nil
+}
+
+func hasAnyTag(tags, want []string) bool {
+	for _, t := range want {
+		if hasTag(tags, t) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Repo) UpdateMemory(ctx context.Context, m db.Memory) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cur, ok := r.memories[m.ID]
+	if !ok {
+		return db.ErrNotFound
+	}
+	cur.Content, cur.Tags, cur.Metadata = m.Content, m.Tags, m.Metadata
+	r.memories[m.ID] = cur
+	r.text.add(m.ID, m.Content)
+	return nil
+}
+
+func (r *Repo) DeleteMemory(ctx context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.memories[id]; !ok {
+		return db.ErrNotFound
+	}
+	delete(r.memories, id)
+	delete(r.embeddings, id)
+	r.text.remove(id)
+	return nil
+}
+
+func (r *Repo) AddHistory(ctx context.Context, h db.HistoryEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	h.ID = int64(len(r.history) + 1)
+	if h.CreatedAt == "" {
+		h.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	}
+	r.history = append(r.history, h)
+	return nil
+}
+
+func (r *Repo) ListHistory(ctx context.Context, memoryID int64) ([]db.HistoryEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []db.HistoryEntry
+	for _, h := range r.history {
+		if h.MemoryID == memoryID {
+			out = append(out, h)
+		}
+	}
+	return out, nil
+}
+
+func (r *Repo) AddOutbox(ctx context.Context, e db.OutboxEvent) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e.ID = int64(len(r.outbox) + 1)
+	now := time.Now().UTC()
+	if e.CreatedAt == "" {
+		e.CreatedAt = now.Format(time.RFC3339)
+	}
+	r.outbox = append(r.outbox, outboxEntry{OutboxEvent: e, availableAt: now})
+	return e.ID, nil
+}
+
+func (r *Repo) DueOutbox(ctx context.Context, now time.Time, limit int) ([]db.OutboxEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []db.OutboxEvent
+	for _, e := range r.outbox {
+		if limit > 0 && len(out) == limit {
+			break
+		}
+		if !e.done && !e.failed && !e.availableAt.After(now) {
+			out = append(out, e.OutboxEvent)
+		}
+	}
+	return out, nil
+}
+
+func (r *Repo) CompleteOutbox(ctx context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if id <= 0 || int(id) > len(r.outbox) {
+		return db.ErrNotFound
+	}
+	r.outbox[id-1].done = true
+	return nil
+}
+
+func (r *Repo) FailOutbox(ctx context.Context, id int64, errMsg string, retryAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if id <= 0 || int(id) > len(r.outbox) {
+		return db.ErrNotFound
+	}
+	e := &r.outbox[id-1]
+	e.Attempts++
+	e.LastError = errMsg
+	if retryAt.IsZero() {
+		e.failed = true
+	} else {
+		e.availableAt = retryAt
+	}
+	return nil
+}
+
+func (r *Repo) CreateAPIKey(ctx context.Context, k db.APIKey) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range r.keys {
+		if e.Hash == k.Hash {
+			return 0, fmt.Errorf("inmem: duplicate api key hash")
+		}
+	}
+	k.ID = int64(len(r.keys) + 1)
+	k.Scopes = append([]string{}, k.Scopes...)
+	k.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	k.RevokedAt = ""
+	r.keys = append(r.keys, k)
+	return k.ID, nil
+}
+
+func (r *Repo) APIKeyByHash(ctx context.Context, hash string) (db.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, k := range r.keys {
+		if k.Hash == hash && k.RevokedAt == "" {
+			return k, nil
+		}
+	}
+	return db.APIKey{}, db.ErrNotFound
+}
+
+func (r *Repo) ListAPIKeys(ctx context.Context, userID int64) ([]db.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := []db.APIKey{}
+	for _, k := range r.keys {
+		if userID == 0 || k.UserID == userID {
+			out = append(out, k)
+		}
+	}
+	return out, nil
+}
+
+func (r *Repo) RevokeAPIKey(ctx context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if id <= 0 || id > int64(len(r.keys)) {
+		return db.ErrNotFound
+	}
+	if r.keys[id-1].RevokedAt == "" {
+		r.keys[id-1].RevokedAt = time.Now().UTC().Format(time.RFC3339)
+	}
+	return nil
+}
+
+func (r *Repo) CreateOrganization(ctx context.Context, name string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, o := range r.orgs {
+		if o.Name == name {
+			return 0, fmt.Errorf("inmem: duplicate organization %q", name)
+		}
+	}
+	id := int64(len(r.orgs) + 1)
+	r.orgs = append(r.orgs, db.Organization{ID: id, Name: name, CreatedAt: time.Now().UTC().Format(time.RFC3339)})
+	return id, nil
+}
+
+func (r *Repo) GetOrganization(ctx context.Context, id int64) (db.Organization, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if id <= 0 || id > int64(len(r.orgs)) {
+		return db.Organization{}, db.ErrNotFound
+	}
+	return r.orgs[id-1], nil
+}
+
+func (r *Repo) ListOrganizations(ctx context.Context) ([]db.Organization, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]db.Organization{}, r.orgs...), nil
+}
+
+func (r *Repo) CreateProject(ctx context.Context, p db.Project) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if p.OrgID <= 0 || p.OrgID > int64(len(r.orgs)) {
+		return 0, fmt.Errorf("inmem: organization %d does not exist", p.OrgID)
+	}
+	for _, e := range r.projects {
+		if e.OrgID == p.OrgID && e.Name == p.Name {
+			return 0, fmt.Errorf("inmem: duplicate project %q", p.Name)
+		}
+	}
+	p.ID = int64(len(r.projects) + 1)
+	p.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	r.projects = append(r.projects, p)
+	return p.ID, nil
+}
+
+func (r *Repo) GetProject(ctx context.Context, id int64) (db.Project, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if id <= 0 || id > int64(len(r.projects)) {
+		return db.Project{}, db.ErrNotFound
 	}
-	return db.Memory{ID: id, UserID: 1, Content: r.memories[id-1]}, nil
+	return r.projects[id-1], nil
 }
 
-// Vector implements vectorStore using memory.
-type Vector struct{ points []vector.Point }
+func (r *Repo) ListProjects(ctx context.Context, orgID int64) ([]db.Project, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := []db.Project{}
+	for _, p := range r.projects {
+		if p.OrgID == orgID {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
 
-func NewVector() *Vector { return &Vector{} }
+func (r *Repo) CreateRole(ctx context.Context, role db.Role) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range r.roles {
+		if e.OrgID == role.OrgID && e.ProjectID == role.ProjectID && e.Name == role.Name {
+			return 0, fmt.Errorf("inmem: duplicate role %q", role.Name)
+		}
+	}
+	r.nextRole++
+	role.ID = r.nextRole
+	role.Permissions = append([]string{}, role.Permissions...)
+	role.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	r.roles = append(r.roles, role)
+	return role.ID, nil
+}
 
+func (r *Repo) ListRoles(ctx context.Context, orgID int64) ([]db.Role, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := []db.Role{}
+	for _, role := range r.roles {
+		if role.OrgID == orgID {
+			out = append(out, role)
+		}
+	}
+	return out, nil
+}
+
+func (r *Repo) DeleteRole(ctx context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, role := range r.roles {
+		if role.ID != id {
+			continue
+		}
+		r.roles = append(r.roles[:i:i], r.roles[i+1:]...)
+		kept := r.bindings[:0:0]
+		for _, b := range r.bindings {
+			if b.Role != role.Name || b.OrgID != role.OrgID || (role.ProjectID != 0 && b.ProjectID != role.ProjectID) {
+				kept = append(kept, b)
+			}
+		}
+		r.bindings = kept
+		return nil
+	}
+	return db.ErrNotFound
+}
+
+func (r *Repo) CreateRoleBinding(ctx context.Context, b db.RoleBinding) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range r.bindings {
+		if e.UserID == b.UserID && e.OrgID == b.OrgID && e.ProjectID == b.ProjectID && e.Role == b.Role {
+			return 0, fmt.Errorf("inmem: duplicate role binding")
+		}
+	}
+	r.nextBind++
+	b.ID = r.nextBind
+	b.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	r.bindings = append(r.bindings, b)
+	return b.ID, nil
+}
+
+func (r *Repo) ListRoleBindings(ctx context.Context, userID int64) ([]db.RoleBinding, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := []db.RoleBinding{}
+	for _, b := range r.bindings {
+		if userID == 0 || b.UserID == userID {
+			out = append(out, b)
+		}
+	}
+	return out, nil
+}
+
+func (r *Repo) DeleteRoleBinding(ctx context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, b := range r.bindings {
+		if b.ID == id {
+			r.bindings = append(r.bindings[:i:i], r.bindings[i+1:]...)
+			return nil
+		}
+	}
+	return db.ErrNotFound
+}
+
+// Vector implements vectorStore using memory. Points are kept per
+// collection and scored by brute force with the configured distance.
+type Vector struct {
+	mu          sync.RWMutex
+	distance    vector.Distance
+	collections map[string]map[string]vector.Point
+}
+
+// NewVector returns a Vector scoring by cosine similarity.
+func NewVector() *Vector { return NewVectorWithDistance(vector.Cosine) }
+
+// NewVectorWithDistance returns a Vector scoring with d.
+func NewVectorWithDistance(d vector.Distance) *Vector {
+	return &Vector{distance: d, collections: make(map[string]map[string]vector.Point)}
+}
+
+// Upsert stores pts, replacing any existing points with the same ID.
 func (v *Vector) Upsert(ctx context.Context, collection string, pts []vector.Point) error {
-	v.points = append(v.points, pts...)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	col, ok := v.collections[collection]
+	if !ok {
+		col = make(map[string]vector.Point)
+		v.collections[collection] = col
+	}
+	for _, p := range pts {
+		col[p.ID] = p
+	}
 	return nil
 }
 
-func (v *Vector) Query(ctx context.Context, collection string, vec []float32, limit int) ([]vector.QueryResult, error) {
+// Delete removes points by ID.
+func (v *Vector) Delete(ctx context.Context, collection string, ids []string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for _, id := range ids {
+		delete(v.collections[collection], id)
+	}
+	return nil
+}
+
+// Scroll pages through the points of a collection in ID order.
+func (v *Vector) Scroll(ctx context.Context, collection, offset string, limit int) ([]vector.Point, string, error) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	col := v.collections[collection]
+	ids := make([]string, 0, len(col))
+	for id := range col {
+		ids = append(ids, id)
+	}
+	page, next := vector.PageIDs(ids, offset, limit)
+	out := make([]vector.Point, len(page))
+	for i, id := range page {
+		out[i] = col[id]
+	}
+	return out, next, nil
+}
+
+// Query returns up to limit points nearest to vec whose payload matches
+// filter, best first. Points whose dimension differs from vec are skipped.
+// A limit <= 0 returns every match.
+func (v *Vector) Query(ctx context.Context, collection string, vec []float32, limit int, filter *vector.Filter) ([]vector.QueryResult, error) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
 	out := []vector.QueryResult{}
-	for i, p := range v.points {
-		if i >= limit {
-			break
+	for _, p := range v.collections[collection] {
+		if len(p.Vector) != len(vec) || !filter.Matches(p.Payload) {
+			continue
 		}
-		out = append(out, vector.QueryResult{ID: p.ID, Score: 1})
+		out = append(out, vector.QueryResult{ID: p.ID, Score: v.distance.Score(vec, p.Vector), Payload: p.Payload})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Score != out[j].Score {
+			return v.distance.Better(out[i].Score, out[j].Score)
+		}
+		return out[i].ID < out[j].ID
+	})
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
 	}
 	return out, nil
 }
@@ -67,6 +659,7 @@ func (v *Vector) Query(ctx context.Context, collection string, vec []float32, li
 // Graph implements graphStore using in-memory structures.
 // We reuse graph.Node and graph.Edge types.
 type Graph struct {
+	mu    sync.RWMutex
 	nodes map[string]graph.Node
 	edges []graph.Edge
 	next  int
@@ -75,6 +668,8 @@ type Graph struct {
 func NewGraph() *Graph { return &Graph{nodes: make(map[string]graph.Node)} }
 
 func (g *Graph) CreateNode(_ context.Context, label string, props map[string]interface{}) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
 	g.next++
 	id := fmt.Sprintf("n%d", g.next)
 	g.nodes[id] = graph.Node{ID: id, Label: label, Props: props}
@@ -82,6 +677,8 @@ func (g *Graph) CreateNode(_ context.Context, label string, props map[string]int
 }
 
 func (g *Graph) CreateEdge(_ context.Context, from, to, relType string, props map[string]interface{}) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
 	g.next++
 	id := fmt.Sprintf("e%d", g.next)
 	g.edges = append(g.edges, graph.Edge{ID: id, From: from, To: to, Type: relType, Props: props})
@@ -89,6 +686,8 @@ func (g *Graph) CreateEdge(_ context.Context, from, to, relType string, props ma
 }
 
 func (g *Graph) Neighbors(_ context.Context, id, relType string) ([]graph.Node, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
 	var out []graph.Node
 	for _, e := range g.edges {
 		if e.Type == relType && e.From == id {
@@ -99,3 +698,108 @@ func (g *Graph) Neighbors(_ context.Context, id, relType string) ([]graph.Node,
 	}
 	return out, nil
 }
+
+func (g *Graph) FindNodes(_ context.Context, label string, props map[string]interface{}) ([]graph.Node, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	var out []graph.Node
+	for _, n := range g.nodes {
+		if graph.NodeMatches(n, label, props) {
+			out = append(out, n)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+func (g *Graph) UpdateNode(_ context.Context, id string, props map[string]interface{}) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	n, ok := g.nodes[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", graph.ErrNodeNotFound, id)
+	}
+	n.Props = graph.MergeProps(n.Props, props)
+	g.nodes[id] = n
+	return nil
+}
+
+func (g *Graph) Edges(_ context.Context) ([]graph.Edge, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return append([]graph.Edge(nil), g.edges...), nil
+}
+
+func (g *Graph) Edge(_ context.Context, id string) (graph.Edge, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	for _, e := range g.edges {
+		if e.ID == id {
+			return e, nil
+		}
+	}
+	return graph.Edge{}, fmt.Errorf("%w: %s", graph.ErrEdgeNotFound, id)
+}
+
+func (g *Graph) FindEdges(_ context.Context, q graph.EdgeQuery) ([]graph.Edge, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	var out []graph.Edge
+	for _, e := range g.edges {
+		if graph.EdgeMatches(e, q) {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func (g *Graph) UpdateEdge(_ context.Context, id string, props map[string]interface{}) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for i, e := range g.edges {
+		if e.ID == id {
+			g.edges[i].Props = graph.MergeProps(e.Props, props)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", graph.ErrEdgeNotFound, id)
+}
+
+func (g *Graph) DeleteEdge(_ context.Context, id string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.edges = graph.RemoveEdge(g.edges, id)
+	return nil
+}
+
+func (g *Graph) DeleteNode(_ context.Context, id string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.nodes, id)
+	g.edges = graph.DetachEdges(g.edges, id)
+	return nil
+}
+
+func (g *Graph) Nodes(_ context.Context, ids []string) ([]graph.Node, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return graph.NodesByID(g.nodes, ids), nil
+}
+
+func (g *Graph) Expand(_ context.Context, ids []string, dir graph.Direction, types []string) (map[string][]graph.Hop, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return graph.ExpandEdges(g.nodes, g.edges, ids, dir, types), nil
+}
+
+func (g *Graph) Traverse(ctx context.Context, q graph.TraversalQuery) ([]graph.Visit, error) {
+	return graph.Walk(ctx, g, q)
+}
+
+func (g *Graph) ShortestPath(ctx context.Context, from, to string, e graph.Expansion, maxDepth int) (*graph.Path, error) {
+	return graph.FindPath(ctx, g, from, to, e, maxDepth)
+}
+
+func (g *Graph) Subgraph(ctx context.Context, center string, e graph.Expansion, depth int) (graph.Subgraph, error) {
+	return graph.Extract(ctx, g, center, e, depth)
+}
@@ -0,0 +1,125 @@
+package inmem
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"mem0-go/internal/db"
+	"mem0-go/internal/vector"
+)
+
+func TestVectorQuery(t *testing.T) {
+	ctx := context.Background()
+	v := NewVector()
+	_ = v.Upsert(ctx, "a", []vector.Point{
+		{ID: "x", Vector: []float32{1, 0}},
+		{ID: "y", Vector: []float32{0, 1}},
+		{ID: "z", Vector: []float32{1, 1}, Payload: map[string]interface{}{"k": "v"}},
+		{ID: "short", Vector: []float32{1}},
+	})
+	_ = v.Upsert(ctx, "b", []vector.Point{{ID: "other", Vector: []float32{1, 0}}})
+
+	res, err := v.Query(ctx, "a", []float32{1, 0.1}, 2, nil)
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if len(res) != 2 || res[0].ID != "x" || res[1].ID != "z" || res[1].Payload["k"] != "v" {
+		t.Fatalf("unexpected results: %+v", res)
+	}
+	if res[0].Score <= res[1].Score || res[0].Score > 1 {
+		t.Fatalf("unexpected scores: %+v", res)
+	}
+
+	// upsert replaces by ID
+	_ = v.Upsert(ctx, "a", []vector.Point{{ID: "x", Vector: []float32{-1, 0}}})
+	res, _ = v.Query(ctx, "a", []float32{1, 0.1}, 0, nil)
+	if len(res) != 3 || res[2].ID != "x" {
+		t.Fatalf("unexpected results after replace: %+v", res)
+	}
+
+	_ = v.Delete(ctx, "a", []string{"z"})
+	res, _ = v.Query(ctx, "a", []float32{1, 0.1}, 0, nil)
+	if len(res) != 2 {
+		t.Fatalf("delete failed: %+v", res)
+	}
+}
+
+func TestVectorDistances(t *testing.T) {
+	ctx := context.Background()
+	pts := []vector.Point{
+		{ID: "near", Vector: []float32{1, 1}},
+		{ID: "far", Vector: []float32{10, 10}},
+	}
+	for _, c := range []struct {
+		d     vector.Distance
+		first string
+	}{
+		{vector.Euclid, "near"},
+		{vector.Dot, "far"},
+	} {
+		v := NewVectorWithDistance(c.d)
+		_ = v.Upsert(ctx, "a", pts)
+		res, _ := v.Query(ctx, "a", []float32{1, 1}, 1, nil)
+		if len(res) != 1 || res[0].ID != c.first {
+			t.Fatalf("%s: unexpected results %+v", c.d, res)
+		}
+	}
+}
+
+func TestVectorQueryFilter(t *testing.T) {
+	ctx := context.Background()
+	v := NewVector()
+	_ = v.Upsert(ctx, "a", []vector.Point{
+		{ID: "1", Vector: []float32{1, 0}, Payload: map[string]interface{}{"user_id": int64(1)}},
+		{ID: "2", Vector: []float32{1, 0}, Payload: map[string]interface{}{"user_id": int64(2)}},
+	})
+	f := &vector.Filter{Must: []vector.Condition{vector.MatchValue("user_id", 2)}}
+	res, _ := v.Query(ctx, "a", []float32{1, 0}, 0, f)
+	if len(res) != 1 || res[0].ID != "2" {
+		t.Fatalf("unexpected results: %+v", res)
+	}
+}
+
+func TestRepoSearchText(t *testing.T) {
+	ctx := context.Background()
+	r := NewRepo()
+	for _, m := range []db.Memory{
+		{UserID: 1, Content: "Ticket ZX-4471 is about the login page"},
+		{UserID: 1, Content: "The login page is slow, the login button too"},
+		{UserID: 2, Content: "Ticket ZX-4471 was closed"},
+		{UserID: 1, ProjectID: 3, Content: "ZX-4471 in another project"},
+	} {
+		if _, err := r.CreateMemory(ctx, m); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+
+	res, err := r.SearchText(ctx, db.TextQuery{Query: "zx-4471 login", UserID: 1})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(res) != 2 || res[0].MemoryID != 1 || res[1].MemoryID != 2 || res[0].Score <= res[1].Score {
+		t.Fatalf("unexpected matches: %+v", res)
+	}
+	if res, _ := r.SearchText(ctx, db.TextQuery{Query: "4471", ProjectID: 3}); len(res) != 1 || res[0].MemoryID != 4 {
+		t.Fatalf("project search: %+v", res)
+	}
+	if res, _ := r.SearchText(ctx, db.TextQuery{Query: "zx-4471 login", UserID: 1, Limit: 1, Offset: 1}); len(res) != 1 || res[0].MemoryID != 2 {
+		t.Fatalf("second page: %+v", res)
+	}
+	if res, _ := r.SearchText(ctx, db.TextQuery{Query: "zx-4471 login", UserID: 1, Offset: 2}); len(res) != 0 {
+		t.Fatalf("page past the end: %+v", res)
+	}
+
+	// the index follows updates, deletes and rolled back transactions
+	_ = r.UpdateMemory(ctx, db.Memory{ID: 1, Content: "nothing to see"})
+	_ = r.DeleteMemory(ctx, 3)
+	_ = r.InTx(ctx, func(tx db.TxRepository) error {
+		_, _ = tx.CreateMemory(ctx, db.Memory{UserID: 1, Content: "ZX-4471 again"})
+		return errors.New("rollback")
+	})
+	if res, _ := r.SearchText(ctx, db.TextQuery{Query: "ZX 4471"}); len(res) != 0 {
+		t.Fatalf("stale matches: %+v", res)
+	}
+}
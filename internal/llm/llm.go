@@ -0,0 +1,101 @@
+package llm
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// DefaultTimeout bounds a request to a remote LLM when Config.Timeout is
+// not set.
+const DefaultTimeout = 60 * time.Second
+
+// Message is a single conversation turn passed to a provider.
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Memory is an existing memory shown to a provider when deciding what to
+// do with a newly extracted fact.
+type Memory struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+}
+
+// Event is the action chosen for an extracted fact.
+type Event string
+
+const (
+	EventAdd    Event = "ADD"
+	EventUpdate Event = "UPDATE"
+	EventDelete Event = "DELETE"
+	EventNoop   Event = "NOOP"
+)
+
+// Decision is the provider's verdict for a single fact. ID refers to one of
+// the existing memories for UPDATE, DELETE and NOOP and is empty for ADD.
+type Decision struct {
+	Event Event  `json:"event"`
+	ID    string `json:"id,omitempty"`
+	Text  string `json:"text"`
+}
+
+// Provider extracts facts from conversations and reconciles them with
+// existing memories.
+type Provider interface {
+	ExtractFacts(ctx context.Context, msgs []Message) ([]string, error)
+	Decide(ctx context.Context, fact string, existing []Memory) (Decision, error)
+}
+
+// Config holds LLM provider settings.
+type Config struct {
+	// BaseURL is the OpenAI-compatible API root, e.g. https://api.openai.com/v1.
+	// When empty the offline Local provider is used.
+	BaseURL string
+	APIKey  string
+	Model   string
+	// Timeout bounds each request, including reading the response;
+	// DefaultTimeout when zero.
+	Timeout time.Duration
+}
+
+// LoadConfig reads settings from environment variables with fallbacks.
+func LoadConfig() Config {
+	key := os.Getenv("MEM0_LLM_KEY")
+	if key == "" {
+		key = os.Getenv("MEM0_EMBEDDING_KEY")
+	}
+	model := os.Getenv("MEM0_LLM_MODEL")
+	if model == "" {
+		model = "gpt-4o-mini"
+	}
+	cfg := Config{BaseURL: os.Getenv("MEM0_LLM_URL"), APIKey: key, Model: model}
+	if d, err := time.ParseDuration(os.Getenv("MEM0_LLM_TIMEOUT")); err == nil && d > 0 {
+		cfg.Timeout = d
+	}
+	return cfg
+}
+
+// New returns the provider described by cfg.
+func New(cfg Config) Provider {
+	if cfg.BaseURL == "" {
+		return NewLocal()
+	}
+	return NewOpenAI(cfg)
+}
+
+// valid reports whether d is well formed with respect to existing.
+func valid(d Decision, existing []Memory) bool {
+	switch d.Event {
+	case EventAdd:
+		return d.Text != ""
+	case EventUpdate, EventDelete, EventNoop:
+		for _, m := range existing {
+			if m.ID == d.ID {
+				return d.Event != EventUpdate || d.Text != ""
+			}
+		}
+	}
+	return false
+}
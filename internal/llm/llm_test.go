@@ -0,0 +1,112 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLocalExtractFacts(t *testing.T) {
+	facts, err := NewLocal().ExtractFacts(context.Background(), []Message{
+		{Role: "user", Content: "Hi! I live in Berlin. What's the weather?\nI don't eat meat."},
+		{Role: "assistant", Content: "You live in Berlin."},
+	})
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	want := []string{"I live in Berlin", "I don't eat meat"}
+	if strings.Join(facts, "|") != strings.Join(want, "|") {
+		t.Fatalf("unexpected facts: %q", facts)
+	}
+}
+
+func TestLocalDecide(t *testing.T) {
+	existing := []Memory{{ID: "1", Text: "I live in Berlin"}, {ID: "2", Text: "I like jazz music"}}
+	cases := []struct {
+		fact  string
+		event Event
+		id    string
+	}{
+		{"I live in Berlin", EventNoop, "1"},
+		{"I like jazz and blues music", EventUpdate, "2"},
+		{"I don't like jazz music", EventDelete, "2"},
+		{"My sister is called Ana", EventAdd, ""},
+	}
+	for _, c := range cases {
+		d, err := NewLocal().Decide(context.Background(), c.fact, existing)
+		if err != nil {
+			t.Fatalf("decide %q: %v", c.fact, err)
+		}
+		if d.Event != c.event || d.ID != c.id {
+			t.Fatalf("decide %q: got %+v", c.fact, d)
+		}
+	}
+}
+
+func TestOpenAI(t *testing.T) {
+	replies := []string{
+		`{"facts": ["Lives in Berlin", " "]}`,
+		"```json\n{\"event\": \"update\", \"id\": \"7\", \"text\": \"Lives in Berlin, Germany\"}\n```",
+		`{"event": "DELETE", "id": "99"}`,
+	}
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" {
+			t.Fatalf("unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer key" {
+			t.Fatalf("missing auth header")
+		}
+		var req struct {
+			Model string `json:"model"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Model != "m" {
+			t.Fatalf("bad request: %v %+v", err, req)
+		}
+		resp := map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": Message{Role: "assistant", Content: replies[calls]}}},
+		}
+		calls++
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	p := New(Config{BaseURL: srv.URL + "/", APIKey: "key", Model: "m"})
+	facts, err := p.ExtractFacts(context.Background(), []Message{{Role: "user", Content: "I live in Berlin"}})
+	if err != nil || len(facts) != 1 || facts[0] != "Lives in Berlin" {
+		t.Fatalf("extract: %v %q", err, facts)
+	}
+	existing := []Memory{{ID: "7", Text: "Lives in Germany"}}
+	d, err := p.Decide(context.Background(), "Lives in Berlin", existing)
+	if err != nil || d.Event != EventUpdate || d.ID != "7" {
+		t.Fatalf("decide: %v %+v", err, d)
+	}
+	if _, err := p.Decide(context.Background(), "Lives in Berlin", existing); err == nil {
+		t.Fatalf("expected error for unknown id")
+	}
+}
+
+func TestOpenAITimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	p := New(Config{BaseURL: srv.URL, Model: "m", Timeout: 50 * time.Millisecond})
+	start := time.Now()
+	if _, err := p.ExtractFacts(context.Background(), []Message{{Role: "user", Content: "hi"}}); err == nil {
+		t.Fatalf("expected a timeout from a hung endpoint")
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Fatalf("request took %v despite the timeout", d)
+	}
+	if c := NewOpenAI(Config{}).httpClient; c.Timeout != DefaultTimeout {
+		t.Fatalf("default timeout %v", c.Timeout)
+	}
+}
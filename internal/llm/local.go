@@ -0,0 +1,119 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"unicode"
+)
+
+// Local is a deterministic offline Provider. Facts are the declarative
+// sentences of user messages and decisions are made by word overlap, which
+// is crude but stable enough for tests and development without a model.
+type Local struct {
+	// Threshold is the minimum Jaccard similarity for a fact to be treated
+	// as referring to an existing memory.
+	Threshold float64
+}
+
+// NewLocal returns a Local provider with the default threshold.
+func NewLocal() *Local { return &Local{Threshold: 0.5} }
+
+var negations = map[string]bool{
+	"not": true, "no": true, "never": true, "longer": true,
+	"dont": true, "doesnt": true, "didnt": true, "isnt": true, "wasnt": true,
+	"arent": true, "cant": true, "wont": true, "anymore": true,
+}
+
+var stopwords = map[string]bool{
+	"a": true, "an": true, "the": true, "i": true, "im": true, "my": true,
+	"is": true, "am": true, "are": true, "to": true, "of": true, "and": true,
+}
+
+// ExtractFacts splits user messages into sentences, skipping questions and
+// fragments with fewer than two content words.
+func (l *Local) ExtractFacts(_ context.Context, msgs []Message) ([]string, error) {
+	var facts []string
+	for _, m := range msgs {
+		if m.Role != "" && m.Role != "user" {
+			continue
+		}
+		start := 0
+		for i, r := range m.Content + "\n" {
+			if r != '.' && r != '!' && r != '?' && r != '\n' {
+				continue
+			}
+			s := strings.TrimSpace(m.Content[start:min(i, len(m.Content))])
+			start = i + 1
+			if r == '?' || len(tokens(s)) < 2 {
+				continue
+			}
+			facts = append(facts, s)
+		}
+	}
+	return facts, nil
+}
+
+// Decide compares fact with existing by word overlap.
+func (l *Local) Decide(_ context.Context, fact string, existing []Memory) (Decision, error) {
+	ft := tokens(fact)
+	best, bestSim := -1, 0.0
+	for i, m := range existing {
+		if sim := jaccard(ft, tokens(m.Text)); sim > bestSim {
+			best, bestSim = i, sim
+		}
+	}
+	if best < 0 || bestSim < l.Threshold {
+		return Decision{Event: EventAdd, Text: fact}, nil
+	}
+	m := existing[best]
+	switch {
+	case negated(fact) != negated(m.Text):
+		return Decision{Event: EventDelete, ID: m.ID, Text: m.Text}, nil
+	case bestSim == 1:
+		return Decision{Event: EventNoop, ID: m.ID, Text: m.Text}, nil
+	default:
+		return Decision{Event: EventUpdate, ID: m.ID, Text: fact}, nil
+	}
+}
+
+// words lowercases s and splits it into letter/digit runs, dropping
+// apostrophes so "don't" becomes "dont".
+func words(s string) []string {
+	s = strings.ReplaceAll(strings.ToLower(s), "'", "")
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// tokens returns the content words of s with negations and stopwords removed.
+func tokens(s string) map[string]bool {
+	out := map[string]bool{}
+	for _, w := range words(s) {
+		if !negations[w] && !stopwords[w] {
+			out[w] = true
+		}
+	}
+	return out
+}
+
+func negated(s string) bool {
+	for _, w := range words(s) {
+		if negations[w] {
+			return true
+		}
+	}
+	return false
+}
+
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 0
+	}
+	inter := 0
+	for w := range a {
+		if b[w] {
+			inter++
+		}
+	}
+	return float64(inter) / float64(len(a)+len(b)-inter)
+}
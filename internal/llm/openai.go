@@ -0,0 +1,143 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const extractPrompt = `You extract durable facts about the user from a conversation.
+Return a JSON object {"facts": [...]} where each entry is a short, self-contained
+statement in the third person. Ignore greetings, questions and small talk.
+Return {"facts": []} when nothing is worth remembering.`
+
+const decidePrompt = `You maintain a memory store. Given a new fact and the most similar
+existing memories, choose exactly one event:
+- ADD: the fact is new information.
+- UPDATE: the fact refines or supersedes an existing memory; give its id and the merged text.
+- DELETE: the fact contradicts an existing memory so it must be removed; give its id.
+- NOOP: the fact is already captured by an existing memory; give its id.
+Return a JSON object {"event": "...", "id": "...", "text": "..."}.`
+
+// OpenAI implements Provider using an OpenAI-compatible chat completions API.
+type OpenAI struct {
+	baseURL    string
+	apiKey     string
+	model      string
+	httpClient *http.Client
+}
+
+// NewOpenAI constructs a provider talking to cfg.BaseURL.
+func NewOpenAI(cfg Config) *OpenAI {
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &OpenAI{
+		baseURL:    strings.TrimRight(cfg.BaseURL, "/"),
+		apiKey:     cfg.APIKey,
+		model:      cfg.Model,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// ExtractFacts asks the model for the facts stated in msgs.
+func (o *OpenAI) ExtractFacts(ctx context.Context, msgs []Message) ([]string, error) {
+	var b strings.Builder
+	for _, m := range msgs {
+		fmt.Fprintf(&b, "%s: %s\n", m.Role, m.Content)
+	}
+	var out struct {
+		Facts []string `json:"facts"`
+	}
+	if err := o.Chat(ctx, extractPrompt, b.String(), &out); err != nil {
+		return nil, err
+	}
+	facts := out.Facts[:0]
+	for _, f := range out.Facts {
+		if f = strings.TrimSpace(f); f != "" {
+			facts = append(facts, f)
+		}
+	}
+	return facts, nil
+}
+
+// Decide asks the model how fact relates to the existing memories.
+func (o *OpenAI) Decide(ctx context.Context, fact string, existing []Memory) (Decision, error) {
+	mems, err := json.Marshal(existing)
+	if err != nil {
+		return Decision{}, err
+	}
+	user := fmt.Sprintf("New fact: %s\nExisting memories: %s", fact, mems)
+	var d Decision
+	if err := o.Chat(ctx, decidePrompt, user, &d); err != nil {
+		return Decision{}, err
+	}
+	d.Event = Event(strings.ToUpper(string(d.Event)))
+	if d.Event == EventAdd && d.Text == "" {
+		d.Text = fact
+	}
+	if !valid(d, existing) {
+		return Decision{}, fmt.Errorf("llm: invalid decision %+v", d)
+	}
+	return d, nil
+}
+
+// Chat sends a system and user prompt and decodes the JSON reply into out.
+func (o *OpenAI) Chat(ctx context.Context, system, user string, out interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"model": o.model,
+		"messages": []Message{
+			{Role: "system", Content: system},
+			{Role: "user", Content: user},
+		},
+		"response_format": map[string]string{"type": "json_object"},
+		"temperature":     0,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if o.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.apiKey)
+	}
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("llm status %d", resp.StatusCode)
+	}
+	var res struct {
+		Choices []struct {
+			Message Message `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return err
+	}
+	if len(res.Choices) == 0 {
+		return fmt.Errorf("llm: empty response")
+	}
+	return json.Unmarshal([]byte(stripFence(res.Choices[0].Message.Content)), out)
+}
+
+// stripFence removes a surrounding markdown code fence some models emit
+// even when asked for bare JSON.
+func stripFence(s string) string {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "```") {
+		return s
+	}
+	s = strings.TrimPrefix(s, "```")
+	s = strings.TrimPrefix(s, "json")
+	return strings.TrimSpace(strings.TrimSuffix(s, "```"))
+}
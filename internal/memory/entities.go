@@ -0,0 +1,177 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"mem0-go/internal/auth"
+	"mem0-go/internal/graph"
+)
+
+var (
+	// ErrEntityHasRelationships is returned when deleting an entity that
+	// still has relationships without cascading to them.
+	ErrEntityHasRelationships = errors.New("memory: entity has relationships")
+	// ErrReservedLabel is returned when creating an entity with the label
+	// of the nodes the service keeps for memories or merges.
+	ErrReservedLabel = errors.New("memory: reserved label")
+)
+
+// FindEntities returns the graph nodes of the call's project with the given
+// label, any label when empty, that have every property in props.
+func (s *Service) FindEntities(ctx context.Context, label string, props map[string]interface{}) ([]graph.Node, error) {
+	if err := auth.Require(ctx, auth.PermGraphRead); err != nil {
+		return nil, err
+	}
+	nodes, err := s.graph.FindNodes(ctx, label, props)
+	if err != nil {
+		return nil, err
+	}
+	return inProjectNodes(ctx, nodes), nil
+}
+
+// UpdateEntity merges props into the properties of node id and returns the
+// node as stored. A nil value removes the property. It needs graph:write and
+// returns graph.ErrNodeNotFound when the node is not an entity of the
+// call's project.
+func (s *Service) UpdateEntity(ctx context.Context, id string, props map[string]interface{}) (graph.Node, error) {
+	if err := auth.Require(ctx, auth.PermGraphWrite); err != nil {
+		return graph.Node{}, err
+	}
+	if _, err := s.entity(ctx, id); err != nil {
+		return graph.Node{}, err
+	}
+	if err := s.graph.UpdateNode(ctx, id, withTenant(ctx, props)); err != nil {
+		return graph.Node{}, err
+	}
+	return s.node(ctx, id)
+}
+
+// DeleteEntity removes node id. Unless cascade is set it refuses with
+// ErrEntityHasRelationships when relationships start or end at the node;
+// otherwise they are removed with it. It needs graph:write and returns
+// graph.ErrNodeNotFound when the node is not an entity of the call's
+// project.
+func (s *Service) DeleteEntity(ctx context.Context, id string, cascade bool) error {
+	if err := auth.Require(ctx, auth.PermGraphWrite); err != nil {
+		return err
+	}
+	if _, err := s.entity(ctx, id); err != nil {
+		return err
+	}
+	if !cascade {
+		for _, q := range []graph.EdgeQuery{{From: id}, {To: id}} {
+			edges, err := s.graph.FindEdges(ctx, q)
+			if err != nil {
+				return err
+			}
+			if len(edges) > 0 {
+				return fmt.Errorf("%w: %s", ErrEntityHasRelationships, id)
+			}
+		}
+	}
+	return s.graph.DeleteNode(ctx, id)
+}
+
+// node returns node id of the call's project or graph.ErrNodeNotFound.
+func (s *Service) node(ctx context.Context, id string) (graph.Node, error) {
+	nodes, err := s.graph.Nodes(ctx, []string{id})
+	if err != nil {
+		return graph.Node{}, err
+	}
+	if len(nodes) == 0 || !nodeInProject(ctx, nodes[0].Props) {
+		return graph.Node{}, fmt.Errorf("%w: %s", graph.ErrNodeNotFound, id)
+	}
+	return nodes[0], nil
+}
+
+// entity returns node id of the call's project or graph.ErrNodeNotFound
+// when there is none or it is a Memory or Merge node, which only the
+// service writes.
+func (s *Service) entity(ctx context.Context, id string) (graph.Node, error) {
+	n, err := s.node(ctx, id)
+	if err != nil {
+		return graph.Node{}, err
+	}
+	if !isEntity(n) {
+		return graph.Node{}, fmt.Errorf("%w: %s", graph.ErrNodeNotFound, id)
+	}
+	return n, nil
+}
+
+// Relationship returns the relationship with the given ID, or nil if there
+// is none in the call's project.
+func (s *Service) Relationship(ctx context.Context, id string) (*graph.Edge, error) {
+	if err := auth.Require(ctx, auth.PermGraphRead); err != nil {
+		return nil, err
+	}
+	e, err := s.edge(ctx, id)
+	if errors.Is(err, graph.ErrEdgeNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// FindRelationships returns the relationships of the call's project that
+// match q.
+func (s *Service) FindRelationships(ctx context.Context, q graph.EdgeQuery) ([]graph.Edge, error) {
+	if err := auth.Require(ctx, auth.PermGraphRead); err != nil {
+		return nil, err
+	}
+	edges, err := s.graph.FindEdges(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	out := []graph.Edge{}
+	for _, e := range edges {
+		if edgeInProject(ctx, e) {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+// UpdateRelationship merges props into the properties of relationship id
+// and returns it as stored. A nil value removes the property. It needs
+// graph:write and returns graph.ErrEdgeNotFound when the relationship is
+// not in the call's project.
+func (s *Service) UpdateRelationship(ctx context.Context, id string, props map[string]interface{}) (graph.Edge, error) {
+	if err := auth.Require(ctx, auth.PermGraphWrite); err != nil {
+		return graph.Edge{}, err
+	}
+	if _, err := s.edge(ctx, id); err != nil {
+		return graph.Edge{}, err
+	}
+	if err := s.graph.UpdateEdge(ctx, id, withTenant(ctx, props)); err != nil {
+		return graph.Edge{}, err
+	}
+	return s.edge(ctx, id)
+}
+
+// DeleteRelationship removes relationship id. It needs graph:write.
+func (s *Service) DeleteRelationship(ctx context.Context, id string) error {
+	if err := auth.Require(ctx, auth.PermGraphWrite); err != nil {
+		return err
+	}
+	if _, err := s.edge(ctx, id); err != nil {
+		return err
+	}
+	return s.graph.DeleteEdge(ctx, id)
+}
+
+// edge returns relationship id of the call's project or
+// graph.ErrEdgeNotFound.
+func (s *Service) edge(ctx context.Context, id string) (graph.Edge, error) {
+	e, err := s.graph.Edge(ctx, id)
+	if err != nil {
+		return graph.Edge{}, err
+	}
+	if !edgeInProject(ctx, e) {
+		return graph.Edge{}, fmt.Errorf("%w: %s", graph.ErrEdgeNotFound, id)
+	}
+	return e, nil
+}
@@ -0,0 +1,252 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"mem0-go/internal/auth"
+	"mem0-go/internal/db"
+	"mem0-go/internal/graph"
+)
+
+// Graph search limits.
+const (
+	// MaxHops bounds how far GraphSearch expands from the seed entities.
+	MaxHops = 3
+	// defaultRelatedLimit is the number of related memories GraphSearch
+	// returns without a limit.
+	defaultRelatedLimit = 20
+)
+
+// GraphSearchRequest describes a graph-augmented search. The embedded
+// SearchRequest finds the seed memories. The entities linked to their
+// graph nodes are expanded over Hops relationships, 1 by default and at
+// most MaxHops, and up to RelatedLimit memories linked to the entities
+// reached are returned, 20 by default.
+type GraphSearchRequest struct {
+	SearchRequest
+	Hops         int
+	RelatedLimit int
+}
+
+// GraphSearchResult is the context GraphSearch assembled: the seed
+// memories, the entities reached from them, the relationships between
+// those entities that were followed, and the other memories linked to
+// them.
+type GraphSearchResult struct {
+	Seeds    []MemoryResult  `json:"seeds"`
+	Entities []graph.Node    `json:"entities"`
+	Triples  []Triple        `json:"triples"`
+	Related  []RelatedMemory `json:"related"`
+}
+
+// Triple is a relationship between two entities, named for reading.
+// Subject and Object are the entities' name properties, or their IDs when
+// they have none, and Predicate is the relationship type.
+type Triple struct {
+	Subject      string     `json:"subject"`
+	Predicate    string     `json:"predicate"`
+	Object       string     `json:"object"`
+	Relationship graph.Edge `json:"relationship"`
+}
+
+// String renders t as "Alice WORKS_AT Acme".
+func (t Triple) String() string { return t.Subject + " " + t.Predicate + " " + t.Object }
+
+// RelatedMemory is a memory linked to an entity GraphSearch reached. Hops
+// is how many relationships separate that entity from the entities of the
+// seed memories, and EntityIDs are the reached entities it is linked to.
+type RelatedMemory struct {
+	Memory    db.Memory `json:"memory"`
+	Hops      int       `json:"hops"`
+	EntityIDs []string  `json:"entityIDs"`
+}
+
+// GraphSearch finds seed memories with req.SearchRequest, maps them to the
+// entities their graph nodes are related to and expands those over
+// entity relationships, returning the memories linked to what it reached
+// together with the relationships followed. It needs memories:read and
+// graph:read.
+func (s *Service) GraphSearch(ctx context.Context, req GraphSearchRequest) (GraphSearchResult, error) {
+	if err := auth.Require(ctx, auth.PermGraphRead); err != nil {
+		return GraphSearchResult{}, err
+	}
+	hops := req.Hops
+	if hops == 0 {
+		hops = 1
+	}
+	if hops < 0 || hops > MaxHops {
+		return GraphSearchResult{}, fmt.Errorf("%w: hops %d outside [1, %d]", ErrInvalidSearch, req.Hops, MaxHops)
+	}
+	limit := req.RelatedLimit
+	if limit <= 0 {
+		limit = defaultRelatedLimit
+	}
+	userID, err := scopeUser(ctx, req.UserID)
+	if err != nil {
+		return GraphSearchResult{}, err
+	}
+	seeds, err := s.SearchMemories(ctx, req.SearchRequest)
+	if err != nil {
+		return GraphSearchResult{}, err
+	}
+	g := searchGraph{nodes: map[string]graph.Node{}, memories: map[string][]int64{}}
+
+	// entities of the seed memories, at distance 0
+	seedIDs := map[int64]bool{}
+	var seedNodes []string
+	for _, r := range seeds {
+		seedIDs[r.ID] = true
+		nodes, err := s.graph.FindNodes(ctx, MemoryLabel, map[string]interface{}{"memory_id": r.ID})
+		if err != nil {
+			return GraphSearchResult{}, err
+		}
+		for _, n := range inProjectNodes(ctx, nodes) {
+			seedNodes = append(seedNodes, n.ID)
+		}
+	}
+	depth := map[string]int{}
+	var frontier []string
+	linked, err := s.expandScoped(ctx, seedNodes)
+	if err != nil {
+		return GraphSearchResult{}, err
+	}
+	for _, id := range seedNodes {
+		for _, h := range linked[id] {
+			if n := h.Node; n.Label != MemoryLabel {
+				g.nodes[n.ID] = n
+				if _, ok := depth[n.ID]; !ok {
+					depth[n.ID] = 0
+					frontier = append(frontier, n.ID)
+				}
+			}
+		}
+	}
+
+	// breadth-first over entity relationships; the entities of the last
+	// level are expanded only for the memories linked to them
+	res := GraphSearchResult{Seeds: seeds, Entities: []graph.Node{}, Triples: []Triple{}, Related: []RelatedMemory{}}
+	followed := map[string]bool{}
+	for d := 0; d <= hops && len(frontier) > 0; d++ {
+		adjacent, err := s.expandScoped(ctx, frontier)
+		if err != nil {
+			return GraphSearchResult{}, err
+		}
+		var next []string
+		for _, id := range frontier {
+			for _, h := range adjacent[id] {
+				n := h.Node
+				if n.Label == MemoryLabel {
+					if mid, ok := intValue(n.Props["memory_id"]); ok {
+						g.memories[id] = append(g.memories[id], mid)
+					}
+					continue
+				}
+				if d == hops {
+					continue
+				}
+				g.nodes[n.ID] = n
+				if !followed[h.Edge.ID] {
+					followed[h.Edge.ID] = true
+					res.Triples = append(res.Triples, g.triple(h.Edge))
+				}
+				if _, ok := depth[n.ID]; !ok {
+					depth[n.ID] = d + 1
+					next = append(next, n.ID)
+				}
+			}
+		}
+		frontier = next
+	}
+	for id := range depth {
+		res.Entities = append(res.Entities, g.nodes[id])
+	}
+	sort.Slice(res.Entities, func(i, j int) bool {
+		a, b := res.Entities[i].ID, res.Entities[j].ID
+		if depth[a] != depth[b] {
+			return depth[a] < depth[b]
+		}
+		return a < b
+	})
+
+	// other memories linked to the entities reached
+	related := map[int64]*RelatedMemory{}
+	for _, n := range res.Entities {
+		for _, id := range g.memories[n.ID] {
+			if seedIDs[id] {
+				continue
+			}
+			if r, ok := related[id]; ok {
+				r.EntityIDs = append(r.EntityIDs, n.ID)
+				continue
+			}
+			m, err := s.get(ctx, id)
+			if errors.Is(err, db.ErrNotFound) || (err == nil && userID != 0 && m.UserID != userID) {
+				continue
+			}
+			if err != nil {
+				return GraphSearchResult{}, err
+			}
+			related[id] = &RelatedMemory{Memory: m, Hops: depth[n.ID], EntityIDs: []string{n.ID}}
+		}
+	}
+	for _, r := range related {
+		res.Related = append(res.Related, *r)
+	}
+	sort.Slice(res.Related, func(i, j int) bool {
+		a, b := res.Related[i], res.Related[j]
+		if a.Hops != b.Hops {
+			return a.Hops < b.Hops
+		}
+		return a.Memory.ID < b.Memory.ID
+	})
+	if len(res.Related) > limit {
+		res.Related = res.Related[:limit]
+	}
+	return res, nil
+}
+
+// searchGraph is the part of the call's project GraphSearch reached: the
+// entities it entered and the IDs of the memories linked to each.
+type searchGraph struct {
+	nodes    map[string]graph.Node
+	memories map[string][]int64
+}
+
+// expandScoped returns the relationships of ids, in either direction, that
+// stay in the call's project, with the nodes at their other ends.
+func (s *Service) expandScoped(ctx context.Context, ids []string) (map[string][]graph.Hop, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	hops, err := s.graph.Expand(ctx, ids, graph.Both, nil)
+	if err != nil {
+		return nil, err
+	}
+	e := scoped(ctx, graph.Expansion{})
+	for id, hs := range hops {
+		kept := hs[:0]
+		for _, h := range hs {
+			if e.EdgeFilter(h.Edge) && e.NodeFilter(h.Node) {
+				kept = append(kept, h)
+			}
+		}
+		hops[id] = kept
+	}
+	return hops, nil
+}
+
+// triple names the ends of e.
+func (g searchGraph) triple(e graph.Edge) Triple {
+	return Triple{Subject: g.name(e.From), Predicate: e.Type, Object: g.name(e.To), Relationship: e}
+}
+
+// name returns the name property of node id, or its ID.
+func (g searchGraph) name(id string) string {
+	if name, ok := g.nodes[id].Props["name"].(string); ok && name != "" {
+		return name
+	}
+	return id
+}
@@ -0,0 +1,216 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"mem0-go/internal/db"
+)
+
+// Search modes.
+const (
+	SearchVector  = "vector"
+	SearchKeyword = "keyword"
+	SearchHybrid  = "hybrid"
+)
+
+// Fusion methods of hybrid search.
+const (
+	// FusionRRF scores a memory by reciprocal rank fusion: the sum of
+	// 1/(60+rank) over the result lists it appears in.
+	FusionRRF = "rrf"
+	// FusionWeighted blends the vector and keyword scores, each first
+	// scaled to [0, 1] across its results.
+	FusionWeighted = "weighted"
+)
+
+const (
+	// rrfK damps the weight of the top ranks in reciprocal rank fusion.
+	rrfK = 60
+	// hybridCandidates is how many candidates per requested result each
+	// side of a hybrid search fetches.
+	hybridCandidates = 4
+	// defaultSearchLimit is the result count of keyword and hybrid
+	// searches without a limit.
+	defaultSearchLimit = 10
+)
+
+var (
+	// ErrInvalidSearch is returned for an unknown search mode or fusion, a
+	// vector weight outside [0, 1] or a keyword search without a query.
+	ErrInvalidSearch = errors.New("memory: invalid search")
+	// ErrNoTextSearch is returned for keyword and hybrid searches when the
+	// repository has no full-text index.
+	ErrNoTextSearch = errors.New("memory: repository does not support keyword search")
+)
+
+// ScoreExplanation breaks down the score of a keyword, hybrid or reranked
+// match.
+// Ranks are 1-based positions in the vector and keyword results, 0 when
+// the memory was not among them, and scores are those the stores gave.
+type ScoreExplanation struct {
+	Fusion      string  `json:"fusion,omitempty"`
+	VectorRank  int     `json:"vectorRank"`
+	VectorScore float32 `json:"vectorScore"`
+	TextRank    int     `json:"textRank"`
+	TextScore   float32 `json:"textScore"`
+	// RetrievalRank and RetrievalScore are the rank and score a reranked
+	// result had before reranking.
+	RetrievalRank  int     `json:"retrievalRank,omitempty"`
+	RetrievalScore float32 `json:"retrievalScore,omitempty"`
+}
+
+// searchHybrid runs a keyword search for req and, in hybrid mode, a vector
+// search, fusing the two.
+func (s *Service) searchHybrid(ctx context.Context, req SearchRequest) ([]MemoryResult, error) {
+	if s.text == nil {
+		return nil, ErrNoTextSearch
+	}
+	if req.Query == "" {
+		return nil, fmt.Errorf("%w: %s search needs a query", ErrInvalidSearch, req.Mode)
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	if req.Mode == SearchKeyword {
+		text, err := s.searchText(ctx, req, limit)
+		if err != nil {
+			return nil, err
+		}
+		for i := range text {
+			text[i].Explanation = &ScoreExplanation{TextRank: i + 1, TextScore: text[i].Score}
+		}
+		return text, nil
+	}
+
+	fusion, weight := req.Fusion, float32(0.5)
+	switch fusion {
+	case "":
+		fusion = FusionRRF
+	case FusionRRF, FusionWeighted:
+	default:
+		return nil, fmt.Errorf("%w: unknown fusion %q", ErrInvalidSearch, fusion)
+	}
+	if req.VectorWeight != nil {
+		if weight = *req.VectorWeight; weight < 0 || weight > 1 {
+			return nil, fmt.Errorf("%w: vector weight %v outside [0, 1]", ErrInvalidSearch, weight)
+		}
+	}
+	vec, err := s.searchVector(ctx, req, limit*hybridCandidates)
+	if err != nil {
+		return nil, err
+	}
+	text, err := s.searchText(ctx, req, limit*hybridCandidates)
+	if err != nil {
+		return nil, err
+	}
+	out := fuse(vec, text, fusion, weight)
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+// searchText returns up to limit keyword matches of req, best first. The
+// text index only narrows by the shorthand filters, so matches are checked
+// against the full filter as the vector store would, fetching further
+// pages until limit of them pass or the index has no more.
+func (s *Service) searchText(ctx context.Context, req SearchRequest, limit int) ([]MemoryResult, error) {
+	_, projectID := tenant(ctx)
+	q := db.TextQuery{
+		Query:     req.Query,
+		UserID:    req.UserID,
+		ProjectID: projectID,
+		AgentID:   req.AgentID,
+		RunID:     req.RunID,
+		Tags:      req.Tags,
+		Limit:     limit,
+	}
+	filter := projectFilter(ctx, req.filter())
+	out := []MemoryResult{}
+	for len(out) < limit {
+		matches, err := s.text.SearchText(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		for _, tm := range matches {
+			m, err := s.repo.GetMemory(ctx, tm.MemoryID)
+			if errors.Is(err, db.ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			if p := payload(m); filter.Matches(p) && len(out) < limit {
+				out = append(out, MemoryResult{ID: m.ID, Score: tm.Score, Payload: p})
+			}
+		}
+		if len(matches) < q.Limit {
+			break
+		}
+		q.Offset += len(matches)
+	}
+	return out, nil
+}
+
+// fuse merges ranked vector and keyword results into one ranking.
+func fuse(vec, text []MemoryResult, fusion string, weight float32) []MemoryResult {
+	byID := map[int64]*MemoryResult{}
+	var out []*MemoryResult
+	add := func(r MemoryResult) *MemoryResult {
+		if f, ok := byID[r.ID]; ok {
+			return f
+		}
+		f := &MemoryResult{ID: r.ID, Payload: r.Payload, Explanation: &ScoreExplanation{Fusion: fusion}}
+		byID[r.ID] = f
+		out = append(out, f)
+		return f
+	}
+	vecScale, textScale := scale(vec), scale(text)
+	for i, r := range vec {
+		f := add(r)
+		f.Explanation.VectorRank, f.Explanation.VectorScore = i+1, r.Score
+		if fusion == FusionRRF {
+			f.Score += 1 / float32(rrfK+i+1)
+		} else {
+			f.Score += weight * vecScale(r.Score)
+		}
+	}
+	for i, r := range text {
+		f := add(r)
+		f.Explanation.TextRank, f.Explanation.TextScore = i+1, r.Score
+		if fusion == FusionRRF {
+			f.Score += 1 / float32(rrfK+i+1)
+		} else {
+			f.Score += (1 - weight) * textScale(r.Score)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Score != out[j].Score {
+			return out[i].Score > out[j].Score
+		}
+		return out[i].ID < out[j].ID
+	})
+	res := make([]MemoryResult, len(out))
+	for i, f := range out {
+		res[i] = *f
+	}
+	return res
+}
+
+// scale maps the scores of results, ordered best first, onto [0, 1] with
+// the best at 1. It works whichever direction the scores improve in, so
+// distances scale the same as similarities.
+func scale(results []MemoryResult) func(float32) float32 {
+	if len(results) == 0 {
+		return func(float32) float32 { return 0 }
+	}
+	best, worst := results[0].Score, results[len(results)-1].Score
+	if best == worst {
+		return func(float32) float32 { return 1 }
+	}
+	return func(s float32) float32 { return (s - worst) / (best - worst) }
+}
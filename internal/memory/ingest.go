@@ -0,0 +1,144 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"mem0-go/internal/auth"
+	"mem0-go/internal/llm"
+	"mem0-go/internal/vector"
+)
+
+// ErrIngestUnavailable is returned by Ingest when the service has no LLM
+// provider or embedder configured.
+var ErrIngestUnavailable = errors.New("memory: ingest requires an llm provider and embedder")
+
+// ingestCandidates is how many similar memories are shown to the provider
+// for each extracted fact.
+const ingestCandidates = 5
+
+// IngestResult reports the decision taken for one extracted fact.
+type IngestResult struct {
+	Event    llm.Event `json:"event"`
+	MemoryID int64     `json:"memoryID,omitempty"`
+	Text     string    `json:"text"`
+	OldText  string    `json:"oldText,omitempty"`
+	// Skipped says why the decision was not carried out, such as the
+	// permission deleting needs.
+	Skipped string `json:"skipped,omitempty"`
+}
+
+// Ingest extracts facts from a conversation turn and reconciles each one
+// with the user's most similar memories, adding, updating or deleting
+// memories as the provider decides. Deleting needs memories:delete on top
+// of memories:write; callers without it, such as the writer role, get
+// their other decisions applied and the deletes reported as skipped.
+func (s *Service) Ingest(ctx context.Context, userID int64, msgs []llm.Message) ([]IngestResult, error) {
+	if s.llm == nil || s.embedder == nil {
+		return nil, ErrIngestUnavailable
+	}
+	if err := auth.Require(ctx, auth.PermMemoriesWrite); err != nil {
+		return nil, err
+	}
+	userID, err := scopeUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	facts, err := s.llm.ExtractFacts(ctx, msgs)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]IngestResult, 0, len(facts))
+	if len(facts) == 0 {
+		return out, nil
+	}
+	vecs, err := s.embedder.Embed(ctx, facts)
+	if err != nil {
+		return nil, err
+	}
+	if len(vecs) != len(facts) {
+		return nil, fmt.Errorf("memory: embedder returned %d vectors for %d facts", len(vecs), len(facts))
+	}
+	for i, fact := range facts {
+		existing, err := s.similar(ctx, userID, vecs[i])
+		if err != nil {
+			return out, err
+		}
+		d, err := s.llm.Decide(ctx, fact, existing)
+		if err != nil {
+			return out, err
+		}
+		r, err := s.apply(ctx, userID, fact, vecs[i], d, existing)
+		if err != nil {
+			return out, err
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+// similar returns the user's memories closest to vec.
+func (s *Service) similar(ctx context.Context, userID int64, vec []float32) ([]llm.Memory, error) {
+	filter := &vector.Filter{Must: []vector.Condition{vector.MatchValue("user_id", userID)}}
+	res, err := s.vector.Query(ctx, Collection, vec, ingestCandidates, projectFilter(ctx, filter))
+	if err != nil {
+		return nil, err
+	}
+	out := make([]llm.Memory, 0, len(res))
+	for _, r := range res {
+		id, err := strconv.ParseInt(r.ID, 10, 64)
+		if err != nil {
+			continue
+		}
+		m, err := s.repo.GetMemory(ctx, id)
+		if err != nil || m.UserID != userID || !inProject(ctx, m.ProjectID) {
+			continue
+		}
+		out = append(out, llm.Memory{ID: r.ID, Text: m.Content})
+	}
+	return out, nil
+}
+
+// ingestActor is the history actor for changes made by Ingest.
+const ingestActor = "ingest"
+
+// apply carries out decision d for fact across the stores.
+func (s *Service) apply(ctx context.Context, userID int64, fact string, vec []float32, d llm.Decision, existing []llm.Memory) (IngestResult, error) {
+	r := IngestResult{Event: d.Event, Text: d.Text}
+	if d.Event == llm.EventAdd {
+		id, err := s.Store(ctx, StoreRequest{UserID: userID, Content: d.Text, Vector: vec, Actor: ingestActor})
+		r.MemoryID = id
+		return r, err
+	}
+	for _, m := range existing {
+		if m.ID == d.ID {
+			r.OldText = m.Text
+		}
+	}
+	id, err := strconv.ParseInt(d.ID, 10, 64)
+	if err != nil {
+		return r, fmt.Errorf("memory: invalid memory id %q", d.ID)
+	}
+	r.MemoryID = id
+	switch d.Event {
+	case llm.EventUpdate:
+		if d.Text != fact {
+			if vec, err = s.embed(ctx, d.Text); err != nil {
+				return r, err
+			}
+		}
+		_, err := s.Update(ctx, id, UpdateRequest{Content: &d.Text, Vector: vec, Actor: ingestActor})
+		return r, err
+	case llm.EventDelete:
+		r.Text = r.OldText
+		if err := auth.Require(ctx, auth.PermMemoriesDelete); err != nil {
+			r.Skipped = err.Error()
+			return r, nil
+		}
+		return r, s.Delete(ctx, id, ingestActor)
+	default:
+		return r, nil
+	}
+}
@@ -0,0 +1,260 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"mem0-go/internal/db"
+	"mem0-go/internal/extract"
+	"mem0-go/internal/graph"
+)
+
+// MentionsType is the type of the relationships from a memory's node to
+// the entities the memory mentions.
+const MentionsType = "MENTIONS"
+
+// ErrNoExtractor is returned by LinkMemory when no extractor is configured.
+var ErrNoExtractor = errors.New("memory: no entity extractor configured")
+
+// linkQueue schedules LinkMemory to run elsewhere, such as on the links
+// queue served by cmd/worker.
+type linkQueue interface {
+	EnqueueLink(ctx context.Context, memoryID int64) error
+}
+
+// LinkResult is what LinkMemory linked a memory to.
+type LinkResult struct {
+	// Entities are the nodes the memory mentions, created or reused.
+	Entities []graph.Node `json:"entities"`
+	// Relationships are those the memory states between its entities.
+	Relationships []graph.Edge `json:"relationships"`
+}
+
+// link schedules LinkMemory for m on the link queue or, without one or
+// when the queue refuses the job, runs it straight away, when there is an
+// extractor. Failures are not the write's: a memory whose entities could
+// not be linked is linked again when it is next written.
+func (s *Service) link(ctx context.Context, m db.Memory) {
+	if s.extractor == nil {
+		return
+	}
+	if s.links != nil && s.links.EnqueueLink(ctx, m.ID) == nil {
+		return
+	}
+	_, _ = s.LinkMemory(ctx, m.ID)
+}
+
+// LinkMemory extracts the entities and relations memory id mentions and
+// adds them to the graph of the memory's project: an entity is reused when
+// a node with its name exists and created otherwise, relations become
+// relationships between the entities, and the memory's node gets a
+// MENTIONS relationship to each entity. Relationships record the memories
+// stating them in "memory_ids"; those the memory no longer states stop
+// listing it and are removed once no memory does, as are MENTIONS
+// relationships to entities it no longer mentions, so linking again after
+// an update is safe. A memory that no longer exists is skipped.
+func (s *Service) LinkMemory(ctx context.Context, id int64) (LinkResult, error) {
+	if s.extractor == nil {
+		return LinkResult{}, ErrNoExtractor
+	}
+	m, err := s.repo.GetMemory(ctx, id)
+	if errors.Is(err, db.ErrNotFound) {
+		return LinkResult{}, nil
+	}
+	if err != nil {
+		return LinkResult{}, err
+	}
+	found, err := s.extractor.Extract(ctx, m.Content)
+	if err != nil {
+		return LinkResult{}, err
+	}
+	if err := s.syncGraph(ctx, m, false); err != nil {
+		return LinkResult{}, err
+	}
+	nodes, err := s.graph.FindNodes(ctx, MemoryLabel, map[string]interface{}{"memory_id": m.ID})
+	if err != nil {
+		return LinkResult{}, err
+	}
+	if len(nodes) == 0 {
+		return LinkResult{}, graph.ErrNodeNotFound
+	}
+	memNode := nodes[0].ID
+	before, err := s.graph.FindEdges(ctx, graph.EdgeQuery{From: memNode, Type: MentionsType})
+	if err != nil {
+		return LinkResult{}, err
+	}
+
+	res := LinkResult{Entities: []graph.Node{}, Relationships: []graph.Edge{}}
+	ids := map[string]string{}
+	mentioned := map[string]bool{}
+	for _, e := range found.Entities {
+		n, err := s.entityNode(ctx, m, e)
+		if err != nil {
+			return LinkResult{}, err
+		}
+		ids[e.Name] = n.ID
+		res.Entities = append(res.Entities, n)
+		if _, err := s.ensureEdge(ctx, m, memNode, n.ID, MentionsType); err != nil {
+			return LinkResult{}, err
+		}
+		mentioned[n.ID] = true
+	}
+	for _, r := range found.Relations {
+		from, to := ids[r.Source], ids[r.Target]
+		if from == "" || to == "" {
+			continue
+		}
+		e, err := s.ensureEdge(ctx, m, from, to, r.Type)
+		if err != nil {
+			return LinkResult{}, err
+		}
+		res.Relationships = append(res.Relationships, e)
+	}
+
+	if err := s.unlinkStale(ctx, m, before, res.Relationships); err != nil {
+		return LinkResult{}, err
+	}
+	for _, e := range before {
+		if !mentioned[e.To] {
+			if err := s.graph.DeleteEdge(ctx, e.ID); err != nil {
+				return LinkResult{}, err
+			}
+		}
+	}
+	return res, nil
+}
+
+// unlinkStale drops m from the relationships between the entities it
+// mentioned before that it no longer states, deleting those no other
+// memory states.
+func (s *Service) unlinkStale(ctx context.Context, m db.Memory, before []graph.Edge, kept []graph.Edge) error {
+	old := map[string]bool{}
+	for _, e := range before {
+		old[e.To] = true
+	}
+	keep := map[string]bool{}
+	for _, e := range kept {
+		keep[e.ID] = true
+	}
+	for id := range old {
+		edges, err := s.graph.FindEdges(ctx, graph.EdgeQuery{From: id})
+		if err != nil {
+			return err
+		}
+		for _, e := range edges {
+			if e.Type == MentionsType || !old[e.To] || keep[e.ID] || !inMemoryProject(m, e.Props) {
+				continue
+			}
+			ids := memoryIDs(e.Props)
+			rest := slices.DeleteFunc(slices.Clone(ids), func(id int64) bool { return id == m.ID })
+			switch {
+			case len(rest) == len(ids):
+			case len(rest) == 0:
+				err = s.graph.DeleteEdge(ctx, e.ID)
+			default:
+				err = s.graph.UpdateEdge(ctx, e.ID, map[string]interface{}{"memory_id": rest[0], "memory_ids": rest})
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// entityNode returns the node of m's project named e.Name, preferring one
+// labelled e.Type, or creates one.
+func (s *Service) entityNode(ctx context.Context, m db.Memory, e extract.Entity) (graph.Node, error) {
+	label := e.Type
+	if label == MemoryLabel {
+		label = extract.TypeEntity
+	}
+	nodes, err := s.graph.FindNodes(ctx, "", map[string]interface{}{"name": e.Name})
+	if err != nil {
+		return graph.Node{}, err
+	}
+	var match *graph.Node
+	for i, n := range nodes {
+		if n.Label == MemoryLabel || !inMemoryProject(m, n.Props) {
+			continue
+		}
+		if match == nil || n.Label == label {
+			match = &nodes[i]
+		}
+	}
+	if match != nil {
+		return *match, nil
+	}
+	props := memoryTenant(m, map[string]interface{}{"name": e.Name})
+	id, err := s.graph.CreateNode(ctx, label, props)
+	if err != nil {
+		return graph.Node{}, err
+	}
+	return graph.Node{ID: id, Label: label, Props: props}, nil
+}
+
+// ensureEdge returns the relationship of m's project from from to to of
+// relType, creating it for m when there is none.
+func (s *Service) ensureEdge(ctx context.Context, m db.Memory, from, to, relType string) (graph.Edge, error) {
+	edges, err := s.graph.FindEdges(ctx, graph.EdgeQuery{From: from, To: to, Type: relType})
+	if err != nil {
+		return graph.Edge{}, err
+	}
+	for _, e := range edges {
+		if !inMemoryProject(m, e.Props) {
+			continue
+		}
+		if ids := memoryIDs(e.Props); !slices.Contains(ids, m.ID) {
+			ids = append(ids, m.ID)
+			if err := s.graph.UpdateEdge(ctx, e.ID, map[string]interface{}{"memory_ids": ids}); err != nil {
+				return graph.Edge{}, err
+			}
+			e.Props = graph.MergeProps(e.Props, map[string]interface{}{"memory_ids": ids})
+		}
+		return e, nil
+	}
+	props := memoryTenant(m, map[string]interface{}{"memory_id": m.ID, "memory_ids": []int64{m.ID}})
+	id, err := s.graph.CreateEdge(ctx, from, to, relType, props)
+	if err != nil {
+		return graph.Edge{}, err
+	}
+	return graph.Edge{ID: id, From: from, To: to, Type: relType, Props: props}, nil
+}
+
+// memoryIDs returns the memories stating a relationship with props: its
+// "memory_ids", or the "memory_id" it was created for when it predates
+// them.
+func memoryIDs(props map[string]interface{}) []int64 {
+	var ids []int64
+	switch v := props["memory_ids"].(type) {
+	case []int64:
+		ids = append(ids, v...)
+	case []interface{}:
+		for _, x := range v {
+			if id, ok := intValue(x); ok {
+				ids = append(ids, id)
+			}
+		}
+	}
+	if id, ok := intValue(props["memory_id"]); ok && len(ids) == 0 {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// memoryTenant tags props with m's organization and project, as
+// withTenant does for the call's.
+func memoryTenant(m db.Memory, props map[string]interface{}) map[string]interface{} {
+	if m.ProjectID != 0 {
+		props["org_id"], props["project_id"] = m.OrgID, m.ProjectID
+	}
+	return props
+}
+
+// inMemoryProject reports whether props, of a node or relationship, belong
+// to m's project.
+func inMemoryProject(m db.Memory, props map[string]interface{}) bool {
+	p, _ := intValue(props["project_id"])
+	return p == m.ProjectID
+}
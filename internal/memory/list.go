@@ -0,0 +1,156 @@
+package memory
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"mem0-go/internal/auth"
+	"mem0-go/internal/db"
+)
+
+// Page sizes for ListMemories.
+const (
+	DefaultListLimit = 20
+	MaxListLimit     = 100
+)
+
+var (
+	// ErrInvalidCursor is returned for a cursor that ListMemories did not
+	// issue or that belongs to a listing in another order.
+	ErrInvalidCursor = errors.New("memory: invalid cursor")
+	// ErrInvalidSort is returned for an unknown sort order.
+	ErrInvalidSort = errors.New("memory: invalid sort")
+)
+
+// ListRequest selects, orders and pages memories. Zero fields leave the
+// listing unrestricted. Sort is db.SortByID, the default, or
+// db.SortByCreatedAt, descending when Desc is set. Cursor continues from a
+// previous page's NextCursor; AfterID is for callers that page an ID
+// ordered listing by memory ID instead. Limit defaults to
+// DefaultListLimit and is capped at MaxListLimit.
+type ListRequest struct {
+	UserID        int64
+	AgentID       string
+	RunID         string
+	Tag           string
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	Contains      string
+	Sort          string
+	Desc          bool
+	Cursor        string
+	AfterID       int64
+	Limit         int
+}
+
+// MemoryPage is one page of a listing. NextCursor continues the listing
+// while HasMore is set.
+type MemoryPage struct {
+	Memories   []db.Memory `json:"memories"`
+	NextCursor string      `json:"nextCursor,omitempty"`
+	HasMore    bool        `json:"hasMore"`
+
+	sort string
+	desc bool
+}
+
+// Cursor returns the cursor that continues the listing after m.
+func (p MemoryPage) Cursor(m db.Memory) string {
+	c := cursor{Sort: p.sort, Desc: p.desc, ID: m.ID}
+	if p.sort == db.SortByCreatedAt {
+		c.CreatedAt = m.CreatedAt
+	}
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// cursor is the position a listing stopped at. Clients see it base64
+// encoded and should treat it as opaque.
+type cursor struct {
+	Sort      string `json:"s"`
+	Desc      bool   `json:"d,omitempty"`
+	ID        int64  `json:"i"`
+	CreatedAt string `json:"c,omitempty"`
+}
+
+// parseCursor decodes s, checking it was issued for the order of q.
+func parseCursor(s string, q db.MemoryQuery) (cursor, error) {
+	var c cursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || json.Unmarshal(b, &c) != nil || c.ID <= 0 {
+		return cursor{}, ErrInvalidCursor
+	}
+	if c.Sort != q.Sort || c.Desc != q.Desc {
+		return cursor{}, ErrInvalidCursor
+	}
+	if c.Sort == db.SortByCreatedAt {
+		if _, err := parseTime(c.CreatedAt); err != nil {
+			return cursor{}, ErrInvalidCursor
+		}
+	}
+	return c, nil
+}
+
+// ListMemories returns a page of the call's project's memories matching
+// req. Principals that are not admins only list their own memories. It
+// needs memories:read.
+func (s *Service) ListMemories(ctx context.Context, req ListRequest) (MemoryPage, error) {
+	if err := auth.Require(ctx, auth.PermMemoriesRead); err != nil {
+		return MemoryPage{}, err
+	}
+	userID, err := scopeUser(ctx, req.UserID)
+	if err != nil {
+		return MemoryPage{}, err
+	}
+	if req.Sort == "" {
+		req.Sort = db.SortByID
+	}
+	if req.Sort != db.SortByID && req.Sort != db.SortByCreatedAt {
+		return MemoryPage{}, ErrInvalidSort
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = DefaultListLimit
+	}
+	limit = min(limit, MaxListLimit)
+	_, projectID := tenant(ctx)
+	q := db.MemoryQuery{
+		UserID:        userID,
+		ProjectID:     &projectID,
+		AgentID:       req.AgentID,
+		RunID:         req.RunID,
+		Tag:           req.Tag,
+		CreatedAfter:  req.CreatedAfter,
+		CreatedBefore: req.CreatedBefore,
+		Contains:      req.Contains,
+		Sort:          req.Sort,
+		Desc:          req.Desc,
+		Limit:         limit + 1,
+	}
+	switch {
+	case req.Cursor != "":
+		c, err := parseCursor(req.Cursor, q)
+		if err != nil {
+			return MemoryPage{}, err
+		}
+		q.AfterID, q.AfterCreatedAt = c.ID, c.CreatedAt
+	case req.AfterID != 0:
+		if q.Sort != db.SortByID {
+			return MemoryPage{}, ErrInvalidCursor
+		}
+		q.AfterID = req.AfterID
+	}
+	list, err := s.repo.ListMemories(ctx, q)
+	if err != nil {
+		return MemoryPage{}, err
+	}
+	page := MemoryPage{Memories: list, sort: q.Sort, desc: q.Desc}
+	if len(list) > limit {
+		page.Memories, page.HasMore = list[:limit], true
+		page.NextCursor = page.Cursor(page.Memories[limit-1])
+	}
+	return page, nil
+}
@@ -0,0 +1,436 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"mem0-go/internal/auth"
+	"mem0-go/internal/graph"
+)
+
+// MergeLabel is the graph label of the nodes recording entity merges.
+const MergeLabel = "Merge"
+
+var (
+	// ErrInvalidMerge is returned for merges naming no entities, the
+	// survivor among the merged, or nodes that are not entities.
+	ErrInvalidMerge = errors.New("memory: invalid merge")
+	// ErrMergeNotFound is returned for merges not recorded in the call's
+	// project.
+	ErrMergeNotFound = errors.New("memory: merge not found")
+	// ErrMergeUndone is returned when undoing a merge twice.
+	ErrMergeUndone = errors.New("memory: merge already undone")
+)
+
+// MergeRecord is the provenance of a merge: enough of the graph as it was
+// to undo it.
+type MergeRecord struct {
+	// ID is the ID of the Merge node the record is kept in.
+	ID       string `json:"id"`
+	Survivor string `json:"survivor"`
+	// Merged are the merged nodes as they were.
+	Merged []graph.Node `json:"merged"`
+	// SurvivorProps are the survivor's properties before the merge.
+	SurvivorProps map[string]interface{} `json:"survivorProps"`
+	// Edges are the relationships of the merged nodes as they were.
+	Edges []graph.Edge `json:"edges"`
+	// Rewired maps the ID of each relationship in Edges moved to the
+	// survivor to the ID of its replacement. Relationships that would
+	// duplicate one the survivor has, or join two of the merged entities,
+	// are dropped instead.
+	Rewired   map[string]string `json:"rewired"`
+	Actor     string            `json:"actor,omitempty"`
+	CreatedAt string            `json:"createdAt"`
+	UndoneAt  string            `json:"undoneAt,omitempty"`
+	// Restored maps each merged node's ID to the ID of the node it was
+	// re-created as when the merge was undone.
+	Restored map[string]string `json:"restored,omitempty"`
+}
+
+// MergeEntities merges the entities merged into survivor: their
+// relationships, including memories' MENTIONS, are moved to survivor,
+// properties survivor lacks are copied to it, their names are added to its
+// "aliases" property, and they are deleted. The merge is recorded in a
+// Merge node, written before the graph is changed, so UndoMerge can
+// reverse it; a merge that fails partway is rolled back and its record
+// deleted. It needs graph:write.
+func (s *Service) MergeEntities(ctx context.Context, survivor string, merged []string) (MergeRecord, error) {
+	if err := auth.Require(ctx, auth.PermGraphWrite); err != nil {
+		return MergeRecord{}, err
+	}
+	if len(merged) == 0 {
+		return MergeRecord{}, fmt.Errorf("%w: nothing to merge", ErrInvalidMerge)
+	}
+	target, err := s.mergeable(ctx, survivor)
+	if err != nil {
+		return MergeRecord{}, err
+	}
+	rec := MergeRecord{
+		Survivor:      survivor,
+		SurvivorProps: target.Props,
+		Rewired:       map[string]string{},
+		Actor:         actor(ctx, ""),
+		CreatedAt:     time.Now().UTC().Format(time.RFC3339),
+	}
+	gone := map[string]bool{}
+	for _, id := range merged {
+		if id == survivor || gone[id] {
+			return MergeRecord{}, fmt.Errorf("%w: %s named twice", ErrInvalidMerge, id)
+		}
+		n, err := s.mergeable(ctx, id)
+		if err != nil {
+			return MergeRecord{}, err
+		}
+		gone[id] = true
+		rec.Merged = append(rec.Merged, n)
+	}
+
+	seen := map[string]bool{}
+	for _, n := range rec.Merged {
+		for _, q := range []graph.EdgeQuery{{From: n.ID}, {To: n.ID}} {
+			edges, err := s.graph.FindEdges(ctx, q)
+			if err != nil {
+				return MergeRecord{}, err
+			}
+			for _, e := range edges {
+				if !seen[e.ID] {
+					seen[e.ID] = true
+					rec.Edges = append(rec.Edges, e)
+				}
+			}
+		}
+	}
+
+	props, err := recordProps(rec)
+	if err != nil {
+		return MergeRecord{}, err
+	}
+	if rec.ID, err = s.graph.CreateNode(ctx, MergeLabel, withTenant(ctx, props)); err != nil {
+		return MergeRecord{}, err
+	}
+	if err := s.applyMerge(ctx, &rec, target, gone); err != nil {
+		if rerr := s.undo(ctx, &rec); rerr != nil {
+			return MergeRecord{}, errors.Join(err, fmt.Errorf("memory: merge %s not rolled back: %w", rec.ID, rerr))
+		}
+		if rerr := s.graph.DeleteNode(ctx, rec.ID); rerr != nil {
+			return MergeRecord{}, errors.Join(err, rerr)
+		}
+		return MergeRecord{}, err
+	}
+	return rec, nil
+}
+
+// applyMerge makes the graph changes rec records, adding the relationships
+// it moves to rec.Rewired. The record is saved before any node is deleted
+// so that it describes the graph should a later step fail.
+func (s *Service) applyMerge(ctx context.Context, rec *MergeRecord, target graph.Node, gone map[string]bool) error {
+	survivor := rec.Survivor
+	for _, e := range rec.Edges {
+		from, to := e.From, e.To
+		if gone[from] {
+			from = survivor
+		}
+		if gone[to] {
+			to = survivor
+		}
+		if from == survivor && to == survivor {
+			continue
+		}
+		dup, err := s.graph.FindEdges(ctx, graph.EdgeQuery{From: from, To: to, Type: e.Type})
+		if err != nil {
+			return err
+		}
+		if len(dup) > 0 {
+			continue
+		}
+		id, err := s.graph.CreateEdge(ctx, from, to, e.Type, e.Props)
+		if err != nil {
+			return err
+		}
+		rec.Rewired[e.ID] = id
+	}
+	props, err := recordProps(*rec)
+	if err != nil {
+		return err
+	}
+	if err := s.graph.UpdateNode(ctx, rec.ID, props); err != nil {
+		return err
+	}
+
+	if err := s.graph.UpdateNode(ctx, survivor, mergedProps(target, rec.Merged)); err != nil {
+		return err
+	}
+	for _, n := range rec.Merged {
+		if err := s.graph.DeleteNode(ctx, n.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeable returns entity id of the call's project.
+func (s *Service) mergeable(ctx context.Context, id string) (graph.Node, error) {
+	n, err := s.node(ctx, id)
+	if err != nil {
+		return graph.Node{}, err
+	}
+	if !isEntity(n) {
+		return graph.Node{}, fmt.Errorf("%w: %s is a %s node", ErrInvalidMerge, id, n.Label)
+	}
+	return n, nil
+}
+
+// mergedProps returns the property changes merging nodes into survivor
+// makes: the properties it lacks, and its aliases extended with their
+// names and aliases.
+func mergedProps(survivor graph.Node, nodes []graph.Node) map[string]interface{} {
+	out := map[string]interface{}{}
+	name, aliases := names(survivor.Props)
+	have := map[string]bool{name: true}
+	for _, a := range aliases {
+		have[a] = true
+	}
+	for _, n := range nodes {
+		for k, v := range n.Props {
+			if _, ok := survivor.Props[k]; !ok && k != "aliases" {
+				if _, ok := out[k]; !ok {
+					out[k] = v
+				}
+			}
+		}
+		other, more := names(n.Props)
+		for _, a := range append([]string{other}, more...) {
+			if a != "" && !have[a] {
+				have[a] = true
+				aliases = append(aliases, a)
+			}
+		}
+	}
+	if len(aliases) > 0 {
+		out["aliases"] = aliases
+	}
+	return out
+}
+
+// UndoMerge reverses merge id: the merged entities are re-created, with
+// new IDs, the relationships moved to the survivor are moved back and the
+// dropped ones restored, and the survivor's properties are reset to what
+// they were before the merge. Relationships to nodes deleted since are
+// not restored. It needs graph:write.
+func (s *Service) UndoMerge(ctx context.Context, id string) (MergeRecord, error) {
+	if err := auth.Require(ctx, auth.PermGraphWrite); err != nil {
+		return MergeRecord{}, err
+	}
+	rec, err := s.merge(ctx, id)
+	if err != nil {
+		return MergeRecord{}, err
+	}
+	if rec.UndoneAt != "" {
+		return MergeRecord{}, fmt.Errorf("%w: %s", ErrMergeUndone, id)
+	}
+	if err := s.undo(ctx, &rec); err != nil {
+		return MergeRecord{}, err
+	}
+
+	rec.UndoneAt = time.Now().UTC().Format(time.RFC3339)
+	props, err := recordProps(rec)
+	if err != nil {
+		return MergeRecord{}, err
+	}
+	if err := s.graph.UpdateNode(ctx, id, props); err != nil {
+		return MergeRecord{}, err
+	}
+	return rec, nil
+}
+
+// undo reverses the graph changes of rec, adding the nodes it re-creates
+// to rec.Restored. Merged nodes that still exist, as after a merge that
+// failed partway, are kept along with their relationships. Everything
+// undo writes is tagged with the call's project and only joins nodes of
+// it, so a record naming another project's nodes or relationships is
+// refused.
+func (s *Service) undo(ctx context.Context, rec *MergeRecord) error {
+	survivor, err := s.node(ctx, rec.Survivor)
+	if err != nil {
+		return err
+	}
+	ids := make([]string, len(rec.Merged))
+	for i, n := range rec.Merged {
+		if !isEntity(n) || !nodeInProject(ctx, n.Props) {
+			return fmt.Errorf("%w: %s is not an entity of the project", ErrInvalidMerge, n.ID)
+		}
+		ids[i] = n.ID
+	}
+	for _, e := range rec.Edges {
+		if !edgeInProject(ctx, e) {
+			return fmt.Errorf("%w: relationship %s is not in the project", ErrInvalidMerge, e.ID)
+		}
+	}
+	for _, moved := range rec.Rewired {
+		if _, err := s.edge(ctx, moved); errors.Is(err, graph.ErrEdgeNotFound) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if err := s.graph.DeleteEdge(ctx, moved); err != nil && !errors.Is(err, graph.ErrEdgeNotFound) {
+			return err
+		}
+	}
+	left, err := s.graph.Nodes(ctx, ids)
+	if err != nil {
+		return err
+	}
+	kept := map[string]bool{}
+	for _, n := range inProjectNodes(ctx, left) {
+		kept[n.ID] = true
+	}
+	rec.Restored = map[string]string{}
+	for _, n := range rec.Merged {
+		if kept[n.ID] {
+			continue
+		}
+		nid, err := s.graph.CreateNode(ctx, n.Label, withTenant(ctx, n.Props))
+		if err != nil {
+			return err
+		}
+		rec.Restored[n.ID] = nid
+	}
+edges:
+	for _, e := range rec.Edges {
+		from, fromOK := rec.Restored[e.From]
+		to, toOK := rec.Restored[e.To]
+		if !fromOK && !toOK {
+			// both ends were kept, and so was the relationship
+			continue
+		}
+		if !fromOK {
+			from = e.From
+		}
+		if !toOK {
+			to = e.To
+		}
+		for _, id := range []string{from, to} {
+			// nodes deleted since, or of another project, are skipped
+			if _, err := s.node(ctx, id); errors.Is(err, graph.ErrNodeNotFound) {
+				continue edges
+			} else if err != nil {
+				return err
+			}
+		}
+		if _, err := s.graph.CreateEdge(ctx, from, to, e.Type, withTenant(ctx, e.Props)); err != nil {
+			return err
+		}
+	}
+	reset := map[string]interface{}{}
+	for k := range survivor.Props {
+		if _, ok := rec.SurvivorProps[k]; !ok {
+			reset[k] = nil
+		}
+	}
+	for k, v := range withTenant(ctx, rec.SurvivorProps) {
+		reset[k] = v
+	}
+	return s.graph.UpdateNode(ctx, rec.Survivor, reset)
+}
+
+// Merges returns the merges recorded in the call's project, newest first.
+// It needs graph:read.
+func (s *Service) Merges(ctx context.Context) ([]MergeRecord, error) {
+	if err := auth.Require(ctx, auth.PermGraphRead); err != nil {
+		return nil, err
+	}
+	nodes, err := s.graph.FindNodes(ctx, MergeLabel, nil)
+	if err != nil {
+		return nil, err
+	}
+	out := []MergeRecord{}
+	for _, n := range inProjectNodes(ctx, nodes) {
+		rec, err := parseRecord(n)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, rec)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].CreatedAt > out[j].CreatedAt })
+	return out, nil
+}
+
+// Merge returns merge id of the call's project. It needs graph:read.
+func (s *Service) Merge(ctx context.Context, id string) (MergeRecord, error) {
+	if err := auth.Require(ctx, auth.PermGraphRead); err != nil {
+		return MergeRecord{}, err
+	}
+	return s.merge(ctx, id)
+}
+
+func (s *Service) merge(ctx context.Context, id string) (MergeRecord, error) {
+	n, err := s.node(ctx, id)
+	if errors.Is(err, graph.ErrNodeNotFound) || (err == nil && n.Label != MergeLabel) {
+		return MergeRecord{}, fmt.Errorf("%w: %s", ErrMergeNotFound, id)
+	}
+	if err != nil {
+		return MergeRecord{}, err
+	}
+	return parseRecord(n)
+}
+
+// recordProps returns the properties of the Merge node keeping rec. Graph
+// properties cannot nest, so the record is kept as JSON.
+func recordProps(rec MergeRecord) (map[string]interface{}, error) {
+	rec.ID = ""
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"survivor": rec.Survivor, "record": string(b), "undone": rec.UndoneAt != ""}, nil
+}
+
+// parseRecord reads the record kept in Merge node n.
+func parseRecord(n graph.Node) (MergeRecord, error) {
+	raw, _ := n.Props["record"].(string)
+	dec := json.NewDecoder(bytes.NewReader([]byte(raw)))
+	dec.UseNumber()
+	var rec MergeRecord
+	if err := dec.Decode(&rec); err != nil {
+		return MergeRecord{}, fmt.Errorf("memory: merge %s: %w", n.ID, err)
+	}
+	rec.ID = n.ID
+	rec.SurvivorProps = numbers(rec.SurvivorProps)
+	for i := range rec.Merged {
+		rec.Merged[i].Props = numbers(rec.Merged[i].Props)
+	}
+	for i := range rec.Edges {
+		rec.Edges[i].Props = numbers(rec.Edges[i].Props)
+	}
+	return rec, nil
+}
+
+// numbers converts the JSON numbers in props back to int64 when integral
+// and float64 otherwise, the types properties are stored with.
+func numbers(props map[string]interface{}) map[string]interface{} {
+	for k, v := range props {
+		props[k] = number(v)
+	}
+	return props
+}
+
+func number(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case []interface{}:
+		for i := range v {
+			v[i] = number(v[i])
+		}
+	}
+	return v
+}
@@ -0,0 +1,94 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"mem0-go/internal/db"
+)
+
+// change is a repository write to propagate to the vector and graph
+// stores.
+type change struct {
+	kind string
+	mem  db.Memory
+	emb  []float32
+}
+
+// write runs fn, which changes the repository and describes the change,
+// then propagates the change. With an outbox, fn runs in a transaction that
+// also queues the change as an event, so a failed propagation leaves the
+// event for the dispatcher instead of failing the write.
+func (s *Service) write(ctx context.Context, fn func(db.Repository) (change, error)) error {
+	if s.outbox == nil {
+		c, err := fn(s.repo)
+		if err != nil {
+			return err
+		}
+		return s.propagate(ctx, c)
+	}
+	var e db.OutboxEvent
+	err := s.outbox.InTx(ctx, func(tx db.TxRepository) error {
+		c, err := fn(tx)
+		if err != nil {
+			return err
+		}
+		e = db.OutboxEvent{Kind: c.kind, MemoryID: c.mem.ID}
+		e.ID, err = tx.AddOutbox(ctx, e)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	// Best effort: events left pending are retried by the dispatcher, and
+	// applying one twice is harmless.
+	if s.ApplyEvent(ctx, e) == nil {
+		_ = s.outbox.CompleteOutbox(ctx, e.ID)
+	}
+	return nil
+}
+
+// propagate brings the vector point and graph node of c.mem in line with
+// c.
+func (s *Service) propagate(ctx context.Context, c change) error {
+	if c.kind == db.OutboxDelete {
+		if err := s.vector.Delete(ctx, Collection, []string{fmt.Sprint(c.mem.ID)}); err != nil {
+			return err
+		}
+		return s.syncGraph(ctx, c.mem, true)
+	}
+	if err := s.index(ctx, c.mem, c.emb); err != nil {
+		return err
+	}
+	if err := s.syncGraph(ctx, c.mem, false); err != nil {
+		return err
+	}
+	s.link(ctx, c.mem)
+	return nil
+}
+
+// ApplyEvent applies an outbox event to the vector and graph stores from
+// the memory's current row. It is idempotent: an upsert for a memory that
+// has since been deleted does nothing, leaving cleanup to the delete event.
+func (s *Service) ApplyEvent(ctx context.Context, e db.OutboxEvent) error {
+	switch e.Kind {
+	case db.OutboxDelete:
+		return s.propagate(ctx, change{kind: e.Kind, mem: db.Memory{ID: e.MemoryID}})
+	case db.OutboxUpsert:
+		m, err := s.repo.GetMemory(ctx, e.MemoryID)
+		if errors.Is(err, db.ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		emb, err := s.repo.GetEmbedding(ctx, e.MemoryID)
+		if err != nil {
+			return err
+		}
+		return s.propagate(ctx, change{kind: e.Kind, mem: m, emb: emb})
+	default:
+		return fmt.Errorf("memory: unknown outbox event %q", e.Kind)
+	}
+}
@@ -0,0 +1,271 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"mem0-go/internal/auth"
+	"mem0-go/internal/db"
+)
+
+// reconcileBatch is the page size used when scanning the stores.
+const reconcileBatch = 256
+
+// ReconcileOptions controls Reconcile.
+type ReconcileOptions struct {
+	// Repair fixes the drift found; otherwise Reconcile only reports it.
+	Repair bool
+	// Dimension is the expected vector size. When zero the size of each
+	// memory's stored embedding is expected.
+	Dimension int
+}
+
+// DimensionMismatch reports a memory whose point or stored embedding has
+// the wrong size.
+type DimensionMismatch struct {
+	MemoryID  int64 `json:"memoryID"`
+	Point     int   `json:"point,omitempty"`
+	Embedding int   `json:"embedding"`
+	Want      int   `json:"want"`
+}
+
+// ReconcileReport describes drift between Postgres, the vector collection
+// and the graph. Postgres is the source of truth.
+type ReconcileReport struct {
+	Memories int `json:"memories"`
+	Points   int `json:"points"`
+	Nodes    int `json:"nodes"`
+	Edges    int `json:"edges"`
+	// MissingPoints are memories without a vector point.
+	MissingPoints []int64 `json:"missingPoints"`
+	// OrphanPoints are points without a memory; StalePoints are tagged
+	// with a different project than their memory.
+	OrphanPoints        []string            `json:"orphanPoints"`
+	StalePoints         []int64             `json:"stalePoints"`
+	DimensionMismatches []DimensionMismatch `json:"dimensionMismatches"`
+	// MissingNodes are memories without a graph node; StaleNodes have
+	// content or a project differing from their memory.
+	MissingNodes []int64  `json:"missingNodes"`
+	StaleNodes   []string `json:"staleNodes"`
+	// OrphanNodes are memory nodes without a memory.
+	OrphanNodes []string `json:"orphanNodes"`
+	// DanglingEdges point at nodes that do not exist.
+	DanglingEdges []string `json:"danglingEdges"`
+	Repaired      bool     `json:"repaired"`
+}
+
+// Drift reports whether any inconsistency was found.
+func (r ReconcileReport) Drift() bool {
+	return len(r.MissingPoints)+len(r.OrphanPoints)+len(r.StalePoints)+len(r.DimensionMismatches)+
+		len(r.MissingNodes)+len(r.StaleNodes)+len(r.OrphanNodes)+len(r.DanglingEdges) > 0
+}
+
+// Reconcile scans the repository, the vector collection and the graph and
+// reports where they disagree. With opts.Repair it re-upserts points and
+// nodes from the repository, re-embedding memories whose stored embedding
+// has the wrong size, and deletes orphan points, nodes and dangling edges.
+// It spans every user and project and needs the reconcile permission.
+func (s *Service) Reconcile(ctx context.Context, opts ReconcileOptions) (ReconcileReport, error) {
+	if err := auth.Require(ctx, auth.PermReconcile); err != nil {
+		return ReconcileReport{}, err
+	}
+	rep := ReconcileReport{
+		MissingPoints:       []int64{},
+		OrphanPoints:        []string{},
+		StalePoints:         []int64{},
+		DimensionMismatches: []DimensionMismatch{},
+		MissingNodes:        []int64{},
+		StaleNodes:          []string{},
+		OrphanNodes:         []string{},
+		DanglingEdges:       []string{},
+	}
+
+	// memories and the size of their stored embedding
+	dims := map[int64]int{}
+	contents := map[int64]string{}
+	projects := map[int64]int64{}
+	for after := int64(0); ; {
+		page, err := s.repo.ListMemories(ctx, db.MemoryQuery{AfterID: after, Limit: reconcileBatch})
+		if err != nil {
+			return rep, err
+		}
+		for _, m := range page {
+			emb, err := s.repo.GetEmbedding(ctx, m.ID)
+			if err != nil && !errors.Is(err, db.ErrNotFound) {
+				return rep, err
+			}
+			dims[m.ID] = len(emb)
+			contents[m.ID] = m.Content
+			projects[m.ID] = m.ProjectID
+			after = m.ID
+		}
+		if len(page) < reconcileBatch {
+			break
+		}
+	}
+	rep.Memories = len(dims)
+	want := func(id int64) int {
+		if opts.Dimension > 0 {
+			return opts.Dimension
+		}
+		return dims[id]
+	}
+
+	// vector points
+	repair := map[int64]bool{}
+	seen := map[int64]bool{}
+	for offset := ""; ; {
+		pts, next, err := s.vector.Scroll(ctx, Collection, offset, reconcileBatch)
+		if err != nil {
+			return rep, err
+		}
+		for _, p := range pts {
+			rep.Points++
+			id, err := strconv.ParseInt(p.ID, 10, 64)
+			if _, ok := dims[id]; err != nil || !ok {
+				rep.OrphanPoints = append(rep.OrphanPoints, p.ID)
+				continue
+			}
+			seen[id] = true
+			if project, _ := intValue(p.Payload["project_id"]); project != projects[id] {
+				rep.StalePoints = append(rep.StalePoints, id)
+				repair[id] = true
+			}
+			if len(p.Vector) != want(id) || dims[id] != want(id) {
+				rep.DimensionMismatches = append(rep.DimensionMismatches,
+					DimensionMismatch{MemoryID: id, Point: len(p.Vector), Embedding: dims[id], Want: want(id)})
+				repair[id] = true
+			}
+		}
+		if next == "" {
+			break
+		}
+		offset = next
+	}
+	for _, id := range sortedIDs(dims) {
+		if !seen[id] {
+			rep.MissingPoints = append(rep.MissingPoints, id)
+			repair[id] = true
+			if dims[id] != want(id) {
+				rep.DimensionMismatches = append(rep.DimensionMismatches,
+					DimensionMismatch{MemoryID: id, Embedding: dims[id], Want: want(id)})
+			}
+		}
+	}
+
+	// graph
+	nodes, err := s.graph.FindNodes(ctx, "", nil)
+	if err != nil {
+		return rep, err
+	}
+	rep.Nodes = len(nodes)
+	exists := map[string]bool{}
+	linked := map[int64]bool{}
+	for _, n := range nodes {
+		exists[n.ID] = true
+		if n.Label != MemoryLabel {
+			continue
+		}
+		id, ok := intValue(n.Props["memory_id"])
+		if _, found := dims[id]; !ok || !found {
+			rep.OrphanNodes = append(rep.OrphanNodes, n.ID)
+			continue
+		}
+		linked[id] = true
+		if project, _ := intValue(n.Props["project_id"]); n.Props["content"] != contents[id] || project != projects[id] {
+			rep.StaleNodes = append(rep.StaleNodes, n.ID)
+			repair[id] = true
+		}
+	}
+	for _, id := range sortedIDs(dims) {
+		if !linked[id] {
+			rep.MissingNodes = append(rep.MissingNodes, id)
+			repair[id] = true
+		}
+	}
+	edges, err := s.graph.Edges(ctx)
+	if err != nil {
+		return rep, err
+	}
+	rep.Edges = len(edges)
+	for _, e := range edges {
+		if !exists[e.From] || !exists[e.To] {
+			rep.DanglingEdges = append(rep.DanglingEdges, e.ID)
+		}
+	}
+
+	if !opts.Repair || !rep.Drift() {
+		return rep, nil
+	}
+	if len(rep.OrphanPoints) > 0 {
+		if err := s.vector.Delete(ctx, Collection, rep.OrphanPoints); err != nil {
+			return rep, err
+		}
+	}
+	for _, id := range rep.OrphanNodes {
+		if err := s.graph.DeleteNode(ctx, id); err != nil {
+			return rep, err
+		}
+	}
+	for _, id := range rep.DanglingEdges {
+		if err := s.graph.DeleteEdge(ctx, id); err != nil {
+			return rep, err
+		}
+	}
+	for _, id := range sortedIDs(repair) {
+		if err := s.repair(ctx, id, want(id)); err != nil {
+			return rep, fmt.Errorf("repair memory %d: %w", id, err)
+		}
+	}
+	rep.Repaired = true
+	return rep, nil
+}
+
+// repair re-upserts memory id's point and graph node from the repository,
+// re-embedding its content first when the stored embedding is not dim long.
+func (s *Service) repair(ctx context.Context, id int64, dim int) error {
+	m, err := s.repo.GetMemory(ctx, id)
+	if err != nil {
+		return err
+	}
+	emb, err := s.repo.GetEmbedding(ctx, id)
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		return err
+	}
+	if len(emb) != dim || len(emb) == 0 {
+		if emb, err = s.embed(ctx, m.Content); err != nil {
+			return err
+		}
+		if err := s.repo.AddEmbedding(ctx, id, emb); err != nil {
+			return err
+		}
+	}
+	return s.propagate(ctx, change{kind: db.OutboxUpsert, mem: m, emb: emb})
+}
+
+// intValue reads a numeric node property or payload value, such as
+// memory_id or project_id, which may have been decoded as any numeric
+// type.
+func intValue(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return n, true
+	case int:
+		return int64(n), true
+	case float64:
+		return int64(n), true
+	}
+	return 0, false
+}
+
+func sortedIDs[V any](m map[int64]V) []int64 {
+	ids := make([]int64, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
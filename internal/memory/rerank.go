@@ -0,0 +1,97 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"mem0-go/internal/db"
+	"mem0-go/internal/rerank"
+)
+
+// rerankCandidates is how many candidates per requested result a reranked
+// search retrieves by default.
+const rerankCandidates = 4
+
+// searchReranked retrieves the candidates of req and reorders them with
+// its rerank pipeline.
+func (s *Service) searchReranked(ctx context.Context, req SearchRequest) ([]MemoryResult, error) {
+	pipeline, err := rerank.Build(req.Rerank, s.crossEnc)
+	if err != nil {
+		return nil, err
+	}
+	vectors := false
+	for _, spec := range req.Rerank {
+		if spec.Type == rerank.TypeCrossEncoder && req.Query == "" {
+			return nil, fmt.Errorf("%w: cross-encoder reranking needs a query", ErrInvalidSearch)
+		}
+		vectors = vectors || spec.Type == rerank.TypeMMR
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	first := req
+	first.Limit = limit * rerankCandidates
+	if req.Candidates > 0 {
+		first.Limit = max(req.Candidates, limit)
+	}
+	res, err := s.retrieve(ctx, first)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int64]MemoryResult, len(res))
+	rank := make(map[int64]int, len(res))
+	cands := make([]rerank.Candidate, 0, len(res))
+	for i, r := range res {
+		m, err := s.repo.GetMemory(ctx, r.ID)
+		if errors.Is(err, db.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		c := rerank.Candidate{ID: r.ID, Text: m.Content, Score: r.Score, Importance: importance(m.Metadata)}
+		c.CreatedAt, _ = parseTime(m.CreatedAt)
+		if vectors {
+			if c.Vector, err = s.repo.GetEmbedding(ctx, r.ID); err != nil && !errors.Is(err, db.ErrNotFound) {
+				return nil, err
+			}
+		}
+		byID[r.ID], rank[r.ID] = r, i+1
+		cands = append(cands, c)
+	}
+	cands, err = pipeline.Rerank(ctx, req.Query, cands)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]MemoryResult, 0, min(limit, len(cands)))
+	for _, c := range cands[:min(limit, len(cands))] {
+		r := byID[c.ID]
+		e := ScoreExplanation{}
+		if r.Explanation != nil {
+			e = *r.Explanation
+		}
+		e.RetrievalRank, e.RetrievalScore = rank[c.ID], r.Score
+		r.Score, r.Explanation = c.Score, &e
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+// importance reads the "importance" metadata of a memory, clamped to
+// [0, 1].
+func importance(md map[string]interface{}) float64 {
+	var v float64
+	switch n := md["importance"].(type) {
+	case float64:
+		v = n
+	case int:
+		v = float64(n)
+	case int64:
+		v = float64(n)
+	}
+	return min(max(v, 0), 1)
+}
@@ -0,0 +1,255 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+
+	"mem0-go/internal/auth"
+	"mem0-go/internal/graph"
+	"mem0-go/internal/vector"
+)
+
+// Reasons a pair of entities is reported as a candidate duplicate.
+const (
+	// ReasonName means the names are equal once normalized, or one's words
+	// begin the other's, as "Alice" and "alice cooper".
+	ReasonName = "name"
+	// ReasonAlias means a name or alias of one matches one of the other's,
+	// counting common nicknames, as "Bob" and "Robert Smith".
+	ReasonAlias = "alias"
+	// ReasonEmbedding means the descriptions embed close together.
+	ReasonEmbedding = "embedding"
+)
+
+// Defaults for DuplicateOptions.
+const (
+	DefaultMinDuplicateScore  = 0.75
+	DefaultEmbeddingThreshold = 0.9
+)
+
+// DuplicateOptions tunes FindDuplicates.
+type DuplicateOptions struct {
+	// Label restricts the search to entities with the label; all when
+	// empty.
+	Label string
+	// MinScore is the lowest score reported, DefaultMinDuplicateScore
+	// when 0.
+	MinScore float64
+	// EmbeddingThreshold is the lowest cosine similarity of two
+	// descriptions that counts, DefaultEmbeddingThreshold when 0.
+	EmbeddingThreshold float64
+	// Limit caps the candidates returned; all when 0.
+	Limit int
+}
+
+// DuplicateCandidate is a pair of entities that may be the same thing.
+type DuplicateCandidate struct {
+	A       graph.Node `json:"a"`
+	B       graph.Node `json:"b"`
+	Score   float64    `json:"score"`
+	Reasons []string   `json:"reasons"`
+}
+
+// nicknames maps common nicknames to the name they are short for.
+var nicknames = map[string]string{
+	"bob": "robert", "rob": "robert", "robbie": "robert", "bobby": "robert",
+	"bill": "william", "will": "william", "billy": "william", "liam": "william",
+	"jim": "james", "jimmy": "james", "jamie": "james",
+	"mike": "michael", "mick": "michael", "tom": "thomas", "tommy": "thomas",
+	"dick": "richard", "rick": "richard", "rich": "richard",
+	"dave": "david", "dan": "daniel", "danny": "daniel", "joe": "joseph",
+	"chris": "christopher", "alex": "alexander", "sam": "samuel", "ben": "benjamin",
+	"tony": "anthony", "steve": "steven", "matt": "matthew", "nick": "nicholas",
+	"andy": "andrew", "drew": "andrew", "ed": "edward", "ted": "edward",
+	"liz": "elizabeth", "beth": "elizabeth", "betty": "elizabeth", "lizzie": "elizabeth",
+	"kate": "katherine", "katie": "katherine", "kathy": "katherine",
+	"jen": "jennifer", "jenny": "jennifer", "sue": "susan", "maggie": "margaret",
+	"peggy": "margaret", "meg": "margaret", "pat": "patricia", "patty": "patricia",
+}
+
+// titles are honorifics dropped when normalizing names.
+var titles = map[string]bool{"mr": true, "mrs": true, "ms": true, "miss": true, "dr": true, "prof": true, "sir": true}
+
+// nameWords returns the lowercased words of name without punctuation and
+// titles.
+func nameWords(name string) []string {
+	var out []string
+	for _, w := range strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	}) {
+		if w = strings.Trim(w, "'"); w != "" && !titles[w] {
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+// canonical replaces nicknames in words by the names they stand for.
+func canonical(words []string) []string {
+	out := make([]string, len(words))
+	for i, w := range words {
+		if full, ok := nicknames[w]; ok {
+			w = full
+		}
+		out[i] = w
+	}
+	return out
+}
+
+// matchWords scores two normalized names: 1 when equal, 0.8 when the
+// shorter shares the longer's first word and all its words are in the
+// longer, and 0 otherwise.
+func matchWords(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	if strings.Join(a, " ") == strings.Join(b, " ") {
+		return 1
+	}
+	if a[0] != b[0] {
+		return 0
+	}
+	in := map[string]bool{}
+	for _, w := range b {
+		in[w] = true
+	}
+	for _, w := range a {
+		if !in[w] {
+			return 0
+		}
+	}
+	return 0.8
+}
+
+// entityInfo is what FindDuplicates compares an entity by.
+type entityInfo struct {
+	node    graph.Node
+	name    []string
+	aliases [][]string
+	vec     []float32
+}
+
+// names returns the name and aliases of node props.
+func names(props map[string]interface{}) (string, []string) {
+	name, _ := props["name"].(string)
+	var aliases []string
+	switch v := props["aliases"].(type) {
+	case []string:
+		aliases = v
+	case []interface{}:
+		for _, a := range v {
+			if s, ok := a.(string); ok {
+				aliases = append(aliases, s)
+			}
+		}
+	}
+	return name, aliases
+}
+
+// FindDuplicates reports pairs of entities of the call's project that may
+// name the same thing, best first. Names are compared after lowercasing
+// and dropping punctuation and titles; aliases, from each entity's
+// "aliases" property and a table of common nicknames, are compared the
+// same way; and when an embedder is configured, entities whose
+// "description" properties embed within opts.EmbeddingThreshold of each
+// other are reported too. A pair scores the best of its reasons. It needs
+// graph:read.
+func (s *Service) FindDuplicates(ctx context.Context, opts DuplicateOptions) ([]DuplicateCandidate, error) {
+	if err := auth.Require(ctx, auth.PermGraphRead); err != nil {
+		return nil, err
+	}
+	if opts.MinScore == 0 {
+		opts.MinScore = DefaultMinDuplicateScore
+	}
+	if opts.EmbeddingThreshold == 0 {
+		opts.EmbeddingThreshold = DefaultEmbeddingThreshold
+	}
+	nodes, err := s.graph.FindNodes(ctx, opts.Label, nil)
+	if err != nil {
+		return nil, err
+	}
+	var infos []entityInfo
+	var descs []string
+	var described []int
+	for _, n := range inProjectNodes(ctx, nodes) {
+		if !isEntity(n) {
+			continue
+		}
+		name, aliases := names(n.Props)
+		info := entityInfo{node: n, name: nameWords(name)}
+		for _, a := range append([]string{name}, aliases...) {
+			if w := canonical(nameWords(a)); len(w) > 0 {
+				info.aliases = append(info.aliases, w)
+			}
+		}
+		if d, _ := n.Props["description"].(string); d != "" && s.embedder != nil {
+			descs = append(descs, d)
+			described = append(described, len(infos))
+		}
+		infos = append(infos, info)
+	}
+	if len(descs) > 0 {
+		vecs, err := s.embedder.Embed(ctx, descs)
+		if err != nil {
+			return nil, err
+		}
+		for i, v := range vecs {
+			infos[described[i]].vec = v
+		}
+	}
+
+	out := []DuplicateCandidate{}
+	for i := range infos {
+		for j := i + 1; j < len(infos); j++ {
+			c := compareEntities(infos[i], infos[j], opts.EmbeddingThreshold)
+			if c.Score >= opts.MinScore {
+				out = append(out, c)
+			}
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	if opts.Limit > 0 && len(out) > opts.Limit {
+		out = out[:opts.Limit]
+	}
+	return out, nil
+}
+
+// compareEntities scores a and b by each reason.
+func compareEntities(a, b entityInfo, threshold float64) DuplicateCandidate {
+	c := DuplicateCandidate{A: a.node, B: b.node, Reasons: []string{}}
+	add := func(reason string, score float64) {
+		c.Reasons = append(c.Reasons, reason)
+		if score > c.Score {
+			c.Score = score
+		}
+	}
+	if score := matchWords(a.name, b.name); score > 0 {
+		add(ReasonName, score)
+	}
+	best := 0.0
+	for _, x := range a.aliases {
+		for _, y := range b.aliases {
+			best = max(best, matchWords(x, y))
+		}
+	}
+	// Aliases include the names, so only count them when they add to the
+	// plain name comparison.
+	if best > 0 && best*0.95 > c.Score {
+		add(ReasonAlias, best*0.95)
+	}
+	if len(a.vec) > 0 && len(a.vec) == len(b.vec) {
+		if sim := float64(vector.Cosine.Score(a.vec, b.vec)); sim >= threshold {
+			add(ReasonEmbedding, sim)
+		}
+	}
+	return c
+}
+
+// isEntity reports whether n is an entity rather than a node the service
+// keeps for its own bookkeeping.
+func isEntity(n graph.Node) bool { return n.Label != MemoryLabel && n.Label != MergeLabel }
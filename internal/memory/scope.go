@@ -0,0 +1,144 @@
+package memory
+
+import (
+	"context"
+
+	"mem0-go/internal/auth"
+	"mem0-go/internal/db"
+	"mem0-go/internal/events"
+	"mem0-go/internal/graph"
+	"mem0-go/internal/vector"
+)
+
+// Calls are scoped by the principal in their context. Calls without one
+// come from trusted code such as the workers and act for any user, as do
+// admins; everyone else only sees and changes their own memories. Each
+// call also needs the permission for what it does, such as memories:read
+// or graph:write, failing with an *auth.PermissionError without it.
+//
+// Every call is also confined to one project: the project of its
+// principal's credentials, or the default project 0. Memories, points,
+// nodes and relationships carry org_id and project_id, left out for the
+// default project so data from before projects existed stays in it.
+
+// scopeUser returns the user a call for userID acts on. Principals that
+// are not admins may only act on themselves, which a userID of 0 defaults
+// to.
+func scopeUser(ctx context.Context, userID int64) (int64, error) {
+	p := auth.FromContext(ctx)
+	if p == nil || p.Admin() {
+		return userID, nil
+	}
+	if p.UserID == 0 || (userID != 0 && userID != p.UserID) {
+		return 0, auth.ErrForbidden
+	}
+	return p.UserID, nil
+}
+
+// visible reports whether the caller may see memories of userID.
+func visible(ctx context.Context, userID int64) bool {
+	p := auth.FromContext(ctx)
+	return p == nil || p.Admin() || (p.UserID != 0 && p.UserID == userID)
+}
+
+// tenant returns the organization and project a call acts in.
+func tenant(ctx context.Context) (orgID, projectID int64) {
+	if p := auth.FromContext(ctx); p != nil {
+		return p.OrgID, p.ProjectID
+	}
+	return 0, 0
+}
+
+// inProject reports whether projectID is the call's project.
+func inProject(ctx context.Context, projectID int64) bool {
+	_, p := tenant(ctx)
+	return p == projectID
+}
+
+// canSee reports whether the caller may see m.
+func canSee(ctx context.Context, m db.Memory) bool {
+	return inProject(ctx, m.ProjectID) && visible(ctx, m.UserID)
+}
+
+// projectFilter confines f to the call's project. Points of the default
+// project have no project_id, so it excludes every point that has one.
+func projectFilter(ctx context.Context, f *vector.Filter) *vector.Filter {
+	if _, p := tenant(ctx); p != 0 {
+		return f.And(vector.MatchValue("project_id", p))
+	}
+	one := 1.0
+	out := &vector.Filter{}
+	if f != nil {
+		*out = *f
+	}
+	out.MustNot = append(append([]vector.Condition{}, out.MustNot...), vector.InRange("project_id", vector.Range{GTE: &one}))
+	return out
+}
+
+// withTenant returns a copy of props tagged with the call's organization
+// and project, dropping any tags the caller set itself.
+func withTenant(ctx context.Context, props map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(props)+2)
+	for k, v := range props {
+		if k != "org_id" && k != "project_id" {
+			out[k] = v
+		}
+	}
+	if org, p := tenant(ctx); p != 0 {
+		out["org_id"], out["project_id"] = org, p
+	}
+	return out
+}
+
+// nodeInProject reports whether props, of a node or relationship, belong
+// to the call's project.
+func nodeInProject(ctx context.Context, props map[string]interface{}) bool {
+	p, _ := intValue(props["project_id"])
+	return inProject(ctx, p)
+}
+
+// projectEdges narrows q to relationships tagged with the call's project.
+// Those of the default project carry no tag to match, so callers still
+// filter the results with edgeInProject.
+func projectEdges(ctx context.Context, q graph.EdgeQuery) graph.EdgeQuery {
+	if org, p := tenant(ctx); p != 0 {
+		props := map[string]interface{}{"org_id": org, "project_id": p}
+		for k, v := range q.Props {
+			props[k] = v
+		}
+		q.Props = props
+	}
+	return q
+}
+
+// edgeInProject reports whether e belongs to the call's project.
+func edgeInProject(ctx context.Context, e graph.Edge) bool { return nodeInProject(ctx, e.Props) }
+
+// actor returns who a change is recorded against: the principal when
+// there is one, and otherwise whoever the caller named.
+func actor(ctx context.Context, named string) string {
+	if p := auth.FromContext(ctx); p != nil && p.Subject != "" {
+		return p.Subject
+	}
+	return named
+}
+
+// SubscribeEvents subscribes to the events accepted by filter that the
+// caller may see: relationship events need graph:read and memory events
+// memories:read. It returns nil when the service publishes no events.
+func (s *Service) SubscribeEvents(ctx context.Context, filter func(events.Event) bool) *events.Subscription {
+	if s.events == nil {
+		return nil
+	}
+	p := auth.FromContext(ctx)
+	return s.events.Subscribe(func(e events.Event) bool {
+		if e.Kind == events.RelationshipCreated {
+			if !p.Can(auth.PermGraphRead) || !edgeInProject(ctx, e.Relationship) {
+				return false
+			}
+		} else if !p.Can(auth.PermMemoriesRead) || !canSee(ctx, e.Memory) {
+			return false
+		}
+		return filter == nil || filter(e)
+	})
+}
@@ -2,68 +2,388 @@ package memory
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
+	"time"
 
+	"mem0-go/internal/auth"
 	"mem0-go/internal/db"
+	"mem0-go/internal/events"
+	"mem0-go/internal/extract"
 	"mem0-go/internal/graph"
+	"mem0-go/internal/llm"
+	"mem0-go/internal/rerank"
 	"mem0-go/internal/vector"
 )
 
+// Collection is the vector collection memories are indexed in.
+const Collection = "memories"
+
 // Service orchestrates storage, search and relationships across
 // Postgres, Qdrant and Neo4j.
 // vectorStore defines the subset of vector.Client used by Service.
 type vectorStore interface {
 	Upsert(ctx context.Context, collection string, pts []vector.Point) error
-	Query(ctx context.Context, collection string, vector []float32, limit int) ([]vector.QueryResult, error)
+	Query(ctx context.Context, collection string, vector []float32, limit int, filter *vector.Filter) ([]vector.QueryResult, error)
+	Delete(ctx context.Context, collection string, ids []string) error
+	Scroll(ctx context.Context, collection, offset string, limit int) ([]vector.Point, string, error)
 }
 
 type graphStore interface {
 	CreateNode(ctx context.Context, label string, props map[string]interface{}) (string, error)
 	CreateEdge(ctx context.Context, from, to, relType string, props map[string]interface{}) (string, error)
 	Neighbors(ctx context.Context, id, relType string) ([]graph.Node, error)
+	FindNodes(ctx context.Context, label string, props map[string]interface{}) ([]graph.Node, error)
+	UpdateNode(ctx context.Context, id string, props map[string]interface{}) error
+	DeleteNode(ctx context.Context, id string) error
+	Edges(ctx context.Context) ([]graph.Edge, error)
+	Edge(ctx context.Context, id string) (graph.Edge, error)
+	FindEdges(ctx context.Context, q graph.EdgeQuery) ([]graph.Edge, error)
+	UpdateEdge(ctx context.Context, id string, props map[string]interface{}) error
+	DeleteEdge(ctx context.Context, id string) error
+	Nodes(ctx context.Context, ids []string) ([]graph.Node, error)
+	Expand(ctx context.Context, ids []string, dir graph.Direction, types []string) (map[string][]graph.Hop, error)
+	Traverse(ctx context.Context, q graph.TraversalQuery) ([]graph.Visit, error)
+	ShortestPath(ctx context.Context, from, to string, e graph.Expansion, maxDepth int) (*graph.Path, error)
+	Subgraph(ctx context.Context, center string, e graph.Expansion, depth int) (graph.Subgraph, error)
+}
+
+// MemoryLabel is the graph label of the node each memory is linked to.
+const MemoryLabel = "Memory"
+
+// embedder turns text into vectors.
+type embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
 }
 
 type Service struct {
-	repo   db.Repository
-	vector vectorStore
-	graph  graphStore
+	repo      db.Repository
+	text      db.TextSearcher
+	crossEnc  rerank.Reranker
+	outbox    db.TxRepository
+	vector    vectorStore
+	graph     graphStore
+	llm       llm.Provider
+	embedder  embedder
+	events    *events.Bus
+	extractor extract.Extractor
+	links     linkQueue
+}
+
+// Option configures optional Service dependencies.
+type Option func(*Service)
+
+// WithLLM sets the provider used to extract and reconcile facts.
+func WithLLM(p llm.Provider) Option { return func(s *Service) { s.llm = p } }
+
+// WithEmbedder sets the embedder used for server-side embeddings.
+func WithEmbedder(e embedder) Option { return func(s *Service) { s.embedder = e } }
+
+// WithCrossEncoder sets the reranker serving cross-encoder stages of
+// search rerank pipelines.
+func WithCrossEncoder(r rerank.Reranker) Option { return func(s *Service) { s.crossEnc = r } }
+
+// WithEvents publishes every committed write to bus.
+func WithEvents(bus *events.Bus) Option { return func(s *Service) { s.events = bus } }
+
+// WithExtractor links every stored memory to the entities it mentions
+// with x; see LinkMemory.
+func WithExtractor(x extract.Extractor) Option { return func(s *Service) { s.extractor = x } }
+
+// WithLinkQueue hands linking stored memories to their entities to q
+// instead of doing it as part of the write.
+func WithLinkQueue(q linkQueue) Option { return func(s *Service) { s.links = q } }
+
+// WithOutbox makes writes transactional: rows are committed together with
+// an outbox event that is applied to the vector and graph stores straight
+// away when possible and otherwise by the outbox dispatcher via ApplyEvent.
+// The repository must be a db.TxRepository.
+func WithOutbox() Option {
+	return func(s *Service) {
+		tx, ok := s.repo.(db.TxRepository)
+		if !ok {
+			panic("memory: WithOutbox requires a db.TxRepository")
+		}
+		s.outbox = tx
+	}
 }
 
-// NewService constructs a Service.
-func NewService(repo db.Repository, v vectorStore, g graphStore) *Service {
-	return &Service{repo: repo, vector: v, graph: g}
+// NewService constructs a Service. Keyword and hybrid searches need repo
+// to be a db.TextSearcher.
+func NewService(repo db.Repository, v vectorStore, g graphStore, opts ...Option) *Service {
+	s := &Service{repo: repo, vector: v, graph: g}
+	s.text, _ = repo.(db.TextSearcher)
+	for _, o := range opts {
+		o(s)
+	}
+	return s
 }
 
-// GetMemory retrieves a memory record by ID.
+// GetMemory retrieves a memory record by ID. Memories of other projects,
+// and of other users to principals scoped to their own, are reported as
+// not found.
 func (s *Service) GetMemory(ctx context.Context, id int64) (db.Memory, error) {
-	return s.repo.GetMemory(ctx, id)
+	if err := auth.Require(ctx, auth.PermMemoriesRead); err != nil {
+		return db.Memory{}, err
+	}
+	return s.get(ctx, id)
+}
+
+// get is GetMemory without the permission check, for writes.
+func (s *Service) get(ctx context.Context, id int64) (db.Memory, error) {
+	m, err := s.repo.GetMemory(ctx, id)
+	if err != nil {
+		return db.Memory{}, err
+	}
+	if !canSee(ctx, m) {
+		return db.Memory{}, db.ErrNotFound
+	}
+	return m, nil
+}
+
+// ErrNoEmbedder is returned when text must be embedded server-side but the
+// service has no embedder configured.
+var ErrNoEmbedder = errors.New("memory: no embedder configured")
+
+// StoreRequest describes a memory to store. Vector is optional; Content is
+// embedded server-side when it is empty. Actor is recorded in the history
+// unless the context carries a principal, which is recorded instead.
+type StoreRequest struct {
+	UserID   int64
+	AgentID  string
+	RunID    string
+	Content  string
+	Tags     []string
+	Metadata map[string]interface{}
+	Vector   []float32
+	Actor    string
 }
 
 // StoreMemory persists the text and embedding then indexes it in Qdrant.
+// When emb is empty the content is embedded server-side.
 func (s *Service) StoreMemory(ctx context.Context, userID int64, content string, emb []float32) (int64, error) {
-	id, err := s.repo.CreateMemory(ctx, userID, content)
-	if err != nil {
+	return s.Store(ctx, StoreRequest{UserID: userID, Content: content, Vector: emb})
+}
+
+// Store persists req in the call's project and indexes it in the vector
+// store with its scope, tags and metadata as payload so searches can be
+// filtered on them. A Memory node is created in the graph for entities to
+// link to.
+func (s *Service) Store(ctx context.Context, req StoreRequest) (int64, error) {
+	if err := auth.Require(ctx, auth.PermMemoriesWrite); err != nil {
 		return 0, err
 	}
-	if err := s.repo.AddEmbedding(ctx, id, emb); err != nil {
+	userID, err := scopeUser(ctx, req.UserID)
+	if err != nil {
 		return 0, err
 	}
-	if err := s.vector.Upsert(ctx, "memories", []vector.Point{{ID: fmt.Sprint(id), Vector: emb}}); err != nil {
+	req.UserID, req.Actor = userID, actor(ctx, req.Actor)
+	emb := req.Vector
+	if len(emb) == 0 {
+		var err error
+		if emb, err = s.embed(ctx, req.Content); err != nil {
+			return 0, err
+		}
+	}
+	m := db.Memory{
+		UserID:    req.UserID,
+		AgentID:   req.AgentID,
+		RunID:     req.RunID,
+		Content:   req.Content,
+		Tags:      req.Tags,
+		Metadata:  req.Metadata,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+	m.OrgID, m.ProjectID = tenant(ctx)
+	err = s.write(ctx, func(repo db.Repository) (change, error) {
+		id, err := repo.CreateMemory(ctx, m)
+		if err != nil {
+			return change{}, err
+		}
+		m.ID = id
+		if err := repo.AddEmbedding(ctx, id, emb); err != nil {
+			return change{}, err
+		}
+		return change{kind: db.OutboxUpsert, mem: m, emb: emb}, record(ctx, repo, db.HistoryAdd, req.Actor, nil, &m)
+	})
+	if err != nil {
 		return 0, err
 	}
-	return id, nil
+	s.publish(events.Event{Kind: events.MemoryAdded, Memory: m, Actor: req.Actor})
+	return m.ID, nil
+}
+
+// Events returns the bus writes are published to, or nil.
+func (s *Service) Events() *events.Bus { return s.events }
+
+// publish sends e to the event bus, if there is one.
+func (s *Service) publish(e events.Event) {
+	if s.events != nil {
+		s.events.Publish(e)
+	}
+}
+
+// index upserts m's vector point.
+func (s *Service) index(ctx context.Context, m db.Memory, emb []float32) error {
+	return s.vector.Upsert(ctx, Collection, []vector.Point{{ID: fmt.Sprint(m.ID), Vector: emb, Payload: payload(m)}})
+}
+
+// payload returns the vector payload indexed for m. created_at is stored as
+// Unix seconds so it can be filtered with a range condition; org_id and
+// project_id are left out in the default project.
+func payload(m db.Memory) map[string]interface{} {
+	p := map[string]interface{}{"user_id": m.UserID}
+	if m.ProjectID != 0 {
+		p["org_id"], p["project_id"] = m.OrgID, m.ProjectID
+	}
+	if m.AgentID != "" {
+		p["agent_id"] = m.AgentID
+	}
+	if m.RunID != "" {
+		p["run_id"] = m.RunID
+	}
+	if len(m.Tags) > 0 {
+		p["tags"] = m.Tags
+	}
+	if len(m.Metadata) > 0 {
+		p["metadata"] = m.Metadata
+	}
+	if t, err := parseTime(m.CreatedAt); err == nil {
+		p["created_at"] = t.Unix()
+	}
+	return p
 }
 
-// MemoryResult represents a search match.
+// pgTimestamp is the text form of a Postgres timestamptz.
+const pgTimestamp = "2006-01-02 15:04:05.999999999Z07"
+
+// parseTime parses a CreatedAt timestamp, in RFC 3339 or as Postgres
+// prints it.
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(pgTimestamp, s)
+}
+
+// embed returns the server-side embedding of text.
+func (s *Service) embed(ctx context.Context, text string) ([]float32, error) {
+	if s.embedder == nil {
+		return nil, ErrNoEmbedder
+	}
+	vecs, err := s.embedder.Embed(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	if len(vecs) != 1 {
+		return nil, fmt.Errorf("memory: embedder returned %d vectors for 1 text", len(vecs))
+	}
+	return vecs[0], nil
+}
+
+// MemoryResult represents a search match. Explanation breaks the score
+// of keyword and hybrid matches down into its components.
 type MemoryResult struct {
-	ID    int64
-	Score float32
+	ID          int64                  `json:"id"`
+	Score       float32                `json:"score"`
+	Payload     map[string]interface{} `json:"payload,omitempty"`
+	Explanation *ScoreExplanation      `json:"explanation,omitempty"`
+}
+
+// SearchRequest describes a memory search. Query is embedded server-side
+// unless Vector is set. UserID, AgentID, RunID and Tags are shorthands that
+// are combined with Filter; zero values leave the search unrestricted.
+//
+// Mode is SearchVector, the default, SearchKeyword or SearchHybrid, which
+// fuses vector and keyword results with Fusion: FusionRRF, the default, or
+// FusionWeighted, giving the vector score a VectorWeight of 0.5 unless set.
+//
+// Rerank, when set, reorders the top Candidates results, four times Limit
+// by default, through a pipeline of rerankers before they are cut to
+// Limit.
+type SearchRequest struct {
+	Query        string
+	Vector       []float32
+	Limit        int
+	UserID       int64
+	AgentID      string
+	RunID        string
+	Tags         []string
+	Filter       *vector.Filter
+	Mode         string
+	Fusion       string
+	VectorWeight *float32
+	Rerank       []rerank.Spec
+	Candidates   int
+}
+
+// filter returns the vector filter for req.
+func (req SearchRequest) filter() *vector.Filter {
+	var conds []vector.Condition
+	if req.UserID != 0 {
+		conds = append(conds, vector.MatchValue("user_id", req.UserID))
+	}
+	if req.AgentID != "" {
+		conds = append(conds, vector.MatchValue("agent_id", req.AgentID))
+	}
+	if req.RunID != "" {
+		conds = append(conds, vector.MatchValue("run_id", req.RunID))
+	}
+	if len(req.Tags) > 0 {
+		tags := make([]interface{}, len(req.Tags))
+		for i, t := range req.Tags {
+			tags[i] = t
+		}
+		conds = append(conds, vector.MatchAny("tags", tags...))
+	}
+	return req.Filter.And(conds...)
 }
 
 // Search returns similar memories using Qdrant.
 func (s *Service) Search(ctx context.Context, emb []float32, limit int) ([]MemoryResult, error) {
-	res, err := s.vector.Query(ctx, "memories", emb, limit)
+	return s.SearchMemories(ctx, SearchRequest{Vector: emb, Limit: limit})
+}
+
+// SearchMemories runs req against the vector store within the call's
+// project. Principals that are not admins only search their own memories.
+func (s *Service) SearchMemories(ctx context.Context, req SearchRequest) ([]MemoryResult, error) {
+	if err := auth.Require(ctx, auth.PermMemoriesRead); err != nil {
+		return nil, err
+	}
+	userID, err := scopeUser(ctx, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	req.UserID = userID
+	if len(req.Rerank) > 0 {
+		return s.searchReranked(ctx, req)
+	}
+	return s.retrieve(ctx, req)
+}
+
+// retrieve runs the first stage of a search: vector, keyword or hybrid.
+func (s *Service) retrieve(ctx context.Context, req SearchRequest) ([]MemoryResult, error) {
+	switch req.Mode {
+	case "", SearchVector:
+		return s.searchVector(ctx, req, req.Limit)
+	case SearchKeyword, SearchHybrid:
+		return s.searchHybrid(ctx, req)
+	default:
+		return nil, fmt.Errorf("%w: unknown mode %q", ErrInvalidSearch, req.Mode)
+	}
+}
+
+// searchVector returns up to limit nearest neighbours of req.
+func (s *Service) searchVector(ctx context.Context, req SearchRequest, limit int) ([]MemoryResult, error) {
+	emb := req.Vector
+	if len(emb) == 0 && req.Query != "" {
+		var err error
+		if emb, err = s.embed(ctx, req.Query); err != nil {
+			return nil, err
+		}
+	}
+	res, err := s.vector.Query(ctx, Collection, emb, limit, projectFilter(ctx, req.filter()))
 	if err != nil {
 		return nil, err
 	}
@@ -73,17 +393,151 @@ func (s *Service) Search(ctx context.Context, emb []float32, limit int) ([]Memor
 		if err != nil {
 			continue
 		}
-		out = append(out, MemoryResult{ID: id, Score: r.Score})
+		out = append(out, MemoryResult{ID: id, Score: r.Score, Payload: r.Payload})
 	}
 	return out, nil
 }
 
-// CreateEntity inserts a node into the graph.
+// CreateEntity inserts a node into the graph in the call's project. The
+// Memory and Merge labels are reserved for the service's own nodes.
 func (s *Service) CreateEntity(ctx context.Context, label string, props map[string]interface{}) (string, error) {
-	return s.graph.CreateNode(ctx, label, props)
+	if err := auth.Require(ctx, auth.PermGraphWrite); err != nil {
+		return "", err
+	}
+	if label == MemoryLabel || label == MergeLabel {
+		return "", fmt.Errorf("%w: %s", ErrReservedLabel, label)
+	}
+	return s.graph.CreateNode(ctx, label, withTenant(ctx, props))
 }
 
-// RelateEntities creates a relationship between two nodes.
+// RelateEntities creates a relationship between two nodes of the call's
+// project.
 func (s *Service) RelateEntities(ctx context.Context, fromID, toID, relType string, props map[string]interface{}) (string, error) {
-	return s.graph.CreateEdge(ctx, fromID, toID, relType, props)
+	if err := auth.Require(ctx, auth.PermGraphWrite); err != nil {
+		return "", err
+	}
+	nodes, err := s.graph.Nodes(ctx, []string{fromID, toID})
+	if err != nil {
+		return "", err
+	}
+	found := map[string]bool{}
+	for _, n := range inProjectNodes(ctx, nodes) {
+		found[n.ID] = true
+	}
+	for _, id := range []string{fromID, toID} {
+		if !found[id] {
+			return "", fmt.Errorf("%w: %s", graph.ErrNodeNotFound, id)
+		}
+	}
+	props = withTenant(ctx, props)
+	id, err := s.graph.CreateEdge(ctx, fromID, toID, relType, props)
+	if err != nil {
+		return "", err
+	}
+	s.publish(events.Event{
+		Kind:         events.RelationshipCreated,
+		Relationship: graph.Edge{ID: id, From: fromID, To: toID, Type: relType, Props: props},
+	})
+	return id, nil
+}
+
+// CreateUser registers a user. It needs users:manage.
+func (s *Service) CreateUser(ctx context.Context, username string) (int64, error) {
+	if err := auth.Require(ctx, auth.PermUsersManage); err != nil {
+		return 0, err
+	}
+	return s.repo.CreateUser(ctx, username)
+}
+
+// GetUser retrieves a user by ID. Principals that are not admins only see
+// their own user. It needs memories:read.
+func (s *Service) GetUser(ctx context.Context, id int64) (db.User, error) {
+	if err := auth.Require(ctx, auth.PermMemoriesRead); err != nil {
+		return db.User{}, err
+	}
+	if !visible(ctx, id) {
+		return db.User{}, db.ErrNotFound
+	}
+	return s.repo.GetUser(ctx, id)
+}
+
+// Entity returns the graph node with the given ID, or nil if there is none
+// in the call's project.
+func (s *Service) Entity(ctx context.Context, id string) (*graph.Node, error) {
+	if err := auth.Require(ctx, auth.PermGraphRead); err != nil {
+		return nil, err
+	}
+	n, err := s.node(ctx, id)
+	if errors.Is(err, graph.ErrNodeNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// Entities returns the graph nodes of the call's project with the given
+// label, or every such node when label is empty.
+func (s *Service) Entities(ctx context.Context, label string) ([]graph.Node, error) {
+	return s.FindEntities(ctx, label, nil)
+}
+
+// Neighbors returns the nodes id points to by relationships of relType.
+func (s *Service) Neighbors(ctx context.Context, id, relType string) ([]graph.Node, error) {
+	if n, err := s.Entity(ctx, id); err != nil || n == nil {
+		return []graph.Node{}, err
+	}
+	nodes, err := s.graph.Neighbors(ctx, id, relType)
+	if err != nil {
+		return nil, err
+	}
+	return inProjectNodes(ctx, nodes), nil
+}
+
+// inProjectNodes returns the nodes of the call's project.
+func inProjectNodes(ctx context.Context, nodes []graph.Node) []graph.Node {
+	out := []graph.Node{}
+	for _, n := range nodes {
+		if nodeInProject(ctx, n.Props) {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// Relationships returns the relationships starting or ending at nodeID.
+func (s *Service) Relationships(ctx context.Context, nodeID string) ([]graph.Edge, error) {
+	if err := auth.Require(ctx, auth.PermGraphRead); err != nil {
+		return nil, err
+	}
+	out := []graph.Edge{}
+	for _, q := range []graph.EdgeQuery{{From: nodeID}, {To: nodeID}} {
+		edges, err := s.graph.FindEdges(ctx, projectEdges(ctx, q))
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range edges {
+			// Self-loops match both queries.
+			if edgeInProject(ctx, e) && (q.To == "" || e.From != nodeID) {
+				out = append(out, e)
+			}
+		}
+	}
+	return out, nil
+}
+
+// MemoryNode returns the graph node linked to memory id, or nil.
+func (s *Service) MemoryNode(ctx context.Context, id int64) (*graph.Node, error) {
+	if err := auth.Require(ctx, auth.PermGraphRead); err != nil {
+		return nil, err
+	}
+	nodes, err := s.graph.FindNodes(ctx, MemoryLabel, map[string]interface{}{"memory_id": id})
+	if err != nil {
+		return nil, err
+	}
+	if nodes = inProjectNodes(ctx, nodes); len(nodes) == 0 {
+		return nil, nil
+	}
+	return &nodes[0], nil
 }
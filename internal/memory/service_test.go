@@ -2,11 +2,21 @@ package memory
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
 
+	"mem0-go/internal/auth"
 	"mem0-go/internal/db"
+	"mem0-go/internal/events"
+	"mem0-go/internal/extract"
 	"mem0-go/internal/graph"
+	"mem0-go/internal/inmem"
+	"mem0-go/internal/llm"
+	"mem0-go/internal/rerank"
 	"mem0-go/internal/vector"
 )
 
@@ -15,6 +25,9 @@ type stubRepo struct {
 	memories   []string
 	memoryIDs  []int64
 	embeddings [][]float32
+	vectors    map[int64][]float32
+	history    []db.HistoryEntry
+	deleted    map[int64]bool
 	createErr  error
 	embedErr   error
 }
@@ -24,11 +37,18 @@ func (s *stubRepo) CreateUser(ctx context.Context, username string) (int64, erro
 	return int64(len(s.users)), nil
 }
 
-func (s *stubRepo) CreateMemory(ctx context.Context, userID int64, content string) (int64, error) {
+func (s *stubRepo) GetUser(ctx context.Context, id int64) (db.User, error) {
+	if id <= 0 || int(id) > len(s.users) {
+		return db.User{}, db.ErrNotFound
+	}
+	return db.User{ID: id, Username: s.users[id-1]}, nil
+}
+
+func (s *stubRepo) CreateMemory(ctx context.Context, m db.Memory) (int64, error) {
 	if s.createErr != nil {
 		return 0, s.createErr
 	}
-	s.memories = append(s.memories, content)
+	s.memories = append(s.memories, m.Content)
 	id := int64(len(s.memories))
 	s.memoryIDs = append(s.memoryIDs, id)
 	return id, nil
@@ -39,36 +59,117 @@ func (s *stubRepo) AddEmbedding(ctx context.Context, memoryID int64, vec []float
 		return s.embedErr
 	}
 	s.embeddings = append(s.embeddings, vec)
+	if s.vectors == nil {
+		s.vectors = make(map[int64][]float32)
+	}
+	s.vectors[memoryID] = vec
 	return nil
 }
 
+func (s *stubRepo) GetEmbedding(ctx context.Context, memoryID int64) ([]float32, error) {
+	vec, ok := s.vectors[memoryID]
+	if !ok {
+		return nil, db.ErrNotFound
+	}
+	return vec, nil
+}
+
 func (s *stubRepo) GetMemory(ctx context.Context, id int64) (db.Memory, error) {
-	if int(id) <= 0 || int(id) > len(s.memories) {
-		return db.Memory{}, fmt.Errorf("not found")
+	if int(id) <= 0 || int(id) > len(s.memories) || s.deleted[id] {
+		return db.Memory{}, db.ErrNotFound
 	}
 	return db.Memory{ID: id, UserID: 1, Content: s.memories[id-1]}, nil
 }
 
+func (s *stubRepo) ListMemories(ctx context.Context, q db.MemoryQuery) ([]db.Memory, error) {
+	var out []db.Memory
+	for id := q.AfterID + 1; id <= int64(len(s.memories)) && (q.Limit <= 0 || len(out) < q.Limit); id++ {
+		if m, err := s.GetMemory(ctx, id); err == nil && (q.UserID == 0 || m.UserID == q.UserID) {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+func (s *stubRepo) UpdateMemory(ctx context.Context, m db.Memory) error {
+	if _, err := s.GetMemory(ctx, m.ID); err != nil {
+		return err
+	}
+	s.memories[m.ID-1] = m.Content
+	return nil
+}
+
+func (s *stubRepo) DeleteMemory(ctx context.Context, id int64) error {
+	if _, err := s.GetMemory(ctx, id); err != nil {
+		return err
+	}
+	if s.deleted == nil {
+		s.deleted = make(map[int64]bool)
+	}
+	s.deleted[id] = true
+	return nil
+}
+
+func (s *stubRepo) AddHistory(ctx context.Context, h db.HistoryEntry) error {
+	h.ID = int64(len(s.history) + 1)
+	s.history = append(s.history, h)
+	return nil
+}
+
+func (s *stubRepo) ListHistory(ctx context.Context, memoryID int64) ([]db.HistoryEntry, error) {
+	var out []db.HistoryEntry
+	for _, h := range s.history {
+		if h.MemoryID == memoryID {
+			out = append(out, h)
+		}
+	}
+	return out, nil
+}
+
 type stubVector struct {
 	upsertCalled bool
 	queryCalled  bool
+	points       []vector.Point
+	filter       *vector.Filter
+	deleted      []string
 	upsertErr    error
 	queryErr     error
 }
 
 func (s *stubVector) Upsert(ctx context.Context, col string, pts []vector.Point) error {
 	s.upsertCalled = true
+	s.points = append(s.points, pts...)
 	return s.upsertErr
 }
 
-func (s *stubVector) Query(ctx context.Context, col string, vec []float32, limit int) ([]vector.QueryResult, error) {
+func (s *stubVector) Delete(ctx context.Context, col string, ids []string) error {
+	s.deleted = append(s.deleted, ids...)
+	return nil
+}
+
+func (s *stubVector) Scroll(ctx context.Context, col, offset string, limit int) ([]vector.Point, string, error) {
+	return s.points, "", nil
+}
+
+func (s *stubVector) Query(ctx context.Context, col string, vec []float32, limit int, filter *vector.Filter) ([]vector.QueryResult, error) {
 	s.queryCalled = true
+	s.filter = filter
 	if s.queryErr != nil {
 		return nil, s.queryErr
 	}
 	return []vector.QueryResult{{ID: "1", Score: 0.9}}, nil
 }
 
+type stubEmbedder struct{}
+
+func (stubEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, len(texts))
+	for i, t := range texts {
+		out[i] = []float32{float32(len(t)), 1}
+	}
+	return out, nil
+}
+
 type stubGraph struct {
 	nodes   map[string]graph.Node
 	edges   []graph.Edge
@@ -112,13 +213,94 @@ func (g *stubGraph) Neighbors(_ context.Context, id, relType string) ([]graph.No
 	return out, nil
 }
 
+func (g *stubGraph) FindNodes(_ context.Context, label string, props map[string]interface{}) ([]graph.Node, error) {
+	var out []graph.Node
+	for _, n := range g.nodes {
+		if graph.NodeMatches(n, label, props) {
+			out = append(out, n)
+		}
+	}
+	return out, nil
+}
+
+func (g *stubGraph) UpdateNode(_ context.Context, id string, props map[string]interface{}) error {
+	n := g.nodes[id]
+	n.Props = graph.MergeProps(n.Props, props)
+	g.nodes[id] = n
+	return nil
+}
+
+func (g *stubGraph) Edges(_ context.Context) ([]graph.Edge, error) {
+	return g.edges, nil
+}
+
+func (g *stubGraph) DeleteEdge(_ context.Context, id string) error {
+	g.edges = graph.RemoveEdge(g.edges, id)
+	return nil
+}
+
+func (g *stubGraph) DeleteNode(_ context.Context, id string) error {
+	delete(g.nodes, id)
+	g.edges = graph.DetachEdges(g.edges, id)
+	return nil
+}
+
+func (g *stubGraph) Edge(_ context.Context, id string) (graph.Edge, error) {
+	for _, e := range g.edges {
+		if e.ID == id {
+			return e, nil
+		}
+	}
+	return graph.Edge{}, graph.ErrEdgeNotFound
+}
+
+func (g *stubGraph) FindEdges(_ context.Context, q graph.EdgeQuery) ([]graph.Edge, error) {
+	var out []graph.Edge
+	for _, e := range g.edges {
+		if graph.EdgeMatches(e, q) {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func (g *stubGraph) UpdateEdge(_ context.Context, id string, props map[string]interface{}) error {
+	for i, e := range g.edges {
+		if e.ID == id {
+			g.edges[i].Props = graph.MergeProps(e.Props, props)
+			return nil
+		}
+	}
+	return graph.ErrEdgeNotFound
+}
+
+func (g *stubGraph) Nodes(_ context.Context, ids []string) ([]graph.Node, error) {
+	return graph.NodesByID(g.nodes, ids), nil
+}
+
+func (g *stubGraph) Expand(_ context.Context, ids []string, dir graph.Direction, types []string) (map[string][]graph.Hop, error) {
+	return graph.ExpandEdges(g.nodes, g.edges, ids, dir, types), nil
+}
+
+func (g *stubGraph) Traverse(ctx context.Context, q graph.TraversalQuery) ([]graph.Visit, error) {
+	return graph.Walk(ctx, g, q)
+}
+
+func (g *stubGraph) ShortestPath(ctx context.Context, from, to string, e graph.Expansion, maxDepth int) (*graph.Path, error) {
+	return graph.FindPath(ctx, g, from, to, e, maxDepth)
+}
+
+func (g *stubGraph) Subgraph(ctx context.Context, center string, e graph.Expansion, depth int) (graph.Subgraph, error) {
+	return graph.Extract(ctx, g, center, e, depth)
+}
+
 func TestStoreAndSearch(t *testing.T) {
 	repo := &stubRepo{}
 	vec := &stubVector{}
 	g := &stubGraph{}
 	svc := NewService(repo, vec, g)
 
-	id, err := svc.StoreMemory(context.Background(), 1, "hello", []float32{1, 2})
+	id, err := svc.StoreMemory(auth.Internal(context.Background()), 1, "hello", []float32{1, 2})
 	if err != nil {
 		t.Fatalf("store: %v", err)
 	}
@@ -129,7 +311,7 @@ func TestStoreAndSearch(t *testing.T) {
 		t.Fatalf("upsert not called")
 	}
 
-	res, err := svc.Search(context.Background(), []float32{1, 2}, 1)
+	res, err := svc.Search(auth.Internal(context.Background()), []float32{1, 2}, 1)
 	if err != nil {
 		t.Fatalf("search: %v", err)
 	}
@@ -147,38 +329,85 @@ func TestRelateEntities(t *testing.T) {
 	g := &stubGraph{}
 	svc := NewService(repo, vec, g)
 
-	n1, _ := svc.CreateEntity(context.Background(), "Person", nil)
-	n2, _ := svc.CreateEntity(context.Background(), "Person", nil)
-	id, err := svc.RelateEntities(context.Background(), n1, n2, "KNOWS", nil)
+	n1, _ := svc.CreateEntity(auth.Internal(context.Background()), "Person", nil)
+	n2, _ := svc.CreateEntity(auth.Internal(context.Background()), "Person", nil)
+	id, err := svc.RelateEntities(auth.Internal(context.Background()), n1, n2, "KNOWS", nil)
 	if err != nil {
 		t.Fatalf("relate: %v", err)
 	}
 	if id == "" {
 		t.Fatalf("empty edge id")
 	}
-	neigh, _ := g.Neighbors(context.Background(), n1, "KNOWS")
+	neigh, _ := g.Neighbors(auth.Internal(context.Background()), n1, "KNOWS")
 	if len(neigh) != 1 || neigh[0].ID != n2 {
 		t.Fatalf("unexpected neighbors: %+v", neigh)
 	}
 }
 
+// scanlessGraph fails reads of the whole graph, which are too costly on
+// Neo4j for per-request lookups.
+type scanlessGraph struct{ *inmem.Graph }
+
+func (g scanlessGraph) FindNodes(ctx context.Context, label string, props map[string]interface{}) ([]graph.Node, error) {
+	if label == "" && len(props) == 0 {
+		return nil, errors.New("full node scan")
+	}
+	return g.Graph.FindNodes(ctx, label, props)
+}
+
+func (scanlessGraph) Edges(context.Context) ([]graph.Edge, error) {
+	return nil, errors.New("full edge scan")
+}
+
+func TestEntityLookupsAvoidFullScans(t *testing.T) {
+	svc := NewService(inmem.NewRepo(), inmem.NewVector(), scanlessGraph{inmem.NewGraph()})
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: 1, OrgID: 1, ProjectID: 7})
+	a, _ := svc.CreateEntity(ctx, "Person", nil)
+	b, _ := svc.CreateEntity(ctx, "Person", nil)
+	outsider, _ := svc.CreateEntity(auth.Internal(context.Background()), "Person", nil)
+	if _, err := svc.RelateEntities(ctx, a, b, "KNOWS", nil); err != nil {
+		t.Fatalf("relate: %v", err)
+	}
+	if _, err := svc.RelateEntities(ctx, a, a, "LIKES", nil); err != nil {
+		t.Fatalf("relate to itself: %v", err)
+	}
+	if _, err := svc.RelateEntities(ctx, a, outsider, "KNOWS", nil); !errors.Is(err, graph.ErrNodeNotFound) {
+		t.Fatalf("expected ErrNodeNotFound relating to another project, got %v", err)
+	}
+	if _, err := svc.RelateEntities(ctx, a, "missing", "KNOWS", nil); !errors.Is(err, graph.ErrNodeNotFound) {
+		t.Fatalf("expected ErrNodeNotFound relating to a missing node, got %v", err)
+	}
+	if _, err := svc.RelateEntities(ctx, "missing", "gone", "KNOWS", nil); !errors.Is(err, graph.ErrNodeNotFound) {
+		t.Fatalf("expected ErrNodeNotFound relating missing nodes, got %v", err)
+	}
+	if _, err := svc.RelateEntities(auth.Internal(context.Background()), outsider, "missing", "KNOWS", nil); !errors.Is(err, graph.ErrNodeNotFound) {
+		t.Fatalf("expected ErrNodeNotFound relating to a missing node internally, got %v", err)
+	}
+	if edges, err := svc.Relationships(ctx, a); err != nil || len(edges) != 2 {
+		t.Fatalf("relationships: %+v, %v", edges, err)
+	}
+	if edges, err := svc.Relationships(auth.Internal(context.Background()), a); err != nil || len(edges) != 0 {
+		t.Fatalf("relationships seen from another project: %+v, %v", edges, err)
+	}
+}
+
 func TestStoreMemoryErrors(t *testing.T) {
 	repo := &stubRepo{createErr: fmt.Errorf("boom")}
 	vec := &stubVector{}
 	g := &stubGraph{}
-	if _, err := NewService(repo, vec, g).StoreMemory(context.Background(), 1, "x", nil); err == nil {
+	if _, err := NewService(repo, vec, g).StoreMemory(auth.Internal(context.Background()), 1, "x", nil); err == nil {
 		t.Fatalf("expected create error")
 	}
 
 	repo.createErr = nil
 	repo.embedErr = fmt.Errorf("bad")
-	if _, err := NewService(repo, vec, g).StoreMemory(context.Background(), 1, "x", nil); err == nil {
+	if _, err := NewService(repo, vec, g).StoreMemory(auth.Internal(context.Background()), 1, "x", nil); err == nil {
 		t.Fatalf("expected embed error")
 	}
 
 	repo.embedErr = nil
 	vec.upsertErr = fmt.Errorf("up")
-	if _, err := NewService(repo, vec, g).StoreMemory(context.Background(), 1, "x", nil); err == nil {
+	if _, err := NewService(repo, vec, g).StoreMemory(auth.Internal(context.Background()), 1, "x", nil); err == nil {
 		t.Fatalf("expected upsert error")
 	}
 }
@@ -188,7 +417,7 @@ func TestSearchError(t *testing.T) {
 	vec := &stubVector{queryErr: fmt.Errorf("q")}
 	g := &stubGraph{}
 	svc := NewService(repo, vec, g)
-	if _, err := svc.Search(context.Background(), nil, 1); err == nil {
+	if _, err := svc.Search(auth.Internal(context.Background()), nil, 1); err == nil {
 		t.Fatalf("expected query error")
 	}
 }
@@ -198,8 +427,8 @@ func TestGetMemory(t *testing.T) {
 	vec := &stubVector{}
 	g := &stubGraph{}
 	svc := NewService(repo, vec, g)
-	id, _ := svc.StoreMemory(context.Background(), 1, "hello", []float32{1})
-	m, err := svc.GetMemory(context.Background(), id)
+	id, _ := svc.StoreMemory(auth.Internal(context.Background()), 1, "hello", []float32{1})
+	m, err := svc.GetMemory(auth.Internal(context.Background()), id)
 	if err != nil {
 		t.Fatalf("get memory: %v", err)
 	}
@@ -207,3 +436,1056 @@ func TestGetMemory(t *testing.T) {
 		t.Fatalf("unexpected memory: %+v", m)
 	}
 }
+
+func TestIngest(t *testing.T) {
+	repo := &stubRepo{}
+	vec := &stubVector{}
+	svc := NewService(repo, vec, &stubGraph{}, WithLLM(llm.NewLocal()), WithEmbedder(stubEmbedder{}))
+	ctx := auth.Internal(context.Background())
+	if _, err := svc.StoreMemory(ctx, 1, "User likes green tea", []float32{1, 2}); err != nil {
+		t.Fatalf("store: %v", err)
+	}
+
+	res, err := svc.Ingest(ctx, 1, []llm.Message{
+		{Role: "user", Content: "User likes green tea. User lives in Paris. Do you?"},
+		{Role: "assistant", Content: "Noted, you like tea."},
+	})
+	if err != nil {
+		t.Fatalf("ingest: %v", err)
+	}
+	if len(res) != 2 || res[0].Event != llm.EventNoop || res[0].MemoryID != 1 {
+		t.Fatalf("unexpected results: %+v", res)
+	}
+	if res[1].Event != llm.EventAdd || res[1].MemoryID != 2 || repo.memories[1] != "User lives in Paris" {
+		t.Fatalf("unexpected add: %+v", res[1])
+	}
+
+	res, err = svc.Ingest(ctx, 1, []llm.Message{{Role: "user", Content: "User likes green tea with milk"}})
+	if err != nil {
+		t.Fatalf("ingest: %v", err)
+	}
+	if len(res) != 1 || res[0].Event != llm.EventUpdate || res[0].OldText != "User likes green tea" {
+		t.Fatalf("unexpected update: %+v", res)
+	}
+	if repo.memories[0] != "User likes green tea with milk" {
+		t.Fatalf("memory not updated: %q", repo.memories[0])
+	}
+
+	writer := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: 1})
+	res, err = svc.Ingest(writer, 1, []llm.Message{{Role: "user", Content: "User no longer likes green tea with milk. User owns a cat"}})
+	if err != nil {
+		t.Fatalf("ingest as writer: %v", err)
+	}
+	if len(res) != 2 || res[0].Event != llm.EventDelete || res[0].Skipped == "" || repo.deleted[1] || res[1].Event != llm.EventAdd {
+		t.Fatalf("expected the delete skipped and the add applied: %+v", res)
+	}
+
+	res, err = svc.Ingest(ctx, 1, []llm.Message{{Role: "user", Content: "User no longer likes green tea with milk"}})
+	if err != nil {
+		t.Fatalf("ingest: %v", err)
+	}
+	if len(res) != 1 || res[0].Event != llm.EventDelete || !repo.deleted[1] || len(vec.deleted) != 1 {
+		t.Fatalf("unexpected delete: %+v", res)
+	}
+}
+
+func TestIngestUnavailable(t *testing.T) {
+	svc := NewService(&stubRepo{}, &stubVector{}, &stubGraph{})
+	if _, err := svc.Ingest(auth.Internal(context.Background()), 1, nil); err != ErrIngestUnavailable {
+		t.Fatalf("expected ErrIngestUnavailable, got %v", err)
+	}
+}
+
+func TestStoreAndSearchText(t *testing.T) {
+	repo := &stubRepo{}
+	vec := &stubVector{}
+	svc := NewService(repo, vec, &stubGraph{}, WithEmbedder(stubEmbedder{}))
+
+	if _, err := svc.StoreMemory(auth.Internal(context.Background()), 1, "hello", nil); err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	if len(repo.embeddings) != 1 || repo.embeddings[0][0] != 5 {
+		t.Fatalf("content not embedded: %v", repo.embeddings)
+	}
+	res, err := svc.SearchMemories(auth.Internal(context.Background()), SearchRequest{Query: "hello", Limit: 1})
+	if err != nil || len(res) != 1 {
+		t.Fatalf("search: %v %+v", err, res)
+	}
+
+	svc = NewService(repo, vec, &stubGraph{})
+	if _, err := svc.StoreMemory(auth.Internal(context.Background()), 1, "hello", nil); err != ErrNoEmbedder {
+		t.Fatalf("expected ErrNoEmbedder, got %v", err)
+	}
+	if _, err := svc.SearchMemories(auth.Internal(context.Background()), SearchRequest{Query: "hello"}); err != ErrNoEmbedder {
+		t.Fatalf("expected ErrNoEmbedder, got %v", err)
+	}
+}
+
+func TestStorePayloadAndSearchFilter(t *testing.T) {
+	vec := &stubVector{}
+	svc := NewService(&stubRepo{}, vec, &stubGraph{})
+	_, err := svc.Store(auth.Internal(context.Background()), StoreRequest{
+		UserID:   7,
+		AgentID:  "planner",
+		RunID:    "r1",
+		Content:  "hello",
+		Tags:     []string{"greeting"},
+		Metadata: map[string]interface{}{"source": "chat"},
+		Vector:   []float32{1, 2},
+	})
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	p := vec.points[0].Payload
+	if p["user_id"] != int64(7) || p["agent_id"] != "planner" || p["run_id"] != "r1" {
+		t.Fatalf("unexpected payload: %v", p)
+	}
+	if _, ok := p["created_at"].(int64); !ok {
+		t.Fatalf("missing created_at: %v", p)
+	}
+
+	custom := &vector.Filter{MustNot: []vector.Condition{vector.MatchValue("metadata.source", "import")}}
+	_, err = svc.SearchMemories(auth.Internal(context.Background()), SearchRequest{
+		Vector: []float32{1, 2}, Limit: 1, UserID: 7, AgentID: "planner", Tags: []string{"greeting"}, Filter: custom,
+	})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	// The default project's searches also exclude points with a project_id.
+	if vec.filter == nil || len(vec.filter.Must) != 3 || len(vec.filter.MustNot) != 2 || len(custom.Must) != 0 || len(custom.MustNot) != 1 {
+		t.Fatalf("unexpected filter: %+v", vec.filter)
+	}
+	if !vec.filter.Matches(p) {
+		t.Fatalf("filter should match stored payload")
+	}
+	if (&vector.Filter{Must: []vector.Condition{vector.MatchValue("user_id", 8)}}).Matches(p) {
+		t.Fatalf("filter should not match another user")
+	}
+}
+
+func TestHybridSearch(t *testing.T) {
+	ctx := auth.Internal(context.Background())
+	svc := NewService(inmem.NewRepo(), inmem.NewVector(), inmem.NewGraph())
+	for _, req := range []StoreRequest{
+		{UserID: 1, Content: "ticket ZX-4471 broke the login page", Vector: []float32{0, 1}},
+		{UserID: 1, Content: "login is slow", Vector: []float32{1, 0.1}},
+		{UserID: 1, Content: "prefers tea", Vector: []float32{1, 0}},
+	} {
+		if _, err := svc.Store(ctx, req); err != nil {
+			t.Fatalf("store: %v", err)
+		}
+	}
+	ids := func(res []MemoryResult) []int64 {
+		var out []int64
+		for _, r := range res {
+			out = append(out, r.ID)
+		}
+		return out
+	}
+	search := func(req SearchRequest) []MemoryResult {
+		t.Helper()
+		req.Query, req.Vector = "zx-4471", []float32{1, 0}
+		res, err := svc.SearchMemories(ctx, req)
+		if err != nil {
+			t.Fatalf("search %+v: %v", req, err)
+		}
+		return res
+	}
+
+	// the exact ticket ID is last semantically but first after fusion
+	if res := search(SearchRequest{}); fmt.Sprint(ids(res)) != "[3 2 1]" || res[0].Explanation != nil {
+		t.Fatalf("vector search: %v", ids(res))
+	}
+	res := search(SearchRequest{Mode: SearchHybrid})
+	if fmt.Sprint(ids(res)) != "[1 3 2]" {
+		t.Fatalf("rrf: %v", ids(res))
+	}
+	if e := res[0].Explanation; e == nil || e.Fusion != FusionRRF || e.VectorRank != 3 || e.TextRank != 1 || e.TextScore <= 0 || res[0].Payload["user_id"] != int64(1) {
+		t.Fatalf("rrf explanation: %+v", res[0])
+	}
+	if e := res[1].Explanation; res[1].ID != 3 || e.VectorRank != 1 || e.VectorScore != 1 || e.TextRank != 0 {
+		t.Fatalf("rrf explanation of a vector-only match: %+v", e)
+	}
+	one, zero := float32(1), float32(0)
+	if res := search(SearchRequest{Mode: SearchHybrid, Fusion: FusionWeighted, VectorWeight: &one}); fmt.Sprint(ids(res)) != "[3 2 1]" || res[0].Score != 1 {
+		t.Fatalf("weighted towards vectors: %v", res)
+	}
+	if res := search(SearchRequest{Mode: SearchHybrid, Fusion: FusionWeighted, VectorWeight: &zero, Limit: 1}); fmt.Sprint(ids(res)) != "[1]" {
+		t.Fatalf("weighted towards keywords: %v", ids(res))
+	}
+	res = search(SearchRequest{Mode: SearchKeyword})
+	if fmt.Sprint(ids(res)) != "[1]" || res[0].Explanation.TextRank != 1 || res[0].Explanation.VectorRank != 0 {
+		t.Fatalf("keyword: %+v", res)
+	}
+	if res := search(SearchRequest{Mode: SearchKeyword, Filter: &vector.Filter{Must: []vector.Condition{vector.MatchValue("user_id", 2)}}}); len(res) != 0 {
+		t.Fatalf("keyword search ignored the filter: %v", ids(res))
+	}
+
+	for _, req := range []SearchRequest{
+		{Query: "x", Mode: "fuzzy"},
+		{Mode: SearchKeyword},
+		{Query: "x", Vector: []float32{1, 0}, Mode: SearchHybrid, Fusion: "max"},
+		{Query: "x", Vector: []float32{1, 0}, Mode: SearchHybrid, VectorWeight: new(float32)},
+	} {
+		if req.VectorWeight != nil {
+			*req.VectorWeight = 2
+		}
+		if _, err := svc.SearchMemories(ctx, req); !errors.Is(err, ErrInvalidSearch) {
+			t.Fatalf("%+v: expected ErrInvalidSearch, got %v", req, err)
+		}
+	}
+	svc = NewService(&stubRepo{}, &stubVector{}, &stubGraph{})
+	if _, err := svc.SearchMemories(ctx, SearchRequest{Query: "x", Mode: SearchHybrid}); !errors.Is(err, ErrNoTextSearch) {
+		t.Fatalf("expected ErrNoTextSearch, got %v", err)
+	}
+}
+
+func TestKeywordSearchFillsFilteredLimit(t *testing.T) {
+	ctx := auth.Internal(context.Background())
+	svc := NewService(inmem.NewRepo(), inmem.NewVector(), inmem.NewGraph())
+	for i := 0; i < 12; i++ {
+		source := "import"
+		if i >= 10 {
+			source = "chat"
+		}
+		req := StoreRequest{UserID: 1, Content: "drinks tea", Vector: []float32{1, 0}, Metadata: map[string]interface{}{"source": source}}
+		if _, err := svc.Store(ctx, req); err != nil {
+			t.Fatalf("store: %v", err)
+		}
+	}
+	chat := &vector.Filter{Must: []vector.Condition{vector.MatchValue("metadata.source", "chat")}}
+	res, err := svc.SearchMemories(ctx, SearchRequest{Query: "tea", Mode: SearchKeyword, Limit: 2, Filter: chat})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(res) != 2 || res[0].ID != 11 || res[1].ID != 12 {
+		t.Fatalf("expected both chat memories past the imported ones, got %+v", res)
+	}
+}
+
+// byLength is a cross-encoder stand-in preferring shorter texts.
+type byLength struct{ seen *int }
+
+func (b byLength) Rerank(_ context.Context, _ string, cands []rerank.Candidate) ([]rerank.Candidate, error) {
+	*b.seen = len(cands)
+	out := append([]rerank.Candidate(nil), cands...)
+	for i := range out {
+		out[i].Score = -float32(len(out[i].Text))
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	return out, nil
+}
+
+func TestRerankedSearch(t *testing.T) {
+	ctx := auth.Internal(context.Background())
+	seen := 0
+	svc := NewService(inmem.NewRepo(), inmem.NewVector(), inmem.NewGraph(), WithCrossEncoder(byLength{&seen}))
+	for _, req := range []StoreRequest{
+		{UserID: 1, Content: "drinks green tea every morning", Vector: []float32{1, 0}},
+		{UserID: 1, Content: "drinks green tea each morning", Vector: []float32{1, 0.01}},
+		{UserID: 1, Content: "likes tea", Vector: []float32{0.5, 1}, Metadata: map[string]interface{}{"importance": 5.0}},
+		{UserID: 1, Content: "lives in Paris", Vector: []float32{0, 1}},
+	} {
+		if _, err := svc.Store(ctx, req); err != nil {
+			t.Fatalf("store: %v", err)
+		}
+	}
+	ids := func(res []MemoryResult) string {
+		var out []int64
+		for _, r := range res {
+			out = append(out, r.ID)
+		}
+		return fmt.Sprint(out)
+	}
+	search := func(limit int, specs ...rerank.Spec) []MemoryResult {
+		t.Helper()
+		res, err := svc.SearchMemories(ctx, SearchRequest{Query: "tea", Vector: []float32{1, 0}, Limit: limit, Rerank: specs})
+		if err != nil {
+			t.Fatalf("search %+v: %v", specs, err)
+		}
+		return res
+	}
+
+	res := search(2, rerank.Spec{Type: rerank.TypeCrossEncoder})
+	if ids(res) != "[3 4]" || seen != 4 {
+		t.Fatalf("cross-encoder: %s of %d candidates", ids(res), seen)
+	}
+	if e := res[0].Explanation; e == nil || e.RetrievalRank != 3 || res[0].Score != -9 {
+		t.Fatalf("explanation: %+v", res[0].Explanation)
+	}
+	if res := search(2, rerank.Spec{Type: rerank.TypeMMR, Lambda: 0.3}); ids(res) != "[1 4]" {
+		t.Fatalf("mmr kept the near-duplicate: %s", ids(res))
+	}
+	if res := search(1, rerank.Spec{Type: rerank.TypeRecency, ImportanceWeight: 1}); ids(res) != "[3]" {
+		t.Fatalf("recency: %s", ids(res))
+	}
+	if res := search(4, rerank.Spec{Type: rerank.TypeMMR}, rerank.Spec{Type: rerank.TypeCrossEncoder}); ids(res) != "[3 4 2 1]" {
+		t.Fatalf("pipeline: %s", ids(res))
+	}
+
+	if _, err := svc.SearchMemories(ctx, SearchRequest{Vector: []float32{1, 0}, Rerank: []rerank.Spec{{Type: rerank.TypeCrossEncoder}}}); !errors.Is(err, ErrInvalidSearch) {
+		t.Fatalf("expected ErrInvalidSearch without a query, got %v", err)
+	}
+	if _, err := svc.SearchMemories(ctx, SearchRequest{Query: "tea", Rerank: []rerank.Spec{{Type: "llm"}}}); !errors.Is(err, rerank.ErrInvalidSpec) {
+		t.Fatalf("expected ErrInvalidSpec, got %v", err)
+	}
+}
+
+func TestGraphSearch(t *testing.T) {
+	ctx := auth.Internal(context.Background())
+	g := scanlessGraph{inmem.NewGraph()}
+	svc := NewService(inmem.NewRepo(), inmem.NewVector(), g)
+	alice, _ := svc.CreateEntity(ctx, "Person", map[string]interface{}{"name": "Alice"})
+	acme, _ := svc.CreateEntity(ctx, "Company", map[string]interface{}{"name": "Acme"})
+	berlin, _ := svc.CreateEntity(ctx, "City", map[string]interface{}{"name": "Berlin"})
+	if _, err := svc.RelateEntities(ctx, alice, acme, "WORKS_AT", nil); err != nil {
+		t.Fatalf("relate: %v", err)
+	}
+	if _, err := svc.RelateEntities(ctx, acme, berlin, "HEADQUARTERED_IN", nil); err != nil {
+		t.Fatalf("relate: %v", err)
+	}
+	for _, m := range []struct {
+		req    StoreRequest
+		entity string
+	}{
+		{StoreRequest{UserID: 1, Content: "had lunch with Alice", Vector: []float32{1, 0}}, alice},
+		{StoreRequest{UserID: 1, Content: "Acme ships on Fridays", Vector: []float32{0, 1}}, acme},
+		{StoreRequest{UserID: 1, Content: "Berlin is cold in winter", Vector: []float32{0, 1}}, berlin},
+		{StoreRequest{UserID: 2, Content: "Acme is hiring", Vector: []float32{0, 1}}, acme},
+	} {
+		id, err := svc.Store(ctx, m.req)
+		if err != nil {
+			t.Fatalf("store: %v", err)
+		}
+		nodes, _ := g.FindNodes(ctx, MemoryLabel, map[string]interface{}{"memory_id": id})
+		if len(nodes) != 1 {
+			t.Fatalf("expected memory node, got %+v", nodes)
+		}
+		if _, err := svc.RelateEntities(ctx, nodes[0].ID, m.entity, "MENTIONS", nil); err != nil {
+			t.Fatalf("relate: %v", err)
+		}
+	}
+	search := func(hops int) GraphSearchResult {
+		t.Helper()
+		res, err := svc.GraphSearch(ctx, GraphSearchRequest{
+			SearchRequest: SearchRequest{Vector: []float32{1, 0}, Limit: 1, UserID: 1},
+			Hops:          hops,
+		})
+		if err != nil {
+			t.Fatalf("graph search, %d hops: %v", hops, err)
+		}
+		return res
+	}
+
+	res := search(0)
+	if len(res.Seeds) != 1 || res.Seeds[0].ID != 1 {
+		t.Fatalf("seeds: %+v", res.Seeds)
+	}
+	if len(res.Entities) != 2 || res.Entities[0].ID != alice || res.Entities[1].ID != acme {
+		t.Fatalf("entities: %+v", res.Entities)
+	}
+	if len(res.Triples) != 1 || res.Triples[0].String() != "Alice WORKS_AT Acme" {
+		t.Fatalf("triples: %+v", res.Triples)
+	}
+	if len(res.Related) != 1 || res.Related[0].Memory.ID != 2 || res.Related[0].Hops != 1 || res.Related[0].EntityIDs[0] != acme {
+		t.Fatalf("related: %+v", res.Related)
+	}
+
+	res = search(2)
+	if len(res.Triples) != 2 || res.Triples[1].String() != "Acme HEADQUARTERED_IN Berlin" {
+		t.Fatalf("triples: %+v", res.Triples)
+	}
+	if len(res.Related) != 2 || res.Related[1].Memory.ID != 3 || res.Related[1].Hops != 2 {
+		t.Fatalf("related: %+v", res.Related)
+	}
+
+	if _, err := svc.GraphSearch(ctx, GraphSearchRequest{SearchRequest: SearchRequest{Vector: []float32{1, 0}}, Hops: MaxHops + 1}); !errors.Is(err, ErrInvalidSearch) {
+		t.Fatalf("expected ErrInvalidSearch, got %v", err)
+	}
+}
+
+func TestTraversalIsScopedToProject(t *testing.T) {
+	g := inmem.NewGraph()
+	svc := NewService(inmem.NewRepo(), inmem.NewVector(), g)
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: 1, OrgID: 1, ProjectID: 7})
+	a, _ := svc.CreateEntity(ctx, "Person", map[string]interface{}{"name": "a"})
+	b, _ := svc.CreateEntity(ctx, "Person", map[string]interface{}{"name": "b"})
+	if _, err := svc.RelateEntities(ctx, a, b, "KNOWS", nil); err != nil {
+		t.Fatalf("relate: %v", err)
+	}
+	outsider, _ := svc.CreateEntity(auth.Internal(context.Background()), "Person", map[string]interface{}{"name": "outsider"})
+	_, _ = g.CreateEdge(ctx, a, outsider, "KNOWS", nil)
+
+	visits, err := svc.Traverse(ctx, graph.TraversalQuery{Start: a, MaxDepth: 2})
+	if err != nil {
+		t.Fatalf("traverse: %v", err)
+	}
+	if len(visits) != 1 || visits[0].Node.ID != b || visits[0].Via.Type != "KNOWS" {
+		t.Fatalf("unexpected visits: %+v", visits)
+	}
+	if _, err := svc.Traverse(auth.Internal(context.Background()), graph.TraversalQuery{Start: a}); !errors.Is(err, graph.ErrNodeNotFound) {
+		t.Fatalf("expected ErrNodeNotFound from another project, got %v", err)
+	}
+	if path, err := svc.ShortestPath(ctx, a, outsider, graph.Expansion{}, 2); err != nil || path != nil {
+		t.Fatalf("path left the project: %+v, %v", path, err)
+	}
+	if path, err := svc.ShortestPath(ctx, b, a, graph.Expansion{Direction: graph.Both}, 0); err != nil || path == nil || path.Len() != 1 {
+		t.Fatalf("shortest path: %+v, %v", path, err)
+	}
+	sg, err := svc.Subgraph(ctx, a, graph.Expansion{Direction: graph.Both}, 1)
+	if err != nil || len(sg.Nodes) != 2 || len(sg.Edges) != 1 {
+		t.Fatalf("subgraph: %+v, %v", sg, err)
+	}
+	reader := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: 1, OrgID: 1, ProjectID: 7, Scopes: []string{auth.PermMemoriesRead}})
+	if _, err := svc.Traverse(reader, graph.TraversalQuery{Start: a}); !errors.Is(err, auth.ErrForbidden) {
+		t.Fatalf("expected ErrForbidden without graph:read, got %v", err)
+	}
+}
+
+func TestEntityCRUD(t *testing.T) {
+	g := inmem.NewGraph()
+	svc := NewService(inmem.NewRepo(), inmem.NewVector(), g)
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: 1, OrgID: 1, ProjectID: 7})
+	alice, _ := svc.CreateEntity(ctx, "Person", map[string]interface{}{"name": "Alice", "age": 30})
+	bob, _ := svc.CreateEntity(ctx, "Person", map[string]interface{}{"name": "Bob"})
+	_, _ = svc.CreateEntity(auth.Internal(context.Background()), "Person", map[string]interface{}{"name": "Alice"})
+	rel, err := svc.RelateEntities(ctx, alice, bob, "KNOWS", map[string]interface{}{"since": 2020})
+	if err != nil {
+		t.Fatalf("relate: %v", err)
+	}
+
+	nodes, err := svc.FindEntities(ctx, "Person", map[string]interface{}{"name": "Alice", "age": 30.0})
+	if err != nil || len(nodes) != 1 || nodes[0].ID != alice {
+		t.Fatalf("find: %+v, %v", nodes, err)
+	}
+	n, err := svc.UpdateEntity(ctx, alice, map[string]interface{}{"age": nil, "city": "Paris", "project_id": 8})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if _, ok := n.Props["age"]; ok || n.Props["city"] != "Paris" || n.Props["project_id"] != int64(7) {
+		t.Fatalf("unexpected props: %+v", n.Props)
+	}
+	if _, err := svc.UpdateEntity(auth.Internal(context.Background()), alice, nil); !errors.Is(err, graph.ErrNodeNotFound) {
+		t.Fatalf("expected ErrNodeNotFound from another project, got %v", err)
+	}
+
+	edges, err := svc.FindRelationships(ctx, graph.EdgeQuery{From: alice, Props: map[string]interface{}{"since": 2020}})
+	if err != nil || len(edges) != 1 || edges[0].ID != rel {
+		t.Fatalf("find relationships: %+v, %v", edges, err)
+	}
+	e, err := svc.UpdateRelationship(ctx, rel, map[string]interface{}{"since": 2019})
+	if err != nil || e.Props["since"] != 2019 {
+		t.Fatalf("update relationship: %+v, %v", e, err)
+	}
+	if got, err := svc.Relationship(auth.Internal(context.Background()), rel); err != nil || got != nil {
+		t.Fatalf("relationship visible from another project: %+v, %v", got, err)
+	}
+
+	if err := svc.DeleteEntity(ctx, bob, false); !errors.Is(err, ErrEntityHasRelationships) {
+		t.Fatalf("expected ErrEntityHasRelationships, got %v", err)
+	}
+	if err := svc.DeleteEntity(ctx, bob, true); err != nil {
+		t.Fatalf("cascade delete: %v", err)
+	}
+	if got, _ := svc.Relationship(ctx, rel); got != nil {
+		t.Fatalf("relationship survived cascade: %+v", got)
+	}
+	if err := svc.DeleteRelationship(ctx, rel); !errors.Is(err, graph.ErrEdgeNotFound) {
+		t.Fatalf("expected ErrEdgeNotFound, got %v", err)
+	}
+	if err := svc.DeleteEntity(ctx, alice, false); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if got, err := svc.Entity(ctx, alice); err != nil || got != nil {
+		t.Fatalf("entity survived delete: %+v, %v", got, err)
+	}
+	reader := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: 1, OrgID: 1, ProjectID: 7, Scopes: []string{auth.PermGraphRead}})
+	if err := svc.DeleteRelationship(reader, rel); !errors.Is(err, auth.ErrForbidden) {
+		t.Fatalf("expected ErrForbidden without graph:write, got %v", err)
+	}
+
+	// memory and merge nodes are the service's own
+	for _, label := range []string{MemoryLabel, MergeLabel} {
+		if _, err := svc.CreateEntity(ctx, label, nil); !errors.Is(err, ErrReservedLabel) {
+			t.Fatalf("expected ErrReservedLabel creating a %s node, got %v", label, err)
+		}
+		id, _ := g.CreateNode(ctx, label, map[string]interface{}{"org_id": int64(1), "project_id": int64(7), "memory_id": int64(1)})
+		if _, err := svc.UpdateEntity(ctx, id, map[string]interface{}{"memory_id": 2}); !errors.Is(err, graph.ErrNodeNotFound) {
+			t.Fatalf("expected ErrNodeNotFound updating a %s node, got %v", label, err)
+		}
+		if err := svc.DeleteEntity(ctx, id, true); !errors.Is(err, graph.ErrNodeNotFound) {
+			t.Fatalf("expected ErrNodeNotFound deleting a %s node, got %v", label, err)
+		}
+		if nodes, _ := g.Nodes(ctx, []string{id}); len(nodes) != 1 || nodes[0].Props["memory_id"] != int64(1) {
+			t.Fatalf("%s node changed: %+v", label, nodes)
+		}
+	}
+}
+
+type recordingQueue struct {
+	ids    []int64
+	refuse bool
+}
+
+func (q *recordingQueue) EnqueueLink(_ context.Context, id int64) error {
+	if q.refuse {
+		return errors.New("queue unavailable")
+	}
+	q.ids = append(q.ids, id)
+	return nil
+}
+
+func TestLinkMemory(t *testing.T) {
+	g := inmem.NewGraph()
+	svc := NewService(inmem.NewRepo(), inmem.NewVector(), g, WithEmbedder(stubEmbedder{}), WithExtractor(extract.NewRules()))
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: 1, OrgID: 1, ProjectID: 7})
+	mentions := func(id int64) []string {
+		t.Helper()
+		node, err := svc.MemoryNode(ctx, id)
+		if err != nil || node == nil {
+			t.Fatalf("memory node %d: %+v, %v", id, node, err)
+		}
+		nodes, err := svc.Neighbors(ctx, node.ID, MentionsType)
+		if err != nil {
+			t.Fatalf("neighbors: %v", err)
+		}
+		var names []string
+		for _, n := range nodes {
+			names = append(names, n.Label+":"+n.Props["name"].(string))
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	first, err := svc.Store(ctx, StoreRequest{UserID: 1, Content: "Alice works at Acme"})
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	if got := mentions(first); !reflect.DeepEqual(got, []string{"Organization:Acme", "Person:Alice"}) {
+		t.Fatalf("mentions: %v", got)
+	}
+	alice, _ := svc.FindEntities(ctx, "Person", map[string]interface{}{"name": "Alice"})
+	if len(alice) != 1 {
+		t.Fatalf("expected one Alice, got %+v", alice)
+	}
+	if rels, _ := svc.FindRelationships(ctx, graph.EdgeQuery{From: alice[0].ID, Type: "WORKS_AT"}); len(rels) != 1 {
+		t.Fatalf("expected WORKS_AT, got %+v", rels)
+	}
+
+	second, _ := svc.Store(ctx, StoreRequest{UserID: 1, Content: "Alice lives in Paris"})
+	if got := mentions(second); !reflect.DeepEqual(got, []string{"Location:Paris", "Person:Alice"}) {
+		t.Fatalf("mentions: %v", got)
+	}
+	if all, _ := svc.FindEntities(ctx, "", map[string]interface{}{"name": "Alice"}); len(all) != 1 {
+		t.Fatalf("Alice not reused: %+v", all)
+	}
+	other := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: 1, OrgID: 1, ProjectID: 8})
+	if _, err := svc.Store(other, StoreRequest{UserID: 1, Content: "Alice works at Acme"}); err != nil {
+		t.Fatalf("store in another project: %v", err)
+	}
+	if all, _ := svc.FindEntities(ctx, "", map[string]interface{}{"name": "Alice"}); len(all) != 1 {
+		t.Fatalf("entities shared across projects: %+v", all)
+	}
+
+	content := "Bob works at Acme"
+	if _, err := svc.Update(ctx, first, UpdateRequest{Content: &content}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if got := mentions(first); !reflect.DeepEqual(got, []string{"Organization:Acme", "Person:Bob"}) {
+		t.Fatalf("mentions after update: %v", got)
+	}
+	if rels, _ := svc.FindRelationships(ctx, graph.EdgeQuery{From: alice[0].ID, Type: "WORKS_AT"}); len(rels) != 0 {
+		t.Fatalf("relationship no longer stated survived the update: %+v", rels)
+	}
+	livesIn := func() int {
+		t.Helper()
+		rels, err := svc.FindRelationships(ctx, graph.EdgeQuery{From: alice[0].ID, Type: "LIVES_IN"})
+		if err != nil {
+			t.Fatalf("find: %v", err)
+		}
+		return len(rels)
+	}
+	third, _ := svc.Store(ctx, StoreRequest{UserID: 1, Content: "Alice lives in Paris"})
+	content = "Alice visited Paris"
+	if _, err := svc.Update(ctx, second, UpdateRequest{Content: &content}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if n := livesIn(); n != 1 {
+		t.Fatalf("expected LIVES_IN kept while another memory states it, got %d", n)
+	}
+	content = "Alice likes Paris"
+	if _, err := svc.Update(ctx, third, UpdateRequest{Content: &content}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if n := livesIn(); n != 0 {
+		t.Fatalf("expected LIVES_IN removed once no memory states it, got %d", n)
+	}
+
+	q := &recordingQueue{}
+	queued := NewService(inmem.NewRepo(), inmem.NewVector(), inmem.NewGraph(), WithEmbedder(stubEmbedder{}), WithExtractor(extract.NewRules()), WithLinkQueue(q))
+	id, _ := queued.Store(ctx, StoreRequest{UserID: 1, Content: "Carol knows Dave"})
+	if !reflect.DeepEqual(q.ids, []int64{id}) {
+		t.Fatalf("expected link job for %d, got %v", id, q.ids)
+	}
+	if found, _ := queued.FindEntities(ctx, "", nil); len(found) != 1 {
+		t.Fatalf("expected only the memory node before the job ran, got %+v", found)
+	}
+	res, err := queued.LinkMemory(auth.Internal(context.Background()), id)
+	if err != nil || len(res.Entities) != 2 || len(res.Relationships) != 1 || res.Relationships[0].Type != "KNOWS" {
+		t.Fatalf("link: %+v, %v", res, err)
+	}
+	if res, err := queued.LinkMemory(auth.Internal(context.Background()), 999); err != nil || len(res.Entities) != 0 {
+		t.Fatalf("missing memory: %+v, %v", res, err)
+	}
+	q.refuse = true
+	id, _ = queued.Store(ctx, StoreRequest{UserID: 1, Content: "Erin knows Frank"})
+	if found, _ := queued.FindEntities(ctx, "Person", map[string]interface{}{"name": "Erin"}); len(found) != 1 {
+		t.Fatalf("expected memory %d linked inline when the queue refuses the job, got %+v", id, found)
+	}
+	if _, err := NewService(inmem.NewRepo(), inmem.NewVector(), g).LinkMemory(ctx, first); !errors.Is(err, ErrNoExtractor) {
+		t.Fatalf("expected ErrNoExtractor, got %v", err)
+	}
+}
+
+func TestUpdateDeleteHistory(t *testing.T) {
+	repo := &stubRepo{}
+	vec := &stubVector{}
+	g := &stubGraph{}
+	svc := NewService(repo, vec, g, WithEmbedder(stubEmbedder{}))
+	ctx := auth.Internal(context.Background())
+
+	id, err := svc.Store(ctx, StoreRequest{UserID: 1, Content: "likes tea", Actor: "alice"})
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	person, _ := svc.CreateEntity(ctx, "Person", nil)
+	nodes, _ := g.FindNodes(ctx, MemoryLabel, map[string]interface{}{"memory_id": id})
+	if len(nodes) != 1 {
+		t.Fatalf("expected memory node, got %+v", nodes)
+	}
+	if _, err := svc.RelateEntities(ctx, person, nodes[0].ID, "REMEMBERS", nil); err != nil {
+		t.Fatalf("relate: %v", err)
+	}
+
+	content := "likes green tea"
+	m, err := svc.Update(ctx, id, UpdateRequest{Content: &content, Actor: "bob"})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if m.Content != content || repo.memories[0] != content {
+		t.Fatalf("memory not updated: %+v", m)
+	}
+	last := vec.points[len(vec.points)-1]
+	if last.ID != "1" || last.Vector[0] != float32(len(content)) {
+		t.Fatalf("point not re-indexed: %+v", last)
+	}
+	if nodes, _ := g.FindNodes(ctx, MemoryLabel, nil); nodes[0].Props["content"] != content {
+		t.Fatalf("graph node not updated: %+v", nodes)
+	}
+	if _, err := svc.Update(ctx, id, UpdateRequest{Content: &content}); err != nil {
+		t.Fatalf("noop update: %v", err)
+	}
+
+	if err := svc.Delete(ctx, id, "carol"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if len(vec.deleted) != 1 || vec.deleted[0] != "1" {
+		t.Fatalf("point not deleted: %v", vec.deleted)
+	}
+	if nodes, _ := g.FindNodes(ctx, MemoryLabel, nil); len(nodes) != 0 {
+		t.Fatalf("graph node not deleted: %+v", nodes)
+	}
+	if neigh, _ := g.Neighbors(ctx, person, "REMEMBERS"); len(neigh) != 0 {
+		t.Fatalf("edge not removed: %+v", neigh)
+	}
+
+	h, err := svc.History(ctx, id)
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	if len(h) != 3 {
+		t.Fatalf("expected 3 entries, got %+v", h)
+	}
+	if h[0].Event != db.HistoryAdd || h[0].Actor != "alice" || h[0].New.Content != "likes tea" {
+		t.Fatalf("unexpected add entry: %+v", h[0])
+	}
+	if h[1].Event != db.HistoryUpdate || h[1].Actor != "bob" || h[1].Old.Content != "likes tea" ||
+		h[1].New.Content != content || len(h[1].Fields) != 1 || h[1].Fields[0] != "content" {
+		t.Fatalf("unexpected update entry: %+v", h[1])
+	}
+	if h[2].Event != db.HistoryDelete || h[2].Actor != "carol" || h[2].Old.Content != content || h[2].New != nil {
+		t.Fatalf("unexpected delete entry: %+v", h[2])
+	}
+
+	if _, err := svc.History(ctx, 99); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+	if err := svc.Delete(ctx, id, ""); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+}
+
+func TestWritesPublishEvents(t *testing.T) {
+	bus := events.NewBus()
+	sub := bus.Subscribe(nil)
+	svc := NewService(&stubRepo{}, &stubVector{}, &stubGraph{}, WithEvents(bus))
+	ctx := auth.Internal(context.Background())
+
+	id, err := svc.Store(ctx, StoreRequest{UserID: 1, Content: "a", Vector: []float32{1}, Actor: "ada"})
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	content := "b"
+	if _, err := svc.Update(ctx, id, UpdateRequest{Content: &content, Vector: []float32{2}}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if err := svc.Delete(ctx, id, ""); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	n1, _ := svc.CreateEntity(ctx, "Person", nil)
+	n2, _ := svc.CreateEntity(ctx, "Person", nil)
+	if _, err := svc.RelateEntities(ctx, n1, n2, "KNOWS", nil); err != nil {
+		t.Fatalf("relate: %v", err)
+	}
+
+	want := []events.Kind{events.MemoryAdded, events.MemoryUpdated, events.MemoryDeleted, events.RelationshipCreated}
+	for i, kind := range want {
+		e := <-sub.C
+		if e.Kind != kind {
+			t.Fatalf("event %d: got %s, want %s", i, e.Kind, kind)
+		}
+		if i == 0 && (e.Memory.ID != id || e.Actor != "ada") {
+			t.Fatalf("unexpected added event %+v", e)
+		}
+		if i == 2 && e.Memory.Content != "b" {
+			t.Fatalf("deleted event should carry the last memory, got %+v", e.Memory)
+		}
+		if i == 3 && e.Relationship.Type != "KNOWS" {
+			t.Fatalf("unexpected relationship event %+v", e.Relationship)
+		}
+	}
+}
+
+func TestPrincipalScoping(t *testing.T) {
+	repo := &stubRepo{}
+	vec := &stubVector{}
+	svc := NewService(repo, vec, &stubGraph{})
+	if _, err := svc.StoreMemory(auth.Internal(context.Background()), 1, "owned by 1", []float32{1}); err != nil {
+		t.Fatalf("store: %v", err)
+	}
+
+	owner := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: 1, Subject: "user:1"})
+	other := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: 2, Subject: "user:2", Scopes: []string{auth.RoleWriter, auth.PermMemoriesDelete}})
+	admin := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "admin", Scopes: []string{auth.ScopeAdmin}})
+
+	if _, err := svc.GetMemory(other, 1); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("other user read memory: %v", err)
+	}
+	if _, err := svc.History(other, 1); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("other user read history: %v", err)
+	}
+	if err := svc.Delete(other, 1, ""); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("other user deleted memory: %v", err)
+	}
+	if _, err := svc.GetMemory(admin, 1); err != nil {
+		t.Fatalf("admin read: %v", err)
+	}
+	if _, err := svc.Store(other, StoreRequest{UserID: 1, Content: "x", Vector: []float32{1}}); !errors.Is(err, auth.ErrForbidden) {
+		t.Fatalf("expected ErrForbidden storing for another user, got %v", err)
+	}
+	if _, err := svc.Reconcile(other, ReconcileOptions{}); !errors.Is(err, auth.ErrForbidden) {
+		t.Fatalf("expected ErrForbidden reconciling, got %v", err)
+	}
+
+	if _, err := svc.SearchMemories(other, SearchRequest{Vector: []float32{1}, Limit: 1}); err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if f := vec.filter; f == nil || len(f.Must) != 1 || f.Must[0].Key != "user_id" || f.Must[0].Match.Value != int64(2) {
+		t.Fatalf("search not scoped to principal: %+v", f)
+	}
+	if page, err := svc.ListMemories(other, ListRequest{}); err != nil || len(page.Memories) != 0 {
+		t.Fatalf("list: %v %v", page.Memories, err)
+	}
+
+	content := "changed"
+	if _, err := svc.Update(owner, 1, UpdateRequest{Content: &content, Vector: []float32{2}, Actor: "spoofed"}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if h := repo.history[len(repo.history)-1]; h.Actor != "user:1" {
+		t.Fatalf("expected the principal as actor, got %q", h.Actor)
+	}
+}
+
+func TestPermissions(t *testing.T) {
+	svc := NewService(&stubRepo{}, &stubVector{}, &stubGraph{})
+	writer := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: 1})
+	reader := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: 1, Scopes: []string{auth.RoleReader}})
+
+	id, err := svc.Store(writer, StoreRequest{Content: "mine", Vector: []float32{1}})
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	if _, err := svc.GetMemory(reader, id); err != nil {
+		t.Fatalf("reader get: %v", err)
+	}
+	var perr *auth.PermissionError
+	if _, err := svc.Store(reader, StoreRequest{Content: "x", Vector: []float32{1}}); !errors.As(err, &perr) || perr.Permission != auth.PermMemoriesWrite {
+		t.Fatalf("expected missing %s, got %v", auth.PermMemoriesWrite, err)
+	}
+	if _, err := svc.CreateEntity(reader, "Person", nil); !errors.As(err, &perr) || perr.Permission != auth.PermGraphWrite {
+		t.Fatalf("expected missing %s, got %v", auth.PermGraphWrite, err)
+	}
+	if err := svc.Delete(writer, id, ""); !errors.As(err, &perr) || perr.Permission != auth.PermMemoriesDelete || !errors.Is(err, auth.ErrForbidden) {
+		t.Fatalf("expected writers to lack %s, got %v", auth.PermMemoriesDelete, err)
+	}
+	if _, err := svc.CreateUser(writer, "eve"); !errors.As(err, &perr) || perr.Permission != auth.PermUsersManage {
+		t.Fatalf("expected missing %s, got %v", auth.PermUsersManage, err)
+	}
+	if err := svc.Delete(auth.Internal(context.Background()), id, ""); err != nil {
+		t.Fatalf("internal delete: %v", err)
+	}
+
+	svc = NewService(inmem.NewRepo(), inmem.NewVector(), inmem.NewGraph())
+	if id, err = svc.Store(writer, StoreRequest{Content: "mine", Vector: []float32{1}}); err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	graphOnly := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: 1, Scopes: []string{auth.PermGraphRead}})
+	if _, err := svc.History(graphOnly, id); !errors.As(err, &perr) || perr.Permission != auth.PermMemoriesRead {
+		t.Fatalf("expected history to need %s, got %v", auth.PermMemoriesRead, err)
+	}
+	if _, err := svc.GetUser(graphOnly, 1); !errors.As(err, &perr) || perr.Permission != auth.PermMemoriesRead {
+		t.Fatalf("expected users to need %s, got %v", auth.PermMemoriesRead, err)
+	}
+}
+
+func TestFindDuplicatesAndMerge(t *testing.T) {
+	g := inmem.NewGraph()
+	svc := NewService(inmem.NewRepo(), inmem.NewVector(), g, WithEmbedder(stubEmbedder{}))
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: 1, OrgID: 1, ProjectID: 7})
+	create := func(label string, props map[string]interface{}) string {
+		t.Helper()
+		id, err := svc.CreateEntity(ctx, label, props)
+		if err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		return id
+	}
+	bob := create("Person", map[string]interface{}{"name": "Bob", "age": 41})
+	robert := create("Person", map[string]interface{}{"name": "Robert Smith"})
+	smith := create("Person", map[string]interface{}{"name": "bob smith"})
+	alice := create("Person", map[string]interface{}{"name": "Alice"})
+	acme := create("Organization", map[string]interface{}{"name": "Acme Corp", "description": "maker of widgets and gadgets"})
+	acmeInc := create("Organization", map[string]interface{}{"name": "ACME Inc", "description": "maker of widgets and gizmos"})
+	create("Organization", map[string]interface{}{"name": "Globex", "description": "x"})
+	for _, r := range [][3]string{
+		{alice, smith, "KNOWS"},
+		{robert, acme, "WORKS_AT"},
+		{smith, acme, "WORKS_AT"},
+		{bob, smith, "KNOWS"},
+	} {
+		if _, err := svc.RelateEntities(ctx, r[0], r[1], r[2], nil); err != nil {
+			t.Fatalf("relate: %v", err)
+		}
+	}
+
+	dups, err := svc.FindDuplicates(ctx, DuplicateOptions{})
+	if err != nil {
+		t.Fatalf("find duplicates: %v", err)
+	}
+	got := map[string]string{}
+	for _, d := range dups {
+		pair := []string{d.A.Props["name"].(string), d.B.Props["name"].(string)}
+		sort.Strings(pair)
+		got[strings.Join(pair, "|")] = strings.Join(d.Reasons, ",")
+	}
+	want := map[string]string{
+		"Bob|Robert Smith":       "alias",
+		"Bob|bob smith":          "name",
+		"Robert Smith|bob smith": "alias",
+		"ACME Inc|Acme Corp":     "embedding",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("duplicates: %v", got)
+	}
+	if dups, _ := svc.FindDuplicates(ctx, DuplicateOptions{Label: "Person", MinScore: 0.9}); len(dups) != 1 {
+		t.Fatalf("expected one Person pair above 0.9, got %+v", dups)
+	}
+
+	if _, err := svc.MergeEntities(ctx, robert, []string{robert}); !errors.Is(err, ErrInvalidMerge) {
+		t.Fatalf("expected ErrInvalidMerge, got %v", err)
+	}
+	if _, err := svc.MergeEntities(ctx, acmeInc, []string{"missing"}); !errors.Is(err, graph.ErrNodeNotFound) {
+		t.Fatalf("expected ErrNodeNotFound, got %v", err)
+	}
+	rec, err := svc.MergeEntities(ctx, robert, []string{smith, bob})
+	if err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	if len(rec.Merged) != 2 || len(rec.Edges) != 3 || len(rec.Rewired) != 1 {
+		t.Fatalf("unexpected record: %+v", rec)
+	}
+	n, _ := svc.Entity(ctx, robert)
+	if n == nil || !reflect.DeepEqual(n.Props["aliases"], []string{"bob smith", "Bob"}) || n.Props["age"] != 41 {
+		t.Fatalf("survivor: %+v", n)
+	}
+	for _, id := range []string{smith, bob} {
+		if n, _ := svc.Entity(ctx, id); n != nil {
+			t.Fatalf("merged entity survived: %+v", n)
+		}
+	}
+	if edges, _ := svc.FindRelationships(ctx, graph.EdgeQuery{From: alice, To: robert, Type: "KNOWS"}); len(edges) != 1 {
+		t.Fatalf("relationship not rewired: %+v", edges)
+	}
+	if edges, _ := svc.FindRelationships(ctx, graph.EdgeQuery{From: robert}); len(edges) != 1 {
+		t.Fatalf("expected the duplicate WORKS_AT dropped, got %+v", edges)
+	}
+	if dups, _ := svc.FindDuplicates(ctx, DuplicateOptions{Label: "Person"}); len(dups) != 0 {
+		t.Fatalf("duplicates after merge: %+v", dups)
+	}
+	if recs, err := svc.Merges(ctx); err != nil || len(recs) != 1 || recs[0].ID != rec.ID {
+		t.Fatalf("merges: %+v, %v", recs, err)
+	}
+	if _, err := svc.Merge(auth.Internal(context.Background()), rec.ID); !errors.Is(err, ErrMergeNotFound) {
+		t.Fatalf("expected ErrMergeNotFound from another project, got %v", err)
+	}
+
+	undone, err := svc.UndoMerge(ctx, rec.ID)
+	if err != nil {
+		t.Fatalf("undo: %v", err)
+	}
+	n, _ = svc.Entity(ctx, robert)
+	if _, ok := n.Props["aliases"]; ok || n.Props["name"] != "Robert Smith" {
+		t.Fatalf("survivor not restored: %+v", n.Props)
+	}
+	restored, _ := svc.Entity(ctx, undone.Restored[bob])
+	if restored == nil || restored.Props["name"] != "Bob" || restored.Props["age"] != int64(41) {
+		t.Fatalf("merged entity not restored: %+v", restored)
+	}
+	newSmith := undone.Restored[smith]
+	for _, q := range []graph.EdgeQuery{
+		{From: alice, To: newSmith, Type: "KNOWS"},
+		{From: newSmith, To: acme, Type: "WORKS_AT"},
+		{From: undone.Restored[bob], To: newSmith, Type: "KNOWS"},
+		{From: robert, To: acme, Type: "WORKS_AT"},
+	} {
+		if edges, _ := svc.FindRelationships(ctx, q); len(edges) != 1 {
+			t.Fatalf("relationship %+v not restored: %+v", q, edges)
+		}
+	}
+	if edges, _ := svc.FindRelationships(ctx, graph.EdgeQuery{To: robert}); len(edges) != 0 {
+		t.Fatalf("rewired relationships survived undo: %+v", edges)
+	}
+	if _, err := svc.UndoMerge(ctx, rec.ID); !errors.Is(err, ErrMergeUndone) {
+		t.Fatalf("expected ErrMergeUndone, got %v", err)
+	}
+}
+
+// failingDeleteGraph fails deleting node fail.
+type failingDeleteGraph struct {
+	*inmem.Graph
+	fail string
+}
+
+func (g *failingDeleteGraph) DeleteNode(ctx context.Context, id string) error {
+	if id == g.fail {
+		return errors.New("delete failed")
+	}
+	return g.Graph.DeleteNode(ctx, id)
+}
+
+func TestFailedMergeIsRolledBack(t *testing.T) {
+	g := &failingDeleteGraph{Graph: inmem.NewGraph()}
+	svc := NewService(inmem.NewRepo(), inmem.NewVector(), g)
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: 1, OrgID: 1, ProjectID: 7})
+	robert, _ := svc.CreateEntity(ctx, "Person", map[string]interface{}{"name": "Robert"})
+	bob, _ := svc.CreateEntity(ctx, "Person", map[string]interface{}{"name": "Bob", "age": 41})
+	rob, _ := svc.CreateEntity(ctx, "Person", map[string]interface{}{"name": "Rob"})
+	acme, _ := svc.CreateEntity(ctx, "Organization", map[string]interface{}{"name": "Acme"})
+	if _, err := svc.RelateEntities(ctx, bob, acme, "WORKS_AT", nil); err != nil {
+		t.Fatalf("relate: %v", err)
+	}
+	if _, err := svc.RelateEntities(ctx, rob, acme, "LIKES", nil); err != nil {
+		t.Fatalf("relate: %v", err)
+	}
+
+	g.fail = rob
+	if _, err := svc.MergeEntities(ctx, robert, []string{bob, rob}); err == nil {
+		t.Fatalf("expected the merge to fail")
+	}
+	if recs, _ := svc.Merges(ctx); len(recs) != 0 {
+		t.Fatalf("record of a rolled back merge kept: %+v", recs)
+	}
+	n, _ := svc.Entity(ctx, robert)
+	if _, ok := n.Props["aliases"]; ok || n.Props["age"] != nil {
+		t.Fatalf("survivor not reset: %+v", n.Props)
+	}
+	if edges, _ := svc.Relationships(ctx, robert); len(edges) != 0 {
+		t.Fatalf("rewired relationships survived the rollback: %+v", edges)
+	}
+	if edges, _ := svc.FindRelationships(ctx, graph.EdgeQuery{From: rob, To: acme, Type: "LIKES"}); len(edges) != 1 {
+		t.Fatalf("kept entity lost its relationship: %+v", edges)
+	}
+	people, _ := svc.FindEntities(ctx, "Person", map[string]interface{}{"name": "Bob"})
+	if len(people) != 1 {
+		t.Fatalf("deleted entity not restored: %+v", people)
+	}
+	if edges, _ := svc.FindRelationships(ctx, graph.EdgeQuery{From: people[0].ID, To: acme, Type: "WORKS_AT"}); len(edges) != 1 {
+		t.Fatalf("restored entity lost its relationship: %+v", edges)
+	}
+}
+
+func TestUndoMergeStaysInProject(t *testing.T) {
+	g := inmem.NewGraph()
+	svc := NewService(inmem.NewRepo(), inmem.NewVector(), g)
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: 1, OrgID: 1, ProjectID: 7})
+	other := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: 2, OrgID: 2, ProjectID: 8})
+	robert, _ := svc.CreateEntity(ctx, "Person", map[string]interface{}{"name": "Robert"})
+	bob, _ := svc.CreateEntity(ctx, "Person", map[string]interface{}{"name": "Bob"})
+	carol, _ := svc.CreateEntity(other, "Person", map[string]interface{}{"name": "Carol"})
+	dave, _ := svc.CreateEntity(other, "Person", map[string]interface{}{"name": "Dave"})
+	theirs, _ := svc.RelateEntities(other, carol, dave, "KNOWS", nil)
+	rec, err := svc.MergeEntities(ctx, robert, []string{bob})
+	if err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+
+	// a record rewritten behind the service's back
+	forge := func(edit func(*MergeRecord)) {
+		t.Helper()
+		r, err := svc.Merge(ctx, rec.ID)
+		if err != nil {
+			t.Fatalf("merge record: %v", err)
+		}
+		edit(&r)
+		props, _ := recordProps(r)
+		if err := g.UpdateNode(ctx, rec.ID, props); err != nil {
+			t.Fatalf("forge: %v", err)
+		}
+	}
+	forge(func(r *MergeRecord) {
+		r.Merged[0].Props["project_id"] = int64(8)
+	})
+	if _, err := svc.UndoMerge(ctx, rec.ID); !errors.Is(err, ErrInvalidMerge) {
+		t.Fatalf("expected ErrInvalidMerge for a node of another project, got %v", err)
+	}
+
+	forge(func(r *MergeRecord) {
+		r.Merged[0].Props["project_id"] = int64(7)
+		r.Edges = append(r.Edges, graph.Edge{ID: "forged", From: bob, To: carol, Type: "KNOWS", Props: map[string]interface{}{"org_id": int64(1), "project_id": int64(7)}})
+		r.Rewired["forged"] = theirs
+	})
+	undone, err := svc.UndoMerge(ctx, rec.ID)
+	if err != nil {
+		t.Fatalf("undo: %v", err)
+	}
+	restored, _ := g.Nodes(ctx, []string{undone.Restored[bob]})
+	if len(restored) != 1 || restored[0].Props["project_id"] != int64(7) {
+		t.Fatalf("restored node not tagged with the project: %+v", restored)
+	}
+	if edges, _ := svc.FindRelationships(other, graph.EdgeQuery{From: carol}); len(edges) != 1 || edges[0].ID != theirs {
+		t.Fatalf("other project's relationship deleted: %+v", edges)
+	}
+	if edges, _ := g.FindEdges(ctx, graph.EdgeQuery{To: carol}); len(edges) != 0 {
+		t.Fatalf("relationship restored into another project: %+v", edges)
+	}
+}
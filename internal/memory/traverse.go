@@ -0,0 +1,53 @@
+package memory
+
+import (
+	"context"
+
+	"mem0-go/internal/auth"
+	"mem0-go/internal/graph"
+)
+
+// Traverse walks the graph of the call's project from q.Start, returning
+// the nodes reached in the order visited. It needs graph:read and returns
+// graph.ErrNodeNotFound when the start is not in the project.
+func (s *Service) Traverse(ctx context.Context, q graph.TraversalQuery) ([]graph.Visit, error) {
+	if err := auth.Require(ctx, auth.PermGraphRead); err != nil {
+		return nil, err
+	}
+	q.Expansion = scoped(ctx, q.Expansion)
+	visits, err := s.graph.Traverse(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	if visits == nil {
+		visits = []graph.Visit{}
+	}
+	return visits, nil
+}
+
+// ShortestPath returns a shortest path between two nodes of the call's
+// project over at most maxDepth relationships, graph.MaxDepth when 0, or
+// nil when there is none. It needs graph:read.
+func (s *Service) ShortestPath(ctx context.Context, from, to string, e graph.Expansion, maxDepth int) (*graph.Path, error) {
+	if err := auth.Require(ctx, auth.PermGraphRead); err != nil {
+		return nil, err
+	}
+	return s.graph.ShortestPath(ctx, from, to, scoped(ctx, e), maxDepth)
+}
+
+// Subgraph returns the nodes of the call's project within depth
+// relationships of center and the relationships between them. It needs
+// graph:read.
+func (s *Service) Subgraph(ctx context.Context, center string, e graph.Expansion, depth int) (graph.Subgraph, error) {
+	if err := auth.Require(ctx, auth.PermGraphRead); err != nil {
+		return graph.Subgraph{}, err
+	}
+	return s.graph.Subgraph(ctx, center, scoped(ctx, e), depth)
+}
+
+// scoped restricts e to the nodes and relationships of the call's project.
+func scoped(ctx context.Context, e graph.Expansion) graph.Expansion {
+	e.NodeFilter = func(n graph.Node) bool { return nodeInProject(ctx, n.Props) }
+	e.EdgeFilter = func(r graph.Edge) bool { return edgeInProject(ctx, r) }
+	return e
+}
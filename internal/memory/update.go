@@ -0,0 +1,192 @@
+package memory
+
+import (
+	"context"
+	"reflect"
+
+	"mem0-go/internal/auth"
+	"mem0-go/internal/db"
+	"mem0-go/internal/events"
+)
+
+// UpdateRequest describes changes to a memory. A nil Content, Tags or
+// Metadata leaves that field unchanged; pass empty values to clear them.
+// Vector is optional and is derived from the new content when omitted.
+type UpdateRequest struct {
+	Content  *string
+	Tags     []string
+	Metadata map[string]interface{}
+	Vector   []float32
+	Actor    string
+}
+
+// Update applies req to memory id, re-indexes its vector point and graph
+// node and records the change in the history. Requests that change nothing
+// are not recorded.
+func (s *Service) Update(ctx context.Context, id int64, req UpdateRequest) (db.Memory, error) {
+	if err := auth.Require(ctx, auth.PermMemoriesWrite); err != nil {
+		return db.Memory{}, err
+	}
+	old, err := s.get(ctx, id)
+	if err != nil {
+		return db.Memory{}, err
+	}
+	req.Actor = actor(ctx, req.Actor)
+	m := old
+	if req.Content != nil {
+		m.Content = *req.Content
+	}
+	if req.Tags != nil {
+		m.Tags = req.Tags
+	}
+	if req.Metadata != nil {
+		m.Metadata = req.Metadata
+	}
+	emb := req.Vector
+	if len(changedFields(old, m)) == 0 && len(emb) == 0 {
+		return old, nil
+	}
+	switch {
+	case len(emb) > 0:
+	case m.Content != old.Content:
+		emb, err = s.embed(ctx, m.Content)
+	default:
+		emb, err = s.repo.GetEmbedding(ctx, id)
+	}
+	if err != nil {
+		return db.Memory{}, err
+	}
+	err = s.write(ctx, func(repo db.Repository) (change, error) {
+		if err := repo.UpdateMemory(ctx, m); err != nil {
+			return change{}, err
+		}
+		if err := repo.AddEmbedding(ctx, id, emb); err != nil {
+			return change{}, err
+		}
+		return change{kind: db.OutboxUpsert, mem: m, emb: emb}, record(ctx, repo, db.HistoryUpdate, req.Actor, &old, &m)
+	})
+	if err != nil {
+		return db.Memory{}, err
+	}
+	s.publish(events.Event{Kind: events.MemoryUpdated, Memory: m, Actor: req.Actor})
+	return m, nil
+}
+
+// Delete removes memory id with its vector point and graph node, keeping
+// its history. It needs memories:delete, which writers lack.
+func (s *Service) Delete(ctx context.Context, id int64, by string) error {
+	if err := auth.Require(ctx, auth.PermMemoriesDelete); err != nil {
+		return err
+	}
+	m, err := s.get(ctx, id)
+	if err != nil {
+		return err
+	}
+	by = actor(ctx, by)
+	err = s.write(ctx, func(repo db.Repository) (change, error) {
+		if err := repo.DeleteMemory(ctx, id); err != nil {
+			return change{}, err
+		}
+		return change{kind: db.OutboxDelete, mem: m}, record(ctx, repo, db.HistoryDelete, by, &m, nil)
+	})
+	if err != nil {
+		return err
+	}
+	s.publish(events.Event{Kind: events.MemoryDeleted, Memory: m, Actor: by})
+	return nil
+}
+
+// History returns every recorded version of memory id, oldest first. It
+// fails with the repository's not-found error when the memory never
+// existed or belongs to a user the caller may not see. It needs
+// memories:read.
+func (s *Service) History(ctx context.Context, id int64) ([]db.HistoryEntry, error) {
+	if err := auth.Require(ctx, auth.PermMemoriesRead); err != nil {
+		return nil, err
+	}
+	h, err := s.repo.ListHistory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(h) == 0 {
+		if _, err := s.GetMemory(ctx, id); err != nil {
+			return nil, err
+		}
+		return []db.HistoryEntry{}, nil
+	}
+	if m := snapshot(h[len(h)-1]); m == nil || !canSee(ctx, *m) {
+		return nil, db.ErrNotFound
+	}
+	return h, nil
+}
+
+// snapshot returns the memory an entry recorded.
+func snapshot(h db.HistoryEntry) *db.Memory {
+	if h.New != nil {
+		return h.New
+	}
+	return h.Old
+}
+
+// record appends a history entry to repo for a change from before to after.
+func record(ctx context.Context, repo db.Repository, event, actor string, before, after *db.Memory) error {
+	h := db.HistoryEntry{Event: event, Actor: actor, Old: before, New: after}
+	switch {
+	case before != nil && after != nil:
+		h.MemoryID, h.Fields = after.ID, changedFields(*before, *after)
+	case after != nil:
+		h.MemoryID = after.ID
+	default:
+		h.MemoryID = before.ID
+	}
+	return repo.AddHistory(ctx, h)
+}
+
+// syncGraph creates or updates the graph node linked to m or, when deleted
+// is set, removes it. Removing a node also removes the relationships
+// entities had to it.
+func (s *Service) syncGraph(ctx context.Context, m db.Memory, deleted bool) error {
+	nodes, err := s.graph.FindNodes(ctx, MemoryLabel, map[string]interface{}{"memory_id": m.ID})
+	if err != nil {
+		return err
+	}
+	if len(nodes) == 0 && !deleted {
+		_, err := s.graph.CreateNode(ctx, MemoryLabel, nodeProps(m))
+		return err
+	}
+	for _, n := range nodes {
+		if deleted {
+			err = s.graph.DeleteNode(ctx, n.ID)
+		} else {
+			err = s.graph.UpdateNode(ctx, n.ID, nodeProps(m))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nodeProps returns the properties of m's graph node.
+func nodeProps(m db.Memory) map[string]interface{} {
+	p := map[string]interface{}{"memory_id": m.ID, "user_id": m.UserID, "content": m.Content}
+	if m.ProjectID != 0 {
+		p["org_id"], p["project_id"] = m.OrgID, m.ProjectID
+	}
+	return p
+}
+
+// changedFields lists the user-editable fields that differ between a and b.
+func changedFields(a, b db.Memory) []string {
+	var out []string
+	if a.Content != b.Content {
+		out = append(out, "content")
+	}
+	if len(a.Tags) != len(b.Tags) || (len(a.Tags) > 0 && !reflect.DeepEqual(a.Tags, b.Tags)) {
+		out = append(out, "tags")
+	}
+	if len(a.Metadata) != len(b.Metadata) || (len(a.Metadata) > 0 && !reflect.DeepEqual(a.Metadata, b.Metadata)) {
+		out = append(out, "metadata")
+	}
+	return out
+}
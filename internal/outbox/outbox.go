@@ -0,0 +1,137 @@
+// Package outbox delivers events that were committed to the database
+// together with the rows they describe, retrying failures with exponential
+// backoff so that downstream stores converge even after crashes.
+package outbox
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"mem0-go/internal/db"
+)
+
+// Config controls how events are polled and retried.
+type Config struct {
+	// Interval is how often the outbox is polled when it is drained.
+	Interval time.Duration
+	// Batch is the maximum number of events applied per poll.
+	Batch int
+	// MaxAttempts is how many times an event is tried before it is
+	// abandoned.
+	MaxAttempts int
+	// Backoff is the delay before the first retry; it doubles with each
+	// attempt up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// DefaultConfig returns the default dispatch settings.
+func DefaultConfig() Config {
+	return Config{Interval: time.Second, Batch: 100, MaxAttempts: 10, Backoff: time.Second, MaxBackoff: 5 * time.Minute}
+}
+
+// LoadConfig reads settings from environment variables with fallbacks.
+func LoadConfig() Config {
+	cfg := DefaultConfig()
+	if d, err := time.ParseDuration(os.Getenv("MEM0_OUTBOX_INTERVAL")); err == nil && d > 0 {
+		cfg.Interval = d
+	}
+	cfg.Batch = getint("MEM0_OUTBOX_BATCH", cfg.Batch)
+	cfg.MaxAttempts = getint("MEM0_OUTBOX_MAX_ATTEMPTS", cfg.MaxAttempts)
+	return cfg
+}
+
+func getint(key string, def int) int {
+	if v, err := strconv.Atoi(os.Getenv(key)); err == nil && v > 0 {
+		return v
+	}
+	return def
+}
+
+// Handler applies one event. It must be idempotent since an event may be
+// delivered more than once.
+type Handler func(ctx context.Context, e db.OutboxEvent) error
+
+// Dispatcher applies due outbox events with a Handler.
+type Dispatcher struct {
+	store  db.Outbox
+	handle Handler
+	cfg    Config
+	now    func() time.Time
+	// Logger receives failures; it defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// New returns a Dispatcher reading events from store.
+func New(store db.Outbox, h Handler, cfg Config) *Dispatcher {
+	return &Dispatcher{store: store, handle: h, cfg: cfg, now: time.Now, Logger: slog.Default()}
+}
+
+// RunOnce applies one batch of due events and reports how many succeeded.
+// Failed events are rescheduled, or abandoned after MaxAttempts; only
+// errors reading or updating the outbox itself are returned.
+func (d *Dispatcher) RunOnce(ctx context.Context) (int, error) {
+	events, err := d.store.DueOutbox(ctx, d.now(), d.cfg.Batch)
+	if err != nil {
+		return 0, err
+	}
+	applied := 0
+	for _, e := range events {
+		if err := ctx.Err(); err != nil {
+			return applied, err
+		}
+		herr := d.handle(ctx, e)
+		if herr == nil {
+			if err := d.store.CompleteOutbox(ctx, e.ID); err != nil {
+				return applied, err
+			}
+			applied++
+			continue
+		}
+		var retryAt time.Time
+		if e.Attempts+1 < d.cfg.MaxAttempts {
+			retryAt = d.now().Add(d.backoff(e.Attempts + 1))
+			d.Logger.Warn("outbox event failed", "id", e.ID, "kind", e.Kind, "attempt", e.Attempts+1, "err", herr)
+		} else {
+			d.Logger.Error("outbox event abandoned", "id", e.ID, "kind", e.Kind, "attempts", e.Attempts+1, "err", herr)
+		}
+		if err := d.store.FailOutbox(ctx, e.ID, herr.Error(), retryAt); err != nil {
+			return applied, err
+		}
+	}
+	return applied, nil
+}
+
+// backoff returns the delay before retry attempt n.
+func (d *Dispatcher) backoff(n int) time.Duration {
+	b := d.cfg.Backoff
+	for i := 1; i < n && b < d.cfg.MaxBackoff; i++ {
+		b *= 2
+	}
+	if d.cfg.MaxBackoff > 0 && b > d.cfg.MaxBackoff {
+		b = d.cfg.MaxBackoff
+	}
+	return b
+}
+
+// Run dispatches events until ctx is cancelled. A full batch is followed
+// immediately by the next poll; otherwise Run waits Interval.
+func (d *Dispatcher) Run(ctx context.Context) error {
+	for {
+		n, err := d.RunOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			d.Logger.Error("outbox dispatch failed", "err", err)
+		}
+		if err == nil && n > 0 && n == d.cfg.Batch {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(d.cfg.Interval):
+		}
+	}
+}
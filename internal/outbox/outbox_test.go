@@ -0,0 +1,171 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"mem0-go/internal/auth"
+	"mem0-go/internal/db"
+	"mem0-go/internal/inmem"
+	"mem0-go/internal/memory"
+	"mem0-go/internal/vector"
+)
+
+// flakyVector fails the next failUpserts upserts and failDeletes deletes.
+type flakyVector struct {
+	*inmem.Vector
+	failUpserts int
+	failDeletes int
+}
+
+func (f *flakyVector) Upsert(ctx context.Context, col string, pts []vector.Point) error {
+	if f.failUpserts > 0 {
+		f.failUpserts--
+		return errors.New("qdrant unavailable")
+	}
+	return f.Vector.Upsert(ctx, col, pts)
+}
+
+func (f *flakyVector) Delete(ctx context.Context, col string, ids []string) error {
+	if f.failDeletes > 0 {
+		f.failDeletes--
+		return errors.New("qdrant unavailable")
+	}
+	return f.Vector.Delete(ctx, col, ids)
+}
+
+// crashingRepo fails AddHistory, simulating a crash part way through a
+// transaction.
+type crashingRepo struct{ db.TxRepository }
+
+func (r crashingRepo) AddHistory(context.Context, db.HistoryEntry) error {
+	return errors.New("connection reset")
+}
+
+func (r crashingRepo) InTx(ctx context.Context, fn func(db.TxRepository) error) error {
+	return r.TxRepository.InTx(ctx, func(tx db.TxRepository) error { return fn(crashingRepo{tx}) })
+}
+
+// points counts the points in the memories collection.
+func points(t *testing.T, vec *inmem.Vector) int {
+	t.Helper()
+	res, err := vec.Query(context.Background(), memory.Collection, []float32{1, 0}, 0, nil)
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	return len(res)
+}
+
+func TestStoresConvergeAfterFailures(t *testing.T) {
+	ctx := auth.Internal(context.Background())
+	repo := inmem.NewRepo()
+	vec := &flakyVector{Vector: inmem.NewVector(), failUpserts: 2}
+	g := inmem.NewGraph()
+	svc := memory.NewService(repo, vec, g, memory.WithOutbox())
+
+	id, err := svc.StoreMemory(ctx, 1, "likes tea", []float32{1, 0})
+	if err != nil {
+		t.Fatalf("store should succeed once the row is committed: %v", err)
+	}
+	if n := points(t, vec.Vector); n != 0 {
+		t.Fatalf("expected no point before dispatch, got %d", n)
+	}
+
+	d := New(repo, svc.ApplyEvent, Config{Batch: 10, MaxAttempts: 5, Backoff: time.Minute, MaxBackoff: time.Hour})
+	now := time.Now()
+	d.now = func() time.Time { return now }
+
+	// the first dispatch fails too and schedules a retry a minute out
+	if n, err := d.RunOnce(ctx); err != nil || n != 0 {
+		t.Fatalf("first run: applied %d, err %v", n, err)
+	}
+	if n, _ := d.RunOnce(ctx); n != 0 {
+		t.Fatalf("event retried before its backoff elapsed")
+	}
+	now = now.Add(time.Minute)
+	if n, err := d.RunOnce(ctx); err != nil || n != 1 {
+		t.Fatalf("retry: applied %d, err %v", n, err)
+	}
+	if n := points(t, vec.Vector); n != 1 {
+		t.Fatalf("expected point after dispatch, got %d", n)
+	}
+	nodes, _ := g.FindNodes(ctx, memory.MemoryLabel, map[string]interface{}{"memory_id": id})
+	if len(nodes) != 1 {
+		t.Fatalf("expected memory node, got %+v", nodes)
+	}
+
+	// applying a delivered event again changes nothing
+	if err := svc.ApplyEvent(ctx, db.OutboxEvent{Kind: db.OutboxUpsert, MemoryID: id}); err != nil {
+		t.Fatalf("reapply: %v", err)
+	}
+	if nodes, _ := g.FindNodes(ctx, memory.MemoryLabel, nil); len(nodes) != 1 {
+		t.Fatalf("reapply duplicated nodes: %+v", nodes)
+	}
+
+	vec.failDeletes = 1
+	if err := svc.Delete(ctx, id, ""); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if n := points(t, vec.Vector); n != 1 {
+		t.Fatalf("expected stale point before dispatch, got %d", n)
+	}
+	now = now.Add(time.Minute)
+	if n, err := d.RunOnce(ctx); err != nil || n != 1 {
+		t.Fatalf("delete dispatch: applied %d, err %v", n, err)
+	}
+	if n := points(t, vec.Vector); n != 0 {
+		t.Fatalf("expected point removed, got %d", n)
+	}
+	if nodes, _ := g.FindNodes(ctx, memory.MemoryLabel, nil); len(nodes) != 0 {
+		t.Fatalf("expected node removed, got %+v", nodes)
+	}
+}
+
+func TestCrashRollsBackWrite(t *testing.T) {
+	ctx := auth.Internal(context.Background())
+	repo := inmem.NewRepo()
+	vec := inmem.NewVector()
+	svc := memory.NewService(crashingRepo{repo}, vec, inmem.NewGraph(), memory.WithOutbox())
+
+	if _, err := svc.StoreMemory(ctx, 1, "likes tea", []float32{1, 0}); err == nil {
+		t.Fatalf("expected store to fail")
+	}
+	if _, err := repo.GetMemory(ctx, 1); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("memory row left behind: %v", err)
+	}
+	if events, _ := repo.DueOutbox(ctx, time.Now(), 0); len(events) != 0 {
+		t.Fatalf("outbox event left behind: %+v", events)
+	}
+	if n := points(t, vec); n != 0 {
+		t.Fatalf("vector point written for rolled back memory")
+	}
+}
+
+func TestDispatcherAbandonsAfterMaxAttempts(t *testing.T) {
+	ctx := auth.Internal(context.Background())
+	repo := inmem.NewRepo()
+	if _, err := repo.AddOutbox(ctx, db.OutboxEvent{Kind: db.OutboxUpsert, MemoryID: 1}); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	calls := 0
+	d := New(repo, func(context.Context, db.OutboxEvent) error {
+		calls++
+		return errors.New("boom")
+	}, Config{Batch: 10, MaxAttempts: 3, Backoff: time.Second, MaxBackoff: 2 * time.Second})
+	now := time.Now()
+	d.now = func() time.Time { return now }
+	for i := 0; i < 5; i++ {
+		if _, err := d.RunOnce(ctx); err != nil {
+			t.Fatalf("run: %v", err)
+		}
+		now = now.Add(time.Hour)
+	}
+	if calls != 3 {
+		t.Fatalf("expected 3 attempts, got %d", calls)
+	}
+	if got := d.backoff(5); got != 2*time.Second {
+		t.Fatalf("backoff not capped: %v", got)
+	}
+}
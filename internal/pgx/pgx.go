@@ -0,0 +1,6 @@
+package pgx
+
+import "errors"
+
+// ErrNoRows is returned by Row.Scan when the query selected no rows.
+var ErrNoRows = errors.New("no rows in result set")
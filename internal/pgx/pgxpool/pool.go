@@ -37,3 +37,43 @@ func (r Rows) Close() {}
 func (r Rows) Next() bool { return false }
 
 func (r Rows) Scan(dest ...interface{}) error { return nil }
+
+func (r Rows) Err() error { return nil }
+
+// Tx is a transaction started with Pool.Begin.
+type Tx struct{}
+
+func (p *Pool) Begin(ctx context.Context) (*Tx, error) { return &Tx{}, nil }
+
+func (t *Tx) Exec(ctx context.Context, sql string, args ...interface{}) (CommandTag, error) {
+	return CommandTag{}, nil
+}
+
+func (t *Tx) QueryRow(ctx context.Context, sql string, args ...interface{}) Row { return Row{} }
+
+func (t *Tx) Query(ctx context.Context, sql string, args ...interface{}) (Rows, error) {
+	return Rows{}, nil
+}
+
+func (t *Tx) Commit(ctx context.Context) error { return nil }
+
+func (t *Tx) Rollback(ctx context.Context) error { return nil }
+
+// Conn is a connection acquired from a Pool.
+type Conn struct{}
+
+func (p *Pool) Acquire(ctx context.Context) (*Conn, error) { return &Conn{}, nil }
+
+func (c *Conn) Release() {}
+
+func (c *Conn) Exec(ctx context.Context, sql string, args ...interface{}) (CommandTag, error) {
+	return CommandTag{}, nil
+}
+
+func (c *Conn) QueryRow(ctx context.Context, sql string, args ...interface{}) Row { return Row{} }
+
+func (c *Conn) Query(ctx context.Context, sql string, args ...interface{}) (Rows, error) {
+	return Rows{}, nil
+}
+
+func (c *Conn) Begin(ctx context.Context) (*Tx, error) { return &Tx{}, nil }
@@ -0,0 +1,89 @@
+package rerank
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// CrossEncoder scores each query and candidate text pair with a
+// cross-encoder model behind an HTTP server. It speaks the /rerank API of
+// text-embeddings-inference: the query and texts go in, and a score per
+// text index comes back.
+type CrossEncoder struct {
+	baseURL    string
+	apiKey     string
+	model      string
+	httpClient *http.Client
+}
+
+// NewCrossEncoder constructs a reranker talking to cfg.URL.
+func NewCrossEncoder(cfg Config) *CrossEncoder {
+	return &CrossEncoder{
+		baseURL:    strings.TrimRight(cfg.URL, "/"),
+		apiKey:     cfg.APIKey,
+		model:      cfg.Model,
+		httpClient: &http.Client{},
+	}
+}
+
+// Rerank implements Reranker.
+func (c *CrossEncoder) Rerank(ctx context.Context, query string, cands []Candidate) ([]Candidate, error) {
+	if len(cands) == 0 {
+		return cands, nil
+	}
+	texts := make([]string, len(cands))
+	for i, cand := range cands {
+		texts[i] = cand.Text
+	}
+	body, err := json.Marshal(struct {
+		Model string   `json:"model,omitempty"`
+		Query string   `json:"query"`
+		Texts []string `json:"texts"`
+	}{c.model, query, texts})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/rerank", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("rerank status %d", resp.StatusCode)
+	}
+	var scores []struct {
+		Index int     `json:"index"`
+		Score float32 `json:"score"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&scores); err != nil {
+		return nil, err
+	}
+	if len(scores) != len(cands) {
+		return nil, fmt.Errorf("rerank: got %d scores for %d texts", len(scores), len(cands))
+	}
+	out := make([]Candidate, len(cands))
+	seen := make([]bool, len(cands))
+	for i, s := range scores {
+		if s.Index < 0 || s.Index >= len(cands) || seen[s.Index] {
+			return nil, fmt.Errorf("rerank: index %d out of range", s.Index)
+		}
+		seen[s.Index] = true
+		out[i] = cands[s.Index]
+		out[i].Score = s.Score
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	return out, nil
+}
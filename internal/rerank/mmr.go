@@ -0,0 +1,68 @@
+package rerank
+
+import (
+	"context"
+	"math"
+)
+
+// DefaultLambda is the MMR relevance weight used when none is given.
+const DefaultLambda = 0.7
+
+// MMR reorders candidates by maximal marginal relevance, picking at each
+// step the candidate that best balances relevance to the query against
+// similarity to those already picked, so near-duplicates sink.
+type MMR struct {
+	lambda float64
+}
+
+// NewMMR returns an MMR reranker weighing relevance by lambda and
+// diversity by 1-lambda. A lambda of 0 means DefaultLambda.
+func NewMMR(lambda float64) *MMR {
+	if lambda == 0 {
+		lambda = DefaultLambda
+	}
+	return &MMR{lambda: lambda}
+}
+
+// Rerank implements Reranker. Similarity is the cosine of candidate
+// vectors; candidates without one are never similar to another.
+func (m *MMR) Rerank(_ context.Context, _ string, cands []Candidate) ([]Candidate, error) {
+	rel := relevance(cands)
+	left := append([]Candidate(nil), cands...)
+	out := make([]Candidate, 0, len(cands))
+	for len(left) > 0 {
+		best, bestScore := 0, math.Inf(-1)
+		for i, c := range left {
+			sim := 0.0
+			for _, o := range out {
+				sim = math.Max(sim, cosine(c.Vector, o.Vector))
+			}
+			if s := m.lambda*rel(c.Score) - (1-m.lambda)*sim; s > bestScore {
+				best, bestScore = i, s
+			}
+		}
+		c := left[best]
+		c.Score = float32(bestScore)
+		out = append(out, c)
+		left = append(left[:best], left[best+1:]...)
+	}
+	return out, nil
+}
+
+// cosine returns the cosine similarity of a and b, 0 when either is empty
+// or they differ in length.
+func cosine(a, b []float32) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
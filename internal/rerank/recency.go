@@ -0,0 +1,60 @@
+package rerank
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+)
+
+// RecencyConfig weighs the terms of the Recency score. HalfLife defaults
+// to 30 days, and when every weight is zero they default to 1 for
+// relevance and 0.5 for recency and importance.
+type RecencyConfig struct {
+	HalfLife         time.Duration
+	RelevanceWeight  float64
+	RecencyWeight    float64
+	ImportanceWeight float64
+	// Now returns the current time; time.Now when nil.
+	Now func() time.Time
+}
+
+// Recency scores candidates by a weighted sum of their relevance, scaled
+// to [0, 1] across the candidates, their recency, which halves every
+// HalfLife, and their importance.
+type Recency struct {
+	cfg RecencyConfig
+}
+
+// NewRecency returns a recency and importance weighted reranker.
+func NewRecency(cfg RecencyConfig) *Recency {
+	if cfg.HalfLife == 0 {
+		cfg.HalfLife = 30 * 24 * time.Hour
+	}
+	if cfg.RelevanceWeight == 0 && cfg.RecencyWeight == 0 && cfg.ImportanceWeight == 0 {
+		cfg.RelevanceWeight, cfg.RecencyWeight, cfg.ImportanceWeight = 1, 0.5, 0.5
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	return &Recency{cfg: cfg}
+}
+
+// Rerank implements Reranker. Candidates without a creation time have no
+// recency.
+func (r *Recency) Rerank(_ context.Context, _ string, cands []Candidate) ([]Candidate, error) {
+	rel := relevance(cands)
+	now := r.cfg.Now()
+	out := make([]Candidate, len(cands))
+	for i, c := range cands {
+		recency := 0.0
+		if !c.CreatedAt.IsZero() {
+			age := math.Max(0, now.Sub(c.CreatedAt).Hours())
+			recency = math.Exp2(-age / r.cfg.HalfLife.Hours())
+		}
+		c.Score = float32(r.cfg.RelevanceWeight*rel(c.Score) + r.cfg.RecencyWeight*recency + r.cfg.ImportanceWeight*c.Importance)
+		out[i] = c
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	return out, nil
+}
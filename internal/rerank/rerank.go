@@ -0,0 +1,150 @@
+// Package rerank reorders search candidates in a second stage after
+// retrieval. Rerankers compose into a Pipeline, which is itself a
+// Reranker.
+package rerank
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Candidate is a search result to rerank. Score is the score of the
+// previous stage; candidates arrive best first, whichever direction the
+// scores improve in. Vector is only needed by MMR and Importance, read from
+// a memory's "importance" metadata, is between 0 and 1.
+type Candidate struct {
+	ID         int64
+	Text       string
+	Score      float32
+	Vector     []float32
+	CreatedAt  time.Time
+	Importance float64
+}
+
+// Reranker reorders candidates for a query. It returns them best first
+// with Score set to its own score, higher being better.
+type Reranker interface {
+	Rerank(ctx context.Context, query string, cands []Candidate) ([]Candidate, error)
+}
+
+// Pipeline runs rerankers in order, each reordering the output of the
+// previous one.
+type Pipeline []Reranker
+
+// Rerank implements Reranker.
+func (p Pipeline) Rerank(ctx context.Context, query string, cands []Candidate) ([]Candidate, error) {
+	for _, r := range p {
+		var err error
+		if cands, err = r.Rerank(ctx, query, cands); err != nil {
+			return nil, err
+		}
+	}
+	return cands, nil
+}
+
+// Reranker types of a Spec.
+const (
+	TypeCrossEncoder = "cross-encoder"
+	TypeMMR          = "mmr"
+	TypeRecency      = "recency"
+)
+
+var (
+	// ErrInvalidSpec is returned by Build for an unknown reranker type or
+	// out of range parameters.
+	ErrInvalidSpec = errors.New("rerank: invalid reranker")
+	// ErrNoCrossEncoder is returned by Build for a cross-encoder stage when
+	// no cross-encoder is configured.
+	ErrNoCrossEncoder = errors.New("rerank: no cross-encoder configured")
+)
+
+// Spec describes one stage of a pipeline requested with a search. Zero
+// parameters take the defaults of NewMMR and NewRecency.
+type Spec struct {
+	Type string `json:"type"`
+	// Lambda trades relevance against diversity in MMR.
+	Lambda float64 `json:"lambda,omitempty"`
+	// HalfLife is the age, as a Go duration such as "168h", at which the
+	// recency of a memory has halved.
+	HalfLife string `json:"halfLife,omitempty"`
+	// RelevanceWeight, RecencyWeight and ImportanceWeight weigh the terms
+	// of the recency score.
+	RelevanceWeight  float64 `json:"relevanceWeight,omitempty"`
+	RecencyWeight    float64 `json:"recencyWeight,omitempty"`
+	ImportanceWeight float64 `json:"importanceWeight,omitempty"`
+}
+
+// Build returns the pipeline described by specs, using crossEncoder for
+// cross-encoder stages.
+func Build(specs []Spec, crossEncoder Reranker) (Pipeline, error) {
+	p := make(Pipeline, 0, len(specs))
+	for _, s := range specs {
+		switch s.Type {
+		case TypeCrossEncoder:
+			if crossEncoder == nil {
+				return nil, ErrNoCrossEncoder
+			}
+			p = append(p, crossEncoder)
+		case TypeMMR:
+			if s.Lambda < 0 || s.Lambda > 1 {
+				return nil, fmt.Errorf("%w: mmr lambda %v outside [0, 1]", ErrInvalidSpec, s.Lambda)
+			}
+			p = append(p, NewMMR(s.Lambda))
+		case TypeRecency:
+			var halfLife time.Duration
+			if s.HalfLife != "" {
+				var err error
+				if halfLife, err = time.ParseDuration(s.HalfLife); err != nil || halfLife <= 0 {
+					return nil, fmt.Errorf("%w: half-life %q", ErrInvalidSpec, s.HalfLife)
+				}
+			}
+			if s.RelevanceWeight < 0 || s.RecencyWeight < 0 || s.ImportanceWeight < 0 {
+				return nil, fmt.Errorf("%w: negative recency weight", ErrInvalidSpec)
+			}
+			p = append(p, NewRecency(RecencyConfig{
+				HalfLife:         halfLife,
+				RelevanceWeight:  s.RelevanceWeight,
+				RecencyWeight:    s.RecencyWeight,
+				ImportanceWeight: s.ImportanceWeight,
+			}))
+		default:
+			return nil, fmt.Errorf("%w: unknown type %q", ErrInvalidSpec, s.Type)
+		}
+	}
+	return p, nil
+}
+
+// Config holds cross-encoder settings.
+type Config struct {
+	// URL is the root of a model server with a /rerank endpoint, such as
+	// Hugging Face text-embeddings-inference. Empty disables the
+	// cross-encoder.
+	URL    string
+	APIKey string
+	Model  string
+}
+
+// LoadConfig reads settings from environment variables.
+func LoadConfig() Config {
+	return Config{
+		URL:    os.Getenv("MEM0_RERANK_URL"),
+		APIKey: os.Getenv("MEM0_RERANK_KEY"),
+		Model:  os.Getenv("MEM0_RERANK_MODEL"),
+	}
+}
+
+// relevance returns a function scaling the scores of cands, ordered best
+// first, onto [0, 1] with the best at 1.
+func relevance(cands []Candidate) func(float32) float64 {
+	if len(cands) == 0 {
+		return func(float32) float64 { return 0 }
+	}
+	best, worst := float64(cands[0].Score), float64(cands[len(cands)-1].Score)
+	if best == worst {
+		return func(float32) float64 { return 1 }
+	}
+	return func(s float32) float64 { return (float64(s) - worst) / (best - worst) }
+}
@@ -0,0 +1,138 @@
+package rerank
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func ids(cands []Candidate) []int64 {
+	out := make([]int64, len(cands))
+	for i, c := range cands {
+		out[i] = c.ID
+	}
+	return out
+}
+
+func equal(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestCrossEncoder(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rerank" || r.Header.Get("Authorization") != "Bearer k" {
+			t.Fatalf("unexpected request %s", r.URL.Path)
+		}
+		var req struct {
+			Model string   `json:"model"`
+			Query string   `json:"query"`
+			Texts []string `json:"texts"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if req.Model != "m" || req.Query != "tea" || len(req.Texts) != 3 || req.Texts[1] != "likes tea" {
+			t.Fatalf("bad request %+v", req)
+		}
+		_, _ = w.Write([]byte(`[{"index":1,"score":0.9},{"index":2,"score":0.5},{"index":0,"score":0.1}]`))
+	}))
+	defer srv.Close()
+
+	ce := NewCrossEncoder(Config{URL: srv.URL + "/", APIKey: "k", Model: "m"})
+	out, err := ce.Rerank(context.Background(), "tea", []Candidate{{ID: 1, Text: "lives in Paris"}, {ID: 2, Text: "likes tea"}, {ID: 3, Text: "drinks coffee"}})
+	if err != nil {
+		t.Fatalf("rerank: %v", err)
+	}
+	if !equal(ids(out), []int64{2, 3, 1}) || out[0].Score != 0.9 {
+		t.Fatalf("unexpected order %+v", out)
+	}
+
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[{"index":5,"score":1}]`))
+	}))
+	defer bad.Close()
+	if _, err := NewCrossEncoder(Config{URL: bad.URL}).Rerank(context.Background(), "q", []Candidate{{ID: 1}}); err == nil {
+		t.Fatalf("expected an error for an out of range index")
+	}
+}
+
+func TestMMR(t *testing.T) {
+	cands := []Candidate{
+		{ID: 1, Score: 0.9, Vector: []float32{1, 0}},
+		{ID: 2, Score: 0.89, Vector: []float32{1, 0.01}},
+		{ID: 3, Score: 0.5, Vector: []float32{0, 1}},
+	}
+	out, err := NewMMR(0.5).Rerank(context.Background(), "", cands)
+	if err != nil {
+		t.Fatalf("rerank: %v", err)
+	}
+	if !equal(ids(out), []int64{1, 3, 2}) {
+		t.Fatalf("near-duplicate not demoted: %v", ids(out))
+	}
+	if out, _ := NewMMR(1).Rerank(context.Background(), "", cands); !equal(ids(out), []int64{1, 2, 3}) {
+		t.Fatalf("lambda 1 should keep relevance order: %v", ids(out))
+	}
+}
+
+func TestRecency(t *testing.T) {
+	now := time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC)
+	cands := []Candidate{
+		{ID: 1, Score: 0.9, CreatedAt: now.AddDate(0, 0, -300)},
+		{ID: 2, Score: 0.8, CreatedAt: now.Add(-time.Hour)},
+		{ID: 3, Score: 0.1, CreatedAt: now.AddDate(0, 0, -300), Importance: 1},
+	}
+	r := NewRecency(RecencyConfig{HalfLife: 24 * time.Hour, RelevanceWeight: 1, RecencyWeight: 1, Now: func() time.Time { return now }})
+	out, err := r.Rerank(context.Background(), "", cands)
+	if err != nil {
+		t.Fatalf("rerank: %v", err)
+	}
+	if !equal(ids(out), []int64{2, 1, 3}) {
+		t.Fatalf("recent memory not promoted: %v", ids(out))
+	}
+	r = NewRecency(RecencyConfig{ImportanceWeight: 2, RelevanceWeight: 1, Now: func() time.Time { return now }})
+	if out, _ := r.Rerank(context.Background(), "", cands); out[0].ID != 3 {
+		t.Fatalf("important memory not promoted: %v", ids(out))
+	}
+}
+
+type reverse struct{}
+
+func (reverse) Rerank(_ context.Context, _ string, cands []Candidate) ([]Candidate, error) {
+	out := make([]Candidate, len(cands))
+	for i, c := range cands {
+		c.Score = float32(i)
+		out[len(cands)-1-i] = c
+	}
+	return out, nil
+}
+
+func TestBuild(t *testing.T) {
+	p, err := Build([]Spec{{Type: TypeCrossEncoder}, {Type: TypeMMR, Lambda: 1}}, reverse{})
+	if err != nil || len(p) != 2 {
+		t.Fatalf("build: %v %v", p, err)
+	}
+	out, err := p.Rerank(context.Background(), "q", []Candidate{{ID: 1}, {ID: 2}, {ID: 3}})
+	if err != nil || !equal(ids(out), []int64{3, 2, 1}) {
+		t.Fatalf("pipeline: %v %v", ids(out), err)
+	}
+	if _, err := Build([]Spec{{Type: TypeCrossEncoder}}, nil); !errors.Is(err, ErrNoCrossEncoder) {
+		t.Fatalf("expected ErrNoCrossEncoder, got %v", err)
+	}
+	for _, s := range []Spec{{Type: "llm"}, {Type: TypeMMR, Lambda: 2}, {Type: TypeRecency, HalfLife: "a week"}, {Type: TypeRecency, RecencyWeight: -1}} {
+		if _, err := Build([]Spec{s}, nil); !errors.Is(err, ErrInvalidSpec) {
+			t.Fatalf("%+v: expected ErrInvalidSpec, got %v", s, err)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+
+	"mem0-go/internal/graph"
+	"mem0-go/internal/memory"
+)
+
+// createEntityRequest represents the payload for creating a graph node.
+type createEntityRequest struct {
+	Label      string                 `json:"label"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// createRelationshipRequest represents the payload for relating two nodes.
+type createRelationshipRequest struct {
+	From       string                 `json:"from"`
+	To         string                 `json:"to"`
+	Type       string                 `json:"type"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// updatePropertiesRequest represents the payload for updating a node or
+// relationship: the properties are merged into the stored ones and a null
+// value removes a property.
+type updatePropertiesRequest struct {
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// registerEntities sets up the entity and relationship routes on api.
+func registerEntities(api fiber.Router, svc *memory.Service) {
+	// @Summary Create entity
+	// @Description Create a graph node in the caller's project; the Memory and Merge labels are reserved
+	// @Tags graph
+	// @Accept json
+	// @Produce json
+	// @Param data body createEntityRequest true "entity"
+	// @Success 200 {object} map[string]string
+	// @Failure 400 {object} map[string]string
+	// @Failure 403 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Router /api/v1/entities [post]
+	api.Post("/entities", func(c *fiber.Ctx) error {
+		var req createEntityRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid json"})
+		}
+		if req.Label == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "label required"})
+		}
+		id, err := svc.CreateEntity(c.Context(), req.Label, req.Properties)
+		if err != nil {
+			return graphError(c, err)
+		}
+		return c.JSON(fiber.Map{"id": id})
+	})
+
+	// @Summary Find entities
+	// @Description Graph nodes with the given label, any when omitted, whose properties equal every other query parameter; values are read as JSON when they parse, so age=30 matches a number and name=Alice a string
+	// @Tags graph
+	// @Produce json
+	// @Param label query string false "label"
+	// @Success 200 {array} graph.Node
+	// @Failure 403 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Router /api/v1/entities [get]
+	api.Get("/entities", func(c *fiber.Ctx) error {
+		q := c.Queries()
+		label := q["label"]
+		delete(q, "label")
+		nodes, err := svc.FindEntities(c.Context(), label, queryProps(q))
+		if err != nil {
+			return graphError(c, err)
+		}
+		return c.JSON(nodes)
+	})
+
+	// @Summary Get entity
+	// @Tags graph
+	// @Produce json
+	// @Param id path string true "Node ID"
+	// @Success 200 {object} graph.Node
+	// @Failure 403 {object} map[string]string
+	// @Failure 404 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Router /api/v1/entities/{id} [get]
+	api.Get("/entities/:id", func(c *fiber.Ctx) error {
+		n, err := svc.Entity(c.Context(), c.Params("id"))
+		if err != nil {
+			return graphError(c, err)
+		}
+		if n == nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "node not found"})
+		}
+		return c.JSON(n)
+	})
+
+	// @Summary Update entity
+	// @Description Merge properties into a graph node; null removes a property
+	// @Tags graph
+	// @Accept json
+	// @Produce json
+	// @Param id path string true "Node ID"
+	// @Param data body updatePropertiesRequest true "properties"
+	// @Success 200 {object} graph.Node
+	// @Failure 400 {object} map[string]string
+	// @Failure 403 {object} map[string]string
+	// @Failure 404 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Router /api/v1/entities/{id} [patch]
+	api.Patch("/entities/:id", func(c *fiber.Ctx) error {
+		var req updatePropertiesRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid json"})
+		}
+		n, err := svc.UpdateEntity(c.Context(), c.Params("id"), req.Properties)
+		if err != nil {
+			return graphError(c, err)
+		}
+		return c.JSON(n)
+	})
+
+	// @Summary Delete entity
+	// @Description Delete a graph node; one with relationships is only deleted, together with them, when cascade is true
+	// @Tags graph
+	// @Param id path string true "Node ID"
+	// @Param cascade query bool false "delete the node's relationships too"
+	// @Success 204
+	// @Failure 403 {object} map[string]string
+	// @Failure 404 {object} map[string]string
+	// @Failure 409 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Router /api/v1/entities/{id} [delete]
+	api.Delete("/entities/:id", func(c *fiber.Ctx) error {
+		if err := svc.DeleteEntity(c.Context(), c.Params("id"), c.Query("cascade") == "true"); err != nil {
+			return graphError(c, err)
+		}
+		return c.Status(http.StatusNoContent).Send(nil)
+	})
+
+	// @Summary Entity relationships
+	// @Description Relationships starting or ending at a graph node
+	// @Tags graph
+	// @Produce json
+	// @Param id path string true "Node ID"
+	// @Success 200 {array} graph.Edge
+	// @Failure 403 {object} map[string]string
+	// @Failure 404 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Router /api/v1/entities/{id}/relationships [get]
+	api.Get("/entities/:id/relationships", func(c *fiber.Ctx) error {
+		n, err := svc.Entity(c.Context(), c.Params("id"))
+		if err != nil {
+			return graphError(c, err)
+		}
+		if n == nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "node not found"})
+		}
+		edges, err := svc.Relationships(c.Context(), n.ID)
+		if err != nil {
+			return graphError(c, err)
+		}
+		return c.JSON(edges)
+	})
+
+	// @Summary Create relationship
+	// @Description Relate two graph nodes of the caller's project
+	// @Tags graph
+	// @Accept json
+	// @Produce json
+	// @Param data body createRelationshipRequest true "relationship"
+	// @Success 200 {object} map[string]string
+	// @Failure 400 {object} map[string]string
+	// @Failure 403 {object} map[string]string
+	// @Failure 404 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Router /api/v1/relationships [post]
+	api.Post("/relationships", func(c *fiber.Ctx) error {
+		var req createRelationshipRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid json"})
+		}
+		if req.From == "" || req.To == "" || req.Type == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "from, to and type required"})
+		}
+		id, err := svc.RelateEntities(c.Context(), req.From, req.To, req.Type, req.Properties)
+		if err != nil {
+			return graphError(c, err)
+		}
+		return c.JSON(fiber.Map{"id": id})
+	})
+
+	// @Summary Find relationships
+	// @Description Relationships from and to the given nodes and of the given type, each optional, whose properties equal every other query parameter
+	// @Tags graph
+	// @Produce json
+	// @Param from query string false "start node ID"
+	// @Param to query string false "end node ID"
+	// @Param type query string false "relationship type"
+	// @Success 200 {array} graph.Edge
+	// @Failure 403 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Router /api/v1/relationships [get]
+	api.Get("/relationships", func(c *fiber.Ctx) error {
+		q := c.Queries()
+		eq := graph.EdgeQuery{From: q["from"], To: q["to"], Type: q["type"]}
+		delete(q, "from")
+		delete(q, "to")
+		delete(q, "type")
+		eq.Props = queryProps(q)
+		edges, err := svc.FindRelationships(c.Context(), eq)
+		if err != nil {
+			return graphError(c, err)
+		}
+		return c.JSON(edges)
+	})
+
+	// @Summary Get relationship
+	// @Tags graph
+	// @Produce json
+	// @Param id path string true "Relationship ID"
+	// @Success 200 {object} graph.Edge
+	// @Failure 403 {object} map[string]string
+	// @Failure 404 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Router /api/v1/relationships/{id} [get]
+	api.Get("/relationships/:id", func(c *fiber.Ctx) error {
+		e, err := svc.Relationship(c.Context(), c.Params("id"))
+		if err != nil {
+			return graphError(c, err)
+		}
+		if e == nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "relationship not found"})
+		}
+		return c.JSON(e)
+	})
+
+	// @Summary Update relationship
+	// @Description Merge properties into a relationship; null removes a property
+	// @Tags graph
+	// @Accept json
+	// @Produce json
+	// @Param id path string true "Relationship ID"
+	// @Param data body updatePropertiesRequest true "properties"
+	// @Success 200 {object} graph.Edge
+	// @Failure 400 {object} map[string]string
+	// @Failure 403 {object} map[string]string
+	// @Failure 404 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Router /api/v1/relationships/{id} [patch]
+	api.Patch("/relationships/:id", func(c *fiber.Ctx) error {
+		var req updatePropertiesRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid json"})
+		}
+		e, err := svc.UpdateRelationship(c.Context(), c.Params("id"), req.Properties)
+		if err != nil {
+			return graphError(c, err)
+		}
+		return c.JSON(e)
+	})
+
+	// @Summary Delete relationship
+	// @Tags graph
+	// @Param id path string true "Relationship ID"
+	// @Success 204
+	// @Failure 403 {object} map[string]string
+	// @Failure 404 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Router /api/v1/relationships/{id} [delete]
+	api.Delete("/relationships/:id", func(c *fiber.Ctx) error {
+		if err := svc.DeleteRelationship(c.Context(), c.Params("id")); err != nil {
+			return graphError(c, err)
+		}
+		return c.Status(http.StatusNoContent).Send(nil)
+	})
+}
+
+// queryProps reads query parameters as property values: as JSON when they
+// parse and as strings otherwise.
+func queryProps(q map[string]string) map[string]interface{} {
+	if len(q) == 0 {
+		return nil
+	}
+	props := make(map[string]interface{}, len(q))
+	for k, v := range q {
+		var val interface{}
+		if err := json.Unmarshal([]byte(v), &val); err != nil {
+			val = v
+		}
+		props[k] = val
+	}
+	return props
+}
@@ -0,0 +1,158 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+
+	"mem0-go/internal/graph"
+	"mem0-go/internal/memory"
+)
+
+// expansionRequest selects the relationships a graph query follows:
+// direction out (the default), in or both, any of Types (every type when
+// empty), into nodes matching every Where predicate.
+type expansionRequest struct {
+	Direction string            `json:"direction"`
+	Types     []string          `json:"types"`
+	Where     []graph.Predicate `json:"where"`
+}
+
+func (r expansionRequest) expansion() graph.Expansion {
+	return graph.Expansion{Direction: graph.Direction(r.Direction), Types: r.Types, Where: r.Where}
+}
+
+// traverseRequest represents the payload for walking the graph from Start
+// over up to MaxDepth relationships, breadth first (bfs, the default) or
+// depth first (dfs).
+type traverseRequest struct {
+	Start string `json:"start"`
+	expansionRequest
+	MaxDepth int    `json:"maxDepth"`
+	Strategy string `json:"strategy"`
+	Limit    int    `json:"limit"`
+}
+
+// pathRequest represents the payload for finding a shortest path over at
+// most MaxDepth relationships, 6 by default.
+type pathRequest struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	expansionRequest
+	MaxDepth int `json:"maxDepth"`
+}
+
+// subgraphRequest represents the payload for extracting the neighbourhood
+// of Center.
+type subgraphRequest struct {
+	Center string `json:"center"`
+	expansionRequest
+	Depth int `json:"depth"`
+}
+
+// RegisterGraph sets up the entity, relationship, graph traversal and
+// entity merge routes backed by svc.
+func RegisterGraph(app *fiber.App, svc *memory.Service) {
+	api := app.Group("/api/v1")
+	registerEntities(api, svc)
+	registerMerges(api, svc)
+
+	// @Summary Traverse graph
+	// @Description Walk the graph from an entity, breadth or depth first, following relationships by direction, type and node predicates
+	// @Tags graph
+	// @Accept json
+	// @Produce json
+	// @Param data body traverseRequest true "traversal"
+	// @Success 200 {object} map[string][]graph.Visit
+	// @Failure 400 {object} map[string]string
+	// @Failure 403 {object} map[string]string
+	// @Failure 404 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Router /api/v1/graph/traverse [post]
+	api.Post("/graph/traverse", func(c *fiber.Ctx) error {
+		var req traverseRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid json"})
+		}
+		visits, err := svc.Traverse(c.Context(), graph.TraversalQuery{
+			Start:     req.Start,
+			Expansion: req.expansion(),
+			MaxDepth:  req.MaxDepth,
+			Strategy:  graph.Strategy(req.Strategy),
+			Limit:     req.Limit,
+		})
+		if err != nil {
+			return graphError(c, err)
+		}
+		return c.JSON(fiber.Map{"visits": visits})
+	})
+
+	// @Summary Shortest path
+	// @Description Find a shortest path between two entities; path is null when there is none within maxDepth
+	// @Tags graph
+	// @Accept json
+	// @Produce json
+	// @Param data body pathRequest true "path query"
+	// @Success 200 {object} map[string]graph.Path
+	// @Failure 400 {object} map[string]string
+	// @Failure 403 {object} map[string]string
+	// @Failure 404 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Router /api/v1/graph/path [post]
+	api.Post("/graph/path", func(c *fiber.Ctx) error {
+		var req pathRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid json"})
+		}
+		path, err := svc.ShortestPath(c.Context(), req.From, req.To, req.expansion(), req.MaxDepth)
+		if err != nil {
+			return graphError(c, err)
+		}
+		return c.JSON(fiber.Map{"path": path})
+	})
+
+	// @Summary Extract subgraph
+	// @Description Entities within depth relationships of an entity and the relationships between them
+	// @Tags graph
+	// @Accept json
+	// @Produce json
+	// @Param data body subgraphRequest true "subgraph query"
+	// @Success 200 {object} graph.Subgraph
+	// @Failure 400 {object} map[string]string
+	// @Failure 403 {object} map[string]string
+	// @Failure 404 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Router /api/v1/graph/subgraph [post]
+	api.Post("/graph/subgraph", func(c *fiber.Ctx) error {
+		var req subgraphRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid json"})
+		}
+		sg, err := svc.Subgraph(c.Context(), req.Center, req.expansion(), req.Depth)
+		if err != nil {
+			return graphError(c, err)
+		}
+		return c.JSON(sg)
+	})
+}
+
+// graphError maps graph query errors to a status code.
+func graphError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, graph.ErrInvalidQuery):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, graph.ErrNodeNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "node not found"})
+	case errors.Is(err, graph.ErrEdgeNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "relationship not found"})
+	case errors.Is(err, memory.ErrInvalidMerge), errors.Is(err, memory.ErrReservedLabel):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, memory.ErrMergeNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "merge not found"})
+	case errors.Is(err, memory.ErrEntityHasRelationships), errors.Is(err, memory.ErrMergeUndone):
+		return c.Status(http.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return errorResponse(c, err)
+	}
+}
@@ -1,74 +1,246 @@
 package rest
 
 import (
-	"encoding/json"
+	"errors"
+	"net/http"
 	"strconv"
-	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 
+	"mem0-go/internal/auth"
+	"mem0-go/internal/db"
+	"mem0-go/internal/llm"
 	"mem0-go/internal/memory"
+	"mem0-go/internal/rerank"
+	"mem0-go/internal/vector"
 )
 
 // createMemoryRequest represents the payload for creating a memory.
+// Vector is optional; the content is embedded server-side when omitted.
 type createMemoryRequest struct {
-	UserID  int64     `json:"userID"`
-	Content string    `json:"content"`
-	Vector  []float32 `json:"vector"`
+	UserID   int64                  `json:"userID"`
+	AgentID  string                 `json:"agentID"`
+	RunID    string                 `json:"runID"`
+	Content  string                 `json:"content"`
+	Tags     []string               `json:"tags"`
+	Metadata map[string]interface{} `json:"metadata"`
+	Vector   []float32              `json:"vector"`
 }
 
-// searchRequest represents the payload for searching memories.
+// searchRequest represents the payload for searching memories. Either
+// Query text or a raw Vector may be given. The scope fields and Filter
+// restrict results by payload. Mode is vector (the default), keyword or
+// hybrid; hybrid searches fuse results by Fusion, rrf (the default) or
+// weighted with VectorWeight. Rerank reorders the top Candidates results
+// through a pipeline of rerankers.
 type searchRequest struct {
-	Vector []float32 `json:"vector"`
-	Limit  int       `json:"limit"`
+	Query        string         `json:"query"`
+	Vector       []float32      `json:"vector"`
+	Limit        int            `json:"limit"`
+	UserID       int64          `json:"userID"`
+	AgentID      string         `json:"agentID"`
+	RunID        string         `json:"runID"`
+	Tags         []string       `json:"tags"`
+	Filter       *vector.Filter `json:"filter"`
+	Mode         string         `json:"mode"`
+	Fusion       string         `json:"fusion"`
+	VectorWeight *float32       `json:"vectorWeight"`
+	Rerank       []rerank.Spec  `json:"rerank"`
+	Candidates   int            `json:"candidates"`
+}
+
+// request converts r for the service.
+func (r searchRequest) request() memory.SearchRequest {
+	return memory.SearchRequest{
+		Query:        r.Query,
+		Vector:       r.Vector,
+		Limit:        r.Limit,
+		UserID:       r.UserID,
+		AgentID:      r.AgentID,
+		RunID:        r.RunID,
+		Tags:         r.Tags,
+		Filter:       r.Filter,
+		Mode:         r.Mode,
+		Fusion:       r.Fusion,
+		VectorWeight: r.VectorWeight,
+		Rerank:       r.Rerank,
+		Candidates:   r.Candidates,
+	}
+}
+
+// graphSearchRequest represents the payload for a graph-augmented search:
+// the search for seed memories, how many relationships to expand from
+// their entities and how many related memories to return.
+type graphSearchRequest struct {
+	searchRequest
+	Hops         int `json:"hops"`
+	RelatedLimit int `json:"relatedLimit"`
+}
+
+// updateMemoryRequest represents the payload for PUT and PATCH. PUT
+// replaces content, tags and metadata; PATCH changes only the fields given.
+type updateMemoryRequest struct {
+	Content  *string                `json:"content"`
+	Tags     []string               `json:"tags"`
+	Metadata map[string]interface{} `json:"metadata"`
+	Vector   []float32              `json:"vector"`
+}
+
+// actorHeader names the caller recorded in a memory's history.
+const actorHeader = "X-Actor"
+
+// ingestRequest represents a conversation turn to extract memories from.
+type ingestRequest struct {
+	UserID   int64         `json:"userID"`
+	Messages []llm.Message `json:"messages"`
 }
 
 // Register sets up REST routes on the given app using the service.
 func Register(app *fiber.App, svc *memory.Service) {
+	api := app.Group("/api/v1")
+
 	// @Summary Create memory
-	// @Description Store memory text and embedding
+	// @Description Store memory text, embedding it server-side unless a vector is given
 	// @Tags memories
 	// @Accept json
 	// @Produce json
 	// @Param data body createMemoryRequest true "memory info"
 	// @Success 200 {object} map[string]int64
 	// @Failure 400 {object} map[string]string
+	// @Failure 403 {object} map[string]string
 	// @Failure 500 {object} map[string]string
 	// @Router /api/v1/memories [post]
-	app.Post("/api/v1/memories", func(c *fiber.Ctx) error {
+	api.Post("/memories", func(c *fiber.Ctx) error {
 		var req createMemoryRequest
-		if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		if err := c.BodyParser(&req); err != nil {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid json"})
 		}
-		id, err := svc.StoreMemory(c.Context(), req.UserID, req.Content, req.Vector)
+		id, err := svc.Store(c.Context(), memory.StoreRequest{
+			UserID:   req.UserID,
+			AgentID:  req.AgentID,
+			RunID:    req.RunID,
+			Content:  req.Content,
+			Tags:     req.Tags,
+			Metadata: req.Metadata,
+			Vector:   req.Vector,
+			Actor:    c.Get(actorHeader),
+		})
 		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+			return errorResponse(c, err)
 		}
 		return c.JSON(fiber.Map{"id": id})
 	})
 
+	// @Summary List memories
+	// @Description Page through memories, filtered and sorted, continuing from the cursor of the previous page
+	// @Tags memories
+	// @Produce json
+	// @Param userID query int false "owner"
+	// @Param agentID query string false "agent"
+	// @Param runID query string false "session"
+	// @Param tag query string false "tag the memories carry"
+	// @Param createdAfter query string false "RFC 3339 time, inclusive"
+	// @Param createdBefore query string false "RFC 3339 time, exclusive"
+	// @Param contains query string false "text the content contains, case-insensitively"
+	// @Param sort query string false "id (default) or createdAt"
+	// @Param order query string false "asc (default) or desc"
+	// @Param cursor query string false "nextCursor of the previous page"
+	// @Param limit query int false "page size, default 20, at most 100"
+	// @Success 200 {object} memory.MemoryPage
+	// @Failure 400 {object} map[string]string
+	// @Failure 403 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Router /api/v1/memories [get]
+	api.Get("/memories", func(c *fiber.Ctx) error {
+		req, err := listRequest(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		page, err := svc.ListMemories(c.Context(), req)
+		if err != nil {
+			return errorResponse(c, err)
+		}
+		return c.JSON(page)
+	})
+
+	// @Summary Ingest conversation
+	// @Description Extract facts from messages and add, update or delete memories accordingly
+	// @Tags memories
+	// @Accept json
+	// @Produce json
+	// @Param data body ingestRequest true "conversation turn"
+	// @Success 200 {object} map[string][]memory.IngestResult
+	// @Failure 400 {object} map[string]string
+	// @Failure 403 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Failure 503 {object} map[string]string
+	// @Router /api/v1/memories/ingest [post]
+	api.Post("/memories/ingest", func(c *fiber.Ctx) error {
+		var req ingestRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid json"})
+		}
+		res, err := svc.Ingest(c.Context(), req.UserID, req.Messages)
+		if errors.Is(err, memory.ErrIngestUnavailable) {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err != nil {
+			return errorResponse(c, err)
+		}
+		return c.JSON(fiber.Map{"results": res})
+	})
+
 	// @Summary Search memories
-	// @Description Semantic search over stored memories
+	// @Description Semantic, keyword or hybrid search over stored memories, filtered by payload and optionally reranked
 	// @Tags memories
 	// @Accept json
 	// @Produce json
 	// @Param data body searchRequest true "search parameters"
 	// @Success 200 {object} map[string][]memory.MemoryResult
 	// @Failure 400 {object} map[string]string
+	// @Failure 403 {object} map[string]string
 	// @Failure 500 {object} map[string]string
 	// @Router /api/v1/memories/search [post]
-	app.Post("/api/v1/memories/search", func(c *fiber.Ctx) error {
+	api.Post("/memories/search", func(c *fiber.Ctx) error {
 		var req searchRequest
-		if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		if err := c.BodyParser(&req); err != nil {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid json"})
 		}
-		res, err := svc.Search(c.Context(), req.Vector, req.Limit)
+		res, err := svc.SearchMemories(c.Context(), req.request())
 		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+			return errorResponse(c, err)
 		}
 		return c.JSON(fiber.Map{"results": res})
 	})
 
+	// @Summary Graph-augmented search
+	// @Description Find seed memories, expand the entities they are related to over the graph, and return the memories linked to the entities reached with the relationships followed
+	// @Tags memories
+	// @Accept json
+	// @Produce json
+	// @Param data body graphSearchRequest true "search parameters"
+	// @Success 200 {object} memory.GraphSearchResult
+	// @Failure 400 {object} map[string]string
+	// @Failure 403 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Router /api/v1/memories/search/graph [post]
+	api.Post("/memories/search/graph", func(c *fiber.Ctx) error {
+		var req graphSearchRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid json"})
+		}
+		res, err := svc.GraphSearch(c.Context(), memory.GraphSearchRequest{
+			SearchRequest: req.request(),
+			Hops:          req.Hops,
+			RelatedLimit:  req.RelatedLimit,
+		})
+		if err != nil {
+			return errorResponse(c, err)
+		}
+		return c.JSON(res)
+	})
+
 	// @Summary Get memory
 	// @Description Retrieve memory by ID
 	// @Tags memories
@@ -76,22 +248,194 @@ func Register(app *fiber.App, svc *memory.Service) {
 	// @Param id path int true "Memory ID"
 	// @Success 200 {object} db.Memory
 	// @Failure 400 {object} map[string]string
+	// @Failure 404 {object} map[string]string
 	// @Failure 500 {object} map[string]string
 	// @Router /api/v1/memories/{id} [get]
-	app.Get("/api/v1/memories/", func(c *fiber.Ctx) error {
-		parts := strings.Split(c.Path(), "/")
-		if len(parts) == 0 {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
-		}
-		idStr := parts[len(parts)-1]
-		id, err := strconv.ParseInt(idStr, 10, 64)
+	api.Get("/memories/:id", func(c *fiber.Ctx) error {
+		id, err := memoryID(c)
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
 		}
 		m, err := svc.GetMemory(c.Context(), id)
 		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+			return errorResponse(c, err)
 		}
 		return c.JSON(m)
 	})
+
+	// @Summary Update memory
+	// @Description Replace (PUT) or partially update (PATCH) a memory, re-indexing its vector and graph node
+	// @Tags memories
+	// @Accept json
+	// @Produce json
+	// @Param id path int true "Memory ID"
+	// @Param X-Actor header string false "caller recorded in the history"
+	// @Param data body updateMemoryRequest true "new values"
+	// @Success 200 {object} db.Memory
+	// @Failure 400 {object} map[string]string
+	// @Failure 404 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Router /api/v1/memories/{id} [put]
+	// @Router /api/v1/memories/{id} [patch]
+	api.Put("/memories/:id", updateMemory(svc, true))
+	api.Patch("/memories/:id", updateMemory(svc, false))
+
+	// @Summary Delete memory
+	// @Description Delete a memory with its vector point and graph node
+	// @Tags memories
+	// @Param id path int true "Memory ID"
+	// @Param X-Actor header string false "caller recorded in the history"
+	// @Success 204
+	// @Failure 400 {object} map[string]string
+	// @Failure 404 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Router /api/v1/memories/{id} [delete]
+	api.Delete("/memories/:id", func(c *fiber.Ctx) error {
+		id, err := memoryID(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+		}
+		if err := svc.Delete(c.Context(), id, c.Get(actorHeader)); err != nil {
+			return errorResponse(c, err)
+		}
+		return c.Status(http.StatusNoContent).Send(nil)
+	})
+
+	// @Summary Memory history
+	// @Description Every recorded version of a memory, oldest first
+	// @Tags memories
+	// @Produce json
+	// @Param id path int true "Memory ID"
+	// @Success 200 {object} map[string][]db.HistoryEntry
+	// @Failure 400 {object} map[string]string
+	// @Failure 404 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Router /api/v1/memories/{id}/history [get]
+	api.Get("/memories/:id/history", func(c *fiber.Ctx) error {
+		id, err := memoryID(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+		}
+		h, err := svc.History(c.Context(), id)
+		if err != nil {
+			return errorResponse(c, err)
+		}
+		return c.JSON(fiber.Map{"history": h})
+	})
+}
+
+// updateMemory handles PUT, which replaces content, tags and metadata, and
+// PATCH, which changes only the fields given.
+func updateMemory(svc *memory.Service, replace bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := memoryID(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+		}
+		var req updateMemoryRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid json"})
+		}
+		if replace {
+			if req.Content == nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "content required"})
+			}
+			if req.Tags == nil {
+				req.Tags = []string{}
+			}
+			if req.Metadata == nil {
+				req.Metadata = map[string]interface{}{}
+			}
+		}
+		m, err := svc.Update(c.Context(), id, memory.UpdateRequest{
+			Content:  req.Content,
+			Tags:     req.Tags,
+			Metadata: req.Metadata,
+			Vector:   req.Vector,
+			Actor:    c.Get(actorHeader),
+		})
+		if err != nil {
+			return errorResponse(c, err)
+		}
+		return c.JSON(m)
+	}
+}
+
+// listRequest parses the query parameters of GET /memories.
+func listRequest(c *fiber.Ctx) (memory.ListRequest, error) {
+	req := memory.ListRequest{
+		AgentID:  c.Query("agentID"),
+		RunID:    c.Query("runID"),
+		Tag:      c.Query("tag"),
+		Contains: c.Query("contains"),
+		Sort:     c.Query("sort"),
+		Cursor:   c.Query("cursor"),
+	}
+	switch c.Query("order") {
+	case "", "asc":
+	case "desc":
+		req.Desc = true
+	default:
+		return req, errors.New("invalid order")
+	}
+	var err error
+	if v := c.Query("userID"); v != "" {
+		if req.UserID, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return req, errors.New("invalid userID")
+		}
+	}
+	if v := c.Query("limit"); v != "" {
+		if req.Limit, err = strconv.Atoi(v); err != nil {
+			return req, errors.New("invalid limit")
+		}
+	}
+	if v := c.Query("createdAfter"); v != "" {
+		if req.CreatedAfter, err = time.Parse(time.RFC3339, v); err != nil {
+			return req, errors.New("invalid createdAfter")
+		}
+	}
+	if v := c.Query("createdBefore"); v != "" {
+		if req.CreatedBefore, err = time.Parse(time.RFC3339, v); err != nil {
+			return req, errors.New("invalid createdBefore")
+		}
+	}
+	return req, nil
+}
+
+// memoryID parses the :id route parameter.
+func memoryID(c *fiber.Ctx) (int64, error) {
+	return strconv.ParseInt(c.Params("id"), 10, 64)
+}
+
+// errorResponse maps service errors to a status code.
+func errorResponse(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, auth.ErrForbidden):
+		return forbidden(c, err)
+	case errors.Is(err, db.ErrNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "memory not found"})
+	case errors.Is(err, memory.ErrInvalidCursor), errors.Is(err, memory.ErrInvalidSort),
+		errors.Is(err, memory.ErrInvalidSearch), errors.Is(err, memory.ErrNoTextSearch),
+		errors.Is(err, rerank.ErrInvalidSpec), errors.Is(err, rerank.ErrNoCrossEncoder):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, memory.ErrNoEmbedder):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "vector required"})
+	case errors.As(err, new(*vector.DimensionError)):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+}
+
+// forbidden responds 403, naming the missing permission when err is an
+// *auth.PermissionError.
+func forbidden(c *fiber.Ctx, err error) error {
+	var perr *auth.PermissionError
+	if errors.As(err, &perr) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error":      "missing permission " + perr.Permission,
+			"permission": perr.Permission,
+		})
+	}
+	return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "forbidden"})
 }
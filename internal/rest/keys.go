@@ -0,0 +1,127 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"mem0-go/internal/auth"
+	"mem0-go/internal/db"
+	"mem0-go/internal/tenant"
+)
+
+// createKeyRequest represents the payload for issuing an API key. UserID
+// defaults to the caller's user and ProjectID to the caller's project.
+type createKeyRequest struct {
+	UserID    int64    `json:"userID"`
+	ProjectID int64    `json:"projectID"`
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+}
+
+// createKeyResponse returns a new key. Key is only ever shown here.
+type createKeyResponse struct {
+	db.APIKey
+	Key string `json:"key"`
+}
+
+// RegisterKeys sets up API key management routes using authn. Keys are
+// issued into projects looked up in tenants.
+func RegisterKeys(app *fiber.App, authn *auth.Authenticator, tenants *tenant.Service) {
+	api := app.Group("/api/v1")
+
+	// @Summary Create API key
+	// @Description Issue an API key; the key is only returned once
+	// @Tags keys
+	// @Accept json
+	// @Produce json
+	// @Param data body createKeyRequest true "key info"
+	// @Success 200 {object} createKeyResponse
+	// @Failure 400 {object} map[string]string
+	// @Failure 403 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Router /api/v1/keys [post]
+	api.Post("/keys", func(c *fiber.Ctx) error {
+		var req createKeyRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid json"})
+		}
+		if req.Name == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "name required"})
+		}
+		k := db.APIKey{UserID: req.UserID, ProjectID: req.ProjectID, Name: req.Name, Scopes: req.Scopes}
+		orgID, err := projectOrg(c.Context(), tenants, k.ProjectID)
+		if errors.Is(err, db.ErrNotFound) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "unknown project"})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		k.OrgID = orgID
+		k, key, err := authn.CreateKey(c.Context(), k)
+		if errors.Is(err, auth.ErrForbidden) {
+			return forbidden(c, err)
+		}
+		if errors.Is(err, auth.ErrUserRequired) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(createKeyResponse{APIKey: k, Key: key})
+	})
+
+	// @Summary List API keys
+	// @Description List the caller's API keys, or a user's keys for admins
+	// @Tags keys
+	// @Produce json
+	// @Param userID query int false "user to list keys of"
+	// @Success 200 {object} map[string][]db.APIKey
+	// @Failure 400 {object} map[string]string
+	// @Failure 403 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Router /api/v1/keys [get]
+	api.Get("/keys", func(c *fiber.Ctx) error {
+		var userID int64
+		if v := c.Query("userID"); v != "" {
+			var err error
+			if userID, err = strconv.ParseInt(v, 10, 64); err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid userID"})
+			}
+		}
+		keys, err := authn.ListKeys(c.Context(), userID)
+		if errors.Is(err, auth.ErrForbidden) {
+			return forbidden(c, err)
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(fiber.Map{"keys": keys})
+	})
+
+	// @Summary Revoke API key
+	// @Description Revoke an API key so it no longer authenticates
+	// @Tags keys
+	// @Param id path int true "Key ID"
+	// @Success 204
+	// @Failure 400 {object} map[string]string
+	// @Failure 404 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Router /api/v1/keys/{id} [delete]
+	api.Delete("/keys/:id", func(c *fiber.Ctx) error {
+		id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+		}
+		err = authn.RevokeKey(c.Context(), id)
+		if errors.Is(err, db.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "key not found"})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(http.StatusNoContent).Send(nil)
+	})
+}
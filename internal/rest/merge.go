@@ -0,0 +1,139 @@
+package rest
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"mem0-go/internal/memory"
+)
+
+// mergeRequest represents the payload for merging the Merged entities
+// into Survivor.
+type mergeRequest struct {
+	Survivor string   `json:"survivor"`
+	Merged   []string `json:"merged"`
+}
+
+// registerMerges sets up the entity resolution and merge routes on api.
+func registerMerges(api fiber.Router, svc *memory.Service) {
+	// @Summary Find duplicate entities
+	// @Description Pairs of entities that may be the same thing, best first, scored by normalized names, aliases and nicknames, and the embedding similarity of their descriptions
+	// @Tags graph
+	// @Produce json
+	// @Param label query string false "label"
+	// @Param minScore query number false "lowest score reported, 0.75 by default"
+	// @Param limit query int false "limit"
+	// @Success 200 {array} memory.DuplicateCandidate
+	// @Failure 400 {object} map[string]string
+	// @Failure 403 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Router /api/v1/graph/duplicates [get]
+	api.Get("/graph/duplicates", func(c *fiber.Ctx) error {
+		opts, err := parseDuplicateOptions(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		dups, err := svc.FindDuplicates(c.Context(), opts)
+		if err != nil {
+			return graphError(c, err)
+		}
+		return c.JSON(dups)
+	})
+
+	// @Summary Merge entities
+	// @Description Merge entities into a survivor: their relationships and memory links move to it, it gains their names as aliases and the properties it lacks, and they are deleted; the merge is recorded so it can be undone
+	// @Tags graph
+	// @Accept json
+	// @Produce json
+	// @Param data body mergeRequest true "merge"
+	// @Success 200 {object} memory.MergeRecord
+	// @Failure 400 {object} map[string]string
+	// @Failure 403 {object} map[string]string
+	// @Failure 404 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Router /api/v1/graph/merges [post]
+	api.Post("/graph/merges", func(c *fiber.Ctx) error {
+		var req mergeRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid json"})
+		}
+		rec, err := svc.MergeEntities(c.Context(), req.Survivor, req.Merged)
+		if err != nil {
+			return graphError(c, err)
+		}
+		return c.JSON(rec)
+	})
+
+	// @Summary List merges
+	// @Description Merges recorded in the caller's project, newest first
+	// @Tags graph
+	// @Produce json
+	// @Success 200 {array} memory.MergeRecord
+	// @Failure 403 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Router /api/v1/graph/merges [get]
+	api.Get("/graph/merges", func(c *fiber.Ctx) error {
+		recs, err := svc.Merges(c.Context())
+		if err != nil {
+			return graphError(c, err)
+		}
+		return c.JSON(recs)
+	})
+
+	// @Summary Get merge
+	// @Description A recorded merge with the entities and relationships it replaced
+	// @Tags graph
+	// @Produce json
+	// @Param id path string true "merge ID"
+	// @Success 200 {object} memory.MergeRecord
+	// @Failure 403 {object} map[string]string
+	// @Failure 404 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Router /api/v1/graph/merges/{id} [get]
+	api.Get("/graph/merges/:id", func(c *fiber.Ctx) error {
+		rec, err := svc.Merge(c.Context(), c.Params("id"))
+		if err != nil {
+			return graphError(c, err)
+		}
+		return c.JSON(rec)
+	})
+
+	// @Summary Undo merge
+	// @Description Re-create the merged entities, with new IDs, move their relationships back and reset the survivor's properties
+	// @Tags graph
+	// @Produce json
+	// @Param id path string true "merge ID"
+	// @Success 200 {object} memory.MergeRecord
+	// @Failure 403 {object} map[string]string
+	// @Failure 404 {object} map[string]string
+	// @Failure 409 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Router /api/v1/graph/merges/{id}/undo [post]
+	api.Post("/graph/merges/:id/undo", func(c *fiber.Ctx) error {
+		rec, err := svc.UndoMerge(c.Context(), c.Params("id"))
+		if err != nil {
+			return graphError(c, err)
+		}
+		return c.JSON(rec)
+	})
+}
+
+// parseDuplicateOptions reads the duplicate search options from the query
+// string.
+func parseDuplicateOptions(c *fiber.Ctx) (memory.DuplicateOptions, error) {
+	opts := memory.DuplicateOptions{Label: c.Query("label")}
+	var err error
+	if v := c.Query("minScore"); v != "" {
+		if opts.MinScore, err = strconv.ParseFloat(v, 64); err != nil {
+			return opts, errors.New("invalid minScore")
+		}
+	}
+	if v := c.Query("limit"); v != "" {
+		if opts.Limit, err = strconv.Atoi(v); err != nil {
+			return opts, errors.New("invalid limit")
+		}
+	}
+	return opts, nil
+}
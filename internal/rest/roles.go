@@ -0,0 +1,218 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"mem0-go/internal/auth"
+	"mem0-go/internal/db"
+	"mem0-go/internal/tenant"
+)
+
+// createRoleRequest represents the payload for defining a role. The role
+// is defined in ProjectID, or in every project of OrgID when ProjectID is
+// 0; both default to the caller's project.
+type createRoleRequest struct {
+	Name        string   `json:"name"`
+	Permissions []string `json:"permissions"`
+	OrgID       int64    `json:"orgID"`
+	ProjectID   int64    `json:"projectID"`
+}
+
+// bindRoleRequest represents the payload for granting a role to a user,
+// scoped like createRoleRequest.
+type bindRoleRequest struct {
+	UserID    int64  `json:"userID"`
+	Role      string `json:"role"`
+	OrgID     int64  `json:"orgID"`
+	ProjectID int64  `json:"projectID"`
+}
+
+// RegisterRoles sets up role and role binding routes using authn, looking
+// projects up in tenants.
+func RegisterRoles(app *fiber.App, authn *auth.Authenticator, tenants *tenant.Service) {
+	api := app.Group("/api/v1")
+
+	// @Summary Create role
+	// @Description Define a role; needs roles:manage and every permission it grants
+	// @Tags roles
+	// @Accept json
+	// @Produce json
+	// @Param data body createRoleRequest true "role"
+	// @Success 200 {object} db.Role
+	// @Failure 400 {object} map[string]string
+	// @Failure 403 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Router /api/v1/roles [post]
+	api.Post("/roles", func(c *fiber.Ctx) error {
+		var req createRoleRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid json"})
+		}
+		r := db.Role{Name: req.Name, Permissions: req.Permissions, OrgID: req.OrgID, ProjectID: req.ProjectID}
+		if req.ProjectID != 0 {
+			orgID, err := projectOrg(c.Context(), tenants, req.ProjectID)
+			if errors.Is(err, db.ErrNotFound) {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "unknown project"})
+			}
+			if err != nil {
+				return roleError(c, err)
+			}
+			r.OrgID = orgID
+		}
+		r, err := authn.CreateRole(c.Context(), r)
+		if err != nil {
+			return roleError(c, err)
+		}
+		return c.JSON(r)
+	})
+
+	// @Summary List roles
+	// @Description Built-in roles and the roles defined in a project, the caller's by default
+	// @Tags roles
+	// @Produce json
+	// @Param projectID query int false "project to list roles of; operators only"
+	// @Success 200 {object} map[string][]db.Role
+	// @Failure 400 {object} map[string]string
+	// @Failure 403 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Router /api/v1/roles [get]
+	api.Get("/roles", func(c *fiber.Ctx) error {
+		p := auth.FromContext(c.Context())
+		var orgID, projectID int64
+		if p != nil {
+			orgID, projectID = p.OrgID, p.ProjectID
+		}
+		if v := c.Query("projectID"); v != "" {
+			var err error
+			if projectID, err = strconv.ParseInt(v, 10, 64); err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid projectID"})
+			}
+			if orgID, err = projectOrg(c.Context(), tenants, projectID); err != nil {
+				return roleError(c, err)
+			}
+		}
+		roles, err := authn.ListRoles(c.Context(), orgID, projectID)
+		if err != nil {
+			return roleError(c, err)
+		}
+		return c.JSON(fiber.Map{"roles": roles})
+	})
+
+	// @Summary Delete role
+	// @Description Delete a role and the bindings granting it
+	// @Tags roles
+	// @Param id path int true "Role ID"
+	// @Success 204
+	// @Failure 400 {object} map[string]string
+	// @Failure 403 {object} map[string]string
+	// @Failure 404 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Router /api/v1/roles/{id} [delete]
+	api.Delete("/roles/:id", func(c *fiber.Ctx) error {
+		id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+		}
+		if err := authn.DeleteRole(c.Context(), id); err != nil {
+			return roleError(c, err)
+		}
+		return c.Status(http.StatusNoContent).Send(nil)
+	})
+
+	// @Summary Bind role
+	// @Description Grant a role to a user; needs roles:manage and every permission the role grants
+	// @Tags roles
+	// @Accept json
+	// @Produce json
+	// @Param data body bindRoleRequest true "binding"
+	// @Success 200 {object} db.RoleBinding
+	// @Failure 400 {object} map[string]string
+	// @Failure 403 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Router /api/v1/role-bindings [post]
+	api.Post("/role-bindings", func(c *fiber.Ctx) error {
+		var req bindRoleRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid json"})
+		}
+		b := db.RoleBinding{UserID: req.UserID, Role: req.Role, OrgID: req.OrgID, ProjectID: req.ProjectID}
+		if req.ProjectID != 0 {
+			orgID, err := projectOrg(c.Context(), tenants, req.ProjectID)
+			if errors.Is(err, db.ErrNotFound) {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "unknown project"})
+			}
+			if err != nil {
+				return roleError(c, err)
+			}
+			b.OrgID = orgID
+		}
+		b, err := authn.Bind(c.Context(), b)
+		if err != nil {
+			return roleError(c, err)
+		}
+		return c.JSON(b)
+	})
+
+	// @Summary List role bindings
+	// @Description The caller's role bindings, or a user's with roles:manage
+	// @Tags roles
+	// @Produce json
+	// @Param userID query int false "user to list bindings of"
+	// @Success 200 {object} map[string][]db.RoleBinding
+	// @Failure 400 {object} map[string]string
+	// @Failure 403 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Router /api/v1/role-bindings [get]
+	api.Get("/role-bindings", func(c *fiber.Ctx) error {
+		var userID int64
+		if v := c.Query("userID"); v != "" {
+			var err error
+			if userID, err = strconv.ParseInt(v, 10, 64); err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid userID"})
+			}
+		}
+		bindings, err := authn.ListBindings(c.Context(), userID)
+		if err != nil {
+			return roleError(c, err)
+		}
+		return c.JSON(fiber.Map{"bindings": bindings})
+	})
+
+	// @Summary Delete role binding
+	// @Tags roles
+	// @Param id path int true "Binding ID"
+	// @Success 204
+	// @Failure 400 {object} map[string]string
+	// @Failure 403 {object} map[string]string
+	// @Failure 404 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Router /api/v1/role-bindings/{id} [delete]
+	api.Delete("/role-bindings/:id", func(c *fiber.Ctx) error {
+		id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+		}
+		if err := authn.Unbind(c.Context(), id); err != nil {
+			return roleError(c, err)
+		}
+		return c.Status(http.StatusNoContent).Send(nil)
+	})
+}
+
+// roleError maps role management errors to a status code.
+func roleError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, auth.ErrForbidden):
+		return forbidden(c, err)
+	case errors.Is(err, auth.ErrInvalidRole), errors.Is(err, auth.ErrUserRequired):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, db.ErrNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "not found"})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+}
@@ -0,0 +1,161 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"mem0-go/internal/auth"
+	"mem0-go/internal/db"
+	"mem0-go/internal/tenant"
+)
+
+// nameRequest represents the payload for creating an organization or
+// project.
+type nameRequest struct {
+	Name string `json:"name"`
+}
+
+// RegisterTenants sets up organization and project routes using tenants.
+func RegisterTenants(app *fiber.App, tenants *tenant.Service) {
+	api := app.Group("/api/v1")
+
+	// @Summary Create organization
+	// @Description Create an organization; operators only
+	// @Tags tenants
+	// @Accept json
+	// @Produce json
+	// @Param data body nameRequest true "organization"
+	// @Success 200 {object} db.Organization
+	// @Failure 400 {object} map[string]string
+	// @Failure 403 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Router /api/v1/orgs [post]
+	api.Post("/orgs", func(c *fiber.Ctx) error {
+		var req nameRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid json"})
+		}
+		o, err := tenants.CreateOrganization(c.Context(), req.Name)
+		if err != nil {
+			return tenantError(c, err)
+		}
+		return c.JSON(o)
+	})
+
+	// @Summary List organizations
+	// @Description Organizations the caller may see
+	// @Tags tenants
+	// @Produce json
+	// @Success 200 {object} map[string][]db.Organization
+	// @Failure 500 {object} map[string]string
+	// @Router /api/v1/orgs [get]
+	api.Get("/orgs", func(c *fiber.Ctx) error {
+		orgs, err := tenants.Organizations(c.Context())
+		if err != nil {
+			return tenantError(c, err)
+		}
+		return c.JSON(fiber.Map{"organizations": orgs})
+	})
+
+	// @Summary Create project
+	// @Description Create a project in an organization; operators only
+	// @Tags tenants
+	// @Accept json
+	// @Produce json
+	// @Param id path int true "Organization ID"
+	// @Param data body nameRequest true "project"
+	// @Success 200 {object} db.Project
+	// @Failure 400 {object} map[string]string
+	// @Failure 403 {object} map[string]string
+	// @Failure 404 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Router /api/v1/orgs/{id}/projects [post]
+	api.Post("/orgs/:id/projects", func(c *fiber.Ctx) error {
+		orgID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+		}
+		var req nameRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid json"})
+		}
+		p, err := tenants.CreateProject(c.Context(), orgID, req.Name)
+		if err != nil {
+			return tenantError(c, err)
+		}
+		return c.JSON(p)
+	})
+
+	// @Summary List projects
+	// @Description Projects of an organization the caller may see
+	// @Tags tenants
+	// @Produce json
+	// @Param id path int true "Organization ID"
+	// @Success 200 {object} map[string][]db.Project
+	// @Failure 400 {object} map[string]string
+	// @Failure 404 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Router /api/v1/orgs/{id}/projects [get]
+	api.Get("/orgs/:id/projects", func(c *fiber.Ctx) error {
+		orgID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+		}
+		projects, err := tenants.Projects(c.Context(), orgID)
+		if err != nil {
+			return tenantError(c, err)
+		}
+		return c.JSON(fiber.Map{"projects": projects})
+	})
+
+	// @Summary Get project
+	// @Tags tenants
+	// @Produce json
+	// @Param id path int true "Project ID"
+	// @Success 200 {object} db.Project
+	// @Failure 400 {object} map[string]string
+	// @Failure 404 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Router /api/v1/projects/{id} [get]
+	api.Get("/projects/:id", func(c *fiber.Ctx) error {
+		id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+		}
+		p, err := tenants.Project(c.Context(), id)
+		if err != nil {
+			return tenantError(c, err)
+		}
+		return c.JSON(p)
+	})
+}
+
+// tenantError maps tenant service errors to a status code.
+func tenantError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, auth.ErrForbidden):
+		return forbidden(c, err)
+	case errors.Is(err, db.ErrNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "not found"})
+	case errors.Is(err, tenant.ErrNameRequired):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+}
+
+// projectOrg returns the organization of project projectID, or 0 for the
+// default project. Projects the caller may not see are db.ErrNotFound.
+func projectOrg(ctx context.Context, tenants *tenant.Service, projectID int64) (int64, error) {
+	if projectID == 0 {
+		return 0, nil
+	}
+	p, err := tenants.Project(ctx, projectID)
+	if err != nil {
+		return 0, err
+	}
+	return p.OrgID, nil
+}
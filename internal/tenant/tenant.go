@@ -0,0 +1,116 @@
+// Package tenant manages the organizations and projects memories are
+// partitioned into.
+package tenant
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"mem0-go/internal/auth"
+	"mem0-go/internal/db"
+)
+
+// ErrNameRequired is returned when an organization or project has no
+// name.
+var ErrNameRequired = errors.New("tenant: name required")
+
+// Service creates and looks up organizations and projects. Operators see
+// and create all of them; other callers only see their own organization
+// and project.
+type Service struct {
+	store db.Tenants
+}
+
+// NewService returns a Service storing tenants in store.
+func NewService(store db.Tenants) *Service { return &Service{store: store} }
+
+// CreateOrganization creates an organization. Only operators may.
+func (s *Service) CreateOrganization(ctx context.Context, name string) (db.Organization, error) {
+	if !auth.FromContext(ctx).Operator() {
+		return db.Organization{}, auth.ErrForbidden
+	}
+	if name = strings.TrimSpace(name); name == "" {
+		return db.Organization{}, ErrNameRequired
+	}
+	id, err := s.store.CreateOrganization(ctx, name)
+	if err != nil {
+		return db.Organization{}, err
+	}
+	return s.store.GetOrganization(ctx, id)
+}
+
+// Organization returns organization id, failing with db.ErrNotFound when
+// the caller may not see it.
+func (s *Service) Organization(ctx context.Context, id int64) (db.Organization, error) {
+	if p := auth.FromContext(ctx); !p.Operator() && p.OrgID != id {
+		return db.Organization{}, db.ErrNotFound
+	}
+	return s.store.GetOrganization(ctx, id)
+}
+
+// Organizations lists the organizations the caller may see.
+func (s *Service) Organizations(ctx context.Context) ([]db.Organization, error) {
+	p := auth.FromContext(ctx)
+	if p.Operator() {
+		return s.store.ListOrganizations(ctx)
+	}
+	o, err := s.store.GetOrganization(ctx, p.OrgID)
+	if errors.Is(err, db.ErrNotFound) {
+		return []db.Organization{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []db.Organization{o}, nil
+}
+
+// CreateProject creates a project in organization orgID. Only operators
+// may.
+func (s *Service) CreateProject(ctx context.Context, orgID int64, name string) (db.Project, error) {
+	if !auth.FromContext(ctx).Operator() {
+		return db.Project{}, auth.ErrForbidden
+	}
+	if name = strings.TrimSpace(name); name == "" {
+		return db.Project{}, ErrNameRequired
+	}
+	if _, err := s.store.GetOrganization(ctx, orgID); err != nil {
+		return db.Project{}, err
+	}
+	id, err := s.store.CreateProject(ctx, db.Project{OrgID: orgID, Name: name})
+	if err != nil {
+		return db.Project{}, err
+	}
+	return s.store.GetProject(ctx, id)
+}
+
+// Project returns project id, failing with db.ErrNotFound when the caller
+// may not see it.
+func (s *Service) Project(ctx context.Context, id int64) (db.Project, error) {
+	if p := auth.FromContext(ctx); !p.Operator() && p.ProjectID != id {
+		return db.Project{}, db.ErrNotFound
+	}
+	return s.store.GetProject(ctx, id)
+}
+
+// Projects lists the projects of organization orgID the caller may see.
+func (s *Service) Projects(ctx context.Context, orgID int64) ([]db.Project, error) {
+	if _, err := s.Organization(ctx, orgID); err != nil {
+		return nil, err
+	}
+	all, err := s.store.ListProjects(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	p := auth.FromContext(ctx)
+	if p.Operator() {
+		return all, nil
+	}
+	out := []db.Project{}
+	for _, pr := range all {
+		if pr.ID == p.ProjectID {
+			out = append(out, pr)
+		}
+	}
+	return out, nil
+}
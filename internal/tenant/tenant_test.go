@@ -0,0 +1,49 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"mem0-go/internal/auth"
+	"mem0-go/internal/db"
+	"mem0-go/internal/inmem"
+)
+
+func TestOperatorsManageTenants(t *testing.T) {
+	svc := NewService(inmem.NewRepo())
+	operator := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "admin", Scopes: []string{auth.ScopeAdmin}})
+
+	org, err := svc.CreateOrganization(operator, "acme")
+	if err != nil || org.ID == 0 || org.Name != "acme" {
+		t.Fatalf("create org: %+v %v", org, err)
+	}
+	if _, err := svc.CreateOrganization(operator, " "); !errors.Is(err, ErrNameRequired) {
+		t.Fatalf("expected ErrNameRequired, got %v", err)
+	}
+	if _, err := svc.CreateProject(operator, 99, "x"); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for a missing organization, got %v", err)
+	}
+	a, err := svc.CreateProject(operator, org.ID, "a")
+	if err != nil || a.OrgID != org.ID {
+		t.Fatalf("create project: %+v %v", a, err)
+	}
+	if _, err := svc.CreateProject(operator, org.ID, "b"); err != nil {
+		t.Fatalf("create project: %v", err)
+	}
+
+	// An admin inside project a administers only that project.
+	member := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: 1, Scopes: []string{auth.ScopeAdmin}, OrgID: org.ID, ProjectID: a.ID})
+	if _, err := svc.CreateProject(member, org.ID, "c"); !errors.Is(err, auth.ErrForbidden) {
+		t.Fatalf("expected ErrForbidden, got %v", err)
+	}
+	if ps, err := svc.Projects(member, org.ID); err != nil || len(ps) != 1 || ps[0].ID != a.ID {
+		t.Fatalf("member projects: %+v %v", ps, err)
+	}
+	if _, err := svc.Project(member, a.ID+1); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for another project, got %v", err)
+	}
+	if ps, err := svc.Projects(operator, org.ID); err != nil || len(ps) != 2 {
+		t.Fatalf("operator projects: %+v %v", ps, err)
+	}
+}
@@ -0,0 +1,51 @@
+package vector
+
+import "math"
+
+// Distance is a vector similarity metric, named as in the Qdrant API.
+type Distance string
+
+const (
+	Cosine Distance = "Cosine"
+	Dot    Distance = "Dot"
+	Euclid Distance = "Euclid"
+)
+
+// Score compares a and b under d. For Cosine and Dot higher scores are
+// closer; for Euclid the score is the distance, so lower is closer. The
+// vectors must have the same length.
+func (d Distance) Score(a, b []float32) float32 {
+	switch d {
+	case Dot:
+		return dot(a, b)
+	case Euclid:
+		var sum float64
+		for i := range a {
+			diff := float64(a[i]) - float64(b[i])
+			sum += diff * diff
+		}
+		return float32(math.Sqrt(sum))
+	default:
+		na, nb := dot(a, a), dot(b, b)
+		if na == 0 || nb == 0 {
+			return 0
+		}
+		return dot(a, b) / float32(math.Sqrt(float64(na))*math.Sqrt(float64(nb)))
+	}
+}
+
+// Better reports whether score a ranks ahead of score b under d.
+func (d Distance) Better(a, b float32) bool {
+	if d == Euclid {
+		return a < b
+	}
+	return a > b
+}
+
+func dot(a, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
@@ -0,0 +1,232 @@
+package vector
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Filter narrows a search to points whose payload matches. It mirrors
+// Qdrant's filter syntax so it can be sent as-is: every Must condition has
+// to hold, at least one Should condition (when any are given) and no
+// MustNot condition.
+type Filter struct {
+	Must    []Condition `json:"must,omitempty"`
+	Should  []Condition `json:"should,omitempty"`
+	MustNot []Condition `json:"must_not,omitempty"`
+}
+
+// Condition tests a single payload field, or nests another Filter when
+// Key is empty. Keys may address nested objects with dots, e.g.
+// "metadata.source".
+type Condition struct {
+	Key   string `json:"key,omitempty"`
+	Match *Match `json:"match,omitempty"`
+	Range *Range `json:"range,omitempty"`
+	*Filter
+}
+
+// Match compares a field with a single value, or with any of a set of
+// values when Any is non-nil.
+type Match struct {
+	Value interface{}
+	Any   []interface{}
+}
+
+// Range bounds a numeric field. Nil bounds are ignored.
+type Range struct {
+	GT  *float64 `json:"gt,omitempty"`
+	GTE *float64 `json:"gte,omitempty"`
+	LT  *float64 `json:"lt,omitempty"`
+	LTE *float64 `json:"lte,omitempty"`
+}
+
+// MatchValue returns a condition requiring key to equal value.
+func MatchValue(key string, value interface{}) Condition {
+	return Condition{Key: key, Match: &Match{Value: value}}
+}
+
+// MatchAny returns a condition requiring key to equal one of values.
+func MatchAny(key string, values ...interface{}) Condition {
+	return Condition{Key: key, Match: &Match{Any: values}}
+}
+
+// InRange returns a condition bounding key by r.
+func InRange(key string, r Range) Condition {
+	return Condition{Key: key, Range: &r}
+}
+
+// And returns a filter requiring every condition of f plus conds. f may be
+// nil.
+func (f *Filter) And(conds ...Condition) *Filter {
+	if len(conds) == 0 {
+		return f
+	}
+	out := &Filter{}
+	if f != nil {
+		*out = *f
+	}
+	out.Must = append(append([]Condition{}, out.Must...), conds...)
+	return out
+}
+
+// MarshalJSON encodes m as {"value": v} or {"any": [...]}.
+func (m Match) MarshalJSON() ([]byte, error) {
+	if m.Any != nil {
+		return json.Marshal(map[string]interface{}{"any": m.Any})
+	}
+	return json.Marshal(map[string]interface{}{"value": m.Value})
+}
+
+// UnmarshalJSON decodes the Qdrant match forms.
+func (m *Match) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		Value interface{}   `json:"value"`
+		Any   []interface{} `json:"any"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	m.Value, m.Any = raw.Value, raw.Any
+	return nil
+}
+
+// Matches evaluates f against payload the way Qdrant does. A nil filter
+// matches everything.
+func (f *Filter) Matches(payload map[string]interface{}) bool {
+	if f == nil {
+		return true
+	}
+	for _, c := range f.Must {
+		if !c.matches(payload) {
+			return false
+		}
+	}
+	for _, c := range f.MustNot {
+		if c.matches(payload) {
+			return false
+		}
+	}
+	if len(f.Should) == 0 {
+		return true
+	}
+	for _, c := range f.Should {
+		if c.matches(payload) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c Condition) matches(payload map[string]interface{}) bool {
+	if c.Key == "" {
+		return c.Filter.Matches(payload)
+	}
+	v, ok := lookup(payload, c.Key)
+	if !ok {
+		return false
+	}
+	// array fields match when any element does
+	vals, isArr := v.([]interface{})
+	if !isArr {
+		if ss, ok := v.([]string); ok {
+			for _, s := range ss {
+				vals = append(vals, s)
+			}
+			isArr = true
+		}
+	}
+	if !isArr {
+		vals = []interface{}{v}
+	}
+	for _, x := range vals {
+		if c.matchValue(x) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c Condition) matchValue(v interface{}) bool {
+	if c.Match != nil {
+		if c.Match.Any != nil {
+			found := false
+			for _, want := range c.Match.Any {
+				if equalValues(v, want) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		} else if !equalValues(v, c.Match.Value) {
+			return false
+		}
+	}
+	if c.Range != nil {
+		n, ok := number(v)
+		if !ok {
+			return false
+		}
+		r := c.Range
+		if (r.GT != nil && !(n > *r.GT)) || (r.GTE != nil && !(n >= *r.GTE)) ||
+			(r.LT != nil && !(n < *r.LT)) || (r.LTE != nil && !(n <= *r.LTE)) {
+			return false
+		}
+	}
+	return true
+}
+
+// lookup resolves a dotted key in payload.
+func lookup(payload map[string]interface{}, key string) (interface{}, bool) {
+	var cur interface{} = payload
+	for _, part := range strings.Split(key, ".") {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// equalValues compares payload values, treating all numeric types alike
+// since payloads may hold Go integers or JSON-decoded float64s.
+func equalValues(a, b interface{}) bool {
+	if na, ok := number(a); ok {
+		nb, ok := number(b)
+		return ok && na == nb
+	}
+	switch x := a.(type) {
+	case string:
+		y, ok := b.(string)
+		return ok && x == y
+	case bool:
+		y, ok := b.(bool)
+		return ok && x == y
+	}
+	return false
+}
+
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
@@ -4,71 +4,373 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
+	"sort"
+	"strconv"
+	"sync"
 )
 
 // Config holds Qdrant connection settings.
 type Config struct {
+	// Host is the Qdrant hostname.
+	Host string
 	// Port is the HTTP port Qdrant listens on.
 	Port string
+	// Distance is the metric used to compare vectors.
+	Distance Distance
+	// HNSWM and HNSWEfConstruct tune the collection's HNSW index; zero
+	// keeps Qdrant's defaults.
+	HNSWM           int
+	HNSWEfConstruct int
+	// IndexingThreshold is the segment size in KB above which Qdrant builds
+	// the HNSW index; zero keeps Qdrant's default.
+	IndexingThreshold int
 }
 
 // LoadConfig reads settings from environment variables with fallbacks.
 func LoadConfig() Config {
+	host := os.Getenv("QDRANT_HOST")
+	if host == "" {
+		host = "localhost"
+	}
 	port := os.Getenv("QDRANT_PORT")
 	if port == "" {
 		port = "6333"
 	}
-	return Config{Port: port}
+	dist := Distance(os.Getenv("MEM0_VECTOR_DISTANCE"))
+	if dist == "" {
+		dist = Cosine
+	}
+	return Config{
+		Host:              host,
+		Port:              port,
+		Distance:          dist,
+		HNSWM:             getint("QDRANT_HNSW_M"),
+		HNSWEfConstruct:   getint("QDRANT_HNSW_EF_CONSTRUCT"),
+		IndexingThreshold: getint("QDRANT_INDEXING_THRESHOLD"),
+	}
+}
+
+func getint(key string) int {
+	v, _ := strconv.Atoi(os.Getenv(key))
+	return v
+}
+
+// CollectionConfig returns the collection settings described by c for
+// vectors of the given size.
+func (c Config) CollectionConfig(size int) CollectionConfig {
+	cc := CollectionConfig{Size: size, Distance: c.Distance}
+	if c.HNSWM > 0 || c.HNSWEfConstruct > 0 {
+		cc.HNSW = &HNSWConfig{M: c.HNSWM, EfConstruct: c.HNSWEfConstruct}
+	}
+	if c.IndexingThreshold > 0 {
+		cc.Optimizers = &OptimizersConfig{IndexingThreshold: c.IndexingThreshold}
+	}
+	return cc
 }
 
 // Client provides helpers for interacting with Qdrant.
 type Client struct {
 	baseURL    string
 	httpClient *http.Client
+
+	mu   sync.Mutex
+	dims map[string]int
 }
 
 // Connect initializes a client using the given config.
 func Connect(_ context.Context, cfg Config) (*Client, error) {
+	host := cfg.Host
+	if host == "" {
+		host = "localhost"
+	}
 	return &Client{
-		baseURL:    fmt.Sprintf("http://localhost:%s", cfg.Port),
+		baseURL:    fmt.Sprintf("http://%s:%s", host, cfg.Port),
 		httpClient: &http.Client{},
 	}, nil
 }
 
-// Point represents a single vector with optional payload.
-type Point struct {
-	ID      string                 `json:"id"`
-	Vector  []float32              `json:"vector"`
-	Payload map[string]interface{} `json:"payload,omitempty"`
+// ErrCollectionNotFound is returned when a collection does not exist.
+var ErrCollectionNotFound = errors.New("qdrant: collection not found")
+
+// DimensionError reports a vector whose length does not match the
+// collection it is written to or searched in.
+type DimensionError struct {
+	Collection string
+	ID         string
+	Got, Want  int
 }
 
-// Upsert writes points to the given collection.
-func (c *Client) Upsert(ctx context.Context, collection string, pts []Point) error {
-	body, err := json.Marshal(struct {
-		Points []Point `json:"points"`
-	}{pts})
-	if err != nil {
-		return err
+func (e *DimensionError) Error() string {
+	if e.ID == "" {
+		return fmt.Sprintf("vector dimension %d does not match collection %q dimension %d", e.Got, e.Collection, e.Want)
 	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodPut,
-		fmt.Sprintf("%s/collections/%s/points?wait=true", c.baseURL, collection), bytes.NewReader(body))
+	return fmt.Sprintf("point %s: vector dimension %d does not match collection %q dimension %d", e.ID, e.Got, e.Collection, e.Want)
+}
+
+// statusError is returned for non-2xx Qdrant responses.
+type statusError struct {
+	code int
+	msg  string
+}
+
+func (e *statusError) Error() string {
+	if e.msg == "" {
+		return fmt.Sprintf("qdrant status %d", e.code)
+	}
+	return fmt.Sprintf("qdrant status %d: %s", e.code, e.msg)
+}
+
+// do sends a JSON request to Qdrant and decodes the "result" field of the
+// response into out when out is non-nil.
+func (c *Client) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
 	if err != nil {
 		return err
 	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return err
 	}
 	defer func() { _ = resp.Body.Close() }()
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("qdrant status %d", resp.StatusCode)
+		var e struct {
+			Status struct {
+				Error string `json:"error"`
+			} `json:"status"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&e)
+		return &statusError{code: resp.StatusCode, msg: e.Status.Error}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(&struct {
+		Result interface{} `json:"result"`
+	}{out})
+}
+
+// collectionError turns a 404 for a collection into ErrCollectionNotFound.
+func collectionError(collection string, err error) error {
+	var se *statusError
+	if errors.As(err, &se) && se.code == http.StatusNotFound {
+		return fmt.Errorf("%w: %q", ErrCollectionNotFound, collection)
+	}
+	return err
+}
+
+// HNSWConfig tunes a collection's HNSW index.
+type HNSWConfig struct {
+	M                 int `json:"m,omitempty"`
+	EfConstruct       int `json:"ef_construct,omitempty"`
+	FullScanThreshold int `json:"full_scan_threshold,omitempty"`
+}
+
+// OptimizersConfig tunes a collection's segment optimizers.
+type OptimizersConfig struct {
+	IndexingThreshold    int `json:"indexing_threshold,omitempty"`
+	DefaultSegmentNumber int `json:"default_segment_number,omitempty"`
+}
+
+// CollectionConfig describes a collection to create.
+type CollectionConfig struct {
+	Size       int
+	Distance   Distance
+	HNSW       *HNSWConfig
+	Optimizers *OptimizersConfig
+}
+
+// CollectionInfo describes an existing collection.
+type CollectionInfo struct {
+	Name        string
+	Size        int
+	Distance    Distance
+	Status      string
+	PointsCount int
+}
+
+// CreateCollection creates a collection with the given settings.
+func (c *Client) CreateCollection(ctx context.Context, name string, cfg CollectionConfig) error {
+	if cfg.Distance == "" {
+		cfg.Distance = Cosine
+	}
+	body := map[string]interface{}{
+		"vectors": map[string]interface{}{"size": cfg.Size, "distance": cfg.Distance},
+	}
+	if cfg.HNSW != nil {
+		body["hnsw_config"] = cfg.HNSW
+	}
+	if cfg.Optimizers != nil {
+		body["optimizers_config"] = cfg.Optimizers
+	}
+	if err := c.do(ctx, http.MethodPut, "/collections/"+name, body, nil); err != nil {
+		return err
+	}
+	c.setDim(name, cfg.Size)
+	return nil
+}
+
+// GetCollection describes a collection. It returns an error wrapping
+// ErrCollectionNotFound when the collection does not exist.
+func (c *Client) GetCollection(ctx context.Context, name string) (CollectionInfo, error) {
+	var res struct {
+		Status      string `json:"status"`
+		PointsCount int    `json:"points_count"`
+		Config      struct {
+			Params struct {
+				Vectors struct {
+					Size     int      `json:"size"`
+					Distance Distance `json:"distance"`
+				} `json:"vectors"`
+			} `json:"params"`
+		} `json:"config"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/collections/"+name, nil, &res); err != nil {
+		return CollectionInfo{}, collectionError(name, err)
+	}
+	info := CollectionInfo{
+		Name:        name,
+		Size:        res.Config.Params.Vectors.Size,
+		Distance:    res.Config.Params.Vectors.Distance,
+		Status:      res.Status,
+		PointsCount: res.PointsCount,
+	}
+	c.setDim(name, info.Size)
+	return info, nil
+}
+
+// DeleteCollection removes a collection and all its points.
+func (c *Client) DeleteCollection(ctx context.Context, name string) error {
+	if err := c.do(ctx, http.MethodDelete, "/collections/"+name, nil, nil); err != nil {
+		return collectionError(name, err)
+	}
+	c.mu.Lock()
+	delete(c.dims, name)
+	c.mu.Unlock()
+	return nil
+}
+
+// EnsureCollection creates the collection if it is missing and otherwise
+// checks that its vector size and distance match cfg.
+func (c *Client) EnsureCollection(ctx context.Context, name string, cfg CollectionConfig) error {
+	info, err := c.GetCollection(ctx, name)
+	if errors.Is(err, ErrCollectionNotFound) {
+		return c.CreateCollection(ctx, name, cfg)
+	}
+	if err != nil {
+		return err
+	}
+	if info.Size != cfg.Size {
+		return &DimensionError{Collection: name, Got: cfg.Size, Want: info.Size}
+	}
+	if cfg.Distance != "" && info.Distance != cfg.Distance {
+		return fmt.Errorf("qdrant: collection %q uses distance %s, want %s", name, info.Distance, cfg.Distance)
+	}
+	return nil
+}
+
+func (c *Client) setDim(name string, size int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.dims == nil {
+		c.dims = make(map[string]int)
+	}
+	c.dims[name] = size
+}
+
+// checkDim validates vec against the collection's known dimension. Unknown
+// collections are not checked here; Qdrant rejects mismatches itself.
+func (c *Client) checkDim(collection, id string, vec []float32) error {
+	c.mu.Lock()
+	want, ok := c.dims[collection]
+	c.mu.Unlock()
+	if ok && len(vec) != want {
+		return &DimensionError{Collection: collection, ID: id, Got: len(vec), Want: want}
 	}
 	return nil
 }
 
+// Point represents a single vector with optional payload.
+type Point struct {
+	ID      string                 `json:"id"`
+	Vector  []float32              `json:"vector"`
+	Payload map[string]interface{} `json:"payload,omitempty"`
+}
+
+// pointID is a point ID on the wire. Qdrant only accepts unsigned integers
+// and UUIDs, so numeric IDs are sent as numbers; both forms are accepted
+// when decoding.
+type pointID string
+
+func (id pointID) MarshalJSON() ([]byte, error) {
+	if _, err := strconv.ParseUint(string(id), 10, 64); err == nil {
+		return []byte(id), nil
+	}
+	return json.Marshal(string(id))
+}
+
+func (id *pointID) UnmarshalJSON(b []byte) error {
+	var n json.Number
+	if err := json.Unmarshal(b, &n); err == nil {
+		*id = pointID(n)
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	*id = pointID(s)
+	return nil
+}
+
+type wirePoint struct {
+	ID      pointID                `json:"id"`
+	Vector  []float32              `json:"vector"`
+	Payload map[string]interface{} `json:"payload,omitempty"`
+}
+
+// Upsert writes points to the given collection.
+func (c *Client) Upsert(ctx context.Context, collection string, pts []Point) error {
+	wire := make([]wirePoint, len(pts))
+	for i, p := range pts {
+		if err := c.checkDim(collection, p.ID, p.Vector); err != nil {
+			return err
+		}
+		wire[i] = wirePoint{ID: pointID(p.ID), Vector: p.Vector, Payload: p.Payload}
+	}
+	err := c.do(ctx, http.MethodPut, "/collections/"+collection+"/points?wait=true", struct {
+		Points []wirePoint `json:"points"`
+	}{wire}, nil)
+	return collectionError(collection, err)
+}
+
+// Delete removes points by ID from the given collection.
+func (c *Client) Delete(ctx context.Context, collection string, ids []string) error {
+	wire := make([]pointID, len(ids))
+	for i, id := range ids {
+		wire[i] = pointID(id)
+	}
+	err := c.do(ctx, http.MethodPost, "/collections/"+collection+"/points/delete?wait=true", struct {
+		Points []pointID `json:"points"`
+	}{wire}, nil)
+	return collectionError(collection, err)
+}
+
 // QueryResult is a single vector search match.
 type QueryResult struct {
 	ID      string                 `json:"id"`
@@ -76,33 +378,91 @@ type QueryResult struct {
 	Payload map[string]interface{} `json:"payload,omitempty"`
 }
 
-// Query searches for similar vectors in a collection.
-func (c *Client) Query(ctx context.Context, collection string, vector []float32, limit int) ([]QueryResult, error) {
-	body, err := json.Marshal(struct {
-		Vector []float32 `json:"vector"`
-		Limit  int       `json:"limit"`
-	}{vector, limit})
-	if err != nil {
+// Query searches for similar vectors in a collection, optionally
+// restricted to points whose payload matches filter.
+func (c *Client) Query(ctx context.Context, collection string, vector []float32, limit int, filter *Filter) ([]QueryResult, error) {
+	if err := c.checkDim(collection, "", vector); err != nil {
 		return nil, err
 	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
-		fmt.Sprintf("%s/collections/%s/points/search", c.baseURL, collection), bytes.NewReader(body))
-	if err != nil {
-		return nil, err
+	var res []struct {
+		ID      pointID                `json:"id"`
+		Score   float32                `json:"score"`
+		Payload map[string]interface{} `json:"payload"`
 	}
-	resp, err := c.httpClient.Do(req)
+	err := c.do(ctx, http.MethodPost, "/collections/"+collection+"/points/search", struct {
+		Vector      []float32 `json:"vector"`
+		Limit       int       `json:"limit"`
+		Filter      *Filter   `json:"filter,omitempty"`
+		WithPayload bool      `json:"with_payload"`
+	}{vector, limit, filter, true}, &res)
 	if err != nil {
-		return nil, err
+		return nil, collectionError(collection, err)
 	}
-	defer func() { _ = resp.Body.Close() }()
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("qdrant status %d", resp.StatusCode)
+	out := make([]QueryResult, len(res))
+	for i, r := range res {
+		out[i] = QueryResult{ID: string(r.ID), Score: r.Score, Payload: r.Payload}
+	}
+	return out, nil
+}
+
+// Scroll returns up to limit points of a collection with their vectors and
+// payloads, in ID order starting at offset ("" for the first page). The
+// second result is the offset of the next page, or "" after the last.
+func (c *Client) Scroll(ctx context.Context, collection, offset string, limit int) ([]Point, string, error) {
+	req := struct {
+		Limit       int      `json:"limit"`
+		Offset      *pointID `json:"offset,omitempty"`
+		WithPayload bool     `json:"with_payload"`
+		WithVector  bool     `json:"with_vector"`
+	}{Limit: limit, WithPayload: true, WithVector: true}
+	if offset != "" {
+		id := pointID(offset)
+		req.Offset = &id
 	}
-	var out struct {
-		Result []QueryResult `json:"result"`
+	var res struct {
+		Points []wirePoint `json:"points"`
+		Next   *pointID    `json:"next_page_offset"`
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
-		return nil, err
+	if err := c.do(ctx, http.MethodPost, "/collections/"+collection+"/points/scroll", req, &res); err != nil {
+		return nil, "", collectionError(collection, err)
+	}
+	out := make([]Point, len(res.Points))
+	for i, p := range res.Points {
+		out[i] = Point{ID: string(p.ID), Vector: p.Vector, Payload: p.Payload}
+	}
+	var next string
+	if res.Next != nil {
+		next = string(*res.Next)
+	}
+	return out, next, nil
+}
+
+// PageIDs sorts ids the way Qdrant orders point IDs, numbers first, and
+// returns the page of up to limit IDs starting at offset together with the
+// offset of the next page. A limit <= 0 returns every remaining ID.
+func PageIDs(ids []string, offset string, limit int) ([]string, string) {
+	sort.Slice(ids, func(i, j int) bool { return lessID(ids[i], ids[j]) })
+	start := 0
+	if offset != "" {
+		start = sort.Search(len(ids), func(i int) bool { return !lessID(ids[i], offset) })
+	}
+	ids = ids[start:]
+	if limit <= 0 || len(ids) <= limit {
+		return ids, ""
+	}
+	return ids[:limit], ids[limit]
+}
+
+func lessID(a, b string) bool {
+	na, errA := strconv.ParseUint(a, 10, 64)
+	nb, errB := strconv.ParseUint(b, 10, 64)
+	switch {
+	case errA == nil && errB == nil:
+		return na < nb
+	case errA == nil:
+		return true
+	case errB == nil:
+		return false
 	}
-	return out.Result, nil
+	return a < b
 }
@@ -3,8 +3,11 @@ package vector
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -34,7 +37,7 @@ func TestUpsertAndQuery(t *testing.T) {
 	if err := c.Upsert(context.Background(), "test", []Point{{ID: "1", Vector: []float32{1, 2}}}); err != nil {
 		t.Fatalf("upsert: %v", err)
 	}
-	res, err := c.Query(context.Background(), "test", []float32{1, 2}, 1)
+	res, err := c.Query(context.Background(), "test", []float32{1, 2}, 1, nil)
 	if err != nil {
 		t.Fatalf("query: %v", err)
 	}
@@ -42,3 +45,254 @@ func TestUpsertAndQuery(t *testing.T) {
 		t.Fatalf("unexpected result: %#v", res)
 	}
 }
+
+func TestDelete(t *testing.T) {
+	var got []json.Number
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/collections/test/points/delete" {
+			t.Fatalf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+		var body struct {
+			Points []json.Number `json:"points"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		got = body.Points
+	}))
+	defer srv.Close()
+
+	c := &Client{baseURL: srv.URL, httpClient: srv.Client()}
+	if err := c.Delete(context.Background(), "test", []string{"1", "2"}); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	// numeric IDs go on the wire as numbers
+	if len(got) != 2 || got[0] != "1" {
+		t.Fatalf("unexpected ids: %v", got)
+	}
+}
+
+func TestDistanceScore(t *testing.T) {
+	a, b := []float32{1, 0}, []float32{3, 4}
+	if s := Cosine.Score(a, b); s < 0.599 || s > 0.601 {
+		t.Fatalf("cosine %f", s)
+	}
+	if s := Dot.Score(a, b); s != 3 {
+		t.Fatalf("dot %f", s)
+	}
+	if s := Euclid.Score(a, b); s < 4.472 || s > 4.473 {
+		t.Fatalf("euclid %f", s)
+	}
+	if Cosine.Score([]float32{0, 0}, b) != 0 {
+		t.Fatalf("zero vector should score 0")
+	}
+	if !Euclid.Better(1, 2) || Cosine.Better(1, 2) {
+		t.Fatalf("unexpected ordering")
+	}
+}
+
+// fakeQdrant serves the collection endpoints for a single collection.
+type fakeQdrant struct {
+	t       *testing.T
+	size    int
+	created map[string]interface{}
+}
+
+func (f *fakeQdrant) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	notFound := func() {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"status":{"error":"Not found: Collection ` + "`mem`" + ` doesn't exist!"}}`))
+	}
+	switch {
+	case r.URL.Path == "/collections/mem" && r.Method == http.MethodGet:
+		if f.size == 0 {
+			notFound()
+			return
+		}
+		_, _ = w.Write([]byte(`{"result":{"status":"green","points_count":3,"config":{"params":{"vectors":{"size":` +
+			strconv.Itoa(f.size) + `,"distance":"Cosine"}}}}}`))
+	case r.URL.Path == "/collections/mem" && r.Method == http.MethodPut:
+		if err := json.NewDecoder(r.Body).Decode(&f.created); err != nil {
+			f.t.Fatalf("decode: %v", err)
+		}
+		f.size = int(f.created["vectors"].(map[string]interface{})["size"].(float64))
+		_, _ = w.Write([]byte(`{"result":true}`))
+	case r.URL.Path == "/collections/mem" && r.Method == http.MethodDelete:
+		f.size = 0
+		_, _ = w.Write([]byte(`{"result":true}`))
+	case r.URL.Path == "/collections/mem/points":
+		if f.size == 0 {
+			notFound()
+			return
+		}
+		_, _ = w.Write([]byte(`{"result":{}}`))
+	default:
+		f.t.Fatalf("unexpected request %s %s", r.Method, r.URL.Path)
+	}
+}
+
+func TestCollectionLifecycle(t *testing.T) {
+	fake := &fakeQdrant{t: t}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	ctx := context.Background()
+	c := &Client{baseURL: srv.URL, httpClient: srv.Client()}
+
+	err := c.Upsert(ctx, "mem", []Point{{ID: "1", Vector: []float32{1, 2}}})
+	if !errors.Is(err, ErrCollectionNotFound) {
+		t.Fatalf("expected ErrCollectionNotFound, got %v", err)
+	}
+	if _, err := c.GetCollection(ctx, "mem"); !errors.Is(err, ErrCollectionNotFound) {
+		t.Fatalf("expected ErrCollectionNotFound, got %v", err)
+	}
+
+	cfg := Config{Distance: Cosine, HNSWM: 32, IndexingThreshold: 1000}.CollectionConfig(3)
+	if err := c.EnsureCollection(ctx, "mem", cfg); err != nil {
+		t.Fatalf("ensure: %v", err)
+	}
+	if fake.created["hnsw_config"].(map[string]interface{})["m"].(float64) != 32 {
+		t.Fatalf("hnsw config not sent: %v", fake.created)
+	}
+	if _, ok := fake.created["optimizers_config"]; !ok {
+		t.Fatalf("optimizers config not sent: %v", fake.created)
+	}
+	info, err := c.GetCollection(ctx, "mem")
+	if err != nil || info.Size != 3 || info.Distance != Cosine || info.PointsCount != 3 {
+		t.Fatalf("get: %v %+v", err, info)
+	}
+
+	var dimErr *DimensionError
+	err = c.Upsert(ctx, "mem", []Point{{ID: "1", Vector: []float32{1, 2}}})
+	if !errors.As(err, &dimErr) || dimErr.Got != 2 || dimErr.Want != 3 {
+		t.Fatalf("expected dimension error, got %v", err)
+	}
+	if _, err := c.Query(ctx, "mem", []float32{1}, 1, nil); !errors.As(err, &dimErr) {
+		t.Fatalf("expected dimension error, got %v", err)
+	}
+	if err := c.Upsert(ctx, "mem", []Point{{ID: "1", Vector: []float32{1, 2, 3}}}); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+
+	// existing collection with another size is reported, not recreated
+	if err := c.EnsureCollection(ctx, "mem", CollectionConfig{Size: 4}); !errors.As(err, &dimErr) {
+		t.Fatalf("expected dimension error, got %v", err)
+	}
+
+	if err := c.DeleteCollection(ctx, "mem"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	err = c.Upsert(ctx, "mem", []Point{{ID: "1", Vector: []float32{1, 2}}})
+	if !errors.Is(err, ErrCollectionNotFound) || !strings.Contains(err.Error(), `"mem"`) {
+		t.Fatalf("expected ErrCollectionNotFound, got %v", err)
+	}
+}
+
+func ptr(f float64) *float64 { return &f }
+
+func TestFilterJSON(t *testing.T) {
+	f := &Filter{
+		Must:    []Condition{MatchValue("user_id", 1), InRange("created_at", Range{GTE: ptr(10)})},
+		Should:  []Condition{MatchAny("tags", "a", "b")},
+		MustNot: []Condition{{Filter: &Filter{Must: []Condition{MatchValue("agent_id", false)}}}},
+	}
+	b, err := json.Marshal(f)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	want := `{"must":[{"key":"user_id","match":{"value":1}},{"key":"created_at","range":{"gte":10}}],` +
+		`"should":[{"key":"tags","match":{"any":["a","b"]}}],` +
+		`"must_not":[{"must":[{"key":"agent_id","match":{"value":false}}]}]}`
+	if string(b) != want {
+		t.Fatalf("unexpected json:\n%s\n%s", b, want)
+	}
+	var back Filter
+	if err := json.Unmarshal(b, &back); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if back.MustNot[0].Filter == nil || back.Should[0].Match.Any == nil || *back.Must[1].Range.GTE != 10 {
+		t.Fatalf("unexpected round trip: %+v", back)
+	}
+}
+
+func TestFilterMatches(t *testing.T) {
+	payload := map[string]interface{}{
+		"user_id":    int64(1),
+		"agent_id":   "planner",
+		"tags":       []string{"work", "urgent"},
+		"created_at": int64(100),
+		"metadata":   map[string]interface{}{"source": "chat", "score": 0.5},
+	}
+	cases := []struct {
+		name string
+		f    *Filter
+		want bool
+	}{
+		{"nil", nil, true},
+		{"match number", &Filter{Must: []Condition{MatchValue("user_id", 1.0)}}, true},
+		{"match other user", &Filter{Must: []Condition{MatchValue("user_id", 2)}}, false},
+		{"array element", &Filter{Must: []Condition{MatchValue("tags", "urgent")}}, true},
+		{"any", &Filter{Must: []Condition{MatchAny("agent_id", "x", "planner")}}, true},
+		{"range", &Filter{Must: []Condition{InRange("created_at", Range{GT: ptr(50), LTE: ptr(100)})}}, true},
+		{"range miss", &Filter{Must: []Condition{InRange("created_at", Range{LT: ptr(100)})}}, false},
+		{"nested key", &Filter{Must: []Condition{MatchValue("metadata.source", "chat")}}, true},
+		{"missing key", &Filter{Must: []Condition{MatchValue("run_id", "r")}}, false},
+		{"should none", &Filter{Should: []Condition{MatchValue("agent_id", "x"), MatchValue("tags", "home")}}, false},
+		{"should one", &Filter{Should: []Condition{MatchValue("agent_id", "x"), MatchValue("tags", "work")}}, true},
+		{"must not", &Filter{MustNot: []Condition{MatchValue("metadata.source", "chat")}}, false},
+		{"nested filter", &Filter{Must: []Condition{{Filter: &Filter{Should: []Condition{MatchValue("agent_id", "planner")}}}}}, true},
+		{"map value", &Filter{Must: []Condition{MatchValue("metadata", map[string]interface{}{})}}, false},
+	}
+	for _, c := range cases {
+		if got := c.f.Matches(payload); got != c.want {
+			t.Fatalf("%s: got %v want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestScroll(t *testing.T) {
+	var offsets []interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/collections/test/points/scroll" {
+			t.Fatalf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		offsets = append(offsets, body["offset"])
+		if body["offset"] == nil {
+			w.Write([]byte(`{"result":{"points":[{"id":1,"vector":[1,0],"payload":{"user_id":1}}],"next_page_offset":2}}`))
+			return
+		}
+		w.Write([]byte(`{"result":{"points":[{"id":2,"vector":[0,1]}],"next_page_offset":null}}`))
+	}))
+	defer srv.Close()
+
+	c := &Client{baseURL: srv.URL, httpClient: srv.Client()}
+	pts, next, err := c.Scroll(context.Background(), "test", "", 1)
+	if err != nil || next != "2" || len(pts) != 1 || pts[0].ID != "1" || len(pts[0].Vector) != 2 {
+		t.Fatalf("first page: %+v next %q err %v", pts, next, err)
+	}
+	pts, next, err = c.Scroll(context.Background(), "test", next, 1)
+	if err != nil || next != "" || len(pts) != 1 || pts[0].ID != "2" {
+		t.Fatalf("second page: %+v next %q err %v", pts, next, err)
+	}
+	if offsets[1] != float64(2) {
+		t.Fatalf("offset not sent as a number: %#v", offsets[1])
+	}
+}
+
+func TestPageIDs(t *testing.T) {
+	ids := []string{"10", "b", "2", "a", "1"}
+	page, next := PageIDs(ids, "", 2)
+	if strings.Join(page, ",") != "1,2" || next != "10" {
+		t.Fatalf("first page %v next %q", page, next)
+	}
+	page, next = PageIDs(ids, next, 2)
+	if strings.Join(page, ",") != "10,a" || next != "b" {
+		t.Fatalf("second page %v next %q", page, next)
+	}
+	if page, next = PageIDs(ids, next, 0); len(page) != 1 || next != "" {
+		t.Fatalf("last page %v next %q", page, next)
+	}
+}
@@ -0,0 +1,7 @@
+module github.com/gofiber/websocket/v2
+
+go 1.20
+
+require github.com/gofiber/fiber/v2 v2.52.0
+
+replace github.com/gofiber/fiber/v2 => ../fiber
@@ -0,0 +1,341 @@
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Message types, as defined by RFC 6455.
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+	CloseMessage  = 8
+	PingMessage   = 9
+	PongMessage   = 10
+)
+
+// Close codes.
+const (
+	CloseNormalClosure    = 1000
+	CloseGoingAway        = 1001
+	CloseProtocolError    = 1002
+	CloseNoStatusReceived = 1005
+	CloseMessageTooBig    = 1009
+)
+
+// ErrCloseSent is returned when writing after a close message was sent.
+var ErrCloseSent = errors.New("websocket: close sent")
+
+// CloseError is returned by ReadMessage when the peer closes the
+// connection.
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket: close %d %s", e.Code, e.Text)
+}
+
+// IsCloseError reports whether err is a *CloseError with one of codes.
+func IsCloseError(err error, codes ...int) bool {
+	var ce *CloseError
+	if !errors.As(err, &ce) {
+		return false
+	}
+	for _, c := range codes {
+		if ce.Code == c {
+			return true
+		}
+	}
+	return false
+}
+
+// FormatCloseMessage formats a close message payload.
+func FormatCloseMessage(code int, text string) []byte {
+	b := make([]byte, 2+len(text))
+	binary.BigEndian.PutUint16(b, uint16(code))
+	copy(b[2:], text)
+	return b
+}
+
+// Config configures the upgrade.
+type Config struct {
+	// Subprotocols lists the supported subprotocols in order of
+	// preference.
+	Subprotocols []string
+	// ReadLimit caps the size of a message. Zero means 1 MiB.
+	ReadLimit int64
+}
+
+// IsWebSocketUpgrade reports whether the request asks for a WebSocket
+// upgrade.
+func IsWebSocketUpgrade(c *fiber.Ctx) bool {
+	return headerContains(c.Request.Header, "Connection", "upgrade") &&
+		headerContains(c.Request.Header, "Upgrade", "websocket")
+}
+
+func headerContains(h http.Header, key, token string) bool {
+	for _, v := range h.Values(key) {
+		for _, part := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// New returns a handler that upgrades the request and runs handler with
+// the connection, closing it when handler returns.
+func New(handler func(*Conn), config ...Config) fiber.Handler {
+	var cfg Config
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.ReadLimit == 0 {
+		cfg.ReadLimit = 1 << 20
+	}
+	return func(c *fiber.Ctx) error {
+		r := c.Request
+		key := r.Header.Get("Sec-WebSocket-Key")
+		if r.Method != http.MethodGet || !IsWebSocketUpgrade(c) || key == "" || r.Header.Get("Sec-WebSocket-Version") != "13" {
+			return c.Status(http.StatusUpgradeRequired).SendString("Upgrade Required")
+		}
+		hj, ok := c.ResponseWriter.(http.Hijacker)
+		if !ok {
+			return c.Status(http.StatusInternalServerError).SendString("websocket: response does not support hijacking")
+		}
+		var proto string
+		offered := strings.Split(r.Header.Get("Sec-WebSocket-Protocol"), ",")
+		for _, want := range cfg.Subprotocols {
+			for _, o := range offered {
+				if strings.TrimSpace(o) == want && proto == "" {
+					proto = want
+				}
+			}
+		}
+		netConn, rw, err := hj.Hijack()
+		if err != nil {
+			return err
+		}
+		sum := sha1.Sum([]byte(key + acceptGUID))
+		resp := "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+			"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n"
+		if proto != "" {
+			resp += "Sec-WebSocket-Protocol: " + proto + "\r\n"
+		}
+		if _, err := netConn.Write([]byte(resp + "\r\n")); err != nil {
+			netConn.Close()
+			return err
+		}
+		c.Status(http.StatusSwitchingProtocols)
+		conn := &Conn{conn: netConn, br: rw.Reader, proto: proto, limit: cfg.ReadLimit, req: r}
+		defer conn.Close()
+		handler(conn)
+		return nil
+	}
+}
+
+// Conn is a server-side WebSocket connection. Reads must come from one
+// goroutine; writes may come from several.
+type Conn struct {
+	conn  net.Conn
+	br    *bufio.Reader
+	proto string
+	limit int64
+	req   *http.Request
+
+	wmu       sync.Mutex
+	closeSent bool
+	closeOnce sync.Once
+}
+
+// Subprotocol returns the negotiated subprotocol.
+func (c *Conn) Subprotocol() string { return c.proto }
+
+// Query returns the query parameter key of the upgrade request.
+func (c *Conn) Query(key string, defaultValue ...string) string {
+	if v := c.req.URL.Query().Get(key); v != "" || len(defaultValue) == 0 {
+		return v
+	}
+	return defaultValue[0]
+}
+
+// Headers returns the header key of the upgrade request.
+func (c *Conn) Headers(key string, defaultValue ...string) string {
+	if v := c.req.Header.Get(key); v != "" || len(defaultValue) == 0 {
+		return v
+	}
+	return defaultValue[0]
+}
+
+// SetReadDeadline sets the deadline for future reads.
+func (c *Conn) SetReadDeadline(t time.Time) error { return c.conn.SetReadDeadline(t) }
+
+// ReadMessage returns the next data message. Pings are answered and close
+// messages are echoed and returned as a *CloseError.
+func (c *Conn) ReadMessage() (int, []byte, error) {
+	var (
+		msgType int
+		msg     []byte
+	)
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+		switch op {
+		case PingMessage:
+			if err := c.WriteMessage(PongMessage, payload); err != nil && err != ErrCloseSent {
+				return 0, nil, err
+			}
+			continue
+		case PongMessage:
+			continue
+		case CloseMessage:
+			ce := &CloseError{Code: CloseNoStatusReceived}
+			if len(payload) >= 2 {
+				ce.Code = int(binary.BigEndian.Uint16(payload))
+				ce.Text = string(payload[2:])
+			}
+			_ = c.WriteMessage(CloseMessage, payload)
+			return 0, nil, ce
+		case 0:
+			if msgType == 0 {
+				return 0, nil, c.protocolError("unexpected continuation frame")
+			}
+		case TextMessage, BinaryMessage:
+			if msgType != 0 {
+				return 0, nil, c.protocolError("expected continuation frame")
+			}
+			msgType = op
+		default:
+			return 0, nil, c.protocolError(fmt.Sprintf("unknown opcode %d", op))
+		}
+		if int64(len(msg)+len(payload)) > c.limit {
+			c.WriteClose(CloseMessageTooBig, "message too big")
+			return 0, nil, &CloseError{Code: CloseMessageTooBig, Text: "message too big"}
+		}
+		msg = append(msg, payload...)
+		if fin {
+			return msgType, msg, nil
+		}
+	}
+}
+
+func (c *Conn) protocolError(text string) error {
+	c.WriteClose(CloseProtocolError, text)
+	return &CloseError{Code: CloseProtocolError, Text: text}
+}
+
+func (c *Conn) readFrame() (fin bool, op int, payload []byte, err error) {
+	var h [2]byte
+	if _, err = io.ReadFull(c.br, h[:]); err != nil {
+		return
+	}
+	fin, op = h[0]&0x80 != 0, int(h[0]&0x0f)
+	if h[1]&0x80 == 0 {
+		return false, 0, nil, c.protocolError("client frames must be masked")
+	}
+	n := int64(h[1] & 0x7f)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return
+		}
+		n = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return
+		}
+		n = int64(binary.BigEndian.Uint64(ext[:]))
+	}
+	if n < 0 || n > c.limit {
+		c.WriteClose(CloseMessageTooBig, "message too big")
+		return false, 0, nil, &CloseError{Code: CloseMessageTooBig, Text: "message too big"}
+	}
+	var mask [4]byte
+	if _, err = io.ReadFull(c.br, mask[:]); err != nil {
+		return
+	}
+	payload = make([]byte, n)
+	if _, err = io.ReadFull(c.br, payload); err != nil {
+		return
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, op, payload, nil
+}
+
+// WriteMessage sends a single-frame message.
+func (c *Conn) WriteMessage(messageType int, data []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.closeSent {
+		return ErrCloseSent
+	}
+	if messageType == CloseMessage {
+		c.closeSent = true
+	}
+	frame := []byte{0x80 | byte(messageType)}
+	switch n := len(data); {
+	case n < 126:
+		frame = append(frame, byte(n))
+	case n <= 0xffff:
+		frame = append(frame, 126, byte(n>>8), byte(n))
+	default:
+		frame = append(frame, 127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	_, err := c.conn.Write(append(frame, data...))
+	return err
+}
+
+// WriteJSON sends v as a JSON text message.
+func (c *Conn) WriteJSON(v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.WriteMessage(TextMessage, b)
+}
+
+// ReadJSON reads the next message and decodes it into v.
+func (c *Conn) ReadJSON(v interface{}) error {
+	_, b, err := c.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// WriteClose sends a close message with code and reason.
+func (c *Conn) WriteClose(code int, text string) error {
+	return c.WriteMessage(CloseMessage, FormatCloseMessage(code, text))
+}
+
+// Close closes the underlying connection.
+func (c *Conn) Close() error {
+	var err error
+	c.closeOnce.Do(func() { err = c.conn.Close() })
+	return err
+}
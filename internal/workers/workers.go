@@ -1,7 +1,26 @@
+// Package workers stands in for github.com/jrallison/go-workers with an
+// in-process queue: jobs enqueued for a queue run on the handler Process
+// registered for it once Run starts, instead of going through Redis.
 package workers
 
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+)
+
+// maxRetries is how many times a job whose handler panics is run again.
+const maxRetries = 3
+
+// queueSize is how many jobs a queue holds before Enqueue refuses more.
+const queueSize = 1024
+
 // Msg mimics the message passed to job handlers.
-type Msg struct{ args []interface{} }
+type Msg struct {
+	args    []interface{}
+	retries int
+}
 
 // Args returns job arguments.
 func (m *Msg) Args() []interface{} { return m.args }
@@ -12,10 +31,121 @@ func NewMsg(args []interface{}) *Msg { return &Msg{args: args} }
 // JobFunc is a handler for a job.
 type JobFunc func(*Msg)
 
+type queue struct {
+	fn          JobFunc
+	concurrency int
+	jobs        chan *Msg
+}
+
+var (
+	mu     sync.Mutex
+	queues = map[string]*queue{}
+	quit   chan struct{}
+	nextID int64
+)
+
+// Configure is accepted for compatibility; the in-process queue needs no
+// Redis settings.
 func Configure(map[string]string) {}
 
-func Process(queue string, fn JobFunc, concurrency int) {}
+// Process registers fn to run jobs of queue on up to concurrency
+// goroutines once Run starts.
+func Process(name string, fn JobFunc, concurrency int) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	queues[name] = &queue{fn: fn, concurrency: concurrency, jobs: make(chan *Msg, queueSize)}
+}
+
+// Run processes jobs, including those enqueued before it started, until
+// Quit is called.
+func Run() {
+	mu.Lock()
+	if quit != nil {
+		mu.Unlock()
+		return
+	}
+	done := make(chan struct{})
+	quit = done
+	var wg sync.WaitGroup
+	for _, q := range queues {
+		for i := 0; i < q.concurrency; i++ {
+			wg.Add(1)
+			go func(q *queue) {
+				defer wg.Done()
+				for {
+					select {
+					case <-done:
+						return
+					case msg := <-q.jobs:
+						q.run(msg)
+					}
+				}
+			}(q)
+		}
+	}
+	mu.Unlock()
+	<-done
+	wg.Wait()
+}
+
+// Quit stops Run once the jobs in progress finish. Jobs still queued are
+// kept for the next Run.
+func Quit() {
+	mu.Lock()
+	defer mu.Unlock()
+	if quit != nil {
+		close(quit)
+		quit = nil
+	}
+}
 
-func Run() {}
+// run runs msg, queueing it again when the handler panics, as go-workers
+// retries failed jobs.
+func (q *queue) run(msg *Msg) {
+	defer func() {
+		if recover() != nil && msg.retries < maxRetries {
+			msg.retries++
+			select {
+			case q.jobs <- msg:
+			default:
+			}
+		}
+	}()
+	q.fn(msg)
+}
 
-func Quit() {}
+// Enqueue adds a job for class with args to queue and returns its ID.
+// Arguments go through JSON, as they would through Redis, so numbers reach
+// the handler as float64. It fails when no handler processes queue or the
+// queue is full.
+func Enqueue(name, class string, args interface{}) (string, error) {
+	raw, err := json.Marshal(args)
+	if err != nil {
+		return "", err
+	}
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return "", err
+	}
+	decoded, ok := v.([]interface{})
+	if !ok {
+		decoded = []interface{}{v}
+	}
+	mu.Lock()
+	q, found := queues[name]
+	nextID++
+	id := strconv.FormatInt(nextID, 10)
+	mu.Unlock()
+	if !found {
+		return "", errors.New("workers: no handler for queue " + name)
+	}
+	select {
+	case q.jobs <- &Msg{args: decoded}:
+		return id, nil
+	default:
+		return "", errors.New("workers: queue " + name + " is full")
+	}
+}